@@ -0,0 +1,169 @@
+// Package apitoken issues and verifies org-scoped API tokens. Admins mint
+// tokens through the gateway's admin API; callers present them as bearer
+// tokens. Only the SHA-256 hash of a token is stored, and Verifier caches
+// lookups so that revocations reach every instance within the cache TTL.
+package apitoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tlng/config"
+	"tlng/storage/store"
+
+	"github.com/google/uuid"
+)
+
+// Scopes a token may be granted
+const (
+	ScopeSubmit = "submit" // Submit logs to the gateway
+	ScopeQuery  = "query"  // Query the org's submissions through the query service
+	ScopeAdmin  = "admin"  // Operator endpoints and token management of the org
+)
+
+// Method is the svcauth.Identity method of callers authenticated by API token
+const Method = "api_token"
+
+// Prefix starts every API token, telling them apart from other bearer tokens such as OIDC JWTs
+const Prefix = "tlng_"
+
+// ErrInvalidToken is returned for a mint or rotation request the configuration does not allow
+var ErrInvalidToken = errors.New("invalid api token request")
+
+// ValidScope reports whether scope is one a token may be granted
+func ValidScope(scope string) bool {
+	return scope == ScopeSubmit || scope == ScopeQuery || scope == ScopeAdmin
+}
+
+// Hash returns the hex-encoded SHA-256 of a token, the form it is stored in
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecret returns a new random token
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	return Prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Manager mints, lists, rotates and revokes API tokens
+type Manager struct {
+	store    store.Store
+	verifier *Verifier // Cache dropped on revocation; nil if none
+	cfg      config.APITokensConfig
+}
+
+// NewManager creates a token manager; verifier, if not nil, forgets revoked tokens at once
+func NewManager(s store.Store, cfg config.APITokensConfig, verifier *Verifier) *Manager {
+	return &Manager{store: s, verifier: verifier, cfg: cfg}
+}
+
+// Mint creates a token of an org with the given scopes, valid for ttl (0 for
+// the default TTL). The token itself is only returned here; it cannot be
+// recovered later.
+func (m *Manager) Mint(ctx context.Context, orgID, description string, scopes []string, ttl time.Duration) (string, *store.APIToken, error) {
+	if orgID == "" {
+		return "", nil, fmt.Errorf("%w: org_id is required", ErrInvalidToken)
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidToken)
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", nil, fmt.Errorf("%w: unknown scope '%s' (expected %s, %s or %s)", ErrInvalidToken, scope, ScopeSubmit, ScopeQuery, ScopeAdmin)
+		}
+	}
+	if ttl == 0 {
+		ttl = m.cfg.DefaultTTL
+	}
+	if ttl < 0 || ttl > m.cfg.MaxTTL {
+		return "", nil, fmt.Errorf("%w: ttl must be between 0 and %v", ErrInvalidToken, m.cfg.MaxTTL)
+	}
+
+	secret, t, err := newToken(orgID, description, scopes, ttl)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := m.store.CreateAPIToken(ctx, *t); err != nil {
+		return "", nil, err
+	}
+	return secret, t, nil
+}
+
+// newToken returns a new token and its record
+func newToken(orgID, description string, scopes []string, ttl time.Duration) (string, *store.APIToken, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	return secret, &store.APIToken{
+		TokenID:     uuid.NewString(),
+		OrgID:       orgID,
+		Description: description,
+		Scopes:      slices.Compact(scopes),
+		SecretHash:  Hash(secret),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}, nil
+}
+
+// Get returns a token by ID
+func (m *Manager) Get(ctx context.Context, tokenID string) (*store.APIToken, error) {
+	return m.store.GetAPIToken(ctx, tokenID)
+}
+
+// List returns the tokens of an org, newest first; orgID "" lists all orgs
+func (m *Manager) List(ctx context.Context, orgID string) ([]*store.APIToken, error) {
+	return m.store.ListAPITokens(ctx, orgID)
+}
+
+// Revoke rejects a token from now on
+func (m *Manager) Revoke(ctx context.Context, tokenID string) error {
+	if err := m.store.RevokeAPIToken(ctx, tokenID, time.Now()); err != nil {
+		return err
+	}
+	if m.verifier != nil {
+		m.verifier.forget(tokenID)
+	}
+	return nil
+}
+
+// Rotate replaces an active token by a new one with the same org, scopes and
+// lifetime; the old token keeps working for the rotation grace period so
+// callers can switch over. It returns the new token and the end of the grace period.
+func (m *Manager) Rotate(ctx context.Context, tokenID string) (string, *store.APIToken, time.Time, error) {
+	old, err := m.store.GetAPIToken(ctx, tokenID)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	ttl := min(old.ExpiresAt.Sub(old.CreatedAt), m.cfg.MaxTTL)
+	secret, next, err := newToken(old.OrgID, old.Description, old.Scopes, ttl)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	graceEnd := next.CreatedAt.Add(m.cfg.RotationGrace)
+	if err := m.store.RotateAPIToken(ctx, tokenID, *next, graceEnd); err != nil {
+		return "", nil, time.Time{}, err
+	}
+	if m.verifier != nil {
+		m.verifier.forget(tokenID)
+	}
+	if old.ExpiresAt.Before(graceEnd) {
+		graceEnd = old.ExpiresAt
+	}
+	return secret, next, graceEnd, nil
+}
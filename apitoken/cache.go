@@ -0,0 +1,74 @@
+package apitoken
+
+import (
+	"container/list"
+	"time"
+
+	"tlng/storage/store"
+)
+
+// tokenCache is a least-recently-used cache of token lookups by secret hash,
+// holding at most size entries
+type tokenCache struct {
+	size    int
+	order   *list.List               // Most recently used first
+	entries map[string]*list.Element // Secret hash -> element holding a *cachedToken
+}
+
+// cachedToken is a token lookup and when it was made; token is nil for an unknown hash
+type cachedToken struct {
+	hash    string
+	token   *store.APIToken
+	fetched time.Time
+}
+
+// newTokenCache creates an empty cache of up to size lookups
+func newTokenCache(size int) *tokenCache {
+	return &tokenCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the lookup of hash, if cached
+func (c *tokenCache) get(hash string) (*cachedToken, bool) {
+	e, ok := c.entries[hash]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cachedToken), true
+}
+
+// put caches a lookup, evicting the least recently used one when full
+func (c *tokenCache) put(entry *cachedToken) {
+	if c.size <= 0 {
+		return
+	}
+	if e, ok := c.entries[entry.hash]; ok {
+		e.Value = entry
+		c.order.MoveToFront(e)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedToken).hash)
+	}
+	c.entries[entry.hash] = c.order.PushFront(entry)
+}
+
+// remove drops the lookup of hash
+func (c *tokenCache) remove(hash string) {
+	if e, ok := c.entries[hash]; ok {
+		c.order.Remove(e)
+		delete(c.entries, hash)
+	}
+}
+
+// removeToken drops the lookups that found the token tokenID
+func (c *tokenCache) removeToken(tokenID string) {
+	for hash, e := range c.entries {
+		if t := e.Value.(*cachedToken).token; t != nil && t.TokenID == tokenID {
+			c.order.Remove(e)
+			delete(c.entries, hash)
+		}
+	}
+}
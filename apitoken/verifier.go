@@ -0,0 +1,109 @@
+package apitoken
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tlng/storage/store"
+	"tlng/svcauth"
+)
+
+// Verifier authenticates callers by API token. Bearer tokens without the
+// token prefix, and calls without a token, are passed on to the next verifier,
+// so API tokens work beside SPIFFE or OIDC service authentication.
+type Verifier struct {
+	store store.Store
+	next  svcauth.Verifier // nil if API tokens are the only method
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	tokens *tokenCache // Lookups that found a token
+	misses *tokenCache // Lookups of unknown hashes, apart so that they cannot evict tokens
+}
+
+// NewVerifier creates an API token verifier caching up to cacheSize token
+// lookups, and as many lookups of unknown tokens, for cacheTTL; next, if not
+// nil, verifies callers that present no API token
+func NewVerifier(s store.Store, cacheTTL time.Duration, cacheSize int, next svcauth.Verifier) *Verifier {
+	return &Verifier{store: s, next: next, ttl: cacheTTL, tokens: newTokenCache(cacheSize), misses: newTokenCache(cacheSize)}
+}
+
+// ServerTLSConfig is the next verifier's
+func (v *Verifier) ServerTLSConfig() *tls.Config {
+	if v.next != nil {
+		return v.next.ServerTLSConfig()
+	}
+	return nil
+}
+
+// Verify authenticates a caller by API token, or by the next verifier
+func (v *Verifier) Verify(ctx context.Context, state *tls.ConnectionState, token string) (*svcauth.Identity, error) {
+	if !strings.HasPrefix(token, Prefix) {
+		if v.next != nil {
+			return v.next.Verify(ctx, state, token)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("%w: no api token presented", svcauth.ErrUnauthenticated)
+		}
+		return nil, fmt.Errorf("%w: not an api token", svcauth.ErrUnauthenticated)
+	}
+
+	t, err := v.lookup(ctx, Hash(token))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("%w: unknown api token", svcauth.ErrUnauthenticated)
+	}
+	if !t.Active(time.Now()) {
+		return nil, fmt.Errorf("%w: api token %s is expired or revoked", svcauth.ErrUnauthenticated, t.TokenID)
+	}
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = []string{} // A token never grants every scope
+	}
+	return &svcauth.Identity{Method: Method, ID: t.TokenID, OrgID: t.OrgID, Scopes: scopes}, nil
+}
+
+// lookup returns the token with the given hash, nil if there is none, from the cache or the store
+func (v *Verifier) lookup(ctx context.Context, hash string) (*store.APIToken, error) {
+	now := time.Now()
+	v.mu.Lock()
+	entry, ok := v.tokens.get(hash)
+	if !ok {
+		entry, ok = v.misses.get(hash)
+	}
+	v.mu.Unlock()
+	if ok && now.Sub(entry.fetched) < v.ttl {
+		return entry.token, nil
+	}
+
+	t, err := v.store.GetAPITokenByHash(ctx, hash)
+	if err != nil && !errors.Is(err, store.ErrAPITokenNotFound) {
+		return nil, fmt.Errorf("failed to look up api token: %w", err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	entry = &cachedToken{hash: hash, token: t, fetched: now}
+	if t != nil {
+		v.misses.remove(hash)
+		v.tokens.put(entry)
+	} else {
+		v.tokens.remove(hash)
+		v.misses.put(entry)
+	}
+	return t, nil
+}
+
+// forget drops a token from the cache, so its revocation applies to this instance at once
+func (v *Verifier) forget(tokenID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.tokens.removeToken(tokenID)
+}
@@ -0,0 +1,250 @@
+// Package archive retains raw log submissions in object storage. The Archiver
+// consumes the submission topic with its own consumer group and writes
+// compressed NDJSON objects partitioned by date and source organization.
+// The Exporter writes completed attestation records to Parquet for analytics.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/messaging/consumer"
+	"tlng/internal/models"
+	"tlng/storage/objectstore"
+
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+)
+
+// partitionKey identifies the object partition a record belongs to
+type partitionKey struct {
+	date string // YYYY-MM-DD (UTC) of the gateway receive time
+	org  string // Sanitized source organization ID
+}
+
+// Archiver batches consumed submissions and uploads them to object storage
+type Archiver struct {
+	cfg      config.ArchiveConfig
+	consumer consumer.Consumer
+	store    objectstore.ObjectStore
+	logger   *log.Logger
+
+	partitions map[partitionKey][][]byte // Encoded NDJSON lines per partition
+	acks       []func(success bool)      // Acks of all buffered messages
+	records    int
+	bytes      int
+}
+
+// New creates a new Archiver instance
+func New(cfg config.ArchiveConfig, c consumer.Consumer, s objectstore.ObjectStore, logger *log.Logger) *Archiver {
+	return &Archiver{
+		cfg:        cfg,
+		consumer:   c,
+		store:      s,
+		logger:     logger,
+		partitions: make(map[partitionKey][][]byte),
+	}
+}
+
+// Run consumes messages until ctx is cancelled, flushing buffered records on size or time
+func (a *Archiver) Run(ctx context.Context) {
+	a.logger.Printf("Archiver started: flush_interval=%v, max_records=%d, max_bytes=%d, compression=%s",
+		a.cfg.FlushInterval, a.cfg.MaxRecords, a.cfg.MaxBytes, a.cfg.Compression)
+
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Final flush with a bounded deadline; anything not uploaded stays uncommitted in Kafka
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			a.flush(flushCtx)
+			cancel()
+			a.logger.Println("Archiver stopped.")
+			return
+
+		case <-ticker.C:
+			a.flush(ctx)
+
+		default:
+			consumeCtx, consumeCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			msg, ack, err := a.consumer.Consume(consumeCtx)
+			consumeCancel()
+
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+					continue
+				}
+				a.logger.Printf("Archiver: Consumer error: %v", err)
+				continue
+			}
+			if msg == nil {
+				continue
+			}
+
+			if err := a.add(msg, ack); err != nil {
+				a.logger.Printf("Archiver: Failed to encode message %s, skipping: %v", msg.RequestID, err)
+				ack(true)
+				continue
+			}
+
+			if (a.cfg.MaxRecords > 0 && a.records >= a.cfg.MaxRecords) || (a.cfg.MaxBytes > 0 && a.bytes >= a.cfg.MaxBytes) {
+				a.flush(ctx)
+			}
+		}
+	}
+}
+
+// add encodes a message and buffers it in its partition
+func (a *Archiver) add(msg *models.LogMessage, ack func(success bool)) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	key := partitionKey{
+		date: receivedDate(msg.ReceivedTimestamp).Format("2006-01-02"),
+		org:  sanitize(msg.SourceOrgID),
+	}
+	a.partitions[key] = append(a.partitions[key], line)
+	a.acks = append(a.acks, ack)
+	a.records++
+	a.bytes += len(line)
+	return nil
+}
+
+// flush uploads every buffered partition, retrying until success or ctx is done.
+// Messages are acknowledged only once all partitions are durably stored; Kafka
+// commits are cumulative, so a failed flush must never be followed by a later ack.
+// Uploads therefore only give up on shutdown, and objects may be written twice
+// after a restart (at-least-once).
+func (a *Archiver) flush(ctx context.Context) {
+	if a.records == 0 {
+		return
+	}
+	start := time.Now()
+
+	keys := make([]partitionKey, 0, len(a.partitions))
+	for k := range a.partitions {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].date != keys[j].date {
+			return keys[i].date < keys[j].date
+		}
+		return keys[i].org < keys[j].org
+	})
+
+	for _, k := range keys {
+		if err := a.upload(ctx, k, a.partitions[k]); err != nil {
+			a.logger.Printf("Archiver: Giving up on flush of %d records, leaving offsets uncommitted: %v", a.records, err)
+			a.reset(false)
+			return
+		}
+	}
+
+	a.logger.Printf("Archiver: Archived %d records (%d bytes) in %d objects, took %v",
+		a.records, a.bytes, len(keys), time.Since(start))
+	a.reset(true)
+}
+
+// upload writes one partition object, retrying with a fixed backoff
+func (a *Archiver) upload(ctx context.Context, k partitionKey, lines [][]byte) error {
+	body, contentType, ext, err := a.encode(lines)
+	if err != nil {
+		return fmt.Errorf("failed to encode archive object: %w", err)
+	}
+
+	objectKey := path.Join(
+		"dt="+k.date,
+		"org="+k.org,
+		fmt.Sprintf("%s-%s.ndjson%s", time.Now().UTC().Format("20060102T150405Z"), uuid.NewString(), ext),
+	)
+
+	for attempt := 1; ; attempt++ {
+		err := a.store.Put(ctx, objectKey, body, contentType)
+		if err == nil {
+			return nil
+		}
+		a.logger.Printf("Archiver: Upload of %s failed (attempt %d): %v", objectKey, attempt, err)
+
+		select {
+		case <-time.After(a.cfg.RetryBackoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// encode joins NDJSON lines and applies the configured compression
+func (a *Archiver) encode(lines [][]byte) ([]byte, string, string, error) {
+	raw := bytes.Join(lines, nil)
+
+	switch a.cfg.Compression {
+	case "gzip":
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(raw); err != nil {
+			return nil, "", "", err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "application/gzip", ".gz", nil
+	case "zstd":
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, "", "", err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(raw, nil), "application/zstd", ".zst", nil
+	default:
+		return raw, "application/x-ndjson", "", nil
+	}
+}
+
+// reset acknowledges (or nacks) all buffered messages and clears the buffer
+func (a *Archiver) reset(success bool) {
+	for _, ack := range a.acks {
+		ack(success)
+	}
+	a.partitions = make(map[partitionKey][][]byte)
+	a.acks = nil
+	a.records = 0
+	a.bytes = 0
+}
+
+// receivedDate returns the gateway receive time in UTC, falling back to now if it is missing
+func receivedDate(ts models.Timestamp) time.Time {
+	if ts.IsZero() {
+		return time.Now().UTC()
+	}
+	return ts.UTC()
+}
+
+// sanitize makes an organization ID safe to use as an object key segment
+func sanitize(org string) string {
+	if org == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, org)
+}
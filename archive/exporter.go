@@ -0,0 +1,282 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/parquet"
+	"tlng/storage/objectstore"
+	"tlng/storage/store"
+
+	"github.com/google/uuid"
+)
+
+// exportColumn is one column of the versioned export schema
+type exportColumn struct {
+	parquet.Column
+	Since int                                  // Schema version that introduced the column
+	value func(r *store.LogStatus) interface{} // Extracts the column value from a record
+}
+
+// exportSchema is the Parquet schema of exported attestation records.
+// Schema evolution rules, so that files of every version can be read together
+// (Spark mergeSchema, DuckDB union_by_name):
+//   - columns are only appended, never renamed, retyped or removed
+//   - columns introduced after version 1 must be optional
+//   - adding a column bumps its Since to the new schema version
+var exportSchema = []exportColumn{
+	{parquet.Column{Name: "request_id", Type: parquet.String}, 1, func(r *store.LogStatus) interface{} { return r.RequestID }},
+	{parquet.Column{Name: "log_hash", Type: parquet.String}, 1, func(r *store.LogStatus) interface{} { return r.LogHash }},
+	{parquet.Column{Name: "source_org_id", Type: parquet.String}, 1, func(r *store.LogStatus) interface{} { return r.SourceOrgID }},
+	{parquet.Column{Name: "received_timestamp", Type: parquet.Timestamp}, 1, func(r *store.LogStatus) interface{} { return r.ReceivedTimestamp }},
+	{parquet.Column{Name: "received_at_db", Type: parquet.Timestamp}, 1, func(r *store.LogStatus) interface{} { return r.ReceivedAtDB }},
+	{parquet.Column{Name: "processing_started_at", Type: parquet.Timestamp, Optional: true}, 1, func(r *store.LogStatus) interface{} { return optionalTime(r.ProcessingStartedAt) }},
+	{parquet.Column{Name: "processing_finished_at", Type: parquet.Timestamp, Optional: true}, 1, func(r *store.LogStatus) interface{} { return optionalTime(r.ProcessingFinishedAt) }},
+	{parquet.Column{Name: "tx_hash", Type: parquet.String, Optional: true}, 1, func(r *store.LogStatus) interface{} { return optionalString(r.TxHash) }},
+	{parquet.Column{Name: "block_height", Type: parquet.Int64, Optional: true}, 1, func(r *store.LogStatus) interface{} { return optionalInt64(r.BlockHeight) }},
+	{parquet.Column{Name: "log_hash_on_chain", Type: parquet.String, Optional: true}, 1, func(r *store.LogStatus) interface{} { return optionalString(r.LogHashOnChain) }},
+	{parquet.Column{Name: "retry_count", Type: parquet.Int32}, 1, func(r *store.LogStatus) interface{} { return int32(r.RetryCount) }},
+	{parquet.Column{Name: "region", Type: parquet.String, Optional: true}, 1, func(r *store.LogStatus) interface{} {
+		if r.Region == "" {
+			return nil
+		}
+		return r.Region
+	}},
+	{parquet.Column{Name: "client_timestamp", Type: parquet.Timestamp, Optional: true}, 2, func(r *store.LogStatus) interface{} { return optionalTime(r.ClientTimestamp) }},
+}
+
+// schemaVersion returns the current export schema version, enforcing the evolution rules
+func schemaVersion(schema []exportColumn) (int, error) {
+	version := 0
+	for _, col := range schema {
+		if col.Since < version {
+			return 0, fmt.Errorf("column %s (since v%d) is not appended after v%d columns", col.Name, col.Since, version)
+		}
+		if col.Since > 1 && !col.Optional {
+			return 0, fmt.Errorf("column %s added in v%d must be optional", col.Name, col.Since)
+		}
+		version = col.Since
+	}
+	return version, nil
+}
+
+// Exporter periodically writes completed attestation records to Parquet files
+// in object storage, resuming from a cursor persisted in the database
+type Exporter struct {
+	cfg     config.ExportConfig
+	store   store.Store
+	objects objectstore.ObjectStore
+	logger  *log.Logger
+
+	codec   parquet.Codec
+	version int
+}
+
+// NewExporter creates a new Exporter instance
+func NewExporter(cfg config.ExportConfig, st store.Store, objects objectstore.ObjectStore, logger *log.Logger) (*Exporter, error) {
+	codec, err := parquet.ParseCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	version, err := schemaVersion(exportSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export schema: %w", err)
+	}
+	return &Exporter{
+		cfg:     cfg,
+		store:   st,
+		objects: objects,
+		logger:  logger,
+		codec:   codec,
+		version: version,
+	}, nil
+}
+
+// Run exports immediately and then on every interval until ctx is cancelled
+func (e *Exporter) Run(ctx context.Context) {
+	e.logger.Printf("Exporter started: name=%s, interval=%v, max_rows=%d, schema=v%d, compression=%s",
+		e.cfg.Name, e.cfg.Interval, e.cfg.MaxRows, e.version, e.cfg.Compression)
+
+	if err := e.putSchema(ctx); err != nil {
+		e.logger.Printf("Exporter: Failed to write schema description: %v", err)
+	}
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.ExportOnce(ctx); err != nil && ctx.Err() == nil {
+			e.logger.Printf("Exporter: Export run failed, resuming from saved cursor next run: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			e.logger.Println("Exporter stopped.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExportOnce exports every settled record after the saved cursor. The cursor is
+// saved after each uploaded file, so a failed run re-exports at most one file.
+func (e *Exporter) ExportOnce(ctx context.Context) error {
+	cursor, err := e.store.GetExportCursor(ctx, e.cfg.Name)
+	if err != nil {
+		return err
+	}
+	// Records finishing right now may still commit with an earlier timestamp; hold them back
+	before := time.Now().Add(-e.cfg.SettleDelay)
+
+	start := time.Now()
+	rows, files := 0, 0
+	for {
+		records, err := e.store.ListCompletedAfter(ctx, cursor, before, e.cfg.MaxRows)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		// Split the page by completion date so files land in the right dt= partition
+		for len(records) > 0 {
+			n := sameDate(records)
+			if err := e.upload(ctx, records[:n]); err != nil {
+				return err
+			}
+			last := records[n-1]
+			cursor = store.ExportCursor{FinishedAt: *last.ProcessingFinishedAt, RequestID: last.RequestID}
+			if err := e.store.SaveExportCursor(ctx, e.cfg.Name, cursor); err != nil {
+				return err
+			}
+			rows += n
+			files++
+			records = records[n:]
+		}
+	}
+
+	if rows > 0 {
+		e.logger.Printf("Exporter: Exported %d records in %d files, took %v", rows, files, time.Since(start))
+	}
+	return nil
+}
+
+// upload writes one Parquet file for records that share a completion date
+func (e *Exporter) upload(ctx context.Context, records []*store.LogStatus) error {
+	w := parquet.NewWriter(e.columns(), e.codec)
+	row := make([]interface{}, len(exportSchema))
+	for _, r := range records {
+		for i, col := range exportSchema {
+			row[i] = col.value(r)
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("failed to encode record %s: %w", r.RequestID, err)
+		}
+	}
+
+	body, err := w.Bytes(map[string]string{
+		"tlng.schema_version": strconv.Itoa(e.version),
+		"tlng.export":         e.cfg.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render parquet file: %w", err)
+	}
+
+	key := path.Join(
+		"dt="+records[0].ProcessingFinishedAt.UTC().Format("2006-01-02"),
+		fmt.Sprintf("part-%s-%s.parquet", time.Now().UTC().Format("20060102T150405Z"), uuid.NewString()),
+	)
+	if err := e.objects.Put(ctx, key, body, "application/vnd.apache.parquet"); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+// putSchema writes a JSON description of the current schema version
+func (e *Exporter) putSchema(ctx context.Context) error {
+	type schemaField struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Optional bool   `json:"optional"`
+		Since    int    `json:"since"`
+	}
+	fields := make([]schemaField, len(exportSchema))
+	for i, col := range exportSchema {
+		fields[i] = schemaField{Name: col.Name, Type: typeName(col.Type), Optional: col.Optional, Since: col.Since}
+	}
+	body, err := json.MarshalIndent(map[string]interface{}{
+		"version": e.version,
+		"fields":  fields,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return e.objects.Put(ctx, fmt.Sprintf("_schema/v%d.json", e.version), body, "application/json")
+}
+
+// columns returns the Parquet columns of the export schema
+func (e *Exporter) columns() []parquet.Column {
+	cols := make([]parquet.Column, len(exportSchema))
+	for i, col := range exportSchema {
+		cols[i] = col.Column
+	}
+	return cols
+}
+
+// sameDate returns how many leading records share the first record's completion date (UTC)
+func sameDate(records []*store.LogStatus) int {
+	day := records[0].ProcessingFinishedAt.UTC().Format("2006-01-02")
+	for i, r := range records {
+		if r.ProcessingFinishedAt.UTC().Format("2006-01-02") != day {
+			return i
+		}
+	}
+	return len(records)
+}
+
+func typeName(t parquet.Type) string {
+	switch t {
+	case parquet.Int32:
+		return "int32"
+	case parquet.Int64:
+		return "int64"
+	case parquet.Double:
+		return "double"
+	case parquet.Boolean:
+		return "boolean"
+	case parquet.String:
+		return "string"
+	case parquet.Timestamp:
+		return "timestamp_micros"
+	default:
+		return "unknown"
+	}
+}
+
+func optionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func optionalString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func optionalInt64(v *int64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
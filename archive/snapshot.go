@@ -0,0 +1,249 @@
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/storage/store"
+
+	"github.com/google/uuid"
+)
+
+// File names within a snapshot directory, next to one <table>.csv per table
+const (
+	SnapshotManifestFile = "manifest.json"
+	SnapshotAnchorFile   = "anchor.json"
+)
+
+// SnapshotManifest lists the files of a snapshot with their SHA-256. The
+// SHA-256 of manifest.json itself is anchored on chain.
+type SnapshotManifest struct {
+	ID            string         `json:"id"`
+	TakenAt       time.Time      `json:"taken_at"` // Database time of the snapshot transaction
+	SchemaVersion int            `json:"schema_version"`
+	Files         []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile is one exported table
+type SnapshotFile struct {
+	Table  string `json:"table"`
+	Name   string `json:"name"` // File name within the snapshot directory
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// SnapshotAnchor is the on-chain attestation of a snapshot manifest: a log
+// whose hash is the manifest's SHA-256
+type SnapshotAnchor struct {
+	ManifestSHA256 string `json:"manifest_sha256"`
+	OrgID          string `json:"org_id"`    // Sender org the manifest was anchored as
+	Timestamp      string `json:"timestamp"` // Anchored timestamp (the snapshot time)
+	TxHash         string `json:"tx_hash"`
+	BlockHeight    uint64 `json:"block_height"`
+}
+
+// Snapshotter exports point-in-time snapshots of the attestation tables and
+// anchors their manifests on chain, so the service's own state can be verified
+type Snapshotter struct {
+	store  store.Store
+	chain  blockchain.BlockchainClient
+	orgID  string
+	logger *log.Logger
+}
+
+// NewSnapshotter creates a snapshotter anchoring manifests as orgID. The store
+// is only needed to create snapshots.
+func NewSnapshotter(st store.Store, chain blockchain.BlockchainClient, orgID string, logger *log.Logger) *Snapshotter {
+	return &Snapshotter{store: st, chain: chain, orgID: orgID, logger: logger}
+}
+
+// Create exports a snapshot into a new directory under dir, writes its
+// manifest and anchors it. It returns the snapshot directory; if anchoring
+// fails the snapshot is kept and can be anchored again with Anchor.
+func (s *Snapshotter) Create(ctx context.Context, dir string) (string, *SnapshotAnchor, error) {
+	id := uuid.NewString()
+	snapshotDir := filepath.Join(dir, id)
+	if err := os.MkdirAll(snapshotDir, 0o755); err != nil {
+		return "", nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	hashes := make(map[string]hash.Hash)
+	snapshot, err := s.store.ExportSnapshot(ctx, func(table string) (io.WriteCloser, error) {
+		f, err := os.Create(filepath.Join(snapshotDir, table+".csv"))
+		if err != nil {
+			return nil, err
+		}
+		hashes[table] = sha256.New()
+		return &hashingFile{file: f, w: io.MultiWriter(f, hashes[table])}, nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to export snapshot: %w", err)
+	}
+
+	manifest := SnapshotManifest{ID: id, TakenAt: snapshot.TakenAt.UTC(), SchemaVersion: snapshot.SchemaVersion}
+	for _, table := range snapshot.Tables {
+		manifest.Files = append(manifest.Files, SnapshotFile{
+			Table:  table.Name,
+			Name:   table.Name + ".csv",
+			Rows:   table.Rows,
+			SHA256: hex.EncodeToString(hashes[table.Name].Sum(nil)),
+		})
+		s.logger.Printf("Snapshot %s: exported %d rows of %s", id, table.Rows, table.Name)
+	}
+	if err := writeJSON(filepath.Join(snapshotDir, SnapshotManifestFile), manifest); err != nil {
+		return "", nil, err
+	}
+
+	anchor, err := s.Anchor(ctx, snapshotDir)
+	if err != nil {
+		return snapshotDir, nil, fmt.Errorf("snapshot %s written but not anchored: %w", snapshotDir, err)
+	}
+	return snapshotDir, anchor, nil
+}
+
+// Anchor submits the manifest hash of a snapshot to the chain and records the
+// transaction in anchor.json
+func (s *Snapshotter) Anchor(ctx context.Context, snapshotDir string) (*SnapshotAnchor, error) {
+	manifest, manifestHash, err := readManifest(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := &SnapshotAnchor{
+		ManifestSHA256: manifestHash,
+		OrgID:          s.orgID,
+		Timestamp:      manifest.TakenAt.Format(time.RFC3339Nano),
+	}
+	proof, err := s.chain.SubmitLog(ctx, manifestHash, anchorContent(manifest.ID), s.orgID, anchor.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anchor manifest %s: %w", manifestHash, err)
+	}
+	anchor.TxHash = proof.TransactionID
+	anchor.BlockHeight = proof.BlockHeight
+	if err := writeJSON(filepath.Join(snapshotDir, SnapshotAnchorFile), anchor); err != nil {
+		return nil, err
+	}
+	s.logger.Printf("Snapshot %s: manifest %s anchored in tx %s at block %d", manifest.ID, manifestHash, anchor.TxHash, anchor.BlockHeight)
+	return anchor, nil
+}
+
+// Verify checks a snapshot directory: every file against the manifest, the
+// manifest against anchor.json and the anchor against the chain
+func (s *Snapshotter) Verify(ctx context.Context, snapshotDir string) (*SnapshotManifest, *SnapshotAnchor, error) {
+	manifest, manifestHash, err := readManifest(snapshotDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, file := range manifest.Files {
+		sum, err := fileSHA256(filepath.Join(snapshotDir, file.Name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if sum != file.SHA256 {
+			return nil, nil, fmt.Errorf("%s: SHA-256 %s does not match the manifest (%s)", file.Name, sum, file.SHA256)
+		}
+	}
+
+	var anchor SnapshotAnchor
+	data, err := os.ReadFile(filepath.Join(snapshotDir, SnapshotAnchorFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("snapshot %s is not anchored", manifest.ID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", SnapshotAnchorFile, err)
+	}
+	if err := json.Unmarshal(data, &anchor); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", SnapshotAnchorFile, err)
+	}
+	if anchor.ManifestSHA256 != manifestHash {
+		return nil, nil, fmt.Errorf("manifest SHA-256 %s does not match the anchored %s", manifestHash, anchor.ManifestSHA256)
+	}
+
+	raw, err := s.chain.FindLogByHash(ctx, manifestHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up manifest %s on chain: %w", manifestHash, err)
+	}
+	if raw == "" {
+		return nil, nil, fmt.Errorf("manifest %s is not on chain", manifestHash)
+	}
+	record, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse on-chain record of manifest %s: %w", manifestHash, err)
+	}
+	if record.Get("org_id") != anchor.OrgID || record.Get("content") != anchorContent(manifest.ID) {
+		return nil, nil, fmt.Errorf("on-chain record of manifest %s (org %s) does not attest snapshot %s",
+			manifestHash, record.Get("org_id"), manifest.ID)
+	}
+	return manifest, &anchor, nil
+}
+
+// anchorContent is the log content anchored with a manifest hash
+func anchorContent(snapshotID string) string {
+	return "snapshot:" + snapshotID
+}
+
+// hashingFile writes through to a file and a hash
+type hashingFile struct {
+	file *os.File
+	w    io.Writer
+}
+
+func (f *hashingFile) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f *hashingFile) Close() error {
+	return f.file.Close()
+}
+
+// readManifest reads a snapshot's manifest and the SHA-256 of its bytes
+func readManifest(snapshotDir string) (*SnapshotManifest, string, error) {
+	data, err := os.ReadFile(filepath.Join(snapshotDir, SnapshotManifestFile))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", SnapshotManifestFile, err)
+	}
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", SnapshotManifestFile, err)
+	}
+	sum := sha256.Sum256(data)
+	return &manifest, hex.EncodeToString(sum[:]), nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -2,9 +2,12 @@ package chainmaker
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"tlng/blockchain/types"
@@ -16,9 +19,15 @@ import (
 
 // Client is the wrapper around the ChainMaker SDK client
 type Client struct {
-	sdkClient sdk.ChainClient
-	cfg       *config.BlockchainConfig
-	logger    *log.Logger
+	sdkClient    atomic.Pointer[sdk.ChainClient] // Replaced when the credentials rotate (see watchCredentials)
+	cfg          *config.BlockchainConfig
+	logger       *log.Logger
+	eventParsers map[string]*eventParser // Event topic -> parser for the contract version
+	version      string                  // Contract version the event parsers are for
+
+	credentials   string             // Digest of the credential files the SDK client was built from
+	stopCredWatch context.CancelFunc // Stops watchCredentials; nil if credential reloading is disabled
+	credWatchDone chan struct{}
 }
 
 // NewChainMakerClient initializes the ChainMaker SDK client with the combined configuration
@@ -31,6 +40,44 @@ func NewChainMakerClient(cfg *config.BlockchainConfig, logger *log.Logger) (*Cli
 		return nil, fmt.Errorf("invalid ChainMaker configuration type")
 	}
 
+	version, err := resolveContractVersion(chainmakerCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve contract version: %w", err)
+	}
+	eventParsers, err := newEventParsers(chainmakerCfg, version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event schema configuration: %w", err)
+	}
+
+	credentials, err := credentialsDigest(credentialFiles(chainmakerCfg))
+	if err != nil {
+		return nil, err
+	}
+	client, err := newSDKClient(cfg, chainmakerCfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Println("ChainMaker SDK client initialized successfully.")
+
+	c := &Client{
+		cfg:          cfg,
+		logger:       logger,
+		eventParsers: eventParsers,
+		version:      version,
+		credentials:  credentials,
+	}
+	c.sdkClient.Store(client)
+	if chainmakerCfg.CredentialReloadInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.stopCredWatch, c.credWatchDone = cancel, make(chan struct{})
+		go c.watchCredentials(ctx, chainmakerCfg.CredentialReloadInterval)
+	}
+	return c, nil
+}
+
+// newSDKClient builds a ChainMaker SDK client, reading the credential files
+func newSDKClient(cfg *config.BlockchainConfig, chainmakerCfg *ChainMakerConfig, logger *log.Logger) (*sdk.ChainClient, error) {
 	var clientOptions []sdk.ChainClientOption
 	clientOptions = append(clientOptions, sdk.WithChainClientOrgId(chainmakerCfg.OrgID))
 	clientOptions = append(clientOptions, sdk.WithChainClientChainId(chainmakerCfg.ChainID))
@@ -74,14 +121,7 @@ func NewChainMakerClient(cfg *config.BlockchainConfig, logger *log.Logger) (*Cli
 	if err != nil {
 		logger.Printf("Warning: Failed to enable cert hash: %v\n", err)
 	}
-
-	logger.Println("ChainMaker SDK client initialized successfully.")
-
-	return &Client{
-		sdkClient: *client,
-		cfg:       cfg,
-		logger:    logger,
-	}, nil
+	return client, nil
 }
 
 // NewChainMakerClientFromFile initializes the ChainMaker SDK client directly from a configuration file path
@@ -105,6 +145,15 @@ func NewChainMakerClientFromFile(configPath string, logger *log.Logger) (*Client
 	return NewChainMakerClient(blockchainCfg, logger)
 }
 
+// SingleCallThreshold returns the batch size below which logs are anchored
+// with SubmitLog calls, 0 to always use SubmitLogsBatch
+func (c *Client) SingleCallThreshold() int {
+	if c.cfg == nil {
+		return 0
+	}
+	return c.cfg.SingleCallThreshold
+}
+
 // Config returns the configuration associated with the client.
 func (c *Client) Config() any {
 	if c.cfg == nil || c.cfg.ChainSpecific == nil {
@@ -114,10 +163,14 @@ func (c *Client) Config() any {
 	return c.cfg.ChainSpecific
 }
 
-// Close stops the SDK client
+// Close stops the credential watcher and the SDK client
 func (c *Client) Close() error {
 	c.logger.Println("Closing ChainMaker SDK client...")
-	if err := c.sdkClient.Stop(); err != nil {
+	if c.stopCredWatch != nil {
+		c.stopCredWatch()
+		<-c.credWatchDone
+	}
+	if err := c.chainClient().Stop(); err != nil {
 		c.logger.Printf("Error stopping ChainMaker SDK client: %v", err)
 		return fmt.Errorf("failed to stop ChainMaker SDK client: %w", err)
 	}
@@ -133,19 +186,22 @@ func (c *Client) SubmitLogsBatch(ctx context.Context, entries []types.LogEntry)
 		return nil, nil, fmt.Errorf("batch configuration fields not set in config")
 	}
 
-	// Use generic entries directly - no conversion needed
-	logsJsonBytes, err := json.Marshal(entries)
+	// Canonical encoding, so anyone holding the entries can recompute the payload
+	compression := c.cfg.BatchCompression
+	logsJsonBytes, batchProof, err := types.EncodeBatchPayload(entries, compression.Algorithm, compression.MinBytes)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal log entries to JSON: %w", err)
 	}
-	logsJsonStr := string(logsJsonBytes)
 
 	kvs := []*common.KeyValuePair{
 		{
 			Key:   c.cfg.ChainSpecific.(*ChainMakerConfig).ParamKeyLogsJson,
-			Value: []byte(logsJsonStr),
+			Value: logsJsonBytes,
 		},
 	}
+	if batchProof.Encoding != "" {
+		kvs = append(kvs, &common.KeyValuePair{Key: compression.ParamKey, Value: []byte(batchProof.Encoding)})
+	}
 
 	_, cancel := context.WithTimeout(ctx, time.Duration(c.cfg.TimeoutSeconds)*time.Second)
 	defer cancel()
@@ -153,7 +209,7 @@ func (c *Client) SubmitLogsBatch(ctx context.Context, entries []types.LogEntry)
 	// c.logger.Printf("Calling contract '%s', batch method '%s' with %d entries...",
 	// 	c.cfg.ChainSpecific.(*ChainMakerConfig).ContractName, c.cfg.ChainSpecific.(*ChainMakerConfig).SubmitLogsBatchMethodName, len(entries))
 
-	resp, err := c.sdkClient.InvokeContract(
+	resp, err := c.chainClient().InvokeContract(
 		c.cfg.ChainSpecific.(*ChainMakerConfig).ContractName,
 		c.cfg.ChainSpecific.(*ChainMakerConfig).SubmitLogsBatchMethodName,
 		"",
@@ -163,11 +219,11 @@ func (c *Client) SubmitLogsBatch(ctx context.Context, entries []types.LogEntry)
 	)
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("SDK batch invoke failed: %w", err)
+		return nil, nil, sdkError("SDK batch invoke failed", err)
 	}
 
 	if resp.Code != common.TxStatusCode_SUCCESS {
-		return nil, nil, fmt.Errorf("contract batch execution failed: %s (code: %d)", resp.Message, resp.Code)
+		return nil, nil, txError("contract batch execution failed", resp.Code, resp.Message)
 	}
 	if resp.ContractResult == nil {
 		return nil, nil, fmt.Errorf("contract batch execution returned nil result (tx: %s)", resp.TxId)
@@ -185,10 +241,8 @@ func (c *Client) SubmitLogsBatch(ctx context.Context, entries []types.LogEntry)
 		return nil, nil, fmt.Errorf("failed to unmarshal contract batch results: %w", err)
 	}
 
-	batchProof := &types.BatchProof{
-		TransactionID: resp.TxId,
-		BlockHeight:   resp.TxBlockHeight,
-	}
+	batchProof.TransactionID = resp.TxId
+	batchProof.BlockHeight = resp.TxBlockHeight
 
 	// c.logger.Printf("Successfully processed batch submission. TxID: %s, Block: %d, Results count: %d",
 	// 	batchProof.TransactionID, batchProof.BlockHeight, len(results))
@@ -206,13 +260,13 @@ func (c *Client) SubmitLog(ctx context.Context, logHash, logContent, senderOrgID
 	}
 	_, cancel := context.WithTimeout(ctx, time.Duration(c.cfg.TimeoutSeconds)*time.Second)
 	defer cancel()
-	resp, err := c.sdkClient.InvokeContract(
+	resp, err := c.chainClient().InvokeContract(
 		c.cfg.ChainSpecific.(*ChainMakerConfig).ContractName, c.cfg.ChainSpecific.(*ChainMakerConfig).SubmitLogMethodName, "", kvs, -1, true)
 	if err != nil {
-		return nil, fmt.Errorf("SDK invoke failed: %w", err)
+		return nil, sdkError("SDK invoke failed", err)
 	}
 	if resp.Code != common.TxStatusCode_SUCCESS {
-		return nil, fmt.Errorf("contract execution failed: %s (code: %d)", resp.Message, resp.Code)
+		return nil, txError("contract execution failed", resp.Code, resp.Message)
 	}
 	returnedHash := string(resp.ContractResult.Result)
 	if returnedHash != logHash {
@@ -227,24 +281,27 @@ func (c *Client) FindLogByHash(ctx context.Context, logHash string) (string, err
 	_, cancel := context.WithTimeout(ctx, time.Duration(c.cfg.TimeoutSeconds)*time.Second)
 	defer cancel()
 	kvs := []*common.KeyValuePair{{Key: c.cfg.ChainSpecific.(*ChainMakerConfig).ParamKeyLogHash, Value: []byte(logHash)}}
-	resp, err := c.sdkClient.QueryContract(c.cfg.ChainSpecific.(*ChainMakerConfig).ContractName, c.cfg.ChainSpecific.(*ChainMakerConfig).FindLogByHashMethodName, kvs, -1)
+	resp, err := c.chainClient().QueryContract(c.cfg.ChainSpecific.(*ChainMakerConfig).ContractName, c.cfg.ChainSpecific.(*ChainMakerConfig).FindLogByHashMethodName, kvs, -1)
 	if err != nil {
-		return "", fmt.Errorf("SDK query failed: %w", err)
+		return "", sdkError("SDK query failed", err)
 	}
 	if resp.Code != common.TxStatusCode_SUCCESS {
-		return "", fmt.Errorf("contract query failed: %s (code: %d)", resp.Message, resp.Code)
+		return "", txError("contract query failed", resp.Code, resp.Message)
 	}
 	return string(resp.ContractResult.Result), nil
 }
 
-// GetLogByTxHash performs the "on-chain public audit" by querying transaction details
-func (c *Client) GetLogByTxHash(ctx context.Context, txHash string) (*types.AuditData, error) {
+// GetLogByTxHash performs the "on-chain public audit" by querying transaction details.
+// A single submit emits one submit event; a batch emits one per stored entry.
+// Events are decoded with the parsers for the configured contract version.
+// Every entry carries the anchoring block and its current confirmation count.
+func (c *Client) GetLogByTxHash(ctx context.Context, txHash string) ([]types.AuditData, error) {
 	if txHash == "" {
 		return nil, fmt.Errorf("transaction hash cannot be empty")
 	}
-	txInfo, err := c.sdkClient.GetTxByTxId(txHash)
+	txInfo, err := c.chainClient().GetTxByTxId(txHash)
 	if err != nil {
-		return nil, fmt.Errorf("SDK get transaction failed: %w", err)
+		return nil, sdkError("SDK get transaction failed", err)
 	}
 	if txInfo == nil || txInfo.Transaction == nil || txInfo.Transaction.Result == nil || txInfo.Transaction.Result.ContractResult == nil {
 		return nil, fmt.Errorf("transaction data is incomplete or nil for tx: %s", txHash)
@@ -253,15 +310,95 @@ func (c *Client) GetLogByTxHash(ctx context.Context, txHash string) (*types.Audi
 		return nil, fmt.Errorf("transaction execution failed: %s", txInfo.Transaction.Result.Message)
 	}
 	events := txInfo.Transaction.Result.ContractResult.ContractEvent
+	var entries []types.AuditData
 	for _, event := range events {
-		if event.Topic == c.cfg.ChainSpecific.(*ChainMakerConfig).SubmitEventTopic {
-			eventData := event.EventData
-			if len(eventData) != 3 {
-				return nil, fmt.Errorf("malformed event data: expected 3 fields, got %d", len(eventData))
-			}
-			auditData := &types.AuditData{LogHash: eventData[0], SubmitterOrgID: eventData[1], Timestamp: eventData[2]}
-			return auditData, nil
+		parser, ok := c.eventParsers[event.Topic]
+		if !ok {
+			continue
+		}
+		auditData, err := parser.parse(event.EventData)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", txHash, err)
+		}
+		entries = append(entries, auditData)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("event '%s' not found in transaction %s", c.cfg.ChainSpecific.(*ChainMakerConfig).SubmitEventTopic, txHash)
+	}
+
+	// The chain head only affects the confirmation count, so failing to read it
+	// does not fail the audit
+	var confirmations uint64
+	head, err := c.chainClient().GetCurrentBlockHeight()
+	if err != nil {
+		c.logger.Printf("Warning: failed to read chain height for confirmations of tx %s: %v", txHash, err)
+	} else if head >= txInfo.BlockHeight {
+		confirmations = head - txInfo.BlockHeight + 1
+	}
+	blockHash := hex.EncodeToString(txInfo.BlockHash)
+	blockTime := time.Unix(txInfo.BlockTimestamp, 0)
+	for i := range entries {
+		entries[i].BlockHeight = txInfo.BlockHeight
+		entries[i].BlockHash = blockHash
+		entries[i].BlockTimestamp = blockTime
+		entries[i].Confirmations = confirmations
+	}
+	return entries, nil
+}
+
+// onChainLogPage is the JSON result of the contract's list-by-org method
+type onChainLogPage struct {
+	Logs []struct {
+		LogHash         string `json:"log_hash"`
+		SenderOrgID     string `json:"sender_org_id"`
+		Timestamp       string `json:"timestamp"`
+		ClientTimestamp string `json:"client_timestamp"`
+	} `json:"logs"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// ListOnChainLogs enumerates an org's attestations through the contract's
+// list-by-org method (list_logs_by_org_method_name), a page at a time
+func (c *Client) ListOnChainLogs(ctx context.Context, orgID string, page types.Pagination) (*types.OnChainLogPage, error) {
+	chainmakerCfg := c.cfg.ChainSpecific.(*ChainMakerConfig)
+	if chainmakerCfg.ListLogsByOrgMethodName == "" {
+		return nil, fmt.Errorf("listing logs by org: %w", types.ErrNotSupported)
+	}
+	if orgID == "" {
+		return nil, fmt.Errorf("org ID cannot be empty")
+	}
+	if page.Limit <= 0 {
+		return nil, fmt.Errorf("page limit must be positive")
+	}
+
+	kvs := []*common.KeyValuePair{
+		{Key: chainmakerCfg.ParamKeySenderOrgID, Value: []byte(orgID)},
+		{Key: "cursor", Value: []byte(page.Cursor)},
+		{Key: "limit", Value: []byte(strconv.Itoa(page.Limit))},
+	}
+	resp, err := c.chainClient().QueryContract(chainmakerCfg.ContractName, chainmakerCfg.ListLogsByOrgMethodName, kvs, -1)
+	if err != nil {
+		return nil, sdkError("SDK query failed", err)
+	}
+	if resp.Code != common.TxStatusCode_SUCCESS {
+		return nil, txError("contract query failed", resp.Code, resp.Message)
+	}
+
+	var result onChainLogPage
+	if err := json.Unmarshal(resp.ContractResult.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s result: %w", chainmakerCfg.ListLogsByOrgMethodName, err)
+	}
+	out := &types.OnChainLogPage{
+		Logs:       make([]types.AuditData, len(result.Logs)),
+		NextCursor: result.NextCursor,
+	}
+	for i, l := range result.Logs {
+		out.Logs[i] = types.AuditData{
+			LogHash:         l.LogHash,
+			SubmitterOrgID:  l.SenderOrgID,
+			Timestamp:       l.Timestamp,
+			ClientTimestamp: l.ClientTimestamp,
 		}
 	}
-	return nil, fmt.Errorf("event '%s' not found in transaction %s", c.cfg.ChainSpecific.(*ChainMakerConfig).SubmitEventTopic, txHash)
+	return out, nil
 }
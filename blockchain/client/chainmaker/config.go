@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -33,6 +34,10 @@ type ChainMakerConfig struct {
 
 	Nodes []NodeConfig `yaml:"nodes"`
 
+	// CredentialReloadInterval is how often the key, cert and CA files are checked
+	// for rotation; the SDK client is rebuilt when they change. 0 disables reloading.
+	CredentialReloadInterval time.Duration `yaml:"credential_reload_interval"`
+
 	// --- Business Logic Required ---
 	ContractName              string `yaml:"contract_name"`
 	SubmitLogMethodName       string `yaml:"submit_log_method_name"`
@@ -44,6 +49,30 @@ type ChainMakerConfig struct {
 	SubmitEventTopic          string `yaml:"submit_event_topic"`
 	SubmitLogsBatchMethodName string `yaml:"submit_logs_batch_method_name"`
 	ParamKeyLogsJson          string `yaml:"param_key_logs_json"`
+
+	// --- Optional Contract Methods ---
+	// ListLogsByOrgMethodName enumerates an org's attestations (ListOnChainLogs);
+	// leave empty if the deployed contract does not implement it
+	ListLogsByOrgMethodName string `yaml:"list_logs_by_org_method_name"`
+
+	// --- Contract Version ---
+	// ContractVersion selects the event schemas used to decode contract events. If not set it is
+	// read from ContractMetadataPath, else DefaultContractVersion is assumed.
+	ContractVersion string `yaml:"contract_version"`
+	// ContractMetadataPath is the deployment record written by cmd/contract
+	ContractMetadataPath string `yaml:"contract_metadata_path"`
+	// EventSchemas adds or overrides event schemas: contract version -> event topic -> field names by position
+	EventSchemas map[string]map[string][]string `yaml:"event_schemas"`
+
+	// --- Contract Management (cmd/contract) ---
+	// AdminEndorsers sign contract deploys and upgrades; the client's own signing identity if empty
+	AdminEndorsers []EndorserConfig `yaml:"admin_endorsers"`
+}
+
+// EndorserConfig is an admin identity that endorses contract management transactions
+type EndorserConfig struct {
+	KeyPath  string `yaml:"key_path"`
+	CertPath string `yaml:"cert_path"`
 }
 
 // LoadChainMakerConfig loads ChainMaker configuration from the specified YAML file path
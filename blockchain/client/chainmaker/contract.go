@@ -0,0 +1,93 @@
+package chainmaker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"tlng/blockchain/types"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+	sdkutils "chainmaker.org/chainmaker/sdk-go/v2/utils"
+)
+
+// ExpectedContractVersion returns the contract version the client decodes events for
+func (c *Client) ExpectedContractVersion() string {
+	return c.version
+}
+
+// ContractInfo returns the deployed attestation contract
+func (c *Client) ContractInfo(ctx context.Context) (*types.ContractInfo, error) {
+	name := c.cfg.ChainSpecific.(*ChainMakerConfig).ContractName
+	info, err := c.chainClient().GetContractInfo(name)
+	if err != nil {
+		return nil, fmt.Errorf("SDK get contract info failed: %w", err)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("contract '%s' not found", name)
+	}
+	return &types.ContractInfo{Name: info.Name, Version: info.Version, Runtime: info.RuntimeType.String()}, nil
+}
+
+// DeployContract installs the attestation contract
+func (c *Client) DeployContract(ctx context.Context, spec types.ContractSpec) (*types.ContractDeployment, error) {
+	return c.manageContract(spec, c.chainClient().CreateContractCreatePayload)
+}
+
+// UpgradeContract replaces the deployed attestation contract with a new version
+func (c *Client) UpgradeContract(ctx context.Context, spec types.ContractSpec) (*types.ContractDeployment, error) {
+	return c.manageContract(spec, c.chainClient().CreateContractUpgradePayload)
+}
+
+// contractPayloadFunc builds a contract create or upgrade payload
+type contractPayloadFunc func(contractName, version, byteCodeStringOrFilePath string, runtime common.RuntimeType, kvs []*common.KeyValuePair) (*common.Payload, error)
+
+// manageContract sends a contract create or upgrade transaction endorsed by the admin endorsers
+func (c *Client) manageContract(spec types.ContractSpec, newPayload contractPayloadFunc) (*types.ContractDeployment, error) {
+	cmCfg := c.cfg.ChainSpecific.(*ChainMakerConfig)
+	runtime, ok := common.RuntimeType_value[strings.ToUpper(spec.Runtime)]
+	if !ok {
+		return nil, fmt.Errorf("unknown ChainMaker runtime '%s'", spec.Runtime)
+	}
+	if spec.Version == "" || spec.BytecodePath == "" {
+		return nil, fmt.Errorf("contract version and bytecode path are required")
+	}
+
+	payload, err := newPayload(cmCfg.ContractName, spec.Version, spec.BytecodePath, common.RuntimeType(runtime), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create contract payload: %w", err)
+	}
+
+	var endorsers []*common.EndorsementEntry
+	for _, e := range cmCfg.AdminEndorsers {
+		entry, err := sdkutils.MakeEndorserWithPath(e.KeyPath, e.CertPath, payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to endorse with '%s': %w", e.CertPath, err)
+		}
+		endorsers = append(endorsers, entry)
+	}
+	if len(endorsers) == 0 {
+		entry, err := c.chainClient().SignContractManagePayload(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign contract payload: %w", err)
+		}
+		endorsers = append(endorsers, entry)
+	}
+
+	resp, err := c.chainClient().SendContractManageRequest(payload, endorsers, int64(c.cfg.TimeoutSeconds), true)
+	if err != nil {
+		return nil, fmt.Errorf("SDK contract manage request failed: %w", err)
+	}
+	if resp.Code != common.TxStatusCode_SUCCESS {
+		return nil, fmt.Errorf("contract manage transaction failed: %s (code: %d)", resp.Message, resp.Code)
+	}
+	if resp.ContractResult != nil && resp.ContractResult.Code != 0 {
+		return nil, fmt.Errorf("contract manage transaction failed: %s (tx: %s)", resp.ContractResult.Message, resp.TxId)
+	}
+
+	return &types.ContractDeployment{
+		ContractInfo:  types.ContractInfo{Name: cmCfg.ContractName, Version: spec.Version, Runtime: strings.ToUpper(spec.Runtime)},
+		TransactionID: resp.TxId,
+		BlockHeight:   resp.TxBlockHeight,
+	}, nil
+}
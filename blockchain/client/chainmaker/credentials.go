@@ -0,0 +1,130 @@
+package chainmaker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	sdk "chainmaker.org/chainmaker/sdk-go/v2"
+)
+
+// retiredClientGrace is how long a replaced SDK client is kept open so that
+// calls started on it can finish
+const retiredClientGrace = time.Minute
+
+// chainClient returns the current SDK client
+func (c *Client) chainClient() *sdk.ChainClient {
+	return c.sdkClient.Load()
+}
+
+// credentialFiles lists the files the SDK client reads its credentials from
+func credentialFiles(cfg *ChainMakerConfig) []string {
+	files := []string{cfg.UserKeyPath, cfg.UserCertPath, cfg.UserSignKeyPath, cfg.UserSignCertPath}
+	for _, node := range cfg.Nodes {
+		files = append(files, node.CaPaths...)
+	}
+	return files
+}
+
+// credentialsDigest hashes the contents of the credential files, and of the
+// files in credential directories (CA paths), so a rotation is detected however
+// the files are replaced (rename, symlink swap as in mounted secrets, rewrite)
+func credentialsDigest(paths []string) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		files := []string{path}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ChainMaker credential file '%s': %w", path, err)
+		}
+		if info.IsDir() {
+			if files, err = credentialDirFiles(path); err != nil {
+				return "", err
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return "", fmt.Errorf("failed to read ChainMaker credential file '%s': %w", file, err)
+			}
+			sum := sha256.Sum256(data)
+			h.Write([]byte(file))
+			h.Write(sum[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// credentialDirFiles lists the files directly in dir, in name order
+func credentialDirFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ChainMaker credential directory '%s': %w", dir, err)
+	}
+	var files []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// watchCredentials checks the credential files every interval and rebuilds the
+// SDK client when they rotate. A change is only applied once the files are
+// unchanged for a whole interval, so a key and its cert written one after the
+// other are not loaded half-rotated. If the new client cannot be built, the
+// current one is kept and the rebuild is retried at the next check.
+func (c *Client) watchCredentials(ctx context.Context, interval time.Duration) {
+	defer close(c.credWatchDone)
+	chainmakerCfg := c.cfg.ChainSpecific.(*ChainMakerConfig)
+	files := credentialFiles(chainmakerCfg)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var pending string // Digest seen at the previous check that differs from the loaded one
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		digest, err := credentialsDigest(files)
+		if err != nil {
+			c.logger.Printf("Warning: failed to check ChainMaker credentials for rotation: %v", err)
+			continue
+		}
+		if digest == c.credentials {
+			pending = ""
+			continue
+		}
+		if digest != pending {
+			pending = digest
+			c.logger.Println("ChainMaker credential files changed; reloading once they are stable")
+			continue
+		}
+
+		client, err := newSDKClient(c.cfg, chainmakerCfg, c.logger)
+		if err != nil {
+			c.logger.Printf("Warning: failed to reload rotated ChainMaker credentials, keeping the current client: %v", err)
+			continue
+		}
+		old := c.sdkClient.Swap(client)
+		c.credentials, pending = digest, ""
+		c.logger.Println("ChainMaker SDK client rebuilt with the rotated credentials.")
+		time.AfterFunc(retiredClientGrace, func() {
+			if err := old.Stop(); err != nil {
+				c.logger.Printf("Warning: failed to stop the replaced ChainMaker SDK client: %v", err)
+			}
+		})
+	}
+}
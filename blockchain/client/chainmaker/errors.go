@@ -0,0 +1,41 @@
+package chainmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"tlng/blockchain/types"
+
+	"chainmaker.org/chainmaker/pb-go/v2/common"
+)
+
+// sdkError classifies an error returned by an SDK call. The SDK fails this way
+// when it gets no response from any node, so the chain counts as unavailable
+// unless the call timed out.
+func sdkError(op string, err error) error {
+	kind := types.ErrChainUnavailable
+	var timeout interface{ Timeout() bool }
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &timeout) && timeout.Timeout()) ||
+		strings.Contains(strings.ToLower(err.Error()), "timeout") { // The SDK reports its own timeouts as plain errors
+		kind = types.ErrTimeout
+	}
+	return types.NewChainError(kind, 0, fmt.Errorf("%s: %w", op, err))
+}
+
+// txError classifies a transaction that did not succeed by its status code
+func txError(op string, code common.TxStatusCode, message string) error {
+	var kind error
+	switch code {
+	case common.TxStatusCode_TIMEOUT:
+		kind = types.ErrTimeout
+	case common.TxStatusCode_CONTRACT_FAIL:
+		kind = types.ErrContractValidation
+	case common.TxStatusCode_INTERNAL_ERROR:
+		kind = types.ErrChainUnavailable
+	default: // Invalid parameters, no permission, and the contract management codes
+		kind = types.ErrTxRejected
+	}
+	return types.NewChainError(kind, int(code), fmt.Errorf("%s: %s (code: %d)", op, message, code))
+}
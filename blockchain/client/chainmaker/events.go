@@ -0,0 +1,124 @@
+package chainmaker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"tlng/blockchain/contract"
+	"tlng/blockchain/types"
+)
+
+// Event data fields the event parsers understand. A schema may name a
+// position EventFieldIgnored to skip a field the engine does not use.
+const (
+	EventFieldLogHash         = "log_hash"
+	EventFieldSenderOrgID     = "sender_org_id"
+	EventFieldTimestamp       = "timestamp"
+	EventFieldClientTimestamp = "client_timestamp"
+	EventFieldIgnored         = "-"
+)
+
+// DefaultContractVersion is the log store contract version assumed when contract_version is not set
+const DefaultContractVersion = "v3"
+
+// builtinEventSchemas names the event data fields by position, per contract
+// version and event topic (see blockchain/contracts.md). event_schemas in the
+// ChainMaker config adds versions or overrides topics.
+var builtinEventSchemas = map[string]map[string][]string{
+	// submit_log only
+	"v2": {
+		"log_submitted": {EventFieldLogHash, EventFieldSenderOrgID, EventFieldTimestamp},
+	},
+	// submit_logs_batch appends the client timestamp; submit_log still emits 3 fields
+	"v3": {
+		"log_submitted": {EventFieldLogHash, EventFieldSenderOrgID, EventFieldTimestamp, EventFieldClientTimestamp},
+	},
+}
+
+// eventParser decodes the data of one event topic. Decoding is forward
+// compatible: fields beyond the schema are ignored and fields the event does
+// not carry are left empty. Only the log hash is required.
+type eventParser struct {
+	topic   string
+	version string
+	fields  []string // Field name by position
+}
+
+// parse decodes an event's data
+func (p *eventParser) parse(data []string) (types.AuditData, error) {
+	var audit types.AuditData
+	for i, name := range p.fields {
+		if i >= len(data) {
+			break
+		}
+		switch name {
+		case EventFieldLogHash:
+			audit.LogHash = data[i]
+		case EventFieldSenderOrgID:
+			audit.SubmitterOrgID = data[i]
+		case EventFieldTimestamp:
+			audit.Timestamp = data[i]
+		case EventFieldClientTimestamp:
+			audit.ClientTimestamp = data[i]
+		}
+	}
+	if audit.LogHash == "" {
+		return types.AuditData{}, fmt.Errorf("malformed '%s' event (contract %s): no log hash in %d fields", p.topic, p.version, len(data))
+	}
+	return audit, nil
+}
+
+// resolveContractVersion returns the configured contract version, else the one
+// recorded in the contract metadata, else DefaultContractVersion
+func resolveContractVersion(cfg *ChainMakerConfig) (string, error) {
+	if cfg.ContractVersion != "" {
+		return cfg.ContractVersion, nil
+	}
+	if cfg.ContractMetadataPath != "" {
+		m, err := contract.Load(cfg.ContractMetadataPath)
+		switch {
+		case err == nil && m.Current() != nil:
+			return m.Current().Version, nil
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return "", err
+		}
+	}
+	return DefaultContractVersion, nil
+}
+
+// newEventParsers returns the event parsers for a contract version, by topic
+func newEventParsers(cfg *ChainMakerConfig, version string) (map[string]*eventParser, error) {
+	schemas := make(map[string][]string)
+	for topic, fields := range builtinEventSchemas[version] {
+		schemas[topic] = fields
+	}
+	for topic, fields := range cfg.EventSchemas[version] {
+		schemas[topic] = fields
+	}
+	if len(schemas) == 0 {
+		return nil, fmt.Errorf("no event schemas for contract version '%s'; add them under event_schemas", version)
+	}
+	if _, ok := schemas[cfg.SubmitEventTopic]; !ok {
+		return nil, fmt.Errorf("no event schema for submit event topic '%s' in contract version '%s'", cfg.SubmitEventTopic, version)
+	}
+
+	parsers := make(map[string]*eventParser, len(schemas))
+	for topic, fields := range schemas {
+		hasHash := false
+		for _, name := range fields {
+			switch name {
+			case EventFieldLogHash:
+				hasHash = true
+			case EventFieldSenderOrgID, EventFieldTimestamp, EventFieldClientTimestamp, EventFieldIgnored:
+			default:
+				return nil, fmt.Errorf("event schema for '%s' (contract %s): unknown field '%s'", topic, version, name)
+			}
+		}
+		if !hasHash {
+			return nil, fmt.Errorf("event schema for '%s' (contract %s): missing field '%s'", topic, version, EventFieldLogHash)
+		}
+		parsers[topic] = &eventParser{topic: topic, version: version, fields: fields}
+	}
+	return parsers, nil
+}
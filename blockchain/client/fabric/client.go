@@ -0,0 +1,391 @@
+package fabric
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"tlng/blockchain/types"
+	"tlng/config"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/hash"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Client is the wrapper around the Fabric Gateway SDK client
+type Client struct {
+	conn     *grpc.ClientConn
+	gateway  *client.Gateway
+	contract *client.Contract // The log chaincode
+	qscc     *client.Contract // The query system chaincode, for transactions and blocks
+	cfg      *config.BlockchainConfig
+	logger   *log.Logger
+}
+
+// NewFabricClient connects to the Fabric Gateway with the combined configuration
+func NewFabricClient(cfg *config.BlockchainConfig, logger *log.Logger) (*Client, error) {
+	logger.Println("Initializing Fabric Gateway client...")
+
+	fabricCfg, ok := cfg.ChainSpecific.(*FabricConfig)
+	if !ok {
+		return nil, fmt.Errorf("invalid Fabric configuration type")
+	}
+
+	id, sign, err := newIdentity(fabricCfg)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := newGrpcConnection(fabricCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []client.ConnectOption{
+		client.WithSign(sign),
+		client.WithHash(hash.SHA256),
+		client.WithClientConnection(conn),
+	}
+	if fabricCfg.EvaluateTimeout > 0 {
+		options = append(options, client.WithEvaluateTimeout(fabricCfg.EvaluateTimeout))
+	}
+	if fabricCfg.EndorseTimeout > 0 {
+		options = append(options, client.WithEndorseTimeout(fabricCfg.EndorseTimeout))
+	}
+	if fabricCfg.SubmitTimeout > 0 {
+		options = append(options, client.WithSubmitTimeout(fabricCfg.SubmitTimeout))
+	}
+	if fabricCfg.CommitStatusTimeout > 0 {
+		options = append(options, client.WithCommitStatusTimeout(fabricCfg.CommitStatusTimeout))
+	}
+	gw, err := client.Connect(id, options...)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to connect to Fabric Gateway at %s: %w", fabricCfg.PeerEndpoint, err)
+	}
+
+	network := gw.GetNetwork(fabricCfg.ChannelName)
+	logger.Printf("Fabric Gateway client initialized successfully (channel: %s, chaincode: %s).", fabricCfg.ChannelName, fabricCfg.ChaincodeName)
+	return &Client{
+		conn:     conn,
+		gateway:  gw,
+		contract: network.GetContract(fabricCfg.ChaincodeName),
+		qscc:     network.GetContract("qscc"),
+		cfg:      cfg,
+		logger:   logger,
+	}, nil
+}
+
+// newIdentity reads the client identity and its signing key
+func newIdentity(fabricCfg *FabricConfig) (*identity.X509Identity, identity.Sign, error) {
+	certPEM, err := os.ReadFile(fabricCfg.UserCertPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read user certificate: %w", err)
+	}
+	cert, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse user certificate: %w", err)
+	}
+	id, err := identity.NewX509Identity(fabricCfg.MSPID, cert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(fabricCfg.UserKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read user private key: %w", err)
+	}
+	key, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse user private key: %w", err)
+	}
+	sign, err := identity.NewPrivateKeySign(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+	return id, sign, nil
+}
+
+// newGrpcConnection opens the TLS connection to the gateway peer
+func newGrpcConnection(fabricCfg *FabricConfig) (*grpc.ClientConn, error) {
+	caPEM, err := os.ReadFile(fabricCfg.TLSCACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS CA certificate: %w", err)
+	}
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in TLS CA certificate file '%s'", fabricCfg.TLSCACertPath)
+	}
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, fabricCfg.TLSHostOverride)
+
+	conn, err := grpc.NewClient(fabricCfg.PeerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC connection to %s: %w", fabricCfg.PeerEndpoint, err)
+	}
+	return conn, nil
+}
+
+// NewFabricClientFromFile initializes the Fabric Gateway client directly from a configuration file path
+func NewFabricClientFromFile(configPath string, logger *log.Logger) (*Client, error) {
+	fabricCfg, err := LoadFabricConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Fabric config from file '%s': %w", configPath, err)
+	}
+
+	// Create a wrapper blockchain config
+	blockchainCfg := &config.BlockchainConfig{
+		BlockchainType: "hyperledger_fabric",
+		ChainSpecific:  fabricCfg,
+		// Use defaults for common settings
+		RetryLimit:     20,
+		RetryInterval:  500,
+		TimeoutSeconds: 15,
+	}
+
+	return NewFabricClient(blockchainCfg, logger)
+}
+
+// SingleCallThreshold returns the batch size below which logs are anchored
+// with SubmitLog calls, 0 to always use SubmitLogsBatch
+func (c *Client) SingleCallThreshold() int {
+	if c.cfg == nil {
+		return 0
+	}
+	return c.cfg.SingleCallThreshold
+}
+
+// Config returns the configuration associated with the client.
+func (c *Client) Config() any {
+	if c.cfg == nil || c.cfg.ChainSpecific == nil {
+		log.Println("Warning: Accessing client config before initialization.")
+		return &FabricConfig{} // Return empty config to avoid nil pointer panic
+	}
+	return c.cfg.ChainSpecific
+}
+
+// fabricConfig returns the Fabric-specific configuration
+func (c *Client) fabricConfig() *FabricConfig {
+	return c.cfg.ChainSpecific.(*FabricConfig)
+}
+
+// Close closes the gateway and its gRPC connection
+func (c *Client) Close() error {
+	c.logger.Println("Closing Fabric Gateway client...")
+	c.gateway.Close()
+	if err := c.conn.Close(); err != nil {
+		c.logger.Printf("Error closing Fabric gRPC connection: %v", err)
+		return fmt.Errorf("failed to close Fabric gRPC connection: %w", err)
+	}
+	return nil
+}
+
+// withTimeout bounds a call by timeout_seconds of the blockchain config
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.cfg.TimeoutSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(c.cfg.TimeoutSeconds)*time.Second)
+}
+
+// submit endorses a chaincode transaction, submits it to the orderer and waits
+// for it to commit, returning its result, ID and block number
+func (c *Client) submit(ctx context.Context, method string, args ...string) ([]byte, string, uint64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	proposal, err := c.contract.NewProposal(method, client.WithArguments(args...))
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("failed to create proposal for '%s': %w", method, err)
+	}
+	transaction, err := proposal.EndorseWithContext(ctx)
+	if err != nil {
+		return nil, "", 0, gatewayError("endorsement failed", err)
+	}
+	commit, err := transaction.SubmitWithContext(ctx)
+	if err != nil {
+		return nil, "", 0, gatewayError("submission failed", err)
+	}
+	status, err := commit.StatusWithContext(ctx)
+	if err != nil {
+		return nil, "", 0, gatewayError("commit status failed", err)
+	}
+	if !status.Successful {
+		return nil, "", 0, commitError("transaction failed to commit", status.TransactionID, status.Code)
+	}
+	return transaction.Result(), status.TransactionID, status.BlockNumber, nil
+}
+
+// evaluate runs a chaincode query on the gateway peer
+func (c *Client) evaluate(ctx context.Context, contract *client.Contract, method string, args ...string) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	result, err := contract.EvaluateWithContext(ctx, method, client.WithArguments(args...))
+	if err != nil {
+		return nil, gatewayError("evaluation failed", err)
+	}
+	return result, nil
+}
+
+// SubmitLogsBatch submits a batch of logs in a single transaction
+func (c *Client) SubmitLogsBatch(ctx context.Context, entries []types.LogEntry) (*types.BatchProof, []types.LogStatusInfo, error) {
+	if len(entries) == 0 {
+		return nil, nil, fmt.Errorf("log entry batch cannot be empty")
+	}
+
+	// Canonical encoding, so anyone holding the entries can recompute the payload
+	logsJsonBytes, batchProof, err := types.EncodeBatchPayload(entries, c.cfg.BatchCompression.Algorithm, c.cfg.BatchCompression.MinBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal log entries to JSON: %w", err)
+	}
+	args := []string{string(logsJsonBytes)}
+	if batchProof.Encoding != "" {
+		args = append(args, batchProof.Encoding) // The chaincode decompresses the payload when given its encoding
+	}
+
+	result, txID, blockNumber, err := c.submit(ctx, c.fabricConfig().SubmitLogsBatchMethodName, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(result) == 0 {
+		return nil, nil, fmt.Errorf("chaincode batch execution returned empty result bytes (tx: %s)", txID)
+	}
+
+	var results []types.LogStatusInfo
+	if err := json.Unmarshal(result, &results); err != nil {
+		c.logger.Printf("Failed to unmarshal batch results JSON (TxID: %s). Raw result: %s", txID, string(result))
+		return nil, nil, fmt.Errorf("failed to unmarshal chaincode batch results: %w", err)
+	}
+	batchProof.TransactionID = txID
+	batchProof.BlockHeight = blockNumber
+	return batchProof, results, nil
+}
+
+// SubmitLog submits a single log entry
+func (c *Client) SubmitLog(ctx context.Context, logHash, logContent, senderOrgID, timestamp string) (*types.Proof, error) {
+	result, txID, blockNumber, err := c.submit(ctx, c.fabricConfig().SubmitLogMethodName, logHash, logContent, senderOrgID, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	returnedHash := string(result)
+	if returnedHash != logHash {
+		return nil, fmt.Errorf("chaincode returned hash '%s' does not match sent hash '%s'", returnedHash, logHash)
+	}
+	return &types.Proof{TransactionID: txID, BlockHeight: blockNumber, LogHash: returnedHash}, nil
+}
+
+// FindLogByHash queries the chaincode for a log record by its hash
+func (c *Client) FindLogByHash(ctx context.Context, logHash string) (string, error) {
+	result, err := c.evaluate(ctx, c.contract, c.fabricConfig().FindLogByHashMethodName, logHash)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// GetLogByTxHash performs the "on-chain public audit" by reading the
+// transaction's block through qscc. The submit event carries one entry for a
+// single submit and all stored entries for a batch. Every entry carries the
+// anchoring block and its current confirmation count.
+func (c *Client) GetLogByTxHash(ctx context.Context, txHash string) ([]types.AuditData, error) {
+	if txHash == "" {
+		return nil, fmt.Errorf("transaction hash cannot be empty")
+	}
+	fabricCfg := c.fabricConfig()
+	blockBytes, err := c.evaluate(ctx, c.qscc, "GetBlockByTxID", fabricCfg.ChannelName, txHash)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := findTransaction(blockBytes, txHash)
+	if err != nil {
+		return nil, err
+	}
+	if !tx.valid {
+		return nil, fmt.Errorf("transaction %s is invalid: %s", txHash, tx.validationCode)
+	}
+
+	var entries []types.AuditData
+	for _, event := range tx.events {
+		if event.GetEventName() != fabricCfg.SubmitEventName {
+			continue
+		}
+		parsed, err := parseSubmitEvent(event.GetPayload())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s: %w", txHash, err)
+		}
+		entries = append(entries, parsed...)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("event '%s' not found in transaction %s", fabricCfg.SubmitEventName, txHash)
+	}
+
+	// The chain head only affects the confirmation count, so failing to read it
+	// does not fail the audit
+	var confirmations uint64
+	height, err := c.chainHeight(ctx)
+	if err != nil {
+		c.logger.Printf("Warning: failed to read chain height for confirmations of tx %s: %v", txHash, err)
+	} else if height > tx.blockNumber {
+		confirmations = height - tx.blockNumber
+	}
+	for i := range entries {
+		entries[i].BlockHeight = tx.blockNumber
+		entries[i].BlockHash = tx.blockHash
+		entries[i].BlockTimestamp = tx.timestamp
+		entries[i].Confirmations = confirmations
+	}
+	return entries, nil
+}
+
+// chainHeight returns the number of blocks on the channel
+func (c *Client) chainHeight(ctx context.Context) (uint64, error) {
+	infoBytes, err := c.evaluate(ctx, c.qscc, "GetChainInfo", c.fabricConfig().ChannelName)
+	if err != nil {
+		return 0, err
+	}
+	return decodeChainHeight(infoBytes)
+}
+
+// onChainLogPage is the JSON result of the chaincode's list-by-org function
+type onChainLogPage struct {
+	Logs       []submitEvent `json:"logs"`
+	NextCursor string        `json:"next_cursor"`
+}
+
+// ListOnChainLogs enumerates an org's attestations through the chaincode's
+// list-by-org function (list_logs_by_org_method_name), a page at a time
+func (c *Client) ListOnChainLogs(ctx context.Context, orgID string, page types.Pagination) (*types.OnChainLogPage, error) {
+	fabricCfg := c.fabricConfig()
+	if fabricCfg.ListLogsByOrgMethodName == "" {
+		return nil, fmt.Errorf("listing logs by org: %w", types.ErrNotSupported)
+	}
+	if orgID == "" {
+		return nil, fmt.Errorf("org ID cannot be empty")
+	}
+	if page.Limit <= 0 {
+		return nil, fmt.Errorf("page limit must be positive")
+	}
+
+	result, err := c.evaluate(ctx, c.contract, fabricCfg.ListLogsByOrgMethodName, orgID, page.Cursor, strconv.Itoa(page.Limit))
+	if err != nil {
+		return nil, err
+	}
+	var parsed onChainLogPage
+	if err := json.Unmarshal(result, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse %s result: %w", fabricCfg.ListLogsByOrgMethodName, err)
+	}
+	out := &types.OnChainLogPage{
+		Logs:       make([]types.AuditData, len(parsed.Logs)),
+		NextCursor: parsed.NextCursor,
+	}
+	for i, l := range parsed.Logs {
+		out.Logs[i] = l.auditData()
+	}
+	return out, nil
+}
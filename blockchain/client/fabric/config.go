@@ -0,0 +1,100 @@
+package fabric
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FabricConfig stores Hyperledger Fabric-specific configuration
+type FabricConfig struct {
+	// --- Gateway Connection Required ---
+	PeerEndpoint string `yaml:"peer_endpoint"` // host:port of the peer running the Fabric Gateway service
+	ChannelName  string `yaml:"channel_name"`
+	MSPID        string `yaml:"msp_id"`
+
+	// TLS connection to the gateway peer
+	TLSCACertPath   string `yaml:"tls_ca_cert_path"`  // CA certificate the peer's TLS certificate is checked against
+	TLSHostOverride string `yaml:"tls_host_override"` // Host name expected in the peer's TLS certificate, if not the endpoint's
+
+	// Transaction signing identity
+	UserCertPath string `yaml:"user_cert_path"`
+	UserKeyPath  string `yaml:"user_key_path"`
+
+	// Gateway call timeouts; 0 leaves the SDK defaults
+	EvaluateTimeout     time.Duration `yaml:"evaluate_timeout"`
+	EndorseTimeout      time.Duration `yaml:"endorse_timeout"`
+	SubmitTimeout       time.Duration `yaml:"submit_timeout"`
+	CommitStatusTimeout time.Duration `yaml:"commit_status_timeout"`
+
+	// --- Business Logic Required ---
+	// Chaincode functions take positional string arguments:
+	//   submit_log(log_hash, log_content, sender_org_id, timestamp) returns the log hash
+	//   submit_logs_batch(logs_json) returns the per-entry results as JSON
+	//   find_log_by_hash(log_hash) returns the stored record
+	ChaincodeName             string `yaml:"chaincode_name"`
+	SubmitLogMethodName       string `yaml:"submit_log_method_name"`
+	SubmitLogsBatchMethodName string `yaml:"submit_logs_batch_method_name"`
+	FindLogByHashMethodName   string `yaml:"find_log_by_hash_method_name"`
+	// SubmitEventName is the chaincode event carrying the stored entries. Fabric
+	// keeps one event per transaction, so its payload is a JSON object for a
+	// single submit and an array of them for a batch.
+	SubmitEventName string `yaml:"submit_event_name"`
+
+	// --- Optional Chaincode Functions ---
+	// ListLogsByOrgMethodName(sender_org_id, cursor, limit) enumerates an org's
+	// attestations (ListOnChainLogs); leave empty if the chaincode does not implement it
+	ListLogsByOrgMethodName string `yaml:"list_logs_by_org_method_name"`
+}
+
+// LoadFabricConfig loads Fabric configuration from the specified YAML file path
+func LoadFabricConfig(path string) (*FabricConfig, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get absolute path of Fabric config file: %w", err)
+	}
+
+	fmt.Printf("Loading Fabric configuration from '%s'...\n", absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Fabric config file '%s': %w", absPath, err)
+	}
+
+	var cfg FabricConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse Fabric YAML config file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("fabric configuration error: %w", err)
+	}
+
+	fmt.Println("Fabric configuration loaded successfully.")
+	return &cfg, nil
+}
+
+// Validate checks that the connection and chaincode settings are present
+func (c *FabricConfig) Validate() error {
+	required := []struct{ name, value string }{
+		{"peer_endpoint", c.PeerEndpoint},
+		{"channel_name", c.ChannelName},
+		{"msp_id", c.MSPID},
+		{"tls_ca_cert_path", c.TLSCACertPath},
+		{"user_cert_path", c.UserCertPath},
+		{"user_key_path", c.UserKeyPath},
+		{"chaincode_name", c.ChaincodeName},
+		{"submit_log_method_name", c.SubmitLogMethodName},
+		{"submit_logs_batch_method_name", c.SubmitLogsBatchMethodName},
+		{"find_log_by_hash_method_name", c.FindLogByHashMethodName},
+		{"submit_event_name", c.SubmitEventName},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return fmt.Errorf("%s is required", r.name)
+		}
+	}
+	return nil
+}
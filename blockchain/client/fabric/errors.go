@@ -0,0 +1,46 @@
+package fabric
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tlng/blockchain/types"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gatewayError classifies an error returned by a Fabric Gateway call by its
+// gRPC status. The gateway reports a chaincode that returned an error as
+// Aborted, and peers or orderers it could not reach as Unavailable.
+func gatewayError(op string, err error) error {
+	code := status.Code(err)
+	var kind error
+	switch {
+	case code == codes.DeadlineExceeded || errors.Is(err, context.DeadlineExceeded):
+		kind = types.ErrTimeout
+	case code == codes.Unavailable || code == codes.ResourceExhausted || code == codes.Internal || code == codes.Unknown:
+		kind = types.ErrChainUnavailable
+	case code == codes.Aborted:
+		kind = types.ErrContractValidation
+	default: // Invalid arguments, no permission, unknown channel or chaincode
+		kind = types.ErrTxRejected
+	}
+	return types.NewChainError(kind, int(code), fmt.Errorf("%s: %w", op, err))
+}
+
+// commitError classifies a transaction the peers did not validate by its
+// validation code. Read conflicts with concurrent transactions succeed when
+// endorsed again, so they count as retryable.
+func commitError(op, txID string, code peer.TxValidationCode) error {
+	var kind error
+	switch code {
+	case peer.TxValidationCode_MVCC_READ_CONFLICT, peer.TxValidationCode_PHANTOM_READ_CONFLICT:
+		kind = types.ErrChainUnavailable
+	default: // Endorsement policy failures and malformed transactions
+		kind = types.ErrTxRejected
+	}
+	return types.NewChainError(kind, int(code), fmt.Errorf("%s: %s (tx: %s, code: %d)", op, code, txID, code))
+}
@@ -0,0 +1,188 @@
+package fabric
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"tlng/blockchain/types"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// submitEvent is a stored entry as the chaincode's submit event and
+// list-by-org function encode it
+type submitEvent struct {
+	LogHash         string `json:"log_hash"`
+	SenderOrgID     string `json:"sender_org_id"`
+	Timestamp       string `json:"timestamp"`
+	ClientTimestamp string `json:"client_timestamp,omitempty"`
+}
+
+// auditData returns the entry without its anchoring context
+func (e submitEvent) auditData() types.AuditData {
+	return types.AuditData{
+		LogHash:         e.LogHash,
+		SubmitterOrgID:  e.SenderOrgID,
+		Timestamp:       e.Timestamp,
+		ClientTimestamp: e.ClientTimestamp,
+	}
+}
+
+// parseSubmitEvent decodes a submit event payload: an object for a single
+// submit, an array of them for a batch. Only the log hash is required.
+func parseSubmitEvent(payload []byte) ([]types.AuditData, error) {
+	var events []submitEvent
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("malformed submit event: %w", err)
+		}
+	} else {
+		var event submitEvent
+		if err := json.Unmarshal(trimmed, &event); err != nil {
+			return nil, fmt.Errorf("malformed submit event: %w", err)
+		}
+		events = []submitEvent{event}
+	}
+	entries := make([]types.AuditData, len(events))
+	for i, e := range events {
+		if e.LogHash == "" {
+			return nil, fmt.Errorf("malformed submit event: no log hash in entry %d", i)
+		}
+		entries[i] = e.auditData()
+	}
+	return entries, nil
+}
+
+// blockTransaction is a transaction found in a block
+type blockTransaction struct {
+	blockNumber    uint64
+	blockHash      string // Hex-encoded
+	timestamp      time.Time
+	valid          bool
+	validationCode peer.TxValidationCode
+	events         []*peer.ChaincodeEvent
+}
+
+// findTransaction decodes the block returned by qscc GetBlockByTxID and
+// extracts the transaction txID from it
+func findTransaction(blockBytes []byte, txID string) (*blockTransaction, error) {
+	block := &common.Block{}
+	if err := proto.Unmarshal(blockBytes, block); err != nil {
+		return nil, fmt.Errorf("failed to decode block of tx %s: %w", txID, err)
+	}
+	header := block.GetHeader()
+	if header == nil {
+		return nil, fmt.Errorf("block of tx %s has no header", txID)
+	}
+	var validationCodes []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		validationCodes = metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	for i, envelopeBytes := range block.GetData().GetData() {
+		envelope := &common.Envelope{}
+		if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+			return nil, fmt.Errorf("failed to decode envelope %d of block %d: %w", i, header.GetNumber(), err)
+		}
+		payload := &common.Payload{}
+		if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+			return nil, fmt.Errorf("failed to decode payload %d of block %d: %w", i, header.GetNumber(), err)
+		}
+		channelHeader := &common.ChannelHeader{}
+		if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+			return nil, fmt.Errorf("failed to decode channel header %d of block %d: %w", i, header.GetNumber(), err)
+		}
+		if channelHeader.GetTxId() != txID {
+			continue
+		}
+
+		tx := &blockTransaction{
+			blockNumber:    header.GetNumber(),
+			blockHash:      blockHeaderHash(header),
+			timestamp:      channelHeader.GetTimestamp().AsTime(),
+			validationCode: peer.TxValidationCode_NOT_VALIDATED,
+		}
+		if i < len(validationCodes) {
+			tx.validationCode = peer.TxValidationCode(validationCodes[i])
+		}
+		tx.valid = tx.validationCode == peer.TxValidationCode_VALID
+		if channelHeader.GetType() == int32(common.HeaderType_ENDORSER_TRANSACTION) {
+			events, err := chaincodeEvents(payload.GetData())
+			if err != nil {
+				return nil, fmt.Errorf("tx %s: %w", txID, err)
+			}
+			tx.events = events
+		}
+		return tx, nil
+	}
+	return nil, fmt.Errorf("transaction %s not found in block %d", txID, header.GetNumber())
+}
+
+// chaincodeEvents returns the chaincode events of an endorser transaction
+func chaincodeEvents(data []byte) ([]*peer.ChaincodeEvent, error) {
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(data, transaction); err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	var events []*peer.ChaincodeEvent
+	for _, action := range transaction.GetActions() {
+		actionPayload := &peer.ChaincodeActionPayload{}
+		if err := proto.Unmarshal(action.GetPayload(), actionPayload); err != nil {
+			return nil, fmt.Errorf("failed to decode chaincode action payload: %w", err)
+		}
+		responsePayload := &peer.ProposalResponsePayload{}
+		if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+			return nil, fmt.Errorf("failed to decode proposal response payload: %w", err)
+		}
+		chaincodeAction := &peer.ChaincodeAction{}
+		if err := proto.Unmarshal(responsePayload.GetExtension(), chaincodeAction); err != nil {
+			return nil, fmt.Errorf("failed to decode chaincode action: %w", err)
+		}
+		if len(chaincodeAction.GetEvents()) == 0 {
+			continue
+		}
+		event := &peer.ChaincodeEvent{}
+		if err := proto.Unmarshal(chaincodeAction.GetEvents(), event); err != nil {
+			return nil, fmt.Errorf("failed to decode chaincode event: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// blockHeaderHash returns the hex-encoded hash of a block header, computed the
+// way Fabric links blocks: SHA-256 over the ASN.1 encoding of the header
+func blockHeaderHash(header *common.BlockHeader) string {
+	asn1Header := struct {
+		Number       *big.Int
+		PreviousHash []byte
+		DataHash     []byte
+	}{
+		Number:       new(big.Int).SetUint64(header.GetNumber()),
+		PreviousHash: header.GetPreviousHash(),
+		DataHash:     header.GetDataHash(),
+	}
+	encoded, err := asn1.Marshal(asn1Header)
+	if err != nil {
+		return "" // Unreachable: the header only holds an integer and byte strings
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// decodeChainHeight decodes the result of qscc GetChainInfo
+func decodeChainHeight(infoBytes []byte) (uint64, error) {
+	info := &common.BlockchainInfo{}
+	if err := proto.Unmarshal(infoBytes, info); err != nil {
+		return 0, fmt.Errorf("failed to decode chain info: %w", err)
+	}
+	return info.GetHeight(), nil
+}
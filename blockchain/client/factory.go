@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"tlng/blockchain/client/chainmaker"
+	"tlng/blockchain/client/fabric"
 	"tlng/config"
 )
 
@@ -13,10 +14,10 @@ import (
 type BlockchainType string
 
 const (
-	ChainMaker BlockchainType = "chainmaker"
+	ChainMaker        BlockchainType = "chainmaker"
+	HyperledgerFabric BlockchainType = "hyperledger_fabric"
 	// Future blockchain types can be added here:
 	// Ethereum   BlockchainType = "ethereum"
-	// HyperledgerFabric BlockchainType = "hyperledger_fabric"
 )
 
 // LoadChainSpecificConfig loads chain-specific configuration based on blockchain type
@@ -25,6 +26,9 @@ func LoadChainSpecificConfig(blockchainType string, configDir string) (any, erro
 	case ChainMaker:
 		chainmakerConfigPath := filepath.Join(configDir, "clients", "chainmaker.yml")
 		return chainmaker.LoadChainMakerConfig(chainmakerConfigPath)
+	case HyperledgerFabric:
+		fabricConfigPath := filepath.Join(configDir, "clients", "fabric.yml")
+		return fabric.LoadFabricConfig(fabricConfigPath)
 	case "":
 		// Default to ChainMaker if not specified
 		chainmakerConfigPath := filepath.Join(configDir, "clients", "chainmaker.yml")
@@ -39,6 +43,8 @@ func NewBlockchainClient(cfg *config.BlockchainConfig, logger *log.Logger) (Bloc
 	switch BlockchainType(cfg.BlockchainType) {
 	case ChainMaker:
 		return chainmaker.NewChainMakerClient(cfg, logger)
+	case HyperledgerFabric:
+		return fabric.NewFabricClient(cfg, logger)
 	case "":
 		// Default to ChainMaker if not specified
 		return chainmaker.NewChainMakerClient(cfg, logger)
@@ -7,6 +7,7 @@ import (
 
 // BlockchainClient defines the generic interface for blockchain interactions
 // This interface is blockchain-agnostic and can be implemented by different blockchain clients
+// Errors from the chain are classified into the taxonomy of types.ChainError
 type BlockchainClient interface {
 	// SubmitLog submits a single log entry to the blockchain
 	SubmitLog(ctx context.Context, logHash, logContent, senderOrgID, timestamp string) (*types.Proof, error)
@@ -17,12 +18,42 @@ type BlockchainClient interface {
 	// FindLogByHash queries the blockchain for a log record by its hash
 	FindLogByHash(ctx context.Context, logHash string) (string, error)
 
-	// GetLogByTxHash performs the "on-chain public audit" by querying transaction details
-	GetLogByTxHash(ctx context.Context, txHash string) (*types.AuditData, error)
+	// GetLogByTxHash performs the "on-chain public audit" by querying transaction details.
+	// It returns every entry the transaction attested: one for a single submit, one per
+	// stored entry for a batch (entries skipped by the contract emit nothing).
+	GetLogByTxHash(ctx context.Context, txHash string) ([]types.AuditData, error)
+
+	// ListOnChainLogs enumerates the attestations an org submitted, in the
+	// contract's order, so they can be reconciled against the State DB. It
+	// returns types.ErrNotSupported if the contract cannot enumerate by org.
+	ListOnChainLogs(ctx context.Context, orgID string, page types.Pagination) (*types.OnChainLogPage, error)
 
 	// Close closes the blockchain client and releases resources
 	Close() error
 
 	// Config returns the configuration associated with the client
 	Config() any // Return any to accommodate different config types
+}
+
+// SingleCallPolicy is implemented by clients that anchor small batches with one
+// SubmitLog call per entry instead of SubmitLogsBatch
+type SingleCallPolicy interface {
+	// SingleCallThreshold returns the batch size below which SubmitLog is used, 0 to always batch
+	SingleCallThreshold() int
+}
+
+// ContractManager is implemented by clients that can deploy, upgrade and
+// inspect the attestation contract (see cmd/contract)
+type ContractManager interface {
+	// ContractInfo returns the deployed contract
+	ContractInfo(ctx context.Context) (*types.ContractInfo, error)
+
+	// ExpectedContractVersion returns the contract version the client decodes events for
+	ExpectedContractVersion() string
+
+	// DeployContract installs the contract
+	DeployContract(ctx context.Context, spec types.ContractSpec) (*types.ContractDeployment, error)
+
+	// UpgradeContract replaces the deployed contract with a new version
+	UpgradeContract(ctx context.Context, spec types.ContractSpec) (*types.ContractDeployment, error)
 }
\ No newline at end of file
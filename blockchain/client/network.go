@@ -0,0 +1,20 @@
+package blockchain
+
+import (
+	"tlng/blockchain/client/chainmaker"
+	"tlng/blockchain/client/fabric"
+	"tlng/blockchain/types"
+)
+
+// Network returns the chain type and network ID (ChainMaker chain ID or
+// Fabric channel) of a client, the chain identity recorded in evidence.
+// Both are empty for clients of other chains.
+func Network(c BlockchainClient) (chainType, networkID string) {
+	switch cfg := c.Config().(type) {
+	case *chainmaker.ChainMakerConfig:
+		return types.ChainTypeChainMaker, cfg.ChainID
+	case *fabric.FabricConfig:
+		return types.ChainTypeFabric, cfg.ChannelName
+	}
+	return "", ""
+}
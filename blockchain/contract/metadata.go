@@ -0,0 +1,85 @@
+// Package contract records which attestation contract version is deployed on a
+// chain. cmd/contract writes the record on every deploy and upgrade; blockchain
+// clients read the version from it to select their event schemas when no
+// contract version is configured.
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Metadata is the deployment record of one contract
+type Metadata struct {
+	Chain    string    `json:"chain"` // Chain the contract is deployed on, e.g. the ChainMaker chain ID
+	Name     string    `json:"name"`
+	Releases []Release `json:"releases"` // Oldest first; the last one is deployed
+}
+
+// Release is one deploy or upgrade of the contract
+type Release struct {
+	Version        string    `json:"version"`
+	Runtime        string    `json:"runtime"`
+	BytecodeSHA256 string    `json:"bytecode_sha256"`
+	TransactionID  string    `json:"transaction_id"`
+	BlockHeight    uint64    `json:"block_height"`
+	DeployedAt     time.Time `json:"deployed_at"`
+}
+
+// Current returns the deployed release, or nil if none is recorded
+func (m *Metadata) Current() *Release {
+	if len(m.Releases) == 0 {
+		return nil
+	}
+	return &m.Releases[len(m.Releases)-1]
+}
+
+// Load reads the metadata at path. A missing file yields an error matching os.ErrNotExist.
+func Load(path string) (*Metadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract metadata '%s': %w", path, err)
+	}
+	var m Metadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse contract metadata '%s': %w", path, err)
+	}
+	return &m, nil
+}
+
+// Record appends a release to the metadata at path, creating the file if needed
+func Record(path, chain, name string, release Release) error {
+	m, err := Load(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		m = &Metadata{}
+	}
+	if m.Name != "" && m.Name != name {
+		return fmt.Errorf("contract metadata '%s' records contract '%s', not '%s'", path, m.Name, name)
+	}
+	m.Chain, m.Name = chain, name
+	m.Releases = append(m.Releases, release)
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode contract metadata: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create contract metadata directory: %w", err)
+	}
+	// Write then rename, so readers never see a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write contract metadata: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write contract metadata: %w", err)
+	}
+	return nil
+}
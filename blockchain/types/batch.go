@@ -0,0 +1,54 @@
+package types
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// SortEntries puts batch entries in canonical order: by log_hash, then by
+// timestamp, sender_org_id, client_timestamp, batch_id and log_content, so
+// the order only depends on the set of entries. Entries sharing a log_hash
+// keep a reproducible order, which decides the one the contract accepts.
+func SortEntries(entries []LogEntry) {
+	slices.SortFunc(entries, func(a, b LogEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.LogHash, b.LogHash),
+			cmp.Compare(a.Timestamp, b.Timestamp),
+			cmp.Compare(a.SenderOrgID, b.SenderOrgID),
+			cmp.Compare(a.ClientTimestamp, b.ClientTimestamp),
+			cmp.Compare(a.BatchID, b.BatchID),
+			cmp.Compare(a.LogContent, b.LogContent),
+		)
+	})
+}
+
+// EncodeBatch returns the canonical batch payload of entries: a JSON array of
+// the entries in SortEntries order, each an object with the LogEntry fields in
+// declaration order (empty optional fields omitted), without insignificant
+// whitespace, HTML escaping or a trailing newline. entries is not modified.
+func EncodeBatch(entries []LogEntry) ([]byte, error) {
+	sorted := slices.Clone(entries)
+	SortEntries(sorted)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(sorted); err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// BatchPayloadHash returns the hex SHA-256 of the canonical batch payload
+func BatchPayloadHash(entries []LogEntry) (string, error) {
+	payload, err := EncodeBatch(entries)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR (RFC 8949) modes of the evidence format. Encoding is Core
+// Deterministic (RFC 8949 section 4.2.1): shortest heads, definite lengths
+// and map keys sorted by their encoded bytes, so equal evidence always
+// encodes to the same bytes. Date/times are RFC 3339 text strings with tag 0.
+// Decoding refuses what the encoder never writes: indefinite lengths,
+// duplicate map keys and untagged date/times.
+var (
+	evidenceEncMode = mustCBOR(cbor.EncOptions{
+		Sort:          cbor.SortCoreDeterministic,
+		ShortestFloat: cbor.ShortestFloat16,
+		NaNConvert:    cbor.NaNConvert7e00,
+		InfConvert:    cbor.InfConvertFloat16,
+		IndefLength:   cbor.IndefLengthForbidden,
+		Time:          cbor.TimeRFC3339Nano,
+		TimeTag:       cbor.EncTagRequired,
+		NilContainers: cbor.NilContainerAsEmpty,
+	}.EncMode())
+
+	evidenceDecMode = mustCBOR(cbor.DecOptions{
+		DupMapKey:       cbor.DupMapKeyEnforcedAPF,
+		IndefLength:     cbor.IndefLengthForbidden,
+		TimeTag:         cbor.DecTagRequired,
+		MaxNestedLevels: cborMaxDepth,
+	}.DecMode())
+)
+
+// cborMaxDepth bounds the nesting of decoded arrays, maps and tags
+const cborMaxDepth = 8
+
+// cborMajorMap is the major type of a CBOR map, the first item of evidence
+const cborMajorMap = 5
+
+func mustCBOR[M any](mode M, err error) M {
+	if err != nil {
+		panic("cbor: invalid evidence options: " + err.Error())
+	}
+	return mode
+}
@@ -0,0 +1,98 @@
+package types
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Batch payload encodings, sent to the contract with a compressed payload
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// CompressBatch returns payload compressed with encoding ("gzip" or "zstd")
+func CompressBatch(payload []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, fmt.Errorf("failed to gzip batch payload: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to gzip batch payload: %w", err)
+		}
+		return buf.Bytes(), nil
+	case EncodingZstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		defer enc.Close()
+		return enc.EncodeAll(payload, nil), nil
+	}
+	return nil, fmt.Errorf("unsupported batch encoding '%s'", encoding)
+}
+
+// DecompressBatch returns the canonical payload of a batch sent with
+// encoding; "" is an uncompressed payload
+func DecompressBatch(data []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip batch payload: %w", err)
+		}
+		defer zr.Close()
+		payload, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip batch payload: %w", err)
+		}
+		return payload, nil
+	case EncodingZstd:
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+		}
+		defer dec.Close()
+		payload, err := dec.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd batch payload: %w", err)
+		}
+		return payload, nil
+	}
+	return nil, fmt.Errorf("unsupported batch encoding '%s'", encoding)
+}
+
+// EncodeBatchPayload returns the canonical payload of entries as sent with
+// the given compression: compressed if algorithm is "gzip" or "zstd" and the
+// payload has at least minBytes and shrinks, as is otherwise. The proof
+// carries the sizes and the encoding ("" if sent as is) for the caller to
+// complete.
+func EncodeBatchPayload(entries []LogEntry, algorithm string, minBytes int) ([]byte, *BatchProof, error) {
+	payload, err := EncodeBatch(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	proof := &BatchProof{PayloadBytes: len(payload), SentBytes: len(payload)}
+	if algorithm != EncodingGzip && algorithm != EncodingZstd || len(payload) < minBytes {
+		return payload, proof, nil
+	}
+	compressed, err := CompressBatch(payload, algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(compressed) >= len(payload) {
+		return payload, proof, nil
+	}
+	proof.SentBytes = len(compressed)
+	proof.Encoding = algorithm
+	return compressed, proof, nil
+}
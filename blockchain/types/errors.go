@@ -0,0 +1,80 @@
+package types
+
+import (
+	"errors"
+)
+
+// Blockchain error taxonomy. Clients classify the errors of the chain and its
+// SDK into these, so callers decide with errors.Is instead of matching messages.
+var (
+	// ErrChainUnavailable means no node could be reached or the node failed internally; retry later
+	ErrChainUnavailable = errors.New("chain unavailable")
+	// ErrTimeout means the transaction was not confirmed in time; it may still be committed, so retry
+	ErrTimeout = errors.New("chain timeout")
+	// ErrTxRejected means the chain refused the transaction (parameters, permissions); retrying it fails again
+	ErrTxRejected = errors.New("transaction rejected")
+	// ErrContractValidation means the contract rejected the payload; retrying it fails again
+	ErrContractValidation = errors.New("contract validation failed")
+)
+
+// Error kind names, as returned by ErrorKindName
+const (
+	KindChainUnavailable   = "chain_unavailable"
+	KindTimeout            = "timeout"
+	KindTxRejected         = "tx_rejected"
+	KindContractValidation = "contract_validation"
+	KindUnclassified       = "unclassified"
+)
+
+// ChainError is a blockchain client error classified into the taxonomy
+type ChainError struct {
+	Kind error // One of the taxonomy errors
+	Code int   // Chain-specific status code, 0 if the error came from the client or SDK
+	Err  error // The underlying error, describing what failed
+}
+
+// NewChainError classifies err as kind
+func NewChainError(kind error, code int, err error) *ChainError {
+	return &ChainError{Kind: kind, Code: code, Err: err}
+}
+
+func (e *ChainError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap makes both the kind and the underlying error match errors.Is
+func (e *ChainError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// ErrorKind returns the taxonomy error err was classified as, or nil if it was not classified
+func ErrorKind(err error) error {
+	var chainErr *ChainError
+	if errors.As(err, &chainErr) {
+		return chainErr.Kind
+	}
+	return nil
+}
+
+// IsPermanent reports whether err is one that fails again if the same
+// transaction is retried. Unclassified errors are assumed transient.
+func IsPermanent(err error) bool {
+	kind := ErrorKind(err)
+	return kind == ErrTxRejected || kind == ErrContractValidation
+}
+
+// ErrorKindName returns a stable name for the kind of err, for stats and logs
+func ErrorKindName(err error) string {
+	switch ErrorKind(err) {
+	case ErrChainUnavailable:
+		return KindChainUnavailable
+	case ErrTimeout:
+		return KindTimeout
+	case ErrTxRejected:
+		return KindTxRejected
+	case ErrContractValidation:
+		return KindContractValidation
+	default:
+		return KindUnclassified
+	}
+}
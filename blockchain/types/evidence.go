@@ -0,0 +1,171 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EvidenceVersion is the version of the evidence format written by this build
+const EvidenceVersion = 1
+
+// Chain types of evidence, the blockchain_type values of the blockchain configuration
+const (
+	ChainTypeChainMaker = "chainmaker"
+	ChainTypeFabric     = "hyperledger_fabric"
+)
+
+// ErrEvidenceInvalid indicates evidence that is malformed or does not prove its log hash
+var ErrEvidenceInvalid = errors.New("invalid evidence")
+
+// Evidence is the chain-agnostic proof that a log hash was anchored: which
+// chain, which transaction and block, and, for logs anchored under a Merkle
+// root, the inclusion path to that root. It serializes to JSON and to
+// deterministic CBOR, so it can be handed to auditors and checked against any
+// of the supported chains without knowing how the log was submitted.
+type Evidence struct {
+	Version   int           `json:"version"`
+	ChainType string        `json:"chain_type"` // ChainTypeChainMaker or ChainTypeFabric
+	NetworkID string        `json:"network_id"` // Chain ID (ChainMaker) or channel (Fabric)
+	TxID      string        `json:"tx_id"`
+	Block     EvidenceBlock `json:"block"`
+	LogHash   string        `json:"log_hash"`
+
+	Merkle *EvidenceMerkle `json:"merkle,omitempty"` // Set when the chain holds a Merkle root instead of the log hash
+}
+
+// EvidenceBlock is the block holding the anchoring transaction
+type EvidenceBlock struct {
+	Height    uint64     `json:"height"`
+	Hash      string     `json:"hash,omitempty"`      // Hex-encoded; empty if not known
+	Timestamp *time.Time `json:"timestamp,omitempty"` // Time the block was produced; nil if not known
+}
+
+// EvidenceMerkle is the inclusion path from the log hash to the anchored Merkle root
+type EvidenceMerkle struct {
+	Root string `json:"root"`
+	MerkleProof
+}
+
+// NewEvidence creates the evidence of a log hash anchored directly
+func NewEvidence(chainType, networkID, txID string, block EvidenceBlock, logHash string) *Evidence {
+	return &Evidence{
+		Version:   EvidenceVersion,
+		ChainType: chainType,
+		NetworkID: networkID,
+		TxID:      txID,
+		Block:     block,
+		LogHash:   logHash,
+	}
+}
+
+// NewMerkleEvidence creates the evidence of a log hash anchored under a Merkle root
+func NewMerkleEvidence(chainType, networkID, txID string, block EvidenceBlock, logHash, root string, proof MerkleProof) *Evidence {
+	e := NewEvidence(chainType, networkID, txID, block, logHash)
+	e.Merkle = &EvidenceMerkle{Root: root, MerkleProof: proof}
+	return e
+}
+
+// AnchoredHash returns the hash stored on chain: the Merkle root, or the log hash itself
+func (e *Evidence) AnchoredHash() string {
+	if e.Merkle != nil {
+		return e.Merkle.Root
+	}
+	return e.LogHash
+}
+
+// Validate checks that the evidence is well formed and, with a Merkle path,
+// that the path leads from the log hash to the root
+func (e *Evidence) Validate() error {
+	switch {
+	case e.Version < 1 || e.Version > EvidenceVersion:
+		return fmt.Errorf("%w: unsupported version %d", ErrEvidenceInvalid, e.Version)
+	case e.ChainType == "":
+		return fmt.Errorf("%w: chain_type is required", ErrEvidenceInvalid)
+	case e.TxID == "":
+		return fmt.Errorf("%w: tx_id is required", ErrEvidenceInvalid)
+	case e.LogHash == "":
+		return fmt.Errorf("%w: log_hash is required", ErrEvidenceInvalid)
+	}
+	if e.Merkle != nil {
+		if err := VerifyMerkleProof(e.LogHash, e.Merkle.MerkleProof, e.Merkle.Root); err != nil {
+			return fmt.Errorf("%w: %w", ErrEvidenceInvalid, err)
+		}
+	}
+	return nil
+}
+
+// Verify checks that the evidence proves logHash and matches the record read
+// back from the chain (GetLogByHash of AnchoredHash): same anchored hash, and
+// the same block where both sides know it
+func (e *Evidence) Verify(logHash string, onChain *AuditData) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+	if e.LogHash != logHash {
+		return fmt.Errorf("%w: evidence is for log hash %s, not %s", ErrEvidenceInvalid, e.LogHash, logHash)
+	}
+	if onChain == nil {
+		return nil
+	}
+	if onChain.LogHash != e.AnchoredHash() {
+		return fmt.Errorf("%w: chain holds %s, evidence anchors %s", ErrEvidenceInvalid, onChain.LogHash, e.AnchoredHash())
+	}
+	if onChain.BlockHeight != 0 && onChain.BlockHeight != e.Block.Height {
+		return fmt.Errorf("%w: chain has the anchor in block %d, evidence names block %d", ErrEvidenceInvalid, onChain.BlockHeight, e.Block.Height)
+	}
+	if onChain.BlockHash != "" && e.Block.Hash != "" && onChain.BlockHash != e.Block.Hash {
+		return fmt.Errorf("%w: block %d hash is %s on chain, %s in evidence", ErrEvidenceInvalid, e.Block.Height, onChain.BlockHash, e.Block.Hash)
+	}
+	return nil
+}
+
+// evidenceCBOR is Evidence without its CBOR methods, for the codec to
+// encode by the JSON field names
+type evidenceCBOR Evidence
+
+// MarshalCBOR encodes the evidence as deterministic CBOR, a map keyed by the JSON field names
+func (e *Evidence) MarshalCBOR() ([]byte, error) {
+	out := *e
+	if out.Block.Timestamp != nil {
+		ts := out.Block.Timestamp.UTC()
+		out.Block.Timestamp = &ts
+	}
+	return evidenceEncMode.Marshal((*evidenceCBOR)(&out))
+}
+
+// UnmarshalCBOR decodes evidence encoded by MarshalCBOR. Unknown keys are
+// ignored, so later versions may add fields.
+func (e *Evidence) UnmarshalCBOR(data []byte) error {
+	var out evidenceCBOR
+	if err := evidenceDecMode.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("%w: %w", ErrEvidenceInvalid, err)
+	}
+	*e = Evidence(out)
+	return nil
+}
+
+// DecodeEvidence decodes JSON or CBOR evidence, telling them apart by the
+// first byte, and validates it
+func DecodeEvidence(data []byte) (*Evidence, error) {
+	var e Evidence
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		if err := json.Unmarshal(trimmed, &e); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrEvidenceInvalid, err)
+		}
+	case len(data) > 0 && data[0]>>5 == cborMajorMap:
+		if err := e.UnmarshalCBOR(data); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: neither a JSON object nor a CBOR map", ErrEvidenceInvalid)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,186 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLogHash(i int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("log-%d", i)))
+	return hex.EncodeToString(sum[:])
+}
+
+// testEvidence returns the Merkle evidence of the third of five log hashes and
+// the chain record it was read back as
+func testEvidence(t *testing.T) (*Evidence, *AuditData) {
+	t.Helper()
+	hashes := make([]string, 5)
+	for i := range hashes {
+		hashes[i] = testLogHash(i)
+	}
+	tree, err := NewMerkleTree(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.Proof(hashes[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 3, 4, 5, 6, 7, 890000000, time.UTC)
+	block := EvidenceBlock{Height: 1234, Hash: "ab" + strings.Repeat("0", 62), Timestamp: &ts}
+	e := NewMerkleEvidence(ChainTypeChainMaker, "chain1", "tx-1", block, hashes[2], tree.Root(), proof)
+	return e, &AuditData{LogHash: tree.Root(), BlockHeight: block.Height, BlockHash: block.Hash}
+}
+
+func TestEvidenceRoundTrip(t *testing.T) {
+	merkle, _ := testEvidence(t)
+	direct := NewEvidence(ChainTypeFabric, "logs", "tx-2", EvidenceBlock{Height: 7}, testLogHash(0))
+
+	for _, want := range []*Evidence{merkle, direct} {
+		jsonData, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cborData, err := want.MarshalCBOR()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for format, data := range map[string][]byte{"JSON": jsonData, "CBOR": cborData} {
+			got, err := DecodeEvidence(data)
+			if err != nil {
+				t.Fatalf("DecodeEvidence(%s) failed: %v", format, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s round trip = %+v, want %+v", format, got, want)
+			}
+		}
+	}
+}
+
+func TestEvidenceCBORDeterministic(t *testing.T) {
+	e, _ := testEvidence(t)
+	want, err := e.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same instant in another zone encodes to the same bytes
+	local := e.Block.Timestamp.In(time.FixedZone("UTC+8", 8*3600))
+	e.Block.Timestamp = &local
+	if got, _ := e.MarshalCBOR(); !bytes.Equal(got, want) {
+		t.Errorf("MarshalCBOR() depends on the time zone:\n%x\n%x", got, want)
+	}
+
+	// Decoding and encoding again reproduces the bytes
+	var decoded Evidence
+	if err := decoded.UnmarshalCBOR(want); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := decoded.MarshalCBOR(); !bytes.Equal(got, want) {
+		t.Errorf("MarshalCBOR() after a round trip:\n%x\nwant\n%x", got, want)
+	}
+
+	// Map keys are sorted by their encoded bytes: shorter keys first
+	direct := NewEvidence(ChainTypeFabric, "n", "t", EvidenceBlock{Height: 1}, "h")
+	got, err := direct.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := "a6" + // map(6)
+		"65" + hex.EncodeToString([]byte("block")) + "a1" + "66" + hex.EncodeToString([]byte("height")) + "01" +
+		"65" + hex.EncodeToString([]byte("tx_id")) + "61" + hex.EncodeToString([]byte("t")) +
+		"67" + hex.EncodeToString([]byte("version")) + "01" +
+		"68" + hex.EncodeToString([]byte("log_hash")) + "61" + hex.EncodeToString([]byte("h")) +
+		"6a" + hex.EncodeToString([]byte("chain_type")) + "72" + hex.EncodeToString([]byte(ChainTypeFabric)) +
+		"6a" + hex.EncodeToString([]byte("network_id")) + "61" + hex.EncodeToString([]byte("n"))
+	if hex.EncodeToString(got) != golden {
+		t.Errorf("MarshalCBOR() = %x, want %s", got, golden)
+	}
+}
+
+func TestEvidenceCBORRejectsNonDeterministicInput(t *testing.T) {
+	e, _ := testEvidence(t)
+	valid, err := e.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := func(s string) string { return fmt.Sprintf("%02x", 0x60+len(s)) + hex.EncodeToString([]byte(s)) }
+
+	tests := map[string]string{
+		"trailing bytes": hex.EncodeToString(valid) + "00",
+		"duplicate key":  "a2" + text("version") + "01" + text("version") + "01",
+		"indefinite map": "bf" + text("version") + "01" + "ff",
+		"untagged time":  "a1" + text("block") + "a2" + text("height") + "01" + text("timestamp") + text("2026-03-04T05:06:07Z"),
+		"truncated":      hex.EncodeToString(valid[:len(valid)/2]),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			raw, _ := hex.DecodeString(data)
+			var decoded Evidence
+			if err := decoded.UnmarshalCBOR(raw); !errors.Is(err, ErrEvidenceInvalid) {
+				t.Errorf("UnmarshalCBOR() error = %v, want ErrEvidenceInvalid", err)
+			}
+		})
+	}
+}
+
+func TestEvidenceTampering(t *testing.T) {
+	e, onChain := testEvidence(t)
+	if err := e.Verify(e.LogHash, onChain); err != nil {
+		t.Fatalf("Verify() of untampered evidence failed: %v", err)
+	}
+	jsonData, _ := json.Marshal(e)
+	cborData, _ := e.MarshalCBOR()
+
+	// Replacing the log hash or a sibling in the encoded bytes breaks the
+	// Merkle path in either format
+	sibling := e.Merkle.Siblings[0]
+	flipped := strings.Repeat("0", len(sibling))
+	if sibling == flipped {
+		flipped = strings.Repeat("1", len(sibling))
+	}
+	for format, data := range map[string][]byte{"JSON": jsonData, "CBOR": cborData} {
+		for name, swap := range map[string][2]string{
+			"log hash": {e.LogHash, testLogHash(9)},
+			"sibling":  {sibling, flipped},
+		} {
+			tampered := bytes.Replace(data, []byte(swap[0]), []byte(swap[1]), 1)
+			if bytes.Equal(tampered, data) {
+				t.Fatalf("%s %s not found in the encoding", format, name)
+			}
+			if _, err := DecodeEvidence(tampered); !errors.Is(err, ErrEvidenceInvalid) {
+				t.Errorf("DecodeEvidence(%s with the %s replaced) error = %v, want ErrEvidenceInvalid", format, name, err)
+			}
+		}
+	}
+
+	// Evidence that decodes but disagrees with the chain
+	tests := []struct {
+		name    string
+		logHash string
+		edit    func(*Evidence, *AuditData)
+	}{
+		{"other log hash", testLogHash(3), func(*Evidence, *AuditData) {}},
+		{"other root on chain", e.LogHash, func(_ *Evidence, a *AuditData) { a.LogHash = testLogHash(4) }},
+		{"other block height", e.LogHash, func(ev *Evidence, _ *AuditData) { ev.Block.Height++ }},
+		{"other block hash", e.LogHash, func(ev *Evidence, _ *AuditData) { ev.Block.Hash = "cd" + strings.Repeat("0", 62) }},
+		{"other leaf index", e.LogHash, func(ev *Evidence, _ *AuditData) { ev.Merkle.LeafIndex++ }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, chain := testEvidence(t)
+			tt.edit(ev, chain)
+			if err := ev.Verify(tt.logHash, chain); !errors.Is(err, ErrEvidenceInvalid) {
+				t.Errorf("Verify() error = %v, want ErrEvidenceInvalid", err)
+			}
+		})
+	}
+}
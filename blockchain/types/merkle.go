@@ -0,0 +1,148 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Domain separation prefixes of Merkle tree hashes, so a leaf can never be
+// passed off as an inner node (RFC 6962)
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// ErrMerkleProofInvalid indicates a Merkle proof that does not lead to the root
+var ErrMerkleProofInvalid = errors.New("merkle proof does not match the root")
+
+// MerkleProof proves that a log hash is a leaf of the tree with a given root.
+// Leaves are the batch's distinct log hashes in sorted order; a node without a
+// sibling on its level is promoted unchanged, so LeafIndex and LeafCount
+// determine on which side each sibling goes.
+type MerkleProof struct {
+	LeafIndex int      `json:"leaf_index"`
+	LeafCount int      `json:"leaf_count"`
+	Siblings  []string `json:"siblings"` // Hex-encoded sibling hashes from the leaf level up
+}
+
+// MerkleTree is a Merkle tree over log hashes
+type MerkleTree struct {
+	leaves []string   // Sorted distinct log hashes
+	levels [][][]byte // levels[0] are the leaf hashes, the last level is the root
+}
+
+// NewMerkleTree builds the Merkle tree over the distinct log hashes
+func NewMerkleTree(logHashes []string) (*MerkleTree, error) {
+	if len(logHashes) == 0 {
+		return nil, fmt.Errorf("merkle tree needs at least one log hash")
+	}
+	leaves := slices.Clone(logHashes)
+	slices.Sort(leaves)
+	leaves = slices.Compact(leaves)
+
+	level := make([][]byte, len(leaves))
+	for i, h := range leaves {
+		level[i] = merkleLeafHash(h)
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i]) // Promoted
+				continue
+			}
+			next = append(next, merkleNodeHash(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return &MerkleTree{leaves: leaves, levels: levels}, nil
+}
+
+// Root returns the hex-encoded root hash
+func (t *MerkleTree) Root() string {
+	return hex.EncodeToString(t.levels[len(t.levels)-1][0])
+}
+
+// Len returns the number of leaves
+func (t *MerkleTree) Len() int {
+	return len(t.leaves)
+}
+
+// Proof returns the inclusion proof of a log hash
+func (t *MerkleTree) Proof(logHash string) (MerkleProof, error) {
+	index, found := slices.BinarySearch(t.leaves, logHash)
+	if !found {
+		return MerkleProof{}, fmt.Errorf("log hash %s is not a leaf of the merkle tree", logHash)
+	}
+	proof := MerkleProof{LeafIndex: index, LeafCount: len(t.leaves), Siblings: []string{}}
+	for _, level := range t.levels[:len(t.levels)-1] {
+		if sibling := index ^ 1; sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, hex.EncodeToString(level[sibling]))
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that proof leads from logHash to the hex-encoded
+// root, returning ErrMerkleProofInvalid if it does not
+func VerifyMerkleProof(logHash string, proof MerkleProof, root string) error {
+	if proof.LeafCount < 1 || proof.LeafIndex < 0 || proof.LeafIndex >= proof.LeafCount {
+		return fmt.Errorf("leaf %d of %d: %w", proof.LeafIndex, proof.LeafCount, ErrMerkleProofInvalid)
+	}
+	node := merkleLeafHash(logHash)
+	index, count, used := proof.LeafIndex, proof.LeafCount, 0
+	for count > 1 {
+		if sibling := index ^ 1; sibling < count {
+			if used == len(proof.Siblings) {
+				return fmt.Errorf("too few siblings: %w", ErrMerkleProofInvalid)
+			}
+			hash, err := hex.DecodeString(proof.Siblings[used])
+			if err != nil {
+				return fmt.Errorf("sibling %d is not hex: %w", used, ErrMerkleProofInvalid)
+			}
+			used++
+			if index%2 == 0 {
+				node = merkleNodeHash(node, hash)
+			} else {
+				node = merkleNodeHash(hash, node)
+			}
+		}
+		index /= 2
+		count = (count + 1) / 2
+	}
+	if used != len(proof.Siblings) {
+		return fmt.Errorf("too many siblings: %w", ErrMerkleProofInvalid)
+	}
+	if hex.EncodeToString(node) != root {
+		return ErrMerkleProofInvalid
+	}
+	return nil
+}
+
+// MerkleRootContent returns the log content anchored with a Merkle root
+func MerkleRootContent(leafCount int) string {
+	return fmt.Sprintf(`{"type":"merkle_root","leaf_count":%d}`, leafCount)
+}
+
+// merkleLeafHash returns the hash of the leaf of a log hash
+func merkleLeafHash(logHash string) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeafPrefix})
+	h.Write([]byte(logHash))
+	return h.Sum(nil)
+}
+
+// merkleNodeHash returns the hash of the inner node over two children
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{merkleNodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
@@ -1,12 +1,22 @@
 package types
 
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotSupported is returned for operations the deployed contract does not implement
+var ErrNotSupported = errors.New("not supported by the deployed contract")
+
 // LogEntry corresponds to the struct sent in the batch JSON
 // This is a generic type that can be implemented by any blockchain
 type LogEntry struct {
-	LogHash     string `json:"log_hash"`
-	LogContent  string `json:"log_content"`
-	SenderOrgID string `json:"sender_org_id"`
-	Timestamp   string `json:"timestamp"`
+	LogHash         string `json:"log_hash"`
+	LogContent      string `json:"log_content"`
+	SenderOrgID     string `json:"sender_org_id"`
+	Timestamp       string `json:"timestamp"`                  // Server receive time
+	ClientTimestamp string `json:"client_timestamp,omitempty"` // Client-reported event time, if provided
+	BatchID         string `json:"batch_id,omitempty"`         // Engine batch that anchored the entry, for tracing
 }
 
 // LogProcessingStatus corresponds to the Rust enum for batch results
@@ -31,6 +41,9 @@ type LogStatusInfo struct {
 type BatchProof struct {
 	TransactionID string // The TxID for the single batch transaction
 	BlockHeight   uint64 // The block height where the batch was included
+	PayloadBytes  int    // Size of the canonical batch payload
+	SentBytes     int    // Size of the payload as sent, after compression
+	Encoding      string // Compression of the payload sent, "" if none
 }
 
 // Proof is the on-chain credential returned after successful single SubmitLog
@@ -40,9 +53,50 @@ type Proof struct {
 	LogHash       string
 }
 
+// ContractInfo describes the attestation contract deployed on chain
+type ContractInfo struct {
+	Name    string
+	Version string
+	Runtime string
+}
+
+// ContractSpec is a build of the attestation contract to deploy or upgrade to
+type ContractSpec struct {
+	Version      string
+	BytecodePath string
+	Runtime      string // Chain-specific, e.g. WASMER (Rust) or DOCKER_GO (Go) on ChainMaker
+}
+
+// ContractDeployment is the on-chain credential of a contract deploy or upgrade
+type ContractDeployment struct {
+	ContractInfo
+	TransactionID string
+	BlockHeight   uint64
+}
+
 // AuditData is the raw notarization data parsed from on-chain events
 type AuditData struct {
-	LogHash        string
-	SubmitterOrgID string
-	Timestamp      string
-}
\ No newline at end of file
+	LogHash         string
+	SubmitterOrgID  string
+	Timestamp       string
+	ClientTimestamp string // Set by batch submissions with a client timestamp; empty otherwise
+
+	// Anchoring context of the transaction, the same for all its entries
+	BlockHeight    uint64
+	BlockHash      string    // Hex-encoded
+	BlockTimestamp time.Time // Time the block was produced
+	Confirmations  uint64    // Blocks from the anchoring block to the chain head, inclusive; 0 if the head could not be read
+}
+
+// Pagination selects one page of an on-chain enumeration
+type Pagination struct {
+	Cursor string // Opaque cursor returned with the previous page; empty for the first page
+	Limit  int    // Maximum entries in the page
+}
+
+// OnChainLogPage is one page of the attestations an org submitted on chain.
+// Entries carry the stored record only; their anchoring block is not known.
+type OnChainLogPage struct {
+	Logs       []AuditData
+	NextCursor string // Empty on the last page
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"tlng/archive"
+	"tlng/config"
+	"tlng/internal/messaging/consumer"
+	"tlng/storage/objectstore"
+	"tlng/storage/store"
+)
+
+const archiverConfigPath = "./config/archiver.defaults.yml"
+
+func main() {
+	logger := log.New(os.Stdout, "[ARCHIVER] ", log.LstdFlags|log.Lshortfile)
+	logger.Println("Starting Archiver...")
+
+	// 1. Load Archiver Config
+	archiverCfg, err := config.LoadArchiverConfig(archiverConfigPath)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to load archiver configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 2. Initialize Object Store
+	logger.Printf("Initializing %s object store...", archiverCfg.ObjectStore.Backend)
+	objStore, err := objectstore.New(archiverCfg.ObjectStore, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize object store: %v", err)
+	}
+
+	// Archive the region-scoped topic in region-aware deployments
+	archiverCfg.KafkaConsumer.Topic = archiverCfg.Region.Topic(archiverCfg.KafkaConsumer.Topic)
+
+	// 3. Initialize Consumers
+	var mqConsumers []consumer.Consumer
+	logger.Printf("Initializing %d Kafka consumers...", archiverCfg.KafkaConsumer.Count)
+	for i := 0; i < archiverCfg.KafkaConsumer.Count; i++ {
+		kafkaConsumer, err := consumer.NewKafkaConsumer(archiverCfg.KafkaConsumer, logger)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize Kafka consumer %d: %v", i, err)
+		}
+		mqConsumers = append(mqConsumers, kafkaConsumer)
+	}
+	// Large submissions are published to their own topic; archive them too
+	if archiverCfg.LargeTopic != "" {
+		largeCfg := archiverCfg.KafkaConsumer
+		largeCfg.Topic = archiverCfg.Region.Topic(archiverCfg.LargeTopic)
+		largeCfg.TopicCheck.Partitions = 0
+		kafkaConsumer, err := consumer.NewKafkaConsumer(largeCfg, logger)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize Kafka consumer for %s: %v", largeCfg.Topic, err)
+		}
+		mqConsumers = append(mqConsumers, kafkaConsumer)
+	}
+	defer func() {
+		for _, c := range mqConsumers {
+			c.Close()
+		}
+	}()
+
+	// 4. Start one Archiver per consumer
+	var wg sync.WaitGroup
+	for i, c := range mqConsumers {
+		archiver := archive.New(archiverCfg.Archive, c, objStore, logger)
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			logger.Printf("Starting archiver %d...", id)
+			archiver.Run(ctx)
+		}(i + 1)
+	}
+
+	// 5. Start the Parquet export of completed attestations (optional)
+	if archiverCfg.Export.Enabled {
+		dbStore, err := store.NewPostgresStore(ctx, archiverCfg.Database.DSN, archiverCfg.Database.MaxConnections, archiverCfg.Database.MinConnections, logger)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize database store: %v", err)
+		}
+		defer dbStore.Close()
+
+		// Exports live under their own prefix, next to the raw archive
+		exportStoreCfg := archiverCfg.ObjectStore
+		exportStoreCfg.Prefix = archiverCfg.Export.Prefix
+		exportObjects, err := objectstore.New(exportStoreCfg, logger)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize export object store: %v", err)
+		}
+
+		exporter, err := archive.NewExporter(archiverCfg.Export, dbStore, exportObjects, logger)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize exporter: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			exporter.Run(ctx)
+		}()
+	}
+
+	logger.Printf("Archiver started with %d consumers. Press Ctrl+C to stop.", len(mqConsumers))
+
+	// 6. Graceful Shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	logger.Println("Received shutdown signal, flushing buffered records...")
+	cancel()
+	wg.Wait()
+
+	logger.Println("Archiver shut down gracefully.")
+}
@@ -0,0 +1,220 @@
+// Command contract deploys, upgrades and verifies the attestation contract.
+//
+//	contract info    [-config path]
+//	contract verify  [-config path]
+//	contract deploy  [-config path] -version v3 -bytecode log_store.wasm [-runtime WASMER]
+//	contract upgrade [-config path] -version v4 -bytecode log_store.wasm [-runtime WASMER]
+//
+// Deploys and upgrades are recorded in the contract metadata file, from which
+// the blockchain client takes the contract version (and so the event schemas)
+// when contract_version is not configured.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/blockchain/client/chainmaker"
+	"tlng/blockchain/contract"
+	"tlng/blockchain/types"
+)
+
+const (
+	defaultConfigPath   = "./config/blockchain.defaults.yml"
+	defaultMetadataPath = "./config/clients/contract-metadata.json"
+)
+
+func main() {
+	logger := log.New(os.Stderr, "[CONTRACT] ", log.LstdFlags)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	configPath := fs.String("config", defaultConfigPath, "Blockchain client configuration file")
+	version := fs.String("version", "", "Contract version to deploy or upgrade to (deploy, upgrade)")
+	bytecode := fs.String("bytecode", "", "Contract bytecode file (deploy, upgrade)")
+	runtime := fs.String("runtime", "WASMER", "Contract runtime, e.g. WASMER (Rust) or DOCKER_GO (Go) on ChainMaker (deploy, upgrade)")
+	metadataPath := fs.String("metadata", "", "Contract metadata file (default: contract_metadata_path from the chain config, else "+defaultMetadataPath+")")
+	timeout := fs.Duration("timeout", 2*time.Minute, "Timeout for the operation")
+	fs.Parse(os.Args[2:])
+
+	client, err := blockchain.NewBlockchainClientFromFile(*configPath, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize blockchain client: %v", err)
+	}
+	defer client.Close()
+	mgr, ok := client.(blockchain.ContractManager)
+	if !ok {
+		logger.Fatalf("FATAL: %T does not support contract management", client)
+	}
+	chain, recordPath := chainIdentity(client.Config())
+	if *metadataPath != "" {
+		recordPath = *metadataPath
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch cmd {
+	case "info":
+		if err := printInfo(ctx, mgr, recordPath); err != nil {
+			logger.Fatalf("FATAL: %v", err)
+		}
+	case "verify":
+		if err := verify(ctx, mgr, recordPath); err != nil {
+			logger.Fatalf("FATAL: %v", err)
+		}
+		fmt.Println("OK: deployed contract matches the expected version")
+	case "deploy", "upgrade":
+		spec := types.ContractSpec{Version: *version, BytecodePath: *bytecode, Runtime: *runtime}
+		if err := release(ctx, mgr, cmd == "upgrade", spec, chain, recordPath, logger); err != nil {
+			logger.Fatalf("FATAL: %v", err)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: contract info|verify|deploy|upgrade [flags]; contract <command> -h for flags")
+	os.Exit(2)
+}
+
+// chainIdentity returns the chain ID and the configured metadata path for a client configuration
+func chainIdentity(cfg any) (chain, metadataPath string) {
+	metadataPath = defaultMetadataPath
+	if cmCfg, ok := cfg.(*chainmaker.ChainMakerConfig); ok {
+		chain = cmCfg.ChainID
+		if cmCfg.ContractMetadataPath != "" {
+			metadataPath = cmCfg.ContractMetadataPath
+		}
+	}
+	return chain, metadataPath
+}
+
+// printInfo prints the deployed contract, the expected version and the recorded release
+func printInfo(ctx context.Context, mgr blockchain.ContractManager, metadataPath string) error {
+	info, err := mgr.ContractInfo(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Contract:         %s\n", info.Name)
+	fmt.Printf("Deployed version: %s\n", info.Version)
+	fmt.Printf("Runtime:          %s\n", info.Runtime)
+	fmt.Printf("Expected version: %s\n", mgr.ExpectedContractVersion())
+
+	m, err := contract.Load(metadataPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fmt.Printf("Metadata:         none (%s)\n", metadataPath)
+	case err != nil:
+		return err
+	case m.Current() != nil:
+		r := m.Current()
+		fmt.Printf("Recorded release: %s (%s), tx %s at block %d, %s\n",
+			r.Version, r.Runtime, r.TransactionID, r.BlockHeight, r.DeployedAt.Format(time.RFC3339))
+		fmt.Printf("Bytecode SHA-256: %s\n", r.BytecodeSHA256)
+	}
+	return nil
+}
+
+// verify checks that the deployed contract is the version the client expects
+// and, if metadata is recorded, the version recorded there
+func verify(ctx context.Context, mgr blockchain.ContractManager, metadataPath string) error {
+	info, err := mgr.ContractInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if info.Version != mgr.ExpectedContractVersion() {
+		return fmt.Errorf("contract %s is at version %s, the client expects %s", info.Name, info.Version, mgr.ExpectedContractVersion())
+	}
+	m, err := contract.Load(metadataPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if r := m.Current(); r != nil && r.Version != info.Version {
+		return fmt.Errorf("contract %s is at version %s, metadata records %s", info.Name, info.Version, r.Version)
+	}
+	return nil
+}
+
+// release deploys or upgrades the contract and records the release
+func release(ctx context.Context, mgr blockchain.ContractManager, upgrade bool, spec types.ContractSpec, chain, metadataPath string, logger *log.Logger) error {
+	digest, err := fileSHA256(spec.BytecodePath)
+	if err != nil {
+		return err
+	}
+
+	var deployment *types.ContractDeployment
+	if upgrade {
+		current, err := mgr.ContractInfo(ctx)
+		if err != nil {
+			return err
+		}
+		if current.Version == spec.Version {
+			return fmt.Errorf("contract %s is already at version %s", current.Name, spec.Version)
+		}
+		logger.Printf("Upgrading contract %s from %s to %s...", current.Name, current.Version, spec.Version)
+		deployment, err = mgr.UpgradeContract(ctx, spec)
+		if err != nil {
+			return err
+		}
+	} else {
+		logger.Printf("Deploying contract version %s...", spec.Version)
+		deployment, err = mgr.DeployContract(ctx, spec)
+		if err != nil {
+			return err
+		}
+	}
+	logger.Printf("Contract %s %s on chain: tx %s at block %d", deployment.Name, deployment.Version, deployment.TransactionID, deployment.BlockHeight)
+
+	err = contract.Record(metadataPath, chain, deployment.Name, contract.Release{
+		Version:        deployment.Version,
+		Runtime:        deployment.Runtime,
+		BytecodeSHA256: digest,
+		TransactionID:  deployment.TransactionID,
+		BlockHeight:    deployment.BlockHeight,
+		DeployedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("contract is on chain but recording the release failed: %w", err)
+	}
+	logger.Printf("Recorded release in %s", metadataPath)
+
+	if deployment.Version != mgr.ExpectedContractVersion() {
+		logger.Printf("Warning: clients configured with contract_version %s; update it (or unset it to use the metadata) and make sure event_schemas covers %s",
+			mgr.ExpectedContractVersion(), deployment.Version)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex SHA-256 digest of a file
+func fileSHA256(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("-bytecode is required")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open bytecode: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read bytecode: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/messaging/consumer"
+	"tlng/internal/messaging/producer"
+	"tlng/internal/models"
+	"tlng/storage/store"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// runDLQ lists or re-injects the dead-lettered tasks:
+//
+//	engine dlq list [-config path] [-from time] [-org id] [-request-id ids]
+//	engine dlq reinject [-config path] [-dsn dsn] [-from time] [-org id] [-request-id ids]
+//
+// reinject returns the selected tasks to RECEIVED with a fresh retry budget
+// and publishes their log messages to the log topic again, so the engine
+// anchors them once the cause of the failure is fixed.
+func runDLQ(args []string, logger *log.Logger) {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "reinject") {
+		fmt.Fprintln(os.Stderr, "usage: engine dlq list|reinject [flags]; engine dlq <command> -h for flags")
+		os.Exit(2)
+	}
+	cmd := args[0]
+
+	fs := flag.NewFlagSet("dlq "+cmd, flag.ExitOnError)
+	configPath := fs.String("config", engineConfigPath, "Engine configuration file (Kafka, dlq and database)")
+	dsn := fs.String("dsn", "", "State DB of the tasks (default: database.dsn of the configuration; reinject)")
+	from := fs.String("from", "", "Only tasks dead-lettered at or after this RFC 3339 time (default: earliest retained)")
+	orgID := fs.String("org", "", "Only tasks of this org")
+	requestIDs := fs.String("request-id", "", "Only these comma-separated request IDs")
+	fs.Parse(args[1:])
+
+	cfg, err := config.LoadEngineConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to load engine configuration: %v", err)
+	}
+	if !cfg.DLQ.Enabled {
+		logger.Fatal("FATAL: dlq is not enabled in the configuration")
+	}
+	if len(cfg.KafkaConsumer.Brokers) == 0 || cfg.KafkaConsumer.Brokers[0] == "mock://local" {
+		logger.Fatal("FATAL: dlq requires Kafka brokers in kafka_consumer")
+	}
+	start := consumer.ReplayStart{Offset: kafka.FirstOffset}
+	if *from != "" {
+		if start.Time, err = time.Parse(time.RFC3339, *from); err != nil {
+			logger.Fatalf("FATAL: invalid -from %q: %v", *from, err)
+		}
+	}
+	var wanted []string
+	if *requestIDs != "" {
+		wanted = strings.Split(*requestIDs, ",")
+	}
+	tlsConfig, err := cfg.KafkaConsumer.TLS.TLSConfig()
+	if err != nil {
+		logger.Fatalf("FATAL: invalid Kafka TLS configuration: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// A task dead-lettered more than once is kept with its last failure
+	letters := make(map[string]*producer.DeadLetter)
+	var undecodable int
+	_, err = consumer.Replay(ctx, cfg.KafkaConsumer.Brokers, tlsConfig, cfg.DLQ.Topic, start, func(msg kafka.Message) error {
+		letter, err := producer.DecodeDeadLetter(msg)
+		if err != nil {
+			undecodable++
+			logger.Printf("Warning: skipping undecodable dead letter at %s/%d offset %d: %v", cfg.DLQ.Topic, msg.Partition, msg.Offset, err)
+			return nil
+		}
+		if (*orgID != "" && letter.Message.SourceOrgID != *orgID) || (wanted != nil && !slices.Contains(wanted, letter.Message.RequestID)) {
+			return nil
+		}
+		letters[letter.Message.RequestID] = letter
+		return nil
+	}, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: %v", err)
+	}
+
+	ids := make([]string, 0, len(letters))
+	for id := range letters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	switch cmd {
+	case "list":
+		for _, id := range ids {
+			l := letters[id]
+			fmt.Printf("%s  org=%s  failed_at=%s  retries=%d  reason=%s\n",
+				id, l.Message.SourceOrgID, l.FailedAt.Format(time.RFC3339), l.RetryCount, l.Reason)
+		}
+		fmt.Printf("Dead letters: %d\n", len(ids))
+	case "reinject":
+		reinject(ctx, cfg, *dsn, ids, letters, logger)
+	}
+	if undecodable > 0 {
+		fmt.Printf("Undecodable: %d\n", undecodable)
+		os.Exit(1)
+	}
+}
+
+// reinjectBatchSize is the number of log messages published per write
+const reinjectBatchSize = 500
+
+// reinject requeues the dead-lettered tasks in the State DB and publishes
+// their log messages to the log topic. Tasks that are no longer FAILED (e.g.
+// anchored since) are skipped; tasks requeued by an earlier, interrupted run
+// are published again.
+func reinject(ctx context.Context, cfg *config.EngineConfig, dsn string, ids []string, letters map[string]*producer.DeadLetter, logger *log.Logger) {
+	if len(ids) == 0 {
+		fmt.Println("Nothing to reinject")
+		return
+	}
+	if dsn == "" {
+		dsn = cfg.Database.DSN
+	}
+	dbStore, err := store.NewPostgresStore(ctx, dsn, 2, 2, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize database store: %v", err)
+	}
+	defer dbStore.Close()
+
+	requeued, err := dbStore.RequeueFailedTasks(ctx, ids)
+	if err != nil {
+		logger.Fatalf("FATAL: %v", err)
+	}
+	if len(requeued) == 0 {
+		fmt.Printf("Dead letters: %d, none still FAILED\n", len(ids))
+		return
+	}
+
+	// Keyed like the gateways key them, so sharded engines get each log hash
+	// range on its own partition
+	partitionKey := config.PartitionKeyRequestID
+	if cfg.Worker.Sharding == config.ShardingHashRange {
+		partitionKey = config.PartitionKeyLogHash
+	}
+	p, err := producer.NewKafkaProducer(config.KafkaProducerConfig{
+		Brokers:      cfg.KafkaConsumer.Brokers,
+		Topic:        cfg.Region.Topic(cfg.KafkaConsumer.Topic),
+		RequiredAcks: "all",
+		TLS:          cfg.KafkaConsumer.TLS,
+		Encoding:     "protobuf",
+		PartitionKey: partitionKey,
+	}, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize Kafka producer: %v", err)
+	}
+	defer p.Close()
+
+	msgs := make([]*models.LogMessage, len(requeued))
+	for i, id := range requeued {
+		msgs[i] = letters[id].Message
+	}
+	for i := 0; i < len(msgs); i += reinjectBatchSize {
+		batch := msgs[i:min(i+reinjectBatchSize, len(msgs))]
+		if err := p.PublishBatch(ctx, batch); err != nil {
+			logger.Fatalf("FATAL: %v; run reinject again to publish the remaining tasks", err)
+		}
+	}
+	fmt.Printf("Dead letters:  %d\n", len(ids))
+	fmt.Printf("Re-injected:   %d\n", len(requeued))
+	fmt.Printf("Skipped:       %d (no longer FAILED)\n", len(ids)-len(requeued))
+}
@@ -5,20 +5,24 @@ import (
 	"log"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
-	blockchain "tlng/blockchain/client"
 	"tlng/config"
-	"tlng/internal/messaging/consumer"
-	worker "tlng/processing"
-	"tlng/storage/store"
+	"tlng/processing/app"
 )
 
 const engineConfigPath = "./config/engine.defaults.yml"
 
 func main() {
 	logger := log.New(os.Stdout, "[ENGINE] ", log.LstdFlags|log.Lshortfile)
+	if len(os.Args) > 1 && os.Args[1] == "rebuild" {
+		runRebuild(os.Args[2:], logger)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		runDLQ(os.Args[2:], logger)
+		return
+	}
 	logger.Println("Starting Attestation Engine...")
 
 	// 1. Load Engine Config
@@ -27,77 +31,24 @@ func main() {
 		logger.Fatalf("FATAL: Failed to load engine configuration: %v", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// 2. Initialize Dependencies
-	logger.Println("Initializing database connection...")
-	dbStore, err := store.NewPostgresStore(ctx, engineCfg.Database.DSN, engineCfg.Database.MinConnections, engineCfg.Database.MaxConnections, logger)
+	// 2. Initialize Dependencies, waiting for them to come up instead of failing on the first attempt
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	engine, err := app.New(ctx, engineCfg, app.Deps{}, logger)
 	if err != nil {
-		logger.Fatalf("FATAL: Failed to initialize database store: %v", err)
+		logger.Fatalf("FATAL: Failed to start Attestation Engine: %v", err)
 	}
-	defer dbStore.Close()
 
-	logger.Println("Initializing blockchain client using configuration files...")
-	// Load blockchain client
-	bcClientImpl, err := blockchain.NewBlockchainClientFromFile(engineCfg.BlockchainClientConfigPath, logger)
-	if err != nil {
-		logger.Fatalf("FATAL: Failed to initialize ChainMaker client: %v", err)
-	}
-	defer bcClientImpl.Close()
-
-	// 3. Initialize Multiple Consumers
-	var mqConsumers []consumer.Consumer
-	if len(engineCfg.KafkaConsumer.Brokers) > 0 && engineCfg.KafkaConsumer.Brokers[0] != "mock://local" {
-		logger.Printf("Initializing %d Kafka message queue consumers...", engineCfg.KafkaConsumer.Count)
-		for i := 0; i < engineCfg.KafkaConsumer.Count; i++ {
-			kafkaConsumer, err := consumer.NewKafkaConsumer(engineCfg.KafkaConsumer, logger)
-			if err != nil {
-				logger.Fatalf("FATAL: Failed to initialize Kafka consumer %d: %v", i, err)
-			}
-			mqConsumers = append(mqConsumers, kafkaConsumer)
-		}
+	// 3. Start the workers and servers, consuming until a shutdown signal arrives
+	if err := engine.Run(ctx); err != nil {
+		logger.Printf("%v, shutting down Attestation Engine...", err)
 	} else {
-		logger.Println("Initializing Mock message queue consumer...")
-		mqConsumers = append(mqConsumers, consumer.NewMockConsumer(logger))
+		logger.Println("Received shutdown signal, initiating graceful shutdown...")
 	}
 
-	// Ensure all consumers are closed on exit
-	defer func() {
-		for _, c := range mqConsumers {
-			c.Close()
-		}
-	}()
-
-	// 4. Create and Start Multiple Workers
-	var workers []*worker.Worker
-	var wg sync.WaitGroup
-
-	for i, consumer := range mqConsumers {
-		workerInstance := worker.New(engineCfg.Worker, engineCfg.MaxTaskRetries, logger, dbStore, consumer, bcClientImpl)
-		workers = append(workers, workerInstance)
-
-		wg.Add(1)
-		go func(workerID int, w *worker.Worker) {
-			defer wg.Done()
-			logger.Printf("Starting worker %d with its dedicated consumer...", workerID)
-			w.Run(ctx)
-			logger.Printf("Worker %d stopped.", workerID)
-		}(i+1, workerInstance)
+	// 4. Graceful Shutdown
+	if err := engine.Shutdown(context.Background()); err != nil {
+		logger.Printf("Attestation Engine shutdown incomplete: %v", err)
 	}
-
-	logger.Printf("Attestation Engine started with %d workers. Press Ctrl+C to stop.", len(workers))
-
-	// 6. Graceful Shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	logger.Println("Received shutdown signal, initiating graceful shutdown...")
-	cancel()
-
-	// Wait for all workers to finish
-	logger.Println("Waiting for all workers to finish...")
-	wg.Wait()
-
 	logger.Println("Attestation Engine shut down gracefully.")
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/messaging/consumer"
+	worker "tlng/processing"
+	"tlng/storage/store"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// runRebuild reconstructs the State DB from the Kafka topics:
+//
+//	engine rebuild [-config path] [-dsn dsn] [-from time | -offset n] [-outcomes=false]
+//
+// It is safe to run against a database restored from a backup: existing
+// submissions are kept and COMPLETED ones are never downgraded.
+func runRebuild(args []string, logger *log.Logger) {
+	fs := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	configPath := fs.String("config", engineConfigPath, "Engine configuration file (Kafka and database)")
+	dsn := fs.String("dsn", "", "State DB to rebuild into (default: database.dsn of the configuration)")
+	from := fs.String("from", "", "Replay messages published at or after this RFC 3339 time (default: earliest retained)")
+	offset := fs.Int64("offset", -1, "Replay each partition of the log topics from this offset (instead of -from)")
+	outcomes := fs.Bool("outcomes", true, "Apply the final outcomes from the status_events topic")
+	batchSize := fs.Int("batch-size", 500, "Records written per database round trip")
+	fs.Parse(args)
+
+	cfg, err := config.LoadEngineConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to load engine configuration: %v", err)
+	}
+	if *from != "" && *offset >= 0 {
+		logger.Fatal("FATAL: -from and -offset are mutually exclusive")
+	}
+	if len(cfg.KafkaConsumer.Brokers) == 0 || cfg.KafkaConsumer.Brokers[0] == "mock://local" {
+		logger.Fatal("FATAL: rebuild requires Kafka brokers in kafka_consumer")
+	}
+
+	// The log topics replay from the requested position; the status events
+	// topic, whose offsets are unrelated, from the same time or the beginning
+	start := consumer.ReplayStart{Offset: kafka.FirstOffset}
+	eventsStart := start
+	if *from != "" {
+		t, err := time.Parse(time.RFC3339, *from)
+		if err != nil {
+			logger.Fatalf("FATAL: invalid -from %q: %v", *from, err)
+		}
+		start.Time, eventsStart.Time = t, t
+	} else if *offset >= 0 {
+		start.Offset = *offset
+	}
+
+	tlsConfig, err := cfg.KafkaConsumer.TLS.TLSConfig()
+	if err != nil {
+		logger.Fatalf("FATAL: invalid Kafka TLS configuration: %v", err)
+	}
+	if *dsn == "" {
+		*dsn = cfg.Database.DSN
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbStore, err := store.NewPostgresStore(ctx, *dsn, 2, 4, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize database store: %v", err)
+	}
+	defer dbStore.Close()
+
+	rebuilder := worker.NewRebuilder(dbStore, cfg.KafkaConsumer.Brokers, tlsConfig, *batchSize, logger)
+	topics := []string{cfg.Region.Topic(cfg.KafkaConsumer.Topic)}
+	if cfg.SizeTier.Enabled {
+		topics = append(topics, cfg.Region.Topic(cfg.SizeTier.Topic))
+	}
+	for _, topic := range topics {
+		logger.Printf("Restoring submissions from kafka topic %s...", topic)
+		if err := rebuilder.RestoreSubmissions(ctx, topic, start); err != nil {
+			logger.Fatalf("FATAL: %v", err)
+		}
+	}
+	if *outcomes && cfg.StatusEvents.Topic == "" {
+		logger.Println("Warning: status_events is not enabled, restored submissions stay RECEIVED")
+	} else if *outcomes {
+		logger.Printf("Restoring outcomes from kafka topic %s...", cfg.StatusEvents.Topic)
+		if err := rebuilder.RestoreOutcomes(ctx, cfg.StatusEvents.Topic, eventsStart); err != nil {
+			logger.Fatalf("FATAL: %v", err)
+		}
+	}
+
+	stats := rebuilder.Stats()
+	fmt.Printf("Log messages replayed:   %d\n", stats.Messages)
+	fmt.Printf("Submissions inserted:    %d\n", stats.Inserted)
+	fmt.Printf("Submissions existing:    %d\n", stats.Existing)
+	fmt.Printf("Status events replayed:  %d\n", stats.Events)
+	fmt.Printf("Outcomes written:        %d\n", stats.Outcomes)
+	fmt.Printf("Undecodable:             %d\n", stats.Undecodable)
+	if stats.Undecodable > 0 {
+		os.Exit(1)
+	}
+}
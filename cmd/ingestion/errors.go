@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"tlng/errcatalog"
+)
+
+// runErrors prints the error catalog of the gateway's HTTP and gRPC APIs:
+//
+//	ingestion errors [-lang zh] [-json]
+func runErrors(args []string, logger *log.Logger) {
+	fs := flag.NewFlagSet("errors", flag.ExitOnError)
+	lang := fs.String("lang", "en", "Language of the messages (one of the catalog languages, else English)")
+	asJSON := fs.Bool("json", false, "Print the catalog as JSON")
+	fs.Parse(args)
+	if !errcatalog.Supported(*lang) {
+		logger.Printf("No messages in %q, printing English (available: %v)", *lang, errcatalog.Languages())
+	}
+
+	if *asJSON {
+		type entry struct {
+			errcatalog.Entry
+			LocalizedMessage string `json:"localized_message"`
+		}
+		var entries []entry
+		for _, e := range errcatalog.Entries() {
+			entries = append(entries, entry{Entry: e, LocalizedMessage: errcatalog.Template(e.Code, *lang)})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			logger.Fatalf("FATAL: Failed to encode the catalog: %v", err)
+		}
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODE\tHTTP\tGRPC\tRETRYABLE\tMESSAGE")
+	for _, e := range errcatalog.Entries() {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%t\t%s\n", e.Code, e.HTTPStatus, e.GRPCCode, e.Retryable, errcatalog.Template(e.Code, *lang))
+	}
+	tw.Flush()
+}
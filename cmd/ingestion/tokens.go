@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"tlng/apitoken"
+	apiconfig "tlng/config"
+	"tlng/storage/store"
+)
+
+// runTokens mints an API token directly in the State DB, to bootstrap the
+// first admin token when no service identity can call /admin/tokens:
+//
+//	ingestion tokens mint -org org1 -scopes admin [-description ...] [-ttl 720h]
+func runTokens(args []string, logger *log.Logger) {
+	if len(args) == 0 || args[0] != "mint" {
+		logger.Fatal("FATAL: usage: ingestion tokens mint -org <org_id> -scopes <scope,...> [-description <text>] [-ttl <duration>]")
+	}
+	fs := flag.NewFlagSet("tokens mint", flag.ExitOnError)
+	configPath := fs.String("config", apiConfigPath, "Gateway configuration file (database and api_tokens)")
+	orgID := fs.String("org", "", "Org the token is bound to (required)")
+	scopes := fs.String("scopes", apitoken.ScopeAdmin, "Comma-separated scopes: submit, query, admin")
+	description := fs.String("description", "bootstrap", "Description shown when listing tokens")
+	ttl := fs.Duration("ttl", 0, "Validity (default: api_tokens.default_ttl)")
+	fs.Parse(args[1:])
+
+	cfg, err := apiconfig.LoadApiGatewayConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to load API Gateway configuration: %v", err)
+	}
+	if !cfg.APITokens.Enabled {
+		logger.Fatal("FATAL: api_tokens.enabled is false; the gateway would not accept the token")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	dbStore, err := store.NewPostgresStore(ctx, cfg.Database.DSN, 1, 1, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize database store: %v", err)
+	}
+	defer dbStore.Close()
+
+	manager := apitoken.NewManager(dbStore, cfg.APITokens, nil)
+	secret, t, err := manager.Mint(ctx, *orgID, *description, strings.Split(*scopes, ","), *ttl)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to mint api token: %v", err)
+	}
+	logger.Printf("Minted api token %s for org %s with scopes %v", t.TokenID, t.OrgID, t.Scopes)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(map[string]interface{}{
+		"token_id":   t.TokenID,
+		"org_id":     t.OrgID,
+		"scopes":     t.Scopes,
+		"expires_at": t.ExpiresAt.UTC().Format(time.RFC3339Nano),
+		"token":      secret,
+	})
+}
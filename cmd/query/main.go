@@ -10,8 +10,10 @@ import (
 	"syscall"
 	"time"
 
+	"tlng/apitoken"
 	blockchain "tlng/blockchain/client"
 	"tlng/config"
+	"tlng/internal/integrity"
 	"tlng/query/service/core"
 	queryhttp "tlng/query/service/http"
 	"tlng/storage/store"
@@ -65,6 +67,16 @@ func main() {
 	// 4. Create Query Service
 	logger.Println("Initializing query service...")
 	queryService := core.NewService(dbStore, bcClient, logger)
+	queryService.SetProofCache(queryCfg.ProofCache.Enabled)
+	if queryCfg.IntegrityMetadata {
+		queryService.SetIntegrityMetadata(integrity.ForStore(dbStore))
+	}
+	if queryCfg.GraphQL.Enabled {
+		if err := queryService.SetGraphQL(queryCfg.GraphQL.MaxDepth, queryCfg.GraphQL.MaxComplexity); err != nil {
+			logger.Fatalf("FATAL: Failed to enable GraphQL: %v", err)
+		}
+		logger.Printf("GraphQL enabled at /v1/graphql (max depth %d, max complexity %d)", queryCfg.GraphQL.MaxDepth, queryCfg.GraphQL.MaxComplexity)
+	}
 
 	// 5. Setup HTTP Server
 	logger.Println("Setting up HTTP server...")
@@ -72,6 +84,10 @@ func main() {
 
 	// Register query API routes
 	handler := queryhttp.NewHandler(queryService, logger)
+	if queryCfg.APITokens.Enabled {
+		handler.SetTokenVerifier(apitoken.NewVerifier(dbStore, queryCfg.APITokens.CacheTTL, queryCfg.APITokens.CacheSize, nil))
+		logger.Printf("API tokens with the query scope accepted, lookups cached for %v", queryCfg.APITokens.CacheTTL)
+	}
 	handler.RegisterRoutes(mux)
 
 	// Add health check endpoint
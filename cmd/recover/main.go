@@ -0,0 +1,133 @@
+// Command recover brings the State DB back in line with Kafka and the chain
+// after a point-in-time restore of PostgreSQL:
+//
+//	recover -restore-point time [-config path] [-dsn dsn] [-requeue]
+//
+// Given the time the database was restored to, it reports the Kafka offsets
+// and the anchoring transactions after that point, replays the submissions and
+// outcomes published since into the database, marks the submissions it finds
+// anchored on chain as COMPLETED, optionally requeues the ones the engine
+// consumed but never anchored, and lists the submissions that are lost.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/config"
+	worker "tlng/processing"
+	"tlng/storage/store"
+)
+
+const defaultConfigPath = "./config/engine.defaults.yml"
+
+func main() {
+	logger := log.New(os.Stderr, "[RECOVER] ", log.LstdFlags)
+	configPath := flag.String("config", defaultConfigPath, "Engine configuration file (Kafka, database and blockchain client)")
+	dsn := flag.String("dsn", "", "Restored State DB (default: database.dsn of the configuration)")
+	restorePoint := flag.String("restore-point", "", "RFC 3339 time the State DB was restored to (required)")
+	requeue := flag.Bool("requeue", false, "Publish the submissions the engine consumed but never anchored again")
+	batchSize := flag.Int("batch-size", 500, "Records written per database round trip")
+	flag.Parse()
+
+	if *restorePoint == "" {
+		logger.Fatal("FATAL: -restore-point is required")
+	}
+	at, err := time.Parse(time.RFC3339, *restorePoint)
+	if err != nil {
+		logger.Fatalf("FATAL: invalid -restore-point %q: %v", *restorePoint, err)
+	}
+
+	cfg, err := config.LoadEngineConfig(*configPath)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to load engine configuration: %v", err)
+	}
+	if len(cfg.KafkaConsumer.Brokers) == 0 || cfg.KafkaConsumer.Brokers[0] == "mock://local" {
+		logger.Fatal("FATAL: recover requires Kafka brokers in kafka_consumer")
+	}
+	if cfg.StatusEvents.Topic == "" {
+		logger.Println("Warning: status_events is not enabled, outcomes after the restore point are taken from the chain only and lost submissions cannot be listed")
+	}
+	tlsConfig, err := cfg.KafkaConsumer.TLS.TLSConfig()
+	if err != nil {
+		logger.Fatalf("FATAL: invalid Kafka TLS configuration: %v", err)
+	}
+	if *dsn == "" {
+		*dsn = cfg.Database.DSN
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbStore, err := store.NewPostgresStore(ctx, *dsn, 2, 4, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize database store: %v", err)
+	}
+	defer dbStore.Close()
+
+	client, err := blockchain.NewBlockchainClientFromFile(cfg.BlockchainClientConfigPath, logger)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to initialize blockchain client: %v", err)
+	}
+	defer client.Close()
+
+	topics := []worker.RecoveryTopic{{Name: cfg.Region.Topic(cfg.KafkaConsumer.Topic), GroupID: cfg.KafkaConsumer.GroupID}}
+	if cfg.SizeTier.Enabled {
+		topics = append(topics, worker.RecoveryTopic{Name: cfg.Region.Topic(cfg.SizeTier.Topic), GroupID: cfg.SizeTier.GroupID})
+	}
+	recovery := worker.NewRecovery(dbStore, client, cfg.KafkaConsumer.Brokers, tlsConfig, *batchSize, logger)
+	report, err := recovery.Recover(ctx, at, topics, cfg.StatusEvents.Topic, *requeue)
+	if err != nil {
+		logger.Fatalf("FATAL: %v", err)
+	}
+
+	printReport(report, *requeue)
+	if len(report.Lost) > 0 || report.Stats.Undecodable > 0 || (report.Stranded > 0 && !*requeue) {
+		os.Exit(1)
+	}
+}
+
+// printReport prints the recovery report
+func printReport(r *worker.RecoveryReport, requeue bool) {
+	fmt.Printf("Restore point:           %s\n", r.RestorePoint.Format(time.RFC3339))
+	fmt.Println("Kafka offsets after the restore point:")
+	for _, p := range r.Partitions {
+		note := ""
+		if p.RetentionGap {
+			note = "  (first retained message is newer: messages may have expired)"
+		}
+		fmt.Printf("  %s/%d: %d to %d, committed %d%s\n", p.Topic, p.Partition, p.Offset, p.EndOffset, p.Committed, note)
+	}
+	fmt.Println("Anchoring transactions after the restore point:")
+	for _, tx := range r.Transactions {
+		if tx.Error != "" {
+			fmt.Printf("  %s block %d: %d submissions, not readable on chain: %s\n", tx.TxHash, tx.BlockHeight, tx.Submissions, tx.Error)
+			continue
+		}
+		fmt.Printf("  %s block %d: %d submissions, %d entries on chain\n", tx.TxHash, tx.BlockHeight, tx.Submissions, tx.OnChain)
+	}
+	fmt.Printf("Log messages replayed:   %d\n", r.Stats.Messages)
+	fmt.Printf("Submissions inserted:    %d\n", r.Stats.Inserted)
+	fmt.Printf("Submissions existing:    %d\n", r.Stats.Existing)
+	fmt.Printf("Status events replayed:  %d\n", r.Stats.Events)
+	fmt.Printf("Outcomes written:        %d\n", r.Stats.Outcomes)
+	fmt.Printf("Reconciled from chain:   %d\n", r.Reconciled)
+	fmt.Printf("Pending in Kafka:        %d\n", r.Pending)
+	fmt.Printf("Stranded:                %d\n", r.Stranded)
+	if requeue {
+		fmt.Printf("Requeued:                %d\n", r.Requeued)
+	}
+	fmt.Printf("Unresolved:              %d\n", r.Unresolved)
+	fmt.Printf("Undecodable:             %d\n", r.Stats.Undecodable)
+	fmt.Printf("Lost:                    %d\n", len(r.Lost))
+	for _, l := range r.Lost {
+		fmt.Printf("  %s org=%s log_hash=%s received=%s\n", l.RequestID, l.SourceOrgID, l.LogHash, l.ReceivedAt.Format(time.RFC3339))
+	}
+}
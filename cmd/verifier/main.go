@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/config"
+	"tlng/storage/store"
+	"tlng/verifier/service/core"
+	verifierhttp "tlng/verifier/service/http"
+)
+
+const verifierConfigPath = "./config/verifier.defaults.yml"
+
+func main() {
+	logger := log.New(os.Stdout, "[VERIFIER] ", log.LstdFlags|log.Lshortfile)
+	logger.Println("Starting Verification Service...")
+
+	// 1. Load Verifier Config
+	verifierCfg, err := config.LoadVerifierConfig(verifierConfigPath)
+	if err != nil {
+		logger.Fatalf("FATAL: Failed to load verifier configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 2. Initialize the proof store (optional, read-only role)
+	var proofs core.ProofStore
+	if verifierCfg.Database.DSN != "" {
+		logger.Println("Initializing proof store connection...")
+		dbStore, err := store.NewPostgresStore(
+			ctx,
+			verifierCfg.Database.DSN,
+			verifierCfg.Database.MinConnections,
+			verifierCfg.Database.MaxConnections,
+			logger,
+		)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize proof store: %v", err)
+		}
+		defer dbStore.Close()
+		proofs = dbStore
+	} else {
+		logger.Println("No database configured; verifying against the chains only, without evidence.")
+	}
+
+	// 3. Initialize Blockchain Clients
+	chains := make([]core.Chain, 0, len(verifierCfg.Chains))
+	for _, chainCfg := range verifierCfg.Chains {
+		logger.Printf("Initializing blockchain client %s...", chainCfg.Name)
+		client, err := blockchain.NewBlockchainClientFromFile(chainCfg.ConfigPath, logger)
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to initialize blockchain client %s: %v", chainCfg.Name, err)
+		}
+		defer client.Close()
+		chain := core.NewChain(chainCfg.Name, client)
+		logger.Printf("Chain %s: %s network %s", chain.Name, chain.Type, chain.NetworkID)
+		chains = append(chains, chain)
+	}
+
+	// 4. Setup HTTP Server
+	service := core.NewService(chains, proofs, logger)
+	mux := http.NewServeMux()
+	verifierhttp.NewHandler(service, logger).RegisterRoutes(mux)
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	server := &http.Server{
+		Addr:         verifierCfg.HTTPListenAddr,
+		Handler:      mux,
+		ReadTimeout:  verifierCfg.ReadTimeout,
+		WriteTimeout: verifierCfg.WriteTimeout,
+		IdleTimeout:  verifierCfg.IdleTimeout,
+	}
+	if verifierCfg.TLS.Enabled {
+		server.TLSConfig, err = verifierCfg.TLS.TLSConfig()
+		if err != nil {
+			logger.Fatalf("FATAL: Failed to load TLS configuration: %v", err)
+		}
+	}
+
+	// 5. Start HTTP Server in goroutine
+	go func() {
+		logger.Printf("Verification Service listening on %s (TLS %v, client certificates required %v)",
+			verifierCfg.HTTPListenAddr, verifierCfg.TLS.Enabled, verifierCfg.TLS.RequireClientCert)
+		if verifierCfg.TLS.Enabled {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("FATAL: HTTP server error: %v", err)
+		}
+	}()
+
+	logger.Println("Verification Service started successfully. Press Ctrl+C to stop.")
+
+	// 6. Graceful Shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Println("Received shutdown signal, initiating graceful shutdown...")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("WARNING: HTTP server shutdown error: %v", err)
+	}
+
+	cancel()
+
+	logger.Println("Verification Service shut down gracefully.")
+}
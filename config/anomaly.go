@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// AnomalyDetectionConfig defines the gateway's detection of unusual ingestion
+// patterns. Submissions are counted per org over windows of Interval and
+// compared with a rolling baseline (an exponentially weighted average over
+// about BaselineWindows windows). An org that stops submitting, bursts far
+// above its baseline, or retries an unusual share of its submissions is
+// flagged in metrics, in the log and, if configured, with a webhook.
+type AnomalyDetectionConfig struct {
+	Enabled            bool          `yaml:"enabled"`              // Enable anomaly detection
+	Interval           time.Duration `yaml:"interval"`             // Length of a counting window
+	BaselineWindows    int           `yaml:"baseline_windows"`     // Span of the rolling baseline, in windows
+	WarmupWindows      int           `yaml:"warmup_windows"`       // Windows observed before an org's baseline is trusted
+	MinBaseline        float64       `yaml:"min_baseline"`         // Baseline submissions per window below which silence and bursts are not flagged
+	ZeroWindows        int           `yaml:"zero_windows"`         // Consecutive empty windows that flag an org as silent
+	BurstFactor        float64       `yaml:"burst_factor"`         // Submissions per window above this multiple of the baseline flag a burst
+	DuplicateRate      float64       `yaml:"duplicate_rate"`       // Fraction of duplicate submissions in a window that is flagged
+	MinDuplicateSample int64         `yaml:"min_duplicate_sample"` // Submissions a window needs before its duplicate rate is checked
+	MaxOrgs            int           `yaml:"max_orgs"`             // Orgs tracked at once; submissions of further orgs are not tracked
+	Webhook            WebhookConfig `yaml:"webhook"`              // Optional notification of each anomaly
+}
+
+// WebhookConfig defines an HTTP endpoint notified with a JSON POST
+type WebhookConfig struct {
+	URL     string        `yaml:"url"`     // Endpoint; empty disables notifications
+	Timeout time.Duration `yaml:"timeout"` // Timeout of a notification request
+	Secret  string        `yaml:"secret"`  // Signs the body with HMAC-SHA256 in X-TLNG-Signature; falls back to ANOMALY_WEBHOOK_SECRET
+}
+
+// SetDefaults sets reasonable default values for anomaly detection
+func (c *AnomalyDetectionConfig) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = time.Minute
+		fmt.Printf("Warning: anomaly_detection.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.BaselineWindows == 0 {
+		c.BaselineWindows = 60
+		fmt.Printf("Warning: anomaly_detection.baseline_windows not set, defaulting to %d\n", c.BaselineWindows)
+	}
+	if c.WarmupWindows == 0 {
+		c.WarmupWindows = 10
+		fmt.Printf("Warning: anomaly_detection.warmup_windows not set, defaulting to %d\n", c.WarmupWindows)
+	}
+	if c.MinBaseline == 0 {
+		c.MinBaseline = 10
+		fmt.Printf("Warning: anomaly_detection.min_baseline not set, defaulting to %g\n", c.MinBaseline)
+	}
+	if c.ZeroWindows == 0 {
+		c.ZeroWindows = 3
+		fmt.Printf("Warning: anomaly_detection.zero_windows not set, defaulting to %d\n", c.ZeroWindows)
+	}
+	if c.BurstFactor == 0 {
+		c.BurstFactor = 5
+		fmt.Printf("Warning: anomaly_detection.burst_factor not set, defaulting to %g\n", c.BurstFactor)
+	}
+	if c.DuplicateRate == 0 {
+		c.DuplicateRate = 0.5
+		fmt.Printf("Warning: anomaly_detection.duplicate_rate not set, defaulting to %g\n", c.DuplicateRate)
+	}
+	if c.MinDuplicateSample == 0 {
+		c.MinDuplicateSample = 20
+		fmt.Printf("Warning: anomaly_detection.min_duplicate_sample not set, defaulting to %d\n", c.MinDuplicateSample)
+	}
+	if c.MaxOrgs == 0 {
+		c.MaxOrgs = 10000
+		fmt.Printf("Warning: anomaly_detection.max_orgs not set, defaulting to %d\n", c.MaxOrgs)
+	}
+	if c.Webhook.URL != "" {
+		if c.Webhook.Timeout == 0 {
+			c.Webhook.Timeout = 5 * time.Second
+			fmt.Printf("Warning: anomaly_detection.webhook.timeout not set, defaulting to %v\n", c.Webhook.Timeout)
+		}
+		if c.Webhook.Secret == "" {
+			c.Webhook.Secret = os.Getenv("ANOMALY_WEBHOOK_SECRET")
+		}
+	}
+}
+
+// Validate validates the anomaly detection configuration
+func (c *AnomalyDetectionConfig) Validate() error {
+	if c.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+	if c.BaselineWindows < 1 {
+		return fmt.Errorf("baseline_windows must be positive")
+	}
+	if c.WarmupWindows < 1 {
+		return fmt.Errorf("warmup_windows must be positive")
+	}
+	if c.MinBaseline < 1 {
+		return fmt.Errorf("min_baseline must be at least 1")
+	}
+	if c.ZeroWindows < 1 {
+		return fmt.Errorf("zero_windows must be positive")
+	}
+	if c.BurstFactor <= 1 {
+		return fmt.Errorf("burst_factor must be greater than 1")
+	}
+	if c.DuplicateRate <= 0 || c.DuplicateRate > 1 {
+		return fmt.Errorf("duplicate_rate must be in (0, 1]")
+	}
+	if c.MinDuplicateSample < 1 {
+		return fmt.Errorf("min_duplicate_sample must be positive")
+	}
+	if c.MaxOrgs < 1 {
+		return fmt.Errorf("max_orgs must be positive")
+	}
+	if c.Webhook.URL != "" {
+		u, err := url.Parse(c.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook.url must be an http or https URL")
+		}
+		if c.Webhook.Timeout <= 0 {
+			return fmt.Errorf("webhook.timeout must be positive")
+		}
+	}
+	return nil
+}
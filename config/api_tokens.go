@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// APITokensConfig enables org-scoped API tokens: admins mint, list, rotate and
+// revoke them through the gateway's /admin/tokens API, and callers present
+// them as bearer tokens. Tokens carry scopes (submit, query, admin) and an
+// expiry. Verified tokens are cached for cache_ttl, so a revocation on one
+// instance reaches the others within that time. The minting settings only
+// apply to the gateway; the query service only verifies tokens.
+type APITokensConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	DefaultTTL    time.Duration `yaml:"default_ttl"`    // Lifetime of tokens minted without one
+	MaxTTL        time.Duration `yaml:"max_ttl"`        // Longest lifetime a token may be minted with
+	RotationGrace time.Duration `yaml:"rotation_grace"` // How long a rotated token keeps working beside its replacement
+	CacheTTL      time.Duration `yaml:"cache_ttl"`      // How long token lookups are cached
+	CacheSize     int           `yaml:"cache_size"`     // Most tokens cached (LRU); as many unknown tokens are cached apart
+}
+
+// SetDefaults sets reasonable default values for API tokens
+func (c *APITokensConfig) SetDefaults() {
+	if c.DefaultTTL == 0 {
+		c.DefaultTTL = 90 * 24 * time.Hour
+		fmt.Printf("Warning: api_tokens.default_ttl not set, defaulting to %v\n", c.DefaultTTL)
+	}
+	if c.MaxTTL == 0 {
+		c.MaxTTL = 365 * 24 * time.Hour
+		fmt.Printf("Warning: api_tokens.max_ttl not set, defaulting to %v\n", c.MaxTTL)
+	}
+	if c.RotationGrace == 0 {
+		c.RotationGrace = time.Hour
+		fmt.Printf("Warning: api_tokens.rotation_grace not set, defaulting to %v\n", c.RotationGrace)
+	}
+	if c.CacheTTL == 0 {
+		c.CacheTTL = 30 * time.Second
+		fmt.Printf("Warning: api_tokens.cache_ttl not set, defaulting to %v\n", c.CacheTTL)
+	}
+	if c.CacheSize == 0 {
+		c.CacheSize = 10000
+		fmt.Printf("Warning: api_tokens.cache_size not set, defaulting to %d\n", c.CacheSize)
+	}
+}
+
+// Validate validates the API token configuration
+func (c *APITokensConfig) Validate() error {
+	if c.DefaultTTL < 0 || c.MaxTTL < 0 || c.RotationGrace < 0 || c.CacheTTL < 0 {
+		return fmt.Errorf("default_ttl, max_ttl, rotation_grace and cache_ttl must not be negative")
+	}
+	if c.DefaultTTL > c.MaxTTL {
+		return fmt.Errorf("default_ttl (%v) must not exceed max_ttl (%v)", c.DefaultTTL, c.MaxTTL)
+	}
+	if c.CacheSize < 1 {
+		return fmt.Errorf("cache_size (%d) must be at least 1", c.CacheSize)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ArchiveConfig defines how raw submissions are grouped into archive objects
+type ArchiveConfig struct {
+	FlushInterval time.Duration `yaml:"flush_interval"` // Maximum time records are buffered before upload
+	MaxRecords    int           `yaml:"max_records"`    // Upload once this many records are buffered
+	MaxBytes      int           `yaml:"max_bytes"`      // Upload once this many uncompressed bytes are buffered
+	Compression   string        `yaml:"compression"`    // gzip, zstd or none
+	RetryBackoff  time.Duration `yaml:"retry_backoff"`  // Delay between failed upload attempts
+}
+
+// SetDefaults sets reasonable default values for archive configuration
+func (c *ArchiveConfig) SetDefaults() {
+	if c.FlushInterval == 0 {
+		c.FlushInterval = time.Minute
+		fmt.Printf("Warning: archive.flush_interval not set, defaulting to %v\n", c.FlushInterval)
+	}
+	if c.MaxRecords == 0 {
+		c.MaxRecords = 50000
+		fmt.Printf("Warning: archive.max_records not set, defaulting to %d\n", c.MaxRecords)
+	}
+	if c.MaxBytes == 0 {
+		c.MaxBytes = 64 * 1024 * 1024
+		fmt.Printf("Warning: archive.max_bytes not set, defaulting to %d\n", c.MaxBytes)
+	}
+	if c.Compression == "" {
+		c.Compression = "gzip"
+		fmt.Printf("Warning: archive.compression not set, defaulting to %s\n", c.Compression)
+	}
+	if c.RetryBackoff == 0 {
+		c.RetryBackoff = 5 * time.Second
+		fmt.Printf("Warning: archive.retry_backoff not set, defaulting to %v\n", c.RetryBackoff)
+	}
+}
+
+// Validate validates the archive configuration
+func (c *ArchiveConfig) Validate() error {
+	switch c.Compression {
+	case "gzip", "zstd", "none":
+	default:
+		return fmt.Errorf("invalid compression '%s' (must be gzip, zstd or none)", c.Compression)
+	}
+	if c.MaxRecords < 0 || c.MaxBytes < 0 {
+		return fmt.Errorf("max_records and max_bytes must not be negative")
+	}
+	return nil
+}
+
+// ExportConfig defines the periodic Parquet export of completed attestation records
+type ExportConfig struct {
+	Enabled     bool          `yaml:"enabled"`      // Enable the Parquet export (requires database)
+	Name        string        `yaml:"name"`         // Export name; keys the saved cursor in the database
+	Interval    time.Duration `yaml:"interval"`     // Time between export runs
+	MaxRows     int           `yaml:"max_rows"`     // Maximum rows per Parquet file
+	SettleDelay time.Duration `yaml:"settle_delay"` // Only export records finished at least this long ago
+	Compression string        `yaml:"compression"`  // zstd, gzip or none
+	Prefix      string        `yaml:"prefix"`       // Object key prefix, replaces object_store.prefix for exports
+}
+
+// SetDefaults sets reasonable default values for export configuration
+func (c *ExportConfig) SetDefaults() {
+	if c.Name == "" {
+		c.Name = "parquet-attestations"
+		fmt.Printf("Warning: export.name not set, defaulting to %s\n", c.Name)
+	}
+	if c.Interval == 0 {
+		c.Interval = 15 * time.Minute
+		fmt.Printf("Warning: export.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.MaxRows == 0 {
+		c.MaxRows = 100000
+		fmt.Printf("Warning: export.max_rows not set, defaulting to %d\n", c.MaxRows)
+	}
+	if c.SettleDelay == 0 {
+		c.SettleDelay = time.Minute
+		fmt.Printf("Warning: export.settle_delay not set, defaulting to %v\n", c.SettleDelay)
+	}
+	if c.Compression == "" {
+		c.Compression = "zstd"
+		fmt.Printf("Warning: export.compression not set, defaulting to %s\n", c.Compression)
+	}
+	if c.Prefix == "" {
+		c.Prefix = "attestations"
+		fmt.Printf("Warning: export.prefix not set, defaulting to %s\n", c.Prefix)
+	}
+}
+
+// Validate validates the export configuration
+func (c *ExportConfig) Validate() error {
+	switch c.Compression {
+	case "zstd", "gzip", "none":
+	default:
+		return fmt.Errorf("invalid compression '%s' (must be zstd, gzip or none)", c.Compression)
+	}
+	if c.Interval <= 0 || c.MaxRows <= 0 || c.SettleDelay < 0 {
+		return fmt.Errorf("interval and max_rows must be positive and settle_delay must not be negative")
+	}
+	return nil
+}
+
+// ArchiverConfig defines all configuration for the Archiver service, which
+// consumes the submission topic and retains raw log content in object storage
+type ArchiverConfig struct {
+	KafkaConsumer KafkaConsumerConfig `yaml:"kafka_consumer"`
+	ObjectStore   ObjectStoreConfig   `yaml:"object_store"`
+	Archive       ArchiveConfig       `yaml:"archive"`
+	Region        RegionConfig        `yaml:"region"`
+	LargeTopic    string              `yaml:"large_topic"` // Also archive the gateway's size-tier topic; empty if size tiers are disabled
+
+	// Parquet export of completed attestations; the database is only used when enabled
+	Database DatabaseConfig `yaml:"database"`
+	Export   ExportConfig   `yaml:"export"`
+
+	SecurityProfile SecurityProfile `yaml:"security_profile"` // strict or lenient; see SecurityViolations
+}
+
+// LoadArchiverConfig loads configuration from the specified YAML file path
+func LoadArchiverConfig(path string) (*ArchiverConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archiver config file '%s': %w", path, err)
+	}
+
+	var cfg ArchiverConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse archiver YAML config file: %w", err)
+	}
+	if err := ApplyEnvOverrides(EnvPrefix, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
+
+	cfg.KafkaConsumer.SetDefaults()
+	cfg.KafkaConsumer.TopicCheck.SetDefaults("kafka_consumer")
+	cfg.ObjectStore.SetDefaults("object_store")
+	cfg.Archive.SetDefaults()
+	cfg.SecurityProfile.SetDefaults()
+	if cfg.Export.Enabled {
+		cfg.Export.SetDefaults()
+		cfg.Database.SetDefaults()
+	}
+
+	if len(cfg.KafkaConsumer.Brokers) == 0 || cfg.KafkaConsumer.Topic == "" || cfg.KafkaConsumer.GroupID == "" {
+		return nil, fmt.Errorf("kafka_consumer configuration error: brokers, topic and group_id are required")
+	}
+	if err := cfg.KafkaConsumer.TopicCheck.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_consumer configuration error: %w", err)
+	}
+	if err := cfg.KafkaConsumer.TLS.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_consumer configuration error: %w", err)
+	}
+	if err := cfg.ObjectStore.Validate(); err != nil {
+		return nil, fmt.Errorf("object_store configuration error: %w", err)
+	}
+	if err := cfg.Archive.Validate(); err != nil {
+		return nil, fmt.Errorf("archive configuration error: %w", err)
+	}
+	if err := cfg.Region.Validate(); err != nil {
+		return nil, fmt.Errorf("region configuration error: %w", err)
+	}
+	if cfg.Export.Enabled {
+		if err := cfg.Export.Validate(); err != nil {
+			return nil, fmt.Errorf("export configuration error: %w", err)
+		}
+		if err := cfg.Database.Validate(); err != nil {
+			return nil, fmt.Errorf("database configuration error: %w", err)
+		}
+	}
+	if err := cfg.SecurityProfile.Validate(); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+	if err := cfg.SecurityProfile.Check(cfg.SecurityViolations()); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+
+	return &cfg, nil
+}
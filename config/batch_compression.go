@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+)
+
+// BatchCompressionConfig defines the compression of batch payloads sent to
+// contracts that accept compressed payloads; disabled when Algorithm is empty
+// or "none"
+type BatchCompressionConfig struct {
+	Algorithm string `yaml:"algorithm"` // "none", "gzip" or "zstd"
+	MinBytes  int    `yaml:"min_bytes"` // Payloads smaller than this are sent uncompressed
+	ParamKey  string `yaml:"param_key"` // ChainMaker parameter carrying the payload encoding
+}
+
+// Enabled reports whether batch payloads are compressed
+func (c *BatchCompressionConfig) Enabled() bool {
+	return c.Algorithm != "" && c.Algorithm != "none"
+}
+
+// SetDefaults sets the default threshold and flag parameter when compression is enabled
+func (c *BatchCompressionConfig) SetDefaults() {
+	if !c.Enabled() {
+		return
+	}
+	if c.MinBytes <= 0 {
+		c.MinBytes = 1024
+		fmt.Printf("Warning: batch_compression.min_bytes not set, defaulting to %d\n", c.MinBytes)
+	}
+	if c.ParamKey == "" {
+		c.ParamKey = "logs_encoding"
+		fmt.Printf("Warning: batch_compression.param_key not set, defaulting to '%s'\n", c.ParamKey)
+	}
+}
+
+// Validate validates the algorithm
+func (c *BatchCompressionConfig) Validate() error {
+	switch c.Algorithm {
+	case "", "none", "gzip", "zstd":
+		return nil
+	}
+	return fmt.Errorf("algorithm must be 'none', 'gzip' or 'zstd', got '%s'", c.Algorithm)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// BatchTuningConfig defines adaptive batching in the engine. Workers observe
+// how long chain submissions take and how fast messages arrive, and adjust
+// their batch timeout and size within the bounds so that logs are anchored
+// within TargetLatency of reaching the gateway. Bigger batches are cheaper per
+// log, so workers wait as long as the target allows.
+type BatchTuningConfig struct {
+	Enabled         bool          `yaml:"enabled"`           // Tune worker.batch_timeout and worker.batch_size at runtime
+	TargetLatency   time.Duration `yaml:"target_latency"`    // End-to-end SLO: gateway receive -> on chain
+	MinBatchTimeout time.Duration `yaml:"min_batch_timeout"` // Lower bound of the batch timeout
+	MaxBatchTimeout time.Duration `yaml:"max_batch_timeout"` // Upper bound of the batch timeout
+	MinBatchSize    int           `yaml:"min_batch_size"`    // Lower bound of the batch size
+	MaxBatchSize    int           `yaml:"max_batch_size"`    // Upper bound of the batch size (the chain's transaction limit)
+	Interval        time.Duration `yaml:"interval"`          // How often the values are recomputed
+}
+
+// SetDefaults sets reasonable default values for batch tuning; the batch size
+// is bounded by the configured worker batch size unless set
+func (c *BatchTuningConfig) SetDefaults(batchSize int) {
+	if c.TargetLatency <= 0 {
+		c.TargetLatency = 10 * time.Second
+		fmt.Printf("Warning: batch_tuning.target_latency not set, defaulting to %v\n", c.TargetLatency)
+	}
+	if c.MinBatchTimeout <= 0 {
+		c.MinBatchTimeout = 50 * time.Millisecond
+		fmt.Printf("Warning: batch_tuning.min_batch_timeout not set, defaulting to %v\n", c.MinBatchTimeout)
+	}
+	if c.MaxBatchTimeout <= 0 {
+		c.MaxBatchTimeout = 5 * time.Second
+		fmt.Printf("Warning: batch_tuning.max_batch_timeout not set, defaulting to %v\n", c.MaxBatchTimeout)
+	}
+	if c.MinBatchSize <= 0 {
+		c.MinBatchSize = 1
+		fmt.Printf("Warning: batch_tuning.min_batch_size not set, defaulting to %d\n", c.MinBatchSize)
+	}
+	if c.MaxBatchSize <= 0 {
+		c.MaxBatchSize = batchSize
+		fmt.Printf("Warning: batch_tuning.max_batch_size not set, defaulting to worker.batch_size (%d)\n", c.MaxBatchSize)
+	}
+	if c.Interval <= 0 {
+		c.Interval = 10 * time.Second
+		fmt.Printf("Warning: batch_tuning.interval not set, defaulting to %v\n", c.Interval)
+	}
+}
+
+// Validate validates the batch tuning bounds
+func (c *BatchTuningConfig) Validate() error {
+	if c.MinBatchTimeout > c.MaxBatchTimeout {
+		return fmt.Errorf("min_batch_timeout %v exceeds max_batch_timeout %v", c.MinBatchTimeout, c.MaxBatchTimeout)
+	}
+	if c.MinBatchSize > c.MaxBatchSize {
+		return fmt.Errorf("min_batch_size %d exceeds max_batch_size %d", c.MinBatchSize, c.MaxBatchSize)
+	}
+	if c.MinBatchTimeout >= c.TargetLatency {
+		return fmt.Errorf("min_batch_timeout %v leaves no room within target_latency %v", c.MinBatchTimeout, c.TargetLatency)
+	}
+	return nil
+}
@@ -11,12 +11,14 @@ import (
 // BlockchainConfig stores common blockchain configuration across all blockchain types
 type BlockchainConfig struct {
 	// --- Blockchain Type Selection ---
-	BlockchainType string `yaml:"blockchain_type"` // "chainmaker", "ethereum", etc.
+	BlockchainType string `yaml:"blockchain_type"` // "chainmaker", "hyperledger_fabric", etc.
 
 	// --- Common Behavior Configuration ---
 	RetryLimit    int `yaml:"retry_limit"`
 	RetryInterval int `yaml:"retry_interval"`
 	TimeoutSeconds int `yaml:"timeout_seconds"`
+	SingleCallThreshold int `yaml:"single_call_threshold"` // Batches with fewer entries are anchored with one SubmitLog call per entry; 0 always uses SubmitLogsBatch
+	BatchCompression BatchCompressionConfig `yaml:"batch_compression"` // Compression of SubmitLogsBatch payloads
 
 	// --- Chain-specific Configuration ---
 	// This will be loaded separately based on blockchain type
@@ -42,6 +44,13 @@ func LoadBlockchainConfig(path string) (*BlockchainConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML config file: %w", err)
 	}
+	if err := ApplyEnvOverrides(EnvPrefix, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
+	cfg.BatchCompression.SetDefaults()
+	if err := cfg.BatchCompression.Validate(); err != nil {
+		return nil, fmt.Errorf("batch_compression configuration error: %w", err)
+	}
 
 	fmt.Println("Blockchain configuration loaded successfully.")
 	return &cfg, nil
@@ -0,0 +1,54 @@
+package config
+
+import "fmt"
+
+// CanaryConfig routes a percentage of the API Gateway's submissions to an
+// alternate Kafka topic, consumed by a canary engine deployment running a new
+// worker or contract version. Submissions are split by request ID, or by org
+// so that each org only ever reaches one version. Large submissions (size_tier)
+// are not split. Both tracks report their outcomes by track label (see
+// EngineConfig.Track) so the canary can be compared before a full rollout.
+type CanaryConfig struct {
+	Enabled bool    `yaml:"enabled"`
+	Percent float64 `yaml:"percent"`  // Share of submissions routed to the canary, 0-100 in steps of 0.01
+	Topic   string  `yaml:"topic"`    // Kafka topic consumed by the canary engine
+	SplitBy string  `yaml:"split_by"` // request or org
+}
+
+// Canary split keys
+const (
+	CanarySplitByRequest = "request" // Each submission is routed on its own
+	CanarySplitByOrg     = "org"     // All submissions of an org take the same track
+)
+
+// Deployment tracks, the track label of gateway and engine metrics
+const (
+	TrackStable = "stable"
+	TrackCanary = "canary"
+)
+
+// SetDefaults sets reasonable default values for canary routing
+func (c *CanaryConfig) SetDefaults() {
+	if c.Topic == "" {
+		c.Topic = "log_submissions_canary"
+		fmt.Printf("Warning: canary.topic not set, defaulting to %s\n", c.Topic)
+	}
+	if c.SplitBy == "" {
+		c.SplitBy = CanarySplitByRequest
+		fmt.Printf("Warning: canary.split_by not set, defaulting to %s\n", c.SplitBy)
+	}
+}
+
+// Validate validates the canary configuration against the topics already in use
+func (c *CanaryConfig) Validate(mainTopic string) error {
+	if c.Percent < 0 || c.Percent > 100 {
+		return fmt.Errorf("percent (%v) must be between 0 and 100", c.Percent)
+	}
+	if c.SplitBy != CanarySplitByRequest && c.SplitBy != CanarySplitByOrg {
+		return fmt.Errorf("invalid split_by '%s' (expected %s or %s)", c.SplitBy, CanarySplitByRequest, CanarySplitByOrg)
+	}
+	if c.Topic == mainTopic {
+		return fmt.Errorf("topic must differ from kafka_producer.topic (%s)", mainTopic)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ClickHouseConfig defines the optional ClickHouse sink for status transition analytics
+type ClickHouseConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // Enable the ClickHouse sink
+	URL           string        `yaml:"url"`            // ClickHouse HTTP interface, e.g. http://clickhouse:8123
+	Database      string        `yaml:"database"`       // Target database
+	Table         string        `yaml:"table"`          // Target table for status events
+	Username      string        `yaml:"username"`       // Falls back to CLICKHOUSE_USER
+	Password      string        `yaml:"password"`       // Falls back to CLICKHOUSE_PASSWORD
+	CreateTable   bool          `yaml:"create_table"`   // Create the table at startup if missing
+	BatchSize     int           `yaml:"batch_size"`     // Insert once this many events are buffered
+	FlushInterval time.Duration `yaml:"flush_interval"` // Maximum time events are buffered before insert
+	BufferSize    int           `yaml:"buffer_size"`    // Event batches queued before new events are dropped
+	Timeout       time.Duration `yaml:"timeout"`        // Timeout per insert request
+	MaxRetries    int           `yaml:"max_retries"`    // Insert attempts before a batch is dropped
+}
+
+// SetDefaults sets reasonable default values for ClickHouse configuration
+func (c *ClickHouseConfig) SetDefaults() {
+	if c.Database == "" {
+		c.Database = "default"
+		fmt.Printf("Warning: clickhouse.database not set, defaulting to %s\n", c.Database)
+	}
+	if c.Table == "" {
+		c.Table = "log_status_events"
+		fmt.Printf("Warning: clickhouse.table not set, defaulting to %s\n", c.Table)
+	}
+	if c.Username == "" {
+		c.Username = os.Getenv("CLICKHOUSE_USER")
+	}
+	if c.Password == "" {
+		c.Password = os.Getenv("CLICKHOUSE_PASSWORD")
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 10000
+		fmt.Printf("Warning: clickhouse.batch_size not set or invalid, defaulting to %d\n", c.BatchSize)
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = 5 * time.Second
+		fmt.Printf("Warning: clickhouse.flush_interval not set, defaulting to %v\n", c.FlushInterval)
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 1024
+		fmt.Printf("Warning: clickhouse.buffer_size not set or invalid, defaulting to %d\n", c.BufferSize)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+		fmt.Printf("Warning: clickhouse.timeout not set, defaulting to %v\n", c.Timeout)
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 3
+		fmt.Printf("Warning: clickhouse.max_retries not set or invalid, defaulting to %d\n", c.MaxRetries)
+	}
+}
+
+// Validate validates the ClickHouse configuration
+func (c *ClickHouseConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("clickhouse url is required when the sink is enabled")
+	}
+	if !isIdentifier(c.Database) || !isIdentifier(c.Table) {
+		return fmt.Errorf("clickhouse database and table must be plain identifiers")
+	}
+	return nil
+}
+
+// isIdentifier reports whether s is safe to embed unquoted in SQL
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
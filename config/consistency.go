@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConsistencyCheckConfig defines the engine's continuous check that Kafka, the
+// State DB and the chain agree on recent submissions. Every Interval it
+// samples submissions received between Window and MinAge ago, looks each one
+// up on chain and classifies any divergence. With Repair, submissions found
+// on chain but not recorded as COMPLETED are completed.
+type ConsistencyCheckConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // Sample recent submissions and check them across Kafka, DB and chain
+	Interval   time.Duration `yaml:"interval"`    // How often a sample is checked
+	SampleSize int           `yaml:"sample_size"` // Submissions checked per run
+	Window     time.Duration `yaml:"window"`      // How far back submissions are sampled
+	MinAge     time.Duration `yaml:"min_age"`     // Submissions younger than this are still in flight and not sampled
+	Repair     bool          `yaml:"repair"`      // Complete submissions found on chain but not COMPLETED in the DB
+}
+
+// SetDefaults sets reasonable default values for the consistency check
+func (c *ConsistencyCheckConfig) SetDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+		fmt.Printf("Warning: consistency_check.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.SampleSize <= 0 {
+		c.SampleSize = 50
+		fmt.Printf("Warning: consistency_check.sample_size not set, defaulting to %d\n", c.SampleSize)
+	}
+	if c.Window <= 0 {
+		c.Window = time.Hour
+		fmt.Printf("Warning: consistency_check.window not set, defaulting to %v\n", c.Window)
+	}
+	if c.MinAge <= 0 {
+		c.MinAge = 5 * time.Minute
+		fmt.Printf("Warning: consistency_check.min_age not set, defaulting to %v\n", c.MinAge)
+	}
+}
+
+// Validate validates the sampling window
+func (c *ConsistencyCheckConfig) Validate() error {
+	if c.MinAge >= c.Window {
+		return fmt.Errorf("min_age (%v) must be below window (%v)", c.MinAge, c.Window)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DebugCaptureConfig defines the capture of raw requests of rejected
+// submissions, for orgs that opted in, so support can reproduce client bugs
+type DebugCaptureConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Orgs          []string      `yaml:"orgs"`           // Orgs whose rejected submissions may be captured
+	SampleRate    float64       `yaml:"sample_rate"`    // Fraction of rejected submissions captured, in (0, 1]
+	MaxPerMinute  int           `yaml:"max_per_minute"` // Captures per org per minute on each gateway instance
+	MaxBytes      int           `yaml:"max_bytes"`      // Longer requests are captured truncated
+	TTL           time.Duration `yaml:"ttl"`            // How long captures are kept
+	PurgeInterval time.Duration `yaml:"purge_interval"` // How often expired captures are deleted
+	KeyFile       string        `yaml:"key_file"`       // 32-byte AES-256 key (raw or hex) encrypting the stored payloads
+}
+
+// SetDefaults sets reasonable default values for debug capture
+func (c *DebugCaptureConfig) SetDefaults() {
+	if c.SampleRate == 0 {
+		c.SampleRate = 0.1
+		fmt.Printf("Warning: debug_capture.sample_rate not set, defaulting to %g\n", c.SampleRate)
+	}
+	if c.MaxPerMinute <= 0 {
+		c.MaxPerMinute = 10
+		fmt.Printf("Warning: debug_capture.max_per_minute not set, defaulting to %d\n", c.MaxPerMinute)
+	}
+	if c.MaxBytes <= 0 {
+		c.MaxBytes = 1 << 20
+		fmt.Printf("Warning: debug_capture.max_bytes not set, defaulting to %d\n", c.MaxBytes)
+	}
+	if c.TTL <= 0 {
+		c.TTL = 72 * time.Hour
+		fmt.Printf("Warning: debug_capture.ttl not set, defaulting to %v\n", c.TTL)
+	}
+	if c.PurgeInterval <= 0 {
+		c.PurgeInterval = 10 * time.Minute
+		fmt.Printf("Warning: debug_capture.purge_interval not set, defaulting to %v\n", c.PurgeInterval)
+	}
+}
+
+// Validate validates the debug capture settings
+func (c *DebugCaptureConfig) Validate() error {
+	if len(c.Orgs) == 0 {
+		return fmt.Errorf("orgs must list the orgs that opted in")
+	}
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return fmt.Errorf("sample_rate must be in (0, 1], got %g", c.SampleRate)
+	}
+	if c.KeyFile == "" {
+		return fmt.Errorf("key_file is required: captured payloads are stored encrypted")
+	}
+	return nil
+}
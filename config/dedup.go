@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DedupConfig defines the gateway's duplicate window: submissions repeating the
+// org, content hash and client timestamp of one accepted within TTL get the
+// original request_id back instead of being enqueued again. It absorbs rapid
+// client retries (e.g. an HTTP timeout followed by a resend); submissions
+// carrying an idempotency key are deduplicated by the key instead.
+type DedupConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // Enable the duplicate window
+	TTL        time.Duration `yaml:"ttl"`         // How long an accepted submission is remembered
+	MaxEntries int           `yaml:"max_entries"` // Oldest entries are evicted beyond this many
+}
+
+// SetDefaults sets reasonable default values for the duplicate window
+func (c *DedupConfig) SetDefaults() {
+	if c.TTL == 0 {
+		c.TTL = 10 * time.Second
+		fmt.Printf("Warning: dedup.ttl not set, defaulting to %v\n", c.TTL)
+	}
+	if c.MaxEntries == 0 {
+		c.MaxEntries = 100000
+		fmt.Printf("Warning: dedup.max_entries not set, defaulting to %d\n", c.MaxEntries)
+	}
+}
+
+// Validate validates the duplicate window configuration
+func (c *DedupConfig) Validate() error {
+	if c.TTL < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
+	if c.MaxEntries < 0 {
+		return fmt.Errorf("max_entries must not be negative")
+	}
+	return nil
+}
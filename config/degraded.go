@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Degraded acceptance policies, applied when Kafka publishing fails persistently
+const (
+	DegradedPolicyDrop   = "drop"   // Keep accepting; submissions whose publish failed stay RECEIVED and are not processed
+	DegradedPolicyReject = "reject" // Reject submissions with 503 / UNAVAILABLE until Kafka is tried again
+	DegradedPolicySpool  = "spool"  // Keep accepting; messages are queued in the State DB (QUEUED_LOCAL) and relayed later
+)
+
+// DegradedAcceptanceConfig defines how the gateway behaves when Kafka
+// publishing fails persistently. After FailureThreshold consecutive failed
+// batch publishes the gateway is degraded for Cooldown: with "reject" it
+// refuses submissions, with "spool" it queues their messages in the State DB
+// without trying Kafka. A relay publishes spooled messages once Kafka is back.
+type DegradedAcceptanceConfig struct {
+	Policy           string        `yaml:"policy"`            // drop (default), reject or spool
+	FailureThreshold int           `yaml:"failure_threshold"` // Consecutive failed batch publishes before degrading
+	Cooldown         time.Duration `yaml:"cooldown"`          // How long the gateway stays degraded before trying Kafka again
+	RelayInterval    time.Duration `yaml:"relay_interval"`    // How often the relay publishes spooled messages
+	RelayBatchSize   int           `yaml:"relay_batch_size"`  // Spooled messages published per relay batch
+	RelayLease       time.Duration `yaml:"relay_lease"`       // After this long, messages claimed by a crashed relay are relayed again
+}
+
+// SetDefaults sets reasonable default values for degraded acceptance
+func (c *DegradedAcceptanceConfig) SetDefaults() {
+	if c.Policy == "" {
+		c.Policy = DegradedPolicyDrop
+		fmt.Printf("Warning: degraded_acceptance.policy not set, defaulting to %s\n", c.Policy)
+	}
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 3
+		fmt.Printf("Warning: degraded_acceptance.failure_threshold not set, defaulting to %d\n", c.FailureThreshold)
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = 30 * time.Second
+		fmt.Printf("Warning: degraded_acceptance.cooldown not set, defaulting to %v\n", c.Cooldown)
+	}
+	if c.RelayInterval == 0 {
+		c.RelayInterval = 5 * time.Second
+		fmt.Printf("Warning: degraded_acceptance.relay_interval not set, defaulting to %v\n", c.RelayInterval)
+	}
+	if c.RelayBatchSize == 0 {
+		c.RelayBatchSize = 500
+		fmt.Printf("Warning: degraded_acceptance.relay_batch_size not set, defaulting to %d\n", c.RelayBatchSize)
+	}
+	if c.RelayLease == 0 {
+		c.RelayLease = time.Minute
+		fmt.Printf("Warning: degraded_acceptance.relay_lease not set, defaulting to %v\n", c.RelayLease)
+	}
+}
+
+// Validate validates the degraded acceptance configuration
+func (c *DegradedAcceptanceConfig) Validate() error {
+	switch c.Policy {
+	case DegradedPolicyDrop, DegradedPolicyReject, DegradedPolicySpool:
+	default:
+		return fmt.Errorf("unknown policy '%s' (want drop, reject or spool)", c.Policy)
+	}
+	if c.FailureThreshold <= 0 {
+		return fmt.Errorf("failure_threshold must be positive")
+	}
+	if c.Cooldown < time.Second {
+		return fmt.Errorf("cooldown must be at least 1s")
+	}
+	if c.RelayInterval <= 0 {
+		return fmt.Errorf("relay_interval must be positive")
+	}
+	if c.RelayBatchSize <= 0 {
+		return fmt.Errorf("relay_batch_size must be positive")
+	}
+	if c.RelayLease <= 0 {
+		return fmt.Errorf("relay_lease must be positive")
+	}
+	return nil
+}
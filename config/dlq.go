@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DLQConfig defines the optional dead-letter topic of permanently failed
+// tasks, on the kafka_consumer brokers. Each message holds the original log
+// message with the failure reason in its headers.
+type DLQConfig struct {
+	Enabled      bool          `yaml:"enabled"`       // Publish failed tasks to the dead-letter topic
+	Topic        string        `yaml:"topic"`         // Dead-letter topic
+	WriteTimeout time.Duration `yaml:"write_timeout"` // Timeout per batch write; a failed batch is only logged
+}
+
+// SetDefaults sets reasonable default values for the dead-letter queue
+func (c *DLQConfig) SetDefaults() {
+	if c.Topic == "" {
+		c.Topic = "log_dlq"
+		fmt.Printf("Warning: dlq.topic not set, defaulting to %s\n", c.Topic)
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+		fmt.Printf("Warning: dlq.write_timeout not set, defaulting to %v\n", c.WriteTimeout)
+	}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
+
+	"tlng/svcauth"
 
 	"gopkg.in/yaml.v2"
 )
@@ -18,6 +21,12 @@ type KafkaConsumerConfig struct {
 	MaxProcessingTime string   `yaml:"max_processing_time"` // Maximum time for processing a message
 	AutoOffsetReset   string   `yaml:"auto_offset_reset"`   // earliest/latest
 	EnableAutoCommit  bool     `yaml:"enable_auto_commit"`  // Enable auto offset commit
+	SecondaryBrokers  []string `yaml:"secondary_brokers"`   // Optional DR cluster consumed alongside the primary
+	MaxUncommitted    int      `yaml:"max_uncommitted"`     // Fetched but uncommitted messages at which fetching pauses; 0 disables the bound
+	RedeliveryDelay   string   `yaml:"redelivery_delay"`    // Pause before nacked messages are refetched once the bound is reached
+
+	TopicCheck KafkaTopicConfig `yaml:"topic_check"` // Startup topic verification
+	TLS        KafkaTLSConfig   `yaml:"tls"`         // TLS to the brokers (also used for secondary_brokers)
 }
 
 // SetDefaults sets reasonable default values for Kafka consumer configuration
@@ -42,17 +51,31 @@ func (c *KafkaConsumerConfig) SetDefaults() {
 		c.AutoOffsetReset = "earliest"
 		fmt.Printf("Warning: kafka_consumer.auto_offset_reset not set, defaulting to %s\n", c.AutoOffsetReset)
 	}
+	if c.MaxUncommitted > 0 && c.RedeliveryDelay == "" {
+		c.RedeliveryDelay = "5s"
+		fmt.Printf("Warning: kafka_consumer.redelivery_delay not set, defaulting to %s\n", c.RedeliveryDelay)
+	}
 }
 
 // WorkerConfig defines configuration for worker processing
 type WorkerConfig struct {
-	Concurrency       int    `yaml:"concurrency"`        // Number of concurrent workers per consumer
+	Concurrency       int    `yaml:"concurrency"`        // Number of concurrent workers per pipeline (per consumer by default)
 	BatchSize         int    `yaml:"batch_size"`         // Number of logs per batch for blockchain
 	BatchTimeout      string `yaml:"batch_timeout"`      // Maximum wait time for batch
 	ConsumerRetryDelay string `yaml:"consumer_retry_delay"` // Delay when consumer encounters errors
 	BlockchainTimeout string `yaml:"blockchain_timeout"` // Timeout for blockchain operations
+	PreBatch          bool   `yaml:"pre_batch"`          // Accumulate the next batch while the current one awaits the chain
+	Pipelines         int    `yaml:"pipelines"`          // Worker pipelines sharing one work queue fed by all consumers; 0 gives each consumer its own
+	QueueSize         int    `yaml:"queue_size"`         // Capacity of the shared work queue (with pipelines)
+	Sharding          string `yaml:"sharding"`           // none, or hash_range: each pipeline gets its own log hash range (with pipelines)
 }
 
+// Worker sharding modes
+const (
+	ShardingNone      = "none"
+	ShardingHashRange = "hash_range" // One batching goroutine per pipeline, each reading one log hash range
+)
+
 // SetDefaults sets reasonable default values for worker configuration
 func (c *WorkerConfig) SetDefaults() {
 	if c.BatchSize <= 0 {
@@ -71,13 +94,48 @@ func (c *WorkerConfig) SetDefaults() {
 		c.BlockchainTimeout = "15s"
 		fmt.Printf("Warning: worker.blockchain_timeout not set, defaulting to %s\n", c.BlockchainTimeout)
 	}
+	if c.Pipelines > 0 && c.QueueSize <= 0 {
+		c.QueueSize = c.BatchSize
+		fmt.Printf("Warning: worker.queue_size not set, defaulting to %d\n", c.QueueSize)
+	}
+	if c.Sharding == "" {
+		c.Sharding = ShardingNone
+	}
+	if c.Sharding == ShardingHashRange && c.Concurrency != 1 {
+		fmt.Printf("Warning: worker.concurrency is 1 with sharding %s, ignoring %d; scale with worker.pipelines\n", c.Sharding, c.Concurrency)
+		c.Concurrency = 1
+	}
+}
+
+// Validate validates the worker topology
+func (c *WorkerConfig) Validate() error {
+	if c.Pipelines < 0 {
+		return fmt.Errorf("pipelines must not be negative")
+	}
+	if c.QueueSize < 0 {
+		return fmt.Errorf("queue_size must not be negative")
+	}
+	switch c.Sharding {
+	case ShardingNone:
+	case ShardingHashRange:
+		if c.Pipelines == 0 {
+			return fmt.Errorf("sharding %s needs pipelines", c.Sharding)
+		}
+	default:
+		return fmt.Errorf("invalid sharding '%s' (must be %s or %s)", c.Sharding, ShardingNone, ShardingHashRange)
+	}
+	return nil
 }
 
 // EngineMonitoringConfig defines monitoring configuration for engine
 type EngineMonitoringConfig struct {
+	ListenAddr      string `yaml:"listen_addr"`       // Monitoring HTTP server address; empty disables the server
+	AdminGRPCAddr   string `yaml:"admin_grpc_addr"`   // Admin gRPC server address; empty disables the server
 	EnableMetrics   bool   `yaml:"enable_metrics"`    // Enable metrics collection
 	MetricsPath     string `yaml:"metrics_path"`      // Metrics endpoint path
-	HealthCheckPath string `yaml:"health_check_path"` // Health check endpoint path
+	HealthCheckPath string `yaml:"health_check_path"` // Liveness endpoint path
+	ReadinessPath   string `yaml:"readiness_path"`    // Readiness endpoint path
+	StatusPath      string `yaml:"status_path"`       // Debug status page path
 	LogLevel        string `yaml:"log_level"`         // Logging level
 }
 
@@ -88,9 +146,17 @@ func (c *EngineMonitoringConfig) SetDefaults() {
 		fmt.Printf("Warning: monitoring.metrics_path not set, defaulting to %s\n", c.MetricsPath)
 	}
 	if c.HealthCheckPath == "" {
-		c.HealthCheckPath = "/health"
+		c.HealthCheckPath = "/healthz"
 		fmt.Printf("Warning: monitoring.health_check_path not set, defaulting to %s\n", c.HealthCheckPath)
 	}
+	if c.ReadinessPath == "" {
+		c.ReadinessPath = "/readyz"
+		fmt.Printf("Warning: monitoring.readiness_path not set, defaulting to %s\n", c.ReadinessPath)
+	}
+	if c.StatusPath == "" {
+		c.StatusPath = "/debug/status"
+		fmt.Printf("Warning: monitoring.status_path not set, defaulting to %s\n", c.StatusPath)
+	}
 	if c.LogLevel == "" {
 		c.LogLevel = "info"
 		fmt.Printf("Warning: monitoring.log_level not set, defaulting to %s\n", c.LogLevel)
@@ -105,9 +171,21 @@ type EngineConfig struct {
 	// Kafka Consumer Configuration
 	KafkaConsumer KafkaConsumerConfig `yaml:"kafka_consumer"`
 
+	// Message Queue Selection (Kafka, or NATS JetStream under messaging.nats)
+	Messaging MessagingConfig `yaml:"messaging"`
+
 	// Worker Configuration
 	Worker WorkerConfig `yaml:"worker"`
 
+	// Batch Tuning Configuration (adaptive worker batch timeout and size)
+	BatchTuning BatchTuningConfig `yaml:"batch_tuning"`
+
+	// Priority Lanes Configuration (batches ordered by the message priority header)
+	PriorityLanes PriorityLanesConfig `yaml:"priority_lanes"`
+
+	// Replay Guard Configuration (redelivered messages of terminal tasks acked after startup)
+	ReplayGuard ReplayGuardConfig `yaml:"replay_guard"`
+
 	// Business Rules Configuration
 	MaxTaskRetries int `yaml:"max_task_retries"` // Maximum retry attempts per task (business rule)
 
@@ -116,6 +194,66 @@ type EngineConfig struct {
 
 	// Blockchain Client Configuration
 	BlockchainClientConfigPath string `yaml:"blockchain_client_config_path"`
+
+	// Region Configuration (cross-region active-active deployments)
+	Region RegionConfig `yaml:"region"`
+
+	// ClickHouse Sink Configuration (optional status analytics)
+	ClickHouse ClickHouseConfig `yaml:"clickhouse"`
+
+	// Status Events Configuration (optional Kafka topic of protobuf status transitions)
+	StatusEvents StatusEventsConfig `yaml:"status_events"`
+
+	// Dead-Letter Queue Configuration (optional Kafka topic of permanently failed tasks)
+	DLQ DLQConfig `yaml:"dlq"`
+
+	// Heartbeat Configuration (fleet registration and reclaiming tasks of dead instances)
+	Heartbeat HeartbeatConfig `yaml:"heartbeat"`
+
+	// Stuck Tasks Configuration (recovery of tasks left PROCESSING by a crashed worker)
+	StuckTasks StuckTasksConfig `yaml:"stuck_tasks"`
+
+	// Consistency Check Configuration (sampled agreement of Kafka, DB and chain, with repairs)
+	ConsistencyCheck ConsistencyCheckConfig `yaml:"consistency_check"`
+
+	// Startup Configuration (dependency wait and self-checks at boot)
+	Startup StartupConfig `yaml:"startup"`
+
+	// Shutdown Configuration (graceful shutdown budget)
+	Shutdown ShutdownConfig `yaml:"shutdown"`
+
+	// Error Handling Configuration (retry or fail tasks by the kind of blockchain error)
+	ErrorHandling ErrorHandlingConfig `yaml:"error_handling"`
+
+	// Read-Only Mode Configuration (defer anchoring while the State DB is read-only during failover)
+	ReadOnlyMode ReadOnlyModeConfig `yaml:"read_only_mode"`
+
+	// Size Tier Configuration (dedicated worker pool for large submissions)
+	SizeTier SizeTierWorkerConfig `yaml:"size_tier"`
+
+	// Tracing Configuration (OpenTelemetry spans exported over OTLP)
+	Tracing TracingConfig `yaml:"tracing"`
+
+	// Proof Cache Configuration (cache attestation proofs for the query service)
+	ProofCache ProofCacheConfig `yaml:"proof_cache"`
+
+	// Merkle Anchoring Configuration (anchor one Merkle root per batch)
+	MerkleAnchoring MerkleAnchoringConfig `yaml:"merkle_anchoring"`
+
+	// Deployment track (stable, or canary for an engine consuming the gateway's canary topic)
+	Track string `yaml:"track"`
+
+	// Routing Configuration (per-org blockchain targets)
+	Routing RoutingConfig `yaml:"routing"`
+
+	// Security Profile (strict or lenient; see SecurityViolations)
+	SecurityProfile SecurityProfile `yaml:"security_profile"`
+
+	// Service Authentication (SPIFFE or OIDC callers of the admin gRPC server)
+	ServiceAuth svcauth.Config `yaml:"service_auth"`
+
+	// File the configuration was loaded from (set by LoadEngineConfig)
+	SourcePath string `yaml:"-"`
 }
 
 // LoadEngineConfig loads configuration from the specified YAML file path
@@ -130,12 +268,30 @@ func LoadEngineConfig(path string) (*EngineConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML config file: %w", err)
 	}
+	if err := ApplyEnvOverrides(EnvPrefix, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
 
 	// Set default values for all configurations
 	cfg.Database.SetDefaults()
 	cfg.KafkaConsumer.SetDefaults()
+	cfg.KafkaConsumer.TopicCheck.SetDefaults("kafka_consumer")
 	cfg.Worker.SetDefaults()
 	cfg.Monitoring.SetDefaults()
+	cfg.Startup.SetDefaults()
+	cfg.Shutdown.SetDefaults(30*time.Second, 5*time.Second)
+	cfg.ErrorHandling.SetDefaults()
+	cfg.SecurityProfile.SetDefaults()
+	cfg.ServiceAuth.SetDefaults()
+	cfg.Messaging.SetDefaults()
+	if cfg.SizeTier.Enabled {
+		cfg.SizeTier.SetDefaults(cfg.KafkaConsumer.GroupID)
+	}
+
+	if cfg.Track == "" {
+		cfg.Track = TrackStable
+		fmt.Printf("Warning: track not set, defaulting to %s\n", cfg.Track)
+	}
 
 	// Set default for business rules
 	if cfg.MaxTaskRetries <= 0 {
@@ -148,5 +304,170 @@ func LoadEngineConfig(path string) (*EngineConfig, error) {
 		return nil, fmt.Errorf("database configuration error: %w", err)
 	}
 
+	// Validate Kafka topic verification configuration
+	if err := cfg.KafkaConsumer.TopicCheck.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_consumer configuration error: %w", err)
+	}
+
+	// Validate Kafka TLS configuration
+	if err := cfg.KafkaConsumer.TLS.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_consumer configuration error: %w", err)
+	}
+
+	// Validate the message queue; NATS has no counterpart of the extra Kafka topics and clusters
+	if err := cfg.Messaging.Validate(); err != nil {
+		return nil, fmt.Errorf("messaging configuration error: %w", err)
+	}
+	if cfg.Messaging.UsesNATS() && (cfg.SizeTier.Enabled || len(cfg.KafkaConsumer.SecondaryBrokers) > 0) {
+		return nil, fmt.Errorf("messaging configuration error: size_tier and kafka_consumer.secondary_brokers require type kafka")
+	}
+
+	// Validate the worker topology
+	if err := cfg.Worker.Validate(); err != nil {
+		return nil, fmt.Errorf("worker configuration error: %w", err)
+	}
+
+	// Validate region configuration
+	if err := cfg.Region.Validate(); err != nil {
+		return nil, fmt.Errorf("region configuration error: %w", err)
+	}
+	// A Merkle root hides its logs' hashes from the contract, so logs two regions anchor at once would both land
+	if cfg.Region.Reconcile && cfg.MerkleAnchoring.Enabled {
+		return nil, fmt.Errorf("region configuration error: region.reconcile cannot be combined with merkle_anchoring.enabled")
+	}
+
+	// Validate the startup sequence
+	if err := cfg.Startup.Validate(); err != nil {
+		return nil, fmt.Errorf("startup configuration error: %w", err)
+	}
+
+	// Validate graceful shutdown
+	if err := cfg.Shutdown.Validate(); err != nil {
+		return nil, fmt.Errorf("shutdown configuration error: %w", err)
+	}
+
+	// Validate blockchain error handling
+	if err := cfg.ErrorHandling.Validate(); err != nil {
+		return nil, fmt.Errorf("error_handling configuration error: %w", err)
+	}
+
+	// Validate per-org routing
+	if err := cfg.Routing.Validate(); err != nil {
+		return nil, fmt.Errorf("routing configuration error: %w", err)
+	}
+
+	// Validate batch tuning bounds
+	if cfg.BatchTuning.Enabled {
+		cfg.BatchTuning.SetDefaults(cfg.Worker.BatchSize)
+		if err := cfg.BatchTuning.Validate(); err != nil {
+			return nil, fmt.Errorf("batch_tuning configuration error: %w", err)
+		}
+	}
+
+	// Validate priority lanes
+	if cfg.PriorityLanes.Enabled {
+		cfg.PriorityLanes.SetDefaults(cfg.Worker.BatchSize)
+		batchTimeout, err := time.ParseDuration(cfg.Worker.BatchTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("worker configuration error: invalid batch_timeout '%s': %w", cfg.Worker.BatchTimeout, err)
+		}
+		if err := cfg.PriorityLanes.Validate(cfg.Worker.BatchSize, batchTimeout); err != nil {
+			return nil, fmt.Errorf("priority_lanes configuration error: %w", err)
+		}
+	}
+
+	// Validate the replay guard
+	if cfg.ReplayGuard.Enabled {
+		cfg.ReplayGuard.SetDefaults(cfg.Worker.BatchSize)
+		batchTimeout, err := time.ParseDuration(cfg.Worker.BatchTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("worker configuration error: invalid batch_timeout '%s': %w", cfg.Worker.BatchTimeout, err)
+		}
+		if err := cfg.ReplayGuard.Validate(batchTimeout); err != nil {
+			return nil, fmt.Errorf("replay_guard configuration error: %w", err)
+		}
+	}
+
+	// Validate ClickHouse sink configuration
+	if cfg.ClickHouse.Enabled {
+		cfg.ClickHouse.SetDefaults()
+		if err := cfg.ClickHouse.Validate(); err != nil {
+			return nil, fmt.Errorf("clickhouse configuration error: %w", err)
+		}
+	}
+
+	// Set defaults for status event publishing
+	if cfg.StatusEvents.Enabled {
+		cfg.StatusEvents.SetDefaults()
+	}
+
+	// Set defaults for the dead-letter queue
+	if cfg.DLQ.Enabled {
+		cfg.DLQ.SetDefaults()
+	}
+
+	// Validate the heartbeat
+	if cfg.Heartbeat.Enabled {
+		cfg.Heartbeat.SetDefaults()
+		if err := cfg.Heartbeat.Validate(); err != nil {
+			return nil, fmt.Errorf("heartbeat configuration error: %w", err)
+		}
+	}
+
+	// Validate the stuck task scan
+	if cfg.StuckTasks.Enabled {
+		cfg.StuckTasks.SetDefaults()
+		blockchainTimeout, err := time.ParseDuration(cfg.Worker.BlockchainTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("worker configuration error: invalid blockchain_timeout '%s': %w", cfg.Worker.BlockchainTimeout, err)
+		}
+		if err := cfg.StuckTasks.Validate(blockchainTimeout); err != nil {
+			return nil, fmt.Errorf("stuck_tasks configuration error: %w", err)
+		}
+	}
+
+	// Validate the consistency check
+	if cfg.ConsistencyCheck.Enabled {
+		cfg.ConsistencyCheck.SetDefaults()
+		if err := cfg.ConsistencyCheck.Validate(); err != nil {
+			return nil, fmt.Errorf("consistency_check configuration error: %w", err)
+		}
+	}
+
+	// Set defaults for Merkle-root anchoring
+	if cfg.MerkleAnchoring.Enabled {
+		cfg.MerkleAnchoring.SetDefaults()
+	}
+
+	// Validate read-only mode
+	if cfg.ReadOnlyMode.Enabled {
+		cfg.ReadOnlyMode.SetDefaults()
+		if err := cfg.ReadOnlyMode.Validate(); err != nil {
+			return nil, fmt.Errorf("read_only_mode configuration error: %w", err)
+		}
+	}
+
+	// Validate tracing
+	if cfg.Tracing.Enabled {
+		cfg.Tracing.SetDefaults("attestation-engine")
+		if err := cfg.Tracing.Validate(); err != nil {
+			return nil, fmt.Errorf("tracing configuration error: %w", err)
+		}
+	}
+
+	// Validate service-to-service authentication
+	if err := cfg.ServiceAuth.Validate(); err != nil {
+		return nil, fmt.Errorf("service_auth configuration error: %w", err)
+	}
+
+	// Evaluate the security profile last, against the complete configuration
+	if err := cfg.SecurityProfile.Validate(); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+	if err := cfg.SecurityProfile.Check(cfg.SecurityViolations()); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+
+	cfg.SourcePath = path
 	return &cfg, nil
 }
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix prefixes the environment variables that override YAML settings
+const EnvPrefix = "TLNG"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ApplyEnvOverrides overrides fields of cfg, a pointer to a configuration
+// struct, from environment variables. A field's variable is the prefix and its
+// YAML path in upper case joined by underscores: database.dsn is
+// TLNG_DATABASE_DSN and kafka_consumer.brokers is TLNG_KAFKA_CONSUMER_BROKERS.
+// Strings, booleans, numbers, durations ("30s") and comma-separated string
+// lists can be overridden; maps and lists of objects cannot. Overrides are
+// applied before defaults and validation, so they are checked like YAML values.
+func ApplyEnvOverrides(prefix string, cfg any) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("environment overrides need a pointer to a struct, got %T", cfg)
+	}
+	_, err := applyEnvStruct(v.Elem(), prefix, "")
+	return err
+}
+
+// applyEnvStruct overrides the fields of the struct v and reports whether any was set
+func applyEnvStruct(v reflect.Value, envName, path string) (bool, error) {
+	set := false
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, inline := yamlFieldName(field)
+		if name == "-" {
+			continue
+		}
+		fieldEnv, fieldPath := envName, path
+		if !inline {
+			fieldEnv = envName + "_" + strings.ToUpper(name)
+			fieldPath = joinYAMLPath(path, name)
+		}
+		ok, err := applyEnvValue(v.Field(i), fieldEnv, fieldPath)
+		if err != nil {
+			return false, err
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+// applyEnvValue overrides a single field and reports whether it was set
+func applyEnvValue(v reflect.Value, envName, path string) (bool, error) {
+	switch {
+	case v.Kind() == reflect.Struct:
+		return applyEnvStruct(v, envName, path)
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+		// Only allocate an unset section when a variable actually fills it
+		target := v
+		if v.IsNil() {
+			target = reflect.New(v.Type().Elem())
+		}
+		ok, err := applyEnvStruct(target.Elem(), envName, path)
+		if ok && v.IsNil() {
+			v.Set(target)
+		}
+		return ok, err
+	}
+
+	raw, ok := os.LookupEnv(envName)
+	if !ok {
+		return false, nil
+	}
+	if err := setFromEnv(v, strings.TrimSpace(raw)); err != nil {
+		return false, fmt.Errorf("%s (%s): %w", envName, path, err)
+	}
+	fmt.Printf("Config: %s overridden by %s\n", path, envName)
+	return true, nil
+}
+
+// setFromEnv parses raw into v according to its type
+func setFromEnv(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", raw, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("lists of %s cannot be set from the environment", v.Type().Elem())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			list.Index(i).SetString(item)
+		}
+		v.Set(list)
+	default:
+		return fmt.Errorf("%s fields cannot be set from the environment", v.Type())
+	}
+	return nil
+}
+
+// yamlFieldName returns the YAML key of a field, the way yaml.v2 derives it
+func yamlFieldName(field reflect.StructField) (name string, inline bool) {
+	tag := field.Tag.Get("yaml")
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "-" {
+		return name, false
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "inline" {
+			return "", true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
+
+func joinYAMLPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"tlng/blockchain/types"
+)
+
+// ErrorHandlingConfig defines how the engine reacts to failed blockchain
+// submissions, by the kind the error is classified as (see types.ChainError):
+// permanent kinds fail the tasks at once, the others are retried with backoff
+type ErrorHandlingConfig struct {
+	PermanentKinds  []string          `yaml:"permanent_kinds"`   // Error kinds that mark tasks FAILED without retrying
+	Rules           []ErrorRuleConfig `yaml:"rules"`             // Reclassify errors by their message; the first match wins
+	RetryBackoff    time.Duration     `yaml:"retry_backoff"`     // Pause of a worker after a transient failure, doubled per consecutive failure
+	MaxRetryBackoff time.Duration     `yaml:"max_retry_backoff"` // Upper bound of the pause
+}
+
+// ErrorRuleConfig reclassifies the errors whose message contains a text, for
+// chain or SDK errors the client does not classify, or classifies differently
+type ErrorRuleConfig struct {
+	Contains string `yaml:"contains"` // Case-insensitive substring of the error message
+	Kind     string `yaml:"kind"`     // chain_unavailable, timeout, tx_rejected or contract_validation
+}
+
+// SetDefaults sets reasonable default values for error handling
+func (c *ErrorHandlingConfig) SetDefaults() {
+	if c.PermanentKinds == nil {
+		c.PermanentKinds = []string{types.KindTxRejected, types.KindContractValidation}
+		fmt.Printf("Warning: error_handling.permanent_kinds not set, defaulting to %v\n", c.PermanentKinds)
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = time.Second
+		fmt.Printf("Warning: error_handling.retry_backoff not set, defaulting to %v\n", c.RetryBackoff)
+	}
+	if c.MaxRetryBackoff <= 0 {
+		c.MaxRetryBackoff = time.Minute
+		fmt.Printf("Warning: error_handling.max_retry_backoff not set, defaulting to %v\n", c.MaxRetryBackoff)
+	}
+}
+
+// Validate validates the error kinds and rules
+func (c *ErrorHandlingConfig) Validate() error {
+	for _, kind := range c.PermanentKinds {
+		if !validErrorKind(kind) && kind != types.KindUnclassified {
+			return fmt.Errorf("invalid permanent kind '%s'", kind)
+		}
+	}
+	for i, rule := range c.Rules {
+		if rule.Contains == "" {
+			return fmt.Errorf("rule %d: contains is required", i)
+		}
+		if !validErrorKind(rule.Kind) {
+			return fmt.Errorf("rule %d: invalid kind '%s'", i, rule.Kind)
+		}
+	}
+	if c.MaxRetryBackoff < c.RetryBackoff {
+		return fmt.Errorf("max_retry_backoff (%v) must not be less than retry_backoff (%v)", c.MaxRetryBackoff, c.RetryBackoff)
+	}
+	return nil
+}
+
+// validErrorKind reports whether kind names a kind of the error taxonomy
+func validErrorKind(kind string) bool {
+	switch kind {
+	case types.KindChainUnavailable, types.KindTimeout, types.KindTxRejected, types.KindContractValidation:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ConfigFingerprintConfig defines how the gateway anchors the fingerprint of
+// its sanitized configuration and binary version on chain, as a regular
+// submission of a system org
+type ConfigFingerprintConfig struct {
+	Enabled     bool          `yaml:"enabled"`     // Submit a fingerprint record at startup and every interval
+	Interval    time.Duration `yaml:"interval"`    // How often the fingerprint is anchored again
+	OrgID       string        `yaml:"org_id"`      // Org the records are submitted under
+	Application string        `yaml:"application"` // Application field of the records, to find them with /v1/logs/search
+}
+
+// SetDefaults sets reasonable default values for fingerprint anchoring
+func (c *ConfigFingerprintConfig) SetDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = time.Hour
+		fmt.Printf("Warning: config_fingerprint.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.OrgID == "" {
+		c.OrgID = "tlng-system"
+		fmt.Printf("Warning: config_fingerprint.org_id not set, defaulting to %s\n", c.OrgID)
+	}
+	if c.Application == "" {
+		c.Application = "tlng-gateway"
+		fmt.Printf("Warning: config_fingerprint.application not set, defaulting to %s\n", c.Application)
+	}
+}
+
+// Validate validates the fingerprint anchoring settings
+func (c *ConfigFingerprintConfig) Validate() error {
+	if c.Interval < time.Minute {
+		return fmt.Errorf("interval (%v) must be at least 1m", c.Interval)
+	}
+	return nil
+}
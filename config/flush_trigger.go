@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// FlushTriggerConfig defines the queue-depth-driven flush trigger of the
+// gateway batch processors. Every CheckInterval it looks at the batches queued
+// for the State DB and Kafka and at the Kafka writer's load: while the writer
+// is idle a partial batch of IdleMinEntries or more is flushed early, while
+// batches back up the timer flush is postponed by up to BackpressureDelay so
+// they grow instead of piling up, and above MemoryHighWatermark the buffer is
+// flushed at once.
+type FlushTriggerConfig struct {
+	Enabled                bool          `yaml:"enabled"`                  // Enable the flush trigger
+	CheckInterval          time.Duration `yaml:"check_interval"`           // How often the queue depth and writer load are checked
+	IdleMinEntries         int           `yaml:"idle_min_entries"`         // Buffered entries flushed early while the writer is idle
+	BackpressureQueueDepth int           `yaml:"backpressure_queue_depth"` // Batches queued or in flight from which the writer counts as backpressured
+	BackpressureDelay      time.Duration `yaml:"backpressure_delay"`       // Longest postponement of a timer flush under backpressure
+	MemoryHighWatermark    int64         `yaml:"memory_high_watermark"`    // Heap bytes in use above which the buffer is flushed at once; 0 disables the check
+}
+
+// SetDefaults sets default values derived from the batch size and timeout
+func (c *FlushTriggerConfig) SetDefaults(batchSize int, batchTimeout time.Duration) {
+	if c.CheckInterval == 0 {
+		c.CheckInterval = max(batchTimeout/4, 5*time.Millisecond)
+		fmt.Printf("Warning: batch_processor.flush_trigger.check_interval not set, defaulting to %v\n", c.CheckInterval)
+	}
+	if c.IdleMinEntries == 0 {
+		c.IdleMinEntries = max(batchSize/4, 1)
+		fmt.Printf("Warning: batch_processor.flush_trigger.idle_min_entries not set, defaulting to %d\n", c.IdleMinEntries)
+	}
+	if c.BackpressureQueueDepth == 0 {
+		c.BackpressureQueueDepth = 2
+		fmt.Printf("Warning: batch_processor.flush_trigger.backpressure_queue_depth not set, defaulting to %d\n", c.BackpressureQueueDepth)
+	}
+	if c.BackpressureDelay == 0 {
+		c.BackpressureDelay = batchTimeout / 2
+		fmt.Printf("Warning: batch_processor.flush_trigger.backpressure_delay not set, defaulting to %v\n", c.BackpressureDelay)
+	}
+}
+
+// Validate validates the flush trigger configuration
+func (c *FlushTriggerConfig) Validate() error {
+	if c.CheckInterval <= 0 {
+		return fmt.Errorf("check_interval must be positive")
+	}
+	if c.IdleMinEntries < 1 {
+		return fmt.Errorf("idle_min_entries must be at least 1")
+	}
+	if c.BackpressureQueueDepth < 1 {
+		return fmt.Errorf("backpressure_queue_depth must be at least 1")
+	}
+	if c.BackpressureDelay < 0 {
+		return fmt.Errorf("backpressure_delay must not be negative")
+	}
+	if c.MemoryHighWatermark < 0 {
+		return fmt.Errorf("memory_high_watermark must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// GapDetectionConfig defines the detection of gaps in sequenced sources. Clients
+// may number the logs of a source (org, source host and application) from 1;
+// every interval the gateway records the numbers still missing grace after the
+// higher numbers arrived, so logs deleted at the source become visible.
+type GapDetectionConfig struct {
+	Enabled  bool          `yaml:"enabled"`  // Record and report missing sequence numbers
+	Interval time.Duration `yaml:"interval"` // How often new sequenced submissions are checked
+	Grace    time.Duration `yaml:"grace"`    // How long a number may arrive out of order before it is recorded as missing
+}
+
+// SetDefaults sets reasonable default values for gap detection
+func (c *GapDetectionConfig) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = time.Minute
+		fmt.Printf("Warning: gap_detection.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.Grace == 0 {
+		c.Grace = 5 * time.Minute
+		fmt.Printf("Warning: gap_detection.grace not set, defaulting to %v\n", c.Grace)
+	}
+}
+
+// Validate validates the gap detection settings
+func (c *GapDetectionConfig) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if c.Grace < 0 {
+		return fmt.Errorf("grace must not be negative")
+	}
+	return nil
+}
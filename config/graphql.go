@@ -0,0 +1,37 @@
+package config
+
+import "fmt"
+
+// GraphQLConfig enables the query service's GraphQL endpoint (POST /v1/graphql):
+// ad-hoc queries joining status, batches and proofs, scoped to the caller's
+// org. Queries deeper than max_depth are rejected before they run. A field
+// that queries the State DB costs 10, plus 1 per requested item for list
+// fields, and a query's lookups stop once they cost more than max_complexity.
+type GraphQLConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	MaxDepth      int  `yaml:"max_depth"`      // Deepest field nesting; top-level fields are at depth 1
+	MaxComplexity int  `yaml:"max_complexity"` // Highest total cost of a query's lookups
+}
+
+// SetDefaults sets reasonable default values for GraphQL
+func (c *GraphQLConfig) SetDefaults() {
+	if c.MaxDepth == 0 {
+		c.MaxDepth = 8
+		fmt.Printf("Warning: graphql.max_depth not set, defaulting to %d\n", c.MaxDepth)
+	}
+	if c.MaxComplexity == 0 {
+		c.MaxComplexity = 5000
+		fmt.Printf("Warning: graphql.max_complexity not set, defaulting to %d\n", c.MaxComplexity)
+	}
+}
+
+// Validate validates the GraphQL configuration
+func (c *GraphQLConfig) Validate() error {
+	if c.MaxDepth < 1 {
+		return fmt.Errorf("max_depth (%d) must be at least 1", c.MaxDepth)
+	}
+	if c.MaxComplexity < 1 {
+		return fmt.Errorf("max_complexity (%d) must be at least 1", c.MaxComplexity)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// HeartbeatConfig defines how an engine instance registers itself in the
+// State DB fleet table and detects instances that died while processing
+type HeartbeatConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // Register the instance in tbl_worker_instances and refresh its heartbeat
+	InstanceID string        `yaml:"instance_id"` // Unique per running instance; defaults to "<hostname>-<random>"
+	Interval   time.Duration `yaml:"interval"`    // How often the heartbeat is refreshed and dead instances are looked for
+	DeadAfter  time.Duration `yaml:"dead_after"`  // Heartbeat age after which an instance is considered dead
+	Reclaim    bool          `yaml:"reclaim"`     // Return the PROCESSING tasks of dead instances to RECEIVED
+}
+
+// SetDefaults sets reasonable default values for the heartbeat
+func (c *HeartbeatConfig) SetDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = 5 * time.Second
+		fmt.Printf("Warning: heartbeat.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.DeadAfter <= 0 {
+		c.DeadAfter = 3 * c.Interval
+		fmt.Printf("Warning: heartbeat.dead_after not set, defaulting to %v\n", c.DeadAfter)
+	}
+}
+
+// Validate validates the heartbeat settings
+func (c *HeartbeatConfig) Validate() error {
+	if c.DeadAfter < 2*c.Interval {
+		return fmt.Errorf("dead_after (%v) must be at least twice the interval (%v), or live instances are reclaimed", c.DeadAfter, c.Interval)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// InFlightConfig bounds the submissions the gateway holds at once across its
+// HTTP and gRPC listeners. When the batch processors slow down (e.g. under
+// Kafka backpressure) requests pile up in memory; beyond MaxRequests new
+// submissions wait up to QueueTimeout for a slot and are then rejected with
+// 503 / UNAVAILABLE instead of being buffered.
+type InFlightConfig struct {
+	Enabled      bool          `yaml:"enabled"`       // Enable the in-flight limiter
+	MaxRequests  int           `yaml:"max_requests"`  // Submissions processed at once
+	MaxQueued    int           `yaml:"max_queued"`    // Submissions waiting for a slot; beyond this they are rejected immediately
+	QueueTimeout time.Duration `yaml:"queue_timeout"` // How long a submission waits for a slot
+	RetryAfter   time.Duration `yaml:"retry_after"`   // Retry-After sent with rejected submissions
+}
+
+// SetDefaults sets reasonable default values for the in-flight limiter
+func (c *InFlightConfig) SetDefaults() {
+	if c.MaxRequests == 0 {
+		c.MaxRequests = 2000
+		fmt.Printf("Warning: in_flight.max_requests not set, defaulting to %d\n", c.MaxRequests)
+	}
+	if c.MaxQueued == 0 {
+		c.MaxQueued = c.MaxRequests
+		fmt.Printf("Warning: in_flight.max_queued not set, defaulting to %d\n", c.MaxQueued)
+	}
+	if c.QueueTimeout == 0 {
+		c.QueueTimeout = 500 * time.Millisecond
+		fmt.Printf("Warning: in_flight.queue_timeout not set, defaulting to %v\n", c.QueueTimeout)
+	}
+	if c.RetryAfter == 0 {
+		c.RetryAfter = time.Second
+		fmt.Printf("Warning: in_flight.retry_after not set, defaulting to %v\n", c.RetryAfter)
+	}
+}
+
+// Validate validates the in-flight limiter configuration
+func (c *InFlightConfig) Validate() error {
+	if c.MaxRequests <= 0 {
+		return fmt.Errorf("max_requests must be positive")
+	}
+	if c.MaxQueued < 0 {
+		return fmt.Errorf("max_queued must not be negative")
+	}
+	if c.QueueTimeout < 0 {
+		return fmt.Errorf("queue_timeout must not be negative")
+	}
+	if c.RetryAfter < time.Second {
+		return fmt.Errorf("retry_after must be at least 1s")
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"time"
 
+	"tlng/svcauth"
+
 	"gopkg.in/yaml.v2"
 )
 
@@ -25,6 +27,58 @@ type KafkaProducerConfig struct {
 	// Performance settings
 	WriteTimeout time.Duration `yaml:"write_timeout"`
 	ReadTimeout  time.Duration `yaml:"read_timeout"`
+
+	// Delivery retry settings (async mode only)
+	RetryBufferSize  int           `yaml:"retry_buffer_size"`  // Max messages held for redelivery
+	RetryMaxAttempts int           `yaml:"retry_max_attempts"` // Attempts before a message is dropped as undeliverable
+	RetryBackoff     time.Duration `yaml:"retry_backoff"`      // Initial backoff between redelivery attempts
+	RetryMaxBackoff  time.Duration `yaml:"retry_max_backoff"`  // Upper bound for exponential backoff
+
+	TLS KafkaTLSConfig `yaml:"tls"` // TLS to the brokers (also used for secondary_brokers)
+
+	// Failover settings (enabled when secondary_brokers is set)
+	SecondaryBrokers      []string      `yaml:"secondary_brokers"`       // DR cluster used while the primary is unreachable
+	FailoverThreshold     time.Duration `yaml:"failover_threshold"`      // How long the primary must be unreachable before failing over
+	FailoverCheckInterval time.Duration `yaml:"failover_check_interval"` // Interval between primary reachability probes
+
+	// Startup topic verification
+	TopicCheck KafkaTopicConfig `yaml:"topic_check"`
+
+	// Message encoding: protobuf (tlng.internal.LogMessage) or json (legacy consumers)
+	Encoding string `yaml:"encoding"`
+
+	// Partitioning: request_id spreads messages by load, log_hash sends each
+	// log hash range to its own partition (for sharded engines)
+	PartitionKey string `yaml:"partition_key"`
+}
+
+// Kafka partitioning of log messages
+const (
+	PartitionKeyRequestID = "request_id" // Least-loaded partition
+	PartitionKeyLogHash   = "log_hash"   // Partition of the message's log hash range
+)
+
+// SetDefaults sets the default message encoding and partitioning
+func (c *KafkaProducerConfig) SetDefaults() {
+	if c.Encoding == "" {
+		c.Encoding = "protobuf"
+		fmt.Printf("Warning: kafka_producer.encoding not set, defaulting to %s\n", c.Encoding)
+	}
+	if c.PartitionKey == "" {
+		c.PartitionKey = PartitionKeyRequestID
+		fmt.Printf("Warning: kafka_producer.partition_key not set, defaulting to %s\n", c.PartitionKey)
+	}
+}
+
+// Validate validates the message encoding and partitioning
+func (c *KafkaProducerConfig) Validate() error {
+	if c.Encoding != "protobuf" && c.Encoding != "json" {
+		return fmt.Errorf("invalid encoding '%s' (must be protobuf or json)", c.Encoding)
+	}
+	if c.PartitionKey != PartitionKeyRequestID && c.PartitionKey != PartitionKeyLogHash {
+		return fmt.Errorf("invalid partition_key '%s' (must be %s or %s)", c.PartitionKey, PartitionKeyRequestID, PartitionKeyLogHash)
+	}
+	return nil
 }
 
 // BatchProcessorConfig defines configuration for batch processing
@@ -33,6 +87,11 @@ type BatchProcessorConfig struct {
 	BatchTimeout        time.Duration `yaml:"batch_timeout"`
 	MaxBufferSize       int           `yaml:"max_buffer_size"`
 	FlushChannelBuffer  int           `yaml:"flush_channel_buffer"`  // Buffer size for flush channel
+	ConflictPolicy      string        `yaml:"conflict_policy"`       // Duplicate request_id handling: do_nothing or update
+	WALPath             string        `yaml:"wal_path"`              // Local spool for batches that could not be persisted; empty disables it
+
+	FlushTrigger FlushTriggerConfig `yaml:"flush_trigger"` // Early and postponed flushes driven by queue depth, writer load and memory
+	Outbox       OutboxConfig       `yaml:"outbox"`        // Messages written in the insert transaction and relayed if their publish fails
 }
 
 // SetDefaults sets reasonable default values for batch processor configuration
@@ -53,6 +112,34 @@ func (c *BatchProcessorConfig) SetDefaults() {
 		c.FlushChannelBuffer = 100
 		fmt.Printf("Warning: batch_processor.flush_channel_buffer not set, defaulting to %d\n", c.FlushChannelBuffer)
 	}
+	if c.ConflictPolicy == "" {
+		c.ConflictPolicy = "do_nothing"
+		fmt.Printf("Warning: batch_processor.conflict_policy not set, defaulting to %s\n", c.ConflictPolicy)
+	}
+	if c.FlushTrigger.Enabled {
+		c.FlushTrigger.SetDefaults(c.BatchSize, c.BatchTimeout)
+	}
+	if c.Outbox.Enabled {
+		c.Outbox.SetDefaults()
+	}
+}
+
+// Validate validates the batch processor configuration
+func (c *BatchProcessorConfig) Validate() error {
+	if c.ConflictPolicy != "do_nothing" && c.ConflictPolicy != "update" {
+		return fmt.Errorf("invalid conflict_policy '%s' (must be do_nothing or update)", c.ConflictPolicy)
+	}
+	if c.FlushTrigger.Enabled {
+		if err := c.FlushTrigger.Validate(); err != nil {
+			return fmt.Errorf("flush_trigger: %w", err)
+		}
+	}
+	if c.Outbox.Enabled {
+		if err := c.Outbox.Validate(); err != nil {
+			return fmt.Errorf("outbox: %w", err)
+		}
+	}
+	return nil
 }
 
 
@@ -76,11 +163,50 @@ type ApiGatewayConfig struct {
 	HttpListenAddr string `yaml:"http_listen_addr"`
 	GrpcListenAddr string `yaml:"grpc_listen_addr"`
 
+	// RequestIDStrategy selects how request_ids are generated: "uuid" (default), "uuidv7" or "ulid"
+	RequestIDStrategy string `yaml:"request_id_strategy"`
+
 	Database       DatabaseConfig       `yaml:"database"`       // Use unified DatabaseConfig
 	KafkaProducer  KafkaProducerConfig  `yaml:"kafka_producer"` // Local Kafka producer config
+	Messaging      MessagingConfig      `yaml:"messaging"`      // Kafka (kafka_producer) or NATS JetStream to the engine
 	BatchProcessor BatchProcessorConfig `yaml:"batch_processor"`
 	HttpServer     HttpServerConfig     `yaml:"http_server"`
+	HttpTLS        ServerTLSConfig      `yaml:"http_tls"` // TLS, optionally mutual, of the HTTP listener
+	GrpcTLS        ServerTLSConfig      `yaml:"grpc_tls"` // TLS, optionally mutual, of the gRPC listener
 	Monitoring     GatewayMonitoringConfig     `yaml:"monitoring"`
+	Region         RegionConfig         `yaml:"region"`
+
+	TimestampPolicy TimestampPolicyConfig `yaml:"timestamp_policy"` // Client timestamp validation
+	Quota           QuotaConfig           `yaml:"quota"`            // Per-org rate limits and monthly quotas
+	Maintenance     MaintenanceConfig     `yaml:"maintenance"`      // Write rejection during store migrations
+	Startup         StartupConfig         `yaml:"startup"`          // Dependency wait and self-checks at boot
+	Shutdown        ShutdownConfig        `yaml:"shutdown"`         // Graceful shutdown budget
+	Dedup           DedupConfig           `yaml:"dedup"`            // Duplicate window for client retries
+	SizeTier        SizeTierConfig        `yaml:"size_tier"`        // Separate batch path and topic for large submissions
+	Canary          CanaryConfig          `yaml:"canary"`           // Share of submissions routed to a canary engine's topic
+	InFlight        InFlightConfig        `yaml:"in_flight"`        // Bound on submissions held in memory
+	LoadShedding    LoadSheddingConfig    `yaml:"load_shedding"`    // Rejection of low-priority submissions under downstream errors
+	RequestSize     RequestSizeConfig     `yaml:"request_size"`     // HTTP body and gRPC message limits, per org tier
+	DebugCapture    DebugCaptureConfig    `yaml:"debug_capture"`    // Raw requests of rejected submissions of opted-in orgs
+	TestTraffic     TestTrafficConfig     `yaml:"test_traffic"`     // Synthetic submissions excluded from usage and statistics
+
+	DegradedAcceptance DegradedAcceptanceConfig `yaml:"degraded_acceptance"` // Behaviour while Kafka is unavailable
+	ConfigFingerprint  ConfigFingerprintConfig  `yaml:"config_fingerprint"`  // On-chain anchoring of the sanitized configuration's hash
+	SelfTest           SelfTestConfig           `yaml:"self_test"`           // End-to-end smoke test served at /admin/selftest
+	Tracing            TracingConfig            `yaml:"tracing"`             // OpenTelemetry spans exported over OTLP
+	AnomalyDetection   AnomalyDetectionConfig   `yaml:"anomaly_detection"`   // Per-org silence, burst and duplicate-rate alerts
+	GapDetection       GapDetectionConfig       `yaml:"gap_detection"`       // Missing numbers of sequenced sources
+	Syslog             SyslogConfig             `yaml:"syslog"`              // RFC 5424/3164 receiver over UDP, TCP and TLS
+	OTLP               OTLPConfig               `yaml:"otlp"`                // OpenTelemetry logs at /v1/otlp/logs
+
+	SecurityProfile SecurityProfile `yaml:"security_profile"` // strict or lenient; see SecurityViolations
+	IngressAuth     bool            `yaml:"ingress_auth"`     // Submissions are authenticated by the ingress in front of the gateway
+	ServiceAuth     svcauth.Config  `yaml:"service_auth"`     // SPIFFE or OIDC authentication of agents and admin callers
+	APITokens       APITokensConfig `yaml:"api_tokens"`       // Org-scoped bearer tokens managed at /admin/tokens
+
+	IntegrityMetadata bool `yaml:"integrity_metadata"` // Hash algorithm, canonicalization and versions in SubmitLog responses
+
+	SourcePath string `yaml:"-"` // File the configuration was loaded from (set by LoadApiGatewayConfig)
 }
 
 // LoadApiGatewayConfig loads API gateway configuration from the specified YAML file path
@@ -94,6 +220,9 @@ func LoadApiGatewayConfig(path string) (*ApiGatewayConfig, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse API Gateway YAML config file: %w", err)
 	}
+	if err := ApplyEnvOverrides(EnvPrefix, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
 
 	// Set defaults for database configuration
 	cfg.Database.SetDefaults()
@@ -101,6 +230,45 @@ func LoadApiGatewayConfig(path string) (*ApiGatewayConfig, error) {
 	// Set defaults for batch processor configuration
 	cfg.BatchProcessor.SetDefaults()
 
+	// Set defaults for Kafka producer message encoding
+	cfg.KafkaProducer.SetDefaults()
+
+	// Set defaults for Kafka topic verification
+	cfg.KafkaProducer.TopicCheck.SetDefaults("kafka_producer")
+
+	// Set defaults for client timestamp validation
+	cfg.TimestampPolicy.SetDefaults()
+
+	// Set defaults for per-org quotas
+	cfg.Quota.SetDefaults()
+
+	// Set defaults for maintenance mode
+	cfg.Maintenance.SetDefaults()
+
+	// Set defaults for the startup sequence
+	cfg.Startup.SetDefaults()
+
+	// Set defaults for the duplicate window
+	cfg.Dedup.SetDefaults()
+
+	// Set defaults for degraded acceptance
+	cfg.DegradedAcceptance.SetDefaults()
+
+	// Set defaults for graceful shutdown
+	cfg.Shutdown.SetDefaults(15*time.Second, 10*time.Second)
+
+	// Set defaults for the security profile
+	cfg.SecurityProfile.SetDefaults()
+
+	// Set defaults for request size limits
+	cfg.RequestSize.SetDefaults()
+
+	// Set defaults for service-to-service authentication
+	cfg.ServiceAuth.SetDefaults()
+
+	// Set defaults for the message queue
+	cfg.Messaging.SetDefaults()
+
 	// Validation
 	if cfg.HttpListenAddr == "" && cfg.GrpcListenAddr == "" {
 		return nil, fmt.Errorf("configuration error: at least one of http_listen_addr or grpc_listen_addr must be configured")
@@ -111,5 +279,221 @@ func LoadApiGatewayConfig(path string) (*ApiGatewayConfig, error) {
 		return nil, fmt.Errorf("database configuration error: %w", err)
 	}
 
+	// Validate batch processor configuration
+	if err := cfg.BatchProcessor.Validate(); err != nil {
+		return nil, fmt.Errorf("batch processor configuration error: %w", err)
+	}
+
+	// Validate Kafka producer message encoding
+	if err := cfg.KafkaProducer.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_producer configuration error: %w", err)
+	}
+
+	// Validate Kafka topic verification configuration
+	if err := cfg.KafkaProducer.TopicCheck.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_producer configuration error: %w", err)
+	}
+
+	// Validate Kafka TLS configuration
+	if err := cfg.KafkaProducer.TLS.Validate(); err != nil {
+		return nil, fmt.Errorf("kafka_producer configuration error: %w", err)
+	}
+
+	// Validate the message queue; NATS has no counterpart of the extra Kafka topics and clusters
+	if err := cfg.Messaging.Validate(); err != nil {
+		return nil, fmt.Errorf("messaging configuration error: %w", err)
+	}
+	if cfg.Messaging.UsesNATS() && (cfg.SizeTier.Enabled || cfg.Canary.Enabled || len(cfg.KafkaProducer.SecondaryBrokers) > 0) {
+		return nil, fmt.Errorf("messaging configuration error: size_tier, canary and kafka_producer.secondary_brokers require type kafka")
+	}
+
+	// Validate region configuration
+	if err := cfg.Region.Validate(); err != nil {
+		return nil, fmt.Errorf("region configuration error: %w", err)
+	}
+
+	// Validate client timestamp policy
+	if err := cfg.TimestampPolicy.Validate(); err != nil {
+		return nil, fmt.Errorf("timestamp_policy configuration error: %w", err)
+	}
+
+	// Validate per-org quotas
+	if err := cfg.Quota.Validate(); err != nil {
+		return nil, fmt.Errorf("quota configuration error: %w", err)
+	}
+
+	// Validate maintenance mode
+	if err := cfg.Maintenance.Validate(); err != nil {
+		return nil, fmt.Errorf("maintenance configuration error: %w", err)
+	}
+
+	// Validate the startup sequence
+	if err := cfg.Startup.Validate(); err != nil {
+		return nil, fmt.Errorf("startup configuration error: %w", err)
+	}
+
+	// Validate the duplicate window
+	if err := cfg.Dedup.Validate(); err != nil {
+		return nil, fmt.Errorf("dedup configuration error: %w", err)
+	}
+
+	// Validate graceful shutdown
+	if err := cfg.Shutdown.Validate(); err != nil {
+		return nil, fmt.Errorf("shutdown configuration error: %w", err)
+	}
+
+	// Validate degraded acceptance
+	if err := cfg.DegradedAcceptance.Validate(); err != nil {
+		return nil, fmt.Errorf("degraded_acceptance configuration error: %w", err)
+	}
+	if cfg.BatchProcessor.Outbox.Enabled && cfg.DegradedAcceptance.Policy == DegradedPolicySpool {
+		return nil, fmt.Errorf("degraded_acceptance configuration error: policy spool cannot be combined with batch_processor.outbox, which already keeps unpublished messages")
+	}
+
+	// Validate the in-flight limiter
+	if cfg.InFlight.Enabled {
+		cfg.InFlight.SetDefaults()
+		if err := cfg.InFlight.Validate(); err != nil {
+			return nil, fmt.Errorf("in_flight configuration error: %w", err)
+		}
+	}
+
+	// Validate load shedding
+	if cfg.LoadShedding.Enabled {
+		cfg.LoadShedding.SetDefaults()
+		if err := cfg.LoadShedding.Validate(); err != nil {
+			return nil, fmt.Errorf("load_shedding configuration error: %w", err)
+		}
+	}
+
+	// Validate size-tier routing
+	if cfg.SizeTier.Enabled {
+		cfg.SizeTier.SetDefaults()
+		if err := cfg.SizeTier.Validate(); err != nil {
+			return nil, fmt.Errorf("size_tier configuration error: %w", err)
+		}
+	}
+
+	// Validate canary routing
+	if cfg.Canary.Enabled {
+		cfg.Canary.SetDefaults()
+		if err := cfg.Canary.Validate(cfg.KafkaProducer.Topic); err != nil {
+			return nil, fmt.Errorf("canary configuration error: %w", err)
+		}
+		if cfg.SizeTier.Enabled && cfg.Canary.Topic == cfg.SizeTier.Topic {
+			return nil, fmt.Errorf("canary configuration error: topic must differ from size_tier.topic (%s)", cfg.SizeTier.Topic)
+		}
+	}
+
+	// Validate request size limits
+	if err := cfg.RequestSize.Validate(); err != nil {
+		return nil, fmt.Errorf("request_size configuration error: %w", err)
+	}
+
+	// Validate debug capture
+	if cfg.DebugCapture.Enabled {
+		cfg.DebugCapture.SetDefaults()
+		if err := cfg.DebugCapture.Validate(); err != nil {
+			return nil, fmt.Errorf("debug_capture configuration error: %w", err)
+		}
+	}
+
+	// Validate test traffic
+	if cfg.TestTraffic.Enabled {
+		cfg.TestTraffic.SetDefaults()
+		if err := cfg.TestTraffic.Validate(); err != nil {
+			return nil, fmt.Errorf("test_traffic configuration error: %w", err)
+		}
+	}
+
+	// Validate anomaly detection
+	if cfg.AnomalyDetection.Enabled {
+		cfg.AnomalyDetection.SetDefaults()
+		if err := cfg.AnomalyDetection.Validate(); err != nil {
+			return nil, fmt.Errorf("anomaly_detection configuration error: %w", err)
+		}
+	}
+
+	// Validate gap detection
+	if cfg.GapDetection.Enabled {
+		cfg.GapDetection.SetDefaults()
+		if err := cfg.GapDetection.Validate(); err != nil {
+			return nil, fmt.Errorf("gap_detection configuration error: %w", err)
+		}
+	}
+
+	// Validate the syslog receiver
+	if cfg.Syslog.Enabled {
+		cfg.Syslog.SetDefaults()
+		if err := cfg.Syslog.Validate(); err != nil {
+			return nil, fmt.Errorf("syslog configuration error: %w", err)
+		}
+	}
+
+	// Set defaults for the OpenTelemetry logs endpoint
+	if cfg.OTLP.Enabled {
+		if cfg.HttpListenAddr == "" {
+			return nil, fmt.Errorf("otlp configuration error: needs http_listen_addr")
+		}
+		cfg.OTLP.SetDefaults()
+	}
+
+	// Validate configuration fingerprint anchoring
+	if cfg.ConfigFingerprint.Enabled {
+		cfg.ConfigFingerprint.SetDefaults()
+		if err := cfg.ConfigFingerprint.Validate(); err != nil {
+			return nil, fmt.Errorf("config_fingerprint configuration error: %w", err)
+		}
+	}
+
+	// Validate the end-to-end self-test
+	if cfg.SelfTest.Enabled {
+		cfg.SelfTest.SetDefaults()
+		if err := cfg.SelfTest.Validate(); err != nil {
+			return nil, fmt.Errorf("self_test configuration error: %w", err)
+		}
+	}
+
+	// Validate tracing
+	if cfg.Tracing.Enabled {
+		cfg.Tracing.SetDefaults("api-gateway")
+		if err := cfg.Tracing.Validate(); err != nil {
+			return nil, fmt.Errorf("tracing configuration error: %w", err)
+		}
+	}
+
+	// Validate service-to-service authentication
+	if err := cfg.ServiceAuth.Validate(); err != nil {
+		return nil, fmt.Errorf("service_auth configuration error: %w", err)
+	}
+
+	// Validate listener TLS; spiffe service authentication serves its own
+	if err := cfg.HttpTLS.Validate(); err != nil {
+		return nil, fmt.Errorf("http_tls configuration error: %w", err)
+	}
+	if err := cfg.GrpcTLS.Validate(); err != nil {
+		return nil, fmt.Errorf("grpc_tls configuration error: %w", err)
+	}
+	if cfg.ServiceAuth.Mode == svcauth.ModeSPIFFE && (cfg.HttpTLS.Enabled || cfg.GrpcTLS.Enabled) {
+		return nil, fmt.Errorf("http_tls and grpc_tls cannot be combined with service_auth mode spiffe, which serves TLS with the SVID")
+	}
+
+	// Validate API tokens
+	if cfg.APITokens.Enabled {
+		cfg.APITokens.SetDefaults()
+		if err := cfg.APITokens.Validate(); err != nil {
+			return nil, fmt.Errorf("api_tokens configuration error: %w", err)
+		}
+	}
+
+	// Evaluate the security profile last, against the complete configuration
+	if err := cfg.SecurityProfile.Validate(); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+	if err := cfg.SecurityProfile.Check(cfg.SecurityViolations()); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+
+	cfg.SourcePath = path
 	return &cfg, nil
 }
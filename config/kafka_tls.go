@@ -0,0 +1,59 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// KafkaTLSConfig defines TLS for connections to the Kafka brokers
+type KafkaTLSConfig struct {
+	Enabled            bool   `yaml:"enabled"`              // Connect to the brokers over TLS
+	CAFile             string `yaml:"ca_file"`              // PEM CA bundle; empty uses the system roots
+	CertFile           string `yaml:"cert_file"`            // Client certificate for mutual TLS (requires key_file)
+	KeyFile            string `yaml:"key_file"`             // Client private key for mutual TLS (requires cert_file)
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // Skip broker certificate verification (testing only)
+}
+
+// Validate validates the Kafka TLS configuration
+func (c *KafkaTLSConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	}
+	return nil
+}
+
+// TLSConfig builds the client TLS configuration; nil when TLS is disabled
+func (c *KafkaTLSConfig) TLSConfig() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA file '%s': %w", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in kafka CA file '%s'", c.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaintenanceConfig defines the API Gateway's maintenance mode, in which write
+// endpoints return 503 while query services stay available. It can be toggled
+// at runtime through the admin API.
+type MaintenanceConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // Start in maintenance mode
+	RetryAfter time.Duration `yaml:"retry_after"` // Retry-After sent with rejected writes
+	Message    string        `yaml:"message"`     // Returned to clients with rejected writes
+}
+
+// SetDefaults sets reasonable default values for maintenance configuration
+func (c *MaintenanceConfig) SetDefaults() {
+	if c.RetryAfter == 0 {
+		c.RetryAfter = time.Minute
+		fmt.Printf("Warning: maintenance.retry_after not set, defaulting to %v\n", c.RetryAfter)
+	}
+}
+
+// Validate validates the maintenance configuration
+func (c *MaintenanceConfig) Validate() error {
+	if c.RetryAfter < time.Second {
+		return fmt.Errorf("retry_after must be at least 1s")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package config
+
+import "fmt"
+
+// MerkleAnchoringConfig defines Merkle-root anchoring. Instead of every log of
+// a batch, the engine anchors one entry per batch and routing target: the root
+// of a Merkle tree over the batch's log hashes, submitted under OrgID. Each
+// log's inclusion proof is stored in tbl_merkle_proof (schema v13); logs of
+// batches whose proofs cannot be stored are retried.
+type MerkleAnchoringConfig struct {
+	Enabled bool   `yaml:"enabled"` // Anchor Merkle roots instead of individual logs
+	OrgID   string `yaml:"org_id"`  // Org the root entries are submitted under
+}
+
+// SetDefaults sets reasonable default values for Merkle-root anchoring
+func (c *MerkleAnchoringConfig) SetDefaults() {
+	if c.OrgID == "" {
+		c.OrgID = "tlng-system"
+		fmt.Printf("Warning: merkle_anchoring.org_id not set, defaulting to %s\n", c.OrgID)
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Message queues bridging the gateway and the engine
+const (
+	MessagingTypeKafka = "kafka" // kafka_producer / kafka_consumer
+	MessagingTypeNATS  = "nats"  // NATS JetStream, configured under messaging.nats
+)
+
+// MessagingConfig selects the message queue between the gateway and the engine
+type MessagingConfig struct {
+	Type string     `yaml:"type"` // kafka (default) or nats
+	NATS NATSConfig `yaml:"nats"` // Used when type is nats
+}
+
+// NATSConfig defines the NATS JetStream stream submissions are published to
+// and consumed from
+type NATSConfig struct {
+	URLs    []string `yaml:"urls"`    // e.g., ["nats://nats1:4222", "nats://nats2:4222"], tried in order
+	Stream  string   `yaml:"stream"`  // JetStream stream holding the submissions
+	Subject string   `yaml:"subject"` // Subject published to and captured by the stream (region-scoped like Kafka topics)
+
+	CreateStream bool          `yaml:"create_stream"` // Create the stream and the durable consumer if they do not exist
+	Replicas     int           `yaml:"replicas"`      // Replicas of a created stream
+	MaxAge       time.Duration `yaml:"max_age"`       // Oldest message a created stream keeps; 0 keeps them until acked
+
+	Username string         `yaml:"username"`
+	Password string         `yaml:"password"`
+	Token    string         `yaml:"token"` // Alternative to username/password
+	TLS      KafkaTLSConfig `yaml:"tls"`   // TLS to the servers; same settings as the Kafka clients
+
+	ConnectTimeout time.Duration `yaml:"connect_timeout"` // Dial and handshake timeout per server
+	Encoding       string        `yaml:"encoding"`        // Gateway: protobuf or json, as kafka_producer.encoding
+	PublishTimeout time.Duration `yaml:"publish_timeout"` // Gateway: wait for the stream to acknowledge a publish
+
+	Durable    string        `yaml:"durable"`     // Engine: durable pull consumer shared by all engine consumers
+	Count      int           `yaml:"count"`       // Engine: number of consumers to create
+	FetchBatch int           `yaml:"fetch_batch"` // Engine: messages pulled per fetch request
+	AckWait    time.Duration `yaml:"ack_wait"`    // Engine: unacknowledged messages are redelivered after this long
+	MaxDeliver int           `yaml:"max_deliver"` // Engine: deliveries before a message is given up; -1 is unlimited
+	NakDelay   time.Duration `yaml:"nak_delay"`   // Engine: delay before a nacked message is redelivered
+}
+
+// SetDefaults sets the default message queue and, for NATS, its defaults
+func (c *MessagingConfig) SetDefaults() {
+	if c.Type == "" {
+		c.Type = MessagingTypeKafka
+		fmt.Printf("Warning: messaging.type not set, defaulting to %s\n", c.Type)
+	}
+	if c.Type != MessagingTypeNATS {
+		return
+	}
+	n := &c.NATS
+	if n.Stream == "" {
+		n.Stream = "TLNG_LOGS"
+		fmt.Printf("Warning: messaging.nats.stream not set, defaulting to %s\n", n.Stream)
+	}
+	if n.Subject == "" {
+		n.Subject = "tlng.logs"
+		fmt.Printf("Warning: messaging.nats.subject not set, defaulting to %s\n", n.Subject)
+	}
+	if n.Replicas <= 0 {
+		n.Replicas = 1
+		fmt.Printf("Warning: messaging.nats.replicas not set or invalid, defaulting to %d\n", n.Replicas)
+	}
+	if n.ConnectTimeout <= 0 {
+		n.ConnectTimeout = 5 * time.Second
+		fmt.Printf("Warning: messaging.nats.connect_timeout not set or invalid, defaulting to %s\n", n.ConnectTimeout)
+	}
+	if n.Encoding == "" {
+		n.Encoding = "protobuf"
+		fmt.Printf("Warning: messaging.nats.encoding not set, defaulting to %s\n", n.Encoding)
+	}
+	if n.PublishTimeout <= 0 {
+		n.PublishTimeout = 5 * time.Second
+		fmt.Printf("Warning: messaging.nats.publish_timeout not set or invalid, defaulting to %s\n", n.PublishTimeout)
+	}
+	if n.Durable == "" {
+		n.Durable = "tlng-engine"
+		fmt.Printf("Warning: messaging.nats.durable not set, defaulting to %s\n", n.Durable)
+	}
+	if n.Count <= 0 {
+		n.Count = 1
+		fmt.Printf("Warning: messaging.nats.count not set or invalid, defaulting to %d\n", n.Count)
+	}
+	if n.FetchBatch <= 0 {
+		n.FetchBatch = 100
+		fmt.Printf("Warning: messaging.nats.fetch_batch not set or invalid, defaulting to %d\n", n.FetchBatch)
+	}
+	if n.AckWait <= 0 {
+		n.AckWait = 60 * time.Second
+		fmt.Printf("Warning: messaging.nats.ack_wait not set or invalid, defaulting to %s\n", n.AckWait)
+	}
+	if n.MaxDeliver == 0 {
+		n.MaxDeliver = -1
+		fmt.Printf("Warning: messaging.nats.max_deliver not set, defaulting to %d (unlimited)\n", n.MaxDeliver)
+	}
+	if n.NakDelay <= 0 {
+		n.NakDelay = 5 * time.Second
+		fmt.Printf("Warning: messaging.nats.nak_delay not set or invalid, defaulting to %s\n", n.NakDelay)
+	}
+}
+
+// Validate validates the message queue selection and the NATS settings
+func (c *MessagingConfig) Validate() error {
+	switch c.Type {
+	case MessagingTypeKafka:
+		return nil
+	case MessagingTypeNATS:
+	default:
+		return fmt.Errorf("invalid type '%s' (must be %s or %s)", c.Type, MessagingTypeKafka, MessagingTypeNATS)
+	}
+	n := &c.NATS
+	if len(n.URLs) == 0 {
+		return fmt.Errorf("nats.urls is required when type is nats")
+	}
+	if n.Encoding != "protobuf" && n.Encoding != "json" {
+		return fmt.Errorf("invalid nats.encoding '%s' (must be protobuf or json)", n.Encoding)
+	}
+	if n.Token != "" && (n.Username != "" || n.Password != "") {
+		return fmt.Errorf("nats.token cannot be combined with nats.username/password")
+	}
+	if n.MaxDeliver < -1 {
+		return fmt.Errorf("nats.max_deliver must be positive or -1")
+	}
+	if err := n.TLS.Validate(); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// UsesNATS reports whether submissions travel over NATS JetStream
+func (c *MessagingConfig) UsesNATS() bool {
+	return c.Type == MessagingTypeNATS
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// ObjectStoreConfig defines an object storage destination (S3, GCS or a local directory)
+type ObjectStoreConfig struct {
+	Backend         string `yaml:"backend"`           // s3, gcs or fs
+	Bucket          string `yaml:"bucket"`            // Bucket name (s3/gcs)
+	Prefix          string `yaml:"prefix"`            // Key prefix for all objects
+	Endpoint        string `yaml:"endpoint"`          // Service endpoint; defaults per backend, set for MinIO etc.
+	Region          string `yaml:"region"`            // Signing region (s3); GCS uses "auto"
+	AccessKeyID     string `yaml:"access_key_id"`     // Falls back to OBJECT_STORE_ACCESS_KEY_ID
+	SecretAccessKey string `yaml:"secret_access_key"` // Falls back to OBJECT_STORE_SECRET_ACCESS_KEY
+	PathStyle       bool   `yaml:"path_style"`        // Use path-style URLs (required by most S3-compatible servers)
+	Directory       string `yaml:"directory"`         // Root directory (fs)
+}
+
+// SetDefaults sets reasonable default values for object storage configuration
+func (c *ObjectStoreConfig) SetDefaults(prefix string) {
+	if c.Backend == "" {
+		c.Backend = "fs"
+		fmt.Printf("Warning: %s.backend not set, defaulting to %s\n", prefix, c.Backend)
+	}
+	switch c.Backend {
+	case "s3":
+		if c.Region == "" {
+			c.Region = "us-east-1"
+			fmt.Printf("Warning: %s.region not set, defaulting to %s\n", prefix, c.Region)
+		}
+		if c.Endpoint == "" {
+			c.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.Region)
+		}
+	case "gcs":
+		// GCS is accessed through its S3-compatible XML API with HMAC keys
+		if c.Endpoint == "" {
+			c.Endpoint = "https://storage.googleapis.com"
+		}
+		c.Region = "auto"
+	case "fs":
+		if c.Directory == "" {
+			c.Directory = "./archive"
+			fmt.Printf("Warning: %s.directory not set, defaulting to %s\n", prefix, c.Directory)
+		}
+	}
+	if c.AccessKeyID == "" {
+		c.AccessKeyID = os.Getenv("OBJECT_STORE_ACCESS_KEY_ID")
+	}
+	if c.SecretAccessKey == "" {
+		c.SecretAccessKey = os.Getenv("OBJECT_STORE_SECRET_ACCESS_KEY")
+	}
+}
+
+// Validate validates the object storage configuration
+func (c *ObjectStoreConfig) Validate() error {
+	switch c.Backend {
+	case "s3", "gcs":
+		if c.Bucket == "" {
+			return fmt.Errorf("object store bucket is required for backend %s", c.Backend)
+		}
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return fmt.Errorf("object store credentials are required for backend %s", c.Backend)
+		}
+	case "fs":
+		if c.Directory == "" {
+			return fmt.Errorf("object store directory is required for backend fs")
+		}
+	default:
+		return fmt.Errorf("invalid object store backend '%s' (must be s3, gcs or fs)", c.Backend)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package config
+
+import "fmt"
+
+// OTLPConfig defines the OpenTelemetry logs endpoint (OTLP/HTTP) at
+// /v1/otlp/logs. Each log record is submitted on its own, attributed to the
+// org, host and application named by attributes of its resource.
+type OTLPConfig struct {
+	Enabled              bool   `yaml:"enabled"`
+	OrgAttribute         string `yaml:"org_attribute"`         // Resource attribute holding the source org
+	HostAttribute        string `yaml:"host_attribute"`        // Resource attribute stored as source_host
+	ApplicationAttribute string `yaml:"application_attribute"` // Resource attribute stored as application
+}
+
+// SetDefaults sets the OpenTelemetry semantic conventions for host and application
+func (c *OTLPConfig) SetDefaults() {
+	if c.OrgAttribute == "" {
+		c.OrgAttribute = "tlng.org_id"
+		fmt.Printf("Warning: otlp.org_attribute not set, defaulting to %s\n", c.OrgAttribute)
+	}
+	if c.HostAttribute == "" {
+		c.HostAttribute = "host.name"
+		fmt.Printf("Warning: otlp.host_attribute not set, defaulting to %s\n", c.HostAttribute)
+	}
+	if c.ApplicationAttribute == "" {
+		c.ApplicationAttribute = "service.name"
+		fmt.Printf("Warning: otlp.application_attribute not set, defaulting to %s\n", c.ApplicationAttribute)
+	}
+}
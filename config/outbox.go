@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// OutboxConfig defines the gateway's transactional outbox. Each batch's Kafka
+// messages are written to tbl_outbox in the transaction that inserts its
+// tbl_log_status rows, so a crash between the insert and the publish cannot
+// lose a message. The batch publishes right after the commit as before and
+// marks its messages sent; a relay publishes the messages left unsent by a
+// failed publish or a crashed gateway. Delivery is at-least-once: the engine
+// skips submissions that are no longer RECEIVED.
+type OutboxConfig struct {
+	Enabled        bool          `yaml:"enabled"`          // Write messages to the outbox with their submissions
+	RelayInterval  time.Duration `yaml:"relay_interval"`   // How often the relay publishes unsent messages
+	RelayBatchSize int           `yaml:"relay_batch_size"` // Messages published per relay batch
+	Lease          time.Duration `yaml:"lease"`            // How long a batch or relay owns the messages it publishes; a crashed owner's are relayed after it
+	Retention      time.Duration `yaml:"retention"`        // How long sent messages are kept before they are purged
+}
+
+// SetDefaults sets reasonable default values for the outbox
+func (c *OutboxConfig) SetDefaults() {
+	if c.RelayInterval == 0 {
+		c.RelayInterval = time.Second
+		fmt.Printf("Warning: batch_processor.outbox.relay_interval not set, defaulting to %v\n", c.RelayInterval)
+	}
+	if c.RelayBatchSize == 0 {
+		c.RelayBatchSize = 500
+		fmt.Printf("Warning: batch_processor.outbox.relay_batch_size not set, defaulting to %d\n", c.RelayBatchSize)
+	}
+	if c.Lease == 0 {
+		c.Lease = 30 * time.Second
+		fmt.Printf("Warning: batch_processor.outbox.lease not set, defaulting to %v\n", c.Lease)
+	}
+	if c.Retention == 0 {
+		c.Retention = 24 * time.Hour
+		fmt.Printf("Warning: batch_processor.outbox.retention not set, defaulting to %v\n", c.Retention)
+	}
+}
+
+// Validate validates the outbox configuration
+func (c *OutboxConfig) Validate() error {
+	if c.RelayInterval <= 0 {
+		return fmt.Errorf("relay_interval must be positive")
+	}
+	if c.RelayBatchSize <= 0 {
+		return fmt.Errorf("relay_batch_size must be positive")
+	}
+	if c.Lease < time.Second {
+		return fmt.Errorf("lease must be at least 1s")
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must not be negative")
+	}
+	return nil
+}
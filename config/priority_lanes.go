@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// PriorityLanesConfig defines how engine workers order messages by the
+// priority header (high, normal, low) set by the gateway. Each worker buffers
+// a consumption window of messages and cuts its batches from the highest
+// priority down. Buffered high-priority messages are flushed within
+// HighMaxLatency, in a batch of their own if need be, even if it is underfilled.
+type PriorityLanesConfig struct {
+	Enabled        bool          `yaml:"enabled"`          // Order batches by the message priority header
+	Window         int           `yaml:"window"`           // Messages buffered per worker before the next batch is cut
+	HighMaxLatency time.Duration `yaml:"high_max_latency"` // Longest a high-priority message waits for its batch
+}
+
+// SetDefaults sets reasonable default values for priority lanes; the window
+// holds several batches of the configured worker batch size unless set
+func (c *PriorityLanesConfig) SetDefaults(batchSize int) {
+	if c.Window <= 0 {
+		c.Window = 4 * batchSize
+		fmt.Printf("Warning: priority_lanes.window not set, defaulting to %d (4 x worker.batch_size)\n", c.Window)
+	}
+	if c.HighMaxLatency <= 0 {
+		c.HighMaxLatency = 200 * time.Millisecond
+		fmt.Printf("Warning: priority_lanes.high_max_latency not set, defaulting to %v\n", c.HighMaxLatency)
+	}
+}
+
+// Validate validates the window against the worker's batch size and timeout
+func (c *PriorityLanesConfig) Validate(batchSize int, batchTimeout time.Duration) error {
+	if c.Window < batchSize {
+		return fmt.Errorf("window (%d) must hold at least one batch (worker.batch_size %d)", c.Window, batchSize)
+	}
+	if c.HighMaxLatency >= batchTimeout {
+		return fmt.Errorf("high_max_latency (%v) must be below worker.batch_timeout (%v), or high-priority messages wait no less than the others", c.HighMaxLatency, batchTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package config
+
+// ProofCacheConfig defines the attestation proof cache (tbl_attestation_proof).
+// The engine caches the proof of every log it anchors or reconciles, and the
+// query service answers verification calls from the cache instead of the chain
+// unless the caller asks for force_chain. Requires schema v6; on an older
+// schema the cache switches itself off.
+type ProofCacheConfig struct {
+	Enabled bool `yaml:"enabled"` // Populate (engine) or read (query) the proof cache
+}
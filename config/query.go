@@ -14,6 +14,12 @@ type QueryConfig struct {
 	Database   DatabaseConfig        `yaml:"database"`
 	Blockchain QueryBlockchainConfig `yaml:"blockchain"`
 	Logging    QueryLoggingConfig    `yaml:"logging"`
+	ProofCache ProofCacheConfig      `yaml:"proof_cache"`
+	APITokens  APITokensConfig       `yaml:"api_tokens"` // Bearer API tokens with the query scope, beside ingress API keys
+	GraphQL    GraphQLConfig         `yaml:"graphql"`    // Ad-hoc queries over the read model at /v1/graphql
+
+	SecurityProfile   SecurityProfile `yaml:"security_profile"`   // strict or lenient; see SecurityViolations
+	IntegrityMetadata bool            `yaml:"integrity_metadata"` // Hash algorithm, canonicalization and versions in receipts and audits
 }
 
 // QueryServerConfig defines HTTP server configuration for Query service
@@ -49,6 +55,9 @@ func LoadQueryConfig(path string) (*QueryConfig, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse YAML config file: %w", err)
 	}
+	if err := ApplyEnvOverrides(EnvPrefix, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
 
 	// Set defaults
 	cfg.SetDefaults()
@@ -58,6 +67,11 @@ func LoadQueryConfig(path string) (*QueryConfig, error) {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
 
+	// Evaluate the security profile against the complete configuration
+	if err := cfg.SecurityProfile.Check(cfg.SecurityViolations()); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+
 	return &cfg, nil
 }
 
@@ -81,6 +95,19 @@ func (c *QueryConfig) SetDefaults() {
 	// Database defaults
 	c.Database.SetDefaults()
 
+	// Security profile default
+	c.SecurityProfile.SetDefaults()
+
+	// API token defaults
+	if c.APITokens.Enabled {
+		c.APITokens.SetDefaults()
+	}
+
+	// GraphQL defaults
+	if c.GraphQL.Enabled {
+		c.GraphQL.SetDefaults()
+	}
+
 	// Logging defaults
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
@@ -116,11 +143,30 @@ func (c *QueryConfig) Validate() error {
 		return fmt.Errorf("database config error: %w", err)
 	}
 
+	// Validate security profile
+	if err := c.SecurityProfile.Validate(); err != nil {
+		return err
+	}
+
 	// Validate blockchain config
 	if c.Blockchain.Enabled && c.Blockchain.ChainMakerConfig == "" {
 		return fmt.Errorf("blockchain is enabled but chainmaker_config is not set")
 	}
 
+	// Validate API tokens
+	if c.APITokens.Enabled {
+		if err := c.APITokens.Validate(); err != nil {
+			return fmt.Errorf("api_tokens config error: %w", err)
+		}
+	}
+
+	// Validate GraphQL
+	if c.GraphQL.Enabled {
+		if err := c.GraphQL.Validate(); err != nil {
+			return fmt.Errorf("graphql config error: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -132,6 +178,8 @@ func (c *QueryConfig) LogConfiguration() {
 	fmt.Printf("  Write Timeout: %s\n", c.Server.WriteTimeout)
 	fmt.Printf("  Idle Timeout: %s\n", c.Server.IdleTimeout)
 	fmt.Printf("  Blockchain Enabled: %v\n", c.Blockchain.Enabled)
+	fmt.Printf("  Proof Cache Enabled: %v\n", c.ProofCache.Enabled)
+	fmt.Printf("  GraphQL Enabled: %v\n", c.GraphQL.Enabled)
 	fmt.Printf("  Logging Level: %s\n", c.Logging.Level)
 	fmt.Printf("  Audit Enabled: %v\n", c.Logging.AuditEnabled)
 	c.Database.LogConfiguration()
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuotaConfig defines per-org submission rate limits and monthly quotas
+// enforced by the API Gateway. Limits of 0 are unlimited.
+type QuotaConfig struct {
+	Enabled      bool                `yaml:"enabled"`
+	RateLimit    int                 `yaml:"rate_limit"`    // Submissions per org per rate_window on each gateway instance
+	RateWindow   time.Duration       `yaml:"rate_window"`   // Fixed rate-limit window
+	MonthlyQuota int64               `yaml:"monthly_quota"` // Submissions per org per calendar month (UTC), across all gateways
+	SyncInterval time.Duration       `yaml:"sync_interval"` // How often usage is written to and refreshed from the State DB
+	Orgs         map[string]OrgQuota `yaml:"orgs"`          // Per-org overrides keyed by source_org_id
+}
+
+// OrgQuota overrides the default limits for one org: 0 keeps the default,
+// a negative value removes the limit
+type OrgQuota struct {
+	RateLimit    int   `yaml:"rate_limit"`
+	MonthlyQuota int64 `yaml:"monthly_quota"`
+}
+
+// SetDefaults sets reasonable default values for quota configuration
+func (c *QuotaConfig) SetDefaults() {
+	if !c.Enabled {
+		return
+	}
+	if c.RateWindow == 0 {
+		c.RateWindow = time.Second
+		fmt.Printf("Warning: quota.rate_window not set, defaulting to %v\n", c.RateWindow)
+	}
+	if c.SyncInterval == 0 {
+		c.SyncInterval = 5 * time.Second
+		fmt.Printf("Warning: quota.sync_interval not set, defaulting to %v\n", c.SyncInterval)
+	}
+}
+
+// Validate validates the quota configuration
+func (c *QuotaConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.RateWindow <= 0 {
+		return fmt.Errorf("rate_window must be positive")
+	}
+	if c.SyncInterval <= 0 {
+		return fmt.Errorf("sync_interval must be positive")
+	}
+	if c.RateLimit < 0 || c.MonthlyQuota < 0 {
+		return fmt.Errorf("rate_limit and monthly_quota must not be negative (use 0 for unlimited)")
+	}
+	return nil
+}
+
+// LimitsFor returns the rate limit and monthly quota that apply to an org (0 = unlimited)
+func (c *QuotaConfig) LimitsFor(orgID string) (rateLimit int, monthlyQuota int64) {
+	rateLimit, monthlyQuota = c.RateLimit, c.MonthlyQuota
+	override, ok := c.Orgs[orgID]
+	if !ok {
+		return rateLimit, monthlyQuota
+	}
+	if override.RateLimit != 0 {
+		rateLimit = max(override.RateLimit, 0)
+	}
+	if override.MonthlyQuota != 0 {
+		monthlyQuota = max(override.MonthlyQuota, 0)
+	}
+	return rateLimit, monthlyQuota
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReadOnlyModeConfig defines how the engine rides out a read-only State DB
+// during failover: it keeps consuming and validating messages, spools the
+// batches it would anchor to a local file instead of submitting them, and
+// anchors the spooled batches once the database accepts writes again
+type ReadOnlyModeConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	SpoolPath     string        `yaml:"spool_path"`     // Local file holding the deferred batches
+	ProbeInterval time.Duration `yaml:"probe_interval"` // How often the database is checked for writes while read-only
+}
+
+// SetDefaults sets reasonable default values for read-only mode
+func (c *ReadOnlyModeConfig) SetDefaults() {
+	if c.ProbeInterval <= 0 {
+		c.ProbeInterval = 5 * time.Second
+		fmt.Printf("Warning: read_only_mode.probe_interval not set, defaulting to %v\n", c.ProbeInterval)
+	}
+}
+
+// Validate validates the read-only mode settings
+func (c *ReadOnlyModeConfig) Validate() error {
+	if c.SpoolPath == "" {
+		return fmt.Errorf("spool_path is required: deferred batches are acknowledged to Kafka once spooled")
+	}
+	return nil
+}
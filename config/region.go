@@ -0,0 +1,49 @@
+package config
+
+import "fmt"
+
+// RegionPeerConfig identifies the State DB of another region in an active-active deployment
+type RegionPeerConfig struct {
+	Name string `yaml:"name"` // Region name, e.g. "eu-west"
+	DSN  string `yaml:"dsn"`  // PostgreSQL connection string of that region's State DB
+}
+
+// RegionConfig defines deployment topology settings for cross-region active-active setups
+type RegionConfig struct {
+	Name         string             `yaml:"name"`          // Region this service runs in; empty disables region awareness
+	ScopedTopics bool               `yaml:"scoped_topics"` // Suffix Kafka topics with the region name
+	Reconcile    bool               `yaml:"reconcile"`     // Engine only: check peer regions before anchoring
+	Peers        []RegionPeerConfig `yaml:"peers"`         // Engine only: peer regions consulted when reconciling
+}
+
+// Topic returns the region-scoped name of a base topic, or the base topic if scoping is disabled
+func (c *RegionConfig) Topic(base string) string {
+	if !c.ScopedTopics || c.Name == "" {
+		return base
+	}
+	return base + "." + c.Name
+}
+
+// Validate validates the region configuration
+func (c *RegionConfig) Validate() error {
+	if c.ScopedTopics && c.Name == "" {
+		return fmt.Errorf("region.scoped_topics requires region.name")
+	}
+	if c.Reconcile {
+		if c.Name == "" {
+			return fmt.Errorf("region.reconcile requires region.name")
+		}
+		if len(c.Peers) == 0 {
+			return fmt.Errorf("region.reconcile requires at least one peer region")
+		}
+	}
+	for i, peer := range c.Peers {
+		if peer.Name == "" || peer.DSN == "" {
+			return fmt.Errorf("region.peers[%d]: name and dsn are required", i)
+		}
+		if peer.Name == c.Name {
+			return fmt.Errorf("region.peers[%d]: peer name must differ from the local region %q", i, c.Name)
+		}
+	}
+	return nil
+}
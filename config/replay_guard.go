@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReplayGuardConfig defines the pre-filter of redelivered messages after an
+// engine start: for Duration, each worker goroutine consumes messages in
+// windows, looks up their tasks in one query and acks the messages of
+// COMPLETED and FAILED tasks without batching them
+type ReplayGuardConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // Pre-filter messages of terminal tasks after startup
+	Duration      time.Duration `yaml:"duration"`       // How long after startup messages are pre-filtered
+	Window        int           `yaml:"window"`         // Most messages looked up in one query
+	WindowTimeout time.Duration `yaml:"window_timeout"` // Longest wait for more messages after the first of a window
+}
+
+// SetDefaults sets reasonable default values for the replay guard; a window
+// holds one batch of the configured worker batch size unless set
+func (c *ReplayGuardConfig) SetDefaults(batchSize int) {
+	if c.Duration <= 0 {
+		c.Duration = 10 * time.Minute
+		fmt.Printf("Warning: replay_guard.duration not set, defaulting to %v\n", c.Duration)
+	}
+	if c.Window <= 0 {
+		c.Window = batchSize
+		fmt.Printf("Warning: replay_guard.window not set, defaulting to %d (worker.batch_size)\n", c.Window)
+	}
+	if c.WindowTimeout <= 0 {
+		c.WindowTimeout = 20 * time.Millisecond
+		fmt.Printf("Warning: replay_guard.window_timeout not set, defaulting to %v\n", c.WindowTimeout)
+	}
+}
+
+// Validate validates the window timeout against the worker's batch timeout
+func (c *ReplayGuardConfig) Validate(batchTimeout time.Duration) error {
+	if c.WindowTimeout >= batchTimeout {
+		return fmt.Errorf("window_timeout (%v) must be below worker.batch_timeout (%v)", c.WindowTimeout, batchTimeout)
+	}
+	return nil
+}
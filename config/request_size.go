@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+)
+
+// RequestSizeConfig defines the largest submission the gateway accepts: HTTP
+// request bodies and gRPC request messages, by default and per org tier
+type RequestSizeConfig struct {
+	HTTPMaxBytes int64             `yaml:"http_max_bytes"` // Default limit of HTTP request bodies, decompressed
+	GRPCMaxBytes int64             `yaml:"grpc_max_bytes"` // Default limit of gRPC request messages
+	Tiers        map[string]int64  `yaml:"tiers"`          // Tier name -> limit for both transports, e.g. {"premium": 52428800}
+	Orgs         map[string]string `yaml:"orgs"`           // Org ID -> tier; other orgs get the defaults
+
+	HTTPBatchMaxEntries  int `yaml:"http_batch_max_entries"`  // Most entries in one POST /v1/logs:batch request
+	GRPCStreamMaxEntries int `yaml:"grpc_stream_max_entries"` // Most entries read from one SubmitLogStream call
+}
+
+// SetDefaults sets the default request size limits
+func (c *RequestSizeConfig) SetDefaults() {
+	if c.HTTPMaxBytes <= 0 {
+		c.HTTPMaxBytes = 10 << 20
+		fmt.Printf("Warning: request_size.http_max_bytes not set, defaulting to %d\n", c.HTTPMaxBytes)
+	}
+	if c.GRPCMaxBytes <= 0 {
+		c.GRPCMaxBytes = 4 << 20
+		fmt.Printf("Warning: request_size.grpc_max_bytes not set, defaulting to %d\n", c.GRPCMaxBytes)
+	}
+	if c.HTTPBatchMaxEntries <= 0 {
+		c.HTTPBatchMaxEntries = 1000
+		fmt.Printf("Warning: request_size.http_batch_max_entries not set, defaulting to %d\n", c.HTTPBatchMaxEntries)
+	}
+	if c.GRPCStreamMaxEntries <= 0 {
+		c.GRPCStreamMaxEntries = 10000
+		fmt.Printf("Warning: request_size.grpc_stream_max_entries not set, defaulting to %d\n", c.GRPCStreamMaxEntries)
+	}
+}
+
+// Validate validates the tiers and the orgs' tier assignments
+func (c *RequestSizeConfig) Validate() error {
+	for tier, limit := range c.Tiers {
+		if limit <= 0 {
+			return fmt.Errorf("tier '%s' limit must be positive", tier)
+		}
+	}
+	for org, tier := range c.Orgs {
+		if _, ok := c.Tiers[tier]; !ok {
+			return fmt.Errorf("org '%s' is assigned unknown tier '%s'", org, tier)
+		}
+	}
+	return nil
+}
+
+// LimitFor returns the limit that applies to an org's requests and the tier it
+// comes from; orgs without a tier get defaultLimit and an empty tier
+func (c *RequestSizeConfig) LimitFor(orgID string, defaultLimit int64) (int64, string) {
+	if tier, ok := c.Orgs[orgID]; ok {
+		return c.Tiers[tier], tier
+	}
+	return defaultLimit, ""
+}
+
+// Largest returns the largest limit of any org, to bound reads before the org is known
+func (c *RequestSizeConfig) Largest(defaultLimit int64) int64 {
+	largest := defaultLimit
+	for _, tier := range c.Orgs {
+		largest = max(largest, c.Tiers[tier])
+	}
+	return largest
+}
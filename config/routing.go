@@ -0,0 +1,60 @@
+package config
+
+import "fmt"
+
+// RoutingConfig maps orgs to their own blockchain targets, for consortiums
+// whose members anchor on their own contracts, chains or ChainMaker chain IDs.
+// Each target has its own blockchain client configuration; orgs not mapped to a
+// target anchor through blockchain_client_config_path.
+type RoutingConfig struct {
+	Targets []RoutingTarget `yaml:"targets"`
+}
+
+// RoutingTarget is a blockchain target and the orgs anchoring through it
+type RoutingTarget struct {
+	Name                       string   `yaml:"name"`
+	BlockchainClientConfigPath string   `yaml:"blockchain_client_config_path"` // Chain-specific config is read from clients/ next to it
+	Orgs                       []string `yaml:"orgs"`
+}
+
+// OrgTargets returns the target name of every routed org
+func (c *RoutingConfig) OrgTargets() map[string]string {
+	targets := make(map[string]string)
+	for _, t := range c.Targets {
+		for _, org := range t.Orgs {
+			targets[org] = t.Name
+		}
+	}
+	return targets
+}
+
+// Validate validates the routing configuration
+func (c *RoutingConfig) Validate() error {
+	names := make(map[string]bool)
+	orgs := make(map[string]string)
+	for i, t := range c.Targets {
+		if t.Name == "" {
+			return fmt.Errorf("targets[%d]: name is required", i)
+		}
+		if t.Name == "default" {
+			return fmt.Errorf("targets[%d]: name 'default' is reserved for blockchain_client_config_path", i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("duplicate target '%s'", t.Name)
+		}
+		names[t.Name] = true
+		if t.BlockchainClientConfigPath == "" {
+			return fmt.Errorf("target '%s': blockchain_client_config_path is required", t.Name)
+		}
+		if len(t.Orgs) == 0 {
+			return fmt.Errorf("target '%s': no orgs", t.Name)
+		}
+		for _, org := range t.Orgs {
+			if other, ok := orgs[org]; ok {
+				return fmt.Errorf("org '%s' is routed to both '%s' and '%s'", org, other, t.Name)
+			}
+			orgs[org] = t.Name
+		}
+	}
+	return nil
+}
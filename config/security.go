@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SecurityProfile selects how configuration combinations that weaken the
+// security posture (plaintext Kafka, unauthenticated public listeners, ...)
+// are treated at startup
+type SecurityProfile string
+
+// Security profiles
+const (
+	SecurityProfileStrict  SecurityProfile = "strict"  // Refuse to start on any violation
+	SecurityProfileLenient SecurityProfile = "lenient" // Start anyway and print a warning per violation
+)
+
+// SetDefaults sets the default security profile
+func (p *SecurityProfile) SetDefaults() {
+	if *p == "" {
+		*p = SecurityProfileLenient
+		fmt.Printf("Warning: security_profile not set, defaulting to %s\n", *p)
+	}
+}
+
+// Validate validates the security profile
+func (p SecurityProfile) Validate() error {
+	switch p {
+	case SecurityProfileStrict, SecurityProfileLenient:
+		return nil
+	default:
+		return fmt.Errorf("unknown security_profile '%s' (want strict or lenient)", p)
+	}
+}
+
+// Check evaluates the violations found in a service's configuration: the
+// strict profile rejects them all at once, the lenient one prints each as a
+// warning
+func (p SecurityProfile) Check(violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	if p == SecurityProfileStrict {
+		return fmt.Errorf("strict profile violated: %s", strings.Join(violations, "; "))
+	}
+	for _, v := range violations {
+		fmt.Printf("Warning: security_profile %s: %s\n", p, v)
+	}
+	return nil
+}
+
+// kafkaTLSViolations reports a Kafka client that does not verify its brokers over TLS
+func kafkaTLSViolations(prefix string, c *KafkaTLSConfig) []string {
+	if !c.Enabled {
+		return []string{fmt.Sprintf("%s connects to Kafka without TLS (set %s.tls.enabled)", prefix, prefix)}
+	}
+	if c.InsecureSkipVerify {
+		return []string{fmt.Sprintf("%s.tls.insecure_skip_verify disables broker certificate verification", prefix)}
+	}
+	return nil
+}
+
+// messagingViolations reports a message queue client that does not verify its servers over TLS
+func messagingViolations(m *MessagingConfig, kafkaPrefix string, kafkaTLS *KafkaTLSConfig) []string {
+	if !m.UsesNATS() {
+		return kafkaTLSViolations(kafkaPrefix, kafkaTLS)
+	}
+	if !m.NATS.TLS.Enabled {
+		return []string{"messaging.nats connects to NATS without TLS (set messaging.nats.tls.enabled)"}
+	}
+	if m.NATS.TLS.InsecureSkipVerify {
+		return []string{"messaging.nats.tls.insecure_skip_verify disables server certificate verification"}
+	}
+	return nil
+}
+
+// databaseViolations reports a PostgreSQL connection with TLS disabled
+func databaseViolations(prefix string, c *DatabaseConfig) []string {
+	if strings.Contains(c.DSN, "sslmode=disable") {
+		return []string{fmt.Sprintf("%s.dsn disables TLS (sslmode=disable)", prefix)}
+	}
+	return nil
+}
+
+// plaintextURLViolations reports an endpoint reached over plain HTTP
+func plaintextURLViolations(key, url string) []string {
+	if strings.HasPrefix(url, "http://") {
+		return []string{fmt.Sprintf("%s %s is plain HTTP", key, url)}
+	}
+	return nil
+}
+
+// tracingViolations reports spans exported in plaintext to a collector off the host
+func tracingViolations(c *TracingConfig) []string {
+	if !c.Enabled || !c.Insecure {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(c.Endpoint)
+	if err != nil {
+		host = c.Endpoint
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+	return []string{fmt.Sprintf("tracing.endpoint %s is reached without TLS (tracing.insecure)", c.Endpoint)}
+}
+
+// isPublicBind reports whether a listen address accepts connections from
+// outside the host or private network: wildcard binds, public IPs and
+// hostnames other than localhost
+func isPublicBind(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if host == "" {
+		return true
+	}
+	if host == "localhost" {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast())
+}
+
+// SecurityViolations lists the gateway configuration combinations that
+// weaken its security posture
+func (c *ApiGatewayConfig) SecurityViolations() []string {
+	violations := messagingViolations(&c.Messaging, "kafka_producer", &c.KafkaProducer.TLS)
+	violations = append(violations, databaseViolations("database", &c.Database)...)
+	violations = append(violations, tracingViolations(&c.Tracing)...)
+	if c.AnomalyDetection.Enabled {
+		violations = append(violations, plaintextURLViolations("anomaly_detection.webhook.url", c.AnomalyDetection.Webhook.URL)...)
+	}
+	if !c.IngressAuth && !c.ServiceAuth.Enabled() && !c.APITokens.Enabled {
+		listeners := []struct {
+			key, addr string
+			tls       *ServerTLSConfig
+		}{
+			{"http_listen_addr", c.HttpListenAddr, &c.HttpTLS},
+			{"grpc_listen_addr", c.GrpcListenAddr, &c.GrpcTLS},
+		}
+		for _, l := range listeners {
+			if l.addr != "" && isPublicBind(l.addr) && !(l.tls.Enabled && l.tls.RequireClientCert) {
+				violations = append(violations, fmt.Sprintf(
+					"%s %s binds a public address but the gateway does not authenticate submissions (bind a private address, enable service_auth, api_tokens or mutual TLS, or front it with an authenticating ingress and set ingress_auth)",
+					l.key, l.addr))
+			}
+		}
+	}
+	// Syslog carries no credentials, so only mutual TLS authenticates its senders
+	if c.Syslog.Enabled {
+		for _, l := range []struct{ key, addr string }{
+			{"syslog.udp_listen_addr", c.Syslog.UDPListenAddr},
+			{"syslog.tcp_listen_addr", c.Syslog.TCPListenAddr},
+		} {
+			if l.addr == "" || !isPublicBind(l.addr) {
+				continue
+			}
+			if l.key == "syslog.tcp_listen_addr" && c.Syslog.TLS.Enabled && c.Syslog.TLS.RequireClientCert {
+				continue
+			}
+			violations = append(violations, fmt.Sprintf(
+				"%s %s binds a public address but syslog senders are not authenticated (bind a private address, or serve TCP with mutual TLS)",
+				l.key, l.addr))
+		}
+	}
+	return violations
+}
+
+// SecurityViolations lists the engine configuration combinations that
+// weaken its security posture
+func (c *EngineConfig) SecurityViolations() []string {
+	violations := messagingViolations(&c.Messaging, "kafka_consumer", &c.KafkaConsumer.TLS)
+	violations = append(violations, databaseViolations("database", &c.Database)...)
+	if c.ClickHouse.Enabled {
+		violations = append(violations, plaintextURLViolations("clickhouse.url", c.ClickHouse.URL)...)
+	}
+	violations = append(violations, tracingViolations(&c.Tracing)...)
+	if addr := c.Monitoring.AdminGRPCAddr; addr != "" && isPublicBind(addr) && !c.ServiceAuth.Enabled() {
+		violations = append(violations, fmt.Sprintf(
+			"monitoring.admin_grpc_addr %s binds a public address without service_auth", addr))
+	}
+	return violations
+}
+
+// SecurityViolations lists the archiver configuration combinations that
+// weaken its security posture
+func (c *ArchiverConfig) SecurityViolations() []string {
+	violations := kafkaTLSViolations("kafka_consumer", &c.KafkaConsumer.TLS)
+	violations = append(violations, plaintextURLViolations("object_store.endpoint", c.ObjectStore.Endpoint)...)
+	if c.Export.Enabled {
+		violations = append(violations, databaseViolations("database", &c.Database)...)
+	}
+	return violations
+}
+
+// SecurityViolations lists the query service configuration combinations that
+// weaken its security posture
+func (c *QueryConfig) SecurityViolations() []string {
+	return databaseViolations("database", &c.Database)
+}
+
+// SecurityViolations lists the verifier configuration combinations that
+// weaken its security posture
+func (c *VerifierConfig) SecurityViolations() []string {
+	violations := databaseViolations("database", &c.Database)
+	if isPublicBind(c.HTTPListenAddr) && !(c.TLS.Enabled && c.TLS.RequireClientCert) {
+		violations = append(violations, fmt.Sprintf(
+			"http_listen_addr %s binds a public address without mutual TLS (bind a private address or set tls.require_client_cert)",
+			c.HTTPListenAddr))
+	}
+	return violations
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SelfTestConfig defines the end-to-end self-test run by POST /admin/selftest:
+// a synthetic submission followed through the State DB, the engine and the chain
+type SelfTestConfig struct {
+	Enabled          bool          `yaml:"enabled"`
+	OrgID            string        `yaml:"org_id"`            // Org the synthetic logs are submitted under
+	Application      string        `yaml:"application"`       // Application field tagging the synthetic logs
+	Timeout          time.Duration `yaml:"timeout"`           // Budget for the whole run, anchoring included
+	PollInterval     time.Duration `yaml:"poll_interval"`     // How often the State DB is checked for progress
+	ChainMakerConfig string        `yaml:"chainmaker_config"` // ChainMaker SDK configuration used to read the log back from the chain
+}
+
+// SetDefaults sets reasonable default values for the self-test
+func (c *SelfTestConfig) SetDefaults() {
+	if c.OrgID == "" {
+		c.OrgID = "tlng-selftest"
+		fmt.Printf("Warning: self_test.org_id not set, defaulting to %s\n", c.OrgID)
+	}
+	if c.Application == "" {
+		c.Application = "tlng-selftest"
+		fmt.Printf("Warning: self_test.application not set, defaulting to %s\n", c.Application)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 2 * time.Minute
+		fmt.Printf("Warning: self_test.timeout not set, defaulting to %v\n", c.Timeout)
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 500 * time.Millisecond
+		fmt.Printf("Warning: self_test.poll_interval not set, defaulting to %v\n", c.PollInterval)
+	}
+}
+
+// Validate validates the self-test settings
+func (c *SelfTestConfig) Validate() error {
+	if c.PollInterval >= c.Timeout {
+		return fmt.Errorf("poll_interval (%v) must be shorter than timeout (%v)", c.PollInterval, c.Timeout)
+	}
+	if c.ChainMakerConfig == "" {
+		return fmt.Errorf("chainmaker_config is required to verify the synthetic log on chain")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// ServerTLSConfig defines TLS for a listener. With a client CA the listener
+// verifies client certificates, and with require_client_cert it requires one
+// (mutual TLS); org_from then takes the caller's source org from the
+// certificate subject instead of trusting the submission.
+type ServerTLSConfig struct {
+	Enabled           bool   `yaml:"enabled"`
+	CertFile          string `yaml:"cert_file"`           // Server certificate chain (leaf first)
+	KeyFile           string `yaml:"key_file"`            // Server private key
+	ClientCAFile      string `yaml:"client_ca_file"`      // PEM CA bundle client certificates are verified against; empty accepts no client certificates
+	RequireClientCert bool   `yaml:"require_client_cert"` // Reject connections without a verified client certificate
+	OrgFrom           string `yaml:"org_from"`            // Subject field holding the source org: organization, organizational_unit or common_name; "" to not derive it
+}
+
+// Client certificate subject fields the source org may be taken from
+const (
+	CertOrgFromOrganization       = "organization"        // First O
+	CertOrgFromOrganizationalUnit = "organizational_unit" // First OU
+	CertOrgFromCommonName         = "common_name"         // CN
+)
+
+// Validate validates the listener TLS configuration
+func (c *ServerTLSConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file are required")
+	}
+	if c.RequireClientCert && c.ClientCAFile == "" {
+		return fmt.Errorf("require_client_cert needs client_ca_file")
+	}
+	switch c.OrgFrom {
+	case "":
+	case CertOrgFromOrganization, CertOrgFromOrganizationalUnit, CertOrgFromCommonName:
+		if c.ClientCAFile == "" {
+			return fmt.Errorf("org_from needs client_ca_file")
+		}
+	default:
+		return fmt.Errorf("invalid org_from '%s' (expected %s, %s or %s)",
+			c.OrgFrom, CertOrgFromOrganization, CertOrgFromOrganizationalUnit, CertOrgFromCommonName)
+	}
+	return nil
+}
+
+// TLSConfig builds the server TLS configuration; nil when TLS is disabled
+func (c *ServerTLSConfig) TLSConfig() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	tlsCfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+	}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file '%s': %w", c.ClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file '%s'", c.ClientCAFile)
+		}
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.RequireClientCert {
+			tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsCfg, nil
+}
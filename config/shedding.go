@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Submission priorities, highest first
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// PriorityRank orders priorities: a lower rank is shed first. Unknown
+// priorities rank as normal.
+func PriorityRank(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 2
+	case PriorityLow:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// LoadSheddingConfig defines adaptive load shedding in the gateway. Every
+// Interval it compares the downstream error rate (batches failing to reach
+// the State DB or Kafka) and the queue depth (accepted submissions not yet
+// persisted) with their thresholds. While either is exceeded, ShedFraction of
+// the submissions at or below ShedPriority are rejected with 503 /
+// UNAVAILABLE, so the error budget is spent on high-priority traffic.
+type LoadSheddingConfig struct {
+	Enabled       bool              `yaml:"enabled"`         // Enable load shedding
+	MaxErrorRate  float64           `yaml:"max_error_rate"`  // Fraction of failed downstream entries per interval that starts shedding
+	MaxQueueDepth int64             `yaml:"max_queue_depth"` // Accepted but unpersisted submissions that start shedding; 0 disables the check
+	ShedFraction  float64           `yaml:"shed_fraction"`   // Fraction of eligible submissions rejected while shedding
+	ShedPriority  string            `yaml:"shed_priority"`   // Highest priority that is shed: low or normal
+	Interval      time.Duration     `yaml:"interval"`        // How often the signals are evaluated
+	RetryAfter    time.Duration     `yaml:"retry_after"`     // Retry-After sent with shed submissions
+	Priorities    map[string]string `yaml:"priorities"`      // Org ID -> high, normal or low
+	Default       string            `yaml:"default"`         // Priority of orgs not listed
+}
+
+// SetDefaults sets reasonable default values for load shedding
+func (c *LoadSheddingConfig) SetDefaults() {
+	if c.MaxErrorRate == 0 {
+		c.MaxErrorRate = 0.05
+		fmt.Printf("Warning: load_shedding.max_error_rate not set, defaulting to %g\n", c.MaxErrorRate)
+	}
+	if c.ShedFraction == 0 {
+		c.ShedFraction = 0.5
+		fmt.Printf("Warning: load_shedding.shed_fraction not set, defaulting to %g\n", c.ShedFraction)
+	}
+	if c.ShedPriority == "" {
+		c.ShedPriority = PriorityLow
+		fmt.Printf("Warning: load_shedding.shed_priority not set, defaulting to %s\n", c.ShedPriority)
+	}
+	if c.Interval == 0 {
+		c.Interval = 10 * time.Second
+		fmt.Printf("Warning: load_shedding.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.RetryAfter == 0 {
+		c.RetryAfter = 5 * time.Second
+		fmt.Printf("Warning: load_shedding.retry_after not set, defaulting to %v\n", c.RetryAfter)
+	}
+	if c.Default == "" {
+		c.Default = PriorityNormal
+		fmt.Printf("Warning: load_shedding.default not set, defaulting to %s\n", c.Default)
+	}
+}
+
+// Validate validates the load shedding configuration
+func (c *LoadSheddingConfig) Validate() error {
+	if c.MaxErrorRate <= 0 || c.MaxErrorRate > 1 {
+		return fmt.Errorf("max_error_rate must be in (0, 1]")
+	}
+	if c.MaxQueueDepth < 0 {
+		return fmt.Errorf("max_queue_depth must not be negative")
+	}
+	if c.ShedFraction <= 0 || c.ShedFraction > 1 {
+		return fmt.Errorf("shed_fraction must be in (0, 1]")
+	}
+	if c.ShedPriority != PriorityLow && c.ShedPriority != PriorityNormal {
+		return fmt.Errorf("invalid shed_priority '%s' (must be low or normal)", c.ShedPriority)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+	if c.RetryAfter < time.Second {
+		return fmt.Errorf("retry_after must be at least 1s")
+	}
+	for org, p := range c.Priorities {
+		if !validPriority(p) {
+			return fmt.Errorf("invalid priority '%s' for org '%s' (must be high, normal or low)", p, org)
+		}
+	}
+	if !validPriority(c.Default) {
+		return fmt.Errorf("invalid default priority '%s' (must be high, normal or low)", c.Default)
+	}
+	return nil
+}
+
+// Priority returns the priority of an org's submissions
+func (c *LoadSheddingConfig) Priority(orgID string) string {
+	if p, ok := c.Priorities[orgID]; ok {
+		return p
+	}
+	return c.Default
+}
+
+func validPriority(p string) bool {
+	return p == PriorityHigh || p == PriorityNormal || p == PriorityLow
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ShutdownConfig defines the graceful shutdown budget. Shutdown runs in two
+// phases: first stop taking new work and let in-flight work finish (Timeout),
+// then flush what was accepted (DrainTimeout). Work still unfinished when a
+// phase runs out is abandoned and reported in the final shutdown summary.
+type ShutdownConfig struct {
+	Timeout      time.Duration `yaml:"timeout"`       // Stop listeners (gateway) or finish in-flight batches (engine)
+	DrainTimeout time.Duration `yaml:"drain_timeout"` // Flush accepted work: batch processor (gateway), status events (engine)
+}
+
+// SetDefaults sets the service's default shutdown budget
+func (c *ShutdownConfig) SetDefaults(timeout, drainTimeout time.Duration) {
+	if c.Timeout == 0 {
+		c.Timeout = timeout
+		fmt.Printf("Warning: shutdown.timeout not set, defaulting to %v\n", c.Timeout)
+	}
+	if c.DrainTimeout == 0 {
+		c.DrainTimeout = drainTimeout
+		fmt.Printf("Warning: shutdown.drain_timeout not set, defaulting to %v\n", c.DrainTimeout)
+	}
+}
+
+// Validate validates the shutdown configuration
+func (c *ShutdownConfig) Validate() error {
+	if c.Timeout < 0 || c.DrainTimeout < 0 {
+		return fmt.Errorf("timeout and drain_timeout must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SizeTierConfig routes submissions whose log_content reaches a size threshold
+// through a separate batch path and Kafka topic at the API Gateway, so one
+// multi-megabyte log does not delay hundreds of small ones
+type SizeTierConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	ThresholdBytes int           `yaml:"threshold_bytes"` // log_content size from which a submission is large
+	Topic          string        `yaml:"topic"`           // Kafka topic for large submissions
+	BatchSize      int           `yaml:"batch_size"`      // Large submissions per batch
+	BatchTimeout   time.Duration `yaml:"batch_timeout"`   // Maximum wait time for a large batch
+	BatchBytes     int           `yaml:"batch_bytes"`     // Kafka producer batch limit for the large topic
+}
+
+// SetDefaults sets reasonable default values for size-tier routing
+func (c *SizeTierConfig) SetDefaults() {
+	if c.ThresholdBytes == 0 {
+		c.ThresholdBytes = 256 * 1024
+		fmt.Printf("Warning: size_tier.threshold_bytes not set, defaulting to %d\n", c.ThresholdBytes)
+	}
+	if c.Topic == "" {
+		c.Topic = "log_submissions_large"
+		fmt.Printf("Warning: size_tier.topic not set, defaulting to %s\n", c.Topic)
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 10
+		fmt.Printf("Warning: size_tier.batch_size not set, defaulting to %d\n", c.BatchSize)
+	}
+	if c.BatchTimeout == 0 {
+		c.BatchTimeout = 100 * time.Millisecond
+		fmt.Printf("Warning: size_tier.batch_timeout not set, defaulting to %v\n", c.BatchTimeout)
+	}
+	if c.BatchBytes == 0 {
+		c.BatchBytes = 16 * 1024 * 1024
+		fmt.Printf("Warning: size_tier.batch_bytes not set, defaulting to %d\n", c.BatchBytes)
+	}
+}
+
+// Validate validates the size-tier configuration
+func (c *SizeTierConfig) Validate() error {
+	if c.ThresholdBytes <= 0 {
+		return fmt.Errorf("threshold_bytes must be positive")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch_size must be positive")
+	}
+	if c.BatchBytes < c.ThresholdBytes {
+		return fmt.Errorf("batch_bytes (%d) must be at least threshold_bytes (%d)", c.BatchBytes, c.ThresholdBytes)
+	}
+	return nil
+}
+
+// SizeTierWorkerConfig defines the Attestation Engine's dedicated worker pool
+// for the large-submission topic, with smaller batches than the main pool
+type SizeTierWorkerConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Topic       string `yaml:"topic"`       // Same as the gateway's size_tier.topic
+	GroupID     string `yaml:"group_id"`    // Consumer group for the large topic
+	Count       int    `yaml:"count"`       // Number of consumers
+	Concurrency int    `yaml:"concurrency"` // Concurrent workers per consumer
+	BatchSize   int    `yaml:"batch_size"`  // Logs per blockchain batch
+}
+
+// SetDefaults sets reasonable default values for the large-submission worker pool
+func (c *SizeTierWorkerConfig) SetDefaults(groupID string) {
+	if c.Topic == "" {
+		c.Topic = "log_submissions_large"
+		fmt.Printf("Warning: size_tier.topic not set, defaulting to %s\n", c.Topic)
+	}
+	if c.GroupID == "" {
+		c.GroupID = groupID + "-large"
+		fmt.Printf("Warning: size_tier.group_id not set, defaulting to %s\n", c.GroupID)
+	}
+	if c.Count <= 0 {
+		c.Count = 1
+		fmt.Printf("Warning: size_tier.count not set or invalid, defaulting to %d\n", c.Count)
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+		fmt.Printf("Warning: size_tier.concurrency not set or invalid, defaulting to %d\n", c.Concurrency)
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 5
+		fmt.Printf("Warning: size_tier.batch_size not set or invalid, defaulting to %d\n", c.BatchSize)
+	}
+}
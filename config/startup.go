@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DependencyWaitConfig defines how long a service waits for one dependency at startup
+type DependencyWaitConfig struct {
+	Timeout        time.Duration `yaml:"timeout"`         // Total time to keep retrying before giving up
+	InitialBackoff time.Duration `yaml:"initial_backoff"` // Wait before the second attempt
+	MaxBackoff     time.Duration `yaml:"max_backoff"`     // Upper bound for exponential backoff
+}
+
+// SetDefaults sets reasonable default values for a dependency wait
+func (c *DependencyWaitConfig) SetDefaults(prefix string) {
+	if c.Timeout == 0 {
+		c.Timeout = 2 * time.Minute
+		fmt.Printf("Warning: %s.timeout not set, defaulting to %v\n", prefix, c.Timeout)
+	}
+	if c.InitialBackoff == 0 {
+		c.InitialBackoff = time.Second
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = 15 * time.Second
+	}
+}
+
+// Validate validates a dependency wait
+func (c *DependencyWaitConfig) Validate() error {
+	if c.Timeout < 0 || c.InitialBackoff <= 0 || c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("timeout must not be negative, and backoffs must be positive with max_backoff >= initial_backoff")
+	}
+	return nil
+}
+
+// StartupConfig defines how services wait for their dependencies and check
+// them before declaring themselves ready
+type StartupConfig struct {
+	Database   DependencyWaitConfig `yaml:"database"`
+	Kafka      DependencyWaitConfig `yaml:"kafka"`
+	Blockchain DependencyWaitConfig `yaml:"blockchain"` // Engine only
+
+	SkipSelfCheck    bool          `yaml:"skip_self_check"`    // Skip the round-trip checks run before serving
+	SelfCheckTimeout time.Duration `yaml:"self_check_timeout"` // Timeout for each self-check
+}
+
+// SetDefaults sets reasonable default values for startup configuration
+func (c *StartupConfig) SetDefaults() {
+	c.Database.SetDefaults("startup.database")
+	c.Kafka.SetDefaults("startup.kafka")
+	c.Blockchain.SetDefaults("startup.blockchain")
+	if c.SelfCheckTimeout == 0 {
+		c.SelfCheckTimeout = 10 * time.Second
+		fmt.Printf("Warning: startup.self_check_timeout not set, defaulting to %v\n", c.SelfCheckTimeout)
+	}
+}
+
+// Validate validates the startup configuration
+func (c *StartupConfig) Validate() error {
+	if err := c.Database.Validate(); err != nil {
+		return fmt.Errorf("database: %w", err)
+	}
+	if err := c.Kafka.Validate(); err != nil {
+		return fmt.Errorf("kafka: %w", err)
+	}
+	if err := c.Blockchain.Validate(); err != nil {
+		return fmt.Errorf("blockchain: %w", err)
+	}
+	if c.SelfCheckTimeout <= 0 {
+		return fmt.Errorf("self_check_timeout must be positive")
+	}
+	return nil
+}
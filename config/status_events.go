@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatusEventsConfig defines the optional Kafka topic of status transition
+// events, published as tlng.internal.StatusChangeEvent protobuf messages to
+// the kafka_consumer brokers
+type StatusEventsConfig struct {
+	Enabled      bool          `yaml:"enabled"`       // Publish status events to Kafka
+	Topic        string        `yaml:"topic"`         // Target topic
+	BufferSize   int           `yaml:"buffer_size"`   // Event batches queued before new events are dropped
+	WriteTimeout time.Duration `yaml:"write_timeout"` // Timeout per batch write; a failed batch is dropped
+}
+
+// SetDefaults sets reasonable default values for status event publishing
+func (c *StatusEventsConfig) SetDefaults() {
+	if c.Topic == "" {
+		c.Topic = "log_status_events"
+		fmt.Printf("Warning: status_events.topic not set, defaulting to %s\n", c.Topic)
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = 1024
+		fmt.Printf("Warning: status_events.buffer_size not set or invalid, defaulting to %d\n", c.BufferSize)
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 10 * time.Second
+		fmt.Printf("Warning: status_events.write_timeout not set, defaulting to %v\n", c.WriteTimeout)
+	}
+}
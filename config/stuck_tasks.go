@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// StuckTasksConfig defines the engine's scan for tasks left PROCESSING by a
+// worker that crashed or hung after locking them. Each stuck task is looked up
+// on chain: anchored ones are marked COMPLETED, the others are returned to
+// RECEIVED counting a retry.
+type StuckTasksConfig struct {
+	Enabled   bool          `yaml:"enabled"`    // Scan for stuck PROCESSING tasks
+	Interval  time.Duration `yaml:"interval"`   // How often the State DB is scanned
+	Threshold time.Duration `yaml:"threshold"`  // Time in PROCESSING after which a task is stuck
+	BatchSize int           `yaml:"batch_size"` // Stuck tasks resolved per scan
+}
+
+// SetDefaults sets reasonable default values for the stuck task scan
+func (c *StuckTasksConfig) SetDefaults() {
+	if c.Interval <= 0 {
+		c.Interval = time.Minute
+		fmt.Printf("Warning: stuck_tasks.interval not set, defaulting to %v\n", c.Interval)
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 10 * time.Minute
+		fmt.Printf("Warning: stuck_tasks.threshold not set, defaulting to %v\n", c.Threshold)
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+		fmt.Printf("Warning: stuck_tasks.batch_size not set, defaulting to %d\n", c.BatchSize)
+	}
+}
+
+// Validate validates the stuck task scan against the worker's blockchain
+// timeout: a batch still waiting for the chain must not be taken for stuck
+func (c *StuckTasksConfig) Validate(blockchainTimeout time.Duration) error {
+	if c.Threshold <= blockchainTimeout {
+		return fmt.Errorf("threshold (%v) must exceed worker.blockchain_timeout (%v), or batches still being anchored are taken for stuck", c.Threshold, blockchainTimeout)
+	}
+	return nil
+}
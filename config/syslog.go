@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// SyslogConfig defines the gateway's syslog receiver, for sources that can
+// only emit syslog. It accepts RFC 5424 and RFC 3164 frames over UDP and TCP
+// (octet-counted or newline-delimited), optionally over TLS, and submits each
+// one to the org given by the first matching org rule.
+type SyslogConfig struct {
+	Enabled        bool            `yaml:"enabled"`
+	UDPListenAddr  string          `yaml:"udp_listen_addr"`  // One frame per datagram; empty disables UDP
+	TCPListenAddr  string          `yaml:"tcp_listen_addr"`  // Stream of frames; empty disables TCP
+	TLS            ServerTLSConfig `yaml:"tls"`              // TLS (RFC 5425) of the TCP listener, optionally mutual
+	MaxMessageSize int             `yaml:"max_message_size"` // Longest accepted frame in bytes
+	IdleTimeout    time.Duration   `yaml:"idle_timeout"`     // TCP connections idle this long are closed
+	Content        string          `yaml:"content"`          // Attested log content: frame or message
+	OrgRules       []SyslogOrgRule `yaml:"org_rules"`        // Evaluated in order, the first match names the org
+	DefaultOrgID   string          `yaml:"default_org_id"`   // Org of frames matching no rule; empty drops them
+}
+
+// SyslogOrgRule maps frames to a source org by hostname and facility; a rule
+// with both matches frames matching both
+type SyslogOrgRule struct {
+	Hostname string `yaml:"hostname"` // Glob pattern (path.Match) of the frame's HOSTNAME; empty matches any
+	Facility string `yaml:"facility"` // Facility keyword (kern, user, ..., local7); empty matches any
+	OrgID    string `yaml:"org_id"`
+}
+
+// Attested content of a syslog frame
+const (
+	SyslogContentFrame   = "frame"   // The frame as received, without its transport framing
+	SyslogContentMessage = "message" // The MSG part only
+)
+
+// SyslogFacilities lists the facility keywords by facility number
+var SyslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "audit", "alert", "clock",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// SetDefaults sets reasonable default values for the syslog receiver
+func (c *SyslogConfig) SetDefaults() {
+	if c.MaxMessageSize <= 0 {
+		c.MaxMessageSize = 64 * 1024
+		fmt.Printf("Warning: syslog.max_message_size not set, defaulting to %d\n", c.MaxMessageSize)
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 5 * time.Minute
+		fmt.Printf("Warning: syslog.idle_timeout not set, defaulting to %v\n", c.IdleTimeout)
+	}
+	if c.Content == "" {
+		c.Content = SyslogContentFrame
+		fmt.Printf("Warning: syslog.content not set, defaulting to %s\n", c.Content)
+	}
+}
+
+// Validate validates the listeners and org rules
+func (c *SyslogConfig) Validate() error {
+	if c.UDPListenAddr == "" && c.TCPListenAddr == "" {
+		return fmt.Errorf("at least one of udp_listen_addr or tcp_listen_addr must be configured")
+	}
+	if c.TLS.Enabled && c.TCPListenAddr == "" {
+		return fmt.Errorf("tls needs tcp_listen_addr")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	if c.Content != SyslogContentFrame && c.Content != SyslogContentMessage {
+		return fmt.Errorf("invalid content '%s' (must be %s or %s)", c.Content, SyslogContentFrame, SyslogContentMessage)
+	}
+	for i, rule := range c.OrgRules {
+		if rule.OrgID == "" {
+			return fmt.Errorf("org_rules[%d]: org_id is required", i)
+		}
+		if _, err := path.Match(rule.Hostname, ""); err != nil {
+			return fmt.Errorf("org_rules[%d]: invalid hostname pattern '%s': %w", i, rule.Hostname, err)
+		}
+		if rule.Facility != "" && SyslogFacility(rule.Facility) < 0 {
+			return fmt.Errorf("org_rules[%d]: unknown facility '%s'", i, rule.Facility)
+		}
+	}
+	if len(c.OrgRules) == 0 && c.DefaultOrgID == "" && c.TLS.OrgFrom == "" {
+		return fmt.Errorf("org_rules, default_org_id or tls.org_from is required to attribute frames to an org")
+	}
+	return nil
+}
+
+// SyslogFacility returns the number of a facility keyword, -1 if unknown
+func SyslogFacility(name string) int {
+	for i, f := range SyslogFacilities {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// OrgFor returns the org of a frame from hostname with facility number
+// facility, "" if no rule matches and there is no default org
+func (c *SyslogConfig) OrgFor(hostname string, facility int) string {
+	for _, rule := range c.OrgRules {
+		if rule.Hostname != "" {
+			if ok, _ := path.Match(rule.Hostname, hostname); !ok {
+				continue
+			}
+		}
+		if rule.Facility != "" && SyslogFacility(rule.Facility) != facility {
+			continue
+		}
+		return rule.OrgID
+	}
+	return c.DefaultOrgID
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// TestTrafficConfig defines the handling of submissions flagged as synthetic or
+// test traffic: they go through the full pipeline and are anchored, but are not
+// counted against quotas or in statistics, and are purged after their TTL
+type TestTrafficConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // Accept the test traffic flag; flagged submissions are rejected otherwise
+	Orgs          []string      `yaml:"orgs"`           // Orgs allowed to flag test traffic; empty allows every org
+	TTL           time.Duration `yaml:"ttl"`            // How long finished test submissions are kept
+	PurgeInterval time.Duration `yaml:"purge_interval"` // How often expired test submissions are deleted
+}
+
+// SetDefaults sets reasonable default values for test traffic
+func (c *TestTrafficConfig) SetDefaults() {
+	if c.TTL <= 0 {
+		c.TTL = 24 * time.Hour
+		fmt.Printf("Warning: test_traffic.ttl not set, defaulting to %v\n", c.TTL)
+	}
+	if c.PurgeInterval <= 0 {
+		c.PurgeInterval = 10 * time.Minute
+		fmt.Printf("Warning: test_traffic.purge_interval not set, defaulting to %v\n", c.PurgeInterval)
+	}
+}
+
+// Validate validates the test traffic settings
+func (c *TestTrafficConfig) Validate() error {
+	for i, org := range c.Orgs {
+		if org == "" {
+			return fmt.Errorf("orgs[%d] is empty", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timestamp policy actions
+const (
+	TimestampReject = "reject" // Reject the submission
+	TimestampClamp  = "clamp"  // Accept, moving the client timestamp to the nearest allowed bound
+	TimestampIgnore = "ignore" // Accept, discarding the client timestamp
+)
+
+// TimestampPolicyConfig defines how client-supplied timestamps are validated
+// against the server clock before they are stored and anchored
+type TimestampPolicyConfig struct {
+	MaxFutureSkew time.Duration `yaml:"max_future_skew"` // How far ahead of server time a client timestamp may be
+	MaxPastSkew   time.Duration `yaml:"max_past_skew"`   // How far behind server time a client timestamp may be
+	OnSkew        string        `yaml:"on_skew"`         // reject, clamp or ignore when outside the skew window
+	OnInvalid     string        `yaml:"on_invalid"`      // reject or ignore when the timestamp cannot be parsed
+}
+
+// SetDefaults sets reasonable default values for the timestamp policy
+func (c *TimestampPolicyConfig) SetDefaults() {
+	if c.MaxFutureSkew <= 0 {
+		c.MaxFutureSkew = 5 * time.Minute
+		fmt.Printf("Warning: timestamp_policy.max_future_skew not set, defaulting to %v\n", c.MaxFutureSkew)
+	}
+	if c.MaxPastSkew <= 0 {
+		c.MaxPastSkew = 24 * time.Hour
+		fmt.Printf("Warning: timestamp_policy.max_past_skew not set, defaulting to %v\n", c.MaxPastSkew)
+	}
+	if c.OnSkew == "" {
+		c.OnSkew = TimestampClamp
+		fmt.Printf("Warning: timestamp_policy.on_skew not set, defaulting to %s\n", c.OnSkew)
+	}
+	if c.OnInvalid == "" {
+		c.OnInvalid = TimestampReject
+		fmt.Printf("Warning: timestamp_policy.on_invalid not set, defaulting to %s\n", c.OnInvalid)
+	}
+}
+
+// Validate validates the timestamp policy
+func (c *TimestampPolicyConfig) Validate() error {
+	switch c.OnSkew {
+	case TimestampReject, TimestampClamp, TimestampIgnore:
+	default:
+		return fmt.Errorf("invalid on_skew '%s' (must be reject, clamp or ignore)", c.OnSkew)
+	}
+	switch c.OnInvalid {
+	case TimestampReject, TimestampIgnore:
+	default:
+		return fmt.Errorf("invalid on_invalid '%s' (must be reject or ignore)", c.OnInvalid)
+	}
+	return nil
+}
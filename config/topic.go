@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// KafkaTopicConfig defines startup verification (and optional creation) of a Kafka topic
+type KafkaTopicConfig struct {
+	Verify            bool          `yaml:"verify"`             // Check the topic exists with the expected layout at startup
+	Create            bool          `yaml:"create"`             // Create the topic if it is missing (implies verify)
+	Partitions        int           `yaml:"partitions"`         // Expected (and created) partition count; 0 skips the check
+	ReplicationFactor int           `yaml:"replication_factor"` // Expected (and created) replication factor; 0 skips the check
+	Timeout           time.Duration `yaml:"timeout"`            // Timeout for the metadata and create requests
+}
+
+// Enabled reports whether the topic should be checked at startup
+func (c *KafkaTopicConfig) Enabled() bool {
+	return c.Verify || c.Create
+}
+
+// SetDefaults sets reasonable default values for topic verification
+func (c *KafkaTopicConfig) SetDefaults(prefix string) {
+	if !c.Enabled() {
+		return
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+		fmt.Printf("Warning: %s.topic_check.timeout not set, defaulting to %v\n", prefix, c.Timeout)
+	}
+}
+
+// Validate validates the topic verification configuration
+func (c *KafkaTopicConfig) Validate() error {
+	if c.Partitions < 0 || c.ReplicationFactor < 0 {
+		return fmt.Errorf("topic_check.partitions and topic_check.replication_factor must not be negative")
+	}
+	if c.Create && (c.Partitions == 0 || c.ReplicationFactor == 0) {
+		return fmt.Errorf("topic_check.create requires topic_check.partitions and topic_check.replication_factor")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TracingConfig defines OpenTelemetry tracing, exported over OTLP/gRPC. Trace
+// context is propagated in W3C traceparent headers over HTTP, gRPC and Kafka
+// whether or not tracing is enabled.
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`      // Export spans
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/gRPC collector address (host:port)
+	Insecure    bool    `yaml:"insecure"`     // Connect to the collector without TLS
+	ServiceName string  `yaml:"service_name"` // service.name resource attribute
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces sampled; traces sampled upstream are always kept
+}
+
+// SetDefaults sets reasonable default values for tracing
+func (c *TracingConfig) SetDefaults(serviceName string) {
+	if c.Endpoint == "" {
+		c.Endpoint = "localhost:4317"
+		fmt.Printf("Warning: tracing.endpoint not set, defaulting to %s\n", c.Endpoint)
+	}
+	if c.ServiceName == "" {
+		c.ServiceName = serviceName
+		fmt.Printf("Warning: tracing.service_name not set, defaulting to %s\n", c.ServiceName)
+	}
+	if c.SampleRatio == 0 {
+		c.SampleRatio = 1
+		fmt.Printf("Warning: tracing.sample_ratio not set, defaulting to %g\n", c.SampleRatio)
+	}
+}
+
+// Validate checks the tracing configuration
+func (c *TracingConfig) Validate() error {
+	if c.Endpoint == "" {
+		return errors.New("endpoint is required")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1, got %g", c.SampleRatio)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// VerifierConfig defines the configuration of the standalone verification service
+type VerifierConfig struct {
+	HTTPListenAddr string          `yaml:"http_listen_addr"`
+	ReadTimeout    time.Duration   `yaml:"read_timeout"`
+	WriteTimeout   time.Duration   `yaml:"write_timeout"`
+	IdleTimeout    time.Duration   `yaml:"idle_timeout"`
+	TLS            ServerTLSConfig `yaml:"tls"` // TLS, optionally mutual, of the listener
+
+	Database DatabaseConfig        `yaml:"database"` // Proof store, read with a read-only role; an empty dsn verifies against the chains only
+	Chains   []VerifierChainConfig `yaml:"chains"`   // Chains verified against, tried in order
+
+	SecurityProfile SecurityProfile `yaml:"security_profile"` // strict or lenient; see SecurityViolations
+}
+
+// VerifierChainConfig names one chain the verifier reads
+type VerifierChainConfig struct {
+	Name       string `yaml:"name"`        // Selected with ?chain=
+	ConfigPath string `yaml:"config_path"` // Blockchain config file; its chain-specific file is read from clients/ beside it
+}
+
+// LoadVerifierConfig loads verifier configuration from the specified YAML file path
+func LoadVerifierConfig(path string) (*VerifierConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verifier config file '%s': %w", path, err)
+	}
+
+	var cfg VerifierConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse verifier YAML config file: %w", err)
+	}
+	if err := ApplyEnvOverrides(EnvPrefix, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+	}
+
+	cfg.SetDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("verifier configuration error: %w", err)
+	}
+
+	// Evaluate the security profile against the complete configuration
+	if err := cfg.SecurityProfile.Check(cfg.SecurityViolations()); err != nil {
+		return nil, fmt.Errorf("security configuration error: %w", err)
+	}
+	return &cfg, nil
+}
+
+// SetDefaults sets reasonable default values for the verifier configuration
+func (c *VerifierConfig) SetDefaults() {
+	if c.HTTPListenAddr == "" {
+		c.HTTPListenAddr = ":8085"
+		fmt.Printf("Warning: http_listen_addr not set, defaulting to %s\n", c.HTTPListenAddr)
+	}
+	if c.ReadTimeout <= 0 {
+		c.ReadTimeout = 30 * time.Second
+	}
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = 60 * time.Second
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 120 * time.Second
+	}
+	if c.Database.DSN != "" {
+		c.Database.SetDefaults()
+	}
+	c.SecurityProfile.SetDefaults()
+}
+
+// Validate validates the verifier configuration
+func (c *VerifierConfig) Validate() error {
+	if len(c.Chains) == 0 {
+		return fmt.Errorf("at least one chain is required")
+	}
+	names := make(map[string]bool, len(c.Chains))
+	for i, chain := range c.Chains {
+		if chain.Name == "" || chain.ConfigPath == "" {
+			return fmt.Errorf("chains[%d]: name and config_path are required", i)
+		}
+		if names[chain.Name] {
+			return fmt.Errorf("chains[%d]: duplicate name %s", i, chain.Name)
+		}
+		names[chain.Name] = true
+	}
+	if c.Database.DSN != "" {
+		if err := c.Database.Validate(); err != nil {
+			return fmt.Errorf("database config error: %w", err)
+		}
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls config error: %w", err)
+	}
+	return c.SecurityProfile.Validate()
+}
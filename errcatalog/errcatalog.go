@@ -0,0 +1,270 @@
+// Package errcatalog is the catalog of error codes the gateway returns over
+// HTTP and gRPC, and the query and verifier services over HTTP. Each code is stable, maps to one HTTP status and one gRPC
+// code, and has a message template with named parameters, optionally
+// translated, so integrators can act on codes and show localized messages
+// without parsing the English error text.
+package errcatalog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Code identifies an error; codes are never renamed or reused
+type Code string
+
+// Generic codes, for errors without a specific one
+const (
+	CodeInvalidRequest       Code = "INVALID_REQUEST"
+	CodeUnauthenticated      Code = "UNAUTHENTICATED"
+	CodePermissionDenied     Code = "PERMISSION_DENIED"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodeMethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	CodeConflict             Code = "CONFLICT"
+	CodeUnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeTooManyRequests      Code = "TOO_MANY_REQUESTS"
+	CodeInternal             Code = "INTERNAL"
+	CodeNotImplemented       Code = "NOT_IMPLEMENTED"
+	CodeUnavailable          Code = "UNAVAILABLE"
+)
+
+// Submission codes
+const (
+	CodeLogContentEmpty        Code = "LOG_CONTENT_EMPTY"
+	CodeHashMismatch           Code = "HASH_MISMATCH"
+	CodeInvalidClientTimestamp Code = "INVALID_CLIENT_TIMESTAMP"
+	CodeClientTimestampSkew    Code = "CLIENT_TIMESTAMP_SKEW"
+	CodeInvalidIdempotencyKey  Code = "INVALID_IDEMPOTENCY_KEY"
+	CodeInvalidLogField        Code = "INVALID_LOG_FIELD"
+	CodeInvalidExternalID      Code = "INVALID_EXTERNAL_ID"
+	CodeExternalIDConflict     Code = "EXTERNAL_ID_CONFLICT"
+	CodeExternalIDUnavailable  Code = "EXTERNAL_ID_UNAVAILABLE"
+	CodeTestTrafficNotAllowed  Code = "TEST_TRAFFIC_NOT_ALLOWED"
+	CodeOrgNotAllowed          Code = "ORG_NOT_ALLOWED"
+	CodeRequestTooLarge        Code = "REQUEST_TOO_LARGE"
+	CodeDecompressedTooLarge   Code = "DECOMPRESSED_TOO_LARGE"
+	CodeRateLimited            Code = "RATE_LIMITED"
+	CodeQuotaExceeded          Code = "QUOTA_EXCEEDED"
+	CodeMaintenance            Code = "MAINTENANCE"
+	CodeQueueUnavailable       Code = "QUEUE_UNAVAILABLE"
+	CodeShed                   Code = "SHED"
+	CodeOverloaded             Code = "OVERLOADED"
+)
+
+// Query and verification codes
+const (
+	CodeUnknownChain     Code = "UNKNOWN_CHAIN"
+	CodeChainUnavailable Code = "CHAIN_UNAVAILABLE"
+)
+
+// Parameters of the message templates
+const (
+	ParamDetail            = "detail"              // English detail of the error, e.g. the offending field
+	ParamLimit             = "limit"               // Limit exceeded
+	ParamSize              = "size"                // Size of the request in bytes
+	ParamRetryAfterSeconds = "retry_after_seconds" // Seconds after which the request may be retried
+	ParamPriority          = "priority"            // Priority of a shed submission
+	ParamClientHash        = "client_hash"
+	ParamServerHash        = "server_hash"
+)
+
+// Entry describes an error code
+type Entry struct {
+	Code       Code       `json:"code"`
+	HTTPStatus int        `json:"http_status"`
+	GRPCCode   codes.Code `json:"grpc_code"`
+	Retryable  bool       `json:"retryable"` // Retrying the same request later may succeed
+	Message    string     `json:"message"`   // English template, parameters as {name}
+}
+
+var entries = []Entry{
+	{CodeInvalidRequest, 400, codes.InvalidArgument, false, "Invalid request: {detail}"},
+	{CodeUnauthenticated, 401, codes.Unauthenticated, false, "Authentication required: {detail}"},
+	{CodePermissionDenied, 403, codes.PermissionDenied, false, "Permission denied: {detail}"},
+	{CodeNotFound, 404, codes.NotFound, false, "Not found: {detail}"},
+	{CodeMethodNotAllowed, 405, codes.Unimplemented, false, "Method not allowed"},
+	{CodeConflict, 409, codes.AlreadyExists, false, "Conflict: {detail}"},
+	{CodeUnsupportedMediaType, 415, codes.InvalidArgument, false, "Unsupported media type: {detail}"},
+	{CodeTooManyRequests, 429, codes.ResourceExhausted, true, "Too many requests: {detail}"},
+	{CodeInternal, 500, codes.Unknown, false, "Internal error: {detail}"},
+	{CodeNotImplemented, 501, codes.Unimplemented, false, "Not implemented: {detail}"},
+	{CodeUnavailable, 503, codes.Unavailable, true, "Service unavailable: {detail}"},
+
+	{CodeLogContentEmpty, 400, codes.InvalidArgument, false, "log_content cannot be empty"},
+	{CodeHashMismatch, 400, codes.InvalidArgument, false, "Client provided hash {client_hash} does not match server calculated hash {server_hash}"},
+	{CodeInvalidClientTimestamp, 400, codes.InvalidArgument, false, "Invalid client_timestamp: {detail}"},
+	{CodeClientTimestampSkew, 400, codes.InvalidArgument, false, "client_timestamp outside allowed clock skew: {detail}"},
+	{CodeInvalidIdempotencyKey, 400, codes.InvalidArgument, false, "Invalid idempotency_key: {detail}"},
+	{CodeInvalidLogField, 400, codes.InvalidArgument, false, "Invalid log field: {detail}"},
+	{CodeInvalidExternalID, 400, codes.InvalidArgument, false, "Invalid external_id: {detail}"},
+	{CodeExternalIDConflict, 409, codes.AlreadyExists, false, "external_id already used for a different log"},
+	{CodeExternalIDUnavailable, 400, codes.FailedPrecondition, false, "external_id is not supported by the State DB schema"},
+	{CodeTestTrafficNotAllowed, 403, codes.PermissionDenied, false, "Test traffic not allowed: {detail}"},
+	{CodeOrgNotAllowed, 403, codes.PermissionDenied, false, "Org not allowed for this caller: {detail}"},
+	{CodeRequestTooLarge, 413, codes.ResourceExhausted, false, "Request exceeds the limit of {limit} bytes"},
+	{CodeDecompressedTooLarge, 413, codes.ResourceExhausted, false, "Decompressed request body exceeds the limit of {limit} bytes"},
+	{CodeRateLimited, 429, codes.ResourceExhausted, true, "Rate limit exceeded, retry in {retry_after_seconds} s"},
+	{CodeQuotaExceeded, 429, codes.ResourceExhausted, true, "Monthly quota of {limit} submissions exceeded, retry in {retry_after_seconds} s"},
+	{CodeMaintenance, 503, codes.Unavailable, true, "Gateway is in maintenance mode, retry in {retry_after_seconds} s: {detail}"},
+	{CodeQueueUnavailable, 503, codes.Unavailable, true, "Message queue unavailable, retry in {retry_after_seconds} s"},
+	{CodeShed, 503, codes.Unavailable, true, "Gateway is shedding {priority} priority submissions, retry in {retry_after_seconds} s"},
+	{CodeOverloaded, 503, codes.Unavailable, true, "Gateway is overloaded, retry in {retry_after_seconds} s"},
+
+	{CodeUnknownChain, 422, codes.InvalidArgument, false, "Chain not configured: {detail}"},
+	{CodeChainUnavailable, 502, codes.Unavailable, true, "Blockchain could not be queried: {detail}"},
+}
+
+// translations holds the message templates by language, then code. Codes a
+// language lacks fall back to English.
+var translations = map[string]map[Code]string{
+	"zh": {
+		CodeInvalidRequest:         "请求无效：{detail}",
+		CodeUnauthenticated:        "需要身份认证：{detail}",
+		CodePermissionDenied:       "权限不足：{detail}",
+		CodeNotFound:               "未找到：{detail}",
+		CodeMethodNotAllowed:       "不支持该请求方法",
+		CodeConflict:               "冲突：{detail}",
+		CodeUnsupportedMediaType:   "不支持的媒体类型：{detail}",
+		CodeTooManyRequests:        "请求过多：{detail}",
+		CodeInternal:               "内部错误：{detail}",
+		CodeNotImplemented:         "尚未实现：{detail}",
+		CodeUnavailable:            "服务不可用：{detail}",
+		CodeLogContentEmpty:        "log_content 不能为空",
+		CodeHashMismatch:           "客户端提供的哈希 {client_hash} 与服务端计算的哈希 {server_hash} 不一致",
+		CodeInvalidClientTimestamp: "client_timestamp 无效：{detail}",
+		CodeClientTimestampSkew:    "client_timestamp 超出允许的时钟偏差：{detail}",
+		CodeInvalidIdempotencyKey:  "idempotency_key 无效：{detail}",
+		CodeInvalidLogField:        "日志字段无效：{detail}",
+		CodeInvalidExternalID:      "external_id 无效：{detail}",
+		CodeExternalIDConflict:     "external_id 已被另一条日志使用",
+		CodeExternalIDUnavailable:  "State DB 模式不支持 external_id",
+		CodeTestTrafficNotAllowed:  "不允许测试流量：{detail}",
+		CodeOrgNotAllowed:          "调用方无权使用该组织：{detail}",
+		CodeRequestTooLarge:        "请求超过 {limit} 字节的上限",
+		CodeDecompressedTooLarge:   "解压后的请求体超过 {limit} 字节的上限",
+		CodeRateLimited:            "超出速率限制，请在 {retry_after_seconds} 秒后重试",
+		CodeQuotaExceeded:          "已超出每月 {limit} 条的配额，请在 {retry_after_seconds} 秒后重试",
+		CodeMaintenance:            "网关处于维护模式，请在 {retry_after_seconds} 秒后重试：{detail}",
+		CodeQueueUnavailable:       "消息队列不可用，请在 {retry_after_seconds} 秒后重试",
+		CodeShed:                   "网关正在丢弃 {priority} 优先级的提交，请在 {retry_after_seconds} 秒后重试",
+		CodeOverloaded:             "网关过载，请在 {retry_after_seconds} 秒后重试",
+		CodeUnknownChain:           "未配置该链：{detail}",
+		CodeChainUnavailable:       "无法查询区块链：{detail}",
+	},
+}
+
+var byCode = func() map[Code]Entry {
+	m := make(map[Code]Entry, len(entries))
+	for _, e := range entries {
+		m[e.Code] = e
+	}
+	return m
+}()
+
+// Entries returns the catalog, generic codes first
+func Entries() []Entry {
+	return append([]Entry(nil), entries...)
+}
+
+// Lookup returns the entry of a code
+func Lookup(code Code) (Entry, bool) {
+	e, ok := byCode[code]
+	return e, ok
+}
+
+// Get returns the entry of a code, or the INTERNAL entry for unknown codes
+func Get(code Code) Entry {
+	if e, ok := byCode[code]; ok {
+		return e
+	}
+	return byCode[CodeInternal]
+}
+
+// ForHTTPStatus returns the generic code of an HTTP status
+func ForHTTPStatus(status int) Code {
+	for _, e := range entries { // Generic codes come first
+		if e.HTTPStatus == status {
+			return e.Code
+		}
+	}
+	if status >= 500 {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
+
+// Languages returns the languages messages are available in, English first
+func Languages() []string {
+	langs := make([]string, 0, len(translations))
+	for lang := range translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return append([]string{"en"}, langs...)
+}
+
+// Supported reports whether messages are available in lang
+func Supported(lang string) bool {
+	_, ok := translations[lang]
+	return ok || lang == "en"
+}
+
+// Template returns the message template of a code in lang, English if unavailable
+func Template(code Code, lang string) string {
+	if t, ok := translations[lang][code]; ok {
+		return t
+	}
+	return Get(code).Message
+}
+
+// Format returns the message of a code in lang (English if unavailable), with
+// its parameters filled in. Missing parameters are left out, along with the
+// separator before them.
+func Format(code Code, lang string, params map[string]string) string {
+	template := Template(code, lang)
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template, '}')
+		if start < 0 || end < start {
+			b.WriteString(template)
+			break
+		}
+		literal, value := template[:start], params[template[start+1:end]]
+		if value == "" {
+			// "Invalid request: {detail}" reads "Invalid request" without a detail
+			literal = strings.TrimRight(literal, " :：,，")
+		}
+		b.WriteString(literal)
+		b.WriteString(value)
+		template = template[end+1:]
+	}
+	return b.String()
+}
+
+// Negotiate picks the catalog language best matching an Accept-Language
+// header (or a bare language tag), "" if none matches
+func Negotiate(acceptLanguage string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := strings.TrimSpace(part), 1.0
+		if name, weight, ok := strings.Cut(tag, ";"); ok {
+			tag = strings.TrimSpace(name)
+			if v, ok := strings.CutPrefix(strings.TrimSpace(weight), "q="); ok {
+				parsed, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					continue
+				}
+				q = parsed
+			}
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > bestQ && Supported(lang) {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,32 @@
+package errcatalog
+
+import "net/http"
+
+// HTTPError is the JSON body of an HTTP error response
+type HTTPError struct {
+	Error            string            `json:"error"` // English message
+	Status           int               `json:"status"`
+	Message          string            `json:"message"` // Status text of Status
+	Code             Code              `json:"code"`
+	Params           map[string]string `json:"params,omitempty"`
+	Locale           string            `json:"locale,omitempty"`
+	LocalizedMessage string            `json:"localized_message,omitempty"`
+}
+
+// NewHTTPError returns the response body of an error with HTTP status
+// statusCode, with the message in the language best matching the
+// acceptLanguage header if the catalog has it
+func NewHTTPError(code Code, params map[string]string, message string, statusCode int, acceptLanguage string) HTTPError {
+	resp := HTTPError{
+		Error:   message,
+		Status:  statusCode,
+		Message: http.StatusText(statusCode),
+		Code:    code,
+		Params:  params,
+	}
+	if lang := Negotiate(acceptLanguage); lang != "" {
+		resp.Locale = lang
+		resp.LocalizedMessage = Format(code, lang, params)
+	}
+	return resp
+}
@@ -0,0 +1,79 @@
+package errcatalog
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// Domain is the ErrorInfo domain of gRPC errors carrying a catalog code
+const Domain = "tlng"
+
+// Error is an error with its catalog code and parameters
+type Error struct {
+	Code    Code
+	Params  map[string]string
+	Message string // As sent by the gateway, in English
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Localize returns the message of the error in lang, English if unavailable
+func (e *Error) Localize(lang string) string {
+	return Format(e.Code, lang, e.Params)
+}
+
+// Status returns the gRPC status of an error: the code of its entry, message
+// as the status message, and details holding an ErrorInfo (reason code,
+// metadata params) and, if lang is set, a LocalizedMessage in lang
+func Status(code Code, params map[string]string, message, lang string) *status.Status {
+	st := status.New(Get(code).GRPCCode, message)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: string(code), Domain: Domain, Metadata: params}}
+	if lang != "" {
+		details = append(details, &errdetails.LocalizedMessage{Locale: lang, Message: Format(code, lang, params)})
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return withDetails
+}
+
+// FromError returns the catalog error of a gRPC error from the gateway
+func FromError(err error) (*Error, bool) {
+	var catalogErr *Error
+	if errors.As(err, &catalogErr) {
+		return catalogErr, true
+	}
+	st := grpcStatus(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == Domain {
+			return &Error{Code: Code(info.GetReason()), Params: info.GetMetadata(), Message: st.Message()}, true
+		}
+	}
+	return nil, false
+}
+
+// LocalizedMessage returns the localized message of a gRPC error, if the
+// gateway sent one
+func LocalizedMessage(err error) (string, bool) {
+	st := grpcStatus(err)
+	for _, detail := range st.Details() {
+		if msg, ok := detail.(*errdetails.LocalizedMessage); ok {
+			return msg.GetMessage(), true
+		}
+	}
+	return "", false
+}
+
+// grpcStatus returns the status of the gRPC error err wraps, with the status
+// message as sent; nil if err wraps none
+func grpcStatus(err error) *status.Status {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &grpcErr) {
+		return nil
+	}
+	return grpcErr.GRPCStatus()
+}
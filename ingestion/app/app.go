@@ -0,0 +1,784 @@
+// Package app assembles the Log Ingestion Service (API Gateway) from its
+// configuration and dependencies, so that cmd/ingestion and other programs,
+// including in-process tests, can run it.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"tlng/apitoken"
+	blockchain "tlng/blockchain/client"
+	"tlng/config"
+	core "tlng/ingestion/service/core"
+	grpchandler "tlng/ingestion/service/grpc"
+	httphandler "tlng/ingestion/service/http"
+	syslogserver "tlng/ingestion/service/syslog"
+	"tlng/internal/buildinfo"
+	"tlng/internal/clock"
+	"tlng/internal/fingerprint"
+	"tlng/internal/idgen"
+	"tlng/internal/integrity"
+	"tlng/internal/messaging/producer"
+	"tlng/internal/messaging/topic"
+	"tlng/internal/startup"
+	"tlng/internal/tracing"
+	pb "tlng/proto/logingestion"
+	"tlng/storage/store"
+	"tlng/svcauth"
+)
+
+// Deps are the external dependencies of the gateway. New opens the ones left
+// nil from the configuration and closes them on Shutdown; injected ones are
+// left to the caller to close.
+type Deps struct {
+	Store          store.Store
+	Producer       producer.Producer
+	LargeProducer  producer.Producer           // Used only with size_tier enabled
+	CanaryProducer producer.Producer           // Used only with canary enabled
+	ChainClient    blockchain.BlockchainClient // Used only with self_test enabled
+
+	Clock clock.Clock     // Replaces the system clock, e.g. with clock.NewFake in tests
+	IDs   idgen.Generator // Replaces the request_id_strategy generator for request and batch IDs
+}
+
+// App is a configured API Gateway. New wires it and binds its listeners, Run
+// serves until its context is done and Shutdown drains it.
+type App struct {
+	cfg    *config.ApiGatewayConfig
+	logger *log.Logger
+
+	svc      *core.Service
+	quota    *core.QuotaTracker
+	capturer *core.DebugCapturer
+	wal      *core.WAL
+
+	httpServer   *http.Server
+	httpListener net.Listener
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+	healthServer *health.Server
+	syslog       *syslogserver.Server
+
+	closers []func() error // Dependencies opened by New, closed in reverse order
+	serving sync.WaitGroup
+	errs    chan error
+}
+
+// New opens the dependencies missing from deps, waiting for them to come up,
+// wires the core service with the configured features, runs the startup
+// self-checks and binds the configured listeners.
+func New(ctx context.Context, cfg *config.ApiGatewayConfig, deps Deps, logger *log.Logger) (a *App, err error) {
+	a = &App{cfg: cfg, logger: logger, errs: make(chan error, 3)}
+	defer func() {
+		if err != nil {
+			a.close()
+		}
+	}()
+
+	// Export spans when tracing is enabled; closed last, after the batches are flushed
+	closeTracing, err := tracing.Setup(ctx, cfg.Tracing, logger)
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, closeTracing)
+
+	boot := startup.New(cfg.Startup, logger)
+	cfg.KafkaProducer.Topic = cfg.Region.Topic(cfg.KafkaProducer.Topic)
+	cfg.Messaging.NATS.Subject = cfg.Region.Topic(cfg.Messaging.NATS.Subject)
+	if err := a.openDeps(ctx, boot, &deps); err != nil {
+		return nil, err
+	}
+
+	idGenerator := deps.IDs
+	if idGenerator == nil {
+		if idGenerator, err = idgen.NewGenerator(cfg.RequestIDStrategy); err != nil {
+			return nil, fmt.Errorf("failed to initialize request ID generator: %w", err)
+		}
+	}
+	clk := deps.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	a.svc = core.NewService(
+		deps.Store,
+		deps.Producer,
+		logger,
+		cfg.BatchProcessor.BatchSize,
+		cfg.BatchProcessor.BatchTimeout,
+		cfg.BatchProcessor.FlushChannelBuffer,
+		cfg.Region.Name,
+		idGenerator,
+		clk,
+		store.ConflictPolicy(cfg.BatchProcessor.ConflictPolicy),
+		cfg.TimestampPolicy,
+	)
+	if err := a.configureService(deps); err != nil {
+		return nil, err
+	}
+
+	// Self-checks before accepting traffic: a read through the State DB and the producer topic's metadata
+	boot.AddCheck("database", func(ctx context.Context) error {
+		_, err := deps.Store.GetLogStatusByRequestID(ctx, "startup-self-check")
+		if errors.Is(err, store.ErrLogNotFound) {
+			return nil
+		}
+		return err
+	})
+	if err := boot.Ready(ctx); err != nil {
+		return nil, fmt.Errorf("startup self-check failed: %w", err)
+	}
+
+	// Spool batches the store rejects to the WAL, and persist what the previous run spooled before serving
+	if cfg.BatchProcessor.WALPath != "" {
+		a.wal, err = core.OpenWAL(cfg.BatchProcessor.WALPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open batch WAL: %w", err)
+		}
+		a.svc.SetWAL(a.wal)
+		replayed, err := a.svc.ReplayWAL()
+		if err != nil {
+			return nil, fmt.Errorf("failed to replay batch WAL: %w", err)
+		}
+		logger.Printf("Batch WAL enabled at %s, replayed %d spooled entries", cfg.BatchProcessor.WALPath, replayed)
+	} else {
+		logger.Println("batch_processor.wal_path not configured, batches the store rejects are dropped.")
+	}
+
+	// Authenticate agents and admin callers by SPIFFE ID or OIDC token when configured
+	var verifier svcauth.Verifier
+	if cfg.ServiceAuth.Enabled() {
+		verifier, err = svcauth.NewVerifier(cfg.ServiceAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize service authentication: %w", err)
+		}
+		logger.Printf("Service authentication enabled: %s (trust domain %s)", cfg.ServiceAuth.Mode, cfg.ServiceAuth.TrustDomain)
+	}
+	// Accept org-scoped API tokens beside it, managed at /admin/tokens
+	if cfg.APITokens.Enabled {
+		tokens := apitoken.NewVerifier(deps.Store, cfg.APITokens.CacheTTL, cfg.APITokens.CacheSize, verifier)
+		a.svc.SetAPITokens(apitoken.NewManager(deps.Store, cfg.APITokens, tokens))
+		verifier = tokens
+		logger.Printf("API tokens enabled at /admin/tokens: default TTL %v, max TTL %v, lookups cached for %v",
+			cfg.APITokens.DefaultTTL, cfg.APITokens.MaxTTL, cfg.APITokens.CacheTTL)
+	}
+	if err := a.listenHTTP(verifier); err != nil {
+		return nil, err
+	}
+	if err := a.listenGRPC(verifier); err != nil {
+		return nil, err
+	}
+	if err := a.listenSyslog(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// openDeps fills in the dependencies missing from deps
+func (a *App) openDeps(ctx context.Context, boot *startup.Orchestrator, deps *Deps) error {
+	cfg, logger := a.cfg, a.logger
+
+	if deps.Store == nil {
+		logger.Println("Initializing database connection...")
+		var dbStore *store.PostgresStore
+		err := boot.Wait(ctx, "database", cfg.Startup.Database, func(ctx context.Context) error {
+			var err error
+			dbStore, err = store.NewPostgresStore(ctx, cfg.Database.DSN, cfg.Database.MaxConnections, cfg.Database.MinConnections, logger)
+			if errors.Is(err, store.ErrIncompatibleSchema) {
+				return startup.Permanent(err)
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize database store: %w", err)
+		}
+		deps.Store = dbStore
+		a.closers = append(a.closers, func() error { dbStore.Close(); return nil })
+	}
+
+	failover := len(cfg.KafkaProducer.SecondaryBrokers) > 0
+	kafkaTLS, err := cfg.KafkaProducer.TLS.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load Kafka TLS configuration: %w", err)
+	}
+	// NATS JetStream replaces Kafka; it waits on the startup.kafka budget
+	if deps.Producer == nil && cfg.Messaging.UsesNATS() {
+		err := boot.Wait(ctx, "nats", cfg.Startup.Kafka, func(ctx context.Context) error {
+			logger.Println("Initializing NATS JetStream producer...")
+			var err error
+			deps.Producer, err = producer.NewNATSProducer(ctx, cfg.Messaging.NATS, cfg.Messaging.NATS.Subject, logger)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize NATS producer: %w", err)
+		}
+		a.closers = append(a.closers, deps.Producer.Close)
+	}
+	if deps.Producer == nil {
+		err := boot.Wait(ctx, "kafka", cfg.Startup.Kafka, func(ctx context.Context) error {
+			var err error
+			if failover {
+				// The failover producer starts on the secondary cluster if the primary is down
+				logger.Println("Initializing Kafka producer with secondary cluster failover...")
+				deps.Producer, err = producer.NewFailoverProducer(cfg.KafkaProducer, logger)
+				return err
+			}
+			if err := topic.Ping(ctx, cfg.KafkaProducer.Brokers, kafkaTLS, cfg.Startup.SelfCheckTimeout); err != nil {
+				return err
+			}
+			logger.Println("Initializing Kafka producer...")
+			deps.Producer, err = producer.NewKafkaProducer(cfg.KafkaProducer, logger)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize Kafka producer: %w", err)
+		}
+		a.closers = append(a.closers, deps.Producer.Close)
+
+		// The topics are checked only for producers opened here
+		if !failover {
+			boot.AddCheck("kafka", func(ctx context.Context) error {
+				if err := topic.Check(ctx, cfg.KafkaProducer.Brokers, kafkaTLS, cfg.KafkaProducer.Topic, cfg.Startup.SelfCheckTimeout, logger); err != nil {
+					return err
+				}
+				if cfg.SizeTier.Enabled {
+					if err := topic.Check(ctx, cfg.KafkaProducer.Brokers, kafkaTLS, cfg.Region.Topic(cfg.SizeTier.Topic), cfg.Startup.SelfCheckTimeout, logger); err != nil {
+						return err
+					}
+				}
+				if cfg.Canary.Enabled {
+					return topic.Check(ctx, cfg.KafkaProducer.Brokers, kafkaTLS, cfg.Region.Topic(cfg.Canary.Topic), cfg.Startup.SelfCheckTimeout, logger)
+				}
+				return nil
+			})
+		}
+	}
+
+	// Large submissions get their own topic, with a producer batch limit that fits them
+	if cfg.SizeTier.Enabled && deps.LargeProducer == nil {
+		largeCfg := cfg.KafkaProducer
+		largeCfg.Topic = cfg.Region.Topic(cfg.SizeTier.Topic)
+		largeCfg.BatchBytes = cfg.SizeTier.BatchBytes
+		largeCfg.TopicCheck.Partitions = 0 // Sized independently of the main topic
+		if failover {
+			deps.LargeProducer, err = producer.NewFailoverProducer(largeCfg, logger)
+		} else {
+			deps.LargeProducer, err = producer.NewKafkaProducer(largeCfg, logger)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to initialize Kafka producer for large submissions: %w", err)
+		}
+		a.closers = append(a.closers, deps.LargeProducer.Close)
+	}
+
+	// Canary submissions go to the topic of the canary engine
+	if cfg.Canary.Enabled && deps.CanaryProducer == nil {
+		canaryCfg := cfg.KafkaProducer
+		canaryCfg.Topic = cfg.Region.Topic(cfg.Canary.Topic)
+		canaryCfg.TopicCheck.Partitions = 0 // Sized independently of the main topic
+		if failover {
+			deps.CanaryProducer, err = producer.NewFailoverProducer(canaryCfg, logger)
+		} else {
+			deps.CanaryProducer, err = producer.NewKafkaProducer(canaryCfg, logger)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to initialize Kafka producer for canary submissions: %w", err)
+		}
+		a.closers = append(a.closers, deps.CanaryProducer.Close)
+	}
+
+	// The self-test reads the synthetic logs back from the chain
+	if cfg.SelfTest.Enabled && deps.ChainClient == nil {
+		deps.ChainClient, err = blockchain.NewBlockchainClientFromFile(cfg.SelfTest.ChainMakerConfig, logger)
+		if err != nil {
+			return fmt.Errorf("failed to initialize blockchain client for the self-test: %w", err)
+		}
+		a.closers = append(a.closers, deps.ChainClient.Close)
+	}
+	return nil
+}
+
+// configureService applies the optional features of the configuration to the core service
+func (a *App) configureService(deps Deps) error {
+	cfg, logger := a.cfg, a.logger
+	a.svc.SetMaintenance(core.MaintenanceState{
+		Enabled:    cfg.Maintenance.Enabled,
+		RetryAfter: cfg.Maintenance.RetryAfter,
+		Message:    cfg.Maintenance.Message,
+	})
+	if cfg.Quota.Enabled {
+		a.quota = core.NewQuotaTracker(cfg.Quota, deps.Store, logger)
+		a.svc.SetQuotaTracker(a.quota)
+		logger.Printf("Per-org quotas enabled: rate_limit=%d per %v, monthly_quota=%d, overrides=%d",
+			cfg.Quota.RateLimit, cfg.Quota.RateWindow, cfg.Quota.MonthlyQuota, len(cfg.Quota.Orgs))
+	}
+	if cfg.SizeTier.Enabled {
+		a.svc.EnableSizeTier(cfg.SizeTier.ThresholdBytes, deps.LargeProducer, cfg.SizeTier.BatchSize, cfg.SizeTier.BatchTimeout)
+		logger.Printf("Size tiers enabled: submissions of %d bytes or more go to topic %s in batches of %d",
+			cfg.SizeTier.ThresholdBytes, cfg.Region.Topic(cfg.SizeTier.Topic), cfg.SizeTier.BatchSize)
+	}
+	if cfg.Canary.Enabled {
+		a.svc.EnableCanary(cfg.Canary, deps.CanaryProducer)
+		logger.Printf("Canary routing enabled: %.2f%% of submissions by %s go to topic %s",
+			cfg.Canary.Percent, cfg.Canary.SplitBy, cfg.Region.Topic(cfg.Canary.Topic))
+	}
+	if cfg.DegradedAcceptance.Policy != config.DegradedPolicyDrop {
+		a.svc.SetDegradedAcceptance(cfg.DegradedAcceptance)
+		logger.Printf("Degraded acceptance enabled: policy=%s after %d failed publishes, cooldown=%v",
+			cfg.DegradedAcceptance.Policy, cfg.DegradedAcceptance.FailureThreshold, cfg.DegradedAcceptance.Cooldown)
+	}
+	if ob := cfg.BatchProcessor.Outbox; ob.Enabled {
+		if sch, ok := deps.Store.(interface{ Schema() store.SchemaInfo }); ok && !sch.Schema().Features().Has(store.FeatureOutbox) {
+			return fmt.Errorf("batch_processor.outbox needs schema version 15 (tbl_outbox), the database is at version %d", sch.Schema().Version)
+		}
+		a.svc.SetOutbox(ob)
+		logger.Printf("Outbox enabled: unsent messages relayed every %v after a %v lease, sent messages kept %v",
+			ob.RelayInterval, ob.Lease, ob.Retention)
+	}
+	if ft := cfg.BatchProcessor.FlushTrigger; ft.Enabled {
+		a.svc.SetFlushTrigger(ft)
+		logger.Printf("Flush trigger enabled: check every %v, early flush from %d entries while Kafka is idle, up to %v later at %d queued batches",
+			ft.CheckInterval, ft.IdleMinEntries, ft.BackpressureDelay, ft.BackpressureQueueDepth)
+	}
+	if cfg.Dedup.Enabled {
+		a.svc.SetDedupCache(core.NewDedupCache(cfg.Dedup.TTL, cfg.Dedup.MaxEntries))
+		logger.Printf("Duplicate window enabled: ttl=%v, max_entries=%d", cfg.Dedup.TTL, cfg.Dedup.MaxEntries)
+	}
+	if cfg.InFlight.Enabled {
+		a.svc.SetInFlightLimiter(core.NewInFlightLimiter(cfg.InFlight.MaxRequests, cfg.InFlight.MaxQueued, cfg.InFlight.QueueTimeout, cfg.InFlight.RetryAfter))
+		logger.Printf("In-flight limiter enabled: max_requests=%d, max_queued=%d, queue_timeout=%v",
+			cfg.InFlight.MaxRequests, cfg.InFlight.MaxQueued, cfg.InFlight.QueueTimeout)
+	}
+	if cfg.LoadShedding.Enabled {
+		a.svc.SetLoadShedder(core.NewLoadShedder(cfg.LoadShedding))
+		logger.Printf("Load shedding enabled: max_error_rate=%g, max_queue_depth=%d, shedding %g of %s-priority submissions",
+			cfg.LoadShedding.MaxErrorRate, cfg.LoadShedding.MaxQueueDepth, cfg.LoadShedding.ShedFraction, cfg.LoadShedding.ShedPriority)
+	}
+	fp, err := fingerprint.Compute(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint the configuration: %w", err)
+	}
+	files, err := fingerprint.Files(cfg.SourcePath, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to checksum the configuration files: %w", err)
+	}
+	a.svc.SetConfigFingerprint(fp, files)
+	if cfg.IntegrityMetadata {
+		a.svc.SetIntegrityMetadata(integrity.ForStore(deps.Store))
+	}
+	a.svc.SetRequestSizeLimits(cfg.RequestSize)
+	if cfg.DebugCapture.Enabled {
+		a.capturer, err = core.NewDebugCapturer(cfg.DebugCapture, deps.Store, logger)
+		if err != nil {
+			return fmt.Errorf("failed to initialize debug capture: %w", err)
+		}
+		a.svc.SetDebugCapturer(a.capturer)
+		logger.Printf("Debug capture enabled for %d orgs: sampling %g of rejected submissions, at most %d per org per minute, kept %v",
+			len(cfg.DebugCapture.Orgs), cfg.DebugCapture.SampleRate, cfg.DebugCapture.MaxPerMinute, cfg.DebugCapture.TTL)
+	}
+	if cfg.TestTraffic.Enabled {
+		a.svc.SetTestTraffic(cfg.TestTraffic)
+		if len(cfg.TestTraffic.Orgs) == 0 {
+			logger.Printf("Test traffic accepted from every org, finished submissions purged after %v", cfg.TestTraffic.TTL)
+		} else {
+			logger.Printf("Test traffic accepted from %d orgs, finished submissions purged after %v", len(cfg.TestTraffic.Orgs), cfg.TestTraffic.TTL)
+		}
+	}
+	if ad := cfg.AnomalyDetection; ad.Enabled {
+		a.svc.SetAnomalyDetector(core.NewAnomalyDetector(ad, logger))
+		logger.Printf("Anomaly detection enabled: %v windows, baseline over %d windows, silence after %d empty windows, bursts above %gx, duplicate rate above %g (webhook: %t)",
+			ad.Interval, ad.BaselineWindows, ad.ZeroWindows, ad.BurstFactor, ad.DuplicateRate, ad.Webhook.URL != "")
+	}
+	if gd := cfg.GapDetection; gd.Enabled {
+		if sch, ok := deps.Store.(interface{ Schema() store.SchemaInfo }); ok && !sch.Schema().Features().Has(store.FeatureSequence) {
+			return fmt.Errorf("gap_detection needs schema version 16 (tbl_sequence_gap), the database is at version %d", sch.Schema().Version)
+		}
+		a.svc.SetGapDetection(gd)
+		logger.Printf("Gap detection enabled: sequenced sources checked every %v, numbers missing for %v recorded as gaps", gd.Interval, gd.Grace)
+	}
+	if cfg.SelfTest.Enabled {
+		a.svc.SetSelfTest(cfg.SelfTest, deps.ChainClient)
+		logger.Printf("Self-test enabled at /admin/selftest: org %s, timeout %v", cfg.SelfTest.OrgID, cfg.SelfTest.Timeout)
+	}
+	logger.Printf("Configuration fingerprint %s (version %s)", fp.SHA256, buildinfo.Version())
+	return nil
+}
+
+// listenHTTP creates the HTTP server, which only registers write routes, and binds its listener
+func (a *App) listenHTTP(verifier svcauth.Verifier) error {
+	cfg := a.cfg
+	if cfg.HttpListenAddr == "" {
+		a.logger.Println("http_listen_addr not configured, skipping HTTP server startup.")
+		return nil
+	}
+
+	logHttpHandler := httphandler.NewLogHandler(a.svc, a.logger)
+	var submitHandler http.Handler = http.HandlerFunc(logHttpHandler.LimitInFlight(logHttpHandler.SubmitLog))
+	var batchHandler http.Handler = http.HandlerFunc(logHttpHandler.LimitInFlight(logHttpHandler.SubmitLogBatch))
+	var adminHandler http.Handler = http.HandlerFunc(logHttpHandler.Maintenance)
+	var configHandler http.Handler = http.HandlerFunc(logHttpHandler.Config)
+	var capturesHandler http.Handler = http.HandlerFunc(logHttpHandler.Captures)
+	var selfTestHandler http.Handler = http.HandlerFunc(logHttpHandler.SelfTest)
+	var tokensHandler http.Handler = http.HandlerFunc(logHttpHandler.Tokens)
+	if verifier != nil {
+		// Service identities pass every scope check; API tokens need the route's
+		// scope, and cannot reach the endpoints acting on the whole gateway
+		submitHandler = svcauth.RequireHTTP(verifier, svcauth.RequireScopeHTTP(apitoken.ScopeSubmit, submitHandler))
+		batchHandler = svcauth.RequireHTTP(verifier, svcauth.RequireScopeHTTP(apitoken.ScopeSubmit, batchHandler))
+		adminHandler = svcauth.RequireHTTP(verifier, svcauth.RequireServiceHTTP(adminHandler))
+		configHandler = svcauth.RequireHTTP(verifier, svcauth.RequireServiceHTTP(configHandler))
+		capturesHandler = svcauth.RequireHTTP(verifier, svcauth.RequireScopeHTTP(apitoken.ScopeAdmin, capturesHandler))
+		selfTestHandler = svcauth.RequireHTTP(verifier, svcauth.RequireServiceHTTP(selfTestHandler))
+		tokensHandler = svcauth.RequireHTTP(verifier, svcauth.RequireScopeHTTP(apitoken.ScopeAdmin, tokensHandler))
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/v1/logs", submitHandler) // Only register write Handler
+	mux.Handle("/v1/logs:batch", batchHandler)
+	mux.HandleFunc("/v1/errors", logHttpHandler.ErrorCatalog) // Public, like the documentation it serves
+	mux.Handle("/admin/maintenance", adminHandler)
+	mux.Handle("/admin/config", configHandler)
+	mux.Handle("/admin/captures", capturesHandler)
+	mux.Handle("/admin/captures/", capturesHandler)
+	mux.Handle("/admin/selftest", selfTestHandler)
+	mux.Handle("/admin/tokens", tokensHandler)
+	mux.Handle("/admin/tokens/", tokensHandler)
+	if cfg.OTLP.Enabled {
+		logHttpHandler.SetOTLP(cfg.OTLP)
+		var otlpHandler http.Handler = http.HandlerFunc(logHttpHandler.LimitInFlight(logHttpHandler.SubmitOTLPLogs))
+		if verifier != nil {
+			otlpHandler = svcauth.RequireHTTP(verifier, svcauth.RequireScopeHTTP(apitoken.ScopeSubmit, otlpHandler))
+		}
+		mux.Handle("/v1/otlp/logs", otlpHandler)
+		a.logger.Printf("OTLP/HTTP log ingestion enabled at /v1/otlp/logs (org attribute %s)", cfg.OTLP.OrgAttribute)
+	}
+	if cfg.Monitoring.EnableMetrics {
+		metricsPath := cfg.Monitoring.MetricsPath
+		if metricsPath == "" {
+			metricsPath = "/metrics"
+		}
+		mux.HandleFunc(metricsPath, logHttpHandler.Metrics)
+	}
+
+	// Bind mutual TLS callers to the org of their client certificate
+	var handler http.Handler = mux
+	if cfg.HttpTLS.Enabled && cfg.HttpTLS.OrgFrom != "" {
+		handler = httphandler.ClientCertOrg(cfg.HttpTLS.OrgFrom, handler)
+	}
+
+	// Use HTTP server configuration with defaults
+	readTimeout := cfg.HttpServer.ReadTimeout
+	if readTimeout == 0 {
+		readTimeout = 5 * time.Second
+	}
+
+	writeTimeout := cfg.HttpServer.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = 10 * time.Second
+	}
+
+	idleTimeout := cfg.HttpServer.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = 60 * time.Second
+	}
+
+	maxHeaderBytes := cfg.HttpServer.MaxHeaderBytes
+	if maxHeaderBytes == 0 {
+		maxHeaderBytes = 1 << 20 // 1 MB
+	}
+
+	a.httpServer = &http.Server{
+		Addr:           cfg.HttpListenAddr,
+		Handler:        otelhttp.NewHandler(handler, "gateway.http"), // Server spans, continuing a caller's traceparent
+		ReadTimeout:    readTimeout,
+		WriteTimeout:   writeTimeout,
+		IdleTimeout:    idleTimeout,
+		MaxHeaderBytes: maxHeaderBytes,
+	}
+	if verifier != nil {
+		a.httpServer.TLSConfig = verifier.ServerTLSConfig() // SVID-based TLS in spiffe mode
+	}
+	if cfg.HttpTLS.Enabled {
+		tlsCfg, err := cfg.HttpTLS.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load http_tls: %w", err)
+		}
+		a.httpServer.TLSConfig = tlsCfg
+		a.logger.Printf("HTTP listener serves TLS (client certificate required: %v, org from: %q)", cfg.HttpTLS.RequireClientCert, cfg.HttpTLS.OrgFrom)
+	}
+
+	lis, err := net.Listen("tcp", cfg.HttpListenAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on HTTP port %s: %w", cfg.HttpListenAddr, err)
+	}
+	a.httpListener = lis
+	return nil
+}
+
+// listenGRPC creates the gRPC server, which only registers the write service, and binds its listener
+func (a *App) listenGRPC(verifier svcauth.Verifier) error {
+	cfg := a.cfg
+	if cfg.GrpcListenAddr == "" {
+		a.logger.Println("grpc_listen_addr not configured, skipping gRPC server startup.")
+		return nil
+	}
+
+	logGrpcService := grpchandler.NewServer(a.svc, a.logger)
+	var opts []grpc.ServerOption
+	if verifier != nil {
+		opts = svcauth.ServerOptions(verifier) // Authenticate before admission to the in-flight budget
+		opts = append(opts, svcauth.ScopeServerOptions(apitoken.ScopeSubmit)...)
+	}
+	if cfg.GrpcTLS.Enabled {
+		tlsCfg, err := cfg.GrpcTLS.TLSConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load grpc_tls: %w", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+		a.logger.Printf("gRPC listener serves TLS (client certificate required: %v, org from: %q)", cfg.GrpcTLS.RequireClientCert, cfg.GrpcTLS.OrgFrom)
+		if cfg.GrpcTLS.OrgFrom != "" {
+			opts = append(opts, grpchandler.ClientCertOrgOptions(cfg.GrpcTLS.OrgFrom)...) // Bind callers to the org of their client certificate
+		}
+	}
+	opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler())) // Server spans, continuing a caller's traceparent
+	opts = append(opts, grpc.ChainUnaryInterceptor(logGrpcService.LimitInFlight))
+	opts = append(opts, grpc.MaxRecvMsgSize(int(a.svc.GRPCMessageLimit())))
+	a.grpcServer = grpc.NewServer(opts...)
+	pb.RegisterLogIngestionServer(a.grpcServer, logGrpcService) // Only register LogIngestion service
+
+	// gRPC health service, used by client-side health checking in load-balanced SDK clients
+	a.healthServer = health.NewServer()
+	a.healthServer.SetServingStatus(pb.LogIngestion_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(a.grpcServer, a.healthServer)
+
+	lis, err := net.Listen("tcp", cfg.GrpcListenAddr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on gRPC port %s: %w", cfg.GrpcListenAddr, err)
+	}
+	a.grpcListener = lis
+	return nil
+}
+
+// listenSyslog creates the syslog server and binds its listeners, if enabled
+func (a *App) listenSyslog() error {
+	cfg := a.cfg.Syslog
+	if !cfg.Enabled {
+		return nil
+	}
+	a.syslog = syslogserver.NewServer(a.svc, cfg, a.logger)
+	if err := a.syslog.Listen(); err != nil {
+		a.syslog = nil
+		return err
+	}
+	a.logger.Printf("Syslog receiver enabled: udp %q, tcp %q (tls: %v), %d org rules, default org %q",
+		cfg.UDPListenAddr, cfg.TCPListenAddr, cfg.TLS.Enabled, len(cfg.OrgRules), cfg.DefaultOrgID)
+	return nil
+}
+
+// Service returns the core service behind the servers
+func (a *App) Service() *core.Service {
+	return a.svc
+}
+
+// HTTPAddr returns the address the HTTP server listens on, or nil without one
+func (a *App) HTTPAddr() net.Addr {
+	if a.httpListener == nil {
+		return nil
+	}
+	return a.httpListener.Addr()
+}
+
+// GRPCAddr returns the address the gRPC server listens on, or nil without one
+func (a *App) GRPCAddr() net.Addr {
+	if a.grpcListener == nil {
+		return nil
+	}
+	return a.grpcListener.Addr()
+}
+
+// Run starts the background tasks and serves until ctx is done, which returns
+// nil, or until a server fails. Shutdown must be called afterwards either way.
+func (a *App) Run(ctx context.Context) error {
+	cfg, logger := a.cfg, a.logger
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if a.quota != nil {
+		go a.quota.Run(ctx)
+	}
+	if cfg.DegradedAcceptance.Policy != config.DegradedPolicyDrop {
+		go a.svc.RunRelay(ctx)
+	}
+	if cfg.BatchProcessor.Outbox.Enabled {
+		go a.svc.RunOutboxRelay(ctx)
+	}
+	if cfg.LoadShedding.Enabled {
+		go a.svc.RunLoadShedding(ctx)
+	}
+	if a.capturer != nil {
+		go a.capturer.Run(ctx)
+	}
+	if cfg.TestTraffic.Enabled {
+		go a.svc.RunTestTrafficPurge(ctx)
+	}
+	if cfg.AnomalyDetection.Enabled {
+		go a.svc.RunAnomalyDetection(ctx)
+	}
+	if cfg.GapDetection.Enabled {
+		go a.svc.RunGapDetection(ctx)
+	}
+
+	// Anchor the configuration fingerprint on chain now and periodically
+	if cfg.ConfigFingerprint.Enabled {
+		go a.svc.RunConfigAnchoring(ctx, cfg.ConfigFingerprint)
+		logger.Printf("Configuration fingerprint anchoring enabled: every %v under org %s",
+			cfg.ConfigFingerprint.Interval, cfg.ConfigFingerprint.OrgID)
+	}
+
+	if a.httpServer != nil {
+		a.serving.Add(1)
+		go func() {
+			defer a.serving.Done()
+			logger.Printf("HTTP server listening on %s", a.httpListener.Addr())
+			var err error
+			if a.httpServer.TLSConfig != nil {
+				err = a.httpServer.ServeTLS(a.httpListener, "", "")
+			} else {
+				err = a.httpServer.Serve(a.httpListener)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				a.errs <- fmt.Errorf("HTTP server failed: %w", err)
+			}
+			logger.Println("HTTP server stopped listening.")
+		}()
+	}
+	if a.grpcServer != nil {
+		a.serving.Add(1)
+		go func() {
+			defer a.serving.Done()
+			logger.Printf("gRPC server listening on %s", a.grpcListener.Addr())
+			if err := a.grpcServer.Serve(a.grpcListener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				a.errs <- fmt.Errorf("gRPC server failed: %w", err)
+			}
+			logger.Println("gRPC server stopped listening.")
+		}()
+	}
+
+	if a.syslog != nil {
+		a.serving.Add(1)
+		go func() {
+			defer a.serving.Done()
+			if err := a.syslog.Serve(); err != nil {
+				a.errs <- fmt.Errorf("syslog server failed: %w", err)
+			}
+			logger.Println("Syslog server stopped listening.")
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-a.errs:
+		return err
+	}
+}
+
+// Shutdown stops the servers within the configured shutdown timeout, flushes
+// the accepted submissions within the drain timeout and closes the
+// dependencies New opened. It returns an error if the flush timed out.
+func (a *App) Shutdown(ctx context.Context) error {
+	cfg, logger := a.cfg, a.logger
+	shutdownStart := time.Now()
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, cfg.Shutdown.Timeout)
+	defer shutdownCancel()
+
+	if a.httpServer != nil {
+		logger.Println("Shutting down HTTP server...")
+		if err := a.httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Printf("HTTP server shutdown failed: %v", err)
+		} else {
+			logger.Println("HTTP server shutdown.")
+		}
+	}
+	if a.syslog != nil {
+		logger.Println("Shutting down syslog server...")
+		if err := a.syslog.Shutdown(shutdownCtx); err != nil {
+			logger.Printf("Syslog server shutdown timed out, open connections closed: %v", err)
+		} else {
+			logger.Println("Syslog server shutdown.")
+		}
+	}
+	if a.grpcServer != nil {
+		// Report NOT_SERVING first so health-checking clients move to other gateways while this one drains
+		a.healthServer.Shutdown()
+		logger.Println("Shutting down gRPC server...")
+		stopped := make(chan struct{})
+		go func() {
+			a.grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			logger.Println("gRPC server shutdown.")
+		case <-shutdownCtx.Done():
+			// Cancels the remaining RPCs; their submissions were not accepted
+			a.grpcServer.Stop()
+			logger.Printf("gRPC server shutdown timed out after %v, in-flight RPCs cancelled.", cfg.Shutdown.Timeout)
+		}
+	}
+
+	// Wait for HTTP server and gRPC server to finish
+	a.serving.Wait()
+
+	// Flush the accepted submissions, then record usage counted since the last sync
+	drainCtx, drainCancel := context.WithTimeout(ctx, cfg.Shutdown.DrainTimeout)
+	defer drainCancel()
+	logger.Println("Flushing batch processor...")
+	batchStats, err := a.svc.Shutdown(drainCtx)
+	if err != nil {
+		logger.Printf("Batch processor flush timed out after %v, unflushed entries spooled to the WAL", cfg.Shutdown.DrainTimeout)
+		err = fmt.Errorf("failed to flush batch processor: %w", err)
+	}
+	if a.quota != nil {
+		if err := a.quota.Sync(drainCtx); err != nil {
+			logger.Printf("Final quota usage sync failed: %v", err)
+		}
+	}
+	a.close()
+	logger.Printf("Shutdown summary: %v, took %v", batchStats, time.Since(shutdownStart).Round(time.Millisecond))
+	return err
+}
+
+// close releases the listeners, the WAL and the dependencies New opened
+func (a *App) close() {
+	// The servers close the listeners they served; these are the ones never served
+	for _, lis := range []net.Listener{a.httpListener, a.grpcListener} {
+		if lis != nil {
+			lis.Close()
+		}
+	}
+	if a.syslog != nil {
+		a.syslog.Close()
+	}
+	if a.wal != nil {
+		a.wal.Close()
+	}
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i](); err != nil {
+			a.logger.Printf("Failed to close dependency: %v", err)
+		}
+	}
+	a.closers = nil
+}
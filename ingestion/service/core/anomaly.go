@@ -0,0 +1,271 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/metrics"
+)
+
+// Kinds of ingestion anomalies
+const (
+	AnomalySilence    = "silence"    // An org with a steady baseline stopped submitting
+	AnomalyBurst      = "burst"      // An org submitted far above its baseline
+	AnomalyDuplicates = "duplicates" // An unusual share of an org's submissions were duplicates
+)
+
+var (
+	anomaliesDetected = metrics.NewCounter("gateway_anomalies_total",
+		"Ingestion anomalies detected, by kind (silence, burst, duplicates).", "kind")
+	anomalyWebhookFailures = metrics.NewCounter("gateway_anomaly_webhook_failures_total",
+		"Anomaly notifications the webhook did not accept.")
+)
+
+// Anomaly is an unusual ingestion pattern of an org, as sent to the webhook
+type Anomaly struct {
+	Kind       string    `json:"kind"`
+	OrgID      string    `json:"org_id"`
+	DetectedAt time.Time `json:"detected_at"`
+	Window     string    `json:"window"`     // Length of the counting window
+	Count      int64     `json:"count"`      // Submissions in the window that raised the anomaly
+	Duplicates int64     `json:"duplicates"` // Duplicate submissions in that window
+	Baseline   float64   `json:"baseline"`   // Submissions per window the org usually makes
+	Message    string    `json:"message"`
+}
+
+// orgWindow is the state of one org: the current window's counters and the
+// rolling baseline of the previous windows
+type orgWindow struct {
+	count      int64
+	duplicates int64
+	baseline   float64 // Exponentially weighted average of the submissions per window
+	windows    int     // Windows evaluated since the org was first seen
+	zeroStreak int     // Consecutive empty windows
+	preSilence float64 // Baseline when the current zero streak began
+
+	// Anomalies in progress; each is reported once until the pattern ends
+	silent, bursting, duplicating bool
+}
+
+// AnomalyDetector flags orgs whose submissions drop to zero, burst, or are
+// mostly duplicates, compared with a rolling baseline per org. Missing logs
+// are a security signal in their own right, so silence is flagged as loudly
+// as a burst.
+type AnomalyDetector struct {
+	cfg    config.AnomalyDetectionConfig
+	alpha  float64 // EWMA smoothing factor of the baseline
+	logger *log.Logger
+	client *http.Client
+
+	mu   sync.Mutex
+	orgs map[string]*orgWindow
+}
+
+// NewAnomalyDetector creates a new AnomalyDetector
+func NewAnomalyDetector(cfg config.AnomalyDetectionConfig, logger *log.Logger) *AnomalyDetector {
+	return &AnomalyDetector{
+		cfg:    cfg,
+		alpha:  2 / (float64(cfg.BaselineWindows) + 1),
+		logger: logger,
+		client: &http.Client{Timeout: cfg.Webhook.Timeout},
+		orgs:   make(map[string]*orgWindow),
+	}
+}
+
+// observe counts an accepted submission of orgID in the current window
+func (d *AnomalyDetector) observe(orgID string, duplicate bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, ok := d.orgs[orgID]
+	if !ok {
+		if len(d.orgs) >= d.cfg.MaxOrgs {
+			return
+		}
+		w = &orgWindow{}
+		d.orgs[orgID] = w
+	}
+	w.count++
+	if duplicate {
+		w.duplicates++
+	}
+}
+
+// observeDuplicate counts a submission already observed that its batch
+// settled as a duplicate of an earlier one
+func (d *AnomalyDetector) observeDuplicate(orgID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if w, ok := d.orgs[orgID]; ok {
+		w.duplicates++
+	}
+}
+
+// evaluate closes the current window of every org, updates the baselines and
+// returns the anomalies that started in the window
+func (d *AnomalyDetector) evaluate(now time.Time) []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var found []Anomaly
+	report := func(kind, orgID string, w *orgWindow, baseline float64, format string, args ...any) {
+		found = append(found, Anomaly{
+			Kind:       kind,
+			OrgID:      orgID,
+			DetectedAt: now,
+			Window:     d.cfg.Interval.String(),
+			Count:      w.count,
+			Duplicates: w.duplicates,
+			Baseline:   math.Round(baseline*100) / 100,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	for orgID, w := range d.orgs {
+		established := w.windows >= d.cfg.WarmupWindows
+		count := float64(w.count)
+
+		// Silence is judged against the baseline from before the streak, which decays while it lasts
+		if w.count == 0 {
+			if w.zeroStreak == 0 {
+				w.preSilence = w.baseline
+			}
+			w.zeroStreak++
+			if established && !w.silent && w.zeroStreak >= d.cfg.ZeroWindows && w.preSilence >= d.cfg.MinBaseline {
+				w.silent = true
+				report(AnomalySilence, orgID, w, w.preSilence, "no submissions for %v, usually %.1f per %v",
+					time.Duration(w.zeroStreak)*d.cfg.Interval, w.preSilence, d.cfg.Interval)
+			}
+		} else {
+			w.zeroStreak = 0
+			w.silent = false
+		}
+
+		burst := established && w.baseline >= d.cfg.MinBaseline && count > d.cfg.BurstFactor*w.baseline
+		if burst && !w.bursting {
+			report(AnomalyBurst, orgID, w, w.baseline, "%d submissions in %v, %.1fx the usual %.1f",
+				w.count, d.cfg.Interval, count/w.baseline, w.baseline)
+		}
+		w.bursting = burst
+
+		duplicating := w.count >= d.cfg.MinDuplicateSample &&
+			float64(w.duplicates) > d.cfg.DuplicateRate*count
+		if duplicating && !w.duplicating {
+			report(AnomalyDuplicates, orgID, w, w.baseline, "%d of %d submissions in %v were duplicates",
+				w.duplicates, w.count, d.cfg.Interval)
+		}
+		w.duplicating = duplicating
+
+		if w.windows == 0 {
+			w.baseline = count
+		} else {
+			w.baseline += d.alpha * (count - w.baseline)
+		}
+		w.windows++
+		w.count, w.duplicates = 0, 0
+
+		// Forget orgs that have gone quiet for good; a silence was reported long before
+		if w.zeroStreak > 0 && w.baseline < 1 && (w.silent || w.preSilence < d.cfg.MinBaseline) {
+			delete(d.orgs, orgID)
+		}
+	}
+	return found
+}
+
+// alert records an anomaly in the metrics and the log and notifies the webhook
+func (d *AnomalyDetector) alert(ctx context.Context, a Anomaly) {
+	anomaliesDetected.Inc(a.Kind)
+	d.logger.Printf("Anomaly detected: %s for org %s: %s", a.Kind, a.OrgID, a.Message)
+	if d.cfg.Webhook.URL == "" {
+		return
+	}
+	if err := d.notify(ctx, a); err != nil {
+		anomalyWebhookFailures.Inc()
+		d.logger.Printf("Warning: failed to notify the anomaly webhook of the %s of org %s: %v", a.Kind, a.OrgID, err)
+	}
+}
+
+// notify POSTs the anomaly to the webhook, signed if a secret is configured
+func (d *AnomalyDetector) notify(ctx context.Context, a Anomaly) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to encode the anomaly: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.cfg.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create the request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.cfg.Webhook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(d.cfg.Webhook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-TLNG-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SetAnomalyDetector enables anomaly detection on the accepted submissions
+func (s *Service) SetAnomalyDetector(d *AnomalyDetector) {
+	s.anomaly = d
+}
+
+// RunAnomalyDetection evaluates a window every interval until ctx is done. It
+// returns immediately if anomaly detection is disabled.
+func (s *Service) RunAnomalyDetection(ctx context.Context) {
+	if s.anomaly == nil {
+		return
+	}
+	ticker := time.NewTicker(s.anomaly.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, a := range s.anomaly.evaluate(s.clock.Now()) {
+				s.anomaly.alert(ctx, a)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// observeSubmission counts an accepted submission for anomaly detection and
+// returns the result callback to hand to the batch processor, which also
+// counts the submission if its batch settles it as a duplicate. Test traffic
+// is not counted.
+func (s *Service) observeSubmission(input *LogInput, duplicate bool) func(EntryResult) {
+	onResult := input.OnResult
+	if s.anomaly == nil || input.TestTraffic {
+		return onResult
+	}
+	s.anomaly.observe(input.ClientSourceOrgID, duplicate)
+	if duplicate {
+		return onResult
+	}
+	orgID := input.ClientSourceOrgID
+	return func(r EntryResult) {
+		if r.Outcome == OutcomeDuplicate {
+			s.anomaly.observeDuplicate(orgID)
+		}
+		if onResult != nil {
+			onResult(r)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"tlng/apitoken"
+)
+
+// SetAPITokens enables token management at /admin/tokens
+func (s *Service) SetAPITokens(m *apitoken.Manager) {
+	s.apiTokens = m
+}
+
+// APITokens returns the token manager, or nil if API tokens are disabled
+func (s *Service) APITokens() *apitoken.Manager {
+	return s.apiTokens
+}
@@ -2,19 +2,39 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"tlng/config"
+	"tlng/internal/clock"
+	"tlng/internal/idgen"
 	"tlng/internal/messaging/producer"
 	"tlng/internal/models"
+	"tlng/internal/tracing"
 	"tlng/storage/store"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Batch paths, recorded with locally queued and outbox messages so the relay publishes them to the right topic
+const (
+	TierDefault = ""
+	TierLarge   = "large"
+	TierCanary  = "canary"
 )
 
 // BatchProcessor handles batching of log requests for improved throughput
 type BatchProcessor struct {
 	batchSize    int
 	batchTimeout time.Duration
+	region       string
+	policy       store.ConflictPolicy
+	idGen        idgen.Generator // Generates batch IDs
+	clock        clock.Clock     // Times batches and measures their stages
 	logger       *log.Logger
 	store        store.Store
 	producer     producer.Producer
@@ -22,37 +42,145 @@ type BatchProcessor struct {
 	// Buffers
 	buffer      []*batchEntry
 	bufferMutex sync.Mutex
-	ticker      *time.Ticker
+	ticker      clock.Ticker
 	flushChan   chan []*batchEntry
+	timerDone   chan struct{} // Closed when batchTimer has stopped queueing batches
+	lastFlushAt atomic.Int64  // Unix nanoseconds a batch was last queued for processing
+
+	trigger atomic.Pointer[flushTrigger] // Optional; flushes by queue depth, writer load and memory
+
+	wal      *WAL                 // Optional; receives entries that could not be persisted
+	tier     string               // TierDefault, TierLarge or TierCanary; tags messages spooled to the local queue
+	track    string               // config.TrackStable or TrackCanary while a canary is configured; empty otherwise
+	degraded *degradedAcceptance  // Optional; handles persistent Kafka publish failures
+	outbox   *config.OutboxConfig // Optional; messages are written to the outbox with their submissions
 
 	// Context for graceful shutdown
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+	opCtx     context.Context // Store and producer calls; cancelled when shutdown runs out of time
+	abort     context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	stats batchCounters // Entry accounting exposed via Stats()
+}
+
+// BatchStats counts the entries handled by the batch processor
+type BatchStats struct {
+	Accepted        int64 `json:"accepted"`          // Entries submitted
+	Persisted       int64 `json:"persisted"`         // Entries inserted into the State DB, including duplicates
+	Duplicates      int64 `json:"duplicates"`        // Entries skipped on conflict as already stored
+	Published       int64 `json:"published"`         // Entries published to Kafka
+	Spooled         int64 `json:"spooled"`           // Entries written to the WAL after a failed batch insert
+	Replayed        int64 `json:"replayed"`          // Entries read back from the WAL at startup
+	InsertFailed    int64 `json:"insert_failed"`     // Entries lost to a failed batch insert (no WAL, or the WAL write failed)
+	PublishFailed   int64 `json:"publish_failed"`    // Entries stored but neither published nor queued locally
+	QueuedLocal     int64 `json:"queued_local"`      // Entries queued in the State DB while Kafka was unavailable
+	Outboxed        int64 `json:"outboxed"`          // Entries whose publish failed, left in the outbox for the relay
+	Relayed         int64 `json:"relayed"`           // Locally queued or outbox entries published by a relay
+	InFlight        int64 `json:"in_flight"`         // Entries in batches currently being processed
+	InFlightBatches int64 `json:"in_flight_batches"` // Batches currently being processed
+	Pending         int64 `json:"pending"`           // Entries buffered or queued, not yet processed
+
+	LastBatchID string    `json:"last_batch_id,omitempty"` // Most recently flushed batch
+	LastBatchAt time.Time `json:"last_batch_at,omitempty"`
+}
+
+// Unfinished returns the number of accepted entries not yet persisted,
+// spooled or failed; they are abandoned if the processor stops now
+func (st BatchStats) Unfinished() int64 {
+	return st.InFlight + st.Pending
+}
+
+func (st BatchStats) String() string {
+	return fmt.Sprintf("accepted=%d (replayed=%d) persisted=%d (duplicates=%d) published=%d queued_local=%d outboxed=%d spooled=%d insert_failed=%d publish_failed=%d unfinished=%d",
+		st.Accepted, st.Replayed, st.Persisted, st.Duplicates, st.Published, st.QueuedLocal, st.Outboxed, st.Spooled, st.InsertFailed, st.PublishFailed, st.Unfinished())
+}
+
+// batchCounters holds the live counters updated by the processor goroutines
+type batchCounters struct {
+	accepted        atomic.Int64
+	persisted       atomic.Int64
+	duplicates      atomic.Int64
+	published       atomic.Int64
+	spooled         atomic.Int64
+	replayed        atomic.Int64
+	insertFailed    atomic.Int64
+	publishFailed   atomic.Int64
+	queuedLocal     atomic.Int64
+	outboxed        atomic.Int64
+	relayed         atomic.Int64
+	inFlight        atomic.Int64
+	inFlightBatches atomic.Int64
+	lastBatch       atomic.Pointer[lastBatch]
+}
+
+// lastBatch identifies the most recently flushed batch
+type lastBatch struct {
+	id string
+	at time.Time
 }
 
 type batchEntry struct {
-	input     *LogInput
-	requestID string
+	input      *LogInput
+	requestID  string
+	receivedAt time.Time         // Server receive time
+	done       func(EntryResult) // Optional; receives the entry's outcome
+}
+
+// EntryOutcome is what became of an entry once its batch was processed
+type EntryOutcome string
+
+const (
+	OutcomePublished     EntryOutcome = "published"      // Stored and published to Kafka
+	OutcomeDuplicate     EntryOutcome = "duplicate"      // Skipped on conflict as already stored
+	OutcomeQueuedLocal   EntryOutcome = "queued_local"   // Stored and queued locally for the relay while Kafka is unavailable
+	OutcomeOutboxed      EntryOutcome = "outboxed"       // Stored; the publish failed and the outbox relay publishes it
+	OutcomePublishFailed EntryOutcome = "publish_failed" // Stored but neither published nor queued locally
+	OutcomeSpooled       EntryOutcome = "spooled"        // Not stored; written to the WAL for replay on restart
+	OutcomeInsertFailed  EntryOutcome = "insert_failed"  // Not stored and lost
+)
+
+// EntryResult reports the outcome of one submitted entry
+type EntryResult struct {
+	RequestID string
+	BatchID   string // Gateway batch that processed the entry
+	Outcome   EntryOutcome
+	Err       error // Cause of a failed, spooled or locally queued outcome
+}
+
+// Persisted reports whether the entry is in the State DB
+func (r EntryResult) Persisted() bool {
+	return r.Outcome != OutcomeSpooled && r.Outcome != OutcomeInsertFailed
 }
 
 // NewBatchProcessor creates a new batch processor
-func NewBatchProcessor(batchSize int, batchTimeout time.Duration, flushChannelBuffer int,
-	store store.Store, producer producer.Producer, logger *log.Logger) *BatchProcessor {
+func NewBatchProcessor(batchSize int, batchTimeout time.Duration, flushChannelBuffer int, region string, policy store.ConflictPolicy,
+	idGen idgen.Generator, clk clock.Clock, store store.Store, producer producer.Producer, logger *log.Logger) *BatchProcessor {
 
 	ctx, cancel := context.WithCancel(context.Background())
+	opCtx, abort := context.WithCancel(context.Background())
 
 	bp := &BatchProcessor{
 		batchSize:    batchSize,
 		batchTimeout: batchTimeout,
+		region:       region,
+		policy:       policy,
+		idGen:        idGen,
+		clock:        clk,
 		logger:       logger,
 		store:        store,
 		producer:     producer,
 		buffer:       make([]*batchEntry, 0, batchSize),
 		flushChan:    make(chan []*batchEntry, flushChannelBuffer), // Configurable buffer for flush requests
+		timerDone:    make(chan struct{}),
 		ctx:          ctx,
 		cancel:       cancel,
+		opCtx:        opCtx,
+		abort:        abort,
 	}
+	bp.markFlushed()
 
 	// Start background goroutines
 	bp.wg.Add(2)
@@ -62,12 +190,22 @@ func NewBatchProcessor(batchSize int, batchTimeout time.Duration, flushChannelBu
 	return bp
 }
 
-// SubmitLog adds a log to the batch with pre-generated request ID
-func (bp *BatchProcessor) SubmitLog(input *LogInput, requestID string) {
+// SetWAL spools entries whose batch insert fails to w instead of dropping them
+func (bp *BatchProcessor) SetWAL(w *WAL) {
+	bp.wal = w
+}
+
+// SubmitLog adds a log to the batch with pre-generated request ID. done, if
+// set, is called once with the entry's outcome from the processor goroutine
+// and must not block. It must not be called once Shutdown has started.
+func (bp *BatchProcessor) SubmitLog(input *LogInput, requestID string, receivedAt time.Time, done func(EntryResult)) {
 	entry := &batchEntry{
-		input:     input,
-		requestID: requestID,
+		input:      input,
+		requestID:  requestID,
+		receivedAt: receivedAt,
+		done:       done,
 	}
+	bp.stats.accepted.Add(1)
 
 	// Add to buffer
 	bp.bufferMutex.Lock()
@@ -77,9 +215,15 @@ func (bp *BatchProcessor) SubmitLog(input *LogInput, requestID string) {
 
 	// Trigger flush if buffer is full
 	if shouldFlush {
+		batch := bp.getAndResetBuffer()
 		select {
-		case bp.flushChan <- bp.getAndResetBuffer():
+		case bp.flushChan <- batch:
+			bp.markFlushed()
 		default:
+			// Put the batch back so the next timer tick flushes it
+			bp.bufferMutex.Lock()
+			bp.buffer = append(batch, bp.buffer...)
+			bp.bufferMutex.Unlock()
 			bp.logger.Printf("Flush channel full, will flush on next timer")
 		}
 	}
@@ -88,15 +232,22 @@ func (bp *BatchProcessor) SubmitLog(input *LogInput, requestID string) {
 // batchTimer handles periodic flushing
 func (bp *BatchProcessor) batchTimer() {
 	defer bp.wg.Done()
+	defer close(bp.timerDone)
 
-	bp.ticker = time.NewTicker(bp.batchTimeout)
+	bp.ticker = bp.clock.NewTicker(bp.batchTimeout)
 	defer bp.ticker.Stop()
 
 	for {
 		select {
-		case <-bp.ticker.C:
-			bp.flushIfNeeded()
+		case <-bp.ticker.C():
+			if !bp.timerFlushPostponed() {
+				bp.flushIfNeeded()
+			}
 		case <-bp.ctx.Done():
+			// The flush trigger may still queue a batch until it stops
+			if t := bp.trigger.Load(); t != nil {
+				<-t.done
+			}
 			return
 		}
 	}
@@ -113,26 +264,29 @@ func (bp *BatchProcessor) batchProcessor() {
 				bp.processBatch(batch)
 			}
 		case <-bp.ctx.Done():
-			// Process remaining buffer before shutdown
-			bp.bufferMutex.Lock()
-			remaining := bp.buffer
-			bp.buffer = nil
-			bp.bufferMutex.Unlock()
-
-			if len(remaining) > 0 {
-				bp.processBatch(remaining)
+			// Once the timer has stopped nothing else is queued: drain the
+			// flush channel completely, then the remaining buffer
+			<-bp.timerDone
+			for {
+				select {
+				case batch := <-bp.flushChan:
+					bp.processBatch(batch)
+				default:
+					bp.processBatch(bp.getAndResetBuffer())
+					return
+				}
 			}
-			return
 		}
 	}
 }
 
-// flushIfNeeded flushes the buffer if it has entries
-func (bp *BatchProcessor) flushIfNeeded() {
+// flushIfNeeded flushes the buffer if it has entries, reporting whether a
+// batch was queued
+func (bp *BatchProcessor) flushIfNeeded() bool {
 	bp.bufferMutex.Lock()
 	if len(bp.buffer) == 0 {
 		bp.bufferMutex.Unlock()
-		return
+		return false
 	}
 
 	batch := make([]*batchEntry, len(bp.buffer))
@@ -142,11 +296,14 @@ func (bp *BatchProcessor) flushIfNeeded() {
 
 	select {
 	case bp.flushChan <- batch:
+		bp.markFlushed()
+		return true
 	default:
 		// If flush channel is full, put it back in buffer
 		bp.bufferMutex.Lock()
 		bp.buffer = append(batch, bp.buffer...)
 		bp.bufferMutex.Unlock()
+		return false
 	}
 }
 
@@ -167,8 +324,24 @@ func (bp *BatchProcessor) processBatch(batch []*batchEntry) {
 		return
 	}
 
-	start := time.Now()
+	start := bp.clock.Now()
+	batchID := bp.idGen.NewID()
+	carriers := make([]map[string]string, len(batch))
+	for i := range batch {
+		carriers[i] = batch[i].input.traceContext
+	}
+	ctx, span := tracer.Start(bp.opCtx, "gateway.batch", trace.WithNewRoot(), trace.WithLinks(tracing.Links(carriers)...),
+		trace.WithAttributes(tracing.AttrBatchID.String(batchID), tracing.AttrBatchSize.Int(len(batch))))
+	defer span.End()
+	bp.stats.lastBatch.Store(&lastBatch{id: batchID, at: start})
 	// bp.logger.Printf("Processing batch of %d logs", len(batch))
+	bp.stats.inFlight.Add(int64(len(batch)))
+	bp.stats.inFlightBatches.Add(1)
+	flushedBatchSize.Observe(float64(len(batch)))
+	defer func() {
+		bp.stats.inFlight.Add(-int64(len(batch)))
+		bp.stats.inFlightBatches.Add(-1)
+	}()
 
 	// Prepare batch data
 	logStatuses := make([]*store.LogStatus, len(batch))
@@ -178,13 +351,23 @@ func (bp *BatchProcessor) processBatch(batch []*batchEntry) {
 
 		logHash := batch[i].input.ClientLogHash
 		sourceOrgID := batch[i].input.ClientSourceOrgID
+		clientTimestamp := batch[i].input.ClientTimestamp
 
 		logStatuses[i] = &store.LogStatus{
 			RequestID:         batch[i].requestID,
 			LogHash:           logHash,
 			SourceOrgID:       sourceOrgID,
-			ReceivedTimestamp: time.Now(),
+			ReceivedTimestamp: batch[i].receivedAt,
 			Status:            store.StatusReceived,
+			Region:            bp.region,
+			ClientTimestamp:   clientTimestamp,
+			GatewayBatchID:    batchID,
+			Severity:          batch[i].input.Severity,
+			SourceHost:        batch[i].input.SourceHost,
+			Application:       batch[i].input.Application,
+			Sequence:          batch[i].input.Sequence,
+			TestTraffic:       batch[i].input.TestTraffic,
+			ExternalID:        batch[i].input.ExternalID,
 		}
 
 		kafkaMessages[i] = &models.LogMessage{
@@ -192,43 +375,275 @@ func (bp *BatchProcessor) processBatch(batch []*batchEntry) {
 			LogContent:        batch[i].input.LogContent,
 			LogHash:           logHash,
 			SourceOrgID:       sourceOrgID,
-			ReceivedTimestamp: time.Now().Format(time.RFC3339Nano),
+			ReceivedTimestamp: models.NewTimestamp(batch[i].receivedAt),
+			Region:            bp.region,
+			BatchID:           batchID,
+			TraceContext:      batch[i].input.traceContext,
+		}
+		if clientTimestamp != nil {
+			ts := models.NewTimestamp(*clientTimestamp)
+			kafkaMessages[i].ClientTimestamp = &ts
 		}
 	}
 
 	// Batch database insert
-	dbStart := time.Now()
-	dbErr := bp.store.InsertLogStatusBatch(context.Background(), logStatuses)
-	dbDuration := time.Since(dbStart)
+	dbStart := bp.clock.Now()
+	dbCtx, dbSpan := tracer.Start(ctx, "gateway.db.insert")
+	insertResult, dbErr := bp.insertStatuses(dbCtx, logStatuses, bp.outboxMessages(kafkaMessages))
+	tracing.End(dbSpan, dbErr)
+	dbDuration := clock.Since(bp.clock, dbStart)
+	dbInsertDuration.ObserveDurationWithExemplar(dbDuration, tracing.TraceID(dbCtx))
 
 	if dbErr != nil {
-		bp.logger.Printf("Batch database insert failed: %v", dbErr)
-		// Notify all entries of failure
-		for range batch {
-			// In production, you might want to retry or use a dead letter queue
-		}
+		dbInsertFailures.Inc()
+		bp.logger.Printf("Batch %s: database insert failed: %v", batchID, dbErr)
+		bp.spool(batchID, batch, dbErr)
 		return
 	}
 
+	bp.stats.persisted.Add(int64(len(batch)))
+
+	// Rows skipped on conflict were stored by an earlier insert, which may have
+	// committed without its batch being published: a timed-out insert spooled to
+	// the WAL, or a retry whose first publish failed. Rows still RECEIVED without
+	// an outbox row are published again, since the engine tolerates duplicate
+	// messages; the others are already queued.
+	// entries stays aligned with kafkaMessages so every entry gets the outcome of its message.
+	entries := batch
+	if len(insertResult.Skipped) > 0 {
+		skipped := make(map[string]struct{}, len(insertResult.Skipped))
+		for _, requestID := range insertResult.Skipped {
+			skipped[requestID] = struct{}{}
+		}
+		unqueued, err := bp.store.ListUnqueuedReceived(ctx, insertResult.Skipped)
+		if err != nil {
+			bp.logger.Printf("Batch %s: failed to check %d duplicate request_ids for unpublished rows: %v", batchID, len(insertResult.Skipped), err)
+		}
+		for _, requestID := range unqueued {
+			delete(skipped, requestID)
+		}
+		bp.logger.Printf("Batch %s: insert skipped %d duplicate request_ids, %d of them unpublished and published again",
+			batchID, len(insertResult.Skipped), len(unqueued))
+		entries = make([]*batchEntry, 0, len(batch))
+		duplicates := make([]*batchEntry, 0, len(skipped))
+		publishable := kafkaMessages[:0]
+		for i, msg := range kafkaMessages {
+			if _, ok := skipped[msg.RequestID]; ok {
+				duplicates = append(duplicates, batch[i])
+				continue
+			}
+			entries = append(entries, batch[i])
+			publishable = append(publishable, msg)
+		}
+		kafkaMessages = publishable
+		bp.settle(batchID, duplicates, OutcomeDuplicate, nil)
+	}
+
+	// While degraded under the spool policy, skip Kafka until the cooldown ends or the relay gets through
+	if bp.degraded.spools() {
+		if open, _ := bp.degraded.breaker.open(bp.clock.Now()); open {
+			bp.queueLocal(batchID, entries, kafkaMessages, ErrQueueUnavailable)
+			return
+		}
+	}
+
 	// Batch Kafka publish
-	kafkaStart := time.Now()
-	kafkaErr := bp.producer.PublishBatch(context.Background(), kafkaMessages)
-	kafkaDuration := time.Since(kafkaStart)
+	kafkaStart := bp.clock.Now()
+	kafkaCtx, kafkaSpan := tracer.Start(ctx, "gateway.kafka.publish")
+	kafkaErr := bp.producer.PublishBatch(kafkaCtx, kafkaMessages)
+	tracing.End(kafkaSpan, kafkaErr)
+	kafkaDuration := clock.Since(bp.clock, kafkaStart)
+	kafkaPublishDuration.ObserveDurationWithExemplar(kafkaDuration, tracing.TraceID(kafkaCtx))
 
 	if kafkaErr != nil {
-		bp.logger.Printf("Batch Kafka publish failed: %v", kafkaErr)
-		// Handle failure - might need to retry or use dead letter queue
+		kafkaPublishFailures.Inc()
+		bp.logger.Printf("Batch %s: Kafka publish failed: %v", batchID, kafkaErr)
+		if bp.degraded != nil && bp.degraded.breaker.failure(bp.clock.Now()) {
+			bp.logger.Printf("Kafka publishing failed %d batches in a row, degraded (policy %s) for %v",
+				bp.degraded.cfg.FailureThreshold, bp.degraded.cfg.Policy, bp.degraded.cfg.Cooldown)
+		}
+		if bp.degraded.spools() {
+			bp.queueLocal(batchID, entries, kafkaMessages, kafkaErr)
+			return
+		}
+		if bp.outbox != nil {
+			bp.releaseOutbox(batchID, entries, kafkaMessages, kafkaErr)
+			return
+		}
+		bp.settle(batchID, entries, OutcomePublishFailed, kafkaErr)
 		return
 	}
+	if bp.degraded != nil {
+		bp.degraded.breaker.success()
+	}
+	if bp.outbox != nil {
+		bp.markOutboxSent(ctx, batchID, kafkaMessages)
+	}
+
+	bp.settle(batchID, entries, OutcomePublished, nil)
+
+	totalDuration := clock.Since(bp.clock, start)
+	bp.logger.Printf("Batch processed: batch_id=%s, %d logs, DB: %v, Kafka: %v, Total: %v",
+		batchID, len(batch), dbDuration, kafkaDuration, totalDuration)
+}
+
+// insertStatuses inserts the rows of a batch. An insert that lost against a
+// concurrent write is retried once; one rejected over a duplicate request_id
+// is retried skipping the duplicates, which then settle as OutcomeDuplicate
+// instead of failing the batch.
+func (bp *BatchProcessor) insertStatuses(ctx context.Context, statuses []*store.LogStatus, outbox []store.OutboxMessage) (*store.InsertResult, error) {
+	insert := func(policy store.ConflictPolicy) (*store.InsertResult, error) {
+		if bp.outbox != nil {
+			return bp.store.InsertLogStatusBatchWithOutbox(ctx, statuses, policy, outbox, bp.outbox.Lease)
+		}
+		return bp.store.InsertLogStatusBatch(ctx, statuses, policy)
+	}
+	result, err := insert(bp.policy)
+	switch {
+	case errors.Is(err, store.ErrConflict):
+		bp.logger.Printf("Insert of %d rows conflicted with a concurrent write, retrying: %v", len(statuses), err)
+		return insert(bp.policy)
+	case errors.Is(err, store.ErrDuplicateRequestID) && bp.policy != store.ConflictDoNothing:
+		return insert(store.ConflictDoNothing)
+	}
+	return result, err
+}
 
-	totalDuration := time.Since(start)
-	bp.logger.Printf("Batch processed: %d logs, DB: %v, Kafka: %v, Total: %v",
-		len(batch), dbDuration, kafkaDuration, totalDuration)
+// spool writes a batch that could not be inserted to the WAL, if configured
+func (bp *BatchProcessor) spool(batchID string, batch []*batchEntry, insertErr error) {
+	if bp.wal == nil {
+		bp.settle(batchID, batch, OutcomeInsertFailed, insertErr)
+		return
+	}
+	if err := bp.wal.Append(batch); err != nil {
+		bp.logger.Printf("Failed to spool %d entries to the WAL, entries lost: %v", len(batch), err)
+		bp.settle(batchID, batch, OutcomeInsertFailed, errors.Join(insertErr, err))
+		return
+	}
+	bp.settle(batchID, batch, OutcomeSpooled, insertErr)
+	bp.logger.Printf("Spooled %d entries to the WAL for replay on restart", len(batch))
+}
+
+// settle counts entries under their outcome and reports it to each entry's callback
+func (bp *BatchProcessor) settle(batchID string, entries []*batchEntry, outcome EntryOutcome, err error) {
+	if len(entries) == 0 {
+		return
+	}
+	bp.stats.outcome(outcome).Add(int64(len(entries)))
+	logsSubmitted.Add(float64(len(entries)), string(outcome))
+	if bp.track != "" {
+		trackLogsSubmitted.Add(float64(len(entries)), bp.track, string(outcome))
+	}
+	for _, e := range entries {
+		if e.done != nil {
+			e.done(EntryResult{RequestID: e.requestID, BatchID: batchID, Outcome: outcome, Err: err})
+		}
+	}
+}
+
+// outcome returns the counter of entries that ended with an outcome
+func (c *batchCounters) outcome(o EntryOutcome) *atomic.Int64 {
+	switch o {
+	case OutcomePublished:
+		return &c.published
+	case OutcomeDuplicate:
+		return &c.duplicates
+	case OutcomeQueuedLocal:
+		return &c.queuedLocal
+	case OutcomeOutboxed:
+		return &c.outboxed
+	case OutcomePublishFailed:
+		return &c.publishFailed
+	case OutcomeSpooled:
+		return &c.spooled
+	default:
+		return &c.insertFailed
+	}
+}
+
+// replay inserts and publishes entries spooled to the WAL by a previous run.
+// Entries that fail again are spooled anew.
+func (bp *BatchProcessor) replay(entries []*batchEntry) {
+	bp.stats.accepted.Add(int64(len(entries)))
+	bp.stats.replayed.Add(int64(len(entries)))
+	for start := 0; start < len(entries); start += bp.batchSize {
+		bp.processBatch(entries[start:min(start+bp.batchSize, len(entries))])
+	}
+}
+
+// add sums two snapshots
+func (st BatchStats) add(o BatchStats) BatchStats {
+	st.Accepted += o.Accepted
+	st.Persisted += o.Persisted
+	st.Duplicates += o.Duplicates
+	st.Published += o.Published
+	st.Spooled += o.Spooled
+	st.Replayed += o.Replayed
+	st.InsertFailed += o.InsertFailed
+	st.PublishFailed += o.PublishFailed
+	st.QueuedLocal += o.QueuedLocal
+	st.Outboxed += o.Outboxed
+	st.Relayed += o.Relayed
+	st.InFlight += o.InFlight
+	st.InFlightBatches += o.InFlightBatches
+	st.Pending += o.Pending
+	if o.LastBatchAt.After(st.LastBatchAt) {
+		st.LastBatchID, st.LastBatchAt = o.LastBatchID, o.LastBatchAt
+	}
+	return st
+}
+
+// Stats returns a snapshot of the processor's entry accounting
+func (bp *BatchProcessor) Stats() BatchStats {
+	st := BatchStats{
+		Accepted:        bp.stats.accepted.Load(),
+		Persisted:       bp.stats.persisted.Load(),
+		Duplicates:      bp.stats.duplicates.Load(),
+		Published:       bp.stats.published.Load(),
+		Spooled:         bp.stats.spooled.Load(),
+		Replayed:        bp.stats.replayed.Load(),
+		InsertFailed:    bp.stats.insertFailed.Load(),
+		PublishFailed:   bp.stats.publishFailed.Load(),
+		QueuedLocal:     bp.stats.queuedLocal.Load(),
+		Outboxed:        bp.stats.outboxed.Load(),
+		Relayed:         bp.stats.relayed.Load(),
+		InFlight:        bp.stats.inFlight.Load(),
+		InFlightBatches: bp.stats.inFlightBatches.Load(),
+	}
+	st.Pending = max(st.Accepted-st.Persisted-st.Spooled-st.InsertFailed-st.InFlight, 0)
+	if last := bp.stats.lastBatch.Load(); last != nil {
+		st.LastBatchID, st.LastBatchAt = last.id, last.at
+	}
+	return st
+}
+
+// Shutdown stops the batch processor and blocks until every accepted entry
+// is persisted or spooled to the WAL: queued batches and the remaining buffer
+// are flushed. If ctx expires first, in-flight store and producer calls are
+// cancelled so the rest goes straight to the WAL, and ctx's error is returned.
+// Without a WAL those entries are lost and counted as insert failures.
+func (bp *BatchProcessor) Shutdown(ctx context.Context) error {
+	bp.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		bp.wg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		bp.logger.Printf("Batch processor flush deadline reached, spooling %d unflushed entries", bp.Stats().Unfinished())
+		bp.abort()
+		<-done
+	}
+	bp.closeOnce.Do(func() { close(bp.flushChan) })
+	return err
 }
 
 // Close gracefully shuts down the batch processor
 func (bp *BatchProcessor) Close() {
-	bp.cancel()
-	bp.wg.Wait()
-	close(bp.flushChan)
+	bp.Shutdown(context.Background())
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"tlng/config"
+	"tlng/svcauth"
+)
+
+// ErrOrgNotAllowed indicates a submission for another org than the one the
+// caller is bound to, by its API token or client certificate
+var ErrOrgNotAllowed = errors.New("org not allowed for this caller")
+
+// ClientCertOrg returns the org named by field in the subject of the caller's
+// verified client certificate; "" if it presented none, and an error if the
+// subject lacks the field
+func ClientCertOrg(state *tls.ConnectionState, field string) (string, error) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", nil
+	}
+	subject := state.VerifiedChains[0][0].Subject
+	var org string
+	switch field {
+	case config.CertOrgFromOrganization:
+		if len(subject.Organization) > 0 {
+			org = subject.Organization[0]
+		}
+	case config.CertOrgFromOrganizationalUnit:
+		if len(subject.OrganizationalUnit) > 0 {
+			org = subject.OrganizationalUnit[0]
+		}
+	case config.CertOrgFromCommonName:
+		org = subject.CommonName
+	}
+	if org == "" {
+		return "", fmt.Errorf("%w: client certificate %s has no %s", ErrOrgNotAllowed, subject, field)
+	}
+	return org, nil
+}
+
+type certOrgKey struct{}
+
+// WithClientCertOrg returns a context carrying the org of the caller's client certificate
+func WithClientCertOrg(ctx context.Context, org string) context.Context {
+	return context.WithValue(ctx, certOrgKey{}, org)
+}
+
+// bindCallerOrg fills in the org of a submission from a caller bound to an
+// org, by API token or client certificate, and rejects a submission naming another org
+func bindCallerOrg(ctx context.Context, input *LogInput) error {
+	var bound []string
+	if id := svcauth.IdentityFromContext(ctx); id != nil && id.OrgID != "" {
+		bound = append(bound, id.OrgID)
+	}
+	if org, _ := ctx.Value(certOrgKey{}).(string); org != "" {
+		bound = append(bound, org)
+	}
+	for _, org := range bound {
+		if input.ClientSourceOrgID == "" {
+			input.ClientSourceOrgID = org
+		} else if input.ClientSourceOrgID != org {
+			return fmt.Errorf("%w: caller is bound to org '%s', submission names '%s'", ErrOrgNotAllowed, org, input.ClientSourceOrgID)
+		}
+	}
+	return nil
+}
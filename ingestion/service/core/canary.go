@@ -0,0 +1,63 @@
+package service
+
+import (
+	"hash/fnv"
+
+	"tlng/config"
+	"tlng/internal/messaging/producer"
+)
+
+// canaryBuckets is the resolution of the canary split: 0.01 percent
+const canaryBuckets = 10000
+
+// canaryRoute splits submissions between the stable and the canary track
+type canaryRoute struct {
+	buckets uint32 // Buckets out of canaryBuckets routed to the canary
+	byOrg   bool   // Split by org instead of request ID
+}
+
+// EnableCanary routes cfg.Percent of the submissions that are not large
+// through a batch processor publishing with p, the canary engine's topic. It
+// must be called after EnableSizeTier.
+func (s *Service) EnableCanary(cfg config.CanaryConfig, p producer.Producer) {
+	bp := s.batchProcessor
+	s.canary = NewBatchProcessor(bp.batchSize, bp.batchTimeout, cap(bp.flushChan), bp.region, bp.policy, s.idGen, s.clock, s.store, p, s.logger)
+	s.canary.tier = TierCanary
+	s.canary.track = config.TrackCanary
+	for _, stable := range []*BatchProcessor{s.batchProcessor, s.large} {
+		if stable != nil {
+			stable.track = config.TrackStable
+		}
+	}
+	s.canaryRoute = canaryRoute{
+		buckets: uint32(cfg.Percent*canaryBuckets/100 + 0.5),
+		byOrg:   cfg.SplitBy == config.CanarySplitByOrg,
+	}
+	if s.wal != nil {
+		s.canary.SetWAL(s.wal)
+	}
+}
+
+// isCanary reports whether a submission goes through the canary batch path.
+// The split is a hash of the request ID or org, so it is the same on every
+// gateway and for a submission replayed from the WAL.
+func (s *Service) isCanary(requestID, orgID string) bool {
+	if s.canary == nil {
+		return false
+	}
+	key := requestID
+	if s.canaryRoute.byOrg {
+		key = orgID
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()%canaryBuckets < s.canaryRoute.buckets
+}
+
+// CanaryBatchStats returns the canary batch processor's entry accounting, if a canary is configured
+func (s *Service) CanaryBatchStats() (BatchStats, bool) {
+	if s.canary == nil {
+		return BatchStats{}, false
+	}
+	return s.canary.Stats(), true
+}
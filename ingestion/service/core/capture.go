@@ -0,0 +1,216 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	mrand "math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"tlng/config"
+	"tlng/storage/store"
+
+	"github.com/google/uuid"
+)
+
+// captureQueueSize bounds the captures waiting to be written; further captures are dropped
+const captureQueueSize = 64
+
+// Rejection is a rejected submission offered for debug capture
+type Rejection struct {
+	OrgID       string
+	Transport   string // http or grpc
+	StatusCode  int    // HTTP status, or gRPC code
+	Reason      string // Error returned to the client
+	ContentType string
+	Payload     []byte // Raw request bytes
+}
+
+// DebugCapturer stores the raw requests of a sample of rejected submissions of
+// opted-in orgs, encrypted, in the State DB. Capturing never blocks a request:
+// captures beyond the per-org rate or the write queue are dropped.
+type DebugCapturer struct {
+	cfg    config.DebugCaptureConfig
+	aead   cipher.AEAD
+	store  store.Store
+	logger *log.Logger
+	queue  chan store.DebugCapture
+
+	mu      sync.Mutex
+	windows map[string]*captureWindow // Org ID -> current minute's captures
+}
+
+// captureWindow counts an org's captures in a one-minute window
+type captureWindow struct {
+	start time.Time
+	count int
+}
+
+// NewDebugCapturer creates a capturer encrypting payloads with the key in cfg.KeyFile
+func NewDebugCapturer(cfg config.DebugCaptureConfig, s store.Store, logger *log.Logger) (*DebugCapturer, error) {
+	key, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read debug capture key: %w", err)
+	}
+	key = bytes.TrimSpace(key)
+	if len(key) == 64 {
+		if decoded, err := hex.DecodeString(string(key)); err == nil {
+			key = decoded
+		}
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("debug capture key must be 32 bytes (or 64 hex digits), got %d bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debug capture cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debug capture cipher: %w", err)
+	}
+	return &DebugCapturer{
+		cfg:     cfg,
+		aead:    aead,
+		store:   s,
+		logger:  logger,
+		queue:   make(chan store.DebugCapture, captureQueueSize),
+		windows: make(map[string]*captureWindow),
+	}, nil
+}
+
+// Capture offers a rejected submission for capture. It is captured if its org
+// opted in, it is sampled, and the org is within its per-minute budget.
+func (c *DebugCapturer) Capture(r Rejection) {
+	if r.OrgID == "" || !slices.Contains(c.cfg.Orgs, r.OrgID) || mrand.Float64() >= c.cfg.SampleRate {
+		return
+	}
+	now := time.Now()
+	if !c.allow(r.OrgID, now) {
+		return
+	}
+
+	payload, truncated := r.Payload, false
+	if len(payload) > c.cfg.MaxBytes {
+		payload, truncated = payload[:c.cfg.MaxBytes], true
+	}
+	capture := store.DebugCapture{
+		CaptureID:   uuid.NewString(),
+		OrgID:       r.OrgID,
+		Transport:   r.Transport,
+		StatusCode:  r.StatusCode,
+		Reason:      r.Reason,
+		ContentType: r.ContentType,
+		PayloadSize: len(r.Payload),
+		Truncated:   truncated,
+		CapturedAt:  now,
+		ExpiresAt:   now.Add(c.cfg.TTL),
+	}
+	capture.Payload = c.seal(payload, capture.CaptureID)
+	select {
+	case c.queue <- capture:
+	default:
+		c.logger.Printf("Warning: debug capture queue full, dropping capture of org %s", r.OrgID)
+	}
+}
+
+// allow takes one capture from the org's budget for the current minute
+func (c *DebugCapturer) allow(orgID string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.windows[orgID]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		w = &captureWindow{start: now}
+		c.windows[orgID] = w
+	}
+	if w.count >= c.cfg.MaxPerMinute {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// seal encrypts a payload, bound to its capture ID, as nonce followed by ciphertext
+func (c *DebugCapturer) seal(payload []byte, captureID string) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	rand.Read(nonce)
+	return c.aead.Seal(nonce, nonce, payload, []byte(captureID))
+}
+
+// open decrypts a payload sealed by seal
+func (c *DebugCapturer) open(sealed []byte, captureID string) ([]byte, error) {
+	n := c.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("debug capture %s payload is too short", captureID)
+	}
+	payload, err := c.aead.Open(nil, sealed[:n], sealed[n:], []byte(captureID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt debug capture %s (wrong key?): %w", captureID, err)
+	}
+	return payload, nil
+}
+
+// Run writes the captures and deletes expired ones every purge interval until ctx is done
+func (c *DebugCapturer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case capture := <-c.queue:
+			if err := c.store.InsertDebugCapture(ctx, capture); err != nil {
+				c.logger.Printf("Warning: failed to store debug capture of org %s: %v", capture.OrgID, err)
+			}
+		case <-ticker.C:
+			purged, err := c.store.PurgeDebugCaptures(ctx)
+			if err != nil {
+				c.logger.Printf("Warning: failed to purge expired debug captures: %v", err)
+			} else if purged > 0 {
+				c.logger.Printf("Purged %d expired debug captures", purged)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// List returns up to limit unexpired captures, newest first, without payloads; orgID "" lists all orgs
+func (c *DebugCapturer) List(ctx context.Context, orgID string, limit int) ([]*store.DebugCapture, error) {
+	return c.store.ListDebugCaptures(ctx, orgID, limit)
+}
+
+// Get returns a capture with its decrypted payload
+func (c *DebugCapturer) Get(ctx context.Context, captureID string) (*store.DebugCapture, error) {
+	capture, err := c.store.GetDebugCapture(ctx, captureID)
+	if err != nil {
+		return nil, err
+	}
+	if capture.Payload, err = c.open(capture.Payload, captureID); err != nil {
+		return nil, err
+	}
+	return capture, nil
+}
+
+// SetDebugCapturer enables debug capture of rejected submissions
+func (s *Service) SetDebugCapturer(c *DebugCapturer) {
+	s.capturer = c
+}
+
+// DebugCapturer returns the debug capturer, or nil if debug capture is disabled
+func (s *Service) DebugCapturer() *DebugCapturer {
+	return s.capturer
+}
+
+// CaptureRejection offers a rejected submission for debug capture, if enabled
+func (s *Service) CaptureRejection(r Rejection) {
+	if s.capturer != nil {
+		s.capturer.Capture(r)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DedupCache remembers recently accepted submissions so that rapid client
+// retries get the original result back instead of being enqueued twice. It is
+// local to one gateway instance; a retry that lands on another gateway is
+// enqueued again unless it carries an idempotency key.
+type DedupCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[dedupKey]*LogResult
+	order   []dedupSlot // Insertion order, which is also expiry order
+
+	hits atomic.Int64
+}
+
+// dedupKey identifies a submission: the same content from the same org with the same client timestamp
+type dedupKey struct {
+	orgID           string
+	logHash         string
+	clientTimestamp int64 // Unix nanoseconds; 0 if none
+}
+
+type dedupSlot struct {
+	key     dedupKey
+	expires time.Time
+}
+
+// DedupStats is a snapshot of the duplicate window
+type DedupStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+}
+
+// NewDedupCache creates a new DedupCache
+func NewDedupCache(ttl time.Duration, maxEntries int) *DedupCache {
+	return &DedupCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[dedupKey]*LogResult),
+	}
+}
+
+// Lookup returns the result of a matching submission accepted within the TTL
+func (c *DedupCache) Lookup(input *LogInput, now time.Time) (*LogResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict(now)
+	result, ok := c.entries[newDedupKey(input)]
+	if ok {
+		c.hits.Add(1)
+	}
+	return result, ok
+}
+
+// Remember records an accepted submission. If a matching one was recorded
+// concurrently, that result is returned and result is not stored.
+func (c *DedupCache) Remember(input *LogInput, result *LogResult, now time.Time) (*LogResult, bool) {
+	key := newDedupKey(input)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict(now)
+	if existing, ok := c.entries[key]; ok {
+		c.hits.Add(1)
+		return existing, true
+	}
+	c.entries[key] = result
+	c.order = append(c.order, dedupSlot{key: key, expires: now.Add(c.ttl)})
+	for len(c.entries) > c.maxEntries {
+		c.evictOldest()
+	}
+	return result, false
+}
+
+// Stats returns a snapshot of the duplicate window
+func (c *DedupCache) Stats() DedupStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return DedupStats{Entries: len(c.entries), Hits: c.hits.Load()}
+}
+
+// evict drops expired entries; the caller holds mu
+func (c *DedupCache) evict(now time.Time) {
+	for len(c.order) > 0 && !now.Before(c.order[0].expires) {
+		c.evictOldest()
+	}
+}
+
+func (c *DedupCache) evictOldest() {
+	delete(c.entries, c.order[0].key)
+	c.order[0] = dedupSlot{}
+	c.order = c.order[1:]
+}
+
+func newDedupKey(input *LogInput) dedupKey {
+	key := dedupKey{orgID: input.ClientSourceOrgID, logHash: input.ClientLogHash}
+	if input.ClientTimestamp != nil {
+		key.clientTimestamp = input.ClientTimestamp.UnixNano()
+	}
+	return key
+}
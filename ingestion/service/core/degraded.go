@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// ErrQueueUnavailable indicates that a submission was rejected because Kafka
+// publishing is failing and the degraded acceptance policy is "reject"
+var ErrQueueUnavailable = errors.New("message queue unavailable, submissions are temporarily rejected")
+
+// DegradedError is returned for submissions rejected while the gateway is degraded
+type DegradedError struct {
+	RetryAfter time.Duration // Until Kafka is tried again
+}
+
+func (e *DegradedError) Error() string { return ErrQueueUnavailable.Error() }
+
+func (e *DegradedError) Unwrap() error { return ErrQueueUnavailable }
+
+// publishBreaker tracks consecutive failed Kafka publishes. After threshold
+// failures it opens for cooldown, during which the gateway is degraded; the
+// first publish after that probes Kafka again.
+type publishBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trips     int64
+}
+
+// DegradedStats is a snapshot of the degraded acceptance state
+type DegradedStats struct {
+	Policy   string `json:"policy"`
+	Degraded bool   `json:"degraded"`
+	Trips    int64  `json:"trips"` // Times the gateway became degraded
+}
+
+func newPublishBreaker(threshold int, cooldown time.Duration) *publishBreaker {
+	return &publishBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// open reports whether the gateway is degraded, and for how much longer
+func (b *publishBreaker) open(now time.Time) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Before(b.openUntil) {
+		return true, b.openUntil.Sub(now)
+	}
+	return false, 0
+}
+
+// failure records a failed publish and reports whether it degraded the gateway
+func (b *publishBreaker) failure(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures < b.threshold || now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	b.trips++
+	return true
+}
+
+// state reports whether the gateway is degraded and how often it became so
+func (b *publishBreaker) state(now time.Time) (bool, int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.openUntil), b.trips
+}
+
+// success records a successful publish, ending degraded mode
+func (b *publishBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// degradedAcceptance is the policy shared by the service's batch processors
+type degradedAcceptance struct {
+	cfg     config.DegradedAcceptanceConfig
+	breaker *publishBreaker
+}
+
+// spools reports whether failed or skipped publishes go to the local queue
+func (d *degradedAcceptance) spools() bool {
+	return d != nil && d.cfg.Policy == config.DegradedPolicySpool
+}
+
+// SetDegradedAcceptance applies a degraded acceptance policy to the batch
+// processors. It must be called after EnableSizeTier.
+func (s *Service) SetDegradedAcceptance(cfg config.DegradedAcceptanceConfig) {
+	s.degraded = &degradedAcceptance{cfg: cfg, breaker: newPublishBreaker(cfg.FailureThreshold, cfg.Cooldown)}
+	for _, bp := range s.processors() {
+		bp.degraded = s.degraded
+	}
+}
+
+// checkDegraded rejects submissions while the gateway is degraded under the "reject" policy
+func (s *Service) checkDegraded() error {
+	if s.degraded == nil || s.degraded.cfg.Policy != config.DegradedPolicyReject {
+		return nil
+	}
+	if open, remaining := s.degraded.breaker.open(s.clock.Now()); open {
+		return &DegradedError{RetryAfter: remaining}
+	}
+	return nil
+}
+
+// DegradedStats returns the degraded acceptance state, if a policy is set
+func (s *Service) DegradedStats() (DegradedStats, bool) {
+	if s.degraded == nil {
+		return DegradedStats{}, false
+	}
+	open, trips := s.degraded.breaker.state(s.clock.Now())
+	return DegradedStats{Policy: s.degraded.cfg.Policy, Degraded: open, Trips: trips}, true
+}
+
+// RunRelay publishes messages spooled to the local queue until ctx is done.
+// It returns immediately unless the policy is "spool".
+func (s *Service) RunRelay(ctx context.Context) {
+	if !s.degraded.spools() {
+		return
+	}
+	ticker := time.NewTicker(s.degraded.cfg.RelayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, bp := range s.processors() {
+				bp.relay(ctx)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// queueLocal spools messages whose publish failed or was skipped (cause) to
+// the local queue. entries are aligned with messages; those that cannot be
+// queued either end as publish failures.
+func (bp *BatchProcessor) queueLocal(batchID string, entries []*batchEntry, messages []*models.LogMessage, cause error) {
+	local := make([]store.LocalMessage, 0, len(messages))
+	queued := make([]*batchEntry, 0, len(entries))
+	for i, msg := range messages {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			bp.logger.Printf("Failed to encode message %s for the local queue: %v", msg.RequestID, err)
+			bp.settle(batchID, entries[i:i+1], OutcomePublishFailed, err)
+			continue
+		}
+		local = append(local, store.LocalMessage{RequestID: msg.RequestID, Tier: bp.tier, Payload: payload})
+		queued = append(queued, entries[i])
+	}
+	if err := bp.store.QueueLocalMessages(bp.opCtx, local); err != nil {
+		bp.logger.Printf("Batch %s: failed to queue %d messages locally: %v", batchID, len(local), err)
+		bp.settle(batchID, queued, OutcomePublishFailed, errors.Join(cause, err))
+		return
+	}
+	bp.settle(batchID, queued, OutcomeQueuedLocal, cause)
+	bp.logger.Printf("Batch %s: queued %d messages locally (QUEUED_LOCAL) for the relay", batchID, len(local))
+}
+
+// relay publishes this processor's locally queued messages, batch by batch,
+// until the queue is empty or a publish fails
+func (bp *BatchProcessor) relay(ctx context.Context) {
+	cfg := bp.degraded.cfg
+	for ctx.Err() == nil {
+		claimed, err := bp.store.ClaimLocalMessages(ctx, bp.tier, cfg.RelayBatchSize, cfg.RelayLease)
+		if err != nil {
+			bp.logger.Printf("Relay: failed to claim locally queued messages: %v", err)
+			return
+		}
+		if len(claimed) == 0 {
+			return
+		}
+
+		requestIDs := make([]string, len(claimed))
+		messages := make([]*models.LogMessage, 0, len(claimed))
+		for i, m := range claimed {
+			requestIDs[i] = m.RequestID
+			var msg models.LogMessage
+			if err := json.Unmarshal(m.Payload, &msg); err != nil {
+				// Leave it leased; it is retried after the lease and keeps showing up in the logs
+				bp.logger.Printf("Relay: failed to decode locally queued message %s: %v", m.RequestID, err)
+				continue
+			}
+			messages = append(messages, &msg)
+		}
+
+		if err := bp.producer.PublishBatch(ctx, messages); err != nil {
+			bp.degraded.breaker.failure(bp.clock.Now())
+			bp.logger.Printf("Relay: failed to publish %d locally queued messages: %v", len(messages), err)
+			if err := bp.store.ReleaseLocalMessages(ctx, requestIDs, false); err != nil {
+				bp.logger.Printf("Relay: failed to return messages to the local queue, they are relayed after the lease: %v", err)
+			}
+			return
+		}
+		bp.degraded.breaker.success()
+		bp.stats.relayed.Add(int64(len(messages)))
+
+		published := make([]string, len(messages))
+		for i, msg := range messages {
+			published[i] = msg.RequestID
+		}
+		if err := bp.store.ReleaseLocalMessages(ctx, published, true); err != nil {
+			// They are published again after the lease; the engine skips tasks no longer RECEIVED
+			bp.logger.Printf("Relay: failed to remove %d relayed messages from the local queue: %v", len(published), err)
+			return
+		}
+		bp.logger.Printf("Relay: published %d locally queued messages", len(messages))
+		if len(claimed) < cfg.RelayBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"tlng/errcatalog"
+)
+
+// sentinelCodes maps the sentinel errors of rejected submissions to their catalog code
+var sentinelCodes = []struct {
+	err  error
+	code errcatalog.Code
+}{
+	{ErrEmptyLogContent, errcatalog.CodeLogContentEmpty},
+	{ErrInvalidClientTimestamp, errcatalog.CodeInvalidClientTimestamp},
+	{ErrClientTimestampSkew, errcatalog.CodeClientTimestampSkew},
+	{ErrInvalidIdempotencyKey, errcatalog.CodeInvalidIdempotencyKey},
+	{ErrInvalidLogField, errcatalog.CodeInvalidLogField},
+	{ErrInvalidExternalID, errcatalog.CodeInvalidExternalID},
+	{ErrExternalIDConflict, errcatalog.CodeExternalIDConflict},
+	{ErrExternalIDUnavailable, errcatalog.CodeExternalIDUnavailable},
+	{ErrTestTrafficNotAllowed, errcatalog.CodeTestTrafficNotAllowed},
+	{ErrOrgNotAllowed, errcatalog.CodeOrgNotAllowed},
+}
+
+// ErrorCode returns the catalog code of a Service layer error, the parameters
+// of its message, and the time after which the client may retry, 0 if not
+// applicable. Errors without a specific code are INTERNAL.
+func (s *Service) ErrorCode(err error) (errcatalog.Code, map[string]string, time.Duration) {
+	var maintenanceErr *MaintenanceError
+	var degradedErr *DegradedError
+	var shedErr *ShedError
+	var quotaErr *QuotaError
+	var sizeErr *RequestSizeError
+	var decompressedErr *DecompressedSizeError
+	var hashErr *HashMismatchError
+	switch {
+	case errors.As(err, &maintenanceErr):
+		retryAfter := maintenanceErr.State.RetryAfter
+		return errcatalog.CodeMaintenance, retryParams(retryAfter, errcatalog.ParamDetail, maintenanceErr.State.Message), retryAfter
+	case errors.As(err, &degradedErr):
+		return errcatalog.CodeQueueUnavailable, retryParams(degradedErr.RetryAfter), degradedErr.RetryAfter
+	case errors.As(err, &shedErr):
+		return errcatalog.CodeShed, retryParams(shedErr.RetryAfter, errcatalog.ParamPriority, shedErr.Priority), shedErr.RetryAfter
+	case errors.As(err, &quotaErr):
+		if errors.Is(quotaErr, ErrQuotaExceeded) {
+			limit := strconv.FormatInt(quotaErr.Status.QuotaLimit, 10)
+			return errcatalog.CodeQuotaExceeded, retryParams(quotaErr.RetryAfter(), errcatalog.ParamLimit, limit), quotaErr.RetryAfter()
+		}
+		return errcatalog.CodeRateLimited, retryParams(quotaErr.RetryAfter()), quotaErr.RetryAfter()
+	case errors.Is(err, ErrOverloaded):
+		return errcatalog.CodeOverloaded, retryParams(s.InFlightRetryAfter()), s.InFlightRetryAfter()
+	case errors.As(err, &sizeErr):
+		params := map[string]string{errcatalog.ParamLimit: strconv.FormatInt(sizeErr.Limit, 10)}
+		if sizeErr.Size > 0 {
+			params[errcatalog.ParamSize] = strconv.FormatInt(sizeErr.Size, 10)
+		}
+		return errcatalog.CodeRequestTooLarge, params, 0
+	case errors.As(err, &decompressedErr):
+		return errcatalog.CodeDecompressedTooLarge, map[string]string{errcatalog.ParamLimit: strconv.FormatInt(decompressedErr.Limit, 10)}, 0
+	case errors.As(err, &hashErr):
+		return errcatalog.CodeHashMismatch, map[string]string{
+			errcatalog.ParamClientHash: hashErr.ClientHash,
+			errcatalog.ParamServerHash: hashErr.ServerHash,
+		}, 0
+	}
+	for _, sc := range sentinelCodes {
+		if errors.Is(err, sc.err) {
+			return sc.code, detailParams(err, sc.err), 0
+		}
+	}
+	return errcatalog.CodeInternal, nil, 0
+}
+
+// retryParams returns the retry_after_seconds parameter, with more name/value pairs
+func retryParams(retryAfter time.Duration, pairs ...string) map[string]string {
+	params := map[string]string{errcatalog.ParamRetryAfterSeconds: strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			params[pairs[i]] = pairs[i+1]
+		}
+	}
+	return params
+}
+
+// detailParams returns the detail an error wrapping sentinel adds to it, if any
+func detailParams(err, sentinel error) map[string]string {
+	detail, ok := strings.CutPrefix(err.Error(), sentinel.Error()+": ")
+	if !ok {
+		return nil
+	}
+	return map[string]string{errcatalog.ParamDetail: detail}
+}
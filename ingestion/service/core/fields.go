@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+
+	"tlng/internal/models"
+)
+
+// Bounds of the structured log fields
+const (
+	maxSourceHostLength  = 255
+	maxApplicationLength = 128
+)
+
+// ErrInvalidLogField indicates an unknown severity, a source host or
+// application that is too long or contains control characters, or a negative
+// sequence
+var ErrInvalidLogField = errors.New("invalid log field")
+
+// validateLogFields checks the optional structured fields of a submission and
+// puts the severity in its canonical form
+func validateLogFields(input *LogInput) error {
+	severity, err := models.ParseSeverity(input.Severity)
+	if err != nil {
+		return fmt.Errorf("%w: severity: %v", ErrInvalidLogField, err)
+	}
+	input.Severity = severity
+	if input.Sequence < 0 {
+		return fmt.Errorf("%w: sequence must not be negative", ErrInvalidLogField)
+	}
+	if err := validateFieldText("source_host", input.SourceHost, maxSourceHostLength); err != nil {
+		return err
+	}
+	return validateFieldText("application", input.Application, maxApplicationLength)
+}
+
+// validateFieldText checks a free-text field against its length bound
+func validateFieldText(name, value string, maxLength int) error {
+	if len(value) > maxLength {
+		return fmt.Errorf("%w: %s longer than %d characters", ErrInvalidLogField, name, maxLength)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) || r == unicode.ReplacementChar {
+			return fmt.Errorf("%w: %s contains control or invalid characters", ErrInvalidLogField, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/buildinfo"
+	"tlng/internal/fingerprint"
+	"tlng/internal/models"
+)
+
+// FingerprintRecordType is the type of the records anchoring a configuration fingerprint
+const FingerprintRecordType = "config_fingerprint"
+
+// FingerprintRecord is the log content submitted to anchor the gateway's
+// configuration fingerprint. Auditors recompute config_sha256 from the
+// sanitized configuration returned by GET /admin/config.
+type FingerprintRecord struct {
+	Type         string `json:"type"`
+	Service      string `json:"service"`
+	Instance     string `json:"instance"`
+	Region       string `json:"region,omitempty"`
+	Version      string `json:"version"`
+	ConfigSHA256 string `json:"config_sha256"`
+	AnchoredAt   string `json:"anchored_at"`
+}
+
+// AnchorState describes the last anchoring of the configuration fingerprint
+type AnchorState struct {
+	RequestID  string
+	LogHash    string
+	AnchoredAt time.Time
+	Err        error // Set if the last attempt was rejected
+}
+
+// SetConfigFingerprint records the fingerprint of the configuration the gateway
+// runs with and the checksums of the files it was loaded from
+func (s *Service) SetConfigFingerprint(fp *fingerprint.Fingerprint, files []fingerprint.File) {
+	s.fingerprint = fp
+	s.configFiles = files
+}
+
+// ConfigFiles returns the checksums of the configuration files
+func (s *Service) ConfigFiles() []fingerprint.File {
+	return s.configFiles
+}
+
+// ConfigFingerprint returns the configuration fingerprint (nil if none was
+// set), the binary version, and the last anchoring (nil if none happened)
+func (s *Service) ConfigFingerprint() (*fingerprint.Fingerprint, string, *AnchorState) {
+	return s.fingerprint, buildinfo.Version(), s.lastAnchor.Load()
+}
+
+// RunConfigAnchoring submits the fingerprint record under cfg.OrgID now and
+// every cfg.Interval until ctx is done. A rejected submission is retried at
+// the next interval.
+func (s *Service) RunConfigAnchoring(ctx context.Context, cfg config.ConfigFingerprintConfig) {
+	if s.fingerprint == nil {
+		return
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		s.anchorFingerprint(ctx, cfg)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// anchorFingerprint submits one fingerprint record
+func (s *Service) anchorFingerprint(ctx context.Context, cfg config.ConfigFingerprintConfig) {
+	hostname, _ := os.Hostname()
+	now := time.Now().UTC()
+	content, err := json.Marshal(FingerprintRecord{
+		Type:         FingerprintRecordType,
+		Service:      "ingestion",
+		Instance:     hostname,
+		Region:       s.region,
+		Version:      buildinfo.Version(),
+		ConfigSHA256: s.fingerprint.SHA256,
+		AnchoredAt:   now.Format(time.RFC3339),
+	})
+	if err != nil {
+		s.logger.Printf("Warning: failed to encode configuration fingerprint record: %v", err)
+		return
+	}
+	state := &AnchorState{AnchoredAt: now}
+	result, err := s.SubmitLog(ctx, &LogInput{
+		LogContent:        string(content),
+		ClientSourceOrgID: cfg.OrgID,
+		Severity:          models.SeverityInfo,
+		SourceHost:        hostname,
+		Application:       cfg.Application,
+	})
+	if err != nil {
+		state.Err = err
+		s.logger.Printf("Warning: anchoring configuration fingerprint %s failed: %v", s.fingerprint.SHA256, err)
+	} else {
+		state.RequestID, state.LogHash = result.RequestID, result.ServerLogHash
+		s.logger.Printf("Service: Submitted configuration fingerprint %s (version %s) for anchoring, request_id=%s",
+			s.fingerprint.SHA256, buildinfo.Version(), result.RequestID)
+	}
+	s.lastAnchor.Store(state)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/clock"
+	"tlng/internal/messaging/producer"
+)
+
+// Reasons a flush trigger flushed a partial batch
+const (
+	flushReasonIdle     = "idle"     // Writer idle and enough entries buffered
+	flushReasonMemory   = "memory"   // Heap above the high watermark
+	flushReasonDeferred = "deferred" // Timer flush postponed under backpressure, delay elapsed
+)
+
+// heapObjectsMetric is the runtime metric compared with the memory high watermark
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// flushTrigger flushes a batch processor's buffer by queue depth, writer load
+// and memory pressure in addition to its size and timer flushes
+type flushTrigger struct {
+	cfg    config.FlushTriggerConfig
+	writer producer.LoadReporter // nil if the producer does not report its load
+
+	backpressured atomic.Bool   // Set while the timer flush is postponed
+	done          chan struct{} // Closed when run has stopped flushing
+}
+
+// SetFlushTrigger starts the flush trigger of the processor. It must be
+// called before Shutdown.
+func (bp *BatchProcessor) SetFlushTrigger(cfg config.FlushTriggerConfig) {
+	t := &flushTrigger{cfg: cfg, done: make(chan struct{})}
+	t.writer, _ = bp.producer.(producer.LoadReporter)
+	bp.trigger.Store(t)
+	go bp.runFlushTrigger(t)
+}
+
+// SetFlushTrigger starts the flush trigger of the batch processors. It must be
+// called after EnableSizeTier.
+func (s *Service) SetFlushTrigger(cfg config.FlushTriggerConfig) {
+	for _, bp := range s.processors() {
+		bp.SetFlushTrigger(cfg)
+	}
+}
+
+// runFlushTrigger checks the processor every check interval until shutdown
+func (bp *BatchProcessor) runFlushTrigger(t *flushTrigger) {
+	defer close(t.done)
+	ticker := bp.clock.NewTicker(t.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			bp.checkFlushTrigger(t)
+		case <-bp.ctx.Done():
+			return
+		}
+	}
+}
+
+// checkFlushTrigger flushes the buffer early under memory pressure or while
+// the writer is idle, and postpones the timer flush while batches back up
+func (bp *BatchProcessor) checkFlushTrigger(t *flushTrigger) {
+	bp.bufferMutex.Lock()
+	buffered := len(bp.buffer)
+	bp.bufferMutex.Unlock()
+	if buffered == 0 {
+		t.backpressured.Store(false)
+		return
+	}
+
+	var load producer.WriterLoad
+	if t.writer != nil {
+		load = t.writer.WriterLoad()
+	}
+	queued := len(bp.flushChan) + int(bp.stats.inFlightBatches.Load())
+	backpressured := queued >= t.cfg.BackpressureQueueDepth || load.RetryBufferLen > 0
+	t.backpressured.Store(backpressured)
+
+	switch {
+	case t.cfg.MemoryHighWatermark > 0 && heapInUse() >= uint64(t.cfg.MemoryHighWatermark):
+		bp.triggerFlush(flushReasonMemory)
+	case backpressured:
+		if clock.Since(bp.clock, bp.lastFlush()) >= bp.batchTimeout+t.cfg.BackpressureDelay {
+			bp.triggerFlush(flushReasonDeferred)
+		}
+	case queued == 0 && load.InFlight == 0 && buffered >= t.cfg.IdleMinEntries:
+		bp.triggerFlush(flushReasonIdle)
+	}
+}
+
+// triggerFlush flushes the buffer and counts the flush under reason
+func (bp *BatchProcessor) triggerFlush(reason string) {
+	if bp.flushIfNeeded() {
+		triggeredFlushes.Inc(reason)
+	}
+}
+
+// timerFlushPostponed reports whether the flush trigger holds back the timer
+// flush because batches are backing up
+func (bp *BatchProcessor) timerFlushPostponed() bool {
+	t := bp.trigger.Load()
+	return t != nil && t.backpressured.Load()
+}
+
+// lastFlush returns when a batch was last queued for processing
+func (bp *BatchProcessor) lastFlush() time.Time {
+	return time.Unix(0, bp.lastFlushAt.Load())
+}
+
+// markFlushed records that a batch was queued for processing
+func (bp *BatchProcessor) markFlushed() {
+	bp.lastFlushAt.Store(bp.clock.Now().UnixNano())
+}
+
+// heapInUse returns the bytes of heap memory occupied by live and unswept objects
+func heapInUse() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/metrics"
+)
+
+var (
+	sequenceGaps = metrics.NewCounter("gateway_sequence_gaps_total",
+		"Gaps recorded in sequenced sources.")
+	sequenceMissing = metrics.NewCounter("gateway_sequence_missing_total",
+		"Sequence numbers missing from sequenced sources.")
+)
+
+// SetGapDetection enables the recording of gaps in sequenced sources
+func (s *Service) SetGapDetection(cfg config.GapDetectionConfig) {
+	s.gapDetection = &cfg
+}
+
+// RunGapDetection records the numbers missing from sequenced sources every
+// interval until ctx is done. The first pass checks the sources with
+// submissions received shortly before the start; a source skipped while no
+// gateway was running is checked with its next submission. It returns
+// immediately if gap detection is disabled.
+func (s *Service) RunGapDetection(ctx context.Context) {
+	if s.gapDetection == nil {
+		return
+	}
+	cfg := s.gapDetection
+	after := s.clock.Now().Add(-cfg.Grace - cfg.Interval)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			gaps, until, err := s.store.DetectSequenceGaps(ctx, after, cfg.Grace)
+			if err != nil {
+				s.logger.Printf("Warning: failed to detect sequence gaps: %v", err)
+				continue
+			}
+			after = until
+			for _, g := range gaps {
+				sequenceGaps.Inc()
+				sequenceMissing.Add(float64(g.Missing()))
+				s.logger.Printf("Warning: sequence gap in org %s (source_host '%s', application '%s'): %d missing (%d-%d)",
+					g.OrgID, g.SourceHost, g.Application, g.Missing(), g.FirstMissing, g.LastMissing)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
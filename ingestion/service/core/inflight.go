@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOverloaded indicates that a submission was rejected because the gateway
+// already holds as many submissions as it is configured to
+var ErrOverloaded = errors.New("gateway is overloaded, too many submissions in flight")
+
+// InFlightLimiter bounds the submissions processed at once. It is a semaphore
+// with a bounded wait queue: a submission that finds no free slot waits up to
+// the queue timeout, and is rejected outright if the queue is full.
+type InFlightLimiter struct {
+	slots        chan struct{}
+	maxQueued    int64
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+
+	queued   atomic.Int64
+	rejected atomic.Int64
+}
+
+// InFlightStats is a snapshot of the in-flight limiter
+type InFlightStats struct {
+	InFlight    int   `json:"in_flight"`
+	MaxRequests int   `json:"max_requests"`
+	Queued      int64 `json:"queued"`
+	Rejected    int64 `json:"rejected"`
+}
+
+// NewInFlightLimiter creates a new InFlightLimiter
+func NewInFlightLimiter(maxRequests, maxQueued int, queueTimeout, retryAfter time.Duration) *InFlightLimiter {
+	return &InFlightLimiter{
+		slots:        make(chan struct{}, maxRequests),
+		maxQueued:    int64(maxQueued),
+		queueTimeout: queueTimeout,
+		retryAfter:   retryAfter,
+	}
+}
+
+// Acquire takes a slot, waiting up to the queue timeout for one. The returned
+// function releases the slot and must be called exactly once. It returns
+// ErrOverloaded if no slot became free, or the context's error if ctx ended first.
+func (l *InFlightLimiter) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	default:
+	}
+
+	if l.queueTimeout <= 0 || l.queued.Add(1) > l.maxQueued {
+		if l.queueTimeout > 0 {
+			l.queued.Add(-1)
+		}
+		l.rejected.Add(1)
+		return nil, ErrOverloaded
+	}
+	defer l.queued.Add(-1)
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return l.release, nil
+	case <-timer.C:
+		l.rejected.Add(1)
+		return nil, ErrOverloaded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *InFlightLimiter) release() {
+	<-l.slots
+}
+
+// RetryAfter is how long rejected clients should wait before retrying
+func (l *InFlightLimiter) RetryAfter() time.Duration {
+	return l.retryAfter
+}
+
+// Stats returns a snapshot of the limiter
+func (l *InFlightLimiter) Stats() InFlightStats {
+	return InFlightStats{
+		InFlight:    len(l.slots),
+		MaxRequests: cap(l.slots),
+		Queued:      l.queued.Load(),
+		Rejected:    l.rejected.Load(),
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrMaintenance indicates that writes are rejected because the gateway is in maintenance mode
+var ErrMaintenance = errors.New("gateway is in maintenance mode")
+
+// MaintenanceState describes the gateway's maintenance mode
+type MaintenanceState struct {
+	Enabled    bool
+	RetryAfter time.Duration // How long clients should wait before retrying
+	Message    string        // Optional reason shown to clients
+	Since      time.Time     // When maintenance mode was last changed
+}
+
+// MaintenanceError is returned for submissions rejected in maintenance mode
+type MaintenanceError struct {
+	State MaintenanceState
+}
+
+func (e *MaintenanceError) Error() string {
+	if e.State.Message != "" {
+		return ErrMaintenance.Error() + ": " + e.State.Message
+	}
+	return ErrMaintenance.Error()
+}
+
+func (e *MaintenanceError) Unwrap() error { return ErrMaintenance }
+
+// SetMaintenance switches maintenance mode. While enabled, SubmitLog rejects
+// every submission with a *MaintenanceError; queries are served elsewhere and
+// are not affected.
+func (s *Service) SetMaintenance(state MaintenanceState) {
+	state.Since = time.Now()
+	s.maintenance.Store(&state)
+	if state.Enabled {
+		s.logger.Printf("Service: Maintenance mode enabled, rejecting writes (retry_after=%v, message=%q)", state.RetryAfter, state.Message)
+	} else {
+		s.logger.Println("Service: Maintenance mode disabled, accepting writes")
+	}
+}
+
+// Maintenance returns the current maintenance state
+func (s *Service) Maintenance() MaintenanceState {
+	if state := s.maintenance.Load(); state != nil {
+		return *state
+	}
+	return MaintenanceState{}
+}
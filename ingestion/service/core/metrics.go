@@ -0,0 +1,25 @@
+package service
+
+import "tlng/internal/metrics"
+
+// Prometheus metrics of the batch processor, served on the gateway's metrics_path
+var (
+	logsSubmitted = metrics.NewCounter("gateway_logs_submitted_total",
+		"Submitted logs by the outcome of their batch (published, duplicate, queued_local, outboxed, publish_failed, spooled, insert_failed).", "outcome")
+	submitDuration = metrics.NewHistogram("gateway_submit_duration_seconds",
+		"Time to handle a submission, until its batch is persisted and published or it is rejected.", metrics.LatencyBuckets)
+	flushedBatchSize = metrics.NewHistogram("gateway_batch_size",
+		"Logs per flushed batch.", metrics.SizeBuckets)
+	dbInsertDuration = metrics.NewHistogram("gateway_db_insert_duration_seconds",
+		"Time to insert a batch into the State DB.", metrics.LatencyBuckets)
+	dbInsertFailures = metrics.NewCounter("gateway_db_insert_failures_total",
+		"Batches whose State DB insert failed.")
+	kafkaPublishDuration = metrics.NewHistogram("gateway_kafka_publish_duration_seconds",
+		"Time to publish a batch to Kafka.", metrics.LatencyBuckets)
+	kafkaPublishFailures = metrics.NewCounter("gateway_kafka_publish_failures_total",
+		"Batches whose Kafka publish failed.")
+	trackLogsSubmitted = metrics.NewCounter("gateway_track_logs_submitted_total",
+		"Submitted logs by deployment track (stable, canary) and batch outcome, while a canary is configured.", "track", "outcome")
+	triggeredFlushes = metrics.NewCounter("gateway_triggered_flushes_total",
+		"Partial batches flushed by the flush trigger, by reason (idle, memory, deferred).", "reason")
+)
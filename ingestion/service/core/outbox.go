@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// SetOutbox writes the batch processors' messages to the outbox in the
+// transaction that stores their submissions. It must be called after
+// EnableSizeTier.
+func (s *Service) SetOutbox(cfg config.OutboxConfig) {
+	s.outbox = &cfg
+	for _, bp := range s.processors() {
+		bp.outbox = s.outbox
+	}
+}
+
+// RunOutboxRelay publishes the outbox messages left unsent and purges sent
+// ones until ctx is done. It returns immediately if the outbox is disabled.
+func (s *Service) RunOutboxRelay(ctx context.Context) {
+	if s.outbox == nil {
+		return
+	}
+	ticker := time.NewTicker(s.outbox.RelayInterval)
+	defer ticker.Stop()
+	purgeEvery := max(s.outbox.Retention/10, time.Minute)
+	var lastPurge time.Time
+	for {
+		select {
+		case <-ticker.C:
+			for _, bp := range s.processors() {
+				bp.relayOutbox(ctx)
+			}
+			if now := s.clock.Now(); s.outbox.Retention > 0 && now.Sub(lastPurge) >= purgeEvery {
+				lastPurge = now
+				n, err := s.store.PurgeOutbox(ctx, now.Add(-s.outbox.Retention))
+				if err != nil {
+					s.logger.Printf("Outbox: failed to purge sent messages: %v", err)
+				} else if n > 0 {
+					s.logger.Printf("Outbox: purged %d messages sent more than %v ago", n, s.outbox.Retention)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// outboxMessages encodes a batch's messages for the outbox; nil if the outbox
+// is disabled. A message that cannot be encoded is left out and only published
+// directly.
+func (bp *BatchProcessor) outboxMessages(messages []*models.LogMessage) []store.OutboxMessage {
+	if bp.outbox == nil {
+		return nil
+	}
+	outbox := make([]store.OutboxMessage, 0, len(messages))
+	for _, msg := range messages {
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			bp.logger.Printf("Failed to encode message %s for the outbox, it is only published directly: %v", msg.RequestID, err)
+			continue
+		}
+		outbox = append(outbox, store.OutboxMessage{RequestID: msg.RequestID, Tier: bp.tier, Payload: payload})
+	}
+	return outbox
+}
+
+// markOutboxSent records that a batch's messages were published. If that
+// fails they are published again after the lease, which the engine tolerates.
+func (bp *BatchProcessor) markOutboxSent(ctx context.Context, batchID string, messages []*models.LogMessage) {
+	if err := bp.store.MarkOutboxSent(ctx, messageIDs(messages)); err != nil {
+		bp.logger.Printf("Batch %s: failed to mark %d outbox messages as sent, they are relayed again after %v: %v",
+			batchID, len(messages), bp.outbox.Lease, err)
+	}
+}
+
+// releaseOutbox hands the messages of a batch whose publish failed to the
+// outbox relay. entries are aligned with messages.
+func (bp *BatchProcessor) releaseOutbox(batchID string, entries []*batchEntry, messages []*models.LogMessage, cause error) {
+	if err := bp.store.ReleaseOutboxMessages(bp.opCtx, messageIDs(messages)); err != nil {
+		bp.logger.Printf("Batch %s: failed to release %d outbox messages, they are relayed after %v: %v",
+			batchID, len(messages), bp.outbox.Lease, err)
+	}
+	bp.settle(batchID, entries, OutcomeOutboxed, cause)
+	bp.logger.Printf("Batch %s: left %d messages in the outbox for the relay", batchID, len(messages))
+}
+
+// relayOutbox publishes this processor's unsent outbox messages, batch by
+// batch, until none are left or a publish fails
+func (bp *BatchProcessor) relayOutbox(ctx context.Context) {
+	cfg := bp.outbox
+	for ctx.Err() == nil {
+		claimed, err := bp.store.ClaimOutboxMessages(ctx, bp.tier, cfg.RelayBatchSize, cfg.Lease)
+		if err != nil {
+			bp.logger.Printf("Outbox relay: failed to claim unsent messages: %v", err)
+			return
+		}
+		if len(claimed) == 0 {
+			return
+		}
+
+		messages := make([]*models.LogMessage, 0, len(claimed))
+		for _, m := range claimed {
+			var msg models.LogMessage
+			if err := json.Unmarshal(m.Payload, &msg); err != nil {
+				// Leave it claimed; it is retried after the lease and keeps showing up in the logs
+				bp.logger.Printf("Outbox relay: failed to decode message %s: %v", m.RequestID, err)
+				continue
+			}
+			messages = append(messages, &msg)
+		}
+
+		if err := bp.producer.PublishBatch(ctx, messages); err != nil {
+			if bp.degraded != nil {
+				bp.degraded.breaker.failure(bp.clock.Now())
+			}
+			bp.logger.Printf("Outbox relay: failed to publish %d messages: %v", len(messages), err)
+			if err := bp.store.ReleaseOutboxMessages(ctx, messageIDs(messages)); err != nil {
+				bp.logger.Printf("Outbox relay: failed to release messages, they are relayed after the lease: %v", err)
+			}
+			return
+		}
+		if bp.degraded != nil {
+			bp.degraded.breaker.success()
+		}
+		bp.stats.relayed.Add(int64(len(messages)))
+
+		if err := bp.store.MarkOutboxSent(ctx, messageIDs(messages)); err != nil {
+			// They are published again after the lease; the engine skips tasks no longer RECEIVED
+			bp.logger.Printf("Outbox relay: failed to mark %d published messages as sent: %v", len(messages), err)
+			return
+		}
+		bp.logger.Printf("Outbox relay: published %d unsent messages", len(messages))
+		if len(claimed) < cfg.RelayBatchSize {
+			return
+		}
+	}
+}
+
+// messageIDs returns the request IDs of messages
+func messageIDs(messages []*models.LogMessage) []string {
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.RequestID
+	}
+	return ids
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"tlng/config"
+	"tlng/storage/store"
+)
+
+// Quota errors, wrapped in a *QuotaError
+var (
+	ErrRateLimited   = errors.New("rate limit exceeded")
+	ErrQuotaExceeded = errors.New("monthly quota exceeded")
+)
+
+// QuotaStatus is an org's rate-limit and quota state after a submission attempt
+type QuotaStatus struct {
+	RateLimit     int           // Submissions allowed per window; 0 if unlimited
+	RateRemaining int           // Submissions left in the current window
+	RateReset     time.Duration // Time until the current window ends
+	QuotaLimit    int64         // Submissions allowed this month; 0 if unlimited
+	QuotaUsed     int64         // Submissions accepted this month
+	QuotaReset    time.Duration // Time until the quota period ends
+}
+
+// Headers returns the rate-limit and quota response headers. Reset values are
+// seconds from now.
+func (st QuotaStatus) Headers() map[string]string {
+	headers := map[string]string{
+		"X-Quota-Used": strconv.FormatInt(st.QuotaUsed, 10),
+	}
+	if st.RateLimit > 0 {
+		headers["X-RateLimit-Limit"] = strconv.Itoa(st.RateLimit)
+		headers["X-RateLimit-Remaining"] = strconv.Itoa(st.RateRemaining)
+		headers["X-RateLimit-Reset"] = strconv.Itoa(ceilSeconds(st.RateReset))
+	}
+	if st.QuotaLimit > 0 {
+		headers["X-Quota-Limit"] = strconv.FormatInt(st.QuotaLimit, 10)
+		headers["X-Quota-Remaining"] = strconv.FormatInt(max(st.QuotaLimit-st.QuotaUsed, 0), 10)
+		headers["X-Quota-Reset"] = strconv.Itoa(ceilSeconds(st.QuotaReset))
+	}
+	return headers
+}
+
+// QuotaError is returned when a submission exceeds its org's rate limit or monthly quota
+type QuotaError struct {
+	Err    error // ErrRateLimited or ErrQuotaExceeded
+	Status QuotaStatus
+}
+
+func (e *QuotaError) Error() string { return e.Err.Error() }
+
+func (e *QuotaError) Unwrap() error { return e.Err }
+
+// RetryAfter returns how long the client should wait before submitting again
+func (e *QuotaError) RetryAfter() time.Duration {
+	if errors.Is(e.Err, ErrQuotaExceeded) {
+		return e.Status.QuotaReset
+	}
+	return e.Status.RateReset
+}
+
+// QuotaTracker enforces per-org rate limits and monthly quotas. Rate limits are
+// counted in memory per gateway instance. Monthly usage is counted locally and
+// added to the State DB every sync interval, which also refreshes the totals
+// of other gateways, so quotas are enforced to within one sync interval.
+type QuotaTracker struct {
+	cfg    config.QuotaConfig
+	store  store.Store
+	logger *log.Logger
+
+	mu      sync.Mutex
+	period  string               // Current accounting period ("2006-01")
+	orgs    map[string]*orgQuota // Orgs seen in the current period
+	pending map[usageKey]int64   // Accepted submissions not yet added to the store
+}
+
+// orgQuota is the in-memory state of one org
+type orgQuota struct {
+	windowStart time.Time
+	windowCount int
+	stored      int64 // Period total as last read from the store
+}
+
+type usageKey struct {
+	period string
+	orgID  string
+}
+
+// NewQuotaTracker creates a new QuotaTracker
+func NewQuotaTracker(cfg config.QuotaConfig, s store.Store, logger *log.Logger) *QuotaTracker {
+	return &QuotaTracker{
+		cfg:     cfg,
+		store:   s,
+		logger:  logger,
+		orgs:    make(map[string]*orgQuota),
+		pending: make(map[usageKey]int64),
+	}
+}
+
+// Take charges one submission to the org, or returns a *QuotaError if the
+// org's rate limit or monthly quota is exhausted
+func (q *QuotaTracker) Take(orgID string, now time.Time) (QuotaStatus, error) {
+	return q.take(orgID, now, true)
+}
+
+// TakeTest counts one test submission against the org's rate limit only: it
+// is neither charged to nor refused by the monthly quota
+func (q *QuotaTracker) TakeTest(orgID string, now time.Time) (QuotaStatus, error) {
+	return q.take(orgID, now, false)
+}
+
+// take counts one submission against the org's rate limit and, if charge is
+// set, its monthly quota
+func (q *QuotaTracker) take(orgID string, now time.Time, charge bool) (QuotaStatus, error) {
+	rateLimit, monthlyQuota := q.cfg.LimitsFor(orgID)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	period := quotaPeriod(now)
+	if period != q.period {
+		q.period = period
+		q.orgs = make(map[string]*orgQuota)
+	}
+	org, ok := q.orgs[orgID]
+	if !ok {
+		org = &orgQuota{}
+		q.orgs[orgID] = org
+	}
+	if now.Sub(org.windowStart) >= q.cfg.RateWindow {
+		org.windowStart = now.Truncate(q.cfg.RateWindow)
+		org.windowCount = 0
+	}
+
+	key := usageKey{period: period, orgID: orgID}
+	st := QuotaStatus{
+		RateLimit:  rateLimit,
+		RateReset:  org.windowStart.Add(q.cfg.RateWindow).Sub(now),
+		QuotaLimit: monthlyQuota,
+		QuotaUsed:  org.stored + q.pending[key],
+		QuotaReset: nextQuotaPeriod(now).Sub(now),
+	}
+	if rateLimit > 0 {
+		st.RateRemaining = max(rateLimit-org.windowCount, 0)
+		if st.RateRemaining == 0 {
+			return st, &QuotaError{Err: ErrRateLimited, Status: st}
+		}
+	}
+	if charge && monthlyQuota > 0 && st.QuotaUsed >= monthlyQuota {
+		return st, &QuotaError{Err: ErrQuotaExceeded, Status: st}
+	}
+
+	org.windowCount++
+	if charge {
+		q.pending[key]++
+		st.QuotaUsed++
+	}
+	if rateLimit > 0 {
+		st.RateRemaining--
+	}
+	return st, nil
+}
+
+// Run syncs usage with the store every sync interval until ctx is cancelled
+func (q *QuotaTracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := q.Sync(ctx); err != nil && ctx.Err() == nil {
+				q.logger.Printf("Quota: Usage sync failed, retrying next interval: %v", err)
+			}
+		}
+	}
+}
+
+// Sync adds locally counted usage to the store and refreshes the totals of
+// all orgs seen in the current period
+func (q *QuotaTracker) Sync(ctx context.Context) error {
+	q.mu.Lock()
+	deltas := make([]store.OrgUsage, 0, len(q.pending)+len(q.orgs))
+	for key, n := range q.pending {
+		deltas = append(deltas, store.OrgUsage{Period: key.period, OrgID: key.orgID, Submissions: n})
+	}
+	// Zero deltas read the usage other gateways added for orgs with no local submissions
+	for orgID := range q.orgs {
+		if _, ok := q.pending[usageKey{period: q.period, orgID: orgID}]; !ok {
+			deltas = append(deltas, store.OrgUsage{Period: q.period, OrgID: orgID})
+		}
+	}
+	flushed := q.pending
+	q.pending = make(map[usageKey]int64)
+	q.mu.Unlock()
+
+	if len(deltas) == 0 {
+		return nil
+	}
+	totals, err := q.store.AddOrgUsage(ctx, deltas)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err != nil {
+		for key, n := range flushed {
+			q.pending[key] += n
+		}
+		return fmt.Errorf("failed to sync org usage: %w", err)
+	}
+	for _, u := range totals {
+		if u.Period != q.period {
+			continue
+		}
+		if org, ok := q.orgs[u.OrgID]; ok {
+			org.stored = u.Submissions
+		}
+	}
+	return nil
+}
+
+// quotaPeriod returns the monthly accounting period (UTC) containing t
+func quotaPeriod(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+// nextQuotaPeriod returns the start of the accounting period after t
+func nextQuotaPeriod(t time.Time) time.Time {
+	year, month, _ := t.UTC().Date()
+	return time.Date(year, month+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"tlng/config"
+)
+
+// ErrRequestTooLarge indicates that a request exceeds its org's size limit, wrapped in a *RequestSizeError
+var ErrRequestTooLarge = errors.New("request too large")
+
+// RequestSizeError is returned for requests larger than the limit of their org
+type RequestSizeError struct {
+	OrgID string // Empty if the request was rejected before its org was known
+	Tier  string // Empty for the default limit
+	Size  int64  // 0 if the request was cut off at the limit
+	Limit int64
+}
+
+func (e *RequestSizeError) Error() string {
+	msg := ErrRequestTooLarge.Error()
+	if e.Size > 0 {
+		msg += fmt.Sprintf(": %d bytes", e.Size)
+	}
+	msg += fmt.Sprintf(" exceed the limit of %d bytes", e.Limit)
+	switch {
+	case e.Tier != "":
+		msg += fmt.Sprintf(" of tier '%s' (org '%s')", e.Tier, e.OrgID)
+	case e.OrgID != "":
+		msg += fmt.Sprintf(" for org '%s'", e.OrgID)
+	}
+	return msg
+}
+
+func (e *RequestSizeError) Unwrap() error { return ErrRequestTooLarge }
+
+// DecompressedSizeError is returned for compressed HTTP request bodies larger
+// than the limit of their org once decompressed
+type DecompressedSizeError struct {
+	OrgID string // Empty if the request was rejected before its org was known
+	Tier  string // Empty for the default limit
+	Limit int64
+}
+
+func (e *DecompressedSizeError) Error() string {
+	msg := fmt.Sprintf("%s: decompressed body exceeds the limit of %d bytes", ErrRequestTooLarge, e.Limit)
+	switch {
+	case e.Tier != "":
+		msg += fmt.Sprintf(" of tier '%s' (org '%s')", e.Tier, e.OrgID)
+	case e.OrgID != "":
+		msg += fmt.Sprintf(" for org '%s'", e.OrgID)
+	}
+	return msg
+}
+
+func (e *DecompressedSizeError) Unwrap() error { return ErrRequestTooLarge }
+
+// SetRequestSizeLimits sets the request size limits of the transports
+func (s *Service) SetRequestSizeLimits(cfg config.RequestSizeConfig) {
+	s.requestSize = cfg
+}
+
+// HTTPBodyLimit returns the largest HTTP request body any org may send
+func (s *Service) HTTPBodyLimit() int64 {
+	return s.requestSize.Largest(s.requestSize.HTTPMaxBytes)
+}
+
+// HTTPBatchMaxEntries returns the most entries an HTTP batch submission may hold
+func (s *Service) HTTPBatchMaxEntries() int {
+	return s.requestSize.HTTPBatchMaxEntries
+}
+
+// GRPCStreamMaxEntries returns the most entries read from one gRPC submission stream
+func (s *Service) GRPCStreamMaxEntries() int {
+	return s.requestSize.GRPCStreamMaxEntries
+}
+
+// GRPCMessageLimit returns the largest gRPC request message any org may send
+func (s *Service) GRPCMessageLimit() int64 {
+	return s.requestSize.Largest(s.requestSize.GRPCMaxBytes)
+}
+
+// HTTPRequestLimit returns the HTTP request body limit of an org and the tier it comes from
+func (s *Service) HTTPRequestLimit(orgID string) (int64, string) {
+	return s.requestSize.LimitFor(orgID, s.requestSize.HTTPMaxBytes)
+}
+
+// CheckHTTPRequestSize rejects an HTTP request body of size bytes larger than the org's limit
+func (s *Service) CheckHTTPRequestSize(orgID string, size int64) error {
+	return s.checkRequestSize(orgID, size, s.requestSize.HTTPMaxBytes)
+}
+
+// CheckGRPCRequestSize rejects a gRPC request message of size bytes larger than the org's limit
+func (s *Service) CheckGRPCRequestSize(orgID string, size int64) error {
+	return s.checkRequestSize(orgID, size, s.requestSize.GRPCMaxBytes)
+}
+
+// checkRequestSize rejects a request larger than the org's limit; a limit of 0 is unlimited
+func (s *Service) checkRequestSize(orgID string, size, defaultLimit int64) error {
+	limit, tier := s.requestSize.LimitFor(orgID, defaultLimit)
+	if limit > 0 && size > limit {
+		return &RequestSizeError{OrgID: orgID, Tier: tier, Size: size, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/config"
+	"tlng/internal/models"
+	"tlng/storage/store"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSelfTestDisabled indicates that the self-test is not configured
+	ErrSelfTestDisabled = errors.New("self-test is not enabled")
+	// ErrSelfTestRunning indicates that another self-test is in progress
+	ErrSelfTestRunning = errors.New("a self-test is already running")
+)
+
+// SelfTestRecordType is the type of the synthetic logs submitted by the self-test
+const SelfTestRecordType = "selftest"
+
+// SelfTestRecord is the log content of a self-test submission
+type SelfTestRecord struct {
+	Type        string `json:"type"`
+	Nonce       string `json:"nonce"` // Makes every run's log, and so its hash, unique
+	Instance    string `json:"instance"`
+	Region      string `json:"region,omitempty"`
+	SubmittedAt string `json:"submitted_at"`
+}
+
+// Self-test stage results
+const (
+	StageOK      = "ok"
+	StageFailed  = "failed"
+	StageSkipped = "skipped" // An earlier stage failed
+)
+
+// SelfTestStage is the outcome of one stage of the self-test. Durations are
+// measured from the end of the previous stage, as observed by the gateway, so
+// the stages polling the State DB are accurate to the poll interval.
+type SelfTestStage struct {
+	Name     string
+	Status   string
+	Duration time.Duration
+	Detail   string
+}
+
+// SelfTestResult is the outcome of a self-test run
+type SelfTestResult struct {
+	Passed      bool
+	RequestID   string
+	LogHash     string
+	TxHash      string
+	BlockHeight int64
+	StartedAt   time.Time
+	Duration    time.Duration
+	Stages      []SelfTestStage
+}
+
+// selfTestStages are the stages of a run, in order:
+//   - submit: the gateway accepts the synthetic log
+//   - persist: the gateway's batch processor stores it in the State DB
+//   - process: the engine picks it up from Kafka
+//   - anchor: the engine records it as anchored on chain
+//   - verify: the log read back from the chain matches the submission
+var selfTestStages = []string{"submit", "persist", "process", "anchor", "verify"}
+
+// selfTest holds the self-test configuration and the chain client reading logs back
+type selfTest struct {
+	cfg     config.SelfTestConfig
+	chain   blockchain.BlockchainClient
+	running sync.Mutex
+}
+
+// SetSelfTest enables the end-to-end self-test, verifying on chain through chain
+func (s *Service) SetSelfTest(cfg config.SelfTestConfig, chain blockchain.BlockchainClient) {
+	s.selfTest = &selfTest{cfg: cfg, chain: chain}
+}
+
+// RunSelfTest submits a synthetic log tagged as a self-test, follows it
+// through the State DB until it is anchored, and reads it back from the chain.
+// A failed stage ends the run: the result then has Passed false and the
+// remaining stages skipped. Only one run is allowed at a time.
+func (s *Service) RunSelfTest(ctx context.Context) (*SelfTestResult, error) {
+	t := s.selfTest
+	if t == nil {
+		return nil, ErrSelfTestDisabled
+	}
+	if !t.running.TryLock() {
+		return nil, ErrSelfTestRunning
+	}
+	defer t.running.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.Timeout)
+	defer cancel()
+
+	result := &SelfTestResult{StartedAt: time.Now()}
+	last := result.StartedAt
+	var content string
+	var status *store.LogStatus
+	for _, name := range selfTestStages {
+		if len(result.Stages) > 0 && result.Stages[len(result.Stages)-1].Status != StageOK {
+			result.Stages = append(result.Stages, SelfTestStage{Name: name, Status: StageSkipped})
+			continue
+		}
+		var detail string
+		var err error
+		switch name {
+		case "submit":
+			content, err = s.submitSelfTest(ctx, t.cfg, result)
+		case "persist":
+			status, err = s.waitSelfTest(ctx, t.cfg, result.RequestID, func(*store.LogStatus) bool { return true })
+			if err == nil && status.GatewayBatchID != "" {
+				detail = "gateway batch " + status.GatewayBatchID
+			}
+		case "process":
+			status, err = s.waitSelfTest(ctx, t.cfg, result.RequestID, func(st *store.LogStatus) bool {
+				return st.ProcessingStartedAt != nil || st.Status == store.StatusCompleted || st.Status == store.StatusFailed
+			})
+			if err == nil && status.EngineBatchID != "" {
+				detail = "engine batch " + status.EngineBatchID
+			}
+		case "anchor":
+			status, err = s.waitSelfTest(ctx, t.cfg, result.RequestID, func(st *store.LogStatus) bool {
+				return st.Status == store.StatusCompleted || st.Status == store.StatusFailed
+			})
+			if err == nil && status.Status == store.StatusFailed {
+				err = fmt.Errorf("anchoring failed: %s", valueOf(status.ErrorMessage))
+			} else if err == nil {
+				result.TxHash = valueOf(status.TxHash)
+				if status.BlockHeight != nil {
+					result.BlockHeight = *status.BlockHeight
+				}
+				detail = fmt.Sprintf("tx %s at block %d", result.TxHash, result.BlockHeight)
+			}
+		case "verify":
+			err = verifySelfTest(ctx, t.chain, result.LogHash, content, t.cfg.OrgID)
+		}
+
+		now := time.Now()
+		stage := SelfTestStage{Name: name, Status: StageOK, Duration: now.Sub(last), Detail: detail}
+		if err != nil {
+			stage.Status, stage.Detail = StageFailed, err.Error()
+		}
+		result.Stages = append(result.Stages, stage)
+		last = now
+	}
+
+	result.Duration = time.Since(result.StartedAt)
+	result.Passed = result.Stages[len(result.Stages)-1].Status == StageOK
+	if result.Passed {
+		s.logger.Printf("Service: Self-test passed in %v, request_id=%s", result.Duration, result.RequestID)
+	} else {
+		s.logger.Printf("Warning: self-test failed after %v, request_id=%s", result.Duration, result.RequestID)
+	}
+	return result, nil
+}
+
+// submitSelfTest submits the synthetic log and returns its content
+func (s *Service) submitSelfTest(ctx context.Context, cfg config.SelfTestConfig, result *SelfTestResult) (string, error) {
+	hostname, _ := os.Hostname()
+	content, err := json.Marshal(SelfTestRecord{
+		Type:        SelfTestRecordType,
+		Nonce:       uuid.NewString(),
+		Instance:    hostname,
+		Region:      s.region,
+		SubmittedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode self-test record: %w", err)
+	}
+	submitted, err := s.SubmitLog(ctx, &LogInput{
+		LogContent:        string(content),
+		ClientSourceOrgID: cfg.OrgID,
+		Severity:          models.SeverityInfo,
+		SourceHost:        hostname,
+		Application:       cfg.Application,
+		TestTraffic:       s.testTrafficAllowed(cfg.OrgID),
+	})
+	if err != nil {
+		return "", err
+	}
+	result.RequestID, result.LogHash = submitted.RequestID, submitted.ServerLogHash
+	return string(content), nil
+}
+
+// waitSelfTest polls the State DB until the submission's status satisfies done
+func (s *Service) waitSelfTest(ctx context.Context, cfg config.SelfTestConfig, requestID string, done func(*store.LogStatus) bool) (*store.LogStatus, error) {
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := s.store.GetLogStatusByRequestID(ctx, requestID)
+		if err == nil && done(status) {
+			return status, nil
+		}
+		if err != nil && !errors.Is(err, store.ErrLogNotFound) && ctx.Err() == nil {
+			s.logger.Printf("Warning: self-test failed to read status of %s: %v", requestID, err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if status != nil {
+				return nil, fmt.Errorf("timed out with status %s: %w", status.Status, ctx.Err())
+			}
+			return nil, fmt.Errorf("timed out: %w", ctx.Err())
+		}
+	}
+}
+
+// verifySelfTest reads the log back from the chain and compares it with the submission
+func verifySelfTest(ctx context.Context, chain blockchain.BlockchainClient, logHash, content, orgID string) error {
+	raw, err := chain.FindLogByHash(ctx, logHash)
+	if err != nil {
+		return fmt.Errorf("failed to read log %s from chain: %w", logHash, err)
+	}
+	if raw == "" {
+		return fmt.Errorf("log %s not found on chain", logHash)
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return fmt.Errorf("failed to parse on-chain log %s: %w", logHash, err)
+	}
+	if values.Get("content") != content {
+		return fmt.Errorf("on-chain content of log %s does not match the submission", logHash)
+	}
+	if values.Get("org_id") != orgID {
+		return fmt.Errorf("on-chain org of log %s is %q, expected %q", logHash, values.Get("org_id"), orgID)
+	}
+	return nil
+}
+
+// valueOf returns the string s points to, or "" if s is nil
+func valueOf(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
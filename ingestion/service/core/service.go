@@ -3,22 +3,60 @@ package service
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"tlng/apitoken"
+	"tlng/config"
+	"tlng/internal/clock"
+	"tlng/internal/fingerprint"
+	"tlng/internal/idgen"
+	"tlng/internal/integrity"
 	"tlng/internal/messaging/producer"
+	"tlng/internal/tracing"
 	"tlng/storage/store"
 
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracer creates the spans of submissions and their batches
+var tracer = tracing.Tracer("tlng/ingestion")
+
 // LogInput defines the core information required for log submission
 type LogInput struct {
 	LogContent        string
 	ClientLogHash     string     // Optional
 	ClientSourceOrgID string     // Optional
 	ClientTimestamp   *time.Time // Optional
+	// ClientTimestampErr is set by the transport when a supplied timestamp could
+	// not be parsed; the timestamp policy decides whether to reject or ignore it
+	ClientTimestampErr error
+	IdempotencyKey     string // Optional; retries with the same key get the same request ID
+	// ExternalID is the client's own ID of the log, unique per org. Re-sending
+	// it returns the state of the submission first accepted under it.
+	ExternalID string
+	// Optional structured fields, stored with the submission for search and
+	// statistics; they are not part of the attested content
+	Severity    string // One of models.Severities, case-insensitive
+	SourceHost  string // Host that produced the log
+	Application string // Application that produced the log
+	// Sequence is the log's position in its source's sequence (org, source
+	// host and application), from 1; 0 if unsequenced. Skipped numbers are
+	// recorded as gaps by the gap detector.
+	Sequence int64
+	// TestTraffic flags a synthetic or test submission: it is anchored like any
+	// other but not charged to the monthly quota or counted in statistics
+	TestTraffic bool
+	// OnResult, if set, is called once the submission's batch has been
+	// processed, with whether it was stored and published. It is not called
+	// for rejected or duplicate submissions and must not block.
+	OnResult func(EntryResult)
+
+	traceContext map[string]string // Trace context of the submission, published in the message headers
 }
 
 // LogResult defines the return information after successful submission
@@ -26,6 +64,18 @@ type LogResult struct {
 	RequestID               string
 	ServerLogHash           string
 	ServerReceivedTimestamp time.Time
+	ClientTimestamp         *time.Time          // Client timestamp as accepted by the policy (possibly clamped); nil if none
+	Quota                   *QuotaStatus        // Org's rate-limit and quota state; nil if quotas are disabled or for duplicates
+	Duplicate               bool                // Retry of a submission accepted within the duplicate window; not enqueued again
+	Integrity               *integrity.Metadata // Hash algorithm and versions; nil unless integrity metadata is enabled
+	Existing                *Attestation        // State of the submission already stored under the external ID; nil otherwise
+}
+
+// Attestation is the stored state of a submission
+type Attestation struct {
+	Status      store.Status
+	TxHash      string // Empty until COMPLETED
+	BlockHeight int64  // 0 until COMPLETED
 }
 
 // Service encapsulates the core business logic of the API gateway
@@ -34,52 +84,328 @@ type Service struct {
 	producer       producer.Producer
 	logger         *log.Logger
 	batchProcessor *BatchProcessor
+	large          *BatchProcessor // Large submissions, nil if size tiers are disabled
+	largeThreshold int             // log_content size from which a submission is large
+	canary         *BatchProcessor // Canary track, nil unless a canary is configured
+	canaryRoute    canaryRoute     // Share of submissions routed to the canary
+	wal            *WAL
+	region         string // Region tag applied to submissions; empty in single-region deployments
+	idGen          idgen.Generator
+	clock          clock.Clock // Stamps submissions with their receive time
+
+	timestampPolicy config.TimestampPolicyConfig
+	quota           *QuotaTracker       // nil if quotas are disabled
+	dedup           *DedupCache         // nil if the duplicate window is disabled
+	inFlight        *InFlightLimiter    // nil if the in-flight limiter is disabled
+	degraded        *degradedAcceptance // nil if no degraded acceptance policy is set
+	shedder         *LoadShedder        // nil if load shedding is disabled
+	maintenance     atomic.Pointer[MaintenanceState]
+	fingerprint     *fingerprint.Fingerprint   // nil until SetConfigFingerprint
+	configFiles     []fingerprint.File         // Checksums of the configuration files, set with the fingerprint
+	integrity       *integrity.Metadata        // nil unless integrity metadata is enabled
+	requestSize     config.RequestSizeConfig   // Zero limits are unlimited
+	capturer        *DebugCapturer             // nil if debug capture is disabled
+	testTraffic     *config.TestTrafficConfig  // nil if test traffic is disabled
+	selfTest        *selfTest                  // nil if the self-test is disabled
+	apiTokens       *apitoken.Manager          // nil if API tokens are disabled
+	anomaly         *AnomalyDetector           // nil if anomaly detection is disabled
+	outbox          *config.OutboxConfig       // nil if the outbox is disabled
+	gapDetection    *config.GapDetectionConfig // nil if gap detection is disabled
+	lastAnchor      atomic.Pointer[AnchorState]
+
+	closeMu     sync.RWMutex   // Held for reading while a submission is accepted
+	closing     bool           // Set by Shutdown; later submissions are rejected
+	submissions sync.WaitGroup // Accepted submissions not yet handed to the batch processor
 }
 
 // NewService creates a new Service instance with configuration
-func NewService(s store.Store, p producer.Producer, l *log.Logger, batchSize int, batchTimeout time.Duration, flushChannelBuffer int) *Service {
+func NewService(s store.Store, p producer.Producer, l *log.Logger, batchSize int, batchTimeout time.Duration, flushChannelBuffer int, region string, idGen idgen.Generator, clk clock.Clock, conflictPolicy store.ConflictPolicy, timestampPolicy config.TimestampPolicyConfig) *Service {
 	return &Service{
 		store:          s,
 		producer:       p,
 		logger:         l,
-		batchProcessor: NewBatchProcessor(batchSize, batchTimeout, flushChannelBuffer, s, p, l),
+		batchProcessor: NewBatchProcessor(batchSize, batchTimeout, flushChannelBuffer, region, conflictPolicy, idGen, clk, s, p, l),
+		region:         region,
+		idGen:          idGen,
+		clock:          clk,
+
+		timestampPolicy: timestampPolicy,
+	}
+}
+
+// SetWAL spools batches the State DB rejects, and unflushed batches at
+// shutdown, to w for replay on the next start
+func (s *Service) SetWAL(w *WAL) {
+	s.wal = w
+	for _, bp := range s.processors() {
+		bp.SetWAL(w)
+	}
+}
+
+// ReplayWAL persists the entries spooled by a previous run, each through the
+// batch path for its size. Call it before the servers start.
+func (s *Service) ReplayWAL() (int, error) {
+	if s.wal == nil {
+		return 0, nil
+	}
+	entries, err := s.wal.Drain()
+	if err != nil {
+		return 0, err
 	}
+	var small, large, canary []*batchEntry
+	for _, e := range entries {
+		switch {
+		case s.isLarge(e.input):
+			large = append(large, e)
+		case s.isCanary(e.requestID, e.input.ClientSourceOrgID):
+			canary = append(canary, e)
+		default:
+			small = append(small, e)
+		}
+	}
+	s.batchProcessor.replay(small)
+	if s.large != nil {
+		s.large.replay(large)
+	}
+	if s.canary != nil {
+		s.canary.replay(canary)
+	}
+	return len(entries), s.wal.Commit()
+}
+
+// EnableSizeTier routes submissions whose log_content reaches threshold bytes
+// through a separate batch processor publishing with p, so large logs are
+// batched and consumed apart from small ones
+func (s *Service) EnableSizeTier(threshold int, p producer.Producer, batchSize int, batchTimeout time.Duration) {
+	bp := s.batchProcessor
+	s.large = NewBatchProcessor(batchSize, batchTimeout, cap(bp.flushChan), bp.region, bp.policy, s.idGen, s.clock, s.store, p, s.logger)
+	s.large.tier = TierLarge
+	s.largeThreshold = threshold
+	if s.wal != nil {
+		s.large.SetWAL(s.wal)
+	}
+}
+
+// isLarge reports whether a submission goes through the large-submission batch path
+func (s *Service) isLarge(input *LogInput) bool {
+	return s.large != nil && len(input.LogContent) >= s.largeThreshold
+}
+
+// processors returns the active batch processors
+func (s *Service) processors() []*BatchProcessor {
+	processors := []*BatchProcessor{s.batchProcessor}
+	if s.large != nil {
+		processors = append(processors, s.large)
+	}
+	if s.canary != nil {
+		processors = append(processors, s.canary)
+	}
+	return processors
+}
+
+// SetDedupCache enables the duplicate window for submissions without an idempotency key
+func (s *Service) SetDedupCache(c *DedupCache) {
+	s.dedup = c
 }
 
-// SubmitLog handles the core logic of log submission
+// DedupStats returns the duplicate window's state, if enabled
+func (s *Service) DedupStats() (DedupStats, bool) {
+	if s.dedup == nil {
+		return DedupStats{}, false
+	}
+	return s.dedup.Stats(), true
+}
+
+// SetInFlightLimiter bounds the submissions the transports hold at once
+func (s *Service) SetInFlightLimiter(l *InFlightLimiter) {
+	s.inFlight = l
+}
+
+// AcquireInFlight takes an in-flight slot for a submission. The transports
+// call it before reading the request, so that a rejected submission costs no
+// memory. Without a limiter it always succeeds.
+func (s *Service) AcquireInFlight(ctx context.Context) (func(), error) {
+	if s.inFlight == nil {
+		return func() {}, nil
+	}
+	return s.inFlight.Acquire(ctx)
+}
+
+// InFlightRetryAfter is how long clients rejected by the in-flight limiter should wait
+func (s *Service) InFlightRetryAfter() time.Duration {
+	if s.inFlight == nil {
+		return 0
+	}
+	return s.inFlight.RetryAfter()
+}
+
+// InFlightStats returns the in-flight limiter's state, if enabled
+func (s *Service) InFlightStats() (InFlightStats, bool) {
+	if s.inFlight == nil {
+		return InFlightStats{}, false
+	}
+	return s.inFlight.Stats(), true
+}
+
+// SetQuotaTracker enables per-org rate limits and monthly quotas
+func (s *Service) SetQuotaTracker(q *QuotaTracker) {
+	s.quota = q
+}
+
+// SetIntegrityMetadata makes accepted submissions report how their log hash
+// was computed and which gateway and schema version recorded them
+func (s *Service) SetIntegrityMetadata(m *integrity.Metadata) {
+	s.integrity = m
+}
+
+// SubmitLog handles the core logic of log submission, in a span whose trace
+// context travels with the message to the engine
 func (s *Service) SubmitLog(ctx context.Context, input *LogInput) (*LogResult, error) {
+	ctx, span := tracer.Start(ctx, "gateway.SubmitLog", trace.WithAttributes(tracing.AttrOrgID.String(input.ClientSourceOrgID)))
+	start := s.clock.Now()
+	result, err := s.submitLog(ctx, input)
+	submitDuration.ObserveDurationWithExemplar(clock.Since(s.clock, start), tracing.TraceID(ctx))
+	if err == nil {
+		span.SetAttributes(tracing.AttrRequestID.String(result.RequestID), tracing.AttrLogHash.String(result.ServerLogHash))
+		result.Integrity = s.integrity
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func (s *Service) submitLog(ctx context.Context, input *LogInput) (*LogResult, error) {
 	// Log function start time
 	// totalStart := time.Now()
 	// s.logger.Println("Service: Starting to process SubmitLog request...")
 
-	// 1. Validate input
+	// 1. Reject writes in maintenance mode or while Kafka is failing, then validate input
+	if state := s.maintenance.Load(); state != nil && state.Enabled {
+		return nil, &MaintenanceError{State: *state}
+	}
+	if err := s.checkDegraded(); err != nil {
+		return nil, err
+	}
+	if err := bindCallerOrg(ctx, input); err != nil {
+		return nil, err
+	}
+	if err := s.checkShedding(input.ClientSourceOrgID); err != nil {
+		return nil, err
+	}
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closing {
+		return nil, &MaintenanceError{State: MaintenanceState{Enabled: true, RetryAfter: time.Second, Message: "shutting down"}}
+	}
 	if input.LogContent == "" {
-		return nil, fmt.Errorf("log_content cannot be empty")
+		return nil, ErrEmptyLogContent
+	}
+	if err := validateIdempotencyKey(input.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if err := validateExternalID(input.ExternalID); err != nil {
+		return nil, err
+	}
+	if err := validateLogFields(input); err != nil {
+		return nil, err
+	}
+	if err := s.checkTestTraffic(input); err != nil {
+		return nil, err
 	}
 
-	// 2. Get received timestamp
-	receivedTimestamp := time.Now()
+	// 2. Get received timestamp and validate the client timestamp against it
+	receivedTimestamp := s.clock.Now()
+	if err := s.applyTimestampPolicy(input, receivedTimestamp); err != nil {
+		return nil, err
+	}
 
-	// 3. Calculate/validate hash
+	// 3. Calculate/validate hash (integrity.HashAlgorithm over the raw content)
 	serverLogHashBytes := sha256.Sum256([]byte(input.LogContent))
 	serverLogHash := fmt.Sprintf("%x", serverLogHashBytes)
 	if input.ClientLogHash != "" && input.ClientLogHash != serverLogHash {
-		return nil, fmt.Errorf("client provided hash '%s' does not match server calculated hash '%s'", input.ClientLogHash, serverLogHash)
+		return nil, &HashMismatchError{ClientHash: input.ClientLogHash, ServerHash: serverLogHash}
 	}
 	input.ClientLogHash = serverLogHash
 
-	// 4. Generate Request ID
-	requestID := uuid.NewString()
+	// A submission already stored under the external ID is returned as it stands, uncharged
+	if input.ExternalID != "" {
+		existing, err := s.existingSubmission(ctx, input, serverLogHash)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			s.observeSubmission(input, true)
+			return existing, nil
+		}
+	}
+
+	// Rapid retries of a recent submission get its result back without being charged or enqueued
+	dedup := s.dedup != nil && input.IdempotencyKey == "" && input.ExternalID == ""
+	if dedup {
+		if original, ok := s.dedup.Lookup(input, receivedTimestamp); ok {
+			s.observeSubmission(input, true)
+			return duplicateResult(original), nil
+		}
+	}
+
+	// 4. Charge the org's rate limit and monthly quota (test traffic only counts against the rate limit)
+	var quota *QuotaStatus
+	if s.quota != nil {
+		take := s.quota.Take
+		if input.TestTraffic {
+			take = s.quota.TakeTest
+		}
+		st, err := take(input.ClientSourceOrgID, receivedTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		quota = &st
+	}
 
-	// 5. Construct and return result immediately
+	// 5. Generate Request ID (region-prefixed so IDs never collide across active-active regions).
+	// With an idempotency key the ID is derived from it, so a retried submission
+	// conflicts with the original row and is neither stored nor published twice.
+	// An external ID does the same for every submission made under it.
+	var requestID string
+	if input.ExternalID != "" {
+		requestID = idgen.FromExternalID(input.ClientSourceOrgID, input.ExternalID)
+	} else if input.IdempotencyKey != "" {
+		requestID = idgen.FromIdempotencyKey(input.ClientSourceOrgID, input.IdempotencyKey, serverLogHash)
+	} else {
+		requestID = s.idGen.NewID()
+	}
+	if s.region != "" {
+		requestID = s.region + "-" + requestID
+	}
+
+	// 6. Construct and return result immediately
 	result := &LogResult{
 		RequestID:               requestID,
 		ServerLogHash:           serverLogHash,
 		ServerReceivedTimestamp: receivedTimestamp,
+		ClientTimestamp:         input.ClientTimestamp,
+		Quota:                   quota,
+	}
+	if dedup {
+		if original, ok := s.dedup.Remember(input, result, receivedTimestamp); ok {
+			s.observeSubmission(input, true)
+			return duplicateResult(original), nil
+		}
 	}
 
-	// 6. Submit to batch processor (asynchronous)
-	go s.batchProcessor.SubmitLog(input, requestID)
+	// 7. Submit to the batch processor for its size tier or canary track (asynchronous)
+	bp := s.batchProcessor
+	if s.isLarge(input) {
+		bp = s.large
+	} else if s.isCanary(requestID, input.ClientSourceOrgID) {
+		bp = s.canary
+	}
+	input.traceContext = tracing.Carrier(ctx)
+	onResult := s.observeSubmission(input, false)
+	s.submissions.Add(1)
+	go func() {
+		defer s.submissions.Done()
+		bp.SubmitLog(input, requestID, receivedTimestamp, onResult)
+	}()
 
 	// Log total function duration
 	// totalDuration := time.Since(totalStart)
@@ -88,7 +414,158 @@ func (s *Service) SubmitLog(ctx context.Context, input *LogInput) (*LogResult, e
 	return result, nil
 }
 
+// duplicateResult returns the result of the original submission for a retry
+func duplicateResult(original *LogResult) *LogResult {
+	dup := *original
+	dup.Quota = nil
+	dup.Duplicate = true
+	return &dup
+}
+
+// ErrEmptyLogContent indicates a submission without log content
+var ErrEmptyLogContent = errors.New("log_content cannot be empty")
+
+// HashMismatchError indicates a client log hash other than the hash of the content
+type HashMismatchError struct {
+	ClientHash string
+	ServerHash string
+}
+
+func (e *HashMismatchError) Error() string {
+	return fmt.Sprintf("client provided hash '%s' does not match server calculated hash '%s'", e.ClientHash, e.ServerHash)
+}
+
+// maxIdempotencyKeyLength bounds client idempotency keys
+const maxIdempotencyKeyLength = 255
+
+// ErrInvalidIdempotencyKey indicates an idempotency key that is too long or contains non-printable characters
+var ErrInvalidIdempotencyKey = errors.New("invalid idempotency_key")
+
+// validateIdempotencyKey checks an optional idempotency key
+func validateIdempotencyKey(key string) error {
+	if len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return fmt.Errorf("%w: only printable ASCII without spaces is allowed", ErrInvalidIdempotencyKey)
+		}
+	}
+	return nil
+}
+
+// maxExternalIDLength bounds client external IDs
+const maxExternalIDLength = 255
+
+var (
+	// ErrInvalidExternalID indicates an external ID that is too long or contains non-printable characters
+	ErrInvalidExternalID = errors.New("invalid external_id")
+	// ErrExternalIDConflict indicates an external ID already used by the org for a different log
+	ErrExternalIDConflict = errors.New("external_id already used for a different log")
+	// ErrExternalIDUnavailable indicates a State DB schema without external IDs
+	ErrExternalIDUnavailable = errors.New("external_id is not supported by the State DB schema")
+)
+
+// validateExternalID checks an optional external ID
+func validateExternalID(id string) error {
+	if len(id) > maxExternalIDLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrInvalidExternalID, maxExternalIDLength)
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return fmt.Errorf("%w: only printable ASCII without spaces is allowed", ErrInvalidExternalID)
+		}
+	}
+	return nil
+}
+
+// existingSubmission returns the result of the submission the org already
+// stored under the input's external ID, nil if there is none. The same
+// external ID with a different log is a conflict.
+func (s *Service) existingSubmission(ctx context.Context, input *LogInput, logHash string) (*LogResult, error) {
+	status, err := s.store.GetLogStatusByExternalID(ctx, input.ClientSourceOrgID, input.ExternalID)
+	switch {
+	case errors.Is(err, store.ErrLogNotFound):
+		return nil, nil
+	case errors.Is(err, store.ErrFeatureUnavailable):
+		return nil, ErrExternalIDUnavailable
+	case err != nil:
+		return nil, fmt.Errorf("failed to look up external_id: %w", err)
+	}
+	if status.LogHash != logHash {
+		return nil, fmt.Errorf("%w: request %s", ErrExternalIDConflict, status.RequestID)
+	}
+	existing := &Attestation{Status: status.Status}
+	if status.TxHash != nil {
+		existing.TxHash = *status.TxHash
+	}
+	if status.BlockHeight != nil {
+		existing.BlockHeight = *status.BlockHeight
+	}
+	return &LogResult{
+		RequestID:               status.RequestID,
+		ServerLogHash:           status.LogHash,
+		ServerReceivedTimestamp: status.ReceivedTimestamp,
+		ClientTimestamp:         status.ClientTimestamp,
+		Duplicate:               true,
+		Existing:                existing,
+	}, nil
+}
+
+// DeliveryStats returns the producer's delivery counters, if it tracks them
+func (s *Service) DeliveryStats() (producer.DeliveryStats, bool) {
+	reporter, ok := s.producer.(producer.DeliveryReporter)
+	if !ok {
+		return producer.DeliveryStats{}, false
+	}
+	return reporter.DeliveryStats(), true
+}
+
+// BatchStats returns the entry accounting of all batch processors combined
+func (s *Service) BatchStats() BatchStats {
+	var total BatchStats
+	for _, bp := range s.processors() {
+		total = total.add(bp.Stats())
+	}
+	return total
+}
+
+// LargeBatchStats returns the large-submission batch processor's entry accounting, if size tiers are enabled
+func (s *Service) LargeBatchStats() (BatchStats, bool) {
+	if s.large == nil {
+		return BatchStats{}, false
+	}
+	return s.large.Stats(), true
+}
+
+// Shutdown rejects further submissions, waits for accepted ones to reach the
+// batch processor and flushes it. Call it after the servers have stopped
+// taking requests. If ctx expires first the unflushed entries are spooled to
+// the WAL (see BatchProcessor.Shutdown) and ctx's error is returned.
+func (s *Service) Shutdown(ctx context.Context) (BatchStats, error) {
+	s.closeMu.Lock()
+	s.closing = true
+	s.closeMu.Unlock()
+
+	// Accepted submissions only append to a buffer, so this wait is short
+	s.submissions.Wait()
+
+	// Flush the size tiers in parallel so a slow large batch does not hold up the small ones
+	processors := s.processors()
+	errs := make([]error, len(processors))
+	var wg sync.WaitGroup
+	for i, bp := range processors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = bp.Shutdown(ctx)
+		}()
+	}
+	wg.Wait()
+	return s.BatchStats(), errors.Join(errs...)
+}
+
 // Close gracefully shuts down the service
 func (s *Service) Close() {
-	s.batchProcessor.Close()
+	s.Shutdown(context.Background())
 }
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"tlng/config"
+)
+
+// ErrShed indicates that a submission was rejected by load shedding
+var ErrShed = errors.New("gateway is shedding low-priority submissions")
+
+// ShedError is returned for submissions rejected by load shedding
+type ShedError struct {
+	Priority   string        // Priority of the rejected submission
+	RetryAfter time.Duration // Suggested wait before retrying
+}
+
+func (e *ShedError) Error() string { return fmt.Sprintf("%s (priority %s)", ErrShed, e.Priority) }
+
+func (e *ShedError) Unwrap() error { return ErrShed }
+
+// LoadShedder rejects a fraction of low-priority submissions while the
+// downstream error rate or the queue depth exceeds its threshold. The signals
+// are sampled from the batch processors' counters once per interval.
+type LoadShedder struct {
+	cfg config.LoadSheddingConfig
+
+	shedding   atomic.Bool
+	errorRate  atomic.Uint64 // float64 bits; failed / processed entries in the last interval
+	queueDepth atomic.Int64
+	shed       atomic.Int64
+	activated  atomic.Int64
+
+	last BatchStats // Counters at the previous sample; only touched by the sampling goroutine
+}
+
+// LoadSheddingStats is a snapshot of the load shedder
+type LoadSheddingStats struct {
+	Shedding   bool    `json:"shedding"`
+	ErrorRate  float64 `json:"error_rate"`  // Failed / processed entries in the last interval
+	QueueDepth int64   `json:"queue_depth"` // Accepted submissions not yet persisted
+	Shed       int64   `json:"shed"`        // Submissions rejected
+	Activated  int64   `json:"activated"`   // Times shedding started
+}
+
+// NewLoadShedder creates a new LoadShedder
+func NewLoadShedder(cfg config.LoadSheddingConfig) *LoadShedder {
+	return &LoadShedder{cfg: cfg}
+}
+
+// observe updates the signals from the batch processors' counters and
+// reports whether shedding changed
+func (l *LoadShedder) observe(st BatchStats) bool {
+	failed := (st.InsertFailed + st.Spooled + st.PublishFailed + st.QueuedLocal + st.Outboxed) -
+		(l.last.InsertFailed + l.last.Spooled + l.last.PublishFailed + l.last.QueuedLocal + l.last.Outboxed)
+	processed := (st.Persisted + st.Spooled + st.InsertFailed) -
+		(l.last.Persisted + l.last.Spooled + l.last.InsertFailed)
+	l.last = st
+
+	var rate float64
+	if processed > 0 {
+		rate = math.Min(float64(failed)/float64(processed), 1)
+	}
+	depth := st.Unfinished()
+	l.errorRate.Store(math.Float64bits(rate))
+	l.queueDepth.Store(depth)
+
+	shedding := rate > l.cfg.MaxErrorRate || (l.cfg.MaxQueueDepth > 0 && depth > l.cfg.MaxQueueDepth)
+	if l.shedding.Swap(shedding) == shedding {
+		return false
+	}
+	if shedding {
+		l.activated.Add(1)
+	}
+	return true
+}
+
+// admit rejects a submission of the given priority with a ShedError if it is shed
+func (l *LoadShedder) admit(priority string) error {
+	if !l.shedding.Load() || config.PriorityRank(priority) > config.PriorityRank(l.cfg.ShedPriority) {
+		return nil
+	}
+	if rand.Float64() >= l.cfg.ShedFraction {
+		return nil
+	}
+	l.shed.Add(1)
+	return &ShedError{Priority: priority, RetryAfter: l.cfg.RetryAfter}
+}
+
+// Stats returns a snapshot of the load shedder
+func (l *LoadShedder) Stats() LoadSheddingStats {
+	return LoadSheddingStats{
+		Shedding:   l.shedding.Load(),
+		ErrorRate:  math.Float64frombits(l.errorRate.Load()),
+		QueueDepth: l.queueDepth.Load(),
+		Shed:       l.shed.Load(),
+		Activated:  l.activated.Load(),
+	}
+}
+
+// SetLoadShedder enables load shedding of low-priority submissions
+func (s *Service) SetLoadShedder(l *LoadShedder) {
+	s.shedder = l
+}
+
+// RunLoadShedding samples the shedding signals until ctx is done. It returns
+// immediately if load shedding is disabled.
+func (s *Service) RunLoadShedding(ctx context.Context) {
+	if s.shedder == nil {
+		return
+	}
+	ticker := time.NewTicker(s.shedder.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.shedder.observe(s.BatchStats()) {
+				st := s.shedder.Stats()
+				if st.Shedding {
+					s.logger.Printf("Load shedding started: error_rate=%.3f, queue_depth=%d; shedding %.0f%% of %s-priority submissions",
+						st.ErrorRate, st.QueueDepth, s.shedder.cfg.ShedFraction*100, s.shedder.cfg.ShedPriority)
+				} else {
+					s.logger.Printf("Load shedding stopped: error_rate=%.3f, queue_depth=%d", st.ErrorRate, st.QueueDepth)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkShedding rejects a submission of orgID that load shedding drops
+func (s *Service) checkShedding(orgID string) error {
+	if s.shedder == nil {
+		return nil
+	}
+	return s.shedder.admit(s.shedder.cfg.Priority(orgID))
+}
+
+// LoadSheddingStats returns the load shedder's state, if enabled
+func (s *Service) LoadSheddingStats() (LoadSheddingStats, bool) {
+	if s.shedder == nil {
+		return LoadSheddingStats{}, false
+	}
+	return s.shedder.Stats(), true
+}
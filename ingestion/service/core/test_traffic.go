@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tlng/config"
+)
+
+// ErrTestTrafficNotAllowed indicates a submission flagged as test traffic while
+// test traffic is disabled, or from an org not allowed to flag it
+var ErrTestTrafficNotAllowed = errors.New("test traffic not allowed")
+
+// SetTestTraffic accepts submissions flagged as test traffic from the orgs cfg allows
+func (s *Service) SetTestTraffic(cfg config.TestTrafficConfig) {
+	s.testTraffic = &cfg
+}
+
+// testTrafficAllowed reports whether orgID may flag its submissions as test traffic
+func (s *Service) testTrafficAllowed(orgID string) bool {
+	return s.testTraffic != nil && (len(s.testTraffic.Orgs) == 0 || slices.Contains(s.testTraffic.Orgs, orgID))
+}
+
+// checkTestTraffic rejects a test traffic flag the configuration does not allow
+func (s *Service) checkTestTraffic(input *LogInput) error {
+	if !input.TestTraffic || s.testTrafficAllowed(input.ClientSourceOrgID) {
+		return nil
+	}
+	if s.testTraffic == nil {
+		return fmt.Errorf("%w: test traffic is disabled", ErrTestTrafficNotAllowed)
+	}
+	return fmt.Errorf("%w for org '%s'", ErrTestTrafficNotAllowed, input.ClientSourceOrgID)
+}
+
+// RunTestTrafficPurge deletes finished test submissions older than the TTL
+// every purge interval until ctx is done
+func (s *Service) RunTestTrafficPurge(ctx context.Context) {
+	if s.testTraffic == nil {
+		return
+	}
+	ticker := time.NewTicker(s.testTraffic.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := s.store.PurgeTestTraffic(ctx, time.Now().Add(-s.testTraffic.TTL))
+			if err != nil {
+				s.logger.Printf("Warning: failed to purge expired test traffic: %v", err)
+			} else if purged > 0 {
+				s.logger.Printf("Purged %d expired test submissions", purged)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tlng/config"
+)
+
+var (
+	// ErrInvalidClientTimestamp indicates a client timestamp that could not be parsed or is out of range
+	ErrInvalidClientTimestamp = errors.New("invalid client_timestamp")
+	// ErrClientTimestampSkew indicates a client timestamp outside the allowed skew window
+	ErrClientTimestampSkew = errors.New("client_timestamp outside allowed clock skew")
+)
+
+// minClientTimestamp is the earliest client timestamp considered plausible
+var minClientTimestamp = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Client timestamp formats, as detected by DetectClientTimestamp
+const (
+	TimestampFormatRFC3339     = "rfc3339"      // 2024-05-01T12:00:00Z
+	TimestampFormatRFC3339Nano = "rfc3339nano"  // 2024-05-01T12:00:00.123456789Z (any fraction digits)
+	TimestampFormatUnixSeconds = "unix_seconds" // 1714564800
+	TimestampFormatUnixMillis  = "unix_millis"  // 1714564800123
+	TimestampFormatUnixNanos   = "unix_nanos"   // 1714564800123456789
+)
+
+// Unix epoch values are told apart by magnitude. Seconds and milliseconds
+// cover every plausible time up to year 5138; values between the millisecond
+// and nanosecond ranges would be microseconds, which are not accepted.
+const (
+	maxUnixSeconds = 1e11
+	maxUnixMillis  = 1e14
+	minUnixNanos   = 1e17
+)
+
+// ParseClientTimestamp parses a client timestamp in any format DetectClientTimestamp accepts
+func ParseClientTimestamp(raw string) (time.Time, error) {
+	ts, _, err := DetectClientTimestamp(raw)
+	return ts, err
+}
+
+// DetectClientTimestamp parses a client timestamp and reports its format:
+// RFC 3339 with or without fractional seconds, or a Unix epoch in seconds,
+// milliseconds or nanoseconds
+func DetectClientTimestamp(raw string) (time.Time, string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, "", fmt.Errorf("%w: empty value", ErrInvalidClientTimestamp)
+	}
+
+	if strings.ContainsAny(raw, "Tt") {
+		ts, err := time.Parse(time.RFC3339Nano, strings.ToUpper(raw))
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("%w: not RFC 3339: %v", ErrInvalidClientTimestamp, err)
+		}
+		if strings.Contains(raw, ".") {
+			return ts, TimestampFormatRFC3339Nano, nil
+		}
+		return ts, TimestampFormatRFC3339, nil
+	}
+
+	epoch, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || epoch < 0 {
+		return time.Time{}, "", fmt.Errorf("%w: %q is neither RFC 3339 nor a Unix epoch in seconds, milliseconds or nanoseconds", ErrInvalidClientTimestamp, raw)
+	}
+	switch {
+	case epoch < maxUnixSeconds:
+		return time.Unix(epoch, 0).UTC(), TimestampFormatUnixSeconds, nil
+	case epoch < maxUnixMillis:
+		return time.UnixMilli(epoch).UTC(), TimestampFormatUnixMillis, nil
+	case epoch >= minUnixNanos:
+		return time.Unix(0, epoch).UTC(), TimestampFormatUnixNanos, nil
+	default:
+		return time.Time{}, "", fmt.Errorf("%w: ambiguous Unix epoch %d (microseconds are not accepted)", ErrInvalidClientTimestamp, epoch)
+	}
+}
+
+// applyTimestampPolicy validates the client timestamp of input against the
+// server receive time in stages: transport parse result, plausibility, then
+// clock skew. On success input.ClientTimestamp holds the accepted value in UTC,
+// or nil if the timestamp was absent or discarded by the policy.
+func (s *Service) applyTimestampPolicy(input *LogInput, serverTime time.Time) error {
+	policy := s.timestampPolicy
+
+	// Stage 1: format, as reported by the transport layer
+	if input.ClientTimestampErr != nil {
+		if policy.OnInvalid == config.TimestampReject {
+			if errors.Is(input.ClientTimestampErr, ErrInvalidClientTimestamp) {
+				return input.ClientTimestampErr
+			}
+			return fmt.Errorf("%w: %v", ErrInvalidClientTimestamp, input.ClientTimestampErr)
+		}
+		s.logger.Printf("Service: Ignoring unparsable client_timestamp: %v", input.ClientTimestampErr)
+		input.ClientTimestamp = nil
+		return nil
+	}
+	if input.ClientTimestamp == nil {
+		return nil
+	}
+
+	// Stage 2: plausibility, independent of the skew window
+	ts := input.ClientTimestamp.UTC()
+	if ts.Before(minClientTimestamp) {
+		if policy.OnInvalid == config.TimestampReject {
+			return fmt.Errorf("%w: %s is before %s", ErrInvalidClientTimestamp, ts.Format(time.RFC3339Nano), minClientTimestamp.Format(time.RFC3339))
+		}
+		input.ClientTimestamp = nil
+		return nil
+	}
+
+	// Stage 3: clock skew relative to the server receive time
+	earliest := serverTime.Add(-policy.MaxPastSkew)
+	latest := serverTime.Add(policy.MaxFutureSkew)
+	if ts.Before(earliest) || ts.After(latest) {
+		switch policy.OnSkew {
+		case config.TimestampReject:
+			return fmt.Errorf("%w: %s differs from server time %s by %v",
+				ErrClientTimestampSkew, ts.Format(time.RFC3339Nano), serverTime.UTC().Format(time.RFC3339Nano), ts.Sub(serverTime))
+		case config.TimestampIgnore:
+			input.ClientTimestamp = nil
+			return nil
+		default: // clamp
+			if ts.Before(earliest) {
+				ts = earliest.UTC()
+			} else {
+				ts = latest.UTC()
+			}
+		}
+	}
+
+	input.ClientTimestamp = &ts
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WAL is a local append-only spool for batch entries the processor could not
+// persist, either because the State DB insert failed or because shutdown ran
+// out of time. Spooled entries are replayed into the store on the next start;
+// replays resolve duplicates through the batch conflict policy.
+type WAL struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// walRecord is the on-disk form of a batch entry, one JSON object per line
+type walRecord struct {
+	RequestID       string     `json:"request_id"`
+	LogContent      string     `json:"log_content"`
+	LogHash         string     `json:"log_hash"`
+	SourceOrgID     string     `json:"source_org_id,omitempty"`
+	ReceivedAt      time.Time  `json:"received_at"`
+	ClientTimestamp *time.Time `json:"client_timestamp,omitempty"`
+	Severity        string     `json:"severity,omitempty"`
+	SourceHost      string     `json:"source_host,omitempty"`
+	Application     string     `json:"application,omitempty"`
+	Sequence        int64      `json:"sequence,omitempty"`
+	TestTraffic     bool       `json:"test_traffic,omitempty"`
+	ExternalID      string     `json:"external_id,omitempty"`
+}
+
+// OpenWAL opens (or creates) the WAL at path
+func OpenWAL(path string) (*WAL, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL '%s': %w", path, err)
+	}
+	return &WAL{path: path, file: file}, nil
+}
+
+// Append durably writes entries to the WAL
+func (w *WAL) Append(entries []*batchEntry) error {
+	buf := make([]byte, 0, 256*len(entries))
+	for _, e := range entries {
+		line, err := json.Marshal(walRecord{
+			RequestID:       e.requestID,
+			LogContent:      e.input.LogContent,
+			LogHash:         e.input.ClientLogHash,
+			SourceOrgID:     e.input.ClientSourceOrgID,
+			ReceivedAt:      e.receivedAt,
+			ClientTimestamp: e.input.ClientTimestamp,
+			Severity:        e.input.Severity,
+			SourceHost:      e.input.SourceHost,
+			Application:     e.input.Application,
+			Sequence:        e.input.Sequence,
+			TestTraffic:     e.input.TestTraffic,
+			ExternalID:      e.input.ExternalID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode WAL record: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(buf); err != nil {
+		return fmt.Errorf("failed to write WAL: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	return nil
+}
+
+// Drain moves the spooled entries aside for replay and starts a fresh log.
+// The moved entries stay on disk until Commit, so a crash during replay
+// replays them again on the next start. Entries that fail again during the
+// replay should be appended to the fresh log.
+func (w *WAL) Drain() ([]*batchEntry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// Fold the current log into the replay file left over from an interrupted replay, if any
+	if err := appendFile(w.replayPath(), w.path); err != nil {
+		return nil, err
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return nil, fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync WAL: %w", err)
+	}
+	return readWAL(w.replayPath())
+}
+
+// Commit discards the entries returned by Drain once they are replayed
+func (w *WAL) Commit() error {
+	if err := os.Remove(w.replayPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove replayed WAL: %w", err)
+	}
+	return nil
+}
+
+// Close closes the WAL file
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+func (w *WAL) replayPath() string {
+	return w.path + ".replay"
+}
+
+// appendFile appends the contents of src to dst, creating dst if needed
+func appendFile(dst, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed to read WAL: %w", err)
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL replay file: %w", err)
+	}
+	if _, err := out.Write(data); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write WAL replay file: %w", err)
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to sync WAL replay file: %w", err)
+	}
+	return out.Close()
+}
+
+// readWAL decodes the entries in a WAL file. A torn last line from a crash
+// mid-write is skipped.
+func readWAL(path string) ([]*batchEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL replay file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []*batchEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec walRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		entries = append(entries, &batchEntry{
+			input: &LogInput{
+				LogContent:        rec.LogContent,
+				ClientLogHash:     rec.LogHash,
+				ClientSourceOrgID: rec.SourceOrgID,
+				ClientTimestamp:   rec.ClientTimestamp,
+				Severity:          rec.Severity,
+				SourceHost:        rec.SourceHost,
+				Application:       rec.Application,
+				Sequence:          rec.Sequence,
+				TestTraffic:       rec.TestTraffic,
+				ExternalID:        rec.ExternalID,
+			},
+			requestID:  rec.RequestID,
+			receivedAt: rec.ReceivedAt,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read WAL replay file: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"strings"
+
+	core "tlng/ingestion/service/core"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// healthMethodPrefix is left unbound so probes keep working with any client certificate
+const healthMethodPrefix = "/grpc.health.v1.Health/"
+
+// ClientCertOrgOptions returns the server options binding RPCs made with a
+// verified client certificate to the org named by field in its subject, like
+// the HTTP ClientCertOrg middleware
+func ClientCertOrgOptions(field string) []grpc.ServerOption {
+	bind := func(ctx context.Context) (context.Context, error) {
+		var state *tls.ConnectionState
+		if p, ok := peer.FromContext(ctx); ok {
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				state = &info.State
+			}
+		}
+		org, err := core.ClientCertOrg(state, field)
+		if err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if org != "" {
+			ctx = core.WithClientCertOrg(ctx, org)
+		}
+		return ctx, nil
+	}
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			if strings.HasPrefix(info.FullMethod, healthMethodPrefix) {
+				return handler(ctx, req)
+			}
+			ctx, err := bind(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if strings.HasPrefix(info.FullMethod, healthMethodPrefix) {
+				return handler(srv, ss)
+			}
+			ctx, err := bind(ss.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, &orgStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// orgStream is a server stream whose context carries the caller's client certificate org
+type orgStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *orgStream) Context() context.Context { return s.ctx }
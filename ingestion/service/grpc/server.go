@@ -2,13 +2,23 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	// Import generated proto code and service layer
+	"tlng/errcatalog"
 	core "tlng/ingestion/service/core"
 	pb "tlng/proto/logingestion"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb" // For Protobuf Timestamp
 )
 
@@ -28,24 +38,38 @@ func NewServer(s *core.Service, l *log.Logger) *Server {
 func (s *Server) SubmitLog(ctx context.Context, req *pb.SubmitLogRequest) (*pb.SubmitLogResponse, error) {
 	s.logger.Println("gRPC Server: Received SubmitLog request")
 
-	// 1. Convert Protobuf request to Service layer input structure
-	input := &core.LogInput{
-		LogContent:        req.GetLogContent(),
-		ClientLogHash:     req.GetClientLogHash(),
-		ClientSourceOrgID: req.GetClientSourceOrgId(),
-	}
-	// Handle optional timestamp
-	if req.ClientTimestamp != nil && req.ClientTimestamp.IsValid() {
-		ts := req.ClientTimestamp.AsTime()
-		input.ClientTimestamp = &ts
+	// Messages above the largest limit of any org were already rejected by the server
+	if err := s.svc.CheckGRPCRequestSize(req.GetClientSourceOrgId(), int64(proto.Size(req))); err != nil {
+		s.captureRejection(req, codes.ResourceExhausted, err)
+		return nil, s.statusError(ctx, err)
 	}
 
+	// 1. Convert Protobuf request to Service layer input structure
+	input := requestInput(req, testTrafficMetadata(ctx))
+
 	// 2. Call core Service layer processing logic
 	result, err := s.svc.SubmitLog(ctx, input)
 	if err != nil {
 		s.logger.Printf("gRPC Server: Service layer error: %v", err)
-		// Can return different gRPC error codes based on error type
-		return nil, fmt.Errorf("failed to process log submission: %w", err) // Return generic error
+		code, retryAfter := s.submitErrorCode(err)
+		if code == codes.Unavailable && retryAfter > 0 {
+			// Retry pushback tells gRPC clients with a retry policy when to try again
+			pushback := strconv.FormatInt(retryAfter.Milliseconds(), 10)
+			if err := grpc.SetTrailer(ctx, metadata.Pairs("grpc-retry-pushback-ms", pushback)); err != nil {
+				s.logger.Printf("gRPC Server: Failed to set retry pushback: %v", err)
+			}
+		}
+		var quotaErr *core.QuotaError
+		if errors.As(err, &quotaErr) {
+			s.setQuotaHeader(ctx, &quotaErr.Status)
+		}
+		if code == codes.InvalidArgument {
+			s.captureRejection(req, code, err)
+		}
+		if code == codes.Unknown {
+			return nil, fmt.Errorf("failed to process log submission: %w", err) // Return generic error
+		}
+		return nil, s.statusError(ctx, err)
 	}
 
 	// 3. Convert Service layer result to Protobuf response
@@ -55,10 +79,143 @@ func (s *Server) SubmitLog(ctx context.Context, req *pb.SubmitLogRequest) (*pb.S
 		ServerReceivedTimestamp: timestamppb.New(result.ServerReceivedTimestamp),
 		Status:                  "ACCEPTED",
 	}
+	if a := result.Existing; a != nil {
+		response.Status = string(a.Status)
+		response.TxHash = a.TxHash
+		response.BlockHeight = uint64(a.BlockHeight)
+	}
+	if m := result.Integrity; m != nil {
+		response.HashAlgorithm = m.HashAlgorithm
+		response.Canonicalization = m.Canonicalization
+		response.ServiceVersion = m.ServiceVersion
+		response.SchemaVersion = int32(m.SchemaVersion)
+	}
+
+	s.setQuotaHeader(ctx, result.Quota)
+	if result.Duplicate {
+		// Tell the client its retry was matched to the original submission
+		if err := grpc.SetHeader(ctx, metadata.Pairs("x-duplicate-submission", "true")); err != nil {
+			s.logger.Printf("gRPC Server: Failed to set duplicate header: %v", err)
+		}
+	}
 
 	s.logger.Printf("gRPC Server: Successfully processed request_id: %s", result.RequestID)
 	return response, nil
 }
 
+// requestInput converts a Protobuf request to the Service layer input structure
+func requestInput(req *pb.SubmitLogRequest, testTraffic bool) *core.LogInput {
+	input := &core.LogInput{
+		LogContent:        req.GetLogContent(),
+		ClientLogHash:     req.GetClientLogHash(),
+		ClientSourceOrgID: req.GetClientSourceOrgId(),
+		IdempotencyKey:    req.GetIdempotencyKey(),
+		ExternalID:        req.GetExternalId(),
+		Severity:          req.GetSeverity(),
+		SourceHost:        req.GetSourceHost(),
+		Application:       req.GetApplication(),
+		Sequence:          req.GetSequence(),
+		TestTraffic:       testTraffic,
+	}
+	// Handle optional timestamp; the service's timestamp policy decides how invalid values are handled
+	if req.ClientTimestamp != nil {
+		if err := req.ClientTimestamp.CheckValid(); err != nil {
+			input.ClientTimestampErr = err
+		} else {
+			ts := req.ClientTimestamp.AsTime()
+			input.ClientTimestamp = &ts
+		}
+	}
+	return input
+}
+
+// submitErrorCode maps a Service layer submission error to its gRPC code, and
+// the time after which the client may retry, 0 if not applicable. Errors
+// without a specific code map to codes.Unknown.
+func (s *Server) submitErrorCode(err error) (codes.Code, time.Duration) {
+	code, _, retryAfter := s.svc.ErrorCode(err)
+	return errcatalog.Get(code).GRPCCode, retryAfter
+}
+
+// statusError returns the status of a Service layer error: its gRPC code and
+// message, with its catalog code and parameters in an ErrorInfo detail and,
+// if the caller's accept-language metadata names a catalog language, its
+// message in that language in a LocalizedMessage detail
+func (s *Server) statusError(ctx context.Context, err error) error {
+	code, params, _ := s.svc.ErrorCode(err)
+	return errcatalog.Status(code, params, err.Error(), requestLanguage(ctx)).Err()
+}
+
+// requestLanguage returns the catalog language matching the caller's
+// accept-language metadata, "" if none does
+func requestLanguage(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return errcatalog.Negotiate(strings.Join(md.Get("accept-language"), ","))
+}
+
+// testTrafficMetadata reports whether the x-test-traffic metadata flags the
+// submission as test traffic
+func testTrafficMetadata(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("x-test-traffic")
+	if len(values) == 0 {
+		return false
+	}
+	flag, _ := strconv.ParseBool(values[0])
+	return flag
+}
+
+// captureRejection offers a rejected request for debug capture. The raw
+// message is not available after decoding, so the request is re-encoded.
+func (s *Server) captureRejection(req *pb.SubmitLogRequest, code codes.Code, err error) {
+	payload, marshalErr := proto.Marshal(req)
+	if marshalErr != nil {
+		s.logger.Printf("gRPC Server: Failed to encode rejected request for capture: %v", marshalErr)
+		return
+	}
+	s.svc.CaptureRejection(core.Rejection{
+		OrgID:       req.GetClientSourceOrgId(),
+		Transport:   "grpc",
+		StatusCode:  int(code),
+		Reason:      err.Error(),
+		ContentType: "application/grpc+proto",
+		Payload:     payload,
+	})
+}
+
+// LimitInFlight is a unary interceptor applying the service's in-flight limiter
+// to SubmitLog. Rejected calls get UNAVAILABLE with a retry pushback; other
+// methods (e.g. health checks) are not limited.
+func (s *Server) LimitInFlight(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod != pb.LogIngestion_SubmitLog_FullMethodName {
+		return handler(ctx, req)
+	}
+	release, err := s.svc.AcquireInFlight(ctx)
+	if err != nil {
+		if !errors.Is(err, core.ErrOverloaded) {
+			return nil, status.FromContextError(err).Err()
+		}
+		pushback := strconv.FormatInt(s.svc.InFlightRetryAfter().Milliseconds(), 10)
+		if err := grpc.SetTrailer(ctx, metadata.Pairs("grpc-retry-pushback-ms", pushback)); err != nil {
+			s.logger.Printf("gRPC Server: Failed to set retry pushback: %v", err)
+		}
+		return nil, s.statusError(ctx, err)
+	}
+	defer release()
+	return handler(ctx, req)
+}
+
+// setQuotaHeader sends the org's rate-limit and quota state as response header
+// metadata (x-ratelimit-*, x-quota-*), if quotas are enabled
+func (s *Server) setQuotaHeader(ctx context.Context, st *core.QuotaStatus) {
+	if st == nil {
+		return
+	}
+	md := metadata.New(st.Headers())
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		s.logger.Printf("gRPC Server: Failed to set quota headers: %v", err)
+	}
+}
+
 // Ensure Server implements the interface (compile-time check)
 var _ pb.LogIngestionServer = (*Server)(nil)
@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+
+	"tlng/errcatalog"
+	core "tlng/ingestion/service/core"
+	pb "tlng/proto/logingestion"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SubmitLogStream implements the SubmitLogStream method in the gRPC interface.
+// Each entry is submitted like a SubmitLog call and handed to the batch
+// processor on its own, so entries are accepted or rejected individually. After
+// the configured number of entries the summary is returned and the rest of the
+// stream is not read.
+func (s *Server) SubmitLogStream(stream pb.LogIngestion_SubmitLogStreamServer) error {
+	ctx := stream.Context()
+
+	// Maintenance rejects every entry alike
+	if state := s.svc.Maintenance(); state.Enabled {
+		pushback := strconv.FormatInt(state.RetryAfter.Milliseconds(), 10)
+		if err := grpc.SetTrailer(ctx, metadata.Pairs("grpc-retry-pushback-ms", pushback)); err != nil {
+			s.logger.Printf("gRPC Server: Failed to set retry pushback: %v", err)
+		}
+		return s.statusError(ctx, &core.MaintenanceError{State: state})
+	}
+
+	testTraffic := testTrafficMetadata(ctx)
+	maxEntries := s.svc.GRPCStreamMaxEntries()
+	resp := &pb.SubmitLogStreamResponse{}
+	var quota *core.QuotaStatus
+	for maxEntries <= 0 || len(resp.Results) < maxEntries {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		entry, entryQuota, err := s.submitStreamEntry(ctx, req, testTraffic, len(resp.Results))
+		if err != nil {
+			return err
+		}
+		if entryQuota != nil {
+			quota = entryQuota
+		}
+		if entry.Status == "ACCEPTED" {
+			resp.Accepted++
+		} else {
+			resp.Rejected++
+		}
+		resp.Results = append(resp.Results, entry)
+	}
+
+	if maxEntries > 0 && len(resp.Results) == maxEntries {
+		s.logger.Printf("gRPC Server: Ending stream at the limit of %d entries", maxEntries)
+	}
+	if resp.Rejected > 0 {
+		s.logger.Printf("gRPC Server: Stream of %d entries: %d accepted, %d rejected", len(resp.Results), resp.Accepted, resp.Rejected)
+	}
+	s.setQuotaHeader(ctx, quota)
+	return stream.SendAndClose(resp)
+}
+
+// submitStreamEntry submits one entry of a stream and returns its outcome and
+// the org's quota state, if known. It fails only if the stream's context is done.
+func (s *Server) submitStreamEntry(ctx context.Context, req *pb.SubmitLogRequest, testTraffic bool, index int) (*pb.SubmitLogStreamResult, *core.QuotaStatus, error) {
+	reject := func(err error) *pb.SubmitLogStreamResult {
+		code, params, retryAfter := s.svc.ErrorCode(err)
+		return &pb.SubmitLogStreamResult{
+			Index:        int32(index),
+			Status:       "REJECTED",
+			Code:         int32(errcatalog.Get(code).GRPCCode),
+			Error:        err.Error(),
+			RetryAfterMs: retryAfter.Milliseconds(),
+			ErrorCode:    string(code),
+			ErrorParams:  params,
+		}
+	}
+
+	// Messages above the largest limit of any org were already rejected by the server
+	if err := s.svc.CheckGRPCRequestSize(req.GetClientSourceOrgId(), int64(proto.Size(req))); err != nil {
+		s.captureRejection(req, codes.ResourceExhausted, err)
+		return reject(err), nil, nil
+	}
+
+	// Entries count against the in-flight budget one at a time, like SubmitLog calls
+	release, err := s.svc.AcquireInFlight(ctx)
+	if err != nil {
+		if !errors.Is(err, core.ErrOverloaded) {
+			return nil, nil, status.FromContextError(err).Err()
+		}
+		return reject(err), nil, nil
+	}
+	defer release()
+
+	result, err := s.svc.SubmitLog(ctx, requestInput(req, testTraffic))
+	if err != nil {
+		if code, _ := s.submitErrorCode(err); code == codes.InvalidArgument {
+			s.captureRejection(req, code, err)
+		}
+		var quota *core.QuotaStatus
+		var quotaErr *core.QuotaError
+		if errors.As(err, &quotaErr) {
+			quota = &quotaErr.Status
+		}
+		return reject(err), quota, nil
+	}
+	entry := &pb.SubmitLogStreamResult{
+		Index:                   int32(index),
+		Status:                  "ACCEPTED",
+		RequestId:               result.RequestID,
+		ServerLogHash:           result.ServerLogHash,
+		ServerReceivedTimestamp: timestamppb.New(result.ServerReceivedTimestamp),
+		Duplicate:               result.Duplicate,
+	}
+	if a := result.Existing; a != nil {
+		entry.Status = string(a.Status)
+	}
+	return entry, result.Quota, nil
+}
@@ -0,0 +1,455 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tlng/apitoken"
+	core "tlng/ingestion/service/core"
+	"tlng/internal/fingerprint"
+	"tlng/storage/store"
+	"tlng/svcauth"
+)
+
+// maintenancePayload is the admin API representation of maintenance mode
+type maintenancePayload struct {
+	Enabled           bool   `json:"enabled"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+	Message           string `json:"message,omitempty"`
+	Since             string `json:"since,omitempty"`
+}
+
+// Maintenance handles GET and PUT /admin/maintenance. PUT switches maintenance
+// mode; an omitted retry_after_seconds keeps the current value. The route is
+// meant for operators and must not be exposed through the external ingress.
+func (h *LogHandler) Maintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var payload maintenancePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			h.respondError(w, "Bad Request: Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+		if payload.RetryAfterSeconds < 0 {
+			h.respondError(w, "retry_after_seconds must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		state := h.svc.Maintenance()
+		state.Enabled = payload.Enabled
+		state.Message = payload.Message
+		if payload.RetryAfterSeconds > 0 {
+			state.RetryAfter = time.Duration(payload.RetryAfterSeconds) * time.Second
+		}
+		h.svc.SetMaintenance(state)
+	default:
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	state := h.svc.Maintenance()
+	resp := maintenancePayload{
+		Enabled:           state.Enabled,
+		RetryAfterSeconds: int(state.RetryAfter.Seconds()),
+		Message:           state.Message,
+	}
+	if !state.Since.IsZero() {
+		resp.Since = state.Since.Format(time.RFC3339Nano)
+	}
+	h.respondJSON(w, resp, http.StatusOK)
+}
+
+// configPayload is the admin API representation of the configuration fingerprint
+type configPayload struct {
+	Version      string          `json:"version"`
+	ConfigSHA256 string          `json:"config_sha256"`
+	Config       json.RawMessage `json:"config"` // Sanitized configuration, exactly the bytes config_sha256 is computed over
+	LastAnchor   *anchorPayload  `json:"last_anchor,omitempty"`
+
+	ConfigFiles []fingerprint.File `json:"config_files"` // Configuration file and the files it names, with their SHA-256
+}
+
+// anchorPayload describes the last anchoring of the fingerprint
+type anchorPayload struct {
+	RequestID  string `json:"request_id,omitempty"`
+	LogHash    string `json:"log_hash,omitempty"`
+	AnchoredAt string `json:"anchored_at"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Config handles GET /admin/config: the sanitized configuration, its
+// fingerprint and the binary version, so auditors can recompute the hash
+// anchored on chain and support can see the effective settings, and the
+// checksums of the configuration files. Like /admin/maintenance it is meant
+// for operators only.
+func (h *LogHandler) Config(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fp, version, anchor := h.svc.ConfigFingerprint()
+	if fp == nil {
+		h.respondError(w, "configuration fingerprint not available", http.StatusNotFound)
+		return
+	}
+	resp := configPayload{Version: version, ConfigSHA256: fp.SHA256, Config: fp.Canonical, ConfigFiles: h.svc.ConfigFiles()}
+	if anchor != nil {
+		resp.LastAnchor = &anchorPayload{
+			RequestID:  anchor.RequestID,
+			LogHash:    anchor.LogHash,
+			AnchoredAt: anchor.AnchoredAt.Format(time.RFC3339),
+		}
+		if anchor.Err != nil {
+			resp.LastAnchor.Error = anchor.Err.Error()
+		}
+	}
+	h.respondJSON(w, resp, http.StatusOK)
+}
+
+// capturePayload is the admin API representation of a debug capture
+type capturePayload struct {
+	CaptureID   string `json:"capture_id"`
+	OrgID       string `json:"org_id"`
+	Transport   string `json:"transport"`
+	StatusCode  int    `json:"status_code"`
+	Reason      string `json:"reason"`
+	ContentType string `json:"content_type,omitempty"`
+	PayloadSize int    `json:"payload_size"`
+	Truncated   bool   `json:"truncated,omitempty"`
+	CapturedAt  string `json:"captured_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// Captures handles GET /admin/captures?org_id=&limit=, listing the debug
+// captures of rejected submissions newest first, and GET /admin/captures/{id},
+// returning a capture's raw request bytes with its metadata in X-Capture-*
+// headers. Captured payloads may hold customer data: a caller bound to an org
+// sees only that org's captures.
+func (h *LogHandler) Captures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	capturer := h.svc.DebugCapturer()
+	if capturer == nil {
+		h.respondError(w, "debug capture is not enabled", http.StatusNotFound)
+		return
+	}
+	callerOrg := ""
+	if id := svcauth.IdentityFromContext(r.Context()); id != nil {
+		callerOrg = id.OrgID
+	}
+
+	if id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/captures"), "/"); id != "" {
+		capture, err := capturer.Get(r.Context(), id)
+		if err == nil && callerOrg != "" && capture.OrgID != callerOrg {
+			// Reported as not found, so captures of other orgs cannot be probed
+			err = store.ErrCaptureNotFound
+		}
+		if errors.Is(err, store.ErrCaptureNotFound) {
+			h.respondError(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			h.logger.Printf("HTTP Handler: Failed to get debug capture %s: %v", id, err)
+			h.respondError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType := capture.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Capture-Org-ID", capture.OrgID)
+		w.Header().Set("X-Capture-Transport", capture.Transport)
+		w.Header().Set("X-Capture-Status", strconv.Itoa(capture.StatusCode))
+		w.Header().Set("X-Capture-Payload-Size", strconv.Itoa(capture.PayloadSize))
+		w.Header().Set("X-Capture-Truncated", strconv.FormatBool(capture.Truncated))
+		w.Header().Set("X-Capture-Captured-At", capture.CapturedAt.UTC().Format(time.RFC3339Nano))
+		w.WriteHeader(http.StatusOK)
+		w.Write(capture.Payload)
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			h.respondError(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	orgID := r.URL.Query().Get("org_id")
+	if callerOrg != "" {
+		if orgID != "" && orgID != callerOrg {
+			h.respondError(w, "token is bound to another org", http.StatusForbidden)
+			return
+		}
+		orgID = callerOrg
+	}
+	captures, err := capturer.List(r.Context(), orgID, limit)
+	if err != nil {
+		h.logger.Printf("HTTP Handler: Failed to list debug captures: %v", err)
+		h.respondError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := make([]capturePayload, 0, len(captures))
+	for _, c := range captures {
+		resp = append(resp, capturePayload{
+			CaptureID:   c.CaptureID,
+			OrgID:       c.OrgID,
+			Transport:   c.Transport,
+			StatusCode:  c.StatusCode,
+			Reason:      c.Reason,
+			ContentType: c.ContentType,
+			PayloadSize: c.PayloadSize,
+			Truncated:   c.Truncated,
+			CapturedAt:  c.CapturedAt.UTC().Format(time.RFC3339Nano),
+			ExpiresAt:   c.ExpiresAt.UTC().Format(time.RFC3339Nano),
+		})
+	}
+	h.respondJSON(w, map[string]interface{}{"captures": resp}, http.StatusOK)
+}
+
+// selfTestPayload is the admin API representation of a self-test run
+type selfTestPayload struct {
+	Passed      bool                   `json:"passed"`
+	RequestID   string                 `json:"request_id,omitempty"`
+	LogHash     string                 `json:"log_hash,omitempty"`
+	TxHash      string                 `json:"tx_hash,omitempty"`
+	BlockHeight int64                  `json:"block_height,omitempty"`
+	StartedAt   string                 `json:"started_at"`
+	DurationMS  int64                  `json:"duration_ms"`
+	Stages      []selfTestStagePayload `json:"stages"`
+}
+
+// selfTestStagePayload describes one stage of a self-test run
+type selfTestStagePayload struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"duration_ms"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// SelfTest handles POST /admin/selftest: it submits a synthetic log, follows
+// it until it is anchored and verified on chain, and reports each stage's
+// timing. The response is 200 if the run passed and 503 if a stage failed.
+// Like /admin/maintenance it is meant for operators only.
+func (h *LogHandler) SelfTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// The run outlasts the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Printf("HTTP Handler: Failed to lift write deadline for self-test: %v", err)
+	}
+
+	result, err := h.svc.RunSelfTest(r.Context())
+	if errors.Is(err, core.ErrSelfTestDisabled) {
+		h.respondError(w, err.Error(), http.StatusNotFound)
+		return
+	} else if errors.Is(err, core.ErrSelfTestRunning) {
+		h.respondError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		h.respondError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := selfTestPayload{
+		Passed:      result.Passed,
+		RequestID:   result.RequestID,
+		LogHash:     result.LogHash,
+		TxHash:      result.TxHash,
+		BlockHeight: result.BlockHeight,
+		StartedAt:   result.StartedAt.UTC().Format(time.RFC3339Nano),
+		DurationMS:  result.Duration.Milliseconds(),
+	}
+	for _, stage := range result.Stages {
+		resp.Stages = append(resp.Stages, selfTestStagePayload{
+			Name:       stage.Name,
+			Status:     stage.Status,
+			DurationMS: stage.Duration.Milliseconds(),
+			Detail:     stage.Detail,
+		})
+	}
+	statusCode := http.StatusOK
+	if !result.Passed {
+		statusCode = http.StatusServiceUnavailable
+	}
+	h.respondJSON(w, resp, statusCode)
+}
+
+// tokenPayload is the admin API representation of an API token
+type tokenPayload struct {
+	TokenID     string   `json:"token_id"`
+	OrgID       string   `json:"org_id"`
+	Description string   `json:"description,omitempty"`
+	Scopes      []string `json:"scopes"`
+	CreatedAt   string   `json:"created_at"`
+	ExpiresAt   string   `json:"expires_at"`
+	RevokedAt   string   `json:"revoked_at,omitempty"`
+	RotatedTo   string   `json:"rotated_to,omitempty"`
+	Active      bool     `json:"active"`
+
+	Token string `json:"token,omitempty"` // The token itself, only when minted or rotated
+}
+
+// newTokenPayload converts a stored token to its admin API representation
+func newTokenPayload(t *store.APIToken, now time.Time) tokenPayload {
+	p := tokenPayload{
+		TokenID:     t.TokenID,
+		OrgID:       t.OrgID,
+		Description: t.Description,
+		Scopes:      t.Scopes,
+		CreatedAt:   t.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ExpiresAt:   t.ExpiresAt.UTC().Format(time.RFC3339Nano),
+		RotatedTo:   t.RotatedTo,
+		Active:      t.Active(now),
+	}
+	if t.RevokedAt != nil {
+		p.RevokedAt = t.RevokedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return p
+}
+
+// mintTokenRequest is the body of POST /admin/tokens
+type mintTokenRequest struct {
+	OrgID       string   `json:"org_id"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+	TTLSeconds  int64    `json:"ttl_seconds"` // 0 for the default TTL
+}
+
+// Tokens handles the API token admin API:
+//   - POST /admin/tokens mints a token; the response is the only time it is shown
+//   - GET /admin/tokens?org_id= lists tokens, newest first
+//   - POST /admin/tokens/{id}/rotate replaces a token, keeping the old one
+//     working for the rotation grace period
+//   - DELETE /admin/tokens/{id} revokes a token
+//
+// A caller authenticated by an org-bound API token only manages its own org's tokens.
+func (h *LogHandler) Tokens(w http.ResponseWriter, r *http.Request) {
+	manager := h.svc.APITokens()
+	if manager == nil {
+		h.respondError(w, "api tokens are not enabled", http.StatusNotFound)
+		return
+	}
+	callerOrg := ""
+	if id := svcauth.IdentityFromContext(r.Context()); id != nil {
+		callerOrg = id.OrgID
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/tokens"), "/")
+	tokenID, action, _ := strings.Cut(path, "/")
+	switch {
+	case tokenID == "" && r.Method == http.MethodGet:
+		orgID := r.URL.Query().Get("org_id")
+		if callerOrg != "" {
+			if orgID != "" && orgID != callerOrg {
+				h.respondError(w, "token is bound to another org", http.StatusForbidden)
+				return
+			}
+			orgID = callerOrg
+		}
+		tokens, err := manager.List(r.Context(), orgID)
+		if err != nil {
+			h.logger.Printf("HTTP Handler: Failed to list api tokens: %v", err)
+			h.respondError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		resp := make([]tokenPayload, 0, len(tokens))
+		for _, t := range tokens {
+			resp = append(resp, newTokenPayload(t, now))
+		}
+		h.respondJSON(w, map[string]interface{}{"tokens": resp}, http.StatusOK)
+
+	case tokenID == "" && r.Method == http.MethodPost:
+		var req mintTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondError(w, "Bad Request: Invalid JSON format", http.StatusBadRequest)
+			return
+		}
+		if callerOrg != "" && req.OrgID != callerOrg {
+			h.respondError(w, "token is bound to another org", http.StatusForbidden)
+			return
+		}
+		secret, t, err := manager.Mint(r.Context(), req.OrgID, req.Description, req.Scopes, time.Duration(req.TTLSeconds)*time.Second)
+		if errors.Is(err, apitoken.ErrInvalidToken) {
+			h.respondError(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			h.logger.Printf("HTTP Handler: Failed to mint api token for org %s: %v", req.OrgID, err)
+			h.respondError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.logger.Printf("HTTP Handler: Minted api token %s for org %s with scopes %v", t.TokenID, t.OrgID, t.Scopes)
+		resp := newTokenPayload(t, time.Now())
+		resp.Token = secret
+		h.respondJSON(w, resp, http.StatusCreated)
+
+	case tokenID != "" && action == "rotate" && r.Method == http.MethodPost:
+		if !h.ownsToken(w, r, manager, tokenID, callerOrg) {
+			return
+		}
+		secret, t, graceEnd, err := manager.Rotate(r.Context(), tokenID)
+		if errors.Is(err, store.ErrAPITokenNotFound) {
+			h.respondError(w, "no active, unrotated api token "+tokenID, http.StatusNotFound)
+			return
+		} else if err != nil {
+			h.logger.Printf("HTTP Handler: Failed to rotate api token %s: %v", tokenID, err)
+			h.respondError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.logger.Printf("HTTP Handler: Rotated api token %s to %s, old token valid until %s", tokenID, t.TokenID, graceEnd.Format(time.RFC3339))
+		resp := newTokenPayload(t, time.Now())
+		resp.Token = secret
+		h.respondJSON(w, map[string]interface{}{
+			"token":                     resp,
+			"previous_token_id":         tokenID,
+			"previous_token_expires_at": graceEnd.UTC().Format(time.RFC3339Nano),
+		}, http.StatusCreated)
+
+	case tokenID != "" && action == "" && r.Method == http.MethodDelete:
+		if !h.ownsToken(w, r, manager, tokenID, callerOrg) {
+			return
+		}
+		if err := manager.Revoke(r.Context(), tokenID); err != nil {
+			h.logger.Printf("HTTP Handler: Failed to revoke api token %s: %v", tokenID, err)
+			h.respondError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.logger.Printf("HTTP Handler: Revoked api token %s", tokenID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ownsToken checks that a token exists and, for an org-bound caller, belongs
+// to its org, responding with the error if not
+func (h *LogHandler) ownsToken(w http.ResponseWriter, r *http.Request, manager *apitoken.Manager, tokenID, callerOrg string) bool {
+	t, err := manager.Get(r.Context(), tokenID)
+	if errors.Is(err, store.ErrAPITokenNotFound) {
+		h.respondError(w, err.Error(), http.StatusNotFound)
+		return false
+	} else if err != nil {
+		h.logger.Printf("HTTP Handler: Failed to get api token %s: %v", tokenID, err)
+		h.respondError(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if callerOrg != "" && t.OrgID != callerOrg {
+		// Reported as not found, so tokens of other orgs cannot be probed
+		h.respondError(w, store.ErrAPITokenNotFound.Error(), http.StatusNotFound)
+		return false
+	}
+	return true
+}
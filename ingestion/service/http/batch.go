@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tlng/errcatalog"
+	core "tlng/ingestion/service/core"
+)
+
+// batchPayload is the JSON body of a batch submission
+type batchPayload struct {
+	Entries []logPayload `json:"entries"`
+}
+
+// SubmitLogBatch handles POST /v1/logs:batch requests. Each entry is submitted
+// like a POST /v1/logs body and handed to the batch processor on its own, so
+// entries are accepted or rejected individually: the response is 202 if all of
+// them were accepted, otherwise 207 with the outcome of each entry.
+func (h *LogHandler) SubmitLogBatch(w http.ResponseWriter, r *http.Request) {
+	rec := &rejectionRecorder{ResponseWriter: w}
+	w = rec
+	var body []byte
+	headerOrgID := r.Header.Get("X-Client-Org-ID")
+	defer func() { h.captureRejection(rec, r, headerOrgID, body) }()
+
+	if r.Method != http.MethodPost {
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, size, ok := h.readBody(rec, r, headerOrgID)
+	if !ok {
+		return
+	}
+
+	var reqPayload batchPayload
+	if err := json.Unmarshal(body, &reqPayload); err != nil {
+		h.logger.Printf("HTTP Handler: Failed to parse JSON batch request: %v", err)
+		h.respondError(w, "Bad Request: Invalid JSON format", http.StatusBadRequest)
+		return
+	}
+	if len(reqPayload.Entries) == 0 {
+		h.respondError(w, "entries is required", http.StatusBadRequest)
+		return
+	}
+	if maxEntries := h.svc.HTTPBatchMaxEntries(); maxEntries > 0 && len(reqPayload.Entries) > maxEntries {
+		h.respondError(w, fmt.Sprintf("batch of %d entries exceeds the limit of %d", len(reqPayload.Entries), maxEntries), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Without an org from the ingress, the body must be within the limit of every org it names
+	if headerOrgID == "" {
+		checked := make(map[string]bool)
+		for _, entry := range reqPayload.Entries {
+			if checked[entry.ClientSourceOrgID] {
+				continue
+			}
+			checked[entry.ClientSourceOrgID] = true
+			if err := h.svc.CheckHTTPRequestSize(entry.ClientSourceOrgID, size); err != nil {
+				h.respondServiceError(w, r, err)
+				return
+			}
+		}
+	}
+
+	// Maintenance rejects every entry alike
+	if state := h.svc.Maintenance(); state.Enabled {
+		h.respondServiceError(w, r, &core.MaintenanceError{State: state})
+		return
+	}
+
+	testTraffic := testTrafficHeader(r)
+	lang := requestLanguage(r)
+	results := make([]map[string]interface{}, len(reqPayload.Entries))
+	var accepted int
+	var quota *core.QuotaStatus
+	var retryAfter time.Duration
+	for i, entry := range reqPayload.Entries {
+		sourceOrgID := headerOrgID
+		if sourceOrgID == "" {
+			sourceOrgID = entry.ClientSourceOrgID
+		}
+		input, clientTimestampFormat := entry.input(sourceOrgID)
+		if testTraffic {
+			input.TestTraffic = true
+		}
+		result, err := h.svc.SubmitLog(r.Context(), input)
+		if err != nil {
+			code, params, entryRetryAfter := h.svc.ErrorCode(err)
+			results[i] = map[string]interface{}{
+				"index":       i,
+				"status":      "REJECTED",
+				"status_code": errcatalog.Get(code).HTTPStatus,
+				"error":       err.Error(),
+				"code":        code,
+			}
+			if len(params) > 0 {
+				results[i]["params"] = params
+			}
+			if lang != "" {
+				results[i]["localized_message"] = errcatalog.Format(code, lang, params)
+			}
+			if entryRetryAfter > 0 {
+				results[i]["retry_after_seconds"] = int(math.Ceil(entryRetryAfter.Seconds()))
+				retryAfter = max(retryAfter, entryRetryAfter)
+			}
+			var quotaErr *core.QuotaError
+			if errors.As(err, &quotaErr) {
+				quota = &quotaErr.Status
+			}
+			continue
+		}
+		accepted++
+		results[i] = acceptedPayload(result, clientTimestampFormat)
+		results[i]["index"] = i
+		if result.Quota != nil {
+			quota = result.Quota
+		}
+	}
+
+	statusCode := http.StatusAccepted
+	if accepted < len(results) {
+		statusCode = http.StatusMultiStatus
+		h.logger.Printf("HTTP Handler: Batch of %d entries: %d accepted, %d rejected", len(results), accepted, len(results)-accepted)
+	}
+	setQuotaHeaders(w, quota)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	h.respondJSON(w, map[string]interface{}{
+		"accepted": accepted,
+		"rejected": len(results) - accepted,
+		"results":  results,
+	}, statusCode)
+}
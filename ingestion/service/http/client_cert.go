@@ -0,0 +1,27 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	core "tlng/ingestion/service/core"
+)
+
+// ClientCertOrg binds requests made with a verified client certificate to the
+// org named by field in its subject, so their submissions cannot name another
+// org. Requests whose certificate subject lacks the field are rejected.
+func ClientCertOrg(field string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		org, err := core.ClientCertOrg(r.TLS, field)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, "{\"error\":%q}\n", err.Error())
+			return
+		}
+		if org != "" {
+			r = r.WithContext(core.WithClientCertOrg(r.Context(), org))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
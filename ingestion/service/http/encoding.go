@@ -0,0 +1,63 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	core "tlng/ingestion/service/core"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// errUnsupportedEncoding indicates a Content-Encoding other than gzip or zstd
+var errUnsupportedEncoding = errors.New("unsupported Content-Encoding")
+
+// decodeBody decompresses a body sent with Content-Encoding encoding (gzip,
+// zstd, or none) to at most limit bytes; 0 is unlimited. Bodies larger once
+// decompressed fail with a *core.DecompressedSizeError.
+func decodeBody(body []byte, encoding string, limit int64) ([]byte, error) {
+	var zr io.Reader
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gz.Close()
+		zr = gz
+	case "zstd":
+		opts := []zstd.DOption{zstd.WithDecoderConcurrency(1)}
+		if limit > 0 {
+			opts = append(opts, zstd.WithDecoderMaxMemory(uint64(limit)))
+		}
+		zd, err := zstd.NewReader(bytes.NewReader(body), opts...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd body: %w", err)
+		}
+		defer zd.Close()
+		zr = zd
+	default:
+		return nil, fmt.Errorf("%w '%s' (must be gzip or zstd)", errUnsupportedEncoding, encoding)
+	}
+
+	if limit > 0 {
+		zr = io.LimitReader(zr, limit+1)
+	}
+	decoded, err := io.ReadAll(zr)
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+		return nil, &core.DecompressedSizeError{Limit: limit}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", encoding, err)
+	}
+	if limit > 0 && int64(len(decoded)) > limit {
+		return nil, &core.DecompressedSizeError{Limit: limit}
+	}
+	return decoded, nil
+}
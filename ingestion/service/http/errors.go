@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+
+	"tlng/errcatalog"
+)
+
+// catalogEntry is an error catalog entry with its message template in the requested language
+type catalogEntry struct {
+	errcatalog.Entry
+	LocalizedMessage string `json:"localized_message"`
+}
+
+// ErrorCatalog handles GET /v1/errors requests: the error catalog, with the
+// message templates in the language of the lang query parameter or of the
+// Accept-Language header, English by default
+func (h *LogHandler) ErrorCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	lang := errcatalog.Negotiate(r.URL.Query().Get("lang"))
+	if lang == "" {
+		lang = requestLanguage(r)
+	}
+	if lang == "" {
+		lang = "en"
+	}
+	var entries []catalogEntry
+	for _, e := range errcatalog.Entries() {
+		entries = append(entries, catalogEntry{Entry: e, LocalizedMessage: errcatalog.Template(e.Code, lang)})
+	}
+	h.respondJSON(w, map[string]interface{}{
+		"locale":    lang,
+		"languages": errcatalog.Languages(),
+		"errors":    entries,
+	}, http.StatusOK)
+}
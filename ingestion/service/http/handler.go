@@ -2,18 +2,28 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"tlng/config"
+	"tlng/errcatalog"
 	core "tlng/ingestion/service/core"
+	"tlng/internal/metrics"
 )
 
 // LogHandler encapsulates the logic for handling HTTP log requests
 type LogHandler struct {
 	svc    *core.Service
 	logger *log.Logger
+	otlp   *config.OTLPConfig // Set by SetOTLP
 }
 
 // NewLogHandler creates a new LogHandler
@@ -25,37 +35,31 @@ func NewLogHandler(s *core.Service, l *log.Logger) *LogHandler {
 func (h *LogHandler) SubmitLog(w http.ResponseWriter, r *http.Request) {
 	// start := time.Now()
 
+	// Rejections of bad submissions are offered for debug capture, with the
+	// body as read so far
+	rec := &rejectionRecorder{ResponseWriter: w}
+	w = rec
+	var body []byte
+	headerOrgID := r.Header.Get("X-Client-Org-ID")
+	defer func() { h.captureRejection(rec, r, headerOrgID, body) }()
+
 	if r.Method != http.MethodPost {
 		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Content-Type validation
-	if r.Header.Get("Content-Type") != "application/json" {
-		h.respondError(w, "Content-Type must be application/json", http.StatusBadRequest)
-		return
-	}
-
-	// Request size limit
-	if r.ContentLength > 10*1024*1024 { // 10MB limit
-		h.respondError(w, "Request body too large", http.StatusRequestEntityTooLarge)
+	body, size, ok := h.readBody(rec, r, headerOrgID)
+	if !ok {
 		return
 	}
 
 	// 1. Parse request body JSON
-	var reqPayload struct {
-		LogContent        string `json:"log_content"`
-		ClientLogHash     string `json:"client_log_hash,omitempty"`
-		ClientSourceOrgID string `json:"client_source_org_id,omitempty"`
-		ClientTimestamp   string `json:"client_timestamp,omitempty"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&reqPayload); err != nil {
+	var reqPayload logPayload
+	if err := json.Unmarshal(body, &reqPayload); err != nil {
 		h.logger.Printf("HTTP Handler: Failed to parse JSON request: %v", err)
 		h.respondError(w, "Bad Request: Invalid JSON format", http.StatusBadRequest)
 		return
 	}
-	defer r.Body.Close()
 
 	// 2. Validate required fields
 	if reqPayload.LogContent == "" {
@@ -64,26 +68,22 @@ func (h *LogHandler) SubmitLog(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 2.5. Get source_org_id from header (set by API Gateway) or from payload
-	sourceOrgID := r.Header.Get("X-Client-Org-ID")
+	sourceOrgID := headerOrgID
 	if sourceOrgID == "" {
 		sourceOrgID = reqPayload.ClientSourceOrgID
+		if err := h.svc.CheckHTTPRequestSize(sourceOrgID, size); err != nil {
+			h.respondServiceError(w, r, err)
+			return
+		}
 	}
 
 	// 3. Construct Service layer input
-	input := &core.LogInput{
-		LogContent:        reqPayload.LogContent,
-		ClientLogHash:     reqPayload.ClientLogHash,
-		ClientSourceOrgID: sourceOrgID,
+	input, clientTimestampFormat := reqPayload.input(sourceOrgID)
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		input.IdempotencyKey = key
 	}
-
-	// Parse optional timestamp
-	if reqPayload.ClientTimestamp != "" {
-		if ts, err := time.Parse(time.RFC3339Nano, reqPayload.ClientTimestamp); err == nil {
-			input.ClientTimestamp = &ts
-		} else {
-			h.logger.Printf("HTTP Handler: Invalid client_timestamp format: %v", err)
-			// Continue processing - invalid timestamp is not fatal
-		}
+	if testTrafficHeader(r) {
+		input.TestTraffic = true
 	}
 
 	// 4. Call Service layer processing logic
@@ -91,15 +91,11 @@ func (h *LogHandler) SubmitLog(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.logger.Printf("HTTP Handler: Service layer processing failed: %v", err)
 
-		// Map service errors to appropriate HTTP status codes
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "log_content cannot be empty" {
-			statusCode = http.StatusBadRequest
-		} else if matched, _ := regexp.MatchString(`client provided hash .* does not match`, err.Error()); matched {
-			statusCode = http.StatusBadRequest
+		var quotaErr *core.QuotaError
+		if errors.As(err, &quotaErr) {
+			setQuotaHeaders(w, &quotaErr.Status)
 		}
-
-		h.respondError(w, err.Error(), statusCode)
+		h.respondServiceError(w, r, err)
 		return
 	}
 
@@ -108,14 +104,224 @@ func (h *LogHandler) SubmitLog(w http.ResponseWriter, r *http.Request) {
 	// h.logger.Printf("HTTP Handler: Processed log submission in %v, request_id: %s", duration, result.RequestID)
 
 	// 6. Construct and return success response (HTTP 202 Accepted)
+	setQuotaHeaders(w, result.Quota)
+	h.respondJSON(w, acceptedPayload(result, clientTimestampFormat), http.StatusAccepted)
+}
+
+// acceptedPayload is the response describing an accepted submission
+func acceptedPayload(result *core.LogResult, clientTimestampFormat string) map[string]interface{} {
 	respPayload := map[string]interface{}{
 		"request_id":                result.RequestID,
 		"server_log_hash":           result.ServerLogHash,
 		"server_received_timestamp": result.ServerReceivedTimestamp.Format(time.RFC3339Nano),
 		"status":                    "ACCEPTED",
 	}
+	if result.ClientTimestamp != nil {
+		respPayload["client_timestamp"] = result.ClientTimestamp.Format(time.RFC3339Nano)
+		if clientTimestampFormat != "" {
+			respPayload["client_timestamp_format"] = clientTimestampFormat
+		}
+	}
+	if result.Duplicate {
+		respPayload["duplicate"] = true
+	}
+	// The submission stored under the external ID, as it stands
+	if a := result.Existing; a != nil {
+		respPayload["status"] = string(a.Status)
+		if a.TxHash != "" {
+			respPayload["tx_hash"] = a.TxHash
+			respPayload["block_height"] = a.BlockHeight
+		}
+	}
+	if m := result.Integrity; m != nil {
+		respPayload["hash_algorithm"] = m.HashAlgorithm
+		respPayload["canonicalization"] = m.Canonicalization
+		respPayload["service_version"] = m.ServiceVersion
+		respPayload["schema_version"] = m.SchemaVersion
+	}
+	return respPayload
+}
 
-	h.respondJSON(w, respPayload, http.StatusAccepted)
+// logPayload is the JSON body of a log submission, or one entry of a batch
+type logPayload struct {
+	LogContent        string          `json:"log_content"`
+	ClientLogHash     string          `json:"client_log_hash,omitempty"`
+	ClientSourceOrgID string          `json:"client_source_org_id,omitempty"`
+	ClientTimestamp   json.RawMessage `json:"client_timestamp,omitempty"` // String or number, see core.DetectClientTimestamp
+	IdempotencyKey    string          `json:"idempotency_key,omitempty"`
+	ExternalID        string          `json:"external_id,omitempty"`
+	Severity          string          `json:"severity,omitempty"`
+	SourceHost        string          `json:"source_host,omitempty"`
+	Application       string          `json:"application,omitempty"`
+	Sequence          int64           `json:"sequence,omitempty"`
+	TestTraffic       bool            `json:"test_traffic,omitempty"`
+}
+
+// input builds the Service layer input of a submission by sourceOrgID, and
+// returns the detected format of its client timestamp, if any
+func (p *logPayload) input(sourceOrgID string) (*core.LogInput, string) {
+	input := &core.LogInput{
+		LogContent:        p.LogContent,
+		ClientLogHash:     p.ClientLogHash,
+		ClientSourceOrgID: sourceOrgID,
+		IdempotencyKey:    p.IdempotencyKey,
+		ExternalID:        p.ExternalID,
+		Severity:          p.Severity,
+		SourceHost:        p.SourceHost,
+		Application:       p.Application,
+		Sequence:          p.Sequence,
+		TestTraffic:       p.TestTraffic,
+	}
+
+	// Parse optional timestamp; the service's timestamp policy decides how parse errors are handled
+	var clientTimestampFormat string
+	if raw := clientTimestampValue(p.ClientTimestamp); raw != "" {
+		if ts, format, err := core.DetectClientTimestamp(raw); err == nil {
+			input.ClientTimestamp = &ts
+			clientTimestampFormat = format
+		} else {
+			input.ClientTimestampErr = err
+		}
+	}
+	return input, clientTimestampFormat
+}
+
+// testTrafficHeader reports whether the X-Test-Traffic header flags the
+// request's submissions as test traffic
+func testTrafficHeader(r *http.Request) bool {
+	flag, _ := strconv.ParseBool(r.Header.Get("X-Test-Traffic"))
+	return flag
+}
+
+// requestLanguage returns the catalog language matching the Accept-Language
+// header of r, "" if none does or r is nil
+func requestLanguage(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return errcatalog.Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// submitErrorStatus returns the HTTP status of the catalog code of a Service
+// layer submission error, and the time after which the client may retry, 0 if
+// not applicable; for responses that report errors in their own body, like OTLP
+// partial success
+func (h *LogHandler) submitErrorStatus(err error) (int, time.Duration) {
+	code, _, retryAfter := h.svc.ErrorCode(err)
+	return errcatalog.Get(code).HTTPStatus, retryAfter
+}
+
+// readBody reads a JSON submission body within the request size limit: the
+// org's if the ingress named it, otherwise the largest of any org until the
+// payload names the org. A gzip or zstd body (Content-Encoding) is
+// decompressed within the same limit. It returns the body and its size
+// decompressed, to check against the org's limit; it responds with the error
+// and returns false if the body cannot be read.
+func (h *LogHandler) readBody(rec *rejectionRecorder, r *http.Request, headerOrgID string) ([]byte, int64, bool) {
+	// Content-Type validation
+	if r.Header.Get("Content-Type") != "application/json" {
+		h.respondError(rec, "Content-Type must be application/json", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	return h.readLimitedBody(rec, r, headerOrgID)
+}
+
+// readLimitedBody reads a submission body of any content type within the
+// request size limit, like readBody
+func (h *LogHandler) readLimitedBody(rec *rejectionRecorder, r *http.Request, headerOrgID string) ([]byte, int64, bool) {
+	limit := &core.RequestSizeError{OrgID: headerOrgID, Limit: h.svc.HTTPBodyLimit()}
+	if headerOrgID != "" {
+		limit.Limit, limit.Tier = h.svc.HTTPRequestLimit(headerOrgID)
+	}
+	if limit.Limit > 0 && r.ContentLength > limit.Limit {
+		limit.Size = r.ContentLength
+		h.respondServiceError(rec, r, limit)
+		return nil, 0, false
+	}
+	var bodyReader io.Reader = r.Body
+	if limit.Limit > 0 {
+		bodyReader = http.MaxBytesReader(rec.ResponseWriter, r.Body, limit.Limit)
+	}
+	body, err := io.ReadAll(bodyReader)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.respondServiceError(rec, r, limit) // Cut off at the limit, size unknown
+			return body, 0, false
+		}
+		h.respondError(rec, "Bad Request: Failed to read request body", http.StatusBadRequest)
+		return body, 0, false
+	}
+
+	// The org's limit applies to the body decompressed, so a small compressed body cannot expand past it
+	decoded, err := decodeBody(body, r.Header.Get("Content-Encoding"), limit.Limit)
+	if err != nil {
+		var sizeErr *core.DecompressedSizeError
+		switch {
+		case errors.As(err, &sizeErr):
+			sizeErr.OrgID, sizeErr.Tier = limit.OrgID, limit.Tier
+			h.respondServiceError(rec, r, sizeErr)
+		case errors.Is(err, errUnsupportedEncoding):
+			h.respondError(rec, err.Error(), http.StatusUnsupportedMediaType)
+		default:
+			h.respondError(rec, fmt.Sprintf("Bad Request: %v", err), http.StatusBadRequest)
+		}
+		return body, 0, false
+	}
+	return decoded, int64(len(decoded)), true
+}
+
+// captureRejection offers a submission rejected as malformed or too large for
+// debug capture, with the body as read so far
+func (h *LogHandler) captureRejection(rec *rejectionRecorder, r *http.Request, headerOrgID string, body []byte) {
+	if rec.status != http.StatusBadRequest && rec.status != http.StatusRequestEntityTooLarge {
+		return
+	}
+	orgID := headerOrgID
+	if orgID == "" {
+		orgID = payloadOrgID(body)
+	}
+	h.svc.CaptureRejection(core.Rejection{
+		OrgID:       orgID,
+		Transport:   "http",
+		StatusCode:  rec.status,
+		Reason:      rec.reason,
+		ContentType: r.Header.Get("Content-Type"),
+		Payload:     body,
+	})
+}
+
+// clientTimestampValue returns the client_timestamp field as text: the string
+// value, or the digits of a number. null and "" are treated as absent.
+func clientTimestampValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	v := strings.TrimSpace(string(raw))
+	if v == "null" {
+		return ""
+	}
+	return v
+}
+
+// LimitInFlight wraps a write handler with the service's in-flight limiter.
+// Submissions beyond the limit are rejected with 503 and Retry-After before
+// their body is read.
+func (h *LogHandler) LimitInFlight(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		release, err := h.svc.AcquireInFlight(r.Context())
+		if err != nil {
+			if errors.Is(err, core.ErrOverloaded) {
+				h.respondServiceError(w, r, err)
+				return
+			}
+			h.respondError(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+		next(w, r)
+	}
 }
 
 // HealthCheck handles GET /health requests
@@ -134,23 +340,61 @@ func (h *LogHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, resp, http.StatusOK)
 }
 
-// Metrics handles GET /metrics requests (basic metrics)
+// Metrics handles GET /metrics requests in the Prometheus text format, or as a JSON stats summary
 func (h *LogHandler) Metrics(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Basic metrics - in production, use proper metrics library
+	// Prometheus exposition by default; the JSON stats summary with ?format=json
+	if r.URL.Query().Get("format") != "json" {
+		metrics.Default.Handler().ServeHTTP(w, r)
+		return
+	}
+
 	resp := map[string]interface{}{
 		"timestamp": time.Now().Unix(),
 		"service":   "api-gateway",
 		"version":   "1.0.0",
 	}
+	resp["maintenance"] = h.svc.Maintenance().Enabled
+	resp["batch_processor"] = h.svc.BatchStats()
+	if stats, ok := h.svc.LargeBatchStats(); ok {
+		resp["batch_processor_large"] = stats
+	}
+	if stats, ok := h.svc.CanaryBatchStats(); ok {
+		resp["batch_processor_canary"] = stats
+	}
+	if stats, ok := h.svc.DedupStats(); ok {
+		resp["dedup"] = stats
+	}
+	if stats, ok := h.svc.DeliveryStats(); ok {
+		resp["kafka_producer"] = stats
+	}
+	if stats, ok := h.svc.DegradedStats(); ok {
+		resp["degraded_acceptance"] = stats
+	}
+	if stats, ok := h.svc.InFlightStats(); ok {
+		resp["in_flight"] = stats
+	}
+	if stats, ok := h.svc.LoadSheddingStats(); ok {
+		resp["load_shedding"] = stats
+	}
 
 	h.respondJSON(w, resp, http.StatusOK)
 }
 
+// setQuotaHeaders adds the org's rate-limit and quota headers, if quotas are enabled
+func setQuotaHeaders(w http.ResponseWriter, st *core.QuotaStatus) {
+	if st == nil {
+		return
+	}
+	for name, value := range st.Headers() {
+		w.Header().Set(name, value)
+	}
+}
+
 // respondJSON sends JSON response
 func (h *LogHandler) respondJSON(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
@@ -164,11 +408,55 @@ func (h *LogHandler) respondJSON(w http.ResponseWriter, data interface{}, status
 
 // respondError sends error response
 func (h *LogHandler) respondError(w http.ResponseWriter, message string, statusCode int) {
-	errorResp := map[string]interface{}{
-		"error":   message,
-		"status":  statusCode,
-		"message": http.StatusText(statusCode),
+	h.respondCodedError(w, nil, message, statusCode, errcatalog.ForHTTPStatus(statusCode), nil)
+}
+
+// respondServiceError sends a Service layer error with its catalog code and
+// HTTP status, and Retry-After if the client may retry
+func (h *LogHandler) respondServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	code, params, retryAfter := h.svc.ErrorCode(err)
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
 	}
+	h.respondCodedError(w, r, err.Error(), errcatalog.Get(code).HTTPStatus, code, params)
+}
+
+// respondCodedError sends an error with its catalog code and message
+// parameters, and the message in the language of r's Accept-Language if the
+// catalog has it
+func (h *LogHandler) respondCodedError(w http.ResponseWriter, r *http.Request, message string, statusCode int, code errcatalog.Code, params map[string]string) {
+	var acceptLanguage string
+	if r != nil {
+		acceptLanguage = r.Header.Get("Accept-Language")
+	}
+	errorResp := errcatalog.NewHTTPError(code, params, message, statusCode, acceptLanguage)
 
+	if rec, ok := w.(*rejectionRecorder); ok {
+		rec.reason = message
+	}
 	h.respondJSON(w, errorResp, statusCode)
 }
+
+// rejectionRecorder records the status and error message of a response
+type rejectionRecorder struct {
+	http.ResponseWriter
+	status int
+	reason string
+}
+
+func (r *rejectionRecorder) WriteHeader(statusCode int) {
+	r.status = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// payloadOrgIDPattern matches the client_source_org_id field of a JSON body
+var payloadOrgIDPattern = regexp.MustCompile(`"client_source_org_id"\s*:\s*"([^"\\]*)"`)
+
+// payloadOrgID extracts client_source_org_id from a body without parsing it
+// as a whole, which may be malformed or cut off
+func payloadOrgID(body []byte) string {
+	if m := payloadOrgIDPattern.FindSubmatch(body); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"tlng/config"
+	core "tlng/ingestion/service/core"
+	"tlng/internal/models"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLP/HTTP content types
+const (
+	otlpProtobuf = "application/x-protobuf"
+	otlpJSON     = "application/json"
+)
+
+// SetOTLP enables POST /v1/otlp/logs with the given attribute mapping
+func (h *LogHandler) SetOTLP(cfg config.OTLPConfig) {
+	h.otlp = &cfg
+}
+
+// SubmitOTLPLogs handles POST /v1/otlp/logs requests: an OTLP/HTTP
+// ExportLogsServiceRequest, in protobuf or JSON, optionally gzip or zstd compressed. Each log
+// record is submitted on its own to the org named by its resource; records
+// that are rejected are reported as a partial success. If none was accepted
+// and the gateway asked to retry later, the request fails with 429 or 503 and
+// Retry-After, which OTLP exporters retry.
+func (h *LogHandler) SubmitOTLPLogs(w http.ResponseWriter, r *http.Request) {
+	rec := &rejectionRecorder{ResponseWriter: w}
+	w = rec
+	var body []byte
+	headerOrgID := r.Header.Get("X-Client-Org-ID")
+	defer func() { h.captureRejection(rec, r, headerOrgID, body) }()
+
+	if r.Method != http.MethodPost {
+		h.respondError(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType != otlpProtobuf && contentType != otlpJSON {
+		h.respondError(w, fmt.Sprintf("Content-Type must be %s or %s", otlpProtobuf, otlpJSON), http.StatusUnsupportedMediaType)
+		return
+	}
+	body, size, ok := h.readLimitedBody(rec, r, headerOrgID)
+	if !ok {
+		return
+	}
+
+	var req collogspb.ExportLogsServiceRequest
+	var err error
+	if contentType == otlpJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &req)
+	} else {
+		err = proto.Unmarshal(body, &req)
+	}
+	if err != nil {
+		h.logger.Printf("HTTP Handler: Failed to decode OTLP logs request: %v", err)
+		h.respondError(w, "Bad Request: invalid ExportLogsServiceRequest", http.StatusBadRequest)
+		return
+	}
+
+	// Flatten the records, each with the org, host and application of its resource
+	var inputs []*core.LogInput
+	orgs := make(map[string]bool)
+	for _, rl := range req.GetResourceLogs() {
+		attrs := stringAttributes(rl.GetResource().GetAttributes())
+		sourceOrgID := headerOrgID
+		if sourceOrgID == "" {
+			sourceOrgID = attrs[h.otlp.OrgAttribute]
+		}
+		orgs[sourceOrgID] = true
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				input := otlpInput(lr, sourceOrgID)
+				input.SourceHost = attrs[h.otlp.HostAttribute]
+				input.Application = attrs[h.otlp.ApplicationAttribute]
+				inputs = append(inputs, input)
+			}
+		}
+	}
+	if maxEntries := h.svc.HTTPBatchMaxEntries(); maxEntries > 0 && len(inputs) > maxEntries {
+		h.respondError(w, fmt.Sprintf("request of %d log records exceeds the limit of %d", len(inputs), maxEntries), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if headerOrgID == "" {
+		for org := range orgs {
+			if err := h.svc.CheckHTTPRequestSize(org, size); err != nil {
+				h.respondServiceError(w, r, err)
+				return
+			}
+		}
+	}
+	if state := h.svc.Maintenance(); state.Enabled {
+		h.respondServiceError(w, r, &core.MaintenanceError{State: state})
+		return
+	}
+
+	testTraffic := testTrafficHeader(r)
+	var accepted, rejected int
+	var firstErr error
+	var quota *core.QuotaStatus
+	var retryErr error // The rejection asking to retry the latest, if any
+	var retryAfter time.Duration
+	for _, input := range inputs {
+		if testTraffic {
+			input.TestTraffic = true
+		}
+		result, err := h.svc.SubmitLog(r.Context(), input)
+		if err != nil {
+			rejected++
+			if firstErr == nil {
+				firstErr = err
+			}
+			statusCode, entryRetryAfter := h.submitErrorStatus(err)
+			if (statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable) && (retryErr == nil || entryRetryAfter > retryAfter) {
+				retryErr, retryAfter = err, entryRetryAfter
+			}
+			var quotaErr *core.QuotaError
+			if errors.As(err, &quotaErr) {
+				quota = &quotaErr.Status
+			}
+			continue
+		}
+		accepted++
+		if result.Quota != nil {
+			quota = result.Quota
+		}
+	}
+	setQuotaHeaders(w, quota)
+
+	if accepted == 0 && retryErr != nil {
+		h.respondServiceError(w, r, retryErr)
+		return
+	}
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if rejected > 0 {
+		h.logger.Printf("HTTP Handler: OTLP request of %d log records: %d accepted, %d rejected (first: %v)", len(inputs), accepted, rejected, firstErr)
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: int64(rejected),
+			ErrorMessage:       firstErr.Error(),
+		}
+	}
+	h.respondOTLP(w, contentType, resp)
+}
+
+// otlpInput converts a log record to the Service layer input for sourceOrgID
+func otlpInput(lr *logspb.LogRecord, sourceOrgID string) *core.LogInput {
+	input := &core.LogInput{
+		LogContent:        anyValueContent(lr.GetBody()),
+		ClientSourceOrgID: sourceOrgID,
+		Severity:          otlpSeverity(lr),
+	}
+	nanos := lr.GetTimeUnixNano()
+	if nanos == 0 {
+		nanos = lr.GetObservedTimeUnixNano()
+	}
+	if nanos != 0 {
+		ts := time.Unix(0, int64(nanos)).UTC()
+		input.ClientTimestamp = &ts
+	}
+	return input
+}
+
+// otlpSeverity maps a record's severity number, or failing that its severity
+// text, to a log severity; "" if neither is usable
+func otlpSeverity(lr *logspb.LogRecord) string {
+	switch n := lr.GetSeverityNumber(); {
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return models.SeverityCritical
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return models.SeverityError
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return models.SeverityWarning
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return models.SeverityInfo
+	case n >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return models.SeverityDebug
+	}
+	severity, err := models.ParseSeverity(lr.GetSeverityText())
+	if err != nil {
+		return ""
+	}
+	return severity
+}
+
+// anyValueContent is the attested content of a record body: a string body
+// as is, any other body as JSON
+func anyValueContent(v *commonpb.AnyValue) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.GetValue().(*commonpb.AnyValue_StringValue); ok {
+		return s.StringValue
+	}
+	content, err := json.Marshal(anyValue(v))
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+// anyValue converts an OTLP value to its plain Go form
+func anyValue(v *commonpb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return v.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(v.ArrayValue.GetValues()))
+		for i, e := range v.ArrayValue.GetValues() {
+			values[i] = anyValue(e)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = anyValue(kv.GetValue())
+		}
+		return values
+	}
+	return nil
+}
+
+// stringAttributes returns the string-valued attributes by key
+func stringAttributes(attrs []*commonpb.KeyValue) map[string]string {
+	values := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		if s, ok := kv.GetValue().GetValue().(*commonpb.AnyValue_StringValue); ok {
+			values[kv.GetKey()] = s.StringValue
+		}
+	}
+	return values
+}
+
+// respondOTLP sends an OTLP response in the content type of the request
+func (h *LogHandler) respondOTLP(w http.ResponseWriter, contentType string, resp proto.Message) {
+	var data []byte
+	var err error
+	if contentType == otlpJSON {
+		data, err = protojson.Marshal(resp)
+	} else {
+		data, err = proto.Marshal(resp)
+	}
+	if err != nil {
+		h.logger.Printf("HTTP Handler: Failed to encode OTLP response: %v", err)
+		h.respondError(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,190 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frame formats
+const (
+	FormatRFC5424 = "rfc5424"
+	FormatRFC3164 = "rfc3164"
+)
+
+// ErrInvalidFrame indicates a frame without a valid PRI or RFC 5424 header
+var ErrInvalidFrame = errors.New("invalid syslog frame")
+
+// Message is a parsed syslog frame. Header fields the frame leaves out ("-"
+// in RFC 5424) are empty.
+type Message struct {
+	Format         string
+	Facility       int        // 0 (kern) to 23 (local7), see config.SyslogFacilities
+	Severity       int        // 0 (emergency) to 7 (debug)
+	Timestamp      *time.Time // nil if the frame has none
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData string // RFC 5424 only, as sent
+	Message        string
+}
+
+// Parse parses an RFC 5424 frame, or failing the version an RFC 3164 one,
+// without its transport framing. RFC 3164 timestamps carry no year or zone:
+// they are read in now's location and year, or the year before if that puts
+// them more than a day ahead of now. A frame whose RFC 3164 header cannot be
+// read keeps everything after the PRI as its message.
+func Parse(frame string, now time.Time) (*Message, error) {
+	if !strings.HasPrefix(frame, "<") {
+		return nil, fmt.Errorf("%w: missing PRI", ErrInvalidFrame)
+	}
+	end := strings.IndexByte(frame, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("%w: malformed PRI", ErrInvalidFrame)
+	}
+	pri, err := strconv.Atoi(frame[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, fmt.Errorf("%w: PRI out of range", ErrInvalidFrame)
+	}
+	msg := &Message{Facility: pri / 8, Severity: pri % 8}
+	rest := frame[end+1:]
+	if strings.HasPrefix(rest, "1 ") {
+		msg.Format = FormatRFC5424
+		if err := parseRFC5424(msg, rest[2:]); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+	msg.Format = FormatRFC3164
+	parseRFC3164(msg, rest, now)
+	return msg, nil
+}
+
+// parseRFC5424 reads TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [SP MSG]
+func parseRFC5424(msg *Message, rest string) error {
+	var fields [5]string
+	for i := range fields {
+		field, tail, ok := strings.Cut(rest, " ")
+		if !ok || field == "" {
+			return fmt.Errorf("%w: truncated RFC 5424 header", ErrInvalidFrame)
+		}
+		if field != "-" {
+			fields[i] = field
+		}
+		rest = tail
+	}
+	if fields[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: timestamp: %v", ErrInvalidFrame, err)
+		}
+		msg.Timestamp = &ts
+	}
+	msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	sd, tail, err := structuredData(rest)
+	if err != nil {
+		return err
+	}
+	if sd != "-" {
+		msg.StructuredData = sd
+	}
+	if tail != "" {
+		if tail[0] != ' ' {
+			return fmt.Errorf("%w: no space after structured data", ErrInvalidFrame)
+		}
+		msg.Message = strings.TrimPrefix(tail[1:], "\ufeff") // UTF-8 BOM
+	}
+	return nil
+}
+
+// structuredData splits the STRUCTURED-DATA field, "-" or one or more
+// [SD-ELEMENT]s, from the rest of the frame
+func structuredData(s string) (string, string, error) {
+	if strings.HasPrefix(s, "-") {
+		return "-", s[1:], nil
+	}
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		quoted := false
+		for i++; ; i++ {
+			if i >= len(s) {
+				return "", "", fmt.Errorf("%w: unterminated structured data", ErrInvalidFrame)
+			}
+			c := s[i]
+			if quoted && c == '\\' {
+				i++ // Escaped ", \ or ]
+				continue
+			}
+			if c == '"' {
+				quoted = !quoted
+			} else if c == ']' && !quoted {
+				i++
+				break
+			}
+		}
+	}
+	if i == 0 {
+		return "", "", fmt.Errorf("%w: malformed structured data", ErrInvalidFrame)
+	}
+	return s[:i], s[i:], nil
+}
+
+// rfc3164Stamp is the RFC 3164 TIMESTAMP, the day of month space-padded
+const rfc3164Stamp = "Jan _2 15:04:05"
+
+// parseRFC3164 reads TIMESTAMP HOSTNAME TAG[PID]: MSG. Relays also send
+// RFC 3339 timestamps, and some senders leave the header out.
+func parseRFC3164(msg *Message, rest string, now time.Time) {
+	msg.Message = rest
+	if len(rest) > len(rfc3164Stamp) && rest[len(rfc3164Stamp)] == ' ' {
+		if ts, err := time.ParseInLocation(rfc3164Stamp, rest[:len(rfc3164Stamp)], now.Location()); err == nil {
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0) // December logs read in January
+			}
+			msg.Timestamp = &ts
+			rest = rest[len(rfc3164Stamp)+1:]
+		}
+	}
+	if msg.Timestamp == nil {
+		stamp, tail, _ := strings.Cut(rest, " ")
+		ts, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			return
+		}
+		msg.Timestamp = &ts
+		rest = tail
+	}
+
+	hostname, tail, ok := strings.Cut(rest, " ")
+	if !ok || hostname == "" {
+		msg.Message = rest
+		return
+	}
+	msg.Hostname = hostname
+	msg.Message = tail
+
+	// TAG is up to 32 alphanumeric characters, followed by [PID] and/or ':'
+	tagEnd := strings.IndexAny(tail, "[: ")
+	if tagEnd <= 0 || tagEnd > 32 {
+		return
+	}
+	tag, after := tail[:tagEnd], tail[tagEnd:]
+	if strings.HasPrefix(after, "[") {
+		pid, afterPID, ok := strings.Cut(after[1:], "]")
+		if !ok {
+			return
+		}
+		msg.ProcID, after = pid, afterPID
+	}
+	if !strings.HasPrefix(after, ":") {
+		msg.ProcID = ""
+		return
+	}
+	msg.AppName = tag
+	msg.Message = strings.TrimPrefix(after[1:], " ")
+}
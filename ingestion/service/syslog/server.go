@@ -0,0 +1,384 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tlng/config"
+	core "tlng/ingestion/service/core"
+	"tlng/internal/metrics"
+	"tlng/internal/models"
+)
+
+// Outcomes of a received frame
+const (
+	OutcomeAccepted   = "accepted"   // Submitted to the core service
+	OutcomeRejected   = "rejected"   // Rejected by the core service (validation, quota, maintenance, ...)
+	OutcomeOverloaded = "overloaded" // Rejected by the in-flight limiter
+	OutcomeUnparsed   = "unparsed"   // No valid PRI or RFC 5424 header
+	OutcomeNoOrg      = "no_org"     // Matched no org rule and there is no default org
+	OutcomeTooLarge   = "too_large"  // Longer than max_message_size
+)
+
+var syslogFrames = metrics.NewCounter("gateway_syslog_frames_total",
+	"Syslog frames received, by transport (udp, tcp, tls) and outcome (accepted, rejected, overloaded, unparsed, no_org, too_large).", "transport", "outcome")
+
+// Server receives syslog frames over UDP and TCP, optionally over TLS, and
+// submits each one to the core service. Syslog has no way to answer a sender,
+// so frames that are not accepted are dropped, counted and logged.
+type Server struct {
+	svc    *core.Service
+	cfg    config.SyslogConfig
+	logger *log.Logger
+
+	udp       net.PacketConn
+	tcp       net.Listener
+	transport string // Of the TCP listener: tcp or tls
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	stopping atomic.Bool
+	handlers sync.WaitGroup // Open TCP connections
+	lastDrop atomic.Int64   // Unix nanoseconds of the last logged drop
+}
+
+// NewServer creates a syslog server submitting to s
+func NewServer(s *core.Service, cfg config.SyslogConfig, l *log.Logger) *Server {
+	return &Server{svc: s, cfg: cfg, logger: l, conns: make(map[net.Conn]struct{})}
+}
+
+// Listen binds the configured UDP and TCP listeners
+func (s *Server) Listen() error {
+	if s.cfg.UDPListenAddr != "" {
+		udp, err := net.ListenPacket("udp", s.cfg.UDPListenAddr)
+		if err != nil {
+			return fmt.Errorf("unable to listen on syslog UDP port %s: %w", s.cfg.UDPListenAddr, err)
+		}
+		s.udp = udp
+	}
+	if s.cfg.TCPListenAddr != "" {
+		tlsCfg, err := s.cfg.TLS.TLSConfig()
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("failed to load syslog tls: %w", err)
+		}
+		tcp, err := net.Listen("tcp", s.cfg.TCPListenAddr)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("unable to listen on syslog TCP port %s: %w", s.cfg.TCPListenAddr, err)
+		}
+		s.tcp, s.transport = tcp, "tcp"
+		if tlsCfg != nil {
+			s.tcp, s.transport = tls.NewListener(tcp, tlsCfg), "tls"
+		}
+	}
+	return nil
+}
+
+// UDPAddr returns the address of the UDP listener, or nil without one
+func (s *Server) UDPAddr() net.Addr {
+	if s.udp == nil {
+		return nil
+	}
+	return s.udp.LocalAddr()
+}
+
+// TCPAddr returns the address of the TCP listener, or nil without one
+func (s *Server) TCPAddr() net.Addr {
+	if s.tcp == nil {
+		return nil
+	}
+	return s.tcp.Addr()
+}
+
+// Serve receives frames until Shutdown; it returns the first listener error
+func (s *Server) Serve() error {
+	errs := make(chan error, 2)
+	var listeners sync.WaitGroup
+	if s.udp != nil {
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			errs <- s.serveUDP()
+		}()
+	}
+	if s.tcp != nil {
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			errs <- s.serveTCP()
+		}()
+	}
+	listeners.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown stops receiving frames and waits, until ctx is done, for the TCP
+// connections to submit the frame in hand; then they are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopping.Store(true)
+	s.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.SetReadDeadline(time.Now()) // Unblocks readers waiting for the next frame
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Close closes the listeners
+func (s *Server) Close() {
+	if s.udp != nil {
+		s.udp.Close()
+	}
+	if s.tcp != nil {
+		s.tcp.Close()
+	}
+}
+
+// serveUDP submits one frame per datagram
+func (s *Server) serveUDP() error {
+	buf := make([]byte, s.cfg.MaxMessageSize+1)
+	for {
+		n, _, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			if s.stopping.Load() {
+				return nil
+			}
+			return fmt.Errorf("syslog UDP listener failed: %w", err)
+		}
+		if n > s.cfg.MaxMessageSize {
+			s.drop("udp", OutcomeTooLarge, fmt.Errorf("datagram longer than %d bytes", s.cfg.MaxMessageSize))
+			continue
+		}
+		s.submit(context.Background(), "udp", strings.TrimRight(string(buf[:n]), "\r\n\x00"), "")
+	}
+}
+
+// serveTCP accepts connections until the listener is closed
+func (s *Server) serveTCP() error {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if s.stopping.Load() {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return fmt.Errorf("syslog TCP listener failed: %w", err)
+		}
+		s.mu.Lock()
+		if s.stopping.Load() {
+			s.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		s.conns[conn] = struct{}{}
+		s.handlers.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn reads the frames of a connection, each octet-counted or
+// newline-delimited (RFC 6587), until it is closed or idle
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+		s.handlers.Done()
+	}()
+
+	// Bind mutual TLS senders to the org of their client certificate
+	var certOrg string
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn.SetDeadline(time.Now().Add(s.cfg.IdleTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			s.logger.Printf("Syslog: TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+			return
+		}
+		conn.SetDeadline(time.Time{})
+		if s.cfg.TLS.OrgFrom != "" {
+			state := tlsConn.ConnectionState()
+			org, err := core.ClientCertOrg(&state, s.cfg.TLS.OrgFrom)
+			if err != nil {
+				s.logger.Printf("Syslog: rejected connection from %s: %v", conn.RemoteAddr(), err)
+				return
+			}
+			certOrg = org
+		}
+	}
+	ctx := context.Background()
+	if certOrg != "" {
+		ctx = core.WithClientCertOrg(ctx, certOrg)
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		// Checked after arming the deadline, so a Shutdown in between still unblocks the read
+		conn.SetReadDeadline(time.Now().Add(s.cfg.IdleTimeout))
+		if s.stopping.Load() {
+			return
+		}
+		frame, err := s.readFrame(r)
+		if errors.Is(err, errFrameTooLarge) {
+			s.drop(s.transport, OutcomeTooLarge, err)
+			continue
+		}
+		if err != nil {
+			var netErr net.Error
+			if err != io.EOF && !(errors.As(err, &netErr) && netErr.Timeout()) {
+				s.logger.Printf("Syslog: connection from %s failed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if frame != "" {
+			s.submit(ctx, s.transport, frame, certOrg)
+		}
+	}
+}
+
+// errFrameTooLarge indicates a frame longer than max_message_size, which was skipped
+var errFrameTooLarge = errors.New("frame too large")
+
+// readFrame reads the next frame: MSG-LEN SP MSG if it starts with a digit,
+// otherwise up to the next LF. Frames too large are skipped.
+func (s *Server) readFrame(r *bufio.Reader) (string, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil || len(prefix) > 11 {
+			return "", fmt.Errorf("invalid octet count %q", prefix)
+		}
+		if n > s.cfg.MaxMessageSize {
+			if _, err := r.Discard(n); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("%w: %d octets, limit %d", errFrameTooLarge, n, s.cfg.MaxMessageSize)
+		}
+		frame := make([]byte, n)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(frame), "\r\n"), nil
+	}
+
+	var frame []byte
+	tooLarge := false
+	for {
+		line, err := r.ReadSlice('\n')
+		if !tooLarge {
+			frame = append(frame, line...)
+			if len(frame) > s.cfg.MaxMessageSize+1 { // +1 for the LF
+				frame, tooLarge = nil, true
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && (err != io.EOF || len(frame) == 0) {
+			return "", err
+		}
+		break
+	}
+	if tooLarge {
+		return "", fmt.Errorf("%w: limit %d bytes", errFrameTooLarge, s.cfg.MaxMessageSize)
+	}
+	return strings.TrimRight(string(frame), "\r\n\x00"), nil
+}
+
+// submit parses a frame and submits it to the org of its certificate or org rules
+func (s *Server) submit(ctx context.Context, transport, frame, certOrg string) {
+	msg, err := Parse(frame, time.Now())
+	if err != nil {
+		s.drop(transport, OutcomeUnparsed, err)
+		return
+	}
+	org := certOrg
+	if org == "" {
+		org = s.cfg.OrgFor(msg.Hostname, msg.Facility)
+	}
+	if org == "" {
+		s.drop(transport, OutcomeNoOrg, fmt.Errorf("no org for hostname %q, facility %s", msg.Hostname, config.SyslogFacilities[msg.Facility]))
+		return
+	}
+
+	input := &core.LogInput{
+		LogContent:        frame,
+		ClientSourceOrgID: org,
+		ClientTimestamp:   msg.Timestamp,
+		Severity:          models.Severities[len(models.Severities)-1-msg.Severity],
+		SourceHost:        msg.Hostname,
+		Application:       msg.AppName,
+	}
+	if s.cfg.Content == config.SyslogContentMessage {
+		input.LogContent = msg.Message
+	}
+
+	release, err := s.svc.AcquireInFlight(ctx)
+	if err != nil {
+		s.drop(transport, OutcomeOverloaded, err)
+		return
+	}
+	defer release()
+	if _, err := s.svc.SubmitLog(ctx, input); err != nil {
+		s.drop(transport, OutcomeRejected, err)
+		return
+	}
+	syslogFrames.Inc(transport, OutcomeAccepted)
+}
+
+// drop counts a frame that was not accepted and logs it, at most once a second
+func (s *Server) drop(transport, outcome string, err error) {
+	syslogFrames.Inc(transport, outcome)
+	now := time.Now().UnixNano()
+	last := s.lastDrop.Load()
+	if now-last >= int64(time.Second) && s.lastDrop.CompareAndSwap(last, now) {
+		s.logger.Printf("Syslog: dropped %s frame (%s): %v", transport, outcome, err)
+	}
+}
@@ -33,9 +33,9 @@ local function get_required_permission()
     local method = ngx.var.request_method or ""
     local uri = ngx.var.uri or ""
 
-    if method == "POST" and uri == "/v1/logs" then
+    if method == "POST" and (uri == "/v1/logs" or uri == "/v1/logs:batch") then
         return "submit_log"
-    elseif method == "GET" and uri:find("^/v1/query/status/") then
+    elseif method == "GET" and (uri:find("^/v1/query/status/") or uri:find("^/v1/logs/[^/:]+$")) then
         return "query_status"
     elseif method == "POST" and uri == "/v1/query_by_content" then
         return "query_by_content"
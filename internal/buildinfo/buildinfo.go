@@ -0,0 +1,35 @@
+// Package buildinfo reports the version of the running binary
+package buildinfo
+
+import "runtime/debug"
+
+// Version returns the module version of the binary, or the VCS revision it was
+// built from ("-dirty" if the tree had local changes), or "dev" if neither is known
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when advanced. Its timers and tickers fire
+// during Advance, dropping ticks the receiver has not taken like time.Ticker.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters map[*fakeWaiter]struct{}
+}
+
+// fakeWaiter is a pending timer or ticker of a Fake clock
+type fakeWaiter struct {
+	clock  *Fake
+	c      chan time.Time
+	at     time.Time
+	period time.Duration // Tickers only
+}
+
+// NewFake creates a fake clock set to start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start, waiters: make(map[*fakeWaiter]struct{})}
+}
+
+// Now returns the fake clock's time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d and fires the timers and tickers due by then
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for w := range f.waiters {
+		if w.at.After(f.now) {
+			continue
+		}
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		if w.period <= 0 {
+			delete(f.waiters, w)
+			continue
+		}
+		for !w.at.After(f.now) {
+			w.at = w.at.Add(w.period)
+		}
+	}
+}
+
+// NewTimer creates a timer that fires once the clock is advanced by d
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1)}
+	w.Reset(d)
+	return w
+}
+
+// NewTicker creates a ticker that fires every time the clock is advanced by d
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1), period: d}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.at = f.now.Add(d)
+	f.waiters[w] = struct{}{}
+	return fakeTicker{w: w}
+}
+
+type fakeTicker struct{ w *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.w.c }
+func (t fakeTicker) Stop()               { t.w.Stop() }
+
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+// Stop stops the timer or ticker; it reports whether a timer was still pending
+func (w *fakeWaiter) Stop() bool {
+	w.clock.mu.Lock()
+	defer w.clock.mu.Unlock()
+	_, pending := w.clock.waiters[w]
+	delete(w.clock.waiters, w)
+	return pending
+}
+
+// Reset makes the timer fire once the clock is advanced by d; it reports
+// whether the timer was still pending
+func (w *fakeWaiter) Reset(d time.Duration) bool {
+	f := w.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, pending := f.waiters[w]
+	w.at = f.now.Add(d)
+	if d <= 0 {
+		delete(f.waiters, w)
+		select {
+		case w.c <- f.now:
+		default:
+		}
+		return pending
+	}
+	f.waiters[w] = struct{}{}
+	return pending
+}
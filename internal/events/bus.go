@@ -0,0 +1,109 @@
+// Package events is the engine's in-process event bus. Components publish
+// status transition events; optional sinks (e.g. analytics) subscribe to them.
+// Publishing never blocks: a subscriber that falls behind loses events rather
+// than slowing down attestation processing.
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tlng/storage/store"
+)
+
+// StatusEvent records one status transition of a log attestation task
+type StatusEvent struct {
+	RequestID   string
+	LogHash     string
+	SourceOrgID string
+	Region      string
+	From        store.Status
+	To          store.Status
+	ReceivedAt  time.Time // Gateway receive time of the submission
+	At          time.Time // Time of the transition
+	RetryCount  int
+	TxHash      string // Set on COMPLETED
+	BlockHeight uint64 // Set on COMPLETED
+	Error       string // Set on FAILED and on retry
+}
+
+// Subscription delivers published events to one subscriber
+type Subscription struct {
+	Name    string
+	ch      chan []StatusEvent
+	dropped atomic.Uint64
+}
+
+// C returns the channel of event batches; it is closed when the bus is closed
+func (s *Subscription) C() <-chan []StatusEvent {
+	return s.ch
+}
+
+// Dropped returns the number of events lost because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Bus fans published events out to all subscribers
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*Subscription
+	closed bool
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a subscriber buffering up to buffer event batches
+func (b *Bus) Subscribe(name string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = 1
+	}
+	sub := &Subscription{Name: name, ch: make(chan []StatusEvent, buffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.ch)
+		return sub
+	}
+	b.subs = append(b.subs, sub)
+	return sub
+}
+
+// Publish delivers a batch of events to every subscriber without blocking.
+// Subscribers must not modify the batch. A nil bus discards events.
+func (b *Bus) Publish(batch []StatusEvent) {
+	if b == nil || len(batch) == 0 {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		select {
+		case sub.ch <- batch:
+		default:
+			sub.dropped.Add(uint64(len(batch)))
+		}
+	}
+}
+
+// Close closes all subscription channels; later publishes are discarded
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for _, sub := range b.subs {
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"tlng/proto/internalpb"
+	"tlng/storage/store"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protoStatus maps store statuses to the canonical enum
+var protoStatus = map[store.Status]internalpb.LogStatus{
+	store.StatusReceived:    internalpb.LogStatus_LOG_STATUS_RECEIVED,
+	store.StatusProcessing:  internalpb.LogStatus_LOG_STATUS_PROCESSING,
+	store.StatusCompleted:   internalpb.LogStatus_LOG_STATUS_COMPLETED,
+	store.StatusFailed:      internalpb.LogStatus_LOG_STATUS_FAILED,
+	store.StatusQueuedLocal: internalpb.LogStatus_LOG_STATUS_QUEUED_LOCAL,
+}
+
+// Proto converts the event to its canonical protobuf form
+func (e *StatusEvent) Proto() *internalpb.StatusChangeEvent {
+	p := &internalpb.StatusChangeEvent{
+		RequestId:   e.RequestID,
+		LogHash:     e.LogHash,
+		SourceOrgId: e.SourceOrgID,
+		Region:      e.Region,
+		From:        protoStatus[e.From],
+		To:          protoStatus[e.To],
+		RetryCount:  uint32(e.RetryCount),
+		TxHash:      e.TxHash,
+		BlockHeight: e.BlockHeight,
+		Error:       e.Error,
+	}
+	if !e.ReceivedAt.IsZero() {
+		p.ReceivedAt = timestamppb.New(e.ReceivedAt)
+	}
+	if !e.At.IsZero() {
+		p.At = timestamppb.New(e.At)
+	}
+	return p
+}
+
+// Outcome returns the final outcome recorded by an event; ok is false for
+// transitions to a status that is not final
+func Outcome(p *internalpb.StatusChangeEvent) (outcome store.LogOutcome, ok bool) {
+	switch p.GetTo() {
+	case internalpb.LogStatus_LOG_STATUS_COMPLETED:
+		outcome.Status = store.StatusCompleted
+	case internalpb.LogStatus_LOG_STATUS_FAILED:
+		outcome.Status = store.StatusFailed
+	default:
+		return outcome, false
+	}
+	outcome.RequestID = p.GetRequestId()
+	outcome.LogHash = p.GetLogHash()
+	outcome.SourceOrgID = p.GetSourceOrgId()
+	outcome.Region = p.GetRegion()
+	outcome.RetryCount = int(p.GetRetryCount())
+	outcome.TxHash = p.GetTxHash()
+	outcome.BlockHeight = p.GetBlockHeight()
+	outcome.ErrorMessage = p.GetError()
+	if p.GetAt() != nil {
+		outcome.FinishedAt = p.GetAt().AsTime()
+	}
+	outcome.ReceivedTimestamp = outcome.FinishedAt
+	if p.GetReceivedAt() != nil {
+		outcome.ReceivedTimestamp = p.GetReceivedAt().AsTime()
+	}
+	return outcome, true
+}
@@ -0,0 +1,88 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// File is a file the configuration was loaded from or names, and the SHA-256
+// of its content
+type File struct {
+	Setting string `json:"setting,omitempty"` // Setting naming the file; empty for the configuration file itself
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256,omitempty"`
+	Error   string `json:"error,omitempty"` // Why the file could not be read
+}
+
+// Files returns the checksums of the configuration file at path and of the
+// files named by cfg's *_file and *_path settings, such as certificates and
+// blockchain client configurations. Settings whose value is not a regular file,
+// like the HTTP paths of the monitoring endpoints, are skipped.
+func Files(path string, cfg any) ([]File, error) {
+	doc, err := Sanitize(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var files []File
+	if path != "" {
+		files = append(files, checksum("", path))
+	}
+	var named []File
+	collectFiles("", doc, &named)
+	sort.Slice(named, func(i, j int) bool { return named[i].Setting < named[j].Setting })
+	return append(files, named...), nil
+}
+
+// collectFiles appends the regular files named by the *_file and *_path
+// settings under prefix in v
+func collectFiles(prefix string, v any, files *[]File) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			setting := k
+			if prefix != "" {
+				setting = prefix + "." + k
+			}
+			if path, ok := val.(string); ok && path != "" && namesFile(k) {
+				if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+					*files = append(*files, checksum(setting, path))
+				}
+				continue
+			}
+			collectFiles(setting, val, files)
+		}
+	case []any:
+		for i, val := range v {
+			collectFiles(fmt.Sprintf("%s[%d]", prefix, i), val, files)
+		}
+	}
+}
+
+// namesFile reports whether a setting names a file
+func namesFile(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasSuffix(key, "_file") || strings.HasSuffix(key, "_path")
+}
+
+// checksum returns the SHA-256 of the file at path
+func checksum(setting, path string) File {
+	file := File{Setting: setting, Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		file.Error = err.Error()
+		return file
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		file.Error = err.Error()
+		return file
+	}
+	file.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return file
+}
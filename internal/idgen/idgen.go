@@ -0,0 +1,104 @@
+package idgen
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Strategy names accepted in configuration
+const (
+	StrategyUUID   = "uuid"   // Random UUIDv4 (default, not time-ordered)
+	StrategyUUIDv7 = "uuidv7" // RFC 9562 UUIDv7, time-ordered and UUID-compatible
+	StrategyULID   = "ulid"   // 26-char Crockford base32 ULID, time-ordered and lexicographically sortable
+)
+
+// Generator produces unique request IDs
+type Generator interface {
+	// NewID returns a new unique ID
+	NewID() string
+}
+
+// NewGenerator creates a generator for the named strategy; an empty name selects UUIDv4
+func NewGenerator(strategy string) (Generator, error) {
+	switch strategy {
+	case "", StrategyUUID:
+		return uuidGenerator{}, nil
+	case StrategyUUIDv7:
+		return uuidV7Generator{}, nil
+	case StrategyULID:
+		return NewULIDGenerator(), nil
+	default:
+		return nil, fmt.Errorf("unsupported request ID strategy: %s", strategy)
+	}
+}
+
+// Timestamp extracts the embedded creation time from a time-ordered ID.
+// It returns false for IDs that carry no timestamp, such as UUIDv4.
+func Timestamp(id string) (time.Time, bool) {
+	if t, ok := ulidTimestamp(id); ok {
+		return t, true
+	}
+	u, err := uuid.Parse(id)
+	if err != nil || u.Version() != 7 {
+		return time.Time{}, false
+	}
+	sec, nsec := u.Time().UnixTime()
+	return time.Unix(sec, nsec), true
+}
+
+// uuidGenerator generates random UUIDv4 IDs
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string {
+	return uuid.NewString()
+}
+
+// uuidV7Generator generates time-ordered UUIDv7 IDs
+type uuidV7Generator struct{}
+
+func (uuidV7Generator) NewID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		// crypto/rand failure; fall back to v4 rather than failing the submission
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// Sequence generates reproducible UUID-formatted IDs counting up from 1, for
+// deterministic tests. It is safe for concurrent use.
+type Sequence struct {
+	n atomic.Uint64
+}
+
+// NewSequence creates a sequence whose first ID ends in 1
+func NewSequence() *Sequence {
+	return &Sequence{}
+}
+
+func (s *Sequence) NewID() string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.n.Add(1))
+}
+
+// idempotencyNamespace is the UUIDv5 namespace for request IDs derived from idempotency keys
+var idempotencyNamespace = uuid.MustParse("ca9e07f5-cfb7-458b-a7f1-84466aa1295c")
+
+// FromIdempotencyKey derives a stable request ID (UUIDv5) from a client
+// idempotency key and the submission it protects, so that every retry of the
+// same submission maps to the same request_id. Such IDs are not time-ordered.
+func FromIdempotencyKey(sourceOrgID, key, logHash string) string {
+	return uuid.NewSHA1(idempotencyNamespace, []byte(sourceOrgID+"\x00"+key+"\x00"+logHash)).String()
+}
+
+// externalIDNamespace is the UUIDv5 namespace for request IDs derived from external IDs
+var externalIDNamespace = uuid.MustParse("5f0c2b9e-8d41-4e27-9a63-1c7e2f84b0d5")
+
+// FromExternalID derives a stable request ID (UUIDv5) from a client's external
+// ID alone, so that every submission an org makes under the same external ID
+// maps to the same request_id whatever its content. Such IDs are not time-ordered.
+func FromExternalID(sourceOrgID, externalID string) string {
+	return uuid.NewSHA1(externalIDNamespace, []byte(sourceOrgID+"\x00"+externalID)).String()
+}
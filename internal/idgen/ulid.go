@@ -0,0 +1,144 @@
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidLen is the length of an encoded ULID
+const ulidLen = 26
+
+// maxULIDTime is the largest millisecond timestamp a ULID can hold (48 bits)
+const maxULIDTime = 1<<48 - 1
+
+// ULIDGenerator generates monotonic ULIDs: IDs created within the same
+// millisecond increment the random component, so they still sort in creation order.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	lastRnd [10]byte
+}
+
+// NewULIDGenerator creates a new monotonic ULID generator
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{}
+}
+
+// NewID returns a new ULID
+func (g *ULIDGenerator) NewID() string {
+	return g.newAt(time.Now())
+}
+
+func (g *ULIDGenerator) newAt(t time.Time) string {
+	ms := uint64(t.UnixMilli())
+
+	g.mu.Lock()
+	if ms <= g.lastMs {
+		// Same (or earlier, on clock step back) millisecond: keep lastMs and increment randomness
+		ms = g.lastMs
+		incrementRandom(&g.lastRnd)
+	} else {
+		g.lastMs = ms
+		if _, err := rand.Read(g.lastRnd[:]); err != nil {
+			// crypto/rand failure: derive entropy from the clock so IDs remain unique per process
+			binary.BigEndian.PutUint64(g.lastRnd[2:], uint64(time.Now().UnixNano()))
+		}
+	}
+	var raw [16]byte
+	putUint48(raw[:6], ms)
+	copy(raw[6:], g.lastRnd[:])
+	g.mu.Unlock()
+
+	return encodeULID(raw)
+}
+
+// ULIDLowerBound returns the smallest ULID created at t, for time-range scans by ID prefix
+func ULIDLowerBound(t time.Time) string {
+	var raw [16]byte
+	putUint48(raw[:6], uint64(t.UnixMilli()))
+	return encodeULID(raw)
+}
+
+// ULIDUpperBound returns the largest ULID created at t, for time-range scans by ID prefix
+func ULIDUpperBound(t time.Time) string {
+	var raw [16]byte
+	putUint48(raw[:6], uint64(t.UnixMilli()))
+	for i := 6; i < 16; i++ {
+		raw[i] = 0xFF
+	}
+	return encodeULID(raw)
+}
+
+// incrementRandom adds one to the 80-bit random component
+func incrementRandom(rnd *[10]byte) {
+	for i := len(rnd) - 1; i >= 0; i-- {
+		rnd[i]++
+		if rnd[i] != 0 {
+			return
+		}
+	}
+}
+
+func putUint48(b []byte, v uint64) {
+	b[0] = byte(v >> 40)
+	b[1] = byte(v >> 32)
+	b[2] = byte(v >> 24)
+	b[3] = byte(v >> 16)
+	b[4] = byte(v >> 8)
+	b[5] = byte(v)
+}
+
+// encodeULID encodes 128 bits as 26 Crockford base32 characters (big-endian, 2 leading pad bits)
+func encodeULID(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+
+	var out [ulidLen]byte
+	for i := ulidLen - 1; i >= 0; i-- {
+		out[i] = crockford[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// ulidTimestamp decodes the timestamp of a ULID string
+func ulidTimestamp(id string) (time.Time, bool) {
+	if len(id) != ulidLen {
+		return time.Time{}, false
+	}
+	var ms uint64
+	for i := 0; i < 10; i++ { // First 10 chars encode the 48-bit timestamp (50 bits, top 2 zero)
+		v := decodeCrockford(id[i])
+		if v < 0 {
+			return time.Time{}, false
+		}
+		ms = ms<<5 | uint64(v)
+	}
+	for i := 10; i < ulidLen; i++ {
+		if decodeCrockford(id[i]) < 0 {
+			return time.Time{}, false
+		}
+	}
+	if ms > maxULIDTime {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(ms)), true
+}
+
+func decodeCrockford(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(crockford); i++ {
+		if crockford[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,41 @@
+// Package integrity describes how log hashes are computed and which software
+// and schema recorded a submission, so receipts and proofs stay verifiable as
+// hash algorithms and formats evolve
+package integrity
+
+import (
+	"tlng/internal/buildinfo"
+	"tlng/storage/store"
+)
+
+const (
+	// HashAlgorithm is the algorithm of log_hash, hex encoded
+	HashAlgorithm = "sha256"
+	// Canonicalization is the profile log content is brought to before
+	// hashing: "raw" hashes the log_content bytes exactly as submitted, without
+	// normalizing whitespace, line endings or encoding
+	Canonicalization = "raw"
+)
+
+// Metadata accompanies submission responses and evidence
+type Metadata struct {
+	HashAlgorithm    string `json:"hash_algorithm,omitempty"`
+	Canonicalization string `json:"canonicalization,omitempty"`
+	ServiceVersion   string `json:"service_version,omitempty"` // Module version or VCS revision of the binary
+	SchemaVersion    int    `json:"schema_version,omitempty"`  // State DB schema version the service runs against
+}
+
+// ForStore returns the metadata of this binary running against s. Stores that
+// do not report their schema are taken to be at store.SchemaVersion.
+func ForStore(s store.Store) *Metadata {
+	schemaVersion := store.SchemaVersion
+	if sch, ok := s.(interface{ Schema() store.SchemaInfo }); ok {
+		schemaVersion = sch.Schema().Version
+	}
+	return &Metadata{
+		HashAlgorithm:    HashAlgorithm,
+		Canonicalization: Canonicalization,
+		ServiceVersion:   buildinfo.Version(),
+		SchemaVersion:    schemaVersion,
+	}
+}
@@ -16,3 +16,28 @@ type Consumer interface {
 	// Close gracefully shuts down the consumer connection.
 	Close() error
 }
+
+// PartitionReporter is implemented by consumers that can report the partitions
+// they are currently receiving messages from.
+type PartitionReporter interface {
+	Partitions() []int
+}
+
+// UncommittedReporter is implemented by consumers that bound the messages
+// fetched but not yet committed. Uncommitted returns their number and whether
+// fetching is paused because the bound is reached.
+type UncommittedReporter interface {
+	Uncommitted() (count int, paused bool)
+}
+
+// QueueReporter is implemented by consumers that buffer fetched messages in a
+// work queue shared by several workers. Queued returns the messages waiting in it.
+type QueueReporter interface {
+	Queued() int
+}
+
+// ShardReporter is implemented by consumers that only receive the messages of
+// one log hash range. HashRange returns its first and last hash prefixes.
+type ShardReporter interface {
+	HashRange() (first, last string)
+}
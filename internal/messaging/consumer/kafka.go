@@ -2,22 +2,44 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
 	"tlng/config"
+	"tlng/internal/messaging/topic"
 	"tlng/internal/models"
+	"tlng/internal/tracing"
 
 	"github.com/segmentio/kafka-go"
 )
 
 // KafkaConsumer implements the Consumer interface to consume log messages from Kafka
 type KafkaConsumer struct {
-	reader *kafka.Reader
-	logger *log.Logger
+	reader       *kafka.Reader
+	readerConfig kafka.ReaderConfig // Kept to recreate the reader when rewinding
+	readerMu     sync.RWMutex       // Held for writing while the reader is replaced
+	logger       *log.Logger
+
+	// Bound on fetched but uncommitted messages (0 disables it)
+	maxUncommitted  int
+	redeliveryDelay time.Duration
+
+	mu          sync.Mutex
+	partitions  map[int]struct{}          // Partitions messages have been fetched from
+	offsets     map[int]*partitionOffsets // Fetch and commit progress per partition
+	unresolved  int                       // Fetched messages whose ack has not been called yet
+	pausedSince time.Time                 // When fetching paused at the bound; zero while fetching
+}
+
+// partitionOffsets tracks one partition's fetched and committed offsets
+type partitionOffsets struct {
+	next    int64 // First offset not yet committed
+	fetched int64 // Last offset fetched
 }
 
 // NewKafkaConsumer creates a new KafkaConsumer instance
@@ -26,6 +48,16 @@ func NewKafkaConsumer(cfg config.KafkaConsumerConfig, logger *log.Logger) (*Kafk
 		return nil, errors.New("incomplete kafka configuration: brokers, topic, group_id are all required")
 	}
 
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail fast on a missing or misconfigured topic instead of silently waiting for messages
+	if err := topic.Ensure(context.Background(), cfg.Brokers, tlsConfig, cfg.Topic, cfg.TopicCheck, logger); err != nil {
+		return nil, fmt.Errorf("kafka topic check failed: %w", err)
+	}
+
 	// Parse session timeout with default
 	sessionTimeout, err := time.ParseDuration(cfg.SessionTimeout)
 	if err != nil {
@@ -59,6 +91,9 @@ func NewKafkaConsumer(cfg config.KafkaConsumerConfig, logger *log.Logger) (*Kafk
 		HeartbeatInterval: heartbeatInterval,
 		StartOffset:       kafka.FirstOffset, // Will be overridden by autoOffsetReset
 	}
+	if tlsConfig != nil {
+		readerConfig.Dialer = &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true, TLS: tlsConfig}
+	}
 
 	// Set start offset based on autoOffsetReset
 	switch autoOffsetReset {
@@ -71,20 +106,41 @@ func NewKafkaConsumer(cfg config.KafkaConsumerConfig, logger *log.Logger) (*Kafk
 		readerConfig.StartOffset = kafka.FirstOffset
 	}
 
+	redeliveryDelay, err := time.ParseDuration(cfg.RedeliveryDelay)
+	if err != nil && cfg.MaxUncommitted > 0 {
+		logger.Printf("Warning: Invalid redelivery_delay '%s', using default 5s", cfg.RedeliveryDelay)
+		redeliveryDelay = 5 * time.Second
+	}
+
 	r := kafka.NewReader(readerConfig)
 
 	logger.Printf("Kafka consumer created, connected to Brokers: %v, Topic: %s, GroupID: %s", cfg.Brokers, cfg.Topic, cfg.GroupID)
 
 	return &KafkaConsumer{
-		reader: r,
-		logger: logger,
+		reader:          r,
+		readerConfig:    readerConfig,
+		logger:          logger,
+		maxUncommitted:  cfg.MaxUncommitted,
+		redeliveryDelay: redeliveryDelay,
+		partitions:      make(map[int]struct{}),
+		offsets:         make(map[int]*partitionOffsets),
 	}, nil
 }
 
 // Consume implements the Consumer interface by reading messages from Kafka
 func (k *KafkaConsumer) Consume(ctx context.Context) (msg *models.LogMessage, ack func(success bool), err error) {
+	// Hold off while too many fetched messages are uncommitted
+	if k.maxUncommitted > 0 {
+		if err := k.waitForCommits(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Fetch message from Kafka
-	kafkaMsg, err := k.reader.FetchMessage(ctx)
+	k.readerMu.RLock()
+	reader := k.reader
+	kafkaMsg, err := reader.FetchMessage(ctx)
+	k.readerMu.RUnlock()
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			k.logger.Println("Kafka consumer: Context cancelled, stopping consumption.")
@@ -93,34 +149,193 @@ func (k *KafkaConsumer) Consume(ctx context.Context) (msg *models.LogMessage, ac
 		return nil, nil, err
 	}
 
-	// Deserialize message body (assumes JSON format)
-	var logMsg models.LogMessage
-	if err := json.Unmarshal(kafkaMsg.Value, &logMsg); err != nil {
+	k.mu.Lock()
+	k.partitions[kafkaMsg.Partition] = struct{}{}
+	po, ok := k.offsets[kafkaMsg.Partition]
+	if !ok {
+		po = &partitionOffsets{next: kafkaMsg.Offset}
+		k.offsets[kafkaMsg.Partition] = po
+	}
+	po.fetched = max(po.fetched, kafkaMsg.Offset)
+	k.mu.Unlock()
+
+	// Deserialize message body by its content type (legacy messages carry none and are JSON)
+	logMsg, err := models.DecodeLogMessage(kafkaMsg.Value, contentType(kafkaMsg.Headers))
+	if err != nil {
 		k.logger.Printf("Kafka consumer: Failed to deserialize message (Offset: %d): %v. Message will be discarded.", kafkaMsg.Offset, err)
-		_ = k.reader.CommitMessages(ctx, kafkaMsg) // Commit offset to avoid blocking
+		k.commit(ctx, reader, kafkaMsg) // Commit offset to avoid blocking
 		return nil, nil, fmt.Errorf("message deserialization failed: %w", err)
 	}
+	logMsg.TraceContext = traceContext(kafkaMsg.Headers)
+	logMsg.Priority = headerValue(kafkaMsg.Headers, models.PriorityHeader)
 
 	// Create ack callback
+	k.mu.Lock()
+	k.unresolved++
+	k.mu.Unlock()
 	ackCallback := func(success bool) {
-		commitCtx := context.Background()
+		k.mu.Lock()
+		k.unresolved--
+		k.mu.Unlock()
 		if success {
-			if err := k.reader.CommitMessages(commitCtx, kafkaMsg); err != nil {
-				k.logger.Printf("Kafka consumer: Failed to commit offset %d: %v", kafkaMsg.Offset, err)
-			}
+			k.commit(context.Background(), reader, kafkaMsg)
 		} else {
 			k.logger.Printf("Kafka consumer: NACK received for offset %d (request_id %s). Offset will not be committed.", kafkaMsg.Offset, logMsg.RequestID)
 		}
 	}
 
-	return &logMsg, ackCallback, nil
+	return logMsg, ackCallback, nil
+}
+
+// traceContext returns the W3C trace context headers of a message, nil if absent
+func traceContext(headers []kafka.Header) map[string]string {
+	var carrier map[string]string
+	for _, h := range headers {
+		if slices.Contains(tracing.Headers(), h.Key) {
+			if carrier == nil {
+				carrier = make(map[string]string, 2)
+			}
+			carrier[h.Key] = string(h.Value)
+		}
+	}
+	return carrier
+}
+
+// contentType returns the value of the content-type header, "" if absent
+func contentType(headers []kafka.Header) string {
+	return headerValue(headers, models.ContentTypeHeader)
+}
+
+// headerValue returns the value of a header, "" if absent
+func headerValue(headers []kafka.Header, key string) string {
+	for _, h := range headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// commit commits a message's offset, which also commits the earlier offsets of its partition
+func (k *KafkaConsumer) commit(ctx context.Context, reader *kafka.Reader, msg kafka.Message) {
+	if err := reader.CommitMessages(ctx, msg); err != nil {
+		k.logger.Printf("Kafka consumer: Failed to commit offset %d: %v", msg.Offset, err)
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if po, ok := k.offsets[msg.Partition]; ok {
+		po.next = max(po.next, msg.Offset+1)
+	}
+}
+
+// uncommittedLocked returns the number of fetched messages not yet committed
+func (k *KafkaConsumer) uncommittedLocked() int {
+	n := 0
+	for _, po := range k.offsets {
+		if po.fetched >= po.next {
+			n += int(po.fetched - po.next + 1)
+		}
+	}
+	return n
+}
+
+// waitForCommits blocks while max_uncommitted messages are uncommitted. Nacked
+// messages are only redelivered by Kafka from the committed offset, so once
+// every held message has been acked or nacked and the bound is still reached,
+// the consumer waits redelivery_delay and rewinds to the committed offsets.
+// This refetches at most max_uncommitted messages per cycle instead of
+// running through the whole topic during an outage.
+func (k *KafkaConsumer) waitForCommits(ctx context.Context) error {
+	for {
+		k.mu.Lock()
+		uncommitted := k.uncommittedLocked()
+		if uncommitted < k.maxUncommitted {
+			if !k.pausedSince.IsZero() {
+				k.logger.Printf("Kafka consumer: Resuming fetch, %d uncommitted messages", uncommitted)
+				k.pausedSince = time.Time{}
+			}
+			k.mu.Unlock()
+			return nil
+		}
+		if k.pausedSince.IsZero() {
+			k.pausedSince = time.Now()
+			k.logger.Printf("Kafka consumer: Pausing fetch, %d uncommitted messages (max_uncommitted %d)", uncommitted, k.maxUncommitted)
+		}
+		rewind := k.unresolved == 0 && time.Since(k.pausedSince) >= k.redeliveryDelay
+		k.mu.Unlock()
+
+		if rewind {
+			if err := k.rewind(); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// rewind replaces the reader, which rejoins the group and resumes from the
+// committed offsets, so the nacked messages are delivered again
+func (k *KafkaConsumer) rewind() error {
+	k.readerMu.Lock()
+	defer k.readerMu.Unlock()
+
+	k.mu.Lock()
+	uncommitted := k.uncommittedLocked()
+	if k.unresolved > 0 || uncommitted < k.maxUncommitted {
+		k.mu.Unlock()
+		return nil // Another goroutine rewound already
+	}
+	old := k.reader
+	k.reader = kafka.NewReader(k.readerConfig)
+	k.offsets = make(map[int]*partitionOffsets)
+	k.pausedSince = time.Time{}
+	k.mu.Unlock()
+
+	k.logger.Printf("Kafka consumer: Rewinding to the committed offsets to redeliver %d nacked messages", uncommitted)
+	if err := old.Close(); err != nil {
+		k.logger.Printf("Kafka consumer: Failed to close reader while rewinding: %v", err)
+	}
+	return nil
+}
+
+// Uncommitted returns the number of fetched messages not yet committed, and
+// whether fetching is paused because they reached max_uncommitted
+func (k *KafkaConsumer) Uncommitted() (int, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.uncommittedLocked(), !k.pausedSince.IsZero()
+}
+
+// Partitions returns the partitions this consumer has fetched messages from.
+// kafka-go does not expose the group assignment directly, so this reflects the
+// partitions observed since the consumer started.
+func (k *KafkaConsumer) Partitions() []int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	partitions := make([]int, 0, len(k.partitions))
+	for p := range k.partitions {
+		partitions = append(partitions, p)
+	}
+	sort.Ints(partitions)
+	return partitions
 }
 
 // Close implements the Consumer interface by closing the Kafka reader
 func (k *KafkaConsumer) Close() error {
 	k.logger.Println("Closing Kafka consumer...")
+	k.readerMu.RLock()
+	defer k.readerMu.RUnlock()
 	return k.reader.Close()
 }
 
 // Ensure KafkaConsumer implements the Consumer interface
 var _ Consumer = (*KafkaConsumer)(nil)
+var _ PartitionReporter = (*KafkaConsumer)(nil)
+var _ UncommittedReporter = (*KafkaConsumer)(nil)
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"log"
-	"strconv"
 	"time"
 	"tlng/internal/models"
 )
@@ -27,7 +26,7 @@ func init() {
 		LogContent:        "Fixed mock log content 1",
 		LogHash:           "fixedhash001",
 		SourceOrgID:       "mock-org-1",
-		ReceivedTimestamp: strconv.FormatInt(time.Now().Unix()-60, 10),
+		ReceivedTimestamp: models.NewTimestamp(time.Now().Add(-60 * time.Second)),
 	}
 	PredefinedMessages = append(PredefinedMessages, msg1)
 
@@ -36,7 +35,7 @@ func init() {
 		LogContent:        "Fixed mock log content 2 with more detail",
 		LogHash:           "fixedhash002",
 		SourceOrgID:       "mock-org-2",
-		ReceivedTimestamp: strconv.FormatInt(time.Now().Unix()-30, 10),
+		ReceivedTimestamp: models.NewTimestamp(time.Now().Add(-30 * time.Second)),
 	}
 	PredefinedMessages = append(PredefinedMessages, msg2)
 
@@ -46,7 +45,7 @@ func init() {
 		LogContent:        "Fixed mock log content 1",
 		LogHash:           "fixedhash001",
 		SourceOrgID:       "mock-org-1",
-		ReceivedTimestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		ReceivedTimestamp: models.NewTimestamp(time.Now()),
 	}
 	PredefinedMessages = append(PredefinedMessages, msg3)
 }
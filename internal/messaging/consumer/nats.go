@@ -0,0 +1,157 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"tlng/config"
+	"tlng/internal/messaging/natsjs"
+	"tlng/internal/models"
+	"tlng/internal/tracing"
+)
+
+// natsFetchWait is how long a pull request waits for messages before it is renewed
+const natsFetchWait = 5 * time.Second
+
+// NATSConsumer implements the Consumer interface on a durable JetStream pull
+// consumer. Several NATSConsumers may share the durable; the server spreads
+// the messages over their pull requests.
+type NATSConsumer struct {
+	client   *natsjs.Client
+	consumer jetstream.Consumer
+	logger   *log.Logger
+	batch    int
+	nakDelay time.Duration
+
+	fetch jetstream.MessageBatch // Pull request being read by Consume; used by one goroutine
+}
+
+// NewNATSConsumer creates a NATSConsumer reading the durable consumer of the configured stream
+func NewNATSConsumer(ctx context.Context, cfg config.NATSConfig, logger *log.Logger) (*NATSConsumer, error) {
+	if len(cfg.URLs) == 0 || cfg.Stream == "" || cfg.Durable == "" {
+		return nil, errors.New("incomplete nats configuration: urls, stream, durable are all required")
+	}
+
+	client, err := natsjs.Dial(cfg, "tlng-engine", logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	// Fail fast on a missing stream or consumer instead of silently waiting for messages
+	if err := natsjs.EnsureStream(ctx, client, cfg, logger); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("nats stream check failed: %w", err)
+	}
+	consumer, err := natsjs.EnsureConsumer(ctx, client, cfg, logger)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("nats consumer check failed: %w", err)
+	}
+
+	logger.Printf("NATS consumer created, connected to Servers: %v, Stream: %s, Durable: %s", cfg.URLs, cfg.Stream, cfg.Durable)
+
+	return &NATSConsumer{
+		client:   client,
+		consumer: consumer,
+		logger:   logger,
+		batch:    cfg.FetchBatch,
+		nakDelay: cfg.NakDelay,
+	}, nil
+}
+
+// Consume implements the Consumer interface by pulling messages from the stream
+func (n *NATSConsumer) Consume(ctx context.Context) (*models.LogMessage, func(success bool), error) {
+	natsMsg, err := n.next(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			n.logger.Println("NATS consumer: Context cancelled, stopping consumption.")
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, err
+	}
+
+	// Deserialize message body by its content type
+	logMsg, err := models.DecodeLogMessage(natsMsg.Data(), natsMsg.Headers().Get(models.ContentTypeHeader))
+	if err != nil {
+		n.logger.Printf("NATS consumer: Failed to deserialize message: %v. Message will be discarded.", err)
+		if termErr := natsMsg.Term(); termErr != nil {
+			n.logger.Printf("NATS consumer: Failed to discard message: %v", termErr)
+		}
+		return nil, nil, fmt.Errorf("message deserialization failed: %w", err)
+	}
+	logMsg.TraceContext = natsTraceContext(natsMsg.Headers())
+	logMsg.Priority = natsMsg.Headers().Get(models.PriorityHeader)
+
+	ackCallback := func(success bool) {
+		if success {
+			if err := natsMsg.Ack(); err != nil {
+				n.logger.Printf("NATS consumer: Failed to ack request_id %s (it will be redelivered after ack_wait): %v", logMsg.RequestID, err)
+			}
+			return
+		}
+		n.logger.Printf("NATS consumer: NACK received for request_id %s. Redelivery in %s.", logMsg.RequestID, n.nakDelay)
+		if err := natsMsg.NakWithDelay(n.nakDelay); err != nil {
+			n.logger.Printf("NATS consumer: Failed to nack request_id %s (it will be redelivered after ack_wait): %v", logMsg.RequestID, err)
+		}
+	}
+
+	return logMsg, ackCallback, nil
+}
+
+// next returns the next message of the current pull request, issuing new
+// requests while they expire without messages
+func (n *NATSConsumer) next(ctx context.Context) (jetstream.Msg, error) {
+	for {
+		if n.fetch == nil {
+			fetch, err := n.consumer.Fetch(n.batch, jetstream.FetchMaxWait(natsFetchWait))
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch from NATS: %w", err)
+			}
+			n.fetch = fetch
+		}
+		select {
+		case msg, ok := <-n.fetch.Messages():
+			if ok {
+				return msg, nil
+			}
+			err := n.fetch.Error()
+			n.fetch = nil
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch from NATS: %w", err)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// natsTraceContext returns the W3C trace context headers of a message, nil if absent
+func natsTraceContext(h nats.Header) map[string]string {
+	var carrier map[string]string
+	for key := range h {
+		if slices.Contains(tracing.Headers(), key) {
+			if carrier == nil {
+				carrier = make(map[string]string, 2)
+			}
+			carrier[key] = h.Get(key)
+		}
+	}
+	return carrier
+}
+
+// Close implements the Consumer interface. Messages fetched but not consumed
+// are redelivered once their ack_wait passes.
+func (n *NATSConsumer) Close() error {
+	n.logger.Println("Closing NATS consumer...")
+	return n.client.Close()
+}
+
+// Ensure NATSConsumer implements the Consumer interface
+var _ Consumer = (*NATSConsumer)(nil)
@@ -0,0 +1,228 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tlng/internal/models"
+	"tlng/internal/shard"
+)
+
+// Pool fans the messages of several consumers into one bounded work queue, so
+// any number of worker pipelines can share them independently of how many
+// consumers (and so partitions) there are. It owns the consumers.
+//
+// A sharded pool splits the work queue by log hash range instead: each shard
+// (see Shard) only receives the messages of its range, so a pipeline reading
+// one shard never shares a log hash with another.
+type Pool struct {
+	consumers  []Consumer
+	queues     []chan delivery // One per shard
+	retryDelay time.Duration
+	errShard   atomic.Uint64 // Spreads consumer errors over the shards
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// delivery is a message, or a consumer error, waiting in the work queue
+type delivery struct {
+	msg *models.LogMessage
+	ack func(success bool)
+	err error
+}
+
+// NewPool starts fetching from consumers into a work queue holding up to size
+// messages. Fetching stops when ctx is done; a consumer that fails waits
+// retryDelay before fetching again.
+func NewPool(ctx context.Context, consumers []Consumer, size int, retryDelay time.Duration) *Pool {
+	return NewShardedPool(ctx, consumers, 1, size, retryDelay)
+}
+
+// NewShardedPool is NewPool with the work queue split into shards queues of
+// size messages each, by log hash range. A full shard holds up fetching for
+// all of them, as a full work queue does.
+func NewShardedPool(ctx context.Context, consumers []Consumer, shards, size int, retryDelay time.Duration) *Pool {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		consumers:  consumers,
+		queues:     make([]chan delivery, max(shards, 1)),
+		retryDelay: retryDelay,
+		cancel:     cancel,
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan delivery, size)
+	}
+	for _, c := range consumers {
+		p.wg.Add(1)
+		go p.fetch(ctx, c)
+	}
+	return p
+}
+
+// fetch moves the messages of one consumer into the work queue. Consumer
+// errors are queued too, so the pipelines count them as their own.
+func (p *Pool) fetch(ctx context.Context, c Consumer) {
+	defer p.wg.Done()
+	for ctx.Err() == nil {
+		msg, ack, err := c.Consume(ctx)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		if err == nil && msg == nil {
+			continue
+		}
+		select {
+		case p.queueOf(msg) <- delivery{msg: msg, ack: ack, err: err}:
+		case <-ctx.Done():
+			if ack != nil {
+				ack(false)
+			}
+			return
+		}
+		if err != nil {
+			select {
+			case <-time.After(p.retryDelay):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// queueOf returns the queue of the shard msg belongs to. Consumer errors
+// (nil msg) are queued round-robin.
+func (p *Pool) queueOf(msg *models.LogMessage) chan delivery {
+	if len(p.queues) == 1 {
+		return p.queues[0]
+	}
+	if msg == nil {
+		return p.queues[p.errShard.Add(1)%uint64(len(p.queues))]
+	}
+	return p.queues[shard.Of(msg.LogHash, len(p.queues))]
+}
+
+// Consume returns the next message of any of the consumers. A sharded pool
+// returns the messages of its first shard only; read the others with Shard.
+func (p *Pool) Consume(ctx context.Context) (*models.LogMessage, func(success bool), error) {
+	return receive(ctx, p.queues[0])
+}
+
+// receive returns the next delivery of queue
+func receive(ctx context.Context, queue chan delivery) (*models.LogMessage, func(success bool), error) {
+	select {
+	case d := <-queue:
+		return d.msg, d.ack, d.err
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// Queued returns the number of messages waiting in the work queue
+func (p *Pool) Queued() int {
+	var n int
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Shard returns the consumer of shard i of the pool. Closing it does nothing;
+// the pool closes the consumers.
+func (p *Pool) Shard(i int) *PoolShard {
+	return &PoolShard{pool: p, index: i}
+}
+
+// PoolShard consumes the messages of one log hash range of a sharded pool
+type PoolShard struct {
+	pool  *Pool
+	index int
+}
+
+// Consume returns the next message of the shard's hash range
+func (s *PoolShard) Consume(ctx context.Context) (*models.LogMessage, func(success bool), error) {
+	return receive(ctx, s.pool.queues[s.index])
+}
+
+// Close does nothing: the pool owns the consumers
+func (s *PoolShard) Close() error {
+	return nil
+}
+
+// Queued returns the number of messages waiting in the shard's queue
+func (s *PoolShard) Queued() int {
+	return len(s.pool.queues[s.index])
+}
+
+// Partitions returns the partitions the pool's consumers receive messages from
+func (s *PoolShard) Partitions() []int {
+	return s.pool.Partitions()
+}
+
+// Uncommitted returns the uncommitted messages of the pool's consumers
+func (s *PoolShard) Uncommitted() (int, bool) {
+	return s.pool.Uncommitted()
+}
+
+// HashRange returns the first and last log hash prefixes of the shard
+func (s *PoolShard) HashRange() (string, string) {
+	return shard.Bounds(s.index, len(s.pool.queues))
+}
+
+// Partitions returns the partitions the consumers receive messages from
+func (p *Pool) Partitions() []int {
+	var partitions []int
+	for _, c := range p.consumers {
+		if pr, ok := c.(PartitionReporter); ok {
+			partitions = append(partitions, pr.Partitions()...)
+		}
+	}
+	slices.Sort(partitions)
+	return slices.Compact(partitions)
+}
+
+// Uncommitted returns the uncommitted messages of all consumers, and whether
+// any of them has paused fetching
+func (p *Pool) Uncommitted() (int, bool) {
+	var count int
+	var paused bool
+	for _, c := range p.consumers {
+		if ur, ok := c.(UncommittedReporter); ok {
+			n, pause := ur.Uncommitted()
+			count += n
+			paused = paused || pause
+		}
+	}
+	return count, paused
+}
+
+// Close stops fetching, returns the messages still queued for redelivery and
+// closes the consumers
+func (p *Pool) Close() error {
+	var errs []error
+	p.once.Do(func() {
+		p.cancel()
+		p.wg.Wait()
+		for _, q := range p.queues {
+		drain:
+			for {
+				select {
+				case d := <-q:
+					if d.ack != nil {
+						d.ack(false)
+					}
+				default:
+					break drain
+				}
+			}
+		}
+		for _, c := range p.consumers {
+			errs = append(errs, c.Close())
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,149 @@
+package consumer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tlng/internal/messaging/topic"
+	"tlng/internal/models"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ReplayStart is where Replay starts reading each partition
+type ReplayStart struct {
+	Time   time.Time // First message at or after Time, if set
+	Offset int64     // Otherwise this offset; kafka.FirstOffset for the earliest retained message
+}
+
+// replayIdleTimeout ends a partition that stops yielding messages before its
+// end offset, e.g. because the tail was compacted away or holds control records
+const replayIdleTimeout = 10 * time.Second
+
+// Replay reads the topic from start up to the end offsets at the time of the
+// call, one partition at a time. It reads without a consumer group, so the
+// offsets committed by the engine are untouched. It returns the number of
+// messages passed to fn, stopping at the first error fn returns.
+func Replay(ctx context.Context, brokers []string, tlsConfig *tls.Config, topicName string, start ReplayStart, fn func(kafka.Message) error, logger *log.Logger) (int64, error) {
+	partitions, err := topic.Offsets(ctx, brokers, tlsConfig, topicName, 10*time.Second)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, p := range partitions {
+		n, err := replayPartition(ctx, brokers, tlsConfig, topicName, p, start, fn, logger)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("failed to replay kafka topic %s partition %d: %w", topicName, p.Partition, err)
+		}
+	}
+	return total, nil
+}
+
+// replayPartition reads one partition from start up to its end offset
+func replayPartition(ctx context.Context, brokers []string, tlsConfig *tls.Config, topicName string, p kafka.PartitionOffsets, start ReplayStart, fn func(kafka.Message) error, logger *log.Logger) (int64, error) {
+	end := p.LastOffset
+	if p.FirstOffset >= end {
+		return 0, nil
+	}
+
+	readerConfig := kafka.ReaderConfig{
+		Brokers:   brokers,
+		Topic:     topicName,
+		Partition: p.Partition,
+		MinBytes:  1,
+		MaxBytes:  10e6, // 10MB
+		MaxWait:   time.Second,
+	}
+	if tlsConfig != nil {
+		readerConfig.Dialer = &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true, TLS: tlsConfig}
+	}
+	reader := kafka.NewReader(readerConfig)
+	defer reader.Close()
+
+	if !start.Time.IsZero() {
+		if err := reader.SetOffsetAt(ctx, start.Time); err != nil {
+			return 0, fmt.Errorf("failed to seek to %s: %w", start.Time.Format(time.RFC3339), err)
+		}
+	} else {
+		offset := start.Offset
+		if offset < p.FirstOffset {
+			offset = p.FirstOffset
+		}
+		if err := reader.SetOffset(offset); err != nil {
+			return 0, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+		}
+	}
+	offset := reader.Offset()
+	if offset >= end {
+		return 0, nil
+	}
+	logger.Printf("Replaying kafka topic %s partition %d from offset %d to %d", topicName, p.Partition, offset, end)
+
+	var n int64
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, replayIdleTimeout)
+		msg, err := reader.ReadMessage(readCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			logger.Printf("Warning: kafka topic %s partition %d idle at offset %d before end offset %d, moving on",
+				topicName, p.Partition, reader.Offset(), end)
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := fn(msg); err != nil {
+			return n, err
+		}
+		n++
+		if msg.Offset+1 >= end {
+			return n, nil
+		}
+	}
+}
+
+// DecodeReplayed decodes a replayed message of the log topic
+func DecodeReplayed(msg kafka.Message) (*models.LogMessage, error) {
+	return models.DecodeLogMessage(msg.Value, contentType(msg.Headers))
+}
+
+// Requeue publishes replayed messages again, each to the topic and partition it
+// was read from, with its original key and headers
+func Requeue(ctx context.Context, brokers []string, tlsConfig *tls.Config, msgs []kafka.Message) error {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     samePartition{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	if tlsConfig != nil {
+		writer.Transport = &kafka.Transport{TLS: tlsConfig}
+	}
+	defer writer.Close()
+
+	requeued := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		requeued[i] = kafka.Message{Topic: msg.Topic, Partition: msg.Partition, Key: msg.Key, Value: msg.Value, Headers: msg.Headers}
+	}
+	if err := writer.WriteMessages(ctx, requeued...); err != nil {
+		return fmt.Errorf("failed to requeue %d messages: %w", len(msgs), err)
+	}
+	return nil
+}
+
+// samePartition balances a requeued message to the partition it was read from
+type samePartition struct{}
+
+func (samePartition) Balance(msg kafka.Message, partitions ...int) int {
+	for _, p := range partitions {
+		if p == msg.Partition {
+			return p
+		}
+	}
+	return partitions[0]
+}
@@ -0,0 +1,140 @@
+// Package natsjs connects the NATS JetStream backends of the producer and
+// consumer packages and checks the stream and durable consumer they use
+package natsjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"tlng/config"
+)
+
+// Client is a JetStream context over a connection that reconnects when it breaks
+type Client struct {
+	jetstream.JetStream
+	conn *nats.Conn
+}
+
+// Dial connects to the servers of a NATS configuration, trying them in order
+func Dial(cfg config.NATSConfig, name string, logger *log.Logger) (*Client, error) {
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts := []nats.Option{
+		nats.Name(name),
+		nats.Timeout(cfg.ConnectTimeout),
+		nats.DontRandomize(),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Printf("NATS connection lost, reconnecting: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Printf("NATS reconnected to %s", nc.ConnectedUrlRedacted())
+		}),
+	}
+	if cfg.Username != "" {
+		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+
+	nc, err := nats.Connect(strings.Join(cfg.URLs, ","), opts...)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &Client{JetStream: js, conn: nc}, nil
+}
+
+// Close closes the connection. Publishes still waiting for acknowledgements fail.
+func (c *Client) Close() error {
+	c.conn.Close()
+	return nil
+}
+
+// EnsureStream checks that the configured stream exists and captures the
+// subject, creating it with create_stream. A created stream has work-queue
+// retention: messages are removed once the engine acknowledges them.
+func EnsureStream(ctx context.Context, c *Client, cfg config.NATSConfig, logger *log.Logger) error {
+	var info *jetstream.StreamInfo
+	stream, err := c.Stream(ctx, cfg.Stream)
+	if err == nil {
+		info = stream.CachedInfo()
+	} else if errors.Is(err, jetstream.ErrStreamNotFound) && cfg.CreateStream {
+		logger.Printf("NATS stream %s not found, creating it for subject %s", cfg.Stream, cfg.Subject)
+		stream, err = c.CreateStream(ctx, jetstream.StreamConfig{
+			Name:      cfg.Stream,
+			Subjects:  []string{cfg.Subject},
+			Retention: jetstream.WorkQueuePolicy,
+			Storage:   jetstream.FileStorage,
+			Replicas:  cfg.Replicas,
+			MaxAge:    cfg.MaxAge,
+		})
+		if err == nil {
+			info = stream.CachedInfo()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("stream %s: %w", cfg.Stream, err)
+	}
+	for _, s := range info.Config.Subjects {
+		if subjectMatches(s, cfg.Subject) {
+			return nil
+		}
+	}
+	return fmt.Errorf("stream %s does not capture subject %s (subjects %v)", cfg.Stream, cfg.Subject, info.Config.Subjects)
+}
+
+// EnsureConsumer returns the configured durable pull consumer, creating it
+// with create_stream
+func EnsureConsumer(ctx context.Context, c *Client, cfg config.NATSConfig, logger *log.Logger) (jetstream.Consumer, error) {
+	consumer, err := c.Consumer(ctx, cfg.Stream, cfg.Durable)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) && cfg.CreateStream {
+		logger.Printf("NATS consumer %s not found on stream %s, creating it", cfg.Durable, cfg.Stream)
+		consumer, err = c.CreateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+			Durable:       cfg.Durable,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       cfg.AckWait,
+			MaxDeliver:    cfg.MaxDeliver,
+			FilterSubject: cfg.Subject,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("consumer %s on stream %s: %w", cfg.Durable, cfg.Stream, err)
+	}
+	return consumer, nil
+}
+
+// subjectMatches reports whether the stream subject pattern captures subject
+func subjectMatches(pattern, subject string) bool {
+	pt, st := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, p := range pt {
+		switch {
+		case p == ">":
+			return len(st) > i
+		case i >= len(st):
+			return false
+		case p != "*" && p != st[i]:
+			return false
+		}
+	}
+	return len(pt) == len(st)
+}
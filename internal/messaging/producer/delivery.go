@@ -0,0 +1,232 @@
+package producer
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// DeliveryStats reports delivery outcomes of an asynchronous producer
+type DeliveryStats struct {
+	Delivered      uint64 `json:"delivered"`        // Messages acknowledged by Kafka
+	Failed         uint64 `json:"failed"`           // Messages whose delivery report carried an error
+	Retried        uint64 `json:"retried"`          // Redelivery attempts made from the retry buffer
+	Undeliverable  uint64 `json:"undeliverable"`    // Messages dropped after exhausting retries or buffer space
+	RetryBufferLen int    `json:"retry_buffer_len"` // Messages currently waiting for redelivery
+}
+
+// DeliveryReporter is implemented by producers that track delivery outcomes
+type DeliveryReporter interface {
+	DeliveryStats() DeliveryStats
+}
+
+// retryItem is a message waiting for redelivery
+type retryItem struct {
+	msg      kafka.Message
+	attempts int
+}
+
+// deliveryTracker captures failed deliveries reported by the async writer into a
+// bounded buffer and redelivers them with exponential backoff through a
+// synchronous writer.
+type deliveryTracker struct {
+	writer      *kafka.Writer // Synchronous writer used for redelivery
+	logger      *log.Logger
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+
+	buffer chan retryItem
+
+	delivered     atomic.Uint64
+	failed        atomic.Uint64
+	retried       atomic.Uint64
+	undeliverable atomic.Uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newDeliveryTracker creates a tracker and starts its redelivery loop
+func newDeliveryTracker(writer *kafka.Writer, bufferSize, maxAttempts int, backoff, maxBackoff time.Duration, logger *log.Logger) *deliveryTracker {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &deliveryTracker{
+		writer:      writer,
+		logger:      logger,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		buffer:      make(chan retryItem, bufferSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	t.wg.Add(1)
+	go t.retryLoop()
+
+	return t
+}
+
+// onCompletion is installed as the async writer's Completion callback
+func (t *deliveryTracker) onCompletion(messages []kafka.Message, err error) {
+	if err == nil {
+		t.delivered.Add(uint64(len(messages)))
+		return
+	}
+
+	t.failed.Add(uint64(len(messages)))
+	t.logger.Printf("Kafka delivery failed for %d messages, queueing for retry: %v", len(messages), err)
+	for _, m := range messages {
+		t.enqueue(retryItem{msg: m})
+	}
+}
+
+// enqueue adds an item to the retry buffer, dropping it if the buffer is full
+func (t *deliveryTracker) enqueue(item retryItem) {
+	// Messages reported by the writer carry the resolved topic/partition,
+	// which must be cleared before handing them back to a writer with a fixed topic
+	item.msg.Topic = ""
+	item.msg.Partition = 0
+	item.msg.Offset = 0
+
+	select {
+	case t.buffer <- item:
+	default:
+		t.undeliverable.Add(1)
+		t.logger.Printf("Kafka retry buffer full, message undeliverable (key: %s)", string(item.msg.Key))
+	}
+}
+
+// retryLoop drains the retry buffer in batches and redelivers them
+func (t *deliveryTracker) retryLoop() {
+	defer t.wg.Done()
+
+	consecutiveFailures := 0
+	for {
+		var first retryItem
+		select {
+		case first = <-t.buffer:
+		case <-t.ctx.Done():
+			return
+		}
+
+		// Collect whatever else is already waiting into the same batch
+		batch := []retryItem{first}
+	collect:
+		for len(batch) < cap(t.buffer) {
+			select {
+			case item := <-t.buffer:
+				batch = append(batch, item)
+			default:
+				break collect
+			}
+		}
+
+		if consecutiveFailures > 0 {
+			select {
+			case <-time.After(t.backoffFor(consecutiveFailures)):
+			case <-t.ctx.Done():
+				t.requeue(batch)
+				return
+			}
+		}
+
+		if t.redeliver(batch) {
+			consecutiveFailures = 0
+		} else {
+			consecutiveFailures++
+		}
+	}
+}
+
+// redeliver writes a batch through the synchronous writer, returning true on success
+func (t *deliveryTracker) redeliver(batch []retryItem) bool {
+	msgs := make([]kafka.Message, len(batch))
+	for i, item := range batch {
+		msgs[i] = item.msg
+	}
+	t.retried.Add(uint64(len(batch)))
+
+	if err := t.writer.WriteMessages(t.ctx, msgs...); err != nil {
+		t.logger.Printf("Kafka redelivery of %d messages failed: %v", len(batch), err)
+		for _, item := range batch {
+			item.attempts++
+			if item.attempts >= t.maxAttempts {
+				t.undeliverable.Add(1)
+				t.logger.Printf("Kafka message undeliverable after %d attempts (key: %s)", item.attempts, string(item.msg.Key))
+				continue
+			}
+			t.enqueue(item)
+		}
+		return false
+	}
+
+	t.delivered.Add(uint64(len(batch)))
+	return true
+}
+
+// requeue returns an unsent batch to the buffer so Close can account for it
+func (t *deliveryTracker) requeue(batch []retryItem) {
+	for _, item := range batch {
+		t.enqueue(item)
+	}
+}
+
+// backoffFor computes the exponential backoff after n consecutive failures
+func (t *deliveryTracker) backoffFor(n int) time.Duration {
+	d := t.backoff
+	for i := 1; i < n && d < t.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > t.maxBackoff {
+		d = t.maxBackoff
+	}
+	return d
+}
+
+// stats returns a snapshot of the delivery counters
+func (t *deliveryTracker) stats() DeliveryStats {
+	return DeliveryStats{
+		Delivered:      t.delivered.Load(),
+		Failed:         t.failed.Load(),
+		Retried:        t.retried.Load(),
+		Undeliverable:  t.undeliverable.Load(),
+		RetryBufferLen: len(t.buffer),
+	}
+}
+
+// close makes one final redelivery attempt for buffered messages, then stops
+// the retry loop. Anything still unsent is counted as undeliverable.
+func (t *deliveryTracker) close() error {
+	t.cancel()
+	t.wg.Wait()
+
+	var remaining []kafka.Message
+	for {
+		select {
+		case item := <-t.buffer:
+			remaining = append(remaining, item.msg)
+			continue
+		default:
+		}
+		break
+	}
+
+	if len(remaining) > 0 {
+		flushCtx, cancel := context.WithTimeout(context.Background(), t.writer.WriteTimeout)
+		defer cancel()
+		if err := t.writer.WriteMessages(flushCtx, remaining...); err != nil {
+			t.undeliverable.Add(uint64(len(remaining)))
+			t.logger.Printf("Kafka final redelivery of %d messages failed, messages undeliverable: %v", len(remaining), err)
+		} else {
+			t.delivered.Add(uint64(len(remaining)))
+		}
+	}
+
+	return t.writer.Close()
+}
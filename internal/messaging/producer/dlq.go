@@ -0,0 +1,132 @@
+package producer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"tlng/config"
+	"tlng/internal/models"
+)
+
+// Headers carrying the failure of a dead-lettered task
+const (
+	DLQReasonHeader     = "x-dlq-reason"      // Why the task failed
+	DLQRetryCountHeader = "x-dlq-retry-count" // Retries before it failed
+	DLQFailedAtHeader   = "x-dlq-failed-at"   // RFC 3339 time it failed
+)
+
+// DeadLetter is a permanently failed task and the log message it came from
+type DeadLetter struct {
+	Message    *models.LogMessage
+	Reason     string
+	RetryCount int
+	FailedAt   time.Time
+}
+
+// DLQProducer publishes permanently failed tasks to the dead-letter topic as
+// protobuf log messages keyed by request ID, so they can be inspected and
+// re-injected into the log topic
+type DLQProducer struct {
+	writer  *kafka.Writer
+	logger  *log.Logger
+	timeout time.Duration
+
+	published atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewDLQProducer creates a producer writing to the dead-letter topic on brokers
+func NewDLQProducer(cfg config.DLQConfig, brokers []string, tlsConfig *tls.Config, logger *log.Logger) *DLQProducer {
+	logger.Printf("Dead-letter queue enabled: topic=%s", cfg.Topic)
+	return &DLQProducer{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        cfg.Topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+			Transport:    newTransport(tlsConfig),
+		},
+		logger:  logger,
+		timeout: cfg.WriteTimeout,
+	}
+}
+
+// Published returns the number of dead letters written to Kafka
+func (p *DLQProducer) Published() uint64 {
+	return p.published.Load()
+}
+
+// Failed returns the number of dead letters that could not be written
+func (p *DLQProducer) Failed() uint64 {
+	return p.failed.Load()
+}
+
+// Publish writes dead letters to the dead-letter topic
+func (p *DLQProducer) Publish(ctx context.Context, letters []DeadLetter) error {
+	if len(letters) == 0 {
+		return nil
+	}
+	msgs := make([]kafka.Message, len(letters))
+	for i, l := range letters {
+		value, contentType, err := models.EncodeLogMessage(l.Message, "protobuf")
+		if err != nil {
+			p.failed.Add(uint64(len(letters)))
+			return fmt.Errorf("failed to serialize dead letter (RequestID: %s): %w", l.Message.RequestID, err)
+		}
+		msgs[i] = kafka.Message{
+			Key:   []byte(l.Message.RequestID),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: models.ContentTypeHeader, Value: []byte(contentType)},
+				{Key: DLQReasonHeader, Value: []byte(l.Reason)},
+				{Key: DLQRetryCountHeader, Value: []byte(strconv.Itoa(l.RetryCount))},
+				{Key: DLQFailedAtHeader, Value: []byte(l.FailedAt.UTC().Format(time.RFC3339Nano))},
+			},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	if err := p.writer.WriteMessages(ctx, msgs...); err != nil {
+		p.failed.Add(uint64(len(letters)))
+		return fmt.Errorf("failed to publish %d dead letters: %w", len(letters), err)
+	}
+	p.published.Add(uint64(len(letters)))
+	return nil
+}
+
+// Close flushes and closes the writer
+func (p *DLQProducer) Close() error {
+	return p.writer.Close()
+}
+
+// DecodeDeadLetter decodes a message of the dead-letter topic
+func DecodeDeadLetter(msg kafka.Message) (*DeadLetter, error) {
+	letter := &DeadLetter{}
+	var contentType string
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case models.ContentTypeHeader:
+			contentType = string(h.Value)
+		case DLQReasonHeader:
+			letter.Reason = string(h.Value)
+		case DLQRetryCountHeader:
+			letter.RetryCount, _ = strconv.Atoi(string(h.Value))
+		case DLQFailedAtHeader:
+			letter.FailedAt, _ = time.Parse(time.RFC3339Nano, string(h.Value))
+		}
+	}
+	logMsg, err := models.DecodeLogMessage(msg.Value, contentType)
+	if err != nil {
+		return nil, err
+	}
+	letter.Message = logMsg
+	return letter, nil
+}
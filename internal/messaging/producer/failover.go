@@ -0,0 +1,196 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"tlng/config"
+	"tlng/internal/models"
+)
+
+// FailoverProducer publishes to a primary Kafka cluster and switches to a
+// secondary cluster once the primary has been unreachable for longer than
+// the configured threshold. It fails back as soon as the primary is reachable again.
+type FailoverProducer struct {
+	primary        *KafkaProducer
+	secondary      *KafkaProducer
+	primaryBrokers []string
+	threshold      time.Duration
+	checkInterval  time.Duration
+	dialer         *kafka.Dialer
+	logger         *log.Logger
+
+	usingSecondary atomic.Bool
+	lastPrimaryOK  atomic.Int64 // Unix nanoseconds of the last successful primary probe
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewFailoverProducer creates producers for both clusters and starts probing the primary
+func NewFailoverProducer(cfg config.KafkaProducerConfig, logger *log.Logger) (*FailoverProducer, error) {
+	if len(cfg.SecondaryBrokers) == 0 {
+		return nil, errors.New("kafka failover configuration incomplete: secondary_brokers is required")
+	}
+
+	threshold := cfg.FailoverThreshold
+	if threshold == 0 {
+		threshold = 30 * time.Second
+	}
+
+	checkInterval := cfg.FailoverCheckInterval
+	if checkInterval == 0 {
+		checkInterval = 5 * time.Second
+	}
+
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	primary, err := NewKafkaProducer(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create primary Kafka producer: %w", err)
+	}
+
+	secondaryCfg := cfg
+	secondaryCfg.Brokers = cfg.SecondaryBrokers
+	secondaryCfg.TopicCheck = config.KafkaTopicConfig{} // The DR cluster may legitimately be unreachable at startup
+	secondary, err := NewKafkaProducer(secondaryCfg, logger)
+	if err != nil {
+		primary.Close()
+		return nil, fmt.Errorf("failed to create secondary Kafka producer: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &FailoverProducer{
+		primary:        primary,
+		secondary:      secondary,
+		primaryBrokers: cfg.Brokers,
+		threshold:      threshold,
+		checkInterval:  checkInterval,
+		dialer:         &kafka.Dialer{Timeout: checkInterval, TLS: tlsConfig},
+		logger:         logger,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	p.lastPrimaryOK.Store(time.Now().UnixNano())
+
+	p.wg.Add(1)
+	go p.monitorPrimary()
+
+	logger.Printf("Kafka failover producer created, primary: %v, secondary: %v, threshold: %v",
+		cfg.Brokers, cfg.SecondaryBrokers, threshold)
+
+	return p, nil
+}
+
+// active returns the producer messages should currently be routed to
+func (p *FailoverProducer) active() *KafkaProducer {
+	if p.usingSecondary.Load() {
+		return p.secondary
+	}
+	return p.primary
+}
+
+// Publish sends a message to the active cluster
+func (p *FailoverProducer) Publish(ctx context.Context, msg *models.LogMessage) error {
+	return p.active().Publish(ctx, msg)
+}
+
+// PublishBatch sends log messages in batch to the active cluster
+func (p *FailoverProducer) PublishBatch(ctx context.Context, msgs []*models.LogMessage) error {
+	return p.active().PublishBatch(ctx, msgs)
+}
+
+// UsingSecondary reports whether traffic is currently routed to the secondary cluster
+func (p *FailoverProducer) UsingSecondary() bool {
+	return p.usingSecondary.Load()
+}
+
+// DeliveryStats returns the combined delivery counters of both clusters
+func (p *FailoverProducer) DeliveryStats() DeliveryStats {
+	a, b := p.primary.DeliveryStats(), p.secondary.DeliveryStats()
+	return DeliveryStats{
+		Delivered:      a.Delivered + b.Delivered,
+		Failed:         a.Failed + b.Failed,
+		Retried:        a.Retried + b.Retried,
+		Undeliverable:  a.Undeliverable + b.Undeliverable,
+		RetryBufferLen: a.RetryBufferLen + b.RetryBufferLen,
+	}
+}
+
+// WriterLoad returns the combined writer load of both clusters
+func (p *FailoverProducer) WriterLoad() WriterLoad {
+	a, b := p.primary.WriterLoad(), p.secondary.WriterLoad()
+	return WriterLoad{
+		InFlight:       a.InFlight + b.InFlight,
+		RetryBufferLen: a.RetryBufferLen + b.RetryBufferLen,
+	}
+}
+
+// monitorPrimary periodically probes the primary cluster and flips the active cluster
+func (p *FailoverProducer) monitorPrimary() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkPrimary()
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// checkPrimary probes the primary brokers and updates the failover state
+func (p *FailoverProducer) checkPrimary() {
+	if p.primaryReachable() {
+		p.lastPrimaryOK.Store(time.Now().UnixNano())
+		if p.usingSecondary.CompareAndSwap(true, false) {
+			p.logger.Printf("Kafka primary cluster %v reachable again, failing back", p.primaryBrokers)
+		}
+		return
+	}
+
+	downFor := time.Since(time.Unix(0, p.lastPrimaryOK.Load()))
+	if downFor >= p.threshold && p.usingSecondary.CompareAndSwap(false, true) {
+		p.logger.Printf("Kafka primary cluster %v unreachable for %v, failing over to secondary", p.primaryBrokers, downFor.Truncate(time.Second))
+	}
+}
+
+// primaryReachable reports whether any primary broker accepts a connection
+func (p *FailoverProducer) primaryReachable() bool {
+	for _, broker := range p.primaryBrokers {
+		conn, err := p.dialer.DialContext(p.ctx, "tcp", broker)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops probing and closes both cluster producers
+func (p *FailoverProducer) Close() error {
+	p.cancel()
+	p.wg.Wait()
+
+	primaryErr := p.primary.Close()
+	secondaryErr := p.secondary.Close()
+	return errors.Join(primaryErr, secondaryErr)
+}
+
+var _ Producer = (*FailoverProducer)(nil)         // Compile-time interface check
+var _ DeliveryReporter = (*FailoverProducer)(nil) // Compile-time interface check
+var _ LoadReporter = (*FailoverProducer)(nil)     // Compile-time interface check
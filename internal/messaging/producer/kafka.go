@@ -2,22 +2,30 @@ package producer
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/segmentio/kafka-go"
 	"tlng/config"
+	"tlng/internal/messaging/topic"
 	"tlng/internal/models"
+	"tlng/internal/shard"
 )
 
 // KafkaProducer implements the Producer interface
 type KafkaProducer struct {
-	writer *kafka.Writer
-	logger *log.Logger
-	topic  string
+	writer    *kafka.Writer
+	logger    *log.Logger
+	topic     string
+	encoding  string           // protobuf or json
+	delivery  *deliveryTracker // Non-nil in async mode only
+	byLogHash bool             // Key messages by log hash (partition_key: log_hash)
+	inFlight  atomic.Int64     // Publish and PublishBatch calls in WriteMessages
 }
 
 // NewKafkaProducer creates a new KafkaProducer
@@ -26,6 +34,16 @@ func NewKafkaProducer(cfg config.KafkaProducerConfig, logger *log.Logger) (*Kafk
 		return nil, errors.New("kafka producer configuration incomplete: both brokers and topic are required")
 	}
 
+	tlsConfig, err := cfg.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail fast on a missing or misconfigured topic instead of hanging on writes
+	if err := topic.Ensure(context.Background(), cfg.Brokers, tlsConfig, cfg.Topic, cfg.TopicCheck, logger); err != nil {
+		return nil, fmt.Errorf("kafka topic check failed: %w", err)
+	}
+
 	// Set defaults for batch settings if not configured
 	batchSize := cfg.BatchSize
 	if batchSize == 0 {
@@ -72,11 +90,41 @@ func NewKafkaProducer(cfg config.KafkaProducerConfig, logger *log.Logger) (*Kafk
 		readTimeout = 5 * time.Second
 	}
 
+	// Set delivery retry defaults if not configured
+	retryBufferSize := cfg.RetryBufferSize
+	if retryBufferSize <= 0 {
+		retryBufferSize = 10000
+	}
+
+	retryMaxAttempts := cfg.RetryMaxAttempts
+	if retryMaxAttempts <= 0 {
+		retryMaxAttempts = 5
+	}
+
+	retryBackoff := cfg.RetryBackoff
+	if retryBackoff == 0 {
+		retryBackoff = 200 * time.Millisecond
+	}
+
+	retryMaxBackoff := cfg.RetryMaxBackoff
+	if retryMaxBackoff == 0 {
+		retryMaxBackoff = 10 * time.Second
+	}
+	if retryMaxBackoff < retryBackoff {
+		retryMaxBackoff = retryBackoff
+	}
+
+	// Messages keyed by log hash go to the partition of their hash range
+	var balancer kafka.Balancer = &kafka.LeastBytes{}
+	if cfg.PartitionKey == config.PartitionKeyLogHash {
+		balancer = hashRangeBalancer{}
+	}
+
 	// Configure Kafka Writer
 	w := &kafka.Writer{
 		Addr:     kafka.TCP(cfg.Brokers...),
 		Topic:    cfg.Topic,
-		Balancer: &kafka.LeastBytes{},
+		Balancer: balancer,
 
 		BatchSize:    batchSize,
 		BatchTimeout: batchTimeout,
@@ -90,36 +138,108 @@ func NewKafkaProducer(cfg config.KafkaProducerConfig, logger *log.Logger) (*Kafk
 		WriteTimeout: writeTimeout,
 		ReadTimeout:  readTimeout,
 
+		Transport: newTransport(tlsConfig),
+
 		// Error handling
 		ErrorLogger: kafka.LoggerFunc(func(msg string, args ...interface{}) {
 			logger.Printf("Kafka Writer Error: "+msg, args...)
 		}),
 	}
 
+	p := &KafkaProducer{
+		writer:    w,
+		logger:    logger,
+		topic:     cfg.Topic,
+		encoding:  cfg.Encoding,
+		byLogHash: cfg.PartitionKey == config.PartitionKeyLogHash,
+	}
+
+	// In async mode WriteMessages returns before delivery, so failures are only
+	// visible through delivery reports. Capture them and redeliver via a
+	// synchronous writer instead of losing the messages.
+	if asyncMode {
+		retryWriter := &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Topic:        cfg.Topic,
+			Balancer:     balancer,
+			BatchSize:    batchSize,
+			BatchTimeout: batchTimeout,
+			BatchBytes:   int64(batchBytes),
+			RequiredAcks: requiredAcks,
+			WriteTimeout: writeTimeout,
+			ReadTimeout:  readTimeout,
+			Transport:    newTransport(tlsConfig),
+		}
+		p.delivery = newDeliveryTracker(retryWriter, retryBufferSize, retryMaxAttempts, retryBackoff, retryMaxBackoff, logger)
+		w.Completion = p.delivery.onCompletion
+	}
+
 	logger.Printf("Kafka producer created, connected to Brokers: %v, Topic: %s", cfg.Brokers, cfg.Topic)
 
-	return &KafkaProducer{
-		writer: w,
-		logger: logger,
-		topic:  cfg.Topic,
-	}, nil
+	return p, nil
+}
+
+// hashRangeBalancer sends each message to the partition of its key's hash
+// range, so the partitions split the log hash space into contiguous ranges
+type hashRangeBalancer struct{}
+
+func (hashRangeBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	return partitions[shard.Of(string(msg.Key), len(partitions))]
+}
+
+// key returns the Kafka message key of msg
+func (p *KafkaProducer) key(msg *models.LogMessage) []byte {
+	if p.byLogHash {
+		return []byte(msg.LogHash)
+	}
+	return []byte(msg.RequestID)
+}
+
+// newTransport returns a transport connecting over TLS, or nil (the default
+// transport) when tlsConfig is nil
+func newTransport(tlsConfig *tls.Config) kafka.RoundTripper {
+	if tlsConfig == nil {
+		return nil
+	}
+	return &kafka.Transport{TLS: tlsConfig}
+}
+
+// headers returns the content-type header, the submission's priority header
+// if set and its trace context headers
+func headers(msg *models.LogMessage, contentType string) []kafka.Header {
+	h := make([]kafka.Header, 1, 2+len(msg.TraceContext))
+	h[0] = kafka.Header{Key: models.ContentTypeHeader, Value: []byte(contentType)}
+	if msg.Priority != "" {
+		h = append(h, kafka.Header{Key: models.PriorityHeader, Value: []byte(msg.Priority)})
+	}
+	keys := make([]string, 0, len(msg.TraceContext))
+	for key := range msg.TraceContext {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h = append(h, kafka.Header{Key: key, Value: []byte(msg.TraceContext[key])})
+	}
+	return h
 }
 
 // Publish sends a message
 func (p *KafkaProducer) Publish(ctx context.Context, msg *models.LogMessage) error {
-	msgBytes, err := json.Marshal(msg)
+	msgBytes, contentType, err := models.EncodeLogMessage(msg, p.encoding)
 	if err != nil {
 		return fmt.Errorf("failed to serialize log message: %w", err)
 	}
 
 	kafkaMsg := kafka.Message{
-		// Key can be used for partitioning strategy, using RequestID here
-		Key:   []byte(msg.RequestID),
-		Value: msgBytes,
+		Key:     p.key(msg),
+		Value:   msgBytes,
+		Headers: headers(msg, contentType),
 	}
 
 	// Send message
+	p.inFlight.Add(1)
 	err = p.writer.WriteMessages(ctx, kafkaMsg)
+	p.inFlight.Add(-1)
 	if err != nil {
 		// This error is usually local errors like buffer full or context cancellation
 		p.logger.Printf("Failed to send Kafka message to buffer (RequestID: %s): %v", msg.RequestID, err)
@@ -138,19 +258,22 @@ func (p *KafkaProducer) PublishBatch(ctx context.Context, msgs []*models.LogMess
 
 	kafkaMsgs := make([]kafka.Message, len(msgs))
 	for i, msg := range msgs {
-		msgBytes, err := json.Marshal(msg)
+		msgBytes, contentType, err := models.EncodeLogMessage(msg, p.encoding)
 		if err != nil {
 			return fmt.Errorf("failed to serialize log message (RequestID: %s): %w", msg.RequestID, err)
 		}
 
 		kafkaMsgs[i] = kafka.Message{
-			Key:   []byte(msg.RequestID),
-			Value: msgBytes,
+			Key:     p.key(msg),
+			Value:   msgBytes,
+			Headers: headers(msg, contentType),
 		}
 	}
 
 	// Send messages in batch
+	p.inFlight.Add(1)
 	err := p.writer.WriteMessages(ctx, kafkaMsgs...)
+	p.inFlight.Add(-1)
 	if err != nil {
 		p.logger.Printf("Failed to send Kafka messages in batch (count: %d): %v", len(msgs), err)
 		return fmt.Errorf("failed to batch write to Kafka buffer: %w", err)
@@ -160,10 +283,44 @@ func (p *KafkaProducer) PublishBatch(ctx context.Context, msgs []*models.LogMess
 	return nil
 }
 
+// DeliveryStats returns delivery counters; all zero in sync mode, where
+// failures are returned directly from Publish/PublishBatch
+func (p *KafkaProducer) DeliveryStats() DeliveryStats {
+	if p.delivery == nil {
+		return DeliveryStats{}
+	}
+	return p.delivery.stats()
+}
+
+// WriterLoad returns the writes in progress and, in async mode, the messages
+// waiting for redelivery
+func (p *KafkaProducer) WriterLoad() WriterLoad {
+	load := WriterLoad{InFlight: int(p.inFlight.Load())}
+	if p.delivery != nil {
+		load.RetryBufferLen = len(p.delivery.buffer)
+	}
+	return load
+}
+
 // Close closes the producer
 func (p *KafkaProducer) Close() error {
 	p.logger.Println("Closing Kafka producer (and flushing buffer)...")
-	return p.writer.Close() // Close will attempt to send remaining messages in buffer
+	err := p.writer.Close() // Close will attempt to send remaining messages in buffer
+
+	// Failed deliveries reported during the flush above land in the retry buffer,
+	// so the tracker must be closed after the main writer
+	if p.delivery != nil {
+		if retryErr := p.delivery.close(); retryErr != nil && err == nil {
+			err = retryErr
+		}
+		stats := p.delivery.stats()
+		if stats.Undeliverable > 0 {
+			p.logger.Printf("Kafka producer closed with %d undeliverable messages", stats.Undeliverable)
+		}
+	}
+	return err
 }
 
-var _ Producer = (*KafkaProducer)(nil) // Compile-time interface check
+var _ Producer = (*KafkaProducer)(nil)         // Compile-time interface check
+var _ DeliveryReporter = (*KafkaProducer)(nil) // Compile-time interface check
+var _ LoadReporter = (*KafkaProducer)(nil)     // Compile-time interface check
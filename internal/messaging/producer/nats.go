@@ -0,0 +1,138 @@
+package producer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"tlng/config"
+	"tlng/internal/messaging/natsjs"
+	"tlng/internal/models"
+)
+
+// NATSProducer implements the Producer interface on a NATS JetStream stream
+type NATSProducer struct {
+	client   *natsjs.Client
+	logger   *log.Logger
+	subject  string
+	encoding string        // protobuf or json
+	timeout  time.Duration // Wait for the stream's acknowledgement
+	inFlight atomic.Int64  // Publish and PublishBatch calls waiting for acknowledgements
+}
+
+// NewNATSProducer creates a NATSProducer publishing to subject, which the configured stream must capture
+func NewNATSProducer(ctx context.Context, cfg config.NATSConfig, subject string, logger *log.Logger) (*NATSProducer, error) {
+	if len(cfg.URLs) == 0 || cfg.Stream == "" || subject == "" {
+		return nil, errors.New("nats producer configuration incomplete: urls, stream and subject are required")
+	}
+
+	client, err := natsjs.Dial(cfg, "tlng-gateway", logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	// Fail fast on a missing stream instead of failing every publish
+	streamCfg := cfg
+	streamCfg.Subject = subject
+	if err := natsjs.EnsureStream(ctx, client, streamCfg, logger); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("nats stream check failed: %w", err)
+	}
+
+	logger.Printf("NATS producer created, connected to Servers: %v, Stream: %s, Subject: %s", cfg.URLs, cfg.Stream, subject)
+
+	return &NATSProducer{
+		client:   client,
+		logger:   logger,
+		subject:  subject,
+		encoding: cfg.Encoding,
+		timeout:  cfg.PublishTimeout,
+	}, nil
+}
+
+// natsHeader returns the content-type header, the submission's priority and
+// trace context headers and its request_id as message ID, so the stream drops
+// republished duplicates within its duplicate window
+func natsHeader(msg *models.LogMessage, contentType string) nats.Header {
+	h := nats.Header{}
+	h.Set(models.ContentTypeHeader, contentType)
+	if msg.Priority != "" {
+		h.Set(models.PriorityHeader, msg.Priority)
+	}
+	h.Set(jetstream.MsgIDHeader, msg.RequestID)
+	for key, value := range msg.TraceContext {
+		h.Set(key, value)
+	}
+	return h
+}
+
+// Publish sends a message and waits until the stream has stored it
+func (p *NATSProducer) Publish(ctx context.Context, msg *models.LogMessage) error {
+	return p.PublishBatch(ctx, []*models.LogMessage{msg})
+}
+
+// PublishBatch sends log messages and waits until the stream has stored all of them
+func (p *NATSProducer) PublishBatch(ctx context.Context, msgs []*models.LogMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	natsMsgs := make([]*nats.Msg, len(msgs))
+	for i, msg := range msgs {
+		msgBytes, contentType, err := models.EncodeLogMessage(msg, p.encoding)
+		if err != nil {
+			return fmt.Errorf("failed to serialize log message (RequestID: %s): %w", msg.RequestID, err)
+		}
+		natsMsgs[i] = &nats.Msg{Subject: p.subject, Header: natsHeader(msg, contentType), Data: msgBytes}
+	}
+
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	pending := make([]jetstream.PubAckFuture, 0, len(natsMsgs))
+	for _, m := range natsMsgs {
+		ack, err := p.client.PublishMsgAsync(m)
+		if err != nil {
+			p.logger.Printf("Failed to publish %d NATS messages: %v", len(msgs), err)
+			return fmt.Errorf("failed to publish to NATS: %w", err)
+		}
+		pending = append(pending, ack)
+	}
+	var errs []error
+	for i, ack := range pending {
+		select {
+		case <-ack.Ok():
+		case err := <-ack.Err():
+			errs = append(errs, fmt.Errorf("RequestID %s: %w", msgs[i].RequestID, err))
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("RequestID %s: %w", msgs[i].RequestID, ctx.Err()))
+		}
+	}
+	if len(errs) > 0 {
+		p.logger.Printf("NATS stream did not store %d of %d messages: %v", len(errs), len(msgs), errs[0])
+		return fmt.Errorf("failed to publish to NATS: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// WriterLoad returns the publishes waiting for acknowledgements
+func (p *NATSProducer) WriterLoad() WriterLoad {
+	return WriterLoad{InFlight: int(p.inFlight.Load())}
+}
+
+// Close closes the producer. Publishes are synchronous, so nothing is buffered.
+func (p *NATSProducer) Close() error {
+	p.logger.Println("Closing NATS producer...")
+	return p.client.Close()
+}
+
+var _ Producer = (*NATSProducer)(nil)     // Compile-time interface check
+var _ LoadReporter = (*NATSProducer)(nil) // Compile-time interface check
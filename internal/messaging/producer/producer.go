@@ -16,3 +16,14 @@ type Producer interface {
 	// Close closes the producer connection
 	Close() error
 }
+
+// WriterLoad reports how busy a producer's writer is
+type WriterLoad struct {
+	InFlight       int // Publish and PublishBatch calls waiting on the writer
+	RetryBufferLen int // Messages waiting for redelivery (async mode)
+}
+
+// LoadReporter is implemented by producers that report their writer's load
+type LoadReporter interface {
+	WriterLoad() WriterLoad
+}
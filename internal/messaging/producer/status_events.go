@@ -0,0 +1,94 @@
+package producer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+	"tlng/config"
+	"tlng/internal/events"
+	"tlng/internal/models"
+)
+
+// StatusEventPublisher publishes status transition events from the event bus
+// to Kafka as tlng.internal.StatusChangeEvent messages keyed by request ID.
+// Publishing is best effort: a batch that cannot be written is dropped.
+type StatusEventPublisher struct {
+	writer  *kafka.Writer
+	logger  *log.Logger
+	timeout time.Duration
+
+	published atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// NewStatusEventPublisher creates a publisher writing to the topic on brokers
+func NewStatusEventPublisher(cfg config.StatusEventsConfig, brokers []string, tlsConfig *tls.Config, logger *log.Logger) *StatusEventPublisher {
+	return &StatusEventPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        cfg.Topic,
+			Balancer:     &kafka.Hash{}, // Keeps the events of a request in order
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireOne,
+			Transport:    newTransport(tlsConfig),
+		},
+		logger:  logger,
+		timeout: cfg.WriteTimeout,
+	}
+}
+
+// Published returns the number of events written to Kafka
+func (p *StatusEventPublisher) Published() uint64 {
+	return p.published.Load()
+}
+
+// Dropped returns the number of events discarded after failed writes
+func (p *StatusEventPublisher) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Run publishes events from sub until the bus is closed, then closes the writer
+func (p *StatusEventPublisher) Run(sub *events.Subscription) {
+	p.logger.Printf("Status event publisher started: topic=%s", p.writer.Topic)
+
+	for batch := range sub.C() {
+		if err := p.publish(batch); err != nil {
+			p.dropped.Add(uint64(len(batch)))
+			p.logger.Printf("Status event publisher: Dropping %d events: %v", len(batch), err)
+			continue
+		}
+		p.published.Add(uint64(len(batch)))
+	}
+
+	if err := p.writer.Close(); err != nil {
+		p.logger.Printf("Status event publisher: Failed to close writer: %v", err)
+	}
+	p.logger.Printf("Status event publisher stopped: published=%d, dropped=%d, dropped_by_bus=%d",
+		p.Published(), p.Dropped(), sub.Dropped())
+}
+
+// publish writes one batch of events
+func (p *StatusEventPublisher) publish(batch []events.StatusEvent) error {
+	msgs := make([]kafka.Message, len(batch))
+	for i := range batch {
+		value, err := proto.Marshal(batch[i].Proto())
+		if err != nil {
+			return fmt.Errorf("failed to serialize status event (RequestID: %s): %w", batch[i].RequestID, err)
+		}
+		msgs[i] = kafka.Message{
+			Key:     []byte(batch[i].RequestID),
+			Value:   value,
+			Headers: []kafka.Header{{Key: models.ContentTypeHeader, Value: []byte(models.ContentTypeProtobuf)}},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	return p.writer.WriteMessages(ctx, msgs...)
+}
@@ -0,0 +1,234 @@
+// Package topic verifies, and optionally creates, Kafka topics at service startup
+// so misconfiguration fails fast instead of surfacing as hanging writes.
+package topic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"tlng/config"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Ensure checks that the topic exists on the brokers with the expected partition
+// count and replication factor, creating it first if it is missing and creation is enabled.
+func Ensure(ctx context.Context, brokers []string, tlsConfig *tls.Config, topic string, cfg config.KafkaTopicConfig, logger *log.Logger) error {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+
+	client := newClient(brokers, tlsConfig, cfg.Timeout)
+
+	meta, err := describe(ctx, client, topic)
+	if errors.Is(err, kafka.UnknownTopicOrPartition) && cfg.Create {
+		logger.Printf("Kafka topic %s does not exist, creating it with %d partitions and replication factor %d",
+			topic, cfg.Partitions, cfg.ReplicationFactor)
+		if err := create(ctx, client, topic, cfg); err != nil {
+			return err
+		}
+		meta, err = describe(ctx, client, topic)
+	}
+	if errors.Is(err, kafka.UnknownTopicOrPartition) {
+		return fmt.Errorf("kafka topic %s does not exist on brokers %v (enable topic_check.create or create it manually)", topic, brokers)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.Partitions > 0 && len(meta.Partitions) != cfg.Partitions {
+		return fmt.Errorf("kafka topic %s has %d partitions, expected %d", topic, len(meta.Partitions), cfg.Partitions)
+	}
+	if cfg.ReplicationFactor > 0 {
+		for _, p := range meta.Partitions {
+			if len(p.Replicas) != cfg.ReplicationFactor {
+				return fmt.Errorf("kafka topic %s partition %d has replication factor %d, expected %d",
+					topic, p.ID, len(p.Replicas), cfg.ReplicationFactor)
+			}
+		}
+	}
+
+	logger.Printf("Kafka topic %s verified: %d partitions, brokers %v", topic, len(meta.Partitions), brokers)
+	return nil
+}
+
+// describe fetches the topic metadata without triggering broker-side auto-creation
+func describe(ctx context.Context, client *kafka.Client, topic string) (*kafka.Topic, error) {
+	resp, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata for kafka topic %s: %w", topic, err)
+	}
+	for i := range resp.Topics {
+		t := &resp.Topics[i]
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		return t, nil
+	}
+	return nil, kafka.UnknownTopicOrPartition
+}
+
+// create creates the topic with the configured layout
+func create(ctx context.Context, client *kafka.Client, topic string, cfg config.KafkaTopicConfig) error {
+	resp, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{
+		Topics: []kafka.TopicConfig{{
+			Topic:             topic,
+			NumPartitions:     cfg.Partitions,
+			ReplicationFactor: cfg.ReplicationFactor,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create kafka topic %s: %w", topic, err)
+	}
+	if err := resp.Errors[topic]; err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+		return fmt.Errorf("failed to create kafka topic %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Ping checks that the brokers are reachable by reading the cluster metadata
+func Ping(ctx context.Context, brokers []string, tlsConfig *tls.Config, timeout time.Duration) error {
+	client := newClient(brokers, tlsConfig, timeout)
+	if _, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{}}); err != nil {
+		return fmt.Errorf("kafka brokers %v unreachable: %w", brokers, err)
+	}
+	return nil
+}
+
+// Check verifies that the topic has at least one partition with a leader. A
+// topic that does not exist yet passes with a warning, since brokers may
+// create it on first write.
+func Check(ctx context.Context, brokers []string, tlsConfig *tls.Config, topic string, timeout time.Duration, logger *log.Logger) error {
+	client := newClient(brokers, tlsConfig, timeout)
+	meta, err := describe(ctx, client, topic)
+	if errors.Is(err, kafka.UnknownTopicOrPartition) {
+		logger.Printf("Warning: kafka topic %s does not exist yet; writes rely on broker auto-creation", topic)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("kafka topic %s: %w", topic, err)
+	}
+	for _, p := range meta.Partitions {
+		if p.Error == nil && p.Leader.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("kafka topic %s has no partition with a leader", topic)
+}
+
+// Offsets returns the first offset and the end offset (one past the last
+// message) of every partition of the topic
+func Offsets(ctx context.Context, brokers []string, tlsConfig *tls.Config, topic string, timeout time.Duration) ([]kafka.PartitionOffsets, error) {
+	client := newClient(brokers, tlsConfig, timeout)
+	meta, err := describe(ctx, client, topic)
+	if err != nil {
+		return nil, fmt.Errorf("kafka topic %s: %w", topic, err)
+	}
+	requests := make([]kafka.OffsetRequest, 0, 2*len(meta.Partitions))
+	for _, p := range meta.Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+	resp, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of kafka topic %s: %w", topic, err)
+	}
+	offsets := resp.Topics[topic]
+	for _, p := range offsets {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets of kafka topic %s partition %d: %w", topic, p.Partition, p.Error)
+		}
+	}
+	return offsets, nil
+}
+
+// Position is the offset of a partition at a point in time
+type Position struct {
+	Partition   int
+	FirstOffset int64     // First retained offset
+	EndOffset   int64     // One past the last message
+	Offset      int64     // First message published at or after the time; EndOffset if none
+	Time        time.Time // Publish time of the message at Offset; zero if none
+}
+
+// OffsetsAt returns the position of every partition of the topic at the given time
+func OffsetsAt(ctx context.Context, brokers []string, tlsConfig *tls.Config, topic string, at time.Time, timeout time.Duration) ([]Position, error) {
+	client := newClient(brokers, tlsConfig, timeout)
+	meta, err := describe(ctx, client, topic)
+	if err != nil {
+		return nil, fmt.Errorf("kafka topic %s: %w", topic, err)
+	}
+	requests := make([]kafka.OffsetRequest, 0, 3*len(meta.Partitions))
+	for _, p := range meta.Partitions {
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID), kafka.TimeOffsetOf(p.ID, at))
+	}
+	resp, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: requests}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list offsets of kafka topic %s: %w", topic, err)
+	}
+
+	positions := make([]Position, 0, len(resp.Topics[topic]))
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to list offsets of kafka topic %s partition %d: %w", topic, p.Partition, p.Error)
+		}
+		pos := Position{Partition: p.Partition, FirstOffset: p.FirstOffset, EndOffset: p.LastOffset, Offset: p.LastOffset}
+		// The broker answers -1 when no message was published at or after the time
+		for offset, t := range p.Offsets {
+			if offset >= 0 {
+				pos.Offset, pos.Time = offset, t
+			}
+		}
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Partition < positions[j].Partition })
+	return positions, nil
+}
+
+// CommittedOffsets returns the offsets the consumer group committed on every
+// partition of the topic, keyed by partition; -1 where it committed none
+func CommittedOffsets(ctx context.Context, brokers []string, tlsConfig *tls.Config, groupID, topic string, timeout time.Duration) (map[int]int64, error) {
+	client := newClient(brokers, tlsConfig, timeout)
+	meta, err := describe(ctx, client, topic)
+	if err != nil {
+		return nil, fmt.Errorf("kafka topic %s: %w", topic, err)
+	}
+	partitions := make([]int, len(meta.Partitions))
+	for i, p := range meta.Partitions {
+		partitions[i] = p.ID
+	}
+	resp, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: groupID, Topics: map[string][]int{topic: partitions}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of consumer group %s: %w", groupID, err)
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("failed to fetch offsets of consumer group %s: %w", groupID, resp.Error)
+	}
+	committed := make(map[int]int64, len(partitions))
+	for _, p := range resp.Topics[topic] {
+		if p.Error != nil {
+			return nil, fmt.Errorf("failed to fetch offsets of consumer group %s on kafka topic %s partition %d: %w", groupID, topic, p.Partition, p.Error)
+		}
+		committed[p.Partition] = p.CommittedOffset
+	}
+	return committed, nil
+}
+
+// newClient creates a metadata client for the brokers, over TLS if tlsConfig is set
+func newClient(brokers []string, tlsConfig *tls.Config, timeout time.Duration) *kafka.Client {
+	client := &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: timeout}
+	if tlsConfig != nil {
+		client.Transport = &kafka.Transport{TLS: tlsConfig}
+	}
+	return client
+}
@@ -0,0 +1,407 @@
+// Package metrics collects process-wide counters, gauges and histograms and renders
+// them in the Prometheus text or OpenMetrics exposition format, the latter with
+// the trace IDs of exemplary histogram observations. Metrics register with the
+// Default registry when created, usually as package variables of the code
+// that observes them.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bucket layouts for histograms
+var (
+	LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}                 // Seconds
+	SizeBuckets    = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}                           // Items
+	ByteBuckets    = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20} // Bytes
+)
+
+// Default is the registry metrics are created in and /metrics renders
+var Default = NewRegistry()
+
+// metric is a registered counter, gauge or histogram
+type metric interface {
+	name() string
+	shape() string // Type and label names; metrics of one name must agree on it
+	write(w io.Writer, f Format)
+}
+
+// Registry holds a set of uniquely named metrics
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// register adds m and returns it. A metric of the same name and shape that is
+// already registered is returned instead, so both creators count into it; one
+// of another shape keeps the name and m is left unexported.
+func (r *Registry) register(m metric) metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, dup := r.metrics[m.name()]
+	if !dup {
+		r.metrics[m.name()] = m
+		return m
+	}
+	if existing.shape() == m.shape() {
+		return existing
+	}
+	log.Printf("Warning: metric %s is already registered as %s, not exporting it as %s", m.name(), existing.shape(), m.shape())
+	return m
+}
+
+// Write renders all metrics in the Prometheus text format, ordered by name
+func (r *Registry) Write(w io.Writer) {
+	r.WriteFormat(w, FormatText)
+}
+
+// WriteFormat renders all metrics in format f, ordered by name, without the
+// closing line of the format (see Format.WriteEOF)
+func (r *Registry) WriteFormat(w io.Writer, f Format) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+	for _, name := range names {
+		r.mu.RLock()
+		m := r.metrics[name]
+		r.mu.RUnlock()
+		m.write(w, f)
+	}
+}
+
+// Handler serves the registry's metrics, in OpenMetrics if the scraper's
+// Accept header asks for it
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		f := NegotiateFormat(req.Header.Get("Accept"))
+		w.Header().Set("Content-Type", f.ContentType())
+		r.WriteFormat(w, f)
+		f.WriteEOF(w)
+	})
+}
+
+// series holds the label names of a metric and its values per label set
+type series[V any] struct {
+	metricName string
+	help       string
+	labels     []string
+	newValue   func() V
+
+	mu     sync.Mutex
+	values map[string]V // Keyed by the joined label values
+	keys   map[string][]string
+}
+
+// get returns the value of a label set, creating it on first use
+func (s *series[V]) get(labelValues []string) V {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", s.metricName, len(s.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		v = s.newValue()
+		s.values[key] = v
+		s.keys[key] = append([]string(nil), labelValues...)
+	}
+	return v
+}
+
+// each calls fn for every label set in a stable order
+func (s *series[V]) each(fn func(labelValues []string, v V)) {
+	s.mu.Lock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.mu.Lock()
+		v, labelValues := s.values[key], s.keys[key]
+		s.mu.Unlock()
+		fn(labelValues, v)
+	}
+}
+
+func (s *series[V]) name() string {
+	return s.metricName
+}
+
+// labelShape renders the label names for shape
+func (s *series[V]) labelShape() string {
+	return "(" + strings.Join(s.labels, ",") + ")"
+}
+
+// labelString renders label pairs as {a="x",b="y"}, with extra pairs appended
+func (s *series[V]) labelString(labelValues []string, extra ...string) string {
+	if len(labelValues) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labelValues)+len(extra)/2)
+	for i, v := range labelValues {
+		pairs = append(pairs, s.labels[i]+"="+QuoteLabelValue(v))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+QuoteLabelValue(extra[i+1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// QuoteLabelValue renders a label value in double quotes, escaping backslash,
+// double quote and newline as both exposition formats require
+func QuoteLabelValue(v string) string {
+	return `"` + labelValueEscaper.Replace(v) + `"`
+}
+
+// Counter is a monotonically increasing count per label set
+type Counter struct {
+	series[*counterValue]
+}
+
+type counterValue struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// NewCounter creates a counter with the given label names in the Default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewCounter creates a counter with the given label names in the registry
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{series[*counterValue]{
+		metricName: name, help: help, labels: labels,
+		newValue: func() *counterValue { return &counterValue{} },
+		values:   make(map[string]*counterValue), keys: make(map[string][]string),
+	}}
+	if existing, ok := r.register(c).(*Counter); ok {
+		return existing
+	}
+	return c
+}
+
+// Inc adds one to the count of the label set
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the count of the label set
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.metricName + " cannot decrease")
+	}
+	cv := c.get(labelValues)
+	cv.mu.Lock()
+	cv.v += v
+	cv.mu.Unlock()
+}
+
+func (c *Counter) shape() string {
+	return "counter" + c.labelShape()
+}
+
+func (c *Counter) write(w io.Writer, f Format) {
+	f.WriteHeader(w, c.metricName, c.help, "counter")
+	sample := f.CounterSample(c.metricName)
+	c.each(func(labelValues []string, cv *counterValue) {
+		cv.mu.Lock()
+		v := cv.v
+		cv.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", sample, c.labelString(labelValues), formatFloat(v))
+	})
+}
+
+// Gauge is a value per label set that can go up and down
+type Gauge struct {
+	series[*gaugeValue]
+}
+
+type gaugeValue struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// NewGauge creates a gauge with the given label names in the Default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// NewGauge creates a gauge with the given label names in the registry
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{series[*gaugeValue]{
+		metricName: name, help: help, labels: labels,
+		newValue: func() *gaugeValue { return &gaugeValue{} },
+		values:   make(map[string]*gaugeValue), keys: make(map[string][]string),
+	}}
+	if existing, ok := r.register(g).(*Gauge); ok {
+		return existing
+	}
+	return g
+}
+
+// Set sets the value of the label set
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	gv := g.get(labelValues)
+	gv.mu.Lock()
+	gv.v = v
+	gv.mu.Unlock()
+}
+
+func (g *Gauge) shape() string {
+	return "gauge" + g.labelShape()
+}
+
+func (g *Gauge) write(w io.Writer, f Format) {
+	f.WriteHeader(w, g.metricName, g.help, "gauge")
+	g.each(func(labelValues []string, gv *gaugeValue) {
+		gv.mu.Lock()
+		v := gv.v
+		gv.mu.Unlock()
+		fmt.Fprintf(w, "%s%s %s\n", g.metricName, g.labelString(labelValues), formatFloat(v))
+	})
+}
+
+// Histogram counts observations in cumulative buckets per label set
+type Histogram struct {
+	series[*histogramValue]
+	buckets []float64
+}
+
+type histogramValue struct {
+	mu        sync.Mutex
+	counts    []uint64   // Per bucket, not cumulative; the last one is +Inf
+	exemplars []exemplar // Per bucket, like counts
+	sum       float64
+}
+
+// exemplar is the latest observation of a bucket made in a trace
+type exemplar struct {
+	traceID string // Empty if no observation of the bucket had one
+	value   float64
+	at      time.Time
+}
+
+// NewHistogram creates a histogram with the given upper bucket bounds and
+// label names in the Default registry
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewHistogram creates a histogram with the given upper bucket bounds and
+// label names in the registry
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{buckets: buckets}
+	h.series = series[*histogramValue]{
+		metricName: name, help: help, labels: labels,
+		newValue: func() *histogramValue {
+			return &histogramValue{counts: make([]uint64, len(buckets)+1), exemplars: make([]exemplar, len(buckets)+1)}
+		},
+		values: make(map[string]*histogramValue), keys: make(map[string][]string),
+	}
+	if existing, ok := r.register(h).(*Histogram); ok {
+		return existing
+	}
+	return h
+}
+
+// Observe records a value for the label set
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.ObserveWithExemplar(v, "", labelValues...)
+}
+
+// ObserveWithExemplar records a value for the label set, made in the trace
+// traceID; the latest such observation of each bucket is its exemplar in
+// OpenMetrics. An empty traceID records the value only.
+func (h *Histogram) ObserveWithExemplar(v float64, traceID string, labelValues ...string) {
+	hv := h.get(labelValues)
+	i := sort.SearchFloat64s(h.buckets, v) // First bucket whose bound is >= v
+	hv.mu.Lock()
+	hv.counts[i]++
+	hv.sum += v
+	if traceID != "" {
+		hv.exemplars[i] = exemplar{traceID: traceID, value: v, at: time.Now()}
+	}
+	hv.mu.Unlock()
+}
+
+// ObserveDuration records a duration in seconds for the label set
+func (h *Histogram) ObserveDuration(d time.Duration, labelValues ...string) {
+	h.Observe(d.Seconds(), labelValues...)
+}
+
+// ObserveDurationWithExemplar records a duration in seconds for the label
+// set, made in the trace traceID, like ObserveWithExemplar
+func (h *Histogram) ObserveDurationWithExemplar(d time.Duration, traceID string, labelValues ...string) {
+	h.ObserveWithExemplar(d.Seconds(), traceID, labelValues...)
+}
+
+func (h *Histogram) shape() string {
+	bounds := make([]string, len(h.buckets))
+	for i, b := range h.buckets {
+		bounds[i] = formatFloat(b)
+	}
+	return "histogram" + h.labelShape() + "[" + strings.Join(bounds, ",") + "]"
+}
+
+func (h *Histogram) write(w io.Writer, f Format) {
+	f.WriteHeader(w, h.metricName, h.help, "histogram")
+	h.each(func(labelValues []string, hv *histogramValue) {
+		hv.mu.Lock()
+		counts := append([]uint64(nil), hv.counts...)
+		exemplars := append([]exemplar(nil), hv.exemplars...)
+		sum := hv.sum
+		hv.mu.Unlock()
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d%s\n", h.metricName, h.labelString(labelValues, "le", formatFloat(bound)), cumulative, exemplars[i].suffix(f))
+		}
+		cumulative += counts[len(h.buckets)]
+		fmt.Fprintf(w, "%s_bucket%s %d%s\n", h.metricName, h.labelString(labelValues, "le", "+Inf"), cumulative, exemplars[len(h.buckets)].suffix(f))
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, h.labelString(labelValues), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, h.labelString(labelValues), cumulative)
+	})
+}
+
+// suffix renders the exemplar after its bucket's sample in OpenMetrics, "" if
+// there is none or the format has no exemplars
+func (e exemplar) suffix(f Format) string {
+	if f != FormatOpenMetrics || e.traceID == "" {
+		return ""
+	}
+	return fmt.Sprintf(" # {trace_id=%s} %s %.3f", QuoteLabelValue(e.traceID), formatFloat(e.value), float64(e.at.UnixMilli())/1e3)
+}
+
+// formatFloat renders a sample value the way Prometheus clients do
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
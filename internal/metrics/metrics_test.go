@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRegistry creates a registry with metrics whose label values and help
+// text need escaping, and a counter not named *_total
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	requests := r.NewCounter("requests_total", `Requests by "path" \ method.`+"\nSecond line.", "path")
+	requests.Add(3, `/a"b\c`+"\nd")
+	requests.Inc("/")
+	r.NewCounter("retries", "Retries.").Inc()
+	r.NewGauge("queue_depth", "Queue depth.", "queue").Set(2.5, "q1")
+
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	h.ObserveWithExemplar(0.05, `trace"1`, "get")
+	h.Observe(0.5, "get")
+	h.Observe(2, "get")
+	at := time.UnixMilli(1700000000123)
+	hv := h.get([]string{"get"})
+	for i := range hv.exemplars {
+		hv.exemplars[i].at = at
+	}
+	return r
+}
+
+func TestWriteText(t *testing.T) {
+	var b strings.Builder
+	newTestRegistry().WriteFormat(&b, FormatText)
+	FormatText.WriteEOF(&b)
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 1
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 2.55
+latency_seconds_count{op="get"} 3
+# HELP queue_depth Queue depth.
+# TYPE queue_depth gauge
+queue_depth{queue="q1"} 2.5
+# HELP requests_total Requests by "path" \\ method.\nSecond line.
+# TYPE requests_total counter
+requests_total{path="/"} 1
+requests_total{path="/a\"b\\c\nd"} 3
+# HELP retries Retries.
+# TYPE retries counter
+retries 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("text exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var b strings.Builder
+	newTestRegistry().WriteFormat(&b, FormatOpenMetrics)
+	FormatOpenMetrics.WriteEOF(&b)
+
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 1 # {trace_id="trace\"1"} 0.05 1700000000.123
+latency_seconds_bucket{op="get",le="1"} 2
+latency_seconds_bucket{op="get",le="+Inf"} 3
+latency_seconds_sum{op="get"} 2.55
+latency_seconds_count{op="get"} 3
+# HELP queue_depth Queue depth.
+# TYPE queue_depth gauge
+queue_depth{queue="q1"} 2.5
+# HELP requests Requests by \"path\" \\ method.\nSecond line.
+# TYPE requests counter
+requests_total{path="/"} 1
+requests_total{path="/a\"b\\c\nd"} 3
+# HELP retries Retries.
+# TYPE retries counter
+retries_total 1
+# EOF
+`
+	if got := b.String(); got != want {
+		t.Errorf("OpenMetrics exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandlerNegotiatesFormat(t *testing.T) {
+	h := newTestRegistry().Handler()
+	for _, tt := range []struct {
+		accept, contentType string
+		eof                 bool
+	}{
+		{"", "text/plain; version=0.0.4; charset=utf-8", false},
+		{"application/openmetrics-text; version=1.0.0,text/plain;q=0.5", "application/openmetrics-text; version=1.0.0; charset=utf-8", true},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Accept", tt.accept)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tt.accept, got, tt.contentType)
+		}
+		if eof := strings.HasSuffix(rec.Body.String(), "# EOF\n"); eof != tt.eof {
+			t.Errorf("Accept %q: ends with # EOF = %v, want %v", tt.accept, eof, tt.eof)
+		}
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	r := NewRegistry()
+	a := r.NewCounter("events_total", "Events.", "kind")
+	b := r.NewCounter("events_total", "Events.", "kind")
+	if a != b {
+		t.Error("a counter of the same name and labels was not shared")
+	}
+	b.Inc("x")
+
+	// Another shape is not exported, and does not panic
+	g := r.NewGauge("events_total", "Events.", "kind")
+	g.Set(5, "x")
+	other := r.NewCounter("events_total", "Events.", "source")
+	other.Inc("y")
+
+	var out strings.Builder
+	r.Write(&out)
+	want := "# HELP events_total Events.\n# TYPE events_total counter\nevents_total{kind=\"x\"} 1\n"
+	if got := out.String(); got != want {
+		t.Errorf("exposition = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format is an exposition format of metrics
+type Format int
+
+const (
+	FormatText        Format = iota // Prometheus text format 0.0.4
+	FormatOpenMetrics               // OpenMetrics 1.0.0, which also carries histogram exemplars
+)
+
+// NegotiateFormat returns the format a scraper's Accept header asks for:
+// OpenMetrics if it lists it, the Prometheus text format otherwise
+func NegotiateFormat(accept string) Format {
+	if strings.Contains(accept, "application/openmetrics-text") {
+		return FormatOpenMetrics
+	}
+	return FormatText
+}
+
+// ContentType returns the Content-Type of a response in the format
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	}
+	return "text/plain; version=0.0.4; charset=utf-8"
+}
+
+// WriteHeader writes the HELP and TYPE lines of a metric family. OpenMetrics
+// names a counter family without the _total suffix of its samples.
+func (f Format) WriteHeader(w io.Writer, name, help, metricType string) {
+	if f == FormatOpenMetrics && metricType == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.escapeHelp(help), name, metricType)
+}
+
+// CounterSample returns the sample name of the counter name: the name itself
+// in the text format, and in OpenMetrics the family name with _total, which
+// counter samples must end in
+func (f Format) CounterSample(name string) string {
+	if f == FormatOpenMetrics {
+		return strings.TrimSuffix(name, "_total") + "_total"
+	}
+	return name
+}
+
+var (
+	textHelpEscaper        = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	openMetricsHelpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp escapes HELP text: backslash and newline, and in OpenMetrics
+// also double quote
+func (f Format) escapeHelp(help string) string {
+	if f == FormatOpenMetrics {
+		return openMetricsHelpEscaper.Replace(help)
+	}
+	return textHelpEscaper.Replace(help)
+}
+
+// WriteEOF ends an exposition; OpenMetrics requires the # EOF line
+func (f Format) WriteEOF(w io.Writer) {
+	if f == FormatOpenMetrics {
+		io.WriteString(w, "# EOF\n")
+	}
+}
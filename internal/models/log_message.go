@@ -7,5 +7,13 @@ type LogMessage struct {
 	LogContent        string `json:"LogContent"`
 	LogHash           string `json:"LogHash"`
 	SourceOrgID       string `json:"SourceOrgID"`
-	ReceivedTimestamp string `json:"ReceivedTimestamp"` // Use string for easy JSON serialization
-}
\ No newline at end of file
+	ReceivedTimestamp Timestamp `json:"ReceivedTimestamp"` // Gateway receive time (Unix nanoseconds; legacy strings accepted)
+	Region            string `json:"Region,omitempty"`  // Region that accepted the submission (active-active deployments)
+	ClientTimestamp   *Timestamp `json:"ClientTimestamp,omitempty"` // Client-reported event time, after the gateway's timestamp policy
+	BatchID           string `json:"BatchID,omitempty"` // Gateway batch that published the message, for tracing
+	TraceContext      map[string]string `json:"-"` // W3C trace context of the submission, carried in message headers
+	Priority          string `json:"-"` // Priority lane of the submission (high, normal, low), carried in the priority header
+}
+
+// PriorityHeader names the message header carrying LogMessage.Priority
+const PriorityHeader = "priority"
\ No newline at end of file
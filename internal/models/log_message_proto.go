@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"tlng/proto/internalpb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Kafka messages name the encoding of their value in the content-type header.
+// Messages without the header predate it and are JSON.
+const (
+	ContentTypeHeader   = "content-type"
+	ContentTypeProtobuf = "application/x-protobuf" // Canonical schema of the topic in proto/internal
+	ContentTypeJSON     = "application/json"       // Legacy JSON encoding of LogMessage
+)
+
+// ToProto converts the message to its canonical protobuf form
+func (m *LogMessage) ToProto() *internalpb.LogMessage {
+	p := &internalpb.LogMessage{
+		RequestId:   m.RequestID,
+		LogContent:  m.LogContent,
+		LogHash:     m.LogHash,
+		SourceOrgId: m.SourceOrgID,
+		Region:      m.Region,
+		BatchId:     m.BatchID,
+	}
+	if !m.ReceivedTimestamp.IsZero() {
+		p.ReceivedTimestamp = timestamppb.New(m.ReceivedTimestamp.Time)
+	}
+	if m.ClientTimestamp != nil {
+		p.ClientTimestamp = timestamppb.New(m.ClientTimestamp.Time)
+	}
+	return p
+}
+
+// LogMessageFromProto converts a protobuf message
+func LogMessageFromProto(p *internalpb.LogMessage) *LogMessage {
+	m := &LogMessage{
+		RequestID:   p.GetRequestId(),
+		LogContent:  p.GetLogContent(),
+		LogHash:     p.GetLogHash(),
+		SourceOrgID: p.GetSourceOrgId(),
+		Region:      p.GetRegion(),
+		BatchID:     p.GetBatchId(),
+	}
+	if p.ReceivedTimestamp != nil {
+		m.ReceivedTimestamp = NewTimestamp(p.ReceivedTimestamp.AsTime())
+	}
+	if p.ClientTimestamp != nil {
+		ts := NewTimestamp(p.ClientTimestamp.AsTime())
+		m.ClientTimestamp = &ts
+	}
+	return m
+}
+
+// MarshalProto encodes the message in its canonical protobuf form
+func (m *LogMessage) MarshalProto() ([]byte, error) {
+	return proto.Marshal(m.ToProto())
+}
+
+// UnmarshalProto decodes a protobuf-encoded message
+func (m *LogMessage) UnmarshalProto(data []byte) error {
+	var p internalpb.LogMessage
+	if err := proto.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("invalid protobuf log message: %w", err)
+	}
+	*m = *LogMessageFromProto(&p)
+	return nil
+}
+
+// EncodeLogMessage encodes a message for the queue as protobuf, or as JSON
+// when encoding is "json", and returns its content type
+func EncodeLogMessage(msg *LogMessage, encoding string) (value []byte, contentType string, err error) {
+	if encoding == "json" {
+		value, err = json.Marshal(msg)
+		return value, ContentTypeJSON, err
+	}
+	value, err = msg.MarshalProto()
+	return value, ContentTypeProtobuf, err
+}
+
+// DecodeLogMessage decodes a queue message by its content type; messages
+// without one are legacy JSON
+func DecodeLogMessage(value []byte, contentType string) (*LogMessage, error) {
+	var msg LogMessage
+	switch contentType {
+	case ContentTypeProtobuf:
+		if err := msg.UnmarshalProto(value); err != nil {
+			return nil, err
+		}
+	case ContentTypeJSON, "":
+		if err := json.Unmarshal(value, &msg); err != nil {
+			return nil, fmt.Errorf("invalid JSON log message: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content type '%s'", contentType)
+	}
+	return &msg, nil
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Log severities, in increasing order of urgency (the syslog levels)
+const (
+	SeverityDebug     = "DEBUG"
+	SeverityInfo      = "INFO"
+	SeverityNotice    = "NOTICE"
+	SeverityWarning   = "WARNING"
+	SeverityError     = "ERROR"
+	SeverityCritical  = "CRITICAL"
+	SeverityAlert     = "ALERT"
+	SeverityEmergency = "EMERGENCY"
+)
+
+// Severities lists the log severities in increasing order of urgency
+var Severities = []string{
+	SeverityDebug, SeverityInfo, SeverityNotice, SeverityWarning,
+	SeverityError, SeverityCritical, SeverityAlert, SeverityEmergency,
+}
+
+// severityAliases maps the short syslog keywords to their severity
+var severityAliases = map[string]string{
+	"WARN":  SeverityWarning,
+	"ERR":   SeverityError,
+	"CRIT":  SeverityCritical,
+	"EMERG": SeverityEmergency,
+}
+
+// ParseSeverity returns the canonical form of a severity name. Names are
+// case-insensitive and the short syslog keywords (warn, err, crit, emerg) are
+// accepted; "" stays "" (no severity).
+func ParseSeverity(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if alias, ok := severityAliases[upper]; ok {
+		return alias, nil
+	}
+	for _, s := range Severities {
+		if upper == s {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity '%s', expected one of %s", name, strings.Join(Severities, ", "))
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Timestamp is a point in time carried in queue messages. It is encoded as a
+// JSON number of Unix nanoseconds. For migration safety, decoding also accepts
+// the legacy string encodings still found in older messages: RFC 3339 (as
+// written by the API Gateway) and Unix seconds (as written by the mock consumer).
+type Timestamp struct {
+	time.Time
+}
+
+// NewTimestamp wraps t as a message timestamp
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{Time: t}
+}
+
+// RFC3339 renders the timestamp in UTC with nanosecond precision; this is the
+// canonical form passed to smart contracts. The zero timestamp renders as "".
+func (t Timestamp) RFC3339() string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// MarshalJSON encodes the timestamp as Unix nanoseconds; the zero timestamp encodes as 0
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
+	}
+	return strconv.AppendInt(nil, t.UnixNano(), 10), nil
+}
+
+// UnmarshalJSON decodes Unix nanoseconds, or a legacy RFC 3339 / Unix seconds string
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*t = Timestamp{}
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("invalid timestamp %s: %w", data, err)
+		}
+		return t.parseLegacy(s)
+	}
+
+	nanos, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %s: expected unix nanoseconds", data)
+	}
+	if nanos == 0 {
+		*t = Timestamp{}
+		return nil
+	}
+	*t = Timestamp{Time: time.Unix(0, nanos).UTC()}
+	return nil
+}
+
+// parseLegacy decodes the string encodings used before timestamps were typed
+func (t *Timestamp) parseLegacy(s string) error {
+	if s == "" {
+		*t = Timestamp{}
+		return nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		*t = Timestamp{Time: ts.UTC()}
+		return nil
+	}
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*t = Timestamp{Time: time.Unix(secs, 0).UTC()}
+		return nil
+	}
+	return fmt.Errorf("invalid legacy timestamp %q: expected RFC 3339 or unix seconds", s)
+}
@@ -0,0 +1,211 @@
+// Package parquet writes Apache Parquet files with flat schemas declared at
+// run time, on top of github.com/parquet-go/parquet-go. Columns keep their
+// declared order.
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// Type is the logical column type
+type Type int
+
+const (
+	Int32     Type = iota // INT32
+	Int64                 // INT64
+	Double                // DOUBLE
+	Boolean               // BOOLEAN
+	String                // BYTE_ARRAY annotated as UTF8
+	Timestamp             // INT64 annotated as TIMESTAMP(MICROS, UTC)
+)
+
+// Codec is the page compression codec
+type Codec int32
+
+const (
+	Uncompressed Codec = 0
+	Gzip         Codec = 2
+	Zstd         Codec = 6
+)
+
+// ParseCodec maps a configuration value to a codec
+func ParseCodec(name string) (Codec, error) {
+	switch name {
+	case "", "none", "uncompressed":
+		return Uncompressed, nil
+	case "gzip":
+		return Gzip, nil
+	case "zstd":
+		return Zstd, nil
+	default:
+		return 0, fmt.Errorf("unsupported parquet compression '%s' (must be none, gzip or zstd)", name)
+	}
+}
+
+// compression returns the library codec of c
+func (c Codec) compression() compress.Codec {
+	switch c {
+	case Gzip:
+		return &pq.Gzip
+	case Zstd:
+		return &pq.Zstd
+	default:
+		return &pq.Uncompressed
+	}
+}
+
+// Column describes one column of a flat schema
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool // Nullable; a nil value is written as null
+}
+
+// node returns the schema node of the column
+func (c Column) node() pq.Node {
+	var n pq.Node
+	switch c.Type {
+	case Int32:
+		n = pq.Leaf(pq.Int32Type)
+	case Int64:
+		n = pq.Leaf(pq.Int64Type)
+	case Double:
+		n = pq.Leaf(pq.DoubleType)
+	case Boolean:
+		n = pq.Leaf(pq.BooleanType)
+	case String:
+		n = pq.String()
+	case Timestamp:
+		n = pq.Timestamp(pq.Microsecond)
+	}
+	if c.Optional {
+		return pq.Optional(n)
+	}
+	return pq.Required(n)
+}
+
+// orderedGroup is a group whose fields keep the order of the columns, where
+// a plain group sorts them by name
+type orderedGroup struct {
+	pq.Group
+	fields []pq.Field
+}
+
+func (g orderedGroup) Fields() []pq.Field {
+	return g.fields
+}
+
+// schemaOf returns the Parquet schema of the columns
+func schemaOf(columns []Column) *pq.Schema {
+	group := make(pq.Group, len(columns))
+	for _, col := range columns {
+		group[col.Name] = col.node()
+	}
+	fields := group.Fields()
+	slices.SortFunc(fields, func(a, b pq.Field) int {
+		return slices.IndexFunc(columns, func(c Column) bool { return c.Name == a.Name() }) -
+			slices.IndexFunc(columns, func(c Column) bool { return c.Name == b.Name() })
+	})
+	return pq.NewSchema("schema", orderedGroup{Group: group, fields: fields})
+}
+
+// Writer encodes rows into an in-memory Parquet file
+type Writer struct {
+	columns []Column
+	buf     bytes.Buffer
+	writer  *pq.Writer
+	rows    int
+}
+
+// NewWriter creates a writer for the given flat schema
+func NewWriter(columns []Column, codec Codec) *Writer {
+	w := &Writer{columns: columns}
+	w.writer = pq.NewWriter(&w.buf, schemaOf(columns), pq.Compression(codec.compression()))
+	return w
+}
+
+// Rows returns the number of rows written so far
+func (w *Writer) Rows() int {
+	return w.rows
+}
+
+// Write appends a row; values must match the column order and types.
+// Accepted Go types: int32, int64, float64, bool, string and time.Time,
+// or nil for optional columns.
+func (w *Writer) Write(row []interface{}) error {
+	if len(row) != len(w.columns) {
+		return fmt.Errorf("row has %d values, schema has %d columns", len(row), len(w.columns))
+	}
+	values := make(pq.Row, len(row))
+	for i, col := range w.columns {
+		v, err := value(col, row[i])
+		if err != nil {
+			return err
+		}
+		definition := 0
+		if col.Optional && row[i] != nil {
+			definition = 1
+		}
+		values[i] = v.Level(0, definition, i)
+	}
+	if _, err := w.writer.WriteRows([]pq.Row{values}); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+// value converts a row value to a Parquet value of the column
+func value(col Column, v interface{}) (pq.Value, error) {
+	if v == nil {
+		if !col.Optional {
+			return pq.Value{}, fmt.Errorf("column %s is required but value is nil", col.Name)
+		}
+		return pq.NullValue(), nil
+	}
+	switch col.Type {
+	case Int32:
+		if x, ok := v.(int32); ok {
+			return pq.Int32Value(x), nil
+		}
+	case Int64:
+		if x, ok := v.(int64); ok {
+			return pq.Int64Value(x), nil
+		}
+	case Double:
+		if x, ok := v.(float64); ok {
+			return pq.DoubleValue(x), nil
+		}
+	case Boolean:
+		if x, ok := v.(bool); ok {
+			return pq.BooleanValue(x), nil
+		}
+	case String:
+		if x, ok := v.(string); ok {
+			return pq.ByteArrayValue([]byte(x)), nil
+		}
+	case Timestamp:
+		if x, ok := v.(time.Time); ok {
+			return pq.Int64Value(x.UnixMicro()), nil
+		}
+	}
+	return pq.Value{}, fmt.Errorf("column %s: unexpected value type %T", col.Name, v)
+}
+
+// Bytes completes the Parquet file, attaching the key/value metadata to the
+// footer. The writer cannot be used afterwards.
+func (w *Writer) Bytes(metadata map[string]string) ([]byte, error) {
+	for k, v := range metadata {
+		w.writer.SetKeyValueMetadata(k, v)
+	}
+	if err := w.writer.Close(); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
@@ -0,0 +1,146 @@
+package parquet
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+var testColumns = []Column{
+	{Name: "s", Type: String},
+	{Name: "i32", Type: Int32},
+	{Name: "i64", Type: Int64, Optional: true},
+	{Name: "f", Type: Double},
+	{Name: "b", Type: Boolean, Optional: true},
+	{Name: "ts", Type: Timestamp, Optional: true},
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	rows := [][]interface{}{
+		{"é", int32(-1), int64(1 << 40), 1.5, true, ts},
+		{"", int32(2), nil, -0.25, nil, nil},
+		{"z", int32(3), int64(-7), 0.0, false, ts.Add(time.Microsecond)},
+	}
+
+	for _, codec := range []Codec{Uncompressed, Gzip, Zstd} {
+		w := NewWriter(testColumns, codec)
+		for _, row := range rows {
+			if err := w.Write(row); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+		data, err := w.Bytes(map[string]string{"tlng.schema_version": "2"})
+		if err != nil {
+			t.Fatalf("Bytes failed: %v", err)
+		}
+
+		f, err := pq.OpenFile(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("codec %d: failed to open file: %v", codec, err)
+		}
+		if v, _ := f.Lookup("tlng.schema_version"); v != "2" {
+			t.Errorf("codec %d: schema version metadata = %q, want 2", codec, v)
+		}
+		if n := f.NumRows(); n != int64(len(rows)) {
+			t.Errorf("codec %d: %d rows, want %d", codec, n, len(rows))
+		}
+
+		fields := f.Schema().Fields()
+		for i, col := range testColumns {
+			if fields[i].Name() != col.Name || fields[i].Optional() != col.Optional {
+				t.Errorf("codec %d: column %d = %s (optional %v), want %s (optional %v)", codec, i, fields[i].Name(), fields[i].Optional(), col.Name, col.Optional)
+			}
+		}
+		if lt := fields[0].Type().LogicalType(); lt == nil || lt.UTF8 == nil {
+			t.Errorf("codec %d: string column logical type = %v, want STRING", codec, lt)
+		}
+		if lt := fields[5].Type().LogicalType(); lt == nil || lt.Timestamp == nil || lt.Timestamp.Unit.Micros == nil || !lt.Timestamp.IsAdjustedToUTC {
+			t.Errorf("codec %d: timestamp column logical type = %v, want TIMESTAMP(MICROS, UTC)", codec, lt)
+		}
+		for _, chunk := range f.Metadata().RowGroups[0].Columns {
+			if want := format.CompressionCodec(codec); chunk.MetaData.Codec != want {
+				t.Errorf("codec %d: column %v written with codec %v", codec, chunk.MetaData.PathInSchema, chunk.MetaData.Codec)
+			}
+		}
+
+		got := readRows(t, f)
+		for r, row := range rows {
+			for i, want := range row {
+				v := got[r][i]
+				if want == nil {
+					if !v.IsNull() {
+						t.Errorf("codec %d: row %d column %s = %v, want null", codec, r, testColumns[i].Name, v)
+					}
+					continue
+				}
+				var value interface{}
+				switch testColumns[i].Type {
+				case String:
+					value = v.String()
+				case Int32:
+					value = v.Int32()
+				case Int64:
+					value = v.Int64()
+				case Double:
+					value = v.Double()
+				case Boolean:
+					value = v.Boolean()
+				case Timestamp:
+					value = time.UnixMicro(v.Int64()).UTC()
+				}
+				if value != want {
+					t.Errorf("codec %d: row %d column %s = %v, want %v", codec, r, testColumns[i].Name, value, want)
+				}
+			}
+		}
+	}
+}
+
+// readRows reads all rows of a file
+func readRows(t *testing.T, f *pq.File) []pq.Row {
+	t.Helper()
+	r := pq.NewReader(f)
+	defer r.Close()
+	var rows []pq.Row
+	buf := make([]pq.Row, 16)
+	for {
+		n, err := r.ReadRows(buf)
+		for _, row := range buf[:n] {
+			rows = append(rows, row.Clone())
+		}
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatalf("failed to read rows: %v", err)
+		}
+	}
+}
+
+func TestWriterRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		row  []interface{}
+	}{
+		{"short row", []interface{}{"a"}},
+		{"null in a required column", []interface{}{nil, int32(1), nil, 1.0, nil, nil}},
+		{"wrong type", []interface{}{"a", 1, nil, 1.0, nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWriter(testColumns, Uncompressed)
+			if err := w.Write(tt.row); err == nil {
+				t.Error("Write succeeded, want an error")
+			}
+			if w.Rows() != 0 {
+				t.Errorf("Rows() = %d, want 0", w.Rows())
+			}
+		})
+	}
+}
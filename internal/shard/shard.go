@@ -0,0 +1,49 @@
+// Package shard splits the log hash space into contiguous ranges, so the
+// gateways' Kafka partitioner and the engines' work queues agree on which
+// range a log hash falls in
+package shard
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// prefixLen is the number of leading hex digits of a log hash that place it in a range
+const prefixLen = 8
+
+// Of returns the range logHash falls in, out of n equal ranges of the hash
+// space ordered by hash. Hashes that are not hex fall in a range chosen by
+// their FNV-1a hash.
+func Of(logHash string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	var v uint64
+	if len(logHash) < prefixLen {
+		v = fnvPrefix(logHash)
+	} else if p, err := strconv.ParseUint(logHash[:prefixLen], 16, 32); err == nil {
+		v = p
+	} else {
+		v = fnvPrefix(logHash)
+	}
+	return int(v * uint64(n) >> 32)
+}
+
+// fnvPrefix returns the 32-bit FNV-1a hash of s
+func fnvPrefix(s string) uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return uint64(h.Sum32())
+}
+
+// Bounds returns the first and last hash prefixes of range i out of n, e.g.
+// "00000000" and "3fffffff" for the first of four ranges
+func Bounds(i, n int) (string, string) {
+	if n <= 1 {
+		return fmt.Sprintf("%08x", 0), fmt.Sprintf("%08x", uint32(1<<32-1))
+	}
+	lo := (uint64(i)<<32 + uint64(n) - 1) / uint64(n)
+	hi := (uint64(i+1)<<32+uint64(n)-1)/uint64(n) - 1
+	return fmt.Sprintf("%08x", lo), fmt.Sprintf("%08x", hi)
+}
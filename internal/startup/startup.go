@@ -0,0 +1,110 @@
+// Package startup brings services up in Kubernetes-style environments where
+// dependencies (database, Kafka, blockchain) may still be starting: each
+// dependency is retried with backoff for a configurable time, and a sequence
+// of round-trip self-checks runs before the service declares itself ready.
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tlng/config"
+)
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable, so Wait returns it immediately
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Check is one self-check run before the service declares itself ready
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Orchestrator runs the startup sequence of a service
+type Orchestrator struct {
+	cfg    config.StartupConfig
+	logger *log.Logger
+	start  time.Time
+	checks []Check
+}
+
+// New creates a new Orchestrator
+func New(cfg config.StartupConfig, logger *log.Logger) *Orchestrator {
+	return &Orchestrator{cfg: cfg, logger: logger, start: time.Now()}
+}
+
+// Wait calls connect until it succeeds, retrying with exponential backoff until
+// the dependency's timeout elapses, ctx is cancelled or connect returns a
+// Permanent error
+func (o *Orchestrator) Wait(ctx context.Context, name string, wait config.DependencyWaitConfig, connect func(ctx context.Context) error) error {
+	deadline := time.Now().Add(wait.Timeout)
+	backoff := wait.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := connect(ctx)
+		if err == nil {
+			if attempt > 1 {
+				o.logger.Printf("Startup: %s available after %d attempts", name, attempt)
+			}
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return fmt.Errorf("%s: %w", name, permanent.err)
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%s unavailable after %d attempts in %v: %w", name, attempt, wait.Timeout, err)
+		}
+
+		delay := min(backoff, remaining)
+		o.logger.Printf("Startup: %s not available (attempt %d), retrying in %v: %v", name, attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %w", name, ctx.Err())
+		case <-time.After(delay):
+		}
+		backoff = min(backoff*2, wait.MaxBackoff)
+	}
+}
+
+// AddCheck registers a self-check; checks run in registration order
+func (o *Orchestrator) AddCheck(name string, run func(ctx context.Context) error) {
+	o.checks = append(o.checks, Check{Name: name, Run: run})
+}
+
+// Ready runs the self-checks and fails on the first error. The service must
+// not accept work before Ready returns nil.
+func (o *Orchestrator) Ready(ctx context.Context) error {
+	if o.cfg.SkipSelfCheck {
+		o.logger.Printf("Startup: Self-checks skipped, ready after %v", time.Since(o.start).Round(time.Millisecond))
+		return nil
+	}
+	for _, check := range o.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, o.cfg.SelfCheckTimeout)
+		start := time.Now()
+		err := check.Run(checkCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("self-check %s failed: %w", check.Name, err)
+		}
+		o.logger.Printf("Startup: Self-check %s passed (%v)", check.Name, time.Since(start).Round(time.Millisecond))
+	}
+	o.logger.Printf("Startup: All %d self-checks passed, ready after %v", len(o.checks), time.Since(o.start).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package tracing sets up OpenTelemetry tracing and carries W3C trace context
+// across Kafka, so a submission can be followed from the gateway's HTTP or
+// gRPC handler to the engine's blockchain transaction.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"tlng/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys shared by the gateway and engine
+const (
+	AttrRequestID   = attribute.Key("tlng.request_id")
+	AttrOrgID       = attribute.Key("tlng.org_id")
+	AttrLogHash     = attribute.Key("tlng.log_hash")
+	AttrBatchID     = attribute.Key("tlng.batch_id")
+	AttrBatchSize   = attribute.Key("tlng.batch_size")
+	AttrTarget      = attribute.Key("tlng.target")
+	AttrTxHash      = attribute.Key("tlng.tx_hash")
+	AttrBlockHeight = attribute.Key("tlng.block_height")
+)
+
+// propagator reads and writes W3C traceparent, tracestate and baggage
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// shutdownTimeout bounds the export of the spans still buffered on close
+const shutdownTimeout = 5 * time.Second
+
+// Setup installs the global W3C propagator and, if tracing is enabled, a tracer
+// provider exporting to the OTLP collector. The returned function flushes the
+// buffered spans and stops the exporter.
+func Setup(ctx context.Context, cfg config.TracingConfig, logger *log.Logger) (func() error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if !cfg.Enabled {
+		return func() error { return nil }, nil
+	}
+
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	logger.Printf("Tracing enabled: exporting spans of %s to %s (sample ratio %g)", cfg.ServiceName, cfg.Endpoint, cfg.SampleRatio)
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// Tracer returns the named tracer of the global provider
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Carrier returns the trace context of ctx as W3C headers, nil if ctx has no span
+func Carrier(ctx context.Context) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier
+}
+
+// TraceID returns the trace ID of the span of ctx if it is sampled, for
+// histogram exemplars; "" otherwise, as no trace would be exported to jump to
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsSampled() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// FromCarrier returns ctx with the remote span context held in carrier as its parent
+func FromCarrier(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// Links returns links to the span contexts held in carriers, skipping empty ones
+func Links(carriers []map[string]string) []trace.Link {
+	links := make([]trace.Link, 0, len(carriers))
+	for _, carrier := range carriers {
+		sc := trace.SpanContextFromContext(FromCarrier(context.Background(), carrier))
+		if sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	return links
+}
+
+// Headers lists the header names the propagator writes, for copying trace
+// context between carriers such as Kafka message headers
+func Headers() []string {
+	return propagator.Fields()
+}
+
+// End records err, if any, as the span's error status and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
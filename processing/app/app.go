@@ -0,0 +1,810 @@
+// Package app assembles the Attestation Engine from its configuration and
+// dependencies, so that cmd/engine and other programs, including in-process
+// tests, can run it.
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/config"
+	"tlng/internal/buildinfo"
+	"tlng/internal/clock"
+	"tlng/internal/events"
+	"tlng/internal/fingerprint"
+	"tlng/internal/idgen"
+	"tlng/internal/messaging/consumer"
+	"tlng/internal/messaging/producer"
+	"tlng/internal/messaging/topic"
+	"tlng/internal/startup"
+	"tlng/internal/tracing"
+	worker "tlng/processing"
+	"tlng/processing/monitor"
+	"tlng/storage/clickhouse"
+	"tlng/storage/store"
+	"tlng/svcauth"
+)
+
+// Deps are the external dependencies of the engine. New opens the ones left
+// nil from the configuration and closes them on Shutdown; injected ones are
+// left to the caller to close, except consumers, which the workers take over.
+type Deps struct {
+	Store        store.Store
+	Chain        blockchain.BlockchainClient
+	RouteClients map[string]blockchain.BlockchainClient // By routing target name
+	PeerStores   map[string]store.Store                 // By peer region name, used with region.reconcile
+	Consumers    []consumer.Consumer                    // Replace the main topic's consumers
+
+	Clock    clock.Clock     // Replaces the system clock of the workers, e.g. with clock.NewFake in tests
+	BatchIDs idgen.Generator // Replaces the UUIDv7 generator of engine batch IDs
+}
+
+// App is a configured Attestation Engine. New wires it, Run consumes until
+// its context is done and Shutdown drains the workers.
+type App struct {
+	cfg    *config.EngineConfig
+	logger *log.Logger
+
+	store        store.Store
+	chain        blockchain.BlockchainClient
+	routeClients map[string]blockchain.BlockchainClient
+	peerStores   map[string]store.Store
+
+	consumers      []consumer.Consumer // Main topic, closed on Shutdown
+	largeConsumers []consumer.Consumer // Size-tier topic, closed on Shutdown
+
+	eventBus *events.Bus
+	sinks    []func(ctx context.Context) // Status event sinks, started by Run under sinksCtx
+	dlq      *producer.DLQProducer
+	sinksWg  sync.WaitGroup
+
+	republisher *worker.Republisher // Publishes parked messages again; nil unless stuck task scans or the fleet requeue tasks
+
+	fleet    *worker.Fleet
+	readOnly *worker.ReadOnlyMode
+	spool    *worker.IntentSpool
+	verifier svcauth.Verifier
+
+	workers       []*worker.Worker
+	workersWg     sync.WaitGroup
+	monitorServer *monitor.Server
+	adminServer   *monitor.AdminServer
+
+	// Batches consumed before Run's context is done are processed under
+	// drainCtx, which Shutdown cancels after the shutdown timeout
+	drainCtx    context.Context
+	drainCancel context.CancelFunc
+	// Status event sinks stop retrying once sinksCtx is done, which Shutdown
+	// cancels after the drain timeout
+	sinksCtx    context.Context
+	sinksCancel context.CancelFunc
+	fleetCancel context.CancelFunc
+	fleetDone   chan struct{}
+
+	inFlight int64        // Messages in flight when Run's context was done
+	before   worker.Stats // Worker counters when Run's context was done
+
+	clock    clock.Clock     // nil for the system clock
+	batchIDs idgen.Generator // nil for UUIDv7
+
+	closers []func() error // Dependencies opened by New, closed in reverse order
+}
+
+// New opens the dependencies missing from deps, waiting for them to come up,
+// creates the consumers and status event sinks, runs the startup self-checks
+// and registers the instance in the fleet.
+func New(ctx context.Context, cfg *config.EngineConfig, deps Deps, logger *log.Logger) (a *App, err error) {
+	a = &App{
+		cfg:          cfg,
+		logger:       logger,
+		store:        deps.Store,
+		chain:        deps.Chain,
+		routeClients: make(map[string]blockchain.BlockchainClient),
+		peerStores:   make(map[string]store.Store),
+		consumers:    deps.Consumers,
+		clock:        deps.Clock,
+		batchIDs:     deps.BatchIDs,
+		fleetCancel:  func() {},
+		fleetDone:    make(chan struct{}),
+	}
+	close(a.fleetDone)
+	a.drainCtx, a.drainCancel = context.WithCancel(context.Background())
+	a.sinksCtx, a.sinksCancel = context.WithCancel(context.Background())
+	defer func() {
+		if err != nil {
+			a.close()
+		}
+	}()
+
+	// Export spans when tracing is enabled; closed last, after the workers are drained
+	closeTracing, err := tracing.Setup(ctx, cfg.Tracing, logger)
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, closeTracing)
+
+	boot := startup.New(cfg.Startup, logger)
+	if err := a.openDeps(ctx, boot, deps); err != nil {
+		return nil, err
+	}
+
+	// Consume the region-scoped topic in region-aware deployments
+	cfg.KafkaConsumer.Topic = cfg.Region.Topic(cfg.KafkaConsumer.Topic)
+	cfg.Messaging.NATS.Subject = cfg.Region.Topic(cfg.Messaging.NATS.Subject)
+	kafkaTLS, err := cfg.KafkaConsumer.TLS.TLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kafka TLS configuration: %w", err)
+	}
+	useKafka := !cfg.Messaging.UsesNATS() && len(cfg.KafkaConsumer.Brokers) > 0 && cfg.KafkaConsumer.Brokers[0] != "mock://local"
+	if cfg.Messaging.UsesNATS() && a.consumers == nil {
+		// NATS JetStream replaces Kafka; it waits on the startup.kafka budget
+		err = boot.Wait(ctx, "nats", cfg.Startup.Kafka, a.openNATSConsumers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize NATS consumers: %w", err)
+		}
+	}
+	if useKafka {
+		err = boot.Wait(ctx, "kafka", cfg.Startup.Kafka, func(ctx context.Context) error {
+			return topic.Ping(ctx, cfg.KafkaConsumer.Brokers, kafkaTLS, cfg.Startup.SelfCheckTimeout)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("kafka unavailable: %w", err)
+		}
+	}
+	if err := a.openConsumers(useKafka); err != nil {
+		return nil, err
+	}
+	if err := a.openSinks(ctx, useKafka, kafkaTLS); err != nil {
+		return nil, err
+	}
+
+	// Self-checks before consuming: a read through the State DB, the consumer
+	// topic's metadata and a read-only contract query on the chain
+	boot.AddCheck("database", func(ctx context.Context) error {
+		_, err := a.store.GetLogStatusByRequestID(ctx, "startup-self-check")
+		if errors.Is(err, store.ErrLogNotFound) {
+			return nil
+		}
+		return err
+	})
+	if useKafka {
+		largeTopic := cfg.Region.Topic(cfg.SizeTier.Topic)
+		boot.AddCheck("kafka", func(ctx context.Context) error {
+			if err := topic.Check(ctx, cfg.KafkaConsumer.Brokers, kafkaTLS, cfg.KafkaConsumer.Topic, cfg.Startup.SelfCheckTimeout, logger); err != nil {
+				return err
+			}
+			if len(a.largeConsumers) > 0 {
+				return topic.Check(ctx, cfg.KafkaConsumer.Brokers, kafkaTLS, largeTopic, cfg.Startup.SelfCheckTimeout, logger)
+			}
+			return nil
+		})
+	}
+	boot.AddCheck("blockchain", func(ctx context.Context) error {
+		return checkBlockchain(ctx, a.chain)
+	})
+	for name, client := range a.routeClients {
+		boot.AddCheck("blockchain target "+name, func(ctx context.Context) error {
+			return checkBlockchain(ctx, client)
+		})
+	}
+	if err := boot.Ready(ctx); err != nil {
+		return nil, fmt.Errorf("startup self-check failed: %w", err)
+	}
+
+	// Register in the fleet before locking tasks under the instance ID
+	if cfg.Heartbeat.Enabled {
+		a.fleet = worker.NewFleet(cfg.Heartbeat, cfg.Region.Name, a.store, logger)
+		if a.republisher != nil {
+			a.fleet.SetRepublisher(a.republisher)
+		}
+		if err := a.fleet.Register(ctx); err != nil {
+			return nil, fmt.Errorf("failed to register engine instance: %w", err)
+		}
+	}
+
+	// While the State DB is read-only during failover, workers spool their batches instead of anchoring them
+	if cfg.ReadOnlyMode.Enabled {
+		a.spool, err = worker.OpenIntentSpool(cfg.ReadOnlyMode.SpoolPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open intent spool: %w", err)
+		}
+		a.readOnly = worker.NewReadOnlyMode(cfg.ReadOnlyMode, a.spool, a.store, logger)
+		logger.Printf("Read-only mode enabled: batches are spooled to %s while the State DB rejects writes", cfg.ReadOnlyMode.SpoolPath)
+	}
+
+	if cfg.Monitoring.AdminGRPCAddr != "" && cfg.ServiceAuth.Enabled() {
+		a.verifier, err = svcauth.NewVerifier(cfg.ServiceAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize service authentication: %w", err)
+		}
+		logger.Printf("Admin gRPC callers authenticated with %s (trust domain %s)", cfg.ServiceAuth.Mode, cfg.ServiceAuth.TrustDomain)
+	}
+	return a, nil
+}
+
+// openDeps fills in the State DBs and blockchain clients missing from deps
+func (a *App) openDeps(ctx context.Context, boot *startup.Orchestrator, deps Deps) error {
+	cfg, logger := a.cfg, a.logger
+
+	if a.store == nil {
+		logger.Println("Initializing database connection...")
+		dbStore, err := a.openStore(ctx, boot, "database", cfg.Database.DSN, cfg.Database.MinConnections, cfg.Database.MaxConnections)
+		if err != nil {
+			return fmt.Errorf("failed to initialize database store: %w", err)
+		}
+		a.store = dbStore
+	}
+
+	if a.chain == nil {
+		logger.Println("Initializing blockchain client using configuration files...")
+		err := boot.Wait(ctx, "blockchain", cfg.Startup.Blockchain, func(ctx context.Context) error {
+			var err error
+			a.chain, err = blockchain.NewBlockchainClientFromFile(cfg.BlockchainClientConfigPath, logger)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize ChainMaker client: %w", err)
+		}
+		a.closers = append(a.closers, a.chain.Close)
+	}
+
+	// Open the blockchain clients of per-org routing targets
+	for _, target := range cfg.Routing.Targets {
+		if client, ok := deps.RouteClients[target.Name]; ok {
+			a.routeClients[target.Name] = client
+			continue
+		}
+		logger.Printf("Initializing blockchain client for routing target %s...", target.Name)
+		err := boot.Wait(ctx, "blockchain target "+target.Name, cfg.Startup.Blockchain, func(ctx context.Context) error {
+			client, err := blockchain.NewBlockchainClientFromFile(target.BlockchainClientConfigPath, logger)
+			if err == nil {
+				a.routeClients[target.Name] = client
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize blockchain client for routing target %s: %w", target.Name, err)
+		}
+		a.closers = append(a.closers, a.routeClients[target.Name].Close)
+	}
+
+	// Open peer region State DBs for cross-region reconciliation
+	if cfg.Region.Reconcile {
+		for _, peer := range cfg.Region.Peers {
+			if peerStore, ok := deps.PeerStores[peer.Name]; ok {
+				a.peerStores[peer.Name] = peerStore
+				continue
+			}
+			logger.Printf("Connecting to State DB of peer region %s...", peer.Name)
+			peerStore, err := a.openStore(ctx, boot, "peer database "+peer.Name, peer.DSN, 5, 1)
+			if err != nil {
+				return fmt.Errorf("failed to connect to State DB of peer region %s: %w", peer.Name, err)
+			}
+			a.peerStores[peer.Name] = peerStore
+		}
+	}
+	return nil
+}
+
+// openStore connects to a State DB, waiting for it to become available, and closes it on Shutdown
+func (a *App) openStore(ctx context.Context, boot *startup.Orchestrator, name, dsn string, minConns, maxConns int) (*store.PostgresStore, error) {
+	var dbStore *store.PostgresStore
+	err := boot.Wait(ctx, name, a.cfg.Startup.Database, func(ctx context.Context) error {
+		var err error
+		dbStore, err = store.NewPostgresStore(ctx, dsn, minConns, maxConns, a.logger)
+		if errors.Is(err, store.ErrIncompatibleSchema) {
+			return startup.Permanent(err)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, func() error { dbStore.Close(); return nil })
+	return dbStore, nil
+}
+
+// openConsumers creates the main topic's consumers unless injected, and the
+// dedicated consumers of large submissions
+func (a *App) openConsumers(useKafka bool) error {
+	cfg, logger := a.cfg, a.logger
+	if a.consumers == nil && useKafka {
+		logger.Printf("Initializing %d Kafka message queue consumers...", cfg.KafkaConsumer.Count)
+		for i := 0; i < cfg.KafkaConsumer.Count; i++ {
+			kafkaConsumer, err := consumer.NewKafkaConsumer(cfg.KafkaConsumer, logger)
+			if err != nil {
+				return fmt.Errorf("failed to initialize Kafka consumer %d: %w", i, err)
+			}
+			a.consumers = append(a.consumers, kafkaConsumer)
+		}
+
+		// Consume from the DR cluster as well so messages produced there during a failover are processed
+		if len(cfg.KafkaConsumer.SecondaryBrokers) > 0 {
+			secondaryCfg := cfg.KafkaConsumer
+			secondaryCfg.Brokers = cfg.KafkaConsumer.SecondaryBrokers
+			secondaryCfg.TopicCheck = config.KafkaTopicConfig{} // The DR cluster may legitimately be unreachable at startup
+			logger.Printf("Initializing %d Kafka consumers for secondary cluster %v...", secondaryCfg.Count, secondaryCfg.Brokers)
+			for i := 0; i < secondaryCfg.Count; i++ {
+				kafkaConsumer, err := consumer.NewKafkaConsumer(secondaryCfg, logger)
+				if err != nil {
+					return fmt.Errorf("failed to initialize secondary Kafka consumer %d: %w", i, err)
+				}
+				a.consumers = append(a.consumers, kafkaConsumer)
+			}
+		}
+	} else if a.consumers == nil {
+		logger.Println("Initializing Mock message queue consumer...")
+		a.consumers = append(a.consumers, consumer.NewMockConsumer(logger))
+	}
+
+	// Large submissions arrive on their own topic and get a dedicated pool with smaller batches
+	if useKafka && cfg.SizeTier.Enabled {
+		largeCfg := cfg.KafkaConsumer
+		largeCfg.Topic = cfg.Region.Topic(cfg.SizeTier.Topic)
+		largeCfg.GroupID = cfg.SizeTier.GroupID
+		largeCfg.TopicCheck.Partitions = 0 // Sized independently of the main topic
+		logger.Printf("Initializing %d Kafka consumers for large submissions on %s...", cfg.SizeTier.Count, largeCfg.Topic)
+		for i := 0; i < cfg.SizeTier.Count; i++ {
+			kafkaConsumer, err := consumer.NewKafkaConsumer(largeCfg, logger)
+			if err != nil {
+				return fmt.Errorf("failed to initialize large-submission Kafka consumer %d: %w", i, err)
+			}
+			a.largeConsumers = append(a.largeConsumers, kafkaConsumer)
+		}
+	}
+	return nil
+}
+
+// openNATSConsumers creates the consumers of the NATS stream's durable consumer
+func (a *App) openNATSConsumers(ctx context.Context) error {
+	cfg, logger := a.cfg, a.logger
+	logger.Printf("Initializing %d NATS JetStream consumers...", cfg.Messaging.NATS.Count)
+	consumers := make([]consumer.Consumer, 0, cfg.Messaging.NATS.Count)
+	for i := 0; i < cfg.Messaging.NATS.Count; i++ {
+		natsConsumer, err := consumer.NewNATSConsumer(ctx, cfg.Messaging.NATS, logger)
+		if err != nil {
+			for _, c := range consumers {
+				c.Close()
+			}
+			return fmt.Errorf("NATS consumer %d: %w", i, err)
+		}
+		consumers = append(consumers, natsConsumer)
+	}
+	a.consumers = consumers
+	return nil
+}
+
+// openSinks creates the optional status event sinks (ClickHouse, Kafka), fed by the
+// event bus, the dead-letter producer and the producers republishing parked messages
+func (a *App) openSinks(ctx context.Context, useKafka bool, kafkaTLS *tls.Config) error {
+	cfg, logger := a.cfg, a.logger
+	if cfg.ClickHouse.Enabled || cfg.StatusEvents.Enabled {
+		a.eventBus = events.NewBus()
+	}
+	if cfg.ClickHouse.Enabled {
+		sink := clickhouse.New(cfg.ClickHouse, logger)
+		if cfg.ClickHouse.CreateTable {
+			if err := sink.EnsureTable(ctx); err != nil {
+				return fmt.Errorf("failed to create ClickHouse table: %w", err)
+			}
+		}
+		sub := a.eventBus.Subscribe("clickhouse", cfg.ClickHouse.BufferSize)
+		a.sinks = append(a.sinks, func(ctx context.Context) { sink.Run(ctx, sub) })
+	}
+	if cfg.StatusEvents.Enabled {
+		if !useKafka {
+			return errors.New("status_events requires Kafka brokers in kafka_consumer")
+		}
+		publisher := producer.NewStatusEventPublisher(cfg.StatusEvents, cfg.KafkaConsumer.Brokers, kafkaTLS, logger)
+		sub := a.eventBus.Subscribe("status_events", cfg.StatusEvents.BufferSize)
+		a.sinks = append(a.sinks, func(context.Context) { publisher.Run(sub) })
+	}
+	if cfg.DLQ.Enabled {
+		if !useKafka {
+			return errors.New("dlq requires Kafka brokers in kafka_consumer")
+		}
+		a.dlq = producer.NewDLQProducer(cfg.DLQ, cfg.KafkaConsumer.Brokers, kafkaTLS, logger)
+		a.closers = append(a.closers, a.dlq.Close)
+	}
+	if cfg.StuckTasks.Enabled || (cfg.Heartbeat.Enabled && cfg.Heartbeat.Reclaim) {
+		if err := a.openRepublisher(ctx, useKafka); err != nil {
+			return err
+		}
+	}
+	if cfg.ConsistencyCheck.Enabled {
+		if sch, ok := a.store.(interface{ Schema() store.SchemaInfo }); ok && !sch.Schema().Features().Has(store.FeatureLogFields) {
+			return fmt.Errorf("consistency_check needs schema version 10 (log search), the database is at version %d", sch.Schema().Version)
+		}
+	}
+	return nil
+}
+
+// openRepublisher creates the producers publishing parked messages again, one
+// per consumed topic and keyed like the gateways key them, so that sharded
+// engines get each log hash range on its own partition
+func (a *App) openRepublisher(ctx context.Context, useKafka bool) error {
+	cfg, logger := a.cfg, a.logger
+	producers := make(map[string]producer.Producer)
+	switch {
+	case cfg.Messaging.UsesNATS():
+		p, err := producer.NewNATSProducer(ctx, cfg.Messaging.NATS, cfg.Messaging.NATS.Subject, logger)
+		if err != nil {
+			return fmt.Errorf("failed to initialize NATS producer for parked messages: %w", err)
+		}
+		producers[cfg.Messaging.NATS.Subject] = p
+	case useKafka:
+		partitionKey := config.PartitionKeyRequestID
+		if cfg.Worker.Sharding == config.ShardingHashRange {
+			partitionKey = config.PartitionKeyLogHash
+		}
+		topics := []string{cfg.KafkaConsumer.Topic}
+		if cfg.SizeTier.Enabled {
+			topics = append(topics, cfg.Region.Topic(cfg.SizeTier.Topic))
+		}
+		for _, t := range topics {
+			p, err := producer.NewKafkaProducer(config.KafkaProducerConfig{
+				Brokers:      cfg.KafkaConsumer.Brokers,
+				Topic:        t,
+				RequiredAcks: "all",
+				TLS:          cfg.KafkaConsumer.TLS,
+				Encoding:     "protobuf",
+				PartitionKey: partitionKey,
+			}, logger)
+			if err != nil {
+				return fmt.Errorf("failed to initialize Kafka producer for parked messages on %s: %w", t, err)
+			}
+			producers[t] = p
+		}
+	default:
+		return nil // The mock consumer never redelivers
+	}
+	for _, p := range producers {
+		a.closers = append(a.closers, p.Close)
+	}
+	a.republisher = worker.NewRepublisher(a.store, producers, logger)
+	return nil
+}
+
+// Workers returns the workers Run started
+func (a *App) Workers() []*worker.Worker {
+	return a.workers
+}
+
+// serveConfig serves the redacted effective configuration and the checksums of
+// the configuration files on the monitoring server, for support
+func (a *App) serveConfig(cfg *config.EngineConfig) error {
+	fp, err := fingerprint.Compute(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint the configuration: %w", err)
+	}
+	files, err := fingerprint.Files(cfg.SourcePath, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to checksum the configuration files: %w", err)
+	}
+	a.monitorServer.ServeConfig(&monitor.ConfigReport{
+		Version:      buildinfo.Version(),
+		ConfigSHA256: fp.SHA256,
+		Config:       fp.Canonical,
+		ConfigFiles:  files,
+	}, a.verifier)
+	return nil
+}
+
+// Run starts the status event sinks, the worker pipelines and the monitoring
+// and admin servers, and consumes until ctx is done, which returns nil, or
+// until a server fails to start. Shutdown must be called afterwards either way.
+func (a *App) Run(ctx context.Context) error {
+	cfg, logger := a.cfg, a.logger
+
+	// The workers stop consuming once Run returns, after the in-flight counters are taken
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	for _, run := range a.sinks {
+		a.sinksWg.Add(1)
+		go func() {
+			defer a.sinksWg.Done()
+			run(a.sinksCtx)
+		}()
+	}
+
+	// With worker.pipelines the consumers feed one work queue shared by that
+	// many worker pipelines; otherwise each consumer has a pipeline of its own
+	mainSources := a.consumers
+	if pipelines := cfg.Worker.Pipelines; pipelines > 0 {
+		retryDelay, err := time.ParseDuration(cfg.Worker.ConsumerRetryDelay)
+		if err != nil {
+			retryDelay = 5 * time.Second
+		}
+		mainSources = make([]consumer.Consumer, pipelines)
+		if cfg.Worker.Sharding == config.ShardingHashRange {
+			// Each pipeline reads one log hash range, so no log hash is in two concurrent batches
+			logger.Printf("Splitting the messages of %d consumers into %d log hash ranges of %d queued messages, one per worker pipeline", len(a.consumers), pipelines, cfg.Worker.QueueSize)
+			pool := consumer.NewShardedPool(runCtx, a.consumers, pipelines, cfg.Worker.QueueSize, retryDelay)
+			a.consumers = []consumer.Consumer{pool} // The pool closes the consumers
+			for i := range mainSources {
+				mainSources[i] = pool.Shard(i)
+			}
+		} else {
+			logger.Printf("Sharing a work queue of %d messages from %d consumers among %d worker pipelines", cfg.Worker.QueueSize, len(a.consumers), pipelines)
+			pool := consumer.NewPool(runCtx, a.consumers, cfg.Worker.QueueSize, retryDelay)
+			a.consumers = []consumer.Consumer{pool} // The pool closes the consumers
+			for i := range mainSources {
+				mainSources[i] = pool
+			}
+		}
+	}
+
+	largeWorkerCfg := cfg.Worker
+	largeWorkerCfg.Concurrency = cfg.SizeTier.Concurrency
+	largeWorkerCfg.BatchSize = cfg.SizeTier.BatchSize
+	mainTopic, largeTopic := cfg.KafkaConsumer.Topic, cfg.Region.Topic(cfg.SizeTier.Topic)
+	if cfg.Messaging.UsesNATS() {
+		mainTopic = cfg.Messaging.NATS.Subject
+	}
+	for i, consumer := range append(mainSources, a.largeConsumers...) {
+		workerCfg, consumedTopic := cfg.Worker, mainTopic
+		if i >= len(mainSources) {
+			workerCfg, consumedTopic = largeWorkerCfg, largeTopic
+		}
+		workerInstance := worker.New(workerCfg, cfg.MaxTaskRetries, logger, a.store, consumer, a.chain)
+		if cfg.BatchTuning.Enabled && i < len(mainSources) {
+			workerInstance.SetBatchTuning(cfg.BatchTuning) // Large submissions keep the size tier's fixed batches
+		}
+		if cfg.PriorityLanes.Enabled {
+			workerInstance.SetPriorityLanes(cfg.PriorityLanes)
+		}
+		if cfg.ReplayGuard.Enabled {
+			workerInstance.SetReplayGuard(cfg.ReplayGuard)
+		}
+		if len(a.peerStores) > 0 {
+			workerInstance.EnableReconciliation(cfg.Region.Name, a.peerStores)
+		}
+		if a.eventBus != nil {
+			workerInstance.SetEventBus(a.eventBus)
+		}
+		if a.dlq != nil {
+			workerInstance.SetDeadLetterQueue(a.dlq)
+		}
+		if a.republisher != nil {
+			workerInstance.SetParking(consumedTopic)
+		}
+		workerInstance.SetProofCache(cfg.ProofCache.Enabled)
+		workerInstance.SetTrack(cfg.Track)
+		if cfg.MerkleAnchoring.Enabled {
+			workerInstance.SetMerkleAnchoring(cfg.MerkleAnchoring.OrgID)
+		}
+		workerInstance.SetErrorHandling(cfg.ErrorHandling)
+		if len(a.routeClients) > 0 {
+			workerInstance.SetRoutes(a.routeClients, cfg.Routing.OrgTargets())
+		}
+		if a.fleet != nil {
+			workerInstance.SetInstanceID(a.fleet.InstanceID())
+		}
+		if a.readOnly != nil {
+			workerInstance.SetReadOnlyMode(a.readOnly)
+		}
+		if a.clock != nil {
+			workerInstance.SetClock(a.clock)
+		}
+		if a.batchIDs != nil {
+			workerInstance.SetBatchIDGenerator(a.batchIDs)
+		}
+		a.workers = append(a.workers, workerInstance)
+
+		a.workersWg.Add(1)
+		go func(workerID int, w *worker.Worker) {
+			defer a.workersWg.Done()
+			logger.Printf("Starting worker %d...", workerID)
+			w.Run(runCtx, a.drainCtx)
+			logger.Printf("Worker %d stopped.", workerID)
+		}(i+1, workerInstance)
+	}
+
+	// Deferred batches are anchored through the first worker once the State DB accepts writes
+	if a.readOnly != nil {
+		a.workersWg.Add(1)
+		go func() {
+			defer a.workersWg.Done()
+			a.readOnly.Run(runCtx, a.workers[0])
+		}()
+	}
+
+	// Recover the tasks crashed or hung workers left PROCESSING
+	if cfg.StuckTasks.Enabled {
+		scanner := worker.NewStuckTaskScanner(cfg.StuckTasks, a.store, a.chain, logger)
+		if len(a.routeClients) > 0 {
+			scanner.SetRoutes(a.routeClients, cfg.Routing.OrgTargets())
+		}
+		if a.republisher != nil {
+			scanner.SetRepublisher(a.republisher)
+		}
+		logger.Printf("Stuck task scan enabled: tasks PROCESSING for over %v are resolved every %v", cfg.StuckTasks.Threshold, cfg.StuckTasks.Interval)
+		a.workersWg.Add(1)
+		go func() {
+			defer a.workersWg.Done()
+			scanner.Run(runCtx)
+		}()
+	}
+
+	// Check that Kafka, the State DB and the chain agree on sampled submissions
+	if cc := cfg.ConsistencyCheck; cc.Enabled {
+		checker := worker.NewConsistencyChecker(cc, a.store, a.chain, logger)
+		if len(a.routeClients) > 0 {
+			checker.SetRoutes(a.routeClients, cfg.Routing.OrgTargets())
+		}
+		logger.Printf("Consistency check enabled: %d submissions received %v to %v ago sampled every %v (repair: %t)", cc.SampleSize, cc.Window, cc.MinAge, cc.Interval, cc.Repair)
+		a.workersWg.Add(1)
+		go func() {
+			defer a.workersWg.Done()
+			checker.Run(runCtx)
+		}()
+	}
+
+	// Heartbeat until shutdown; the fleet outlives ctx so that it can deregister
+	if a.fleet != nil {
+		var fleetCtx context.Context
+		fleetCtx, a.fleetCancel = context.WithCancel(context.Background())
+		a.fleetDone = make(chan struct{})
+		go func() {
+			defer close(a.fleetDone)
+			a.fleet.Run(fleetCtx, a.workers)
+		}()
+	}
+
+	// Start Monitoring and Admin Servers
+	a.monitorServer = monitor.NewServer(cfg.Monitoring, a.workers, a.store, logger)
+	if pinger, ok := a.store.(interface{ Ping(context.Context) error }); ok {
+		a.monitorServer.AddCheck("database", pinger.Ping)
+	}
+	if a.fleet != nil {
+		a.monitorServer.SetFleet(a.fleet)
+	}
+	if err := a.serveConfig(cfg); err != nil {
+		return err
+	}
+	if cfg.Monitoring.ListenAddr != "" {
+		a.monitorServer.Start()
+	}
+	if cfg.Monitoring.AdminGRPCAddr != "" {
+		a.adminServer = monitor.NewAdminServer(cfg.Monitoring.AdminGRPCAddr, a.monitorServer, a.verifier, logger)
+		if err := a.adminServer.Start(); err != nil {
+			a.adminServer = nil
+			return fmt.Errorf("failed to start admin gRPC server: %w", err)
+		}
+	}
+
+	logger.Printf("Attestation Engine started with %d workers.", len(a.workers))
+	<-ctx.Done()
+	for _, w := range a.workers {
+		st := w.Status()
+		a.inFlight += st.PendingMessages + st.InFlightBatchSize
+		a.before = addStats(a.before, st.Stats)
+	}
+	return nil
+}
+
+// Shutdown waits up to the configured shutdown timeout for the workers to
+// finish their in-flight batches, cancelling what is left, delivers the status
+// events published meanwhile within the drain timeout and closes the consumers
+// and the dependencies New opened. It returns an error if batches were cancelled.
+func (a *App) Shutdown(ctx context.Context) error {
+	cfg, logger := a.cfg, a.logger
+	shutdownStart := time.Now()
+
+	// Wait for all workers to finish their in-flight batches, then cancel what is left
+	var err error
+	logger.Printf("Waiting up to %v for all workers to finish %d in-flight messages...", cfg.Shutdown.Timeout, a.inFlight)
+	workersDone := make(chan struct{})
+	go func() {
+		a.workersWg.Wait()
+		close(workersDone)
+	}()
+	waitCtx, waitCancel := context.WithTimeout(ctx, cfg.Shutdown.Timeout)
+	defer waitCancel()
+	select {
+	case <-workersDone:
+	case <-waitCtx.Done():
+		logger.Printf("Workers did not finish within %v, cancelling in-flight batches...", cfg.Shutdown.Timeout)
+		a.drainCancel()
+		<-workersDone
+		err = fmt.Errorf("workers did not finish within %v", cfg.Shutdown.Timeout)
+	}
+
+	// Deliver the events published while draining, then stop the sinks
+	drainTimeout, drainTimeoutCancel := context.WithTimeout(ctx, cfg.Shutdown.DrainTimeout)
+	defer drainTimeoutCancel()
+
+	// Keep heartbeating while the workers finish, then release what they still hold
+	a.fleetCancel()
+	<-a.fleetDone
+	if a.fleet != nil {
+		a.fleet.Stop(drainTimeout)
+	}
+	if a.eventBus != nil {
+		a.eventBus.Close()
+		sinksDone := make(chan struct{})
+		go func() {
+			a.sinksWg.Wait()
+			close(sinksDone)
+		}()
+		select {
+		case <-sinksDone:
+		case <-drainTimeout.Done():
+			logger.Printf("Status event sinks did not drain within %v, remaining events dropped", cfg.Shutdown.DrainTimeout)
+		}
+		a.sinksCancel()
+	}
+
+	if a.adminServer != nil {
+		a.adminServer.Stop()
+	}
+	if a.monitorServer != nil && cfg.Monitoring.ListenAddr != "" {
+		if err := a.monitorServer.Shutdown(drainTimeout); err != nil {
+			logger.Printf("Monitoring server shutdown error: %v", err)
+		}
+	}
+
+	var after worker.Stats
+	for _, w := range a.workers {
+		after = addStats(after, w.Stats())
+	}
+	logger.Printf("Shutdown summary: in_flight=%d flushed_batches=%d failed_batches=%d abandoned=%d (redelivered after restart), took %v",
+		a.inFlight,
+		after.BatchesProcessed-a.before.BatchesProcessed,
+		after.BatchesFailed-a.before.BatchesFailed,
+		after.MessagesAbandoned-a.before.MessagesAbandoned,
+		time.Since(shutdownStart).Round(time.Millisecond))
+	a.close()
+	return err
+}
+
+// close releases the consumers, the intent spool and the dependencies New opened
+func (a *App) close() {
+	a.drainCancel()
+	for _, c := range append(a.consumers, a.largeConsumers...) {
+		c.Close()
+	}
+	a.consumers, a.largeConsumers = nil, nil
+	if a.spool != nil {
+		a.spool.Close()
+	}
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i](); err != nil {
+			a.logger.Printf("Failed to close dependency: %v", err)
+		}
+	}
+	a.closers = nil
+}
+
+// addStats sums the shutdown-relevant worker counters
+func addStats(a, b worker.Stats) worker.Stats {
+	a.BatchesProcessed += b.BatchesProcessed
+	a.BatchesFailed += b.BatchesFailed
+	a.MessagesAbandoned += b.MessagesAbandoned
+	return a
+}
+
+// checkBlockchain runs a read-only contract query and, if the client supports
+// it, checks that the deployed contract is the version it decodes events for
+func checkBlockchain(ctx context.Context, client blockchain.BlockchainClient) error {
+	if _, err := client.FindLogByHash(ctx, "startup-self-check"); err != nil {
+		return err
+	}
+	if mgr, ok := client.(blockchain.ContractManager); ok {
+		info, err := mgr.ContractInfo(ctx)
+		if err != nil {
+			return err
+		}
+		if info.Version != mgr.ExpectedContractVersion() {
+			return fmt.Errorf("contract %s is at version %s, expected %s", info.Name, info.Version, mgr.ExpectedContractVersion())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/blockchain/types"
+	"tlng/config"
+	"tlng/internal/metrics"
+	"tlng/storage/store"
+)
+
+// Divergence patterns found by the consistency checker
+const (
+	DivergenceNotConsumed         = "not_consumed"           // RECEIVED past min_age and never picked up from Kafka
+	DivergenceNotTerminal         = "not_terminal"           // Picked up but neither COMPLETED nor FAILED past min_age, and not on chain
+	DivergenceOnChainNotCompleted = "on_chain_not_completed" // On chain but RECEIVED, PROCESSING or FAILED in the DB
+	DivergenceCompletedNotOnChain = "completed_not_on_chain" // COMPLETED in the DB but not found on chain
+)
+
+// consistencySliceSize is the number of consecutive submissions read from each
+// random point of the window; a sample is made of several slices
+const consistencySliceSize = 10
+
+var (
+	consistencyScore = metrics.NewGauge("engine_consistency_score",
+		"Share of the last consistency sample on which Kafka, the State DB and the chain agree (0-1).")
+	consistencyDivergences = metrics.NewCounter("engine_consistency_divergences_total",
+		"Sampled submissions on which Kafka, the State DB and the chain disagree, by pattern.", "pattern")
+	consistencyRepairs = metrics.NewCounter("engine_consistency_repairs_total",
+		"Divergences repaired by the consistency checker, by pattern.", "pattern")
+)
+
+// ConsistencyChecker continuously samples recent submissions and checks that
+// Kafka, the State DB and the chain agree on them: the engine consumed the
+// message, the task reached COMPLETED or FAILED, and the log is on chain if
+// and only if it is COMPLETED. Whether a message was consumed is read from the
+// task, which the engine stamps when it picks the message up.
+type ConsistencyChecker struct {
+	chainLookup
+	cfg    config.ConsistencyCheckConfig
+	logger *log.Logger
+}
+
+// ConsistencyReport is the outcome of one consistency check
+type ConsistencyReport struct {
+	Sampled     int            // Submissions checked
+	Consistent  int            // Submissions on which all three systems agree
+	Divergences map[string]int // Pattern -> submissions
+	Repaired    int            // Divergent submissions repaired
+}
+
+// Score is the share of the sample on which all three systems agree, 1 for an empty sample
+func (r ConsistencyReport) Score() float64 {
+	if r.Sampled == 0 {
+		return 1
+	}
+	return float64(r.Consistent) / float64(r.Sampled)
+}
+
+// NewConsistencyChecker creates a checker looking submissions up through client
+func NewConsistencyChecker(cfg config.ConsistencyCheckConfig, s store.Store, client blockchain.BlockchainClient, logger *log.Logger) *ConsistencyChecker {
+	return &ConsistencyChecker{chainLookup: chainLookup{store: s, client: client}, cfg: cfg, logger: logger}
+}
+
+// Run checks a sample every interval until ctx is done
+func (c *ConsistencyChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		report, err := c.Check(ctx)
+		if err != nil {
+			c.logger.Printf("Warning: consistency check failed: %v", err)
+		}
+		if report.Consistent < report.Sampled {
+			c.logger.Printf("Consistency check: %d of %d sampled submissions diverge %v, %d repaired",
+				report.Sampled-report.Consistent, report.Sampled, report.Divergences, report.Repaired)
+		}
+	}
+}
+
+// Check samples up to sample_size submissions and classifies each divergence.
+// With repair, submissions found on chain but not COMPLETED are completed.
+// Submissions the chain cannot be asked about are left out of the sample.
+func (c *ConsistencyChecker) Check(ctx context.Context) (ConsistencyReport, error) {
+	report := ConsistencyReport{Divergences: make(map[string]int)}
+	tasks, err := c.sample(ctx)
+	if err != nil {
+		return report, fmt.Errorf("failed to sample submissions: %w", err)
+	}
+
+	var repairs []store.LogOutcome
+	var lookupErrs []error
+	for _, task := range tasks {
+		anchored, completion, err := c.lookup(ctx, task)
+		if err != nil {
+			lookupErrs = append(lookupErrs, fmt.Errorf("submission %s: %w", task.RequestID, err))
+			continue
+		}
+		report.Sampled++
+		pattern := divergence(task, anchored)
+		if pattern == "" {
+			report.Consistent++
+			continue
+		}
+		report.Divergences[pattern]++
+		consistencyDivergences.Inc(pattern)
+		switch pattern {
+		case DivergenceCompletedNotOnChain:
+			c.logger.Printf("CRITICAL: submission %s (log hash %s) is COMPLETED in tx %s but not found on chain",
+				task.RequestID, task.LogHash, stringOrEmpty(task.TxHash))
+		case DivergenceOnChainNotCompleted:
+			if c.cfg.Repair {
+				repairs = append(repairs, store.LogOutcome{
+					RequestID:         task.RequestID,
+					LogHash:           task.LogHash,
+					SourceOrgID:       task.SourceOrgID,
+					Region:            task.Region,
+					ReceivedTimestamp: task.ReceivedTimestamp,
+					Status:            store.StatusCompleted,
+					RetryCount:        task.RetryCount,
+					TxHash:            completion.TxHash,
+					BlockHeight:       completion.BlockHeight,
+					FinishedAt:        time.Now(),
+				})
+			}
+		}
+	}
+
+	if len(repairs) > 0 {
+		// Outcomes never downgrade a COMPLETED task, so a worker completing one meanwhile is kept
+		if _, err := c.store.RestoreLogOutcomes(ctx, repairs); err != nil {
+			lookupErrs = append(lookupErrs, fmt.Errorf("failed to complete submissions found on chain: %w", err))
+		} else {
+			report.Repaired = len(repairs)
+			consistencyRepairs.Add(float64(len(repairs)), DivergenceOnChainNotCompleted)
+		}
+	}
+	consistencyScore.Set(report.Score())
+	return report, errors.Join(lookupErrs...)
+}
+
+// divergence classifies a sampled task given whether its log is on chain, "" if consistent
+func divergence(task *store.LogStatus, anchored bool) string {
+	switch {
+	case task.Status == store.StatusCompleted && !anchored:
+		return DivergenceCompletedNotOnChain
+	case task.Status == store.StatusCompleted:
+		return ""
+	case anchored && task.Status == store.StatusFailed && isSkippedDuplicate(task):
+		return "" // Anchored by an earlier submission of the same log
+	case anchored:
+		return DivergenceOnChainNotCompleted
+	case task.Status == store.StatusFailed:
+		return ""
+	case task.Status == store.StatusReceived && task.ProcessingStartedAt == nil && task.RetryCount == 0:
+		return DivergenceNotConsumed
+	default:
+		return DivergenceNotTerminal
+	}
+}
+
+// isSkippedDuplicate reports whether a task failed because the contract already held its log
+func isSkippedDuplicate(task *store.LogStatus) bool {
+	return task.ErrorMessage != nil && strings.Contains(*task.ErrorMessage, string(types.StatusSkippedDuplicate))
+}
+
+// sample reads slices of consecutive submissions from random points between
+// window and min_age ago, until sample_size submissions or the attempts run out
+func (c *ConsistencyChecker) sample(ctx context.Context) ([]*store.LogStatus, error) {
+	now := time.Now()
+	since, until := now.Add(-c.cfg.Window), now.Add(-c.cfg.MinAge)
+	span := int64(until.Sub(since))
+
+	seen := make(map[string]bool, c.cfg.SampleSize)
+	var tasks []*store.LogStatus
+	attempts := 2 * (c.cfg.SampleSize/consistencySliceSize + 1)
+	for i := 0; i < attempts && len(tasks) < c.cfg.SampleSize; i++ {
+		start := since.Add(time.Duration(rand.Int64N(span)))
+		limit := min(consistencySliceSize, c.cfg.SampleSize-len(tasks))
+		slice, err := c.store.SearchLogStatus(ctx, store.LogFilter{Since: start, Until: until}, store.LogCursor{}, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range slice {
+			if !seen[task.RequestID] {
+				seen[task.RequestID] = true
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// stringOrEmpty dereferences an optional string
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+
+	"tlng/internal/messaging/producer"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// SetDeadLetterQueue makes the worker publish the log messages of tasks it
+// marks FAILED, after the retry limit or a permanent error, to the dead-letter topic
+func (w *Worker) SetDeadLetterQueue(dlq *producer.DLQProducer) {
+	w.dlq = dlq
+}
+
+// deadLetter publishes failed tasks with their failure reasons. The tasks are
+// already FAILED in the State DB, so a failed publish is only logged.
+func (w *Worker) deadLetter(ctx context.Context, tasks map[string]*store.LogStatus, msgs map[string]*models.LogMessage, failures []store.FailureRecord) {
+	if w.dlq == nil || len(failures) == 0 {
+		return
+	}
+	letters := make([]producer.DeadLetter, 0, len(failures))
+	for _, f := range failures {
+		task, msg := tasks[f.RequestID], msgs[f.RequestID]
+		if task == nil || msg == nil {
+			continue
+		}
+		letters = append(letters, producer.DeadLetter{
+			Message:    msg,
+			Reason:     f.ErrorMessage,
+			RetryCount: task.RetryCount,
+			FailedAt:   w.clock.Now(),
+		})
+	}
+	if err := w.dlq.Publish(ctx, letters); err != nil {
+		w.logger.Printf("Warning: failed to dead-letter %d failed tasks: %v", len(letters), err)
+	}
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"tlng/blockchain/types"
+	"tlng/config"
+	"tlng/storage/store"
+)
+
+// errorPolicy decides, by the kind of a failed submission's error, whether its
+// tasks fail at once or are retried, and how long a worker backs off before
+// consuming again after a transient failure
+type errorPolicy struct {
+	cfg   config.ErrorHandlingConfig
+	rules []config.ErrorRuleConfig // Contains lowercased
+}
+
+// SetErrorHandling sets the worker's error handling; without it the kinds
+// types.IsPermanent reports fail at once and there is no backoff
+func (w *Worker) SetErrorHandling(cfg config.ErrorHandlingConfig) {
+	rules := make([]config.ErrorRuleConfig, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rules[i] = config.ErrorRuleConfig{Contains: strings.ToLower(rule.Contains), Kind: rule.Kind}
+	}
+	w.errorPolicy = &errorPolicy{cfg: cfg, rules: rules}
+}
+
+// kind returns the name of err's kind, after the configured rules
+func (p *errorPolicy) kind(err error) string {
+	if p != nil && len(p.rules) > 0 {
+		msg := strings.ToLower(err.Error())
+		for _, rule := range p.rules {
+			if strings.Contains(msg, rule.Contains) {
+				return rule.Kind
+			}
+		}
+	}
+	return types.ErrorKindName(err)
+}
+
+// permanent reports whether the tasks of a submission failing with err fail at once
+func (p *errorPolicy) permanent(err error) bool {
+	if p == nil {
+		return types.IsPermanent(err)
+	}
+	return slices.Contains(p.cfg.PermanentKinds, p.kind(err))
+}
+
+// backoff pauses after the streak-th consecutive transient failure, doubling
+// the pause each time up to the maximum. It returns early if ctx is done.
+func (p *errorPolicy) backoff(ctx context.Context, streak int64) {
+	if p == nil || streak <= 0 {
+		return
+	}
+	delay := p.cfg.RetryBackoff
+	for i := int64(1); i < streak && delay < p.cfg.MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.cfg.MaxRetryBackoff)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// retryOnConflict runs a State DB write, and runs it once more if it lost
+// against a concurrent write (store.ErrConflict), e.g. a deadlock between
+// engine instances, rather than failing the whole batch
+func retryOnConflict(write func() error) error {
+	err := write()
+	if errors.Is(err, store.ErrConflict) {
+		err = write()
+	}
+	return err
+}
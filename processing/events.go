@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"tlng/internal/events"
+	"tlng/storage/store"
+)
+
+// SetEventBus makes the worker publish status transition events to bus
+func (w *Worker) SetEventBus(bus *events.Bus) {
+	w.eventBus = bus
+}
+
+// transition builds a status transition event for a task
+func (w *Worker) transition(task *store.LogStatus, from, to store.Status) events.StatusEvent {
+	return events.StatusEvent{
+		RequestID:   task.RequestID,
+		LogHash:     task.LogHash,
+		SourceOrgID: task.SourceOrgID,
+		Region:      w.region,
+		From:        from,
+		To:          to,
+		ReceivedAt:  task.ReceivedTimestamp,
+		At:          w.clock.Now(),
+		RetryCount:  task.RetryCount,
+	}
+}
+
+// publishTransitions publishes a PROCESSING -> to transition for every task
+func (w *Worker) publishTransitions(tasks map[string]*store.LogStatus, to store.Status, annotate func(e *events.StatusEvent)) {
+	if w.eventBus == nil || len(tasks) == 0 {
+		return
+	}
+	batch := make([]events.StatusEvent, 0, len(tasks))
+	for _, task := range tasks {
+		e := w.transition(task, store.StatusProcessing, to)
+		if annotate != nil {
+			annotate(&e)
+		}
+		batch = append(batch, e)
+	}
+	w.eventBus.Publish(batch)
+}
+
+// publishCompletions publishes PROCESSING -> COMPLETED transitions with their proofs
+func (w *Worker) publishCompletions(tasks map[string]*store.LogStatus, completions []store.CompletionRecord) {
+	if w.eventBus == nil || len(completions) == 0 {
+		return
+	}
+	batch := make([]events.StatusEvent, 0, len(completions))
+	for _, c := range completions {
+		task, ok := tasks[c.RequestID]
+		if !ok {
+			continue
+		}
+		e := w.transition(task, store.StatusProcessing, store.StatusCompleted)
+		e.TxHash = c.TxHash
+		e.BlockHeight = c.BlockHeight
+		batch = append(batch, e)
+	}
+	w.eventBus.Publish(batch)
+}
+
+// publishFailures publishes PROCESSING -> FAILED transitions with their errors
+func (w *Worker) publishFailures(tasks map[string]*store.LogStatus, failures []store.FailureRecord) {
+	if w.eventBus == nil || len(failures) == 0 {
+		return
+	}
+	batch := make([]events.StatusEvent, 0, len(failures))
+	for _, f := range failures {
+		task, ok := tasks[f.RequestID]
+		if !ok {
+			continue
+		}
+		e := w.transition(task, store.StatusProcessing, store.StatusFailed)
+		e.Error = f.ErrorMessage
+		batch = append(batch, e)
+	}
+	w.eventBus.Publish(batch)
+}
@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/buildinfo"
+	"tlng/internal/messaging/consumer"
+	"tlng/storage/store"
+)
+
+// Fleet registers the engine instance in the State DB, keeps its heartbeat
+// fresh, and returns the PROCESSING tasks of instances that stopped
+// heartbeating to RECEIVED. Their messages, parked when Kafka redelivered them
+// while the tasks were still PROCESSING, are then published again.
+type Fleet struct {
+	cfg         config.HeartbeatConfig
+	instance    store.WorkerInstance
+	store       store.Store
+	republisher *Republisher // Optional; publishes the parked messages of reclaimed tasks (see SetRepublisher)
+	logger      *log.Logger
+}
+
+// Instance states reported by the fleet view
+const (
+	InstanceAlive     = "alive"
+	InstanceDead      = "dead"      // No heartbeat within dead_after, tasks not reclaimed yet
+	InstanceReclaimed = "reclaimed" // Dead, and its tasks were returned to RECEIVED
+	InstanceStopped   = "stopped"   // Shut down cleanly
+)
+
+// NewFleet creates the fleet membership of this engine instance. The instance
+// ID defaults to the host name followed by a random suffix.
+func NewFleet(cfg config.HeartbeatConfig, region string, s store.Store, logger *log.Logger) *Fleet {
+	hostname, _ := os.Hostname()
+	id := cfg.InstanceID
+	if id == "" {
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		id = fmt.Sprintf("%s-%s", hostname, hex.EncodeToString(suffix))
+	}
+	return &Fleet{
+		cfg: cfg,
+		instance: store.WorkerInstance{
+			InstanceID: id,
+			Version:    buildinfo.Version(),
+			Hostname:   hostname,
+			Region:     region,
+			StartedAt:  time.Now(),
+		},
+		store:  s,
+		logger: logger,
+	}
+}
+
+// SetRepublisher publishes the parked messages of the tasks the fleet reclaims
+func (f *Fleet) SetRepublisher(r *Republisher) {
+	f.republisher = r
+}
+
+// InstanceID returns the ID this instance is registered under
+func (f *Fleet) InstanceID() string {
+	return f.instance.InstanceID
+}
+
+// Register records the instance in the fleet table. Call it before the workers
+// start locking tasks under the instance ID.
+func (f *Fleet) Register(ctx context.Context) error {
+	if err := f.store.HeartbeatInstance(ctx, f.instance); err != nil {
+		return err
+	}
+	f.logger.Printf("Registered engine instance %s (version %s), heartbeat every %v, dead after %v, reclaim %t",
+		f.instance.InstanceID, f.instance.Version, f.cfg.Interval, f.cfg.DeadAfter, f.cfg.Reclaim)
+	return nil
+}
+
+// Run refreshes the heartbeat with the partitions the workers consume and,
+// if reclaiming is enabled, reclaims the tasks of dead instances, until ctx is done
+func (f *Fleet) Run(ctx context.Context, workers []*Worker) {
+	ticker := time.NewTicker(f.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		f.instance.Partitions = partitionsOf(workers)
+		if err := f.store.HeartbeatInstance(ctx, f.instance); err != nil {
+			// Skip reclaiming too: the database is likely unreachable, and this
+			// instance may itself look dead to the others by now
+			f.logger.Printf("Warning: heartbeat of engine instance %s failed: %v", f.instance.InstanceID, err)
+			continue
+		}
+		if f.cfg.Reclaim {
+			f.reclaim(ctx)
+		}
+	}
+}
+
+// reclaim returns the PROCESSING tasks of dead instances to RECEIVED and
+// publishes the parked messages of reclaimed tasks again. Messages parked
+// after an earlier reclaim are published on the next one.
+func (f *Fleet) reclaim(ctx context.Context) {
+	reclaimed, err := f.store.ReclaimDeadInstances(ctx, f.cfg.DeadAfter)
+	if err != nil {
+		f.logger.Printf("Warning: reclaiming tasks of dead engine instances failed: %v", err)
+		return
+	}
+	for _, r := range reclaimed {
+		f.logger.Printf("Engine instance %s has not heartbeated since %s: returned %d PROCESSING tasks to RECEIVED",
+			r.InstanceID, r.LastHeartbeat.Format(time.RFC3339), r.Tasks)
+	}
+	if f.republisher == nil {
+		return
+	}
+	republished, err := f.republisher.Republish(ctx)
+	if err != nil {
+		f.logger.Printf("Warning: publishing parked messages of reclaimed tasks failed: %v", err)
+	}
+	if republished > 0 {
+		f.logger.Printf("Published the parked messages of %d reclaimed tasks again", republished)
+	}
+}
+
+// Stop marks the instance as cleanly stopped and releases the tasks it still
+// holds. Call it after the workers have stopped.
+func (f *Fleet) Stop(ctx context.Context) {
+	released, err := f.store.StopInstance(ctx, f.instance.InstanceID)
+	if err != nil {
+		f.logger.Printf("Warning: deregistering engine instance %s failed: %v", f.instance.InstanceID, err)
+		return
+	}
+	f.logger.Printf("Deregistered engine instance %s, released %d PROCESSING tasks", f.instance.InstanceID, released)
+}
+
+// FleetInstance is one engine instance of the fleet view
+type FleetInstance struct {
+	InstanceID          string  `json:"instance_id"`
+	Version             string  `json:"version"`
+	Hostname            string  `json:"hostname"`
+	Region              string  `json:"region,omitempty"`
+	Partitions          []int   `json:"partitions"`
+	State               string  `json:"state"` // alive, dead, reclaimed or stopped
+	StartedAt           string  `json:"started_at"`
+	LastHeartbeat       string  `json:"last_heartbeat"`
+	HeartbeatAgeSeconds float64 `json:"heartbeat_age_seconds"`
+	Self                bool    `json:"self"` // The instance serving the view
+}
+
+// FleetView lists the engine instances registered in the State DB
+type FleetView struct {
+	InstanceID       string          `json:"instance_id"`
+	DeadAfterSeconds float64         `json:"dead_after_seconds"`
+	Instances        []FleetInstance `json:"instances"`
+}
+
+// View returns the registered instances and their state as seen from this instance
+func (f *Fleet) View(ctx context.Context) (*FleetView, error) {
+	instances, err := f.store.ListWorkerInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	view := &FleetView{
+		InstanceID:       f.instance.InstanceID,
+		DeadAfterSeconds: f.cfg.DeadAfter.Seconds(),
+		Instances:        make([]FleetInstance, 0, len(instances)),
+	}
+	for _, inst := range instances {
+		age := now.Sub(inst.LastHeartbeat)
+		state := InstanceAlive
+		switch {
+		case inst.StoppedAt != nil:
+			state = InstanceStopped
+		case inst.ReclaimedAt != nil:
+			state = InstanceReclaimed
+		case age > f.cfg.DeadAfter:
+			state = InstanceDead
+		}
+		view.Instances = append(view.Instances, FleetInstance{
+			InstanceID:          inst.InstanceID,
+			Version:             inst.Version,
+			Hostname:            inst.Hostname,
+			Region:              inst.Region,
+			Partitions:          inst.Partitions,
+			State:               state,
+			StartedAt:           inst.StartedAt.UTC().Format(time.RFC3339),
+			LastHeartbeat:       inst.LastHeartbeat.UTC().Format(time.RFC3339),
+			HeartbeatAgeSeconds: max(age.Seconds(), 0),
+			Self:                inst.InstanceID == f.instance.InstanceID,
+		})
+	}
+	return view, nil
+}
+
+// partitionsOf returns the partitions the workers' consumers receive messages from
+func partitionsOf(workers []*Worker) []int {
+	var partitions []int
+	for _, w := range workers {
+		if pr, ok := w.consumer.(consumer.PartitionReporter); ok {
+			partitions = append(partitions, pr.Partitions()...)
+		}
+	}
+	slices.Sort(partitions)
+	return slices.Compact(partitions)
+}
+
+// SetInstanceID makes the worker record instanceID on the tasks it locks, so
+// the fleet can reclaim them if the instance dies
+func (w *Worker) SetInstanceID(instanceID string) {
+	w.instanceID = instanceID
+}
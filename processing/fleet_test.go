@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/messaging/producer"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+func TestReclaimedTaskIsProcessedAgain(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New(io.Discard, "", 0)
+	msg := &models.LogMessage{RequestID: "req-1", LogContent: "user login", LogHash: "hash-1", SourceOrgID: "org-a", ReceivedTimestamp: models.NewTimestamp(time.Now())}
+	s := newMemStore(&store.LogStatus{RequestID: msg.RequestID, LogHash: msg.LogHash, SourceOrgID: msg.SourceOrgID, Status: store.StatusReceived})
+
+	// Instance a locks the task and dies
+	if _, err := s.GetAndMarkBatchAsProcessing(ctx, []string{msg.RequestID}, 3, "batch-a", "engine-a"); err != nil {
+		t.Fatal(err)
+	}
+	s.kill("engine-a")
+
+	// Kafka hands the partition to instance b, which parks the redelivered message
+	chain := newFakeChain()
+	w := New(testWorkerConfig, 3, logger, s, nil, chain)
+	w.SetInstanceID("engine-b")
+	w.SetParking("logs")
+	if err := w.anchorBatch(ctx, "batch-b1", []*models.LogMessage{msg}); err != nil {
+		t.Fatalf("anchorBatch failed: %v", err)
+	}
+
+	p := &fakeProducer{}
+	f := NewFleet(config.HeartbeatConfig{InstanceID: "engine-b", DeadAfter: 15 * time.Second, Reclaim: true}, "", s, logger)
+	f.SetRepublisher(NewRepublisher(s, map[string]producer.Producer{"logs": p}, logger))
+	f.reclaim(ctx)
+	if task := s.task(msg.RequestID); task.Status != store.StatusReceived {
+		t.Fatalf("reclaimed task is %s, want RECEIVED", task.Status)
+	}
+	republished := p.take()
+	if len(republished) != 1 || republished[0].RequestID != msg.RequestID || republished[0].LogContent != msg.LogContent {
+		t.Fatalf("republished %d messages, want the parked message of %s", len(republished), msg.RequestID)
+	}
+
+	if err := w.anchorBatch(ctx, "batch-b2", republished); err != nil {
+		t.Fatalf("anchorBatch failed: %v", err)
+	}
+	if task := s.task(msg.RequestID); task.Status != store.StatusCompleted {
+		t.Errorf("task is %s after the republished message was consumed, want COMPLETED", task.Status)
+	}
+}
@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/blockchain/types"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// memStore keeps tasks and parked messages in memory. The store.Store methods
+// the tests do not use are left unimplemented and panic.
+type memStore struct {
+	store.Store
+
+	mu        sync.Mutex
+	tasks     map[string]*store.LogStatus
+	parked    map[string]store.ParkedMessage
+	lockedBy  map[string]string // Request ID -> engine instance holding the task
+	deadSince map[string]time.Time
+}
+
+func newMemStore(tasks ...*store.LogStatus) *memStore {
+	s := &memStore{
+		tasks:     make(map[string]*store.LogStatus),
+		parked:    make(map[string]store.ParkedMessage),
+		lockedBy:  make(map[string]string),
+		deadSince: make(map[string]time.Time),
+	}
+	for _, t := range tasks {
+		s.tasks[t.RequestID] = t
+	}
+	return s
+}
+
+// task returns a copy of a task
+func (s *memStore) task(requestID string) store.LogStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.tasks[requestID]
+}
+
+func (s *memStore) GetAndMarkBatchAsProcessing(_ context.Context, requestIDs []string, maxRetries int, batchID, instanceID string) (map[string]*store.LogStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	locked := make(map[string]*store.LogStatus)
+	for _, id := range requestIDs {
+		t, ok := s.tasks[id]
+		if !ok || t.Status != store.StatusReceived {
+			continue
+		}
+		if t.RetryCount >= maxRetries {
+			msg := fmt.Sprintf("max retries (%d) exceeded", maxRetries)
+			t.Status, t.ErrorMessage = store.StatusFailed, &msg
+		} else {
+			t.Status, t.ProcessingStartedAt, t.EngineBatchID = store.StatusProcessing, &now, batchID
+			s.lockedBy[id] = instanceID
+		}
+		c := *t
+		locked[id] = &c
+	}
+	return locked, nil
+}
+
+func (s *memStore) MarkBatchAsCompleted(_ context.Context, completions []store.CompletionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range completions {
+		if t, ok := s.tasks[c.RequestID]; ok && t.Status == store.StatusProcessing {
+			txHash := c.TxHash
+			t.Status, t.TxHash = store.StatusCompleted, &txHash
+		}
+	}
+	return nil
+}
+
+func (s *memStore) MarkBatchAsFailed(_ context.Context, failures []store.FailureRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range failures {
+		if t, ok := s.tasks[f.RequestID]; ok && t.Status == store.StatusProcessing {
+			msg := f.ErrorMessage
+			t.Status, t.ErrorMessage = store.StatusFailed, &msg
+		}
+	}
+	return nil
+}
+
+func (s *memStore) MarkBatchForRetry(_ context.Context, requestIDs []string, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range requestIDs {
+		if t, ok := s.tasks[id]; ok && t.Status == store.StatusProcessing {
+			msg := lastError
+			t.Status, t.ErrorMessage, t.ProcessingStartedAt = store.StatusReceived, &msg, nil
+			t.RetryCount++
+		}
+	}
+	return nil
+}
+
+func (s *memStore) MarkBatchDeferred(_ context.Context, requestIDs []string, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range requestIDs {
+		if t, ok := s.tasks[id]; ok && t.Status == store.StatusProcessing {
+			msg := reason
+			t.Status, t.ErrorMessage, t.ProcessingStartedAt = store.StatusReceived, &msg, nil
+		}
+	}
+	return nil
+}
+
+// byHashes returns a copy of the tasks of the given log hashes in status
+func (s *memStore) byHashes(logHashes []string, status store.Status) map[string]*store.LogStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := make(map[string]*store.LogStatus)
+	for _, t := range s.tasks {
+		if t.Status == status && slices.Contains(logHashes, t.LogHash) {
+			c := *t
+			found[t.LogHash] = &c
+		}
+	}
+	return found
+}
+
+func (s *memStore) GetCompletedByHashes(_ context.Context, logHashes []string) (map[string]*store.LogStatus, error) {
+	return s.byHashes(logHashes, store.StatusCompleted), nil
+}
+
+func (s *memStore) GetProcessingByHashes(_ context.Context, logHashes []string) (map[string]*store.LogStatus, error) {
+	return s.byHashes(logHashes, store.StatusProcessing), nil
+}
+
+func (s *memStore) ListStuckTasks(_ context.Context, stuckFor time.Duration, limit int) ([]*store.LogStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stuck []*store.LogStatus
+	for _, t := range s.tasks {
+		if t.Status == store.StatusProcessing && t.ProcessingStartedAt != nil && time.Since(*t.ProcessingStartedAt) > stuckFor {
+			c := *t
+			stuck = append(stuck, &c)
+		}
+	}
+	sort.Slice(stuck, func(i, j int) bool { return stuck[i].ProcessingStartedAt.Before(*stuck[j].ProcessingStartedAt) })
+	return stuck[:min(limit, len(stuck))], nil
+}
+
+func (s *memStore) GetMerkleProof(context.Context, string) (*store.MerkleProof, error) {
+	return nil, store.ErrMerkleProofNotFound
+}
+
+func (s *memStore) ParkMessages(_ context.Context, messages []store.ParkedMessage) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, m := range messages {
+		t, ok := s.tasks[m.RequestID]
+		if _, parked := s.parked[m.RequestID]; parked || !ok || t.Status != store.StatusProcessing {
+			continue
+		}
+		m.ParkedAt = time.Now()
+		s.parked[m.RequestID] = m
+		n++
+	}
+	return n, nil
+}
+
+func (s *memStore) ListReleasedParkedMessages(_ context.Context, topics []string, limit int) ([]store.ParkedMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var released []store.ParkedMessage
+	for _, m := range s.parked {
+		m.Status = store.StatusReceived
+		if t, ok := s.tasks[m.RequestID]; ok {
+			m.Status = t.Status
+		}
+		for _, topic := range topics {
+			if m.Topic == topic && m.Status != store.StatusProcessing {
+				released = append(released, m)
+			}
+		}
+	}
+	sort.Slice(released, func(i, j int) bool { return released[i].ParkedAt.Before(released[j].ParkedAt) })
+	return released[:min(limit, len(released))], nil
+}
+
+func (s *memStore) DeleteParkedMessages(_ context.Context, requestIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range requestIDs {
+		delete(s.parked, id)
+	}
+	return nil
+}
+
+// kill makes an engine instance stop heartbeating
+func (s *memStore) kill(instanceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadSince[instanceID] = time.Now().Add(-time.Hour)
+}
+
+func (s *memStore) ReclaimDeadInstances(_ context.Context, deadAfter time.Duration) ([]store.ReclaimedInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var reclaimed []store.ReclaimedInstance
+	for instanceID, since := range s.deadSince {
+		if time.Since(since) < deadAfter {
+			continue
+		}
+		r := store.ReclaimedInstance{InstanceID: instanceID, LastHeartbeat: since}
+		for id, holder := range s.lockedBy {
+			if t := s.tasks[id]; holder == instanceID && t.Status == store.StatusProcessing {
+				t.Status, t.ProcessingStartedAt = store.StatusReceived, nil
+				t.RetryCount++
+				r.Tasks++
+			}
+		}
+		delete(s.deadSince, instanceID)
+		reclaimed = append(reclaimed, r)
+	}
+	return reclaimed, nil
+}
+
+// parkedCount returns the number of parked messages
+func (s *memStore) parkedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.parked)
+}
+
+// fakeChain anchors every entry it is given. The other BlockchainClient
+// methods panic.
+type fakeChain struct {
+	blockchain.BlockchainClient
+
+	mu       sync.Mutex
+	anchored map[string]string // Log hash -> transaction
+	txs      int
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{anchored: make(map[string]string)}
+}
+
+func (c *fakeChain) SubmitLogsBatch(_ context.Context, entries []types.LogEntry) (*types.BatchProof, []types.LogStatusInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.txs++
+	tx := fmt.Sprintf("tx-%d", c.txs)
+	results := make([]types.LogStatusInfo, len(entries))
+	for i, e := range entries {
+		c.anchored[e.LogHash] = tx
+		results[i] = types.LogStatusInfo{LogHash: e.LogHash, Status: types.StatusSuccess}
+	}
+	return &types.BatchProof{TransactionID: tx, BlockHeight: uint64(c.txs)}, results, nil
+}
+
+func (c *fakeChain) FindLogByHash(_ context.Context, logHash string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.anchored[logHash], nil
+}
+
+// fakeProducer records the messages published to it
+type fakeProducer struct {
+	mu        sync.Mutex
+	published []*models.LogMessage
+}
+
+func (p *fakeProducer) Publish(ctx context.Context, msg *models.LogMessage) error {
+	return p.PublishBatch(ctx, []*models.LogMessage{msg})
+}
+
+func (p *fakeProducer) PublishBatch(_ context.Context, msgs []*models.LogMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.published = append(p.published, msgs...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+// take returns and forgets the published messages
+func (p *fakeProducer) take() []*models.LogMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	msgs := p.published
+	p.published = nil
+	return msgs
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tlng/blockchain/types"
+	"tlng/storage/store"
+)
+
+// SetMerkleAnchoring makes the worker anchor one entry per routing group, the
+// root of a Merkle tree over the group's log hashes, submitted under orgID,
+// and store each log's inclusion proof instead of anchoring the logs
+func (w *Worker) SetMerkleAnchoring(orgID string) {
+	w.merkleOrgID = orgID
+}
+
+// merkleRoot builds the Merkle tree of a routing group and the entry anchoring its root
+func (w *Worker) merkleRoot(g *routeGroup) (*types.MerkleTree, types.LogEntry) {
+	hashes := make([]string, len(g.entries))
+	for i, e := range g.entries {
+		hashes[i] = e.LogHash
+	}
+	tree, _ := types.NewMerkleTree(hashes) // Routing groups are never empty
+	return tree, types.LogEntry{
+		LogHash:     tree.Root(),
+		LogContent:  types.MerkleRootContent(tree.Len()),
+		SenderOrgID: w.merkleOrgID,
+		Timestamp:   w.clock.Now().UTC().Format(time.RFC3339Nano),
+		BatchID:     g.batchID,
+	}
+}
+
+// completeMerkleGroup stores the inclusion proofs of a group whose root was
+// anchored and completes its tasks. Without their proofs the logs could not be
+// verified, so if they cannot be stored the tasks are marked for retry.
+func (w *Worker) completeMerkleGroup(ctx context.Context, g *routeGroup, tree *types.MerkleTree, batchProof *types.BatchProof, results []types.LogStatusInfo) {
+	root := tree.Root()
+	var rootStatus *types.LogStatusInfo
+	for i := range results {
+		if results[i].LogHash == root {
+			rootStatus = &results[i]
+		}
+	}
+	if rootStatus == nil || rootStatus.Status != types.StatusSuccess {
+		errMsg := fmt.Sprintf("Missing result for merkle root %s (TxID: %s)", root, batchProof.TransactionID)
+		if rootStatus != nil {
+			errMsg = fmt.Sprintf("Contract failed for merkle root %s: %s - %s", root, rootStatus.Status, rootStatus.Message)
+		}
+		for reqID := range g.tasks {
+			g.failures = append(g.failures, store.FailureRecord{RequestID: reqID, ErrorMessage: errMsg})
+		}
+		return
+	}
+
+	proofs := make([]store.MerkleProof, 0, tree.Len())
+	for _, e := range g.entries {
+		if len(proofs) > 0 && proofs[len(proofs)-1].LogHash == e.LogHash {
+			continue // Entries are sorted; a duplicate log hash is a single leaf
+		}
+		inclusion, _ := tree.Proof(e.LogHash) // Every entry is a leaf
+		proofs = append(proofs, store.MerkleProof{
+			LogHash:     e.LogHash,
+			MerkleRoot:  root,
+			LeafIndex:   inclusion.LeafIndex,
+			LeafCount:   inclusion.LeafCount,
+			Siblings:    inclusion.Siblings,
+			TxHash:      batchProof.TransactionID,
+			BlockHeight: batchProof.BlockHeight,
+		})
+	}
+	if err := w.store.SaveMerkleProofs(ctx, proofs); err != nil {
+		w.logger.Printf("CRITICAL: saving %d merkle proofs of root %s (batch %s, tx %s) failed: %v",
+			len(proofs), root, g.batchID, batchProof.TransactionID, err)
+		w.retryGroup(ctx, g, fmt.Errorf("failed to save merkle proofs: %w", err))
+		return
+	}
+
+	for reqID := range g.tasks {
+		g.completions = append(g.completions, store.CompletionRecord{
+			RequestID:      reqID,
+			TxHash:         batchProof.TransactionID,
+			LogHashOnChain: root,
+			BlockHeight:    batchProof.BlockHeight,
+		})
+	}
+}
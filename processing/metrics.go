@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"tlng/blockchain/types"
+	"tlng/internal/metrics"
+)
+
+// Prometheus metrics of the workers, served with the monitoring server's per-worker metrics
+var (
+	anchoredBatchSize = metrics.NewHistogram("engine_batch_messages",
+		"Messages per batch handed to anchoring.", metrics.SizeBuckets)
+	chainInvokeDuration = metrics.NewHistogram("engine_blockchain_invoke_duration_seconds",
+		"Time of a batch transaction on the chain, by routing target.", metrics.LatencyBuckets, "target")
+	batchPayloadBytes = metrics.NewHistogram("engine_batch_payload_bytes",
+		"Size of batch payloads sent to the chain, before and after compression (stage), by encoding.", metrics.ByteBuckets, "stage", "encoding")
+	chainInvokeFailures = metrics.NewCounter("engine_blockchain_invoke_failures_total",
+		"Failed batch transactions by routing target and error kind.", "target", "kind")
+	priorityBatches = metrics.NewCounter("engine_priority_batches_total",
+		"Batches cut from priority lanes by trigger (window_full, high_latency, timeout, shutdown).", "trigger")
+	trackTasks = metrics.NewCounter("engine_track_tasks_total",
+		"Tasks by deployment track (stable, canary) and outcome (completed, failed, retried).", "track", "outcome")
+)
+
+// observePayloadSize records the size of a batch payload before and after compression
+func observePayloadSize(proof *types.BatchProof) {
+	if proof.PayloadBytes == 0 {
+		return // Client without payload sizes
+	}
+	encoding := proof.Encoding
+	if encoding == "" {
+		encoding = "none"
+	}
+	batchPayloadBytes.Observe(float64(proof.PayloadBytes), "before", encoding)
+	batchPayloadBytes.Observe(float64(proof.SentBytes), "after", encoding)
+}
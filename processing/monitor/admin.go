@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	pb "tlng/proto/engineadmin"
+	"tlng/svcauth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// AdminServer implements the EngineAdmin gRPC service on top of the monitoring server
+type AdminServer struct {
+	pb.UnimplementedEngineAdminServer // Embed unimplemented service for forward compatibility
+	monitor                           *Server
+	addr                              string
+	logger                            *log.Logger
+	grpcServer                        *grpc.Server
+}
+
+// NewAdminServer creates an admin gRPC server reporting the monitoring
+// server's status. A non-nil verifier requires every caller to authenticate.
+func NewAdminServer(addr string, monitor *Server, verifier svcauth.Verifier, logger *log.Logger) *AdminServer {
+	var opts []grpc.ServerOption
+	if verifier != nil {
+		opts = svcauth.ServerOptions(verifier)
+	}
+	s := &AdminServer{
+		monitor:    monitor,
+		addr:       addr,
+		logger:     logger,
+		grpcServer: grpc.NewServer(opts...),
+	}
+	pb.RegisterEngineAdminServer(s.grpcServer, s)
+	return s
+}
+
+// GetStatus implements the GetStatus method in the gRPC interface
+func (s *AdminServer) GetStatus(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	result, err := toStruct(s.monitor.Status(ctx))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode engine status: %v", err)
+	}
+	return result, nil
+}
+
+// GetFleet implements the GetFleet method in the gRPC interface
+func (s *AdminServer) GetFleet(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	fleet, err := s.monitor.Fleet(ctx)
+	if errors.Is(err, errFleetDisabled) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to list engine instances: %v", err)
+	}
+	result, err := toStruct(fleet)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode engine fleet: %v", err)
+	}
+	return result, nil
+}
+
+// toStruct converts a JSON-encodable document to a protobuf Struct
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(doc)
+}
+
+// Start starts serving in the background
+func (s *AdminServer) Start() error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin address %s: %w", s.addr, err)
+	}
+
+	go func() {
+		s.logger.Printf("Admin gRPC server listening on %s", s.addr)
+		if err := s.grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.logger.Printf("ERROR: Admin gRPC server failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop gracefully stops the server
+func (s *AdminServer) Stop() {
+	s.grpcServer.GracefulStop()
+}
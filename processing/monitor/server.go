@@ -0,0 +1,379 @@
+// Package monitor provides the engine's monitoring HTTP server exposing
+// metrics, liveness/readiness probes and a debug status page.
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/fingerprint"
+	"tlng/internal/metrics"
+	worker "tlng/processing"
+	"tlng/storage/store"
+	"tlng/svcauth"
+)
+
+// CheckFunc is a readiness check; a non-nil error marks the engine as not ready
+type CheckFunc func(ctx context.Context) error
+
+// Server is the engine's monitoring HTTP server
+type Server struct {
+	cfg     config.EngineMonitoringConfig
+	workers []*worker.Worker
+	store   store.Store
+	logger  *log.Logger
+	started time.Time
+
+	mu     sync.RWMutex
+	checks map[string]CheckFunc
+	fleet  *worker.Fleet
+
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+// NewServer creates a monitoring server reporting on the given workers
+func NewServer(cfg config.EngineMonitoringConfig, workers []*worker.Worker, st store.Store, logger *log.Logger) *Server {
+	s := &Server{
+		cfg:     cfg,
+		workers: workers,
+		store:   st,
+		logger:  logger,
+		started: time.Now(),
+		checks:  make(map[string]CheckFunc),
+	}
+
+	mux := http.NewServeMux()
+	s.mux = mux
+	mux.HandleFunc(cfg.HealthCheckPath, s.handleHealth)
+	mux.HandleFunc(cfg.ReadinessPath, s.handleReady)
+	mux.HandleFunc(cfg.StatusPath, s.handleStatus)
+	if cfg.EnableMetrics {
+		mux.HandleFunc(cfg.MetricsPath, s.handleMetrics)
+	}
+
+	s.httpServer = &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// AddCheck registers a named readiness check
+func (s *Server) AddCheck(name string, check CheckFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// SetFleet makes the server report the engine fleet
+func (s *Server) SetFleet(f *worker.Fleet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fleet = f
+}
+
+// ConfigReport is the effective configuration the engine runs with, served at /admin/config
+type ConfigReport struct {
+	Version      string             `json:"version"`
+	ConfigSHA256 string             `json:"config_sha256"`
+	Config       json.RawMessage    `json:"config"`       // With defaults and environment overrides applied and secrets redacted
+	ConfigFiles  []fingerprint.File `json:"config_files"` // Configuration file and the files it names, with their SHA-256
+}
+
+// ServeConfig serves report at GET /admin/config. If verifier is not nil,
+// only the callers it authenticates are served. Call it before Start.
+func (s *Server) ServeConfig(report *ConfigReport, verifier svcauth.Verifier) {
+	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method Not Allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	})
+	if verifier != nil {
+		h = svcauth.RequireHTTP(verifier, h)
+	}
+	s.mux.Handle("/admin/config", h)
+}
+
+// errFleetDisabled is returned for the fleet view when heartbeats are disabled
+var errFleetDisabled = errors.New("heartbeat is disabled, the engine does not register in the fleet")
+
+// Fleet returns the engine instances registered in the State DB
+func (s *Server) Fleet(ctx context.Context) (*worker.FleetView, error) {
+	s.mu.RLock()
+	f := s.fleet
+	s.mu.RUnlock()
+	if f == nil {
+		return nil, errFleetDisabled
+	}
+	return f.View(ctx)
+}
+
+// Start starts serving in the background
+func (s *Server) Start() {
+	go func() {
+		s.logger.Printf("Monitoring server listening on %s", s.cfg.ListenAddr)
+		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Printf("ERROR: Monitoring server failed: %v", err)
+		}
+	}()
+}
+
+// Shutdown gracefully stops the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// handleHealth reports liveness: the process is up and serving
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady runs all readiness checks
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+
+	results := s.runChecks(ctx)
+	status := http.StatusOK
+	for _, result := range results {
+		if result != "ok" {
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	state := "ready"
+	if status != http.StatusOK {
+		state = "not_ready"
+	}
+	writeJSON(w, status, map[string]interface{}{"status": state, "checks": results})
+}
+
+// runChecks executes the registered readiness checks and returns their results by name
+func (s *Server) runChecks(ctx context.Context) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make(map[string]string, len(s.checks))
+	for name, check := range s.checks {
+		if err := check(ctx); err != nil {
+			results[name] = err.Error()
+		} else {
+			results[name] = "ok"
+		}
+	}
+	return results
+}
+
+// WorkerStatus is the per-worker entry of the status report
+type WorkerStatus struct {
+	ID int `json:"id"`
+	worker.Status
+	IdleSeconds int64 `json:"idle_seconds"` // Seconds since the last batch finished (or since startup)
+}
+
+// EngineStatus answers "is the engine stuck?" without reading logs
+type EngineStatus struct {
+	InstanceID        string         `json:"instance_id,omitempty"` // Fleet instance ID, if heartbeats are enabled
+	StartedAt         string         `json:"started_at"`
+	UptimeSeconds     int64          `json:"uptime_seconds"`
+	WorkerCount       int            `json:"worker_count"`
+	RetryBacklog      int64          `json:"retry_backlog"`                 // RECEIVED tasks waiting to be retried; -1 if unknown
+	RetryBacklogError string         `json:"retry_backlog_error,omitempty"` // Why the backlog could not be read
+	Workers           []WorkerStatus `json:"workers"`
+}
+
+// Status collects the current engine status
+func (s *Server) Status(ctx context.Context) EngineStatus {
+	now := time.Now()
+	status := EngineStatus{
+		StartedAt:     s.started.UTC().Format(time.RFC3339),
+		UptimeSeconds: int64(now.Sub(s.started).Seconds()),
+		WorkerCount:   len(s.workers),
+		Workers:       make([]WorkerStatus, len(s.workers)),
+	}
+	s.mu.RLock()
+	if s.fleet != nil {
+		status.InstanceID = s.fleet.InstanceID()
+	}
+	s.mu.RUnlock()
+
+	for i, wk := range s.workers {
+		ws := WorkerStatus{ID: i + 1, Status: wk.Status()}
+		last := ws.LastBatchAt
+		if last.IsZero() {
+			last = s.started
+		}
+		ws.IdleSeconds = int64(now.Sub(last).Seconds())
+		status.Workers[i] = ws
+	}
+
+	backlog, err := s.store.CountRetryBacklog(ctx)
+	if err != nil {
+		status.RetryBacklog = -1
+		status.RetryBacklogError = err.Error()
+	} else {
+		status.RetryBacklog = backlog
+	}
+
+	return status
+}
+
+// handleStatus renders the debug status page
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	writeJSON(w, http.StatusOK, s.Status(ctx))
+}
+
+// handleMetrics renders worker counters in the Prometheus text exposition
+// format, or in OpenMetrics if the scraper's Accept header asks for it
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	format := metrics.NegotiateFormat(r.Header.Get("Accept"))
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(worker.Stats) uint64
+	}{
+		{"engine_messages_consumed_total", "Messages consumed from the message queue.", func(st worker.Stats) uint64 { return st.MessagesConsumed }},
+		{"engine_batches_processed_total", "Batches processed successfully.", func(st worker.Stats) uint64 { return st.BatchesProcessed }},
+		{"engine_batches_failed_total", "Batches whose processing failed.", func(st worker.Stats) uint64 { return st.BatchesFailed }},
+		{"engine_tasks_completed_total", "Tasks marked as completed.", func(st worker.Stats) uint64 { return st.TasksCompleted }},
+		{"engine_tasks_failed_total", "Tasks marked as permanently failed.", func(st worker.Stats) uint64 { return st.TasksFailed }},
+		{"engine_tasks_retried_total", "Tasks scheduled for retry.", func(st worker.Stats) uint64 { return st.TasksRetried }},
+		{"engine_consumer_errors_total", "Message queue consumer errors.", func(st worker.Stats) uint64 { return st.ConsumerErrors }},
+		{"engine_messages_abandoned_total", "Messages nacked during shutdown for redelivery.", func(st worker.Stats) uint64 { return st.MessagesAbandoned }},
+		{"engine_replay_acked_total", "Redelivered messages of COMPLETED or FAILED tasks acked by the replay guard.", func(st worker.Stats) uint64 { return st.ReplayAcked }},
+	}
+
+	statuses := make([]worker.Status, len(s.workers))
+	for i, wk := range s.workers {
+		statuses[i] = wk.Status()
+	}
+
+	for _, c := range counters {
+		format.WriteHeader(&b, c.name, c.help, "counter")
+		for i, st := range statuses {
+			fmt.Fprintf(&b, "%s{worker=\"%d\"} %d\n", format.CounterSample(c.name), i+1, c.value(st.Stats))
+		}
+	}
+
+	b.WriteString("# HELP engine_last_batch_timestamp_seconds Unix time of the last processed batch.\n")
+	b.WriteString("# TYPE engine_last_batch_timestamp_seconds gauge\n")
+	for i, st := range statuses {
+		var ts float64
+		if !st.LastBatchAt.IsZero() {
+			ts = float64(st.LastBatchAt.UnixNano()) / 1e9
+		}
+		fmt.Fprintf(&b, "engine_last_batch_timestamp_seconds{worker=\"%d\"} %.3f\n", i+1, ts)
+	}
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(worker.Status) int64
+	}{
+		{"engine_pending_messages", "Messages buffered for the next batch.", func(st worker.Status) int64 { return st.PendingMessages }},
+		{"engine_in_flight_batch_size", "Messages in batches currently being processed.", func(st worker.Status) int64 { return st.InFlightBatchSize }},
+		{"engine_blockchain_failure_streak", "Consecutive failed blockchain submissions.", func(st worker.Status) int64 { return st.BlockchainFailureStreak }},
+		{"engine_uncommitted_messages", "Fetched messages not yet committed to Kafka.", func(st worker.Status) int64 { return int64(st.UncommittedMessages) }},
+		{"engine_queued_messages", "Messages waiting in the work queue shared by worker pipelines.", func(st worker.Status) int64 { return int64(st.QueuedMessages) }},
+		{"engine_fetch_paused", "Whether fetching is paused at kafka_consumer.max_uncommitted (1) or not (0).", func(st worker.Status) int64 {
+			if st.FetchPaused {
+				return 1
+			}
+			return 0
+		}},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for i, st := range statuses {
+			fmt.Fprintf(&b, "%s{worker=\"%d\"} %d\n", g.name, i+1, g.value(st))
+		}
+	}
+
+	b.WriteString("# HELP engine_batch_size Batch size in effect.\n# TYPE engine_batch_size gauge\n")
+	for i, st := range statuses {
+		fmt.Fprintf(&b, "engine_batch_size{worker=\"%d\"} %d\n", i+1, st.BatchSize)
+	}
+	b.WriteString("# HELP engine_batch_timeout_seconds Batch timeout in effect.\n# TYPE engine_batch_timeout_seconds gauge\n")
+	for i, st := range statuses {
+		fmt.Fprintf(&b, "engine_batch_timeout_seconds{worker=\"%d\"} %.3f\n", i+1, st.BatchTimeoutSeconds)
+	}
+
+	// Observations of batch tuning, for the workers it is enabled on
+	tuning := []struct {
+		name  string
+		help  string
+		value func(*worker.TuningStatus) float64
+	}{
+		{"engine_chain_latency_seconds", "Average chain submission time per batch.", func(t *worker.TuningStatus) float64 { return t.ChainLatencySeconds }},
+		{"engine_end_to_end_latency_seconds", "Average gateway receive to on-chain time of anchored logs.", func(t *worker.TuningStatus) float64 { return t.EndToEndLatencySeconds }},
+		{"engine_arrival_rate", "Messages consumed per second.", func(t *worker.TuningStatus) float64 { return t.ArrivalRate }},
+		{"engine_target_latency_seconds", "End-to-end latency target of batch tuning.", func(t *worker.TuningStatus) float64 { return t.TargetLatencySeconds }},
+	}
+	if slices.ContainsFunc(statuses, func(st worker.Status) bool { return st.Tuning != nil }) {
+		for _, g := range tuning {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+			for i, st := range statuses {
+				if st.Tuning != nil {
+					fmt.Fprintf(&b, "%s{worker=\"%d\"} %.3f\n", g.name, i+1, g.value(st.Tuning))
+				}
+			}
+		}
+	}
+
+	if backlog, err := s.store.CountRetryBacklog(r.Context()); err == nil {
+		fmt.Fprintf(&b, "# HELP engine_retry_backlog Tasks waiting to be retried.\n# TYPE engine_retry_backlog gauge\nengine_retry_backlog %d\n", backlog)
+	}
+
+	b.WriteString("# HELP engine_ready Whether each readiness check passes (1) or fails (0).\n")
+	b.WriteString("# TYPE engine_ready gauge\n")
+	results := s.runChecks(r.Context())
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		up := 0
+		if results[name] == "ok" {
+			up = 1
+		}
+		fmt.Fprintf(&b, "engine_ready{check=%s} %d\n", metrics.QuoteLabelValue(name), up)
+	}
+
+	fmt.Fprintf(&b, "# HELP engine_uptime_seconds Seconds since the engine started.\n# TYPE engine_uptime_seconds gauge\nengine_uptime_seconds %.0f\n",
+		time.Since(s.started).Seconds())
+
+	// Process-wide metrics: batch sizes, blockchain invoke latency and failures
+	metrics.Default.WriteFormat(&b, format)
+	format.WriteEOF(&b)
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
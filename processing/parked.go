@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"tlng/internal/messaging/producer"
+	"tlng/internal/metrics"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+var parkedMessages = metrics.NewCounter("engine_parked_messages_total",
+	"Redelivered messages of PROCESSING tasks, by outcome (parked, republished, discarded).", "outcome")
+
+// SetParking keeps the messages delivered again while their task is
+// PROCESSING in the State DB, recorded as consumed from topic. Such a message
+// is acknowledged and skipped, so if the worker holding the task crashed, the
+// message is gone by the time the stuck task scan or the fleet's reclaim
+// returns the task to RECEIVED; a Republisher then publishes it again.
+func (w *Worker) SetParking(topic string) {
+	w.parkTopic = topic
+	w.parking.Store(topic != "")
+}
+
+// park keeps the messages of a batch whose tasks it did not lock and that are
+// still PROCESSING. A failure is only logged: the task can still be requeued
+// with cmd/recover.
+func (w *Worker) park(ctx context.Context, msgs map[string]*models.LogMessage, locked map[string]*store.LogStatus) {
+	if !w.parking.Load() || len(locked) == len(msgs) {
+		return
+	}
+	var parked []store.ParkedMessage
+	for reqID, msg := range msgs {
+		if _, ok := locked[reqID]; ok {
+			continue
+		}
+		payload, err := json.Marshal(msg)
+		if err != nil {
+			w.logger.Printf("Warning: failed to encode message %s for parking: %v", reqID, err)
+			continue
+		}
+		parked = append(parked, store.ParkedMessage{RequestID: reqID, Topic: w.parkTopic, Payload: payload})
+	}
+	n, err := w.store.ParkMessages(ctx, parked)
+	if errors.Is(err, store.ErrFeatureUnavailable) {
+		if w.parking.CompareAndSwap(true, false) {
+			w.logger.Printf("Warning: parking of redelivered messages disabled: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		w.logger.Printf("Warning: failed to park %d redelivered messages: %v", len(parked), err)
+		return
+	}
+	if n > 0 {
+		parkedMessages.Add(float64(n), "parked")
+		w.logger.Printf("Parked %d redelivered messages of tasks still PROCESSING", n)
+	}
+}
+
+// republishBatchSize is the number of parked messages published per write
+const republishBatchSize = 500
+
+// Republisher publishes the parked messages of tasks returned to RECEIVED
+// again, through a producer of the topic they were consumed from, and drops
+// those of tasks settled meanwhile
+type Republisher struct {
+	store     store.Store
+	producers map[string]producer.Producer // Topic -> producer
+	topics    []string
+	logger    *log.Logger
+}
+
+// NewRepublisher creates a republisher for the topics of producers, which must
+// key messages like the gateways do so that they reach the same partitions
+func NewRepublisher(s store.Store, producers map[string]producer.Producer, logger *log.Logger) *Republisher {
+	topics := make([]string, 0, len(producers))
+	for topic := range producers {
+		topics = append(topics, topic)
+	}
+	return &Republisher{store: s, producers: producers, topics: topics, logger: logger}
+}
+
+// Republish publishes the parked messages of tasks back in RECEIVED, batch by
+// batch, until none are left. It returns how many it published. A message is
+// deleted once published, so concurrent republishers may publish it twice,
+// which the workers tolerate.
+func (r *Republisher) Republish(ctx context.Context) (int, error) {
+	published := 0
+	for ctx.Err() == nil {
+		parked, err := r.store.ListReleasedParkedMessages(ctx, r.topics, republishBatchSize)
+		if errors.Is(err, store.ErrFeatureUnavailable) {
+			return published, nil
+		}
+		if err != nil {
+			return published, err
+		}
+		if len(parked) == 0 {
+			return published, nil
+		}
+
+		done := make([]string, 0, len(parked))
+		byTopic := make(map[string][]*models.LogMessage)
+		discarded := 0
+		for _, p := range parked {
+			if p.Status != store.StatusReceived {
+				done = append(done, p.RequestID) // Settled without it
+				discarded++
+				continue
+			}
+			var msg models.LogMessage
+			if err := json.Unmarshal(p.Payload, &msg); err != nil {
+				r.logger.Printf("Warning: dropping parked message %s that cannot be decoded: %v", p.RequestID, err)
+				done = append(done, p.RequestID)
+				discarded++
+				continue
+			}
+			byTopic[p.Topic] = append(byTopic[p.Topic], &msg)
+		}
+
+		var publishErr error
+		for topic, msgs := range byTopic {
+			if err := r.producers[topic].PublishBatch(ctx, msgs); err != nil {
+				publishErr = errors.Join(publishErr, fmt.Errorf("failed to republish %d parked messages to %s: %w", len(msgs), topic, err))
+				continue
+			}
+			for _, msg := range msgs {
+				done = append(done, msg.RequestID)
+			}
+			published += len(msgs)
+			parkedMessages.Add(float64(len(msgs)), "republished")
+		}
+		if discarded > 0 {
+			parkedMessages.Add(float64(discarded), "discarded")
+		}
+		if err := r.store.DeleteParkedMessages(ctx, done); err != nil {
+			return published, fmt.Errorf("failed to delete parked messages: %w", err)
+		}
+		if publishErr != nil {
+			return published, publishErr
+		}
+	}
+	return published, ctx.Err()
+}
@@ -0,0 +1,233 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/clock"
+	"tlng/internal/models"
+)
+
+// SetPriorityLanes makes the worker cut its batches from a consumption window
+// by message priority (see config.PriorityLanesConfig)
+func (w *Worker) SetPriorityLanes(cfg config.PriorityLanesConfig) {
+	w.priorityLanes = &cfg
+}
+
+// highRank is the lane of high-priority messages
+var highRank = config.PriorityRank(config.PriorityHigh)
+
+// laneEntry is a buffered message with its ack and arrival time
+type laneEntry struct {
+	msg     *models.LogMessage
+	ack     func(success bool)
+	arrived time.Time
+}
+
+// priorityLanes is a worker's consumption window, one FIFO lane per priority
+// indexed by config.PriorityRank
+type priorityLanes struct {
+	lanes [3][]laneEntry // Indexed by config.PriorityRank
+	n     int
+}
+
+func (p *priorityLanes) add(e laneEntry) {
+	rank := config.PriorityRank(e.msg.Priority)
+	p.lanes[rank] = append(p.lanes[rank], e)
+	p.n++
+}
+
+// next removes up to size messages, highest priority first and oldest first
+// within a priority; with onlyHigh the batch holds high-priority messages only
+func (p *priorityLanes) next(size int, onlyHigh bool) ([]*models.LogMessage, []func(success bool)) {
+	batch := make([]*models.LogMessage, 0, min(size, p.n))
+	acks := make([]func(success bool), 0, min(size, p.n))
+	for rank := len(p.lanes) - 1; rank >= 0 && len(batch) < size; rank-- {
+		if onlyHigh && rank != highRank {
+			break
+		}
+		take := min(size-len(batch), len(p.lanes[rank]))
+		for _, e := range p.lanes[rank][:take] {
+			batch = append(batch, e.msg)
+			acks = append(acks, e.ack)
+		}
+		p.lanes[rank] = p.lanes[rank][take:]
+		p.n -= take
+	}
+	return batch, acks
+}
+
+// oldestHigh returns the arrival time of the oldest buffered high-priority message
+func (p *priorityLanes) oldestHigh() (time.Time, bool) {
+	if high := p.lanes[highRank]; len(high) > 0 {
+		return high[0].arrived, true
+	}
+	return time.Time{}, false
+}
+
+// ackSequencer releases acks in consumption order. Batches of a window are
+// not anchored in the order their messages were consumed, and committing a
+// Kafka offset commits the earlier ones of its partition, so a message's ack
+// is held until every message consumed before it is acked or nacked.
+type ackSequencer struct {
+	mu      sync.Mutex
+	pending []*sequencedAck // Consumption order
+}
+
+type sequencedAck struct {
+	ack      func(success bool)
+	resolved bool
+	success  bool
+}
+
+// wrap returns an ack that is passed on once the earlier messages are resolved
+func (s *ackSequencer) wrap(ack func(success bool)) func(success bool) {
+	a := &sequencedAck{ack: ack}
+	s.mu.Lock()
+	s.pending = append(s.pending, a)
+	s.mu.Unlock()
+	return func(success bool) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		a.resolved, a.success = true, success
+		for len(s.pending) > 0 && s.pending[0].resolved {
+			s.pending[0].ack(s.pending[0].success)
+			s.pending[0] = nil
+			s.pending = s.pending[1:]
+		}
+	}
+}
+
+// stopTimer stops a timer and drains its channel
+func stopTimer(t clock.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C():
+		default:
+		}
+	}
+}
+
+// processMessagesByPriority is the main loop of a worker goroutine with
+// priority lanes. It buffers up to the window of messages and cuts a batch,
+// highest priority first, when the window is full; when the oldest buffered
+// high-priority message has waited high_max_latency, from the high lane
+// alone; and when the batch timeout since the window started expires, for
+// the whole window.
+func (w *Worker) processMessagesByPriority(ctx, drainCtx context.Context, workerID int) {
+	cfg := w.priorityLanes
+	lanes := &priorityLanes{}
+	acks := &ackSequencer{}
+	replay := w.newReplayWindow(ctx)
+	defer replay.release()
+	batchTimer := w.clock.NewTimer(0) // Start with stopped timers
+	stopTimer(batchTimer)
+	highTimer := w.clock.NewTimer(0)
+	stopTimer(highTimer)
+
+	// At most one batch is in flight with pre-batching, as in processMessagesInBatch
+	var inFlight chan struct{}
+	waitInFlight := func() {
+		if inFlight != nil {
+			<-inFlight
+			inFlight = nil
+		}
+	}
+	defer waitInFlight()
+
+	// cut submits the next batch of the window and rearms the high-priority
+	// timer for the oldest high-priority message left
+	cut := func(trigger string, onlyHigh bool) {
+		batch, batchAcks := lanes.next(w.currentBatchSize(), onlyHigh)
+		stopTimer(highTimer)
+		if oldest, ok := lanes.oldestHigh(); ok {
+			highTimer.Reset(max(cfg.HighMaxLatency-clock.Since(w.clock, oldest), 0))
+		}
+		if lanes.n == 0 {
+			stopTimer(batchTimer)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		priorityBatches.Inc(trigger)
+		w.stats.pendingMessages.Add(-int64(len(batch)))
+		if w.workerConfig.PreBatch {
+			waitInFlight()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				w.processAndAckBatch(ctx, drainCtx, workerID, batch, batchAcks)
+			}()
+			inFlight = done
+		} else {
+			w.processAndAckBatch(ctx, drainCtx, workerID, batch, batchAcks)
+		}
+	}
+	flushWindow := func(trigger string) {
+		for lanes.n > 0 {
+			cut(trigger, false)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Printf("Worker %d: Context cancelled, stopping.", workerID)
+			// Flush the window while the drain budget lasts, otherwise leave it for redelivery
+			if lanes.n > 0 && drainCtx.Err() == nil {
+				w.logger.Printf("Worker %d: Flushing %d buffered messages before stopping.", workerID, lanes.n)
+				flushWindow("shutdown")
+				return
+			}
+			n := lanes.n
+			_, batchAcks := lanes.next(n, false)
+			for _, ack := range batchAcks {
+				ack(false)
+			}
+			w.stats.pendingMessages.Add(-int64(n))
+			w.stats.messagesAbandoned.Add(uint64(n))
+			return
+
+		case <-batchTimer.C():
+			flushWindow("timeout")
+
+		case <-highTimer.C():
+			cut("high_latency", true)
+
+		default:
+			consumeCtx, consumeCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			msg, ack, err := replay.consume(consumeCtx)
+			consumeCancel()
+
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+					continue
+				}
+				w.stats.consumerErrors.Add(1)
+				w.logger.Printf("Worker %d: Consumer error: %v", workerID, err)
+				time.Sleep(w.consumerRetryDelay)
+				continue
+			}
+			if msg == nil {
+				continue
+			}
+
+			w.stats.messagesConsumed.Add(1)
+			if lanes.n == 0 {
+				batchTimer.Reset(w.currentBatchTimeout())
+			}
+			if _, ok := lanes.oldestHigh(); !ok && config.PriorityRank(msg.Priority) == highRank {
+				highTimer.Reset(cfg.HighMaxLatency)
+			}
+			lanes.add(laneEntry{msg: msg, ack: acks.wrap(ack), arrived: w.clock.Now()})
+			w.stats.pendingMessages.Add(1)
+
+			if lanes.n >= max(cfg.Window, w.currentBatchSize()) {
+				cut("window_full", false)
+			}
+		}
+	}
+}
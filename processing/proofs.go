@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"errors"
+
+	"tlng/blockchain/types"
+	"tlng/storage/store"
+)
+
+// SetProofCache makes the worker cache the attestation proof of every log it
+// anchors or reconciles, so the query service can verify without the chain
+func (w *Worker) SetProofCache(enabled bool) {
+	w.proofCache.Store(enabled)
+}
+
+// cacheProofs saves attestation proofs. Best effort: a missing proof only costs
+// the query service a chain lookup. The cache switches itself off if the schema
+// does not have it.
+func (w *Worker) cacheProofs(ctx context.Context, proofs []store.AttestationProof) {
+	if len(proofs) == 0 || !w.proofCache.Load() {
+		return
+	}
+	err := w.store.SaveAttestationProofs(ctx, proofs)
+	if errors.Is(err, store.ErrFeatureUnavailable) {
+		if w.proofCache.CompareAndSwap(true, false) {
+			w.logger.Printf("Warning: attestation proof cache disabled: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		w.logger.Printf("Warning: caching %d attestation proofs failed: %v", len(proofs), err)
+	}
+}
+
+// anchoredProofs builds the proofs of completed tasks from the entries submitted to the chain
+func anchoredProofs(entries []types.LogEntry, completions []store.CompletionRecord) []store.AttestationProof {
+	byHash := make(map[string]types.LogEntry, len(entries))
+	for _, e := range entries {
+		byHash[e.LogHash] = e
+	}
+	proofs := make([]store.AttestationProof, 0, len(completions))
+	for _, c := range completions {
+		e, ok := byHash[c.LogHashOnChain]
+		if !ok {
+			continue
+		}
+		proofs = append(proofs, store.AttestationProof{
+			LogHash:         e.LogHash,
+			SenderOrgID:     e.SenderOrgID,
+			Timestamp:       e.Timestamp,
+			ClientTimestamp: e.ClientTimestamp,
+			LogContent:      e.LogContent,
+			TxHash:          c.TxHash,
+			BlockHeight:     c.BlockHeight,
+			Source:          store.ProofSourceWorker,
+		})
+	}
+	return proofs
+}
@@ -0,0 +1,335 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// IntentSpool is a local append-only file of batch intents: batches the
+// engine consumed and validated but could not anchor because the State DB was
+// read-only. It follows the gateway's WAL: Drain moves the intents aside for
+// replay and Commit discards them once they are anchored.
+type IntentSpool struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// batchIntent is the on-disk form of a deferred batch, one JSON object per line
+type batchIntent struct {
+	BatchID   string               `json:"batch_id"`
+	SpooledAt time.Time            `json:"spooled_at"`
+	Messages  []*models.LogMessage `json:"messages"`
+}
+
+// OpenIntentSpool opens (or creates) the intent spool at path
+func OpenIntentSpool(path string) (*IntentSpool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create intent spool directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open intent spool '%s': %w", path, err)
+	}
+	return &IntentSpool{path: path, file: file}, nil
+}
+
+// Append durably writes a batch intent
+func (s *IntentSpool) Append(intent batchIntent) error {
+	line, err := json.Marshal(intent)
+	if err != nil {
+		return fmt.Errorf("failed to encode batch intent: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write intent spool: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync intent spool: %w", err)
+	}
+	return nil
+}
+
+// Drain moves the spooled intents aside for replay and starts a fresh spool.
+// The moved intents stay on disk until Commit, so a crash during the replay
+// replays them again.
+func (s *IntentSpool) Drain() ([]batchIntent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Fold the current spool into the replay file left over from an interrupted replay, if any
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read intent spool: %w", err)
+	}
+	if len(data) > 0 {
+		out, err := os.OpenFile(s.replayPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open intent replay file: %w", err)
+		}
+		if _, err := out.Write(data); err != nil {
+			out.Close()
+			return nil, fmt.Errorf("failed to write intent replay file: %w", err)
+		}
+		if err := out.Sync(); err != nil {
+			out.Close()
+			return nil, fmt.Errorf("failed to sync intent replay file: %w", err)
+		}
+		if err := out.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close intent replay file: %w", err)
+		}
+		if err := s.file.Truncate(0); err != nil {
+			return nil, fmt.Errorf("failed to truncate intent spool: %w", err)
+		}
+		if err := s.file.Sync(); err != nil {
+			return nil, fmt.Errorf("failed to sync intent spool: %w", err)
+		}
+	}
+	return readIntents(s.replayPath())
+}
+
+// Commit discards the intents returned by Drain once they are anchored
+func (s *IntentSpool) Commit() error {
+	if err := os.Remove(s.replayPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove replayed intents: %w", err)
+	}
+	return nil
+}
+
+// Close closes the intent spool file
+func (s *IntentSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+func (s *IntentSpool) replayPath() string {
+	return s.path + ".replay"
+}
+
+// readIntents decodes the intents in a spool file. A torn last line from a
+// crash mid-write is skipped; the batch was not acknowledged to Kafka.
+func readIntents(path string) ([]batchIntent, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open intent replay file: %w", err)
+	}
+	defer file.Close()
+
+	var intents []batchIntent
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 256*1024*1024)
+	for scanner.Scan() {
+		var intent batchIntent
+		if err := json.Unmarshal(scanner.Bytes(), &intent); err != nil {
+			continue
+		}
+		intents = append(intents, intent)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read intent replay file: %w", err)
+	}
+	return intents, nil
+}
+
+// ReadOnlyMode is shared by the workers of an engine. While the State DB is
+// read-only, workers spool their validated batches as intents and acknowledge
+// them, instead of locking tasks and anchoring; a failed status update after
+// anchoring would leave logs on chain that the State DB does not know about.
+// Run probes the database and anchors the intents once it accepts writes.
+type ReadOnlyMode struct {
+	cfg    config.ReadOnlyModeConfig
+	spool  *IntentSpool
+	store  store.Store
+	logger *log.Logger
+
+	mu     sync.Mutex // Orders spooling against leaving read-only mode
+	active atomic.Bool
+	since  atomic.Int64 // Unix nanoseconds read-only mode was entered, 0 if inactive
+
+	batchesDeferred  atomic.Uint64
+	messagesDeferred atomic.Uint64
+	messagesInvalid  atomic.Uint64
+	batchesReplayed  atomic.Uint64
+	episodes         atomic.Uint64
+}
+
+// ReadOnlyStatus is a snapshot of the read-only mode
+type ReadOnlyStatus struct {
+	Active           bool      `json:"active"`
+	Since            time.Time `json:"since,omitempty"`
+	BatchesDeferred  uint64    `json:"batches_deferred"`  // Batches spooled while read-only
+	MessagesDeferred uint64    `json:"messages_deferred"` // Messages in those batches
+	MessagesInvalid  uint64    `json:"messages_invalid"`  // Messages dropped by validation while read-only
+	BatchesReplayed  uint64    `json:"batches_replayed"`  // Spooled batches anchored after writes resumed
+	Episodes         uint64    `json:"episodes"`          // Times read-only mode was entered
+}
+
+// NewReadOnlyMode creates the read-only mode shared by an engine's workers
+func NewReadOnlyMode(cfg config.ReadOnlyModeConfig, spool *IntentSpool, s store.Store, logger *log.Logger) *ReadOnlyMode {
+	return &ReadOnlyMode{cfg: cfg, spool: spool, store: s, logger: logger}
+}
+
+// SetReadOnlyMode makes the worker defer anchoring while the State DB is read-only
+func (w *Worker) SetReadOnlyMode(m *ReadOnlyMode) {
+	w.readOnly = m
+}
+
+// Status returns a snapshot of the read-only mode
+func (m *ReadOnlyMode) Status() ReadOnlyStatus {
+	st := ReadOnlyStatus{
+		Active:           m.active.Load(),
+		BatchesDeferred:  m.batchesDeferred.Load(),
+		MessagesDeferred: m.messagesDeferred.Load(),
+		MessagesInvalid:  m.messagesInvalid.Load(),
+		BatchesReplayed:  m.batchesReplayed.Load(),
+		Episodes:         m.episodes.Load(),
+	}
+	if ts := m.since.Load(); ts != 0 {
+		st.Since = time.Unix(0, ts)
+	}
+	return st
+}
+
+// enter switches to read-only mode after a write was rejected with cause
+func (m *ReadOnlyMode) enter(cause error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.active.CompareAndSwap(false, true) {
+		m.since.Store(time.Now().UnixNano())
+		m.episodes.Add(1)
+		m.logger.Printf("Warning: State DB is read-only (%v); deferring chain submission and spooling batches to %s", cause, m.cfg.SpoolPath)
+	}
+}
+
+// deferBatch spools the valid messages of a batch if read-only mode is
+// active. It reports whether the batch was taken; a spool error must nack it.
+func (m *ReadOnlyMode) deferBatch(batchID string, batch []*models.LogMessage) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.active.Load() {
+		return false, nil
+	}
+	valid := make([]*models.LogMessage, 0, len(batch))
+	for _, msg := range batch {
+		if err := validateMessage(msg); err != nil {
+			m.messagesInvalid.Add(1)
+			m.logger.Printf("Warning: dropping invalid message %q of batch %s: %v", msg.RequestID, batchID, err)
+			continue
+		}
+		valid = append(valid, msg)
+	}
+	if len(valid) == 0 {
+		return true, nil
+	}
+	if err := m.spool.Append(batchIntent{BatchID: batchID, SpooledAt: time.Now(), Messages: valid}); err != nil {
+		return true, err
+	}
+	m.batchesDeferred.Add(1)
+	m.messagesDeferred.Add(uint64(len(valid)))
+	return true, nil
+}
+
+// validateMessage checks a message as far as possible without the State DB
+func validateMessage(msg *models.LogMessage) error {
+	if msg.RequestID == "" {
+		return errors.New("missing request_id")
+	}
+	if msg.SourceOrgID == "" {
+		return errors.New("missing source org")
+	}
+	sum := sha256.Sum256([]byte(msg.LogContent))
+	if msg.LogHash != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("log_hash %s does not match the content", msg.LogHash)
+	}
+	return nil
+}
+
+// Run probes the State DB every probe interval while read-only mode is active
+// (or intents are left from a previous run) and, once it accepts writes,
+// anchors the spooled intents through w before leaving read-only mode.
+func (m *ReadOnlyMode) Run(ctx context.Context, w *Worker) {
+	ticker := time.NewTicker(m.cfg.ProbeInterval)
+	defer ticker.Stop()
+	pending := true // Intents may be left over from before a restart
+	for {
+		if m.active.Load() || pending {
+			if err := m.store.Writable(ctx); err != nil {
+				if store.IsReadOnly(err) {
+					m.enter(err)
+				} else if ctx.Err() == nil {
+					m.logger.Printf("Warning: read-only mode probe failed: %v", err)
+				}
+			} else if m.resume(ctx, w) {
+				pending = false
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resume anchors the spooled intents and leaves read-only mode once the spool
+// is empty. Batches keep being spooled during the replay, so they are
+// anchored after the ones deferred before them. It returns false if an
+// intent failed and the replay has to be retried.
+func (m *ReadOnlyMode) resume(ctx context.Context, w *Worker) bool {
+	for {
+		m.mu.Lock()
+		intents, err := m.spool.Drain()
+		if err == nil && len(intents) == 0 {
+			err = m.spool.Commit()
+			if err == nil && m.active.CompareAndSwap(true, false) {
+				m.logger.Printf("State DB accepts writes again after %v; resumed chain submission",
+					time.Since(time.Unix(0, m.since.Load())).Round(time.Second))
+				m.since.Store(0)
+			}
+			m.mu.Unlock()
+			return err == nil
+		}
+		m.mu.Unlock()
+		if err != nil {
+			m.logger.Printf("Warning: failed to drain intent spool: %v", err)
+			return false
+		}
+
+		m.logger.Printf("Anchoring %d batches deferred while the State DB was read-only", len(intents))
+		for _, intent := range intents {
+			if err := w.anchorBatch(ctx, intent.BatchID, intent.Messages); err != nil {
+				// The intents stay in the replay file; anchored tasks are COMPLETED and skipped next time
+				w.logger.Printf("Warning: deferred batch %s failed, retrying at the next probe: %v", intent.BatchID, err)
+				if store.IsReadOnly(err) {
+					m.enter(err)
+				}
+				return false
+			}
+			m.batchesReplayed.Add(1)
+		}
+		if err := m.spool.Commit(); err != nil {
+			m.logger.Printf("Warning: %v", err)
+			return false
+		}
+	}
+}
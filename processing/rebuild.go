@@ -0,0 +1,163 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+
+	"tlng/internal/events"
+	"tlng/internal/messaging/consumer"
+	"tlng/internal/models"
+	"tlng/proto/internalpb"
+	"tlng/storage/store"
+
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+)
+
+// Rebuilder reconstructs tbl_log_status from Kafka, for disaster recovery when
+// the State DB is lost or its backup lags behind the topics' retention.
+// Submissions on the log topics become RECEIVED records, then the final
+// outcomes on the status events topic are applied on top of them.
+type Rebuilder struct {
+	store     store.Store
+	brokers   []string
+	tlsConfig *tls.Config
+	batchSize int
+	logger    *log.Logger
+	stats     RebuildStats
+}
+
+// RebuildStats counts what a rebuild replayed and wrote
+type RebuildStats struct {
+	Messages    int64 // Log topic messages replayed
+	Inserted    int64 // RECEIVED records inserted
+	Existing    int64 // Submissions already in the State DB
+	Events      int64 // Status events replayed
+	Outcomes    int64 // Final outcomes written
+	Undecodable int64 // Messages and events that could not be decoded
+}
+
+// NewRebuilder creates a rebuilder writing to s, in batches of batchSize
+func NewRebuilder(s store.Store, brokers []string, tlsConfig *tls.Config, batchSize int, logger *log.Logger) *Rebuilder {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	return &Rebuilder{store: s, brokers: brokers, tlsConfig: tlsConfig, batchSize: batchSize, logger: logger}
+}
+
+// Stats returns the counts so far
+func (r *Rebuilder) Stats() RebuildStats {
+	return r.stats
+}
+
+// RestoreSubmissions replays a log topic from start, inserting a RECEIVED
+// record for every submission that is not in the State DB yet
+func (r *Rebuilder) RestoreSubmissions(ctx context.Context, topic string, start consumer.ReplayStart) error {
+	return r.restoreSubmissions(ctx, topic, start, nil)
+}
+
+// restoreSubmissions is RestoreSubmissions, passing every decoded message to
+// observe if it is set
+func (r *Rebuilder) restoreSubmissions(ctx context.Context, topic string, start consumer.ReplayStart, observe func(kafka.Message, *models.LogMessage)) error {
+	batch := make([]*store.LogStatus, 0, r.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := r.store.InsertLogStatusBatch(ctx, batch, store.ConflictDoNothing)
+		if err != nil {
+			return fmt.Errorf("failed to insert replayed submissions: %w", err)
+		}
+		r.stats.Inserted += int64(len(result.Inserted))
+		r.stats.Existing += int64(len(result.Skipped))
+		batch = batch[:0]
+		return nil
+	}
+
+	n, err := consumer.Replay(ctx, r.brokers, r.tlsConfig, topic, start, func(msg kafka.Message) error {
+		logMsg, err := consumer.DecodeReplayed(msg)
+		if err != nil || logMsg.RequestID == "" {
+			r.stats.Undecodable++
+			r.logger.Printf("Warning: skipping undecodable message at %s/%d offset %d: %v", topic, msg.Partition, msg.Offset, err)
+			return nil
+		}
+		if observe != nil {
+			observe(msg, logMsg)
+		}
+		status := &store.LogStatus{
+			RequestID:         logMsg.RequestID,
+			LogHash:           logMsg.LogHash,
+			SourceOrgID:       logMsg.SourceOrgID,
+			ReceivedTimestamp: logMsg.ReceivedTimestamp.Time,
+			Status:            store.StatusReceived,
+			Region:            logMsg.Region,
+			GatewayBatchID:    logMsg.BatchID,
+		}
+		if logMsg.ClientTimestamp != nil {
+			t := logMsg.ClientTimestamp.Time
+			status.ClientTimestamp = &t
+		}
+		batch = append(batch, status)
+		if len(batch) >= r.batchSize {
+			return flush()
+		}
+		return nil
+	}, r.logger)
+	r.stats.Messages += n
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// RestoreOutcomes replays the status events topic from start, writing the
+// COMPLETED and FAILED outcomes it records
+func (r *Rebuilder) RestoreOutcomes(ctx context.Context, topic string, start consumer.ReplayStart) error {
+	return r.restoreOutcomes(ctx, topic, start, nil)
+}
+
+// restoreOutcomes is RestoreOutcomes, passing every decoded event to observe
+// if it is set
+func (r *Rebuilder) restoreOutcomes(ctx context.Context, topic string, start consumer.ReplayStart, observe func(*internalpb.StatusChangeEvent)) error {
+	batch := make([]store.LogOutcome, 0, r.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		written, err := r.store.RestoreLogOutcomes(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to restore replayed outcomes: %w", err)
+		}
+		r.stats.Outcomes += written
+		batch = batch[:0]
+		return nil
+	}
+
+	n, err := consumer.Replay(ctx, r.brokers, r.tlsConfig, topic, start, func(msg kafka.Message) error {
+		var event internalpb.StatusChangeEvent
+		if err := proto.Unmarshal(msg.Value, &event); err != nil || event.GetRequestId() == "" {
+			r.stats.Undecodable++
+			r.logger.Printf("Warning: skipping undecodable status event at %s/%d offset %d: %v", topic, msg.Partition, msg.Offset, err)
+			return nil
+		}
+		if observe != nil {
+			observe(&event)
+		}
+		outcome, ok := events.Outcome(&event)
+		if !ok {
+			return nil
+		}
+		batch = append(batch, outcome)
+		if len(batch) >= r.batchSize {
+			return flush()
+		}
+		return nil
+	}, r.logger)
+	r.stats.Events += n
+	if err != nil {
+		return err
+	}
+	return flush()
+}
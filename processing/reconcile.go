@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+
+	"tlng/internal/events"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// EnableReconciliation makes the worker consult peer regions' State DBs before
+// anchoring. Logs already anchored by a peer are marked COMPLETED locally with
+// the peer's transaction proof instead of being submitted again.
+func (w *Worker) EnableReconciliation(region string, peers map[string]store.Store) {
+	w.region = region
+	w.peerStores = peers
+	w.logger.Printf("Cross-region reconciliation enabled for region %s with %d peers", region, len(peers))
+}
+
+// reconcileWithPeers removes tasks already anchored by a peer region from tasks
+// and records them as completed with the peer's proof. msgs holds the tasks'
+// messages by request ID, for caching the reconciled attestation proofs.
+// Tasks a peer is anchoring at the same time are removed too and deferred to
+// the peer; it returns an error if any were, to nack their messages.
+func (w *Worker) reconcileWithPeers(ctx context.Context, tasks map[string]*store.LogStatus, msgs map[string]*models.LogMessage) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	hashToRequestIDs := make(map[string][]string, len(tasks))
+	logHashes := make([]string, 0, len(tasks))
+	for reqID, task := range tasks {
+		if _, seen := hashToRequestIDs[task.LogHash]; !seen {
+			logHashes = append(logHashes, task.LogHash)
+		}
+		hashToRequestIDs[task.LogHash] = append(hashToRequestIDs[task.LogHash], reqID)
+	}
+
+	reconciled := make(map[string]*store.LogStatus)
+	var merged []store.CompletionRecord
+	var proofs []store.AttestationProof
+	for peerName, peer := range w.peerStores {
+		anchored, err := peer.GetCompletedByHashes(ctx, logHashes)
+		if err != nil {
+			// Fail open: the contract rejects duplicate hashes (Merkle anchoring,
+			// which hides them from the contract, is not allowed with reconciliation),
+			// so anchoring is still safe
+			w.logger.Printf("Warning: reconciliation lookup in region %s failed: %v", peerName, err)
+			continue
+		}
+
+		for logHash, record := range anchored {
+			reqIDs, pending := hashToRequestIDs[logHash]
+			if !pending || record.TxHash == nil {
+				continue
+			}
+			var blockHeight uint64
+			if record.BlockHeight != nil {
+				blockHeight = uint64(*record.BlockHeight)
+			}
+			for _, reqID := range reqIDs {
+				merged = append(merged, store.CompletionRecord{
+					RequestID:      reqID,
+					TxHash:         *record.TxHash,
+					LogHashOnChain: logHash,
+					BlockHeight:    blockHeight,
+				})
+				reconciled[reqID] = tasks[reqID]
+				delete(tasks, reqID)
+			}
+			// The peer's State DB keeps timestamps at microsecond precision, so the
+			// proof leaves the on-chain timestamps to be filled in from the chain
+			proofs = append(proofs, store.AttestationProof{
+				LogHash:     logHash,
+				SenderOrgID: record.SourceOrgID,
+				LogContent:  msgs[reqIDs[0]].LogContent,
+				TxHash:      *record.TxHash,
+				BlockHeight: blockHeight,
+				Source:      store.ProofSourceReconcile,
+			})
+			delete(hashToRequestIDs, logHash)
+		}
+	}
+
+	if len(merged) > 0 {
+		w.completeReconciled(ctx, reconciled, merged, proofs)
+	}
+	return w.deferToPeers(ctx, tasks, hashToRequestIDs)
+}
+
+// completeReconciled records tasks anchored by a peer region as completed
+func (w *Worker) completeReconciled(ctx context.Context, reconciled map[string]*store.LogStatus, merged []store.CompletionRecord, proofs []store.AttestationProof) {
+	if err := retryOnConflict(func() error { return w.store.MarkBatchAsCompleted(ctx, merged) }); err != nil {
+		w.logger.Printf("DB update errors: reconciled completion update failed: %v", err)
+		return
+	}
+	w.stats.tasksCompleted.Add(uint64(len(merged)))
+	trackTasks.Add(float64(len(merged)), w.track, "completed")
+	w.publishCompletions(reconciled, merged)
+	w.cacheProofs(ctx, proofs)
+	w.logger.Printf("Reconciled %d tasks already anchored by peer regions", len(merged))
+}
+
+// deferToPeers removes from tasks those a peer region picked up first and is
+// still anchoring, and returns them to RECEIVED without counting a retry, as
+// waiting for a peer is not a failure; their messages are nacked, so on
+// redelivery they are reconciled once the peer completes them. Both
+// regions pick a task up before consulting the other, so of two regions
+// anchoring the same log at once, at least the later one sees the other;
+// ties go to the region whose name sorts first. hashToRequestIDs holds the
+// request IDs of tasks by log hash.
+func (w *Worker) deferToPeers(ctx context.Context, tasks map[string]*store.LogStatus, hashToRequestIDs map[string][]string) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	logHashes := make([]string, 0, len(hashToRequestIDs))
+	for logHash := range hashToRequestIDs {
+		logHashes = append(logHashes, logHash)
+	}
+
+	deferred := make(map[string]*store.LogStatus)
+	for peerName, peer := range w.peerStores {
+		inFlight, err := peer.GetProcessingByHashes(ctx, logHashes)
+		if err != nil {
+			w.logger.Printf("Warning: reconciliation lookup in region %s failed: %v", peerName, err)
+			continue
+		}
+		for logHash, record := range inFlight {
+			for _, reqID := range hashToRequestIDs[logHash] {
+				task, pending := tasks[reqID]
+				if !pending || !pickedUpFirst(record, peerName, task, w.region) {
+					continue
+				}
+				deferred[reqID] = task
+				delete(tasks, reqID)
+			}
+		}
+	}
+	if len(deferred) == 0 {
+		return nil
+	}
+
+	requestIDs := make([]string, 0, len(deferred))
+	for reqID := range deferred {
+		requestIDs = append(requestIDs, reqID)
+	}
+	reason := "deferred to a peer region anchoring the same log"
+	if err := retryOnConflict(func() error { return w.store.MarkBatchDeferred(ctx, requestIDs, reason) }); err != nil {
+		// The tasks stay PROCESSING until the stuck task scanner finds them anchored by the peer
+		w.logger.Printf("CRITICAL: MarkBatchDeferred failed: %v", err)
+	} else {
+		w.stats.tasksRetried.Add(uint64(len(deferred)))
+		trackTasks.Add(float64(len(deferred)), w.track, "retried")
+		w.publishTransitions(deferred, store.StatusReceived, func(e *events.StatusEvent) {
+			e.Error = reason
+		})
+	}
+	return fmt.Errorf("%d tasks %s", len(deferred), reason)
+}
+
+// pickedUpFirst reports whether the peer region's PROCESSING record of a log
+// was picked up before the local task of the same log
+func pickedUpFirst(record *store.LogStatus, peerName string, task *store.LogStatus, region string) bool {
+	if record.ProcessingStartedAt == nil || task.ProcessingStartedAt == nil {
+		return true
+	}
+	if !record.ProcessingStartedAt.Equal(*task.ProcessingStartedAt) {
+		return record.ProcessingStartedAt.Before(*task.ProcessingStartedAt)
+	}
+	return peerName < region
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+func TestRepeatedDeferralsKeepRetryBudget(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New(io.Discard, "", 0)
+	const maxRetries = 3
+	msg := &models.LogMessage{RequestID: "req-1", LogContent: "user login", LogHash: "hash-1", SourceOrgID: "org-a", ReceivedTimestamp: models.NewTimestamp(time.Now())}
+
+	// The peer region picked the same log up an hour ago and is still anchoring it
+	peerStartedAt := time.Now().Add(-time.Hour)
+	peerTask := &store.LogStatus{RequestID: "peer-req-1", LogHash: msg.LogHash, SourceOrgID: msg.SourceOrgID, Status: store.StatusProcessing, ProcessingStartedAt: &peerStartedAt}
+	peer := newMemStore(peerTask)
+	s := newMemStore(&store.LogStatus{RequestID: msg.RequestID, LogHash: msg.LogHash, SourceOrgID: msg.SourceOrgID, Status: store.StatusReceived})
+	chain := newFakeChain()
+	w := New(testWorkerConfig, maxRetries, logger, s, nil, chain)
+	w.EnableReconciliation("eu", map[string]store.Store{"us": peer})
+
+	// Each redelivery is deferred again, more often than the retry budget allows
+	for i := 1; i <= 2*maxRetries; i++ {
+		if err := w.anchorBatch(ctx, "batch", []*models.LogMessage{msg}); err == nil {
+			t.Fatalf("delivery %d: anchorBatch succeeded, want an error to nack the deferred message", i)
+		}
+		task := s.task(msg.RequestID)
+		if task.Status != store.StatusReceived || task.RetryCount != 0 {
+			t.Fatalf("delivery %d: task is %s with %d retries, want RECEIVED with 0", i, task.Status, task.RetryCount)
+		}
+	}
+	if chain.txs != 0 {
+		t.Fatalf("a log deferred to the peer region was anchored")
+	}
+
+	// Once the peer completes the log, the next redelivery is reconciled
+	peer.mu.Lock()
+	txHash := "peer-tx"
+	peerTask.Status, peerTask.TxHash = store.StatusCompleted, &txHash
+	peer.mu.Unlock()
+	if err := w.anchorBatch(ctx, "batch", []*models.LogMessage{msg}); err != nil {
+		t.Fatalf("anchorBatch after the peer completed failed: %v", err)
+	}
+	task := s.task(msg.RequestID)
+	if task.Status != store.StatusCompleted || task.TxHash == nil || *task.TxHash != txHash {
+		t.Errorf("task is %s with tx %v, want COMPLETED with the peer's %s", task.Status, task.TxHash, txHash)
+	}
+	if chain.txs != 0 {
+		t.Errorf("a log anchored by the peer region was anchored again")
+	}
+}
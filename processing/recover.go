@@ -0,0 +1,317 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/internal/events"
+	"tlng/internal/messaging/consumer"
+	"tlng/internal/messaging/topic"
+	"tlng/internal/models"
+	"tlng/proto/internalpb"
+	"tlng/storage/store"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Recovery brings a State DB restored to a point in time back in line with
+// Kafka and the chain. Submissions published after the restore point are
+// replayed into the database and their recorded outcomes applied; the rest are
+// looked up on chain, requeued for the engine if it consumed them already, or
+// reported as lost when their log message is gone.
+type Recovery struct {
+	rebuilder *Rebuilder
+	store     store.Store
+	client    blockchain.BlockchainClient
+	brokers   []string
+	tlsConfig *tls.Config
+	logger    *log.Logger
+}
+
+// RecoveryTopic is a log topic and the engine consumer group reading it
+type RecoveryTopic struct {
+	Name    string
+	GroupID string
+}
+
+// RecoveryReport describes what a recovery found and did
+type RecoveryReport struct {
+	RestorePoint time.Time
+	Partitions   []RecoveryPartition
+	Transactions []RecoveryTransaction // Anchoring transactions recorded after the restore point
+	Stats        RebuildStats
+	Reconciled   int64 // Submissions found anchored on chain and marked COMPLETED
+	Pending      int64 // Submissions the engine has yet to consume
+	Stranded     int64 // Submissions the engine consumed but never anchored
+	Requeued     int64 // Stranded submissions published again for the engine
+	Unresolved   int64 // Submissions received before the restore point, whose log messages are not replayed, still without an outcome
+	Lost         []LostSubmission
+}
+
+// RecoveryPartition is a log topic partition as seen from the restore point
+type RecoveryPartition struct {
+	Topic string
+	topic.Position
+	Committed int64 // Offset committed by the engine's consumer group; -1 if none
+
+	// Messages published after the restore point may have been deleted by
+	// retention: the partition was truncated and its first retained message is
+	// newer than the restore point
+	RetentionGap bool
+}
+
+// RecoveryTransaction is an anchoring transaction recorded in the status events
+type RecoveryTransaction struct {
+	TxHash      string
+	BlockHeight uint64
+	Submissions int    // Submissions recorded as anchored by it
+	OnChain     int    // Entries the transaction attests on chain
+	Error       string // Why it could not be read from the chain, if it could not
+}
+
+// LostSubmission is a submission recorded after the restore point that is
+// neither anchored nor recoverable, because its log message is no longer in Kafka
+type LostSubmission struct {
+	RequestID   string
+	LogHash     string
+	SourceOrgID string
+	ReceivedAt  time.Time
+}
+
+// recoveredSubmission is a submission seen while replaying
+type recoveredSubmission struct {
+	logHash     string
+	sourceOrgID string
+	receivedAt  time.Time
+	msg         *kafka.Message // Nil if only seen in the status events
+	resolved    bool           // A final outcome was recorded
+}
+
+// NewRecovery creates a recovery writing to s in batches of batchSize
+func NewRecovery(s store.Store, client blockchain.BlockchainClient, brokers []string, tlsConfig *tls.Config, batchSize int, logger *log.Logger) *Recovery {
+	return &Recovery{
+		rebuilder: NewRebuilder(s, brokers, tlsConfig, batchSize, logger),
+		store:     s,
+		client:    client,
+		brokers:   brokers,
+		tlsConfig: tlsConfig,
+		logger:    logger,
+	}
+}
+
+// Recover replays the log topics and the status events topic (if not empty)
+// from the restore point and reconciles the submissions left without an
+// outcome. The consumer groups' committed offsets tell the submissions the
+// engine has yet to consume from the stranded ones, which are published again
+// only if requeue is set.
+func (r *Recovery) Recover(ctx context.Context, restorePoint time.Time, logTopics []RecoveryTopic, eventsTopic string, requeue bool) (*RecoveryReport, error) {
+	report := &RecoveryReport{RestorePoint: restorePoint}
+	committed := make(map[string]map[int]int64, len(logTopics))
+	for _, t := range logTopics {
+		positions, err := topic.OffsetsAt(ctx, r.brokers, r.tlsConfig, t.Name, restorePoint, 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		offsets, err := topic.CommittedOffsets(ctx, r.brokers, r.tlsConfig, t.GroupID, t.Name, 10*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		committed[t.Name] = offsets
+		for _, pos := range positions {
+			p := RecoveryPartition{Topic: t.Name, Position: pos, Committed: -1}
+			if offset, ok := offsets[pos.Partition]; ok {
+				p.Committed = offset
+			}
+			p.RetentionGap = pos.FirstOffset > 0 && pos.Offset == pos.FirstOffset && pos.Time.After(restorePoint)
+			report.Partitions = append(report.Partitions, p)
+		}
+	}
+
+	start := consumer.ReplayStart{Time: restorePoint}
+	submissions := make(map[string]*recoveredSubmission)
+	for _, t := range logTopics {
+		r.logger.Printf("Replaying submissions from kafka topic %s since %s...", t.Name, restorePoint.Format(time.RFC3339))
+		err := r.rebuilder.restoreSubmissions(ctx, t.Name, start, func(msg kafka.Message, logMsg *models.LogMessage) {
+			submissions[logMsg.RequestID] = &recoveredSubmission{
+				logHash:     logMsg.LogHash,
+				sourceOrgID: logMsg.SourceOrgID,
+				receivedAt:  logMsg.ReceivedTimestamp.Time,
+				msg:         &msg,
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	transactions := make(map[string]*RecoveryTransaction)
+	if eventsTopic != "" {
+		r.logger.Printf("Replaying outcomes from kafka topic %s since %s...", eventsTopic, restorePoint.Format(time.RFC3339))
+		err := r.rebuilder.restoreOutcomes(ctx, eventsTopic, start, func(event *internalpb.StatusChangeEvent) {
+			sub, seen := submissions[event.GetRequestId()]
+			if !seen {
+				sub = &recoveredSubmission{logHash: event.GetLogHash(), sourceOrgID: event.GetSourceOrgId()}
+				if event.GetReceivedAt() != nil {
+					sub.receivedAt = event.GetReceivedAt().AsTime()
+				}
+				submissions[event.GetRequestId()] = sub
+			}
+			outcome, final := events.Outcome(event)
+			if !final {
+				return
+			}
+			sub.resolved = true
+			if outcome.Status != store.StatusCompleted || outcome.TxHash == "" {
+				return
+			}
+			tx, ok := transactions[outcome.TxHash]
+			if !ok {
+				tx = &RecoveryTransaction{TxHash: outcome.TxHash, BlockHeight: outcome.BlockHeight}
+				transactions[outcome.TxHash] = tx
+			}
+			tx.Submissions++
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Stats = r.rebuilder.Stats()
+
+	anchored, err := r.checkTransactions(ctx, transactions, report)
+	if err != nil {
+		return nil, err
+	}
+	stranded, err := r.reconcile(ctx, submissions, committed, anchored, report)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Stranded = int64(len(stranded))
+	if requeue && len(stranded) > 0 {
+		r.logger.Printf("Requeuing %d stranded submissions...", len(stranded))
+		if err := consumer.Requeue(ctx, r.brokers, r.tlsConfig, stranded); err != nil {
+			return nil, err
+		}
+		report.Requeued = int64(len(stranded))
+	}
+	return report, nil
+}
+
+// checkTransactions reads the anchoring transactions from the chain and
+// returns the transaction anchoring each log hash they attest
+func (r *Recovery) checkTransactions(ctx context.Context, transactions map[string]*RecoveryTransaction, report *RecoveryReport) (map[string]*RecoveryTransaction, error) {
+	hashes := make([]string, 0, len(transactions))
+	for txHash := range transactions {
+		hashes = append(hashes, txHash)
+	}
+	sort.Strings(hashes)
+
+	anchored := make(map[string]*RecoveryTransaction)
+	for _, txHash := range hashes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tx := transactions[txHash]
+		entries, err := r.client.GetLogByTxHash(ctx, txHash)
+		if err != nil {
+			tx.Error = err.Error()
+			r.logger.Printf("Warning: failed to read anchoring transaction %s from the chain: %v", txHash, err)
+		}
+		tx.OnChain = len(entries)
+		for _, entry := range entries {
+			anchored[entry.LogHash] = tx
+			if entry.BlockHeight != 0 {
+				tx.BlockHeight = entry.BlockHeight
+			}
+		}
+		report.Transactions = append(report.Transactions, *tx)
+	}
+	return anchored, nil
+}
+
+// reconcile settles the submissions without a recorded outcome: those the
+// engine resolved since the restore are left alone, anchored ones are marked
+// COMPLETED and unanchored ones are pending, stranded or lost. It returns the
+// log messages of the stranded submissions.
+func (r *Recovery) reconcile(ctx context.Context, submissions map[string]*recoveredSubmission, committed map[string]map[int]int64, anchored map[string]*RecoveryTransaction, report *RecoveryReport) ([]kafka.Message, error) {
+	requestIDs := make([]string, 0, len(submissions))
+	for reqID, sub := range submissions {
+		if !sub.resolved {
+			requestIDs = append(requestIDs, reqID)
+		}
+	}
+	sort.Strings(requestIDs)
+
+	var outcomes []store.LogOutcome
+	var stranded []kafka.Message
+	for _, reqID := range requestIDs {
+		sub := submissions[reqID]
+		current, err := r.store.GetLogStatusByRequestID(ctx, reqID)
+		if err != nil && !errors.Is(err, store.ErrLogNotFound) {
+			return nil, fmt.Errorf("failed to read submission %s: %w", reqID, err)
+		}
+		if current != nil && (current.Status == store.StatusCompleted || current.Status == store.StatusFailed) {
+			continue
+		}
+
+		outcome := store.LogOutcome{
+			RequestID:         reqID,
+			LogHash:           sub.logHash,
+			SourceOrgID:       sub.sourceOrgID,
+			ReceivedTimestamp: sub.receivedAt,
+			Status:            store.StatusCompleted,
+			FinishedAt:        time.Now(),
+		}
+		if tx, ok := anchored[sub.logHash]; ok {
+			outcome.TxHash, outcome.BlockHeight = tx.TxHash, tx.BlockHeight
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+		onChain, err := r.client.FindLogByHash(ctx, sub.logHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up log hash %s on chain: %w", sub.logHash, err)
+		}
+		if onChain != "" {
+			// Anchored, but the transaction is not known
+			outcomes = append(outcomes, outcome)
+			continue
+		}
+
+		switch {
+		case sub.msg == nil && sub.receivedAt.Before(report.RestorePoint):
+			report.Unresolved++
+		case sub.msg == nil:
+			report.Lost = append(report.Lost, LostSubmission{RequestID: reqID, LogHash: sub.logHash, SourceOrgID: sub.sourceOrgID, ReceivedAt: sub.receivedAt})
+		case sub.msg.Offset >= committedOffset(committed, sub.msg.Topic, sub.msg.Partition):
+			report.Pending++
+		default:
+			stranded = append(stranded, *sub.msg)
+		}
+	}
+
+	if len(outcomes) > 0 {
+		written, err := r.store.RestoreLogOutcomes(ctx, outcomes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record reconciled outcomes: %w", err)
+		}
+		report.Reconciled = written
+	}
+	return stranded, nil
+}
+
+// committedOffset returns the engine's committed offset on a partition, 0 if
+// it committed none: the group then starts from the earliest offset, unless
+// auto_offset_reset is latest
+func committedOffset(committed map[string]map[int]int64, topicName string, partition int) int64 {
+	offset, ok := committed[topicName][partition]
+	if !ok || offset < 0 {
+		return 0
+	}
+	return offset
+}
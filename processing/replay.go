@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+
+	"tlng/config"
+	"tlng/internal/models"
+)
+
+// SetReplayGuard makes the worker ack redelivered messages of COMPLETED and
+// FAILED tasks without batching them, for a while after Run starts (see
+// config.ReplayGuardConfig)
+func (w *Worker) SetReplayGuard(cfg config.ReplayGuardConfig) {
+	w.replayGuard = &cfg
+}
+
+// replayMessage is a consumed message waiting in a replay window
+type replayMessage struct {
+	msg *models.LogMessage
+	ack func(success bool)
+}
+
+// replayWindow consumes the messages of one worker goroutine. While the
+// replay guard is active it reads a window of messages, looks up their tasks
+// in one query, acks the messages of terminal tasks and hands out the others
+// one at a time; afterwards it consumes directly. Acks go through an
+// ackSequencer, so the ack of a terminal task waits for the messages consumed
+// before it, which may still be in a window or a batch.
+type replayWindow struct {
+	w       *Worker
+	ctx     context.Context // The worker goroutine's context, for the status lookups
+	pending []replayMessage // Messages of the last window not yet handed out
+	acks    ackSequencer
+}
+
+// newReplayWindow creates the replay window of a worker goroutine
+func (w *Worker) newReplayWindow(ctx context.Context) *replayWindow {
+	return &replayWindow{w: w, ctx: ctx}
+}
+
+// consume returns the next message to batch. It returns a nil message without
+// error when a whole window belonged to terminal tasks.
+func (rw *replayWindow) consume(ctx context.Context) (*models.LogMessage, func(success bool), error) {
+	if len(rw.pending) == 0 {
+		if !rw.active() {
+			msg, ack, err := rw.w.consumer.Consume(ctx)
+			if msg != nil && rw.w.replayGuard != nil {
+				ack = rw.acks.wrap(ack) // Keep the order with the acks held from the guard's windows
+			}
+			return msg, ack, err
+		}
+		if err := rw.fill(ctx); err != nil {
+			return nil, nil, err
+		}
+		if len(rw.pending) == 0 {
+			return nil, nil, nil
+		}
+	}
+	next := rw.pending[0]
+	rw.pending = rw.pending[1:]
+	return next.msg, next.ack, nil
+}
+
+// active reports whether the replay guard still filters messages
+func (rw *replayWindow) active() bool {
+	return rw.w.replayGuard != nil && rw.w.clock.Now().Before(rw.w.replayUntil)
+}
+
+// fill reads the next window and keeps the messages of non-terminal tasks
+func (rw *replayWindow) fill(ctx context.Context) error {
+	cfg := rw.w.replayGuard
+	msg, ack, err := rw.w.consumer.Consume(ctx)
+	if err != nil || msg == nil {
+		return err
+	}
+	window := []replayMessage{{msg, rw.acks.wrap(ack)}}
+
+	// Read what arrives shortly after the first message; a consumer error
+	// ends the window and is reported by the next consume
+	fillCtx, cancel := context.WithTimeout(ctx, cfg.WindowTimeout)
+	for len(window) < cfg.Window {
+		msg, ack, err := rw.w.consumer.Consume(fillCtx)
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+				rw.w.logger.Printf("Replay guard: Consumer error while filling a window: %v", err)
+			}
+			break
+		}
+		if msg != nil {
+			window = append(window, replayMessage{msg, rw.acks.wrap(ack)})
+		}
+	}
+	cancel()
+
+	requestIDs := make([]string, 0, len(window))
+	for _, m := range window {
+		if m.msg.RequestID != "" {
+			requestIDs = append(requestIDs, m.msg.RequestID)
+		}
+	}
+	lookupCtx, lookupCancel := context.WithTimeout(rw.ctx, rw.w.blockchainTimeout)
+	terminal, err := rw.w.store.GetTerminalStatuses(lookupCtx, requestIDs)
+	lookupCancel()
+	if err != nil {
+		// Let batch assembly sort the window out, as without the guard
+		rw.w.logger.Printf("Replay guard: Failed to look up %d tasks, batching them unfiltered: %v", len(requestIDs), err)
+		rw.pending = window
+		return nil
+	}
+
+	for _, m := range window {
+		if _, ok := terminal[m.msg.RequestID]; ok {
+			m.ack(true) // Passed on once the messages consumed before it are resolved
+			rw.w.stats.messagesConsumed.Add(1)
+			rw.w.stats.messagesReplayAcked.Add(1)
+			continue
+		}
+		rw.pending = append(rw.pending, m)
+	}
+	return nil
+}
+
+// release nacks the messages not handed out, for redelivery
+func (rw *replayWindow) release() {
+	for _, m := range rw.pending {
+		m.ack(false)
+	}
+	rw.w.stats.messagesAbandoned.Add(uint64(len(rw.pending)))
+	rw.pending = nil
+}
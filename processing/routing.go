@@ -0,0 +1,221 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/blockchain/types"
+	"tlng/internal/clock"
+	"tlng/internal/events"
+	"tlng/internal/models"
+	"tlng/internal/tracing"
+	"tlng/storage/store"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultTarget is the routing target of orgs without a target of their own
+const DefaultTarget = "default"
+
+// SetRoutes anchors the logs of the orgs in orgTargets through the client of
+// their target (target name -> client) instead of the worker's own client, for
+// consortiums whose members anchor on their own contracts, chains or channels
+func (w *Worker) SetRoutes(clients map[string]blockchain.BlockchainClient, orgTargets map[string]string) {
+	w.routeClients = clients
+	w.orgTargets = orgTargets
+}
+
+// routeGroup is the part of a batch anchored through one routing target, in its own transaction
+type routeGroup struct {
+	batchID string
+	target  string
+	client  blockchain.BlockchainClient
+	entries []types.LogEntry
+	tasks   map[string]*store.LogStatus // request_id -> task
+
+	// Results
+	completions []store.CompletionRecord
+	failures    []store.FailureRecord
+	err         error
+}
+
+// routeBatch groups the tasks of a batch and their log entries by routing target
+func (w *Worker) routeBatch(batchID string, tasks map[string]*store.LogStatus, msgs map[string]*models.LogMessage) []*routeGroup {
+	groups := make(map[string]*routeGroup)
+	for reqID, task := range tasks {
+		msg := msgs[reqID]
+		target := DefaultTarget
+		client := w.blockchainClient
+		if t, ok := w.orgTargets[msg.SourceOrgID]; ok {
+			target, client = t, w.routeClients[t]
+		}
+		g, ok := groups[target]
+		if !ok {
+			g = &routeGroup{batchID: batchID, target: target, client: client, tasks: make(map[string]*store.LogStatus)}
+			groups[target] = g
+		}
+		g.tasks[reqID] = task
+		g.entries = append(g.entries, logEntry(msg, batchID))
+	}
+
+	sorted := make([]*routeGroup, 0, len(groups))
+	for _, g := range groups {
+		types.SortEntries(g.entries) // Same order as the on-chain payload
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].target < sorted[j].target })
+	return sorted
+}
+
+// logEntry builds the on-chain entry of a message anchored by engine batch batchID
+func logEntry(msg *models.LogMessage, batchID string) types.LogEntry {
+	var clientTimestamp string
+	if msg.ClientTimestamp != nil {
+		clientTimestamp = msg.ClientTimestamp.RFC3339()
+	}
+	return types.LogEntry{
+		LogHash:         msg.LogHash,
+		LogContent:      msg.LogContent,
+		SenderOrgID:     msg.SourceOrgID,
+		Timestamp:       msg.ReceivedTimestamp.RFC3339(),
+		ClientTimestamp: clientTimestamp,
+		BatchID:         batchID,
+	}
+}
+
+// submitGroup anchors a routing group in one transaction and sorts its tasks
+// into completions and failures. If the transaction fails with an error of a
+// permanent kind (see SetErrorHandling), all of the group's tasks fail;
+// otherwise they are marked for retry and g.err is set. In Merkle-root mode
+// (see SetMerkleAnchoring) the transaction anchors the group's Merkle root.
+// Groups smaller than the client's single-call threshold are anchored with
+// one SubmitLog transaction per log instead (see submitSingles).
+func (w *Worker) submitGroup(ctx context.Context, g *routeGroup) {
+	invokeCtx, cancel := context.WithTimeout(ctx, w.blockchainTimeout)
+	defer cancel()
+	invokeCtx, span := tracer.Start(invokeCtx, "engine.blockchain.submit", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.AttrBatchID.String(g.batchID), tracing.AttrTarget.String(g.target), tracing.AttrBatchSize.Int(len(g.entries))))
+	entries := g.entries
+	var tree *types.MerkleTree
+	if w.merkleOrgID != "" {
+		var root types.LogEntry
+		tree, root = w.merkleRoot(g)
+		entries = []types.LogEntry{root}
+	}
+	invokeStart := w.clock.Now()
+	if tree == nil && len(entries) < singleCallThreshold(g.client) {
+		err := w.submitSingles(ctx, invokeCtx, g)
+		chainInvokeDuration.ObserveDurationWithExemplar(clock.Since(w.clock, invokeStart), tracing.TraceID(invokeCtx), g.target)
+		tracing.End(span, err)
+		return
+	}
+	batchProof, results, err := g.client.SubmitLogsBatch(invokeCtx, entries)
+	chainInvokeDuration.ObserveDurationWithExemplar(clock.Since(w.clock, invokeStart), tracing.TraceID(invokeCtx), g.target)
+	if err == nil {
+		span.SetAttributes(tracing.AttrTxHash.String(batchProof.TransactionID), tracing.AttrBlockHeight.Int64(int64(batchProof.BlockHeight)))
+		observePayloadSize(batchProof)
+	}
+	tracing.End(span, err)
+
+	if err != nil { // Transaction failed
+		kind := w.errorPolicy.kind(err)
+		w.stats.countChainError(g.target, kind)
+		chainInvokeFailures.Inc(g.target, kind)
+		w.logger.Printf("Blockchain error (batch %s, target %s, %s): %v", g.batchID, g.target, kind, err)
+		if w.errorPolicy.permanent(err) {
+			// The chain is up and refused this transaction; redelivering it would only burn retries
+			w.stats.bcFailureStreak.Store(0)
+			for reqID := range g.tasks {
+				g.failures = append(g.failures, store.FailureRecord{RequestID: reqID, ErrorMessage: err.Error()})
+			}
+			return
+		}
+		w.stats.bcFailureStreak.Add(1)
+		w.retryGroup(ctx, g, err)
+		return
+	}
+	w.stats.bcFailureStreak.Store(0)
+	if tree != nil {
+		w.completeMerkleGroup(ctx, g, tree, batchProof, results)
+		return
+	}
+
+	resultsMap := make(map[string]types.LogStatusInfo, len(results))
+	for _, res := range results {
+		resultsMap[res.LogHash] = res
+	}
+	for reqID, task := range g.tasks {
+		statusInfo, found := resultsMap[task.LogHash]
+		if !found {
+			errMsg := fmt.Sprintf("Missing result for log_hash %s (TxID: %s)", task.LogHash, batchProof.TransactionID)
+			g.failures = append(g.failures, store.FailureRecord{
+				RequestID:    reqID,
+				ErrorMessage: errMsg,
+			})
+			continue
+		}
+
+		switch statusInfo.Status {
+		case types.StatusSuccess:
+			g.completions = append(g.completions, store.CompletionRecord{
+				RequestID:      reqID,
+				TxHash:         batchProof.TransactionID,
+				LogHashOnChain: statusInfo.LogHash,
+				BlockHeight:    batchProof.BlockHeight,
+			})
+		default:
+			errMsg := fmt.Sprintf("Contract failed: %s - %s", statusInfo.Status, statusInfo.Message)
+			g.failures = append(g.failures, store.FailureRecord{
+				RequestID:    reqID,
+				ErrorMessage: errMsg,
+			})
+		}
+	}
+}
+
+// retryGroup marks the tasks of a routing group for retry and sets g.err
+func (w *Worker) retryGroup(ctx context.Context, g *routeGroup, err error) {
+	w.retryTasks(ctx, g, g.tasks, err)
+}
+
+// retryTasks marks some tasks of a routing group for retry and sets g.err
+func (w *Worker) retryTasks(ctx context.Context, g *routeGroup, tasks map[string]*store.LogStatus, err error) {
+	requestIDs := make([]string, 0, len(tasks))
+	for reqID := range tasks {
+		requestIDs = append(requestIDs, reqID)
+	}
+	if markErr := w.store.MarkBatchForRetry(ctx, requestIDs, err.Error()); markErr != nil {
+		w.logger.Printf("CRITICAL: MarkBatchForRetry failed: %v", markErr)
+	} else {
+		w.stats.tasksRetried.Add(uint64(len(tasks)))
+		trackTasks.Add(float64(len(tasks)), w.track, "retried")
+		w.publishTransitions(tasks, store.StatusReceived, func(e *events.StatusEvent) {
+			e.RetryCount++
+			e.Error = err.Error()
+		})
+	}
+	g.err = fmt.Errorf("target %s: %w", g.target, err)
+}
+
+// submitGroups anchors the routing groups concurrently, so a slow chain does not hold up the others
+func (w *Worker) submitGroups(ctx context.Context, groups []*routeGroup) time.Duration {
+	start := w.clock.Now()
+	if len(groups) == 1 {
+		w.submitGroup(ctx, groups[0])
+		return clock.Since(w.clock, start)
+	}
+	done := make(chan struct{}, len(groups))
+	for _, g := range groups {
+		go func() {
+			w.submitGroup(ctx, g)
+			done <- struct{}{}
+		}()
+	}
+	for range groups {
+		<-done
+	}
+	return clock.Since(w.clock, start)
+}
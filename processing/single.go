@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/storage/store"
+)
+
+// singleCallThreshold is the group size below which a client anchors logs
+// with SubmitLog calls, 0 if it always uses SubmitLogsBatch
+func singleCallThreshold(client blockchain.BlockchainClient) int {
+	if p, ok := client.(blockchain.SingleCallPolicy); ok {
+		return p.SingleCallThreshold()
+	}
+	return 0
+}
+
+// submitSingles anchors a routing group with one SubmitLog transaction per
+// log hash, for chains where a batch call is wasteful for a log or two. Each
+// task is completed with the proof of its own transaction, like a batch
+// result. A log failing with a permanent error fails its tasks; after any
+// other error the remaining logs are not submitted and their tasks are marked
+// for retry with the failed one, and that error is returned.
+func (w *Worker) submitSingles(ctx, invokeCtx context.Context, g *routeGroup) error {
+	byHash := make(map[string][]string, len(g.tasks)) // log hash -> request IDs
+	for reqID, task := range g.tasks {
+		byHash[task.LogHash] = append(byHash[task.LogHash], reqID)
+	}
+
+	retry := make(map[string]*store.LogStatus)
+	var retryErr error
+	for _, e := range g.entries {
+		reqIDs, ok := byHash[e.LogHash]
+		if !ok {
+			continue // Submitted for an earlier task with the same log
+		}
+		delete(byHash, e.LogHash)
+		if retryErr != nil {
+			for _, reqID := range reqIDs {
+				retry[reqID] = g.tasks[reqID]
+			}
+			continue
+		}
+
+		proof, err := g.client.SubmitLog(invokeCtx, e.LogHash, e.LogContent, e.SenderOrgID, e.Timestamp)
+		if err != nil {
+			kind := w.errorPolicy.kind(err)
+			w.stats.countChainError(g.target, kind)
+			chainInvokeFailures.Inc(g.target, kind)
+			w.logger.Printf("Blockchain error (batch %s, target %s, log %s, %s): %v", g.batchID, g.target, e.LogHash, kind, err)
+			if w.errorPolicy.permanent(err) {
+				for _, reqID := range reqIDs {
+					g.failures = append(g.failures, store.FailureRecord{RequestID: reqID, ErrorMessage: err.Error()})
+				}
+				continue
+			}
+			retryErr = err
+			for _, reqID := range reqIDs {
+				retry[reqID] = g.tasks[reqID]
+			}
+			continue
+		}
+		for _, reqID := range reqIDs {
+			g.completions = append(g.completions, store.CompletionRecord{
+				RequestID:      reqID,
+				TxHash:         proof.TransactionID,
+				LogHashOnChain: proof.LogHash,
+				BlockHeight:    proof.BlockHeight,
+			})
+		}
+	}
+
+	if retryErr != nil {
+		w.stats.bcFailureStreak.Add(1)
+		w.retryTasks(ctx, g, retry, retryErr)
+		return retryErr
+	}
+	w.stats.bcFailureStreak.Store(0)
+	return nil
+}
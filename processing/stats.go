@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tlng/internal/messaging/consumer"
+)
+
+// Stats is a snapshot of a worker's processing counters
+type Stats struct {
+	MessagesConsumed  uint64    `json:"messages_consumed"`
+	BatchesProcessed  uint64    `json:"batches_processed"`
+	BatchesFailed     uint64    `json:"batches_failed"`
+	TasksCompleted    uint64    `json:"tasks_completed"`
+	TasksFailed       uint64    `json:"tasks_failed"`
+	TasksRetried      uint64    `json:"tasks_retried"`
+	ConsumerErrors    uint64    `json:"consumer_errors"`
+	MessagesAbandoned uint64    `json:"messages_abandoned"` // Nacked during shutdown; Kafka redelivers them after restart
+	ReplayAcked       uint64    `json:"replay_acked"`       // Redelivered messages of terminal tasks acked by the replay guard
+	LastBatchAt       time.Time `json:"last_batch_at,omitempty"`
+	LastBatchID       string    `json:"last_batch_id,omitempty"`
+}
+
+// workerStats holds the live counters updated by the worker goroutines
+type workerStats struct {
+	messagesConsumed    atomic.Uint64
+	batchesProcessed    atomic.Uint64
+	batchesFailed       atomic.Uint64
+	tasksCompleted      atomic.Uint64
+	tasksFailed         atomic.Uint64
+	tasksRetried        atomic.Uint64
+	consumerErrors      atomic.Uint64
+	messagesAbandoned   atomic.Uint64
+	messagesReplayAcked atomic.Uint64
+	lastBatchAt         atomic.Int64 // Unix nanoseconds, 0 if no batch yet
+	lastBatchID         atomic.Pointer[string]
+
+	pendingMessages atomic.Int64 // Messages buffered but not yet submitted as a batch
+	inFlightBatch   atomic.Int64 // Messages in batches currently being processed
+	bcFailureStreak atomic.Int64 // Consecutive failed blockchain submissions
+
+	chainErrorsMu sync.Mutex
+	chainErrors   map[string]map[string]uint64 // Routing target -> error kind -> failed submissions
+}
+
+// countChainError counts a failed submission through a routing target by its error kind
+func (s *workerStats) countChainError(target, kind string) {
+	s.chainErrorsMu.Lock()
+	defer s.chainErrorsMu.Unlock()
+	if s.chainErrors == nil {
+		s.chainErrors = make(map[string]map[string]uint64)
+	}
+	if s.chainErrors[target] == nil {
+		s.chainErrors[target] = make(map[string]uint64)
+	}
+	s.chainErrors[target][kind]++
+}
+
+// chainErrorCounts returns a copy of the failed submissions by routing target and error kind
+func (s *workerStats) chainErrorCounts() map[string]map[string]uint64 {
+	s.chainErrorsMu.Lock()
+	defer s.chainErrorsMu.Unlock()
+	if len(s.chainErrors) == 0 {
+		return nil
+	}
+	counts := make(map[string]map[string]uint64, len(s.chainErrors))
+	for target, kinds := range s.chainErrors {
+		counts[target] = maps.Clone(kinds)
+	}
+	return counts
+}
+
+// Stats returns a snapshot of the worker's processing counters
+func (w *Worker) Stats() Stats {
+	s := Stats{
+		MessagesConsumed:  w.stats.messagesConsumed.Load(),
+		BatchesProcessed:  w.stats.batchesProcessed.Load(),
+		BatchesFailed:     w.stats.batchesFailed.Load(),
+		TasksCompleted:    w.stats.tasksCompleted.Load(),
+		TasksFailed:       w.stats.tasksFailed.Load(),
+		TasksRetried:      w.stats.tasksRetried.Load(),
+		ConsumerErrors:    w.stats.consumerErrors.Load(),
+		MessagesAbandoned: w.stats.messagesAbandoned.Load(),
+		ReplayAcked:       w.stats.messagesReplayAcked.Load(),
+	}
+	if ts := w.stats.lastBatchAt.Load(); ts != 0 {
+		s.LastBatchAt = time.Unix(0, ts)
+	}
+	if id := w.stats.lastBatchID.Load(); id != nil {
+		s.LastBatchID = *id
+	}
+	return s
+}
+
+// Status is a point-in-time view of what a worker is doing. Pipelines sharing
+// a work queue report the consumer figures of all consumers feeding it.
+type Status struct {
+	Stats
+	PendingMessages         int64  `json:"pending_messages"`          // Buffered messages waiting for the next batch
+	InFlightBatchSize       int64  `json:"in_flight_batch_size"`      // Messages in batches currently being processed
+	Partitions              []int  `json:"partitions,omitempty"`      // Consumer partitions observed, if the consumer reports them
+	UncommittedMessages     int    `json:"uncommitted_messages"`      // Fetched but uncommitted messages, if the consumer bounds them
+	FetchPaused             bool   `json:"fetch_paused"`              // Fetching paused at the consumer's max_uncommitted
+	QueuedMessages          int    `json:"queued_messages"`           // Messages waiting in the work queue shared with other pipelines, if any
+	HashRange               string `json:"hash_range,omitempty"`      // "<first>-<last>" log hash prefixes of the pipeline's shard, if sharded
+	BlockchainFailureStreak int64  `json:"blockchain_failure_streak"` // Consecutive failed blockchain submissions
+	BlockchainState         string `json:"blockchain_state"`          // "healthy" or "failing"
+
+	// ChainErrors counts failed submissions by routing target ("default" for the
+	// worker's own chain) and error kind: chain_unavailable, timeout, tx_rejected,
+	// contract_validation or unclassified
+	ChainErrors map[string]map[string]uint64 `json:"chain_errors,omitempty"`
+
+	BatchSize           int           `json:"batch_size"`            // Batch size in effect
+	BatchTimeoutSeconds float64       `json:"batch_timeout_seconds"` // Batch timeout in effect
+	Tuning              *TuningStatus `json:"tuning,omitempty"`      // Set when batch tuning is enabled
+
+	ReadOnly *ReadOnlyStatus `json:"read_only,omitempty"` // Set when read-only mode is enabled; shared by all workers
+}
+
+// Status returns the worker's current processing state
+func (w *Worker) Status() Status {
+	st := Status{
+		Stats:                   w.Stats(),
+		PendingMessages:         w.stats.pendingMessages.Load(),
+		InFlightBatchSize:       w.stats.inFlightBatch.Load(),
+		BlockchainFailureStreak: w.stats.bcFailureStreak.Load(),
+		BlockchainState:         "healthy",
+		ChainErrors:             w.stats.chainErrorCounts(),
+		BatchSize:               w.currentBatchSize(),
+		BatchTimeoutSeconds:     w.currentBatchTimeout().Seconds(),
+	}
+	if w.tuner != nil {
+		st.Tuning = w.tuner.status()
+	}
+	if w.readOnly != nil {
+		ro := w.readOnly.Status()
+		st.ReadOnly = &ro
+	}
+	if st.BlockchainFailureStreak > 0 {
+		st.BlockchainState = "failing"
+	}
+	if pr, ok := w.consumer.(consumer.PartitionReporter); ok {
+		st.Partitions = pr.Partitions()
+	}
+	if ur, ok := w.consumer.(consumer.UncommittedReporter); ok {
+		st.UncommittedMessages, st.FetchPaused = ur.Uncommitted()
+	}
+	if qr, ok := w.consumer.(consumer.QueueReporter); ok {
+		st.QueuedMessages = qr.Queued()
+	}
+	if sr, ok := w.consumer.(consumer.ShardReporter); ok {
+		first, last := sr.HashRange()
+		st.HashRange = first + "-" + last
+	}
+	return st
+}
@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/config"
+	"tlng/internal/metrics"
+	"tlng/storage/store"
+)
+
+var stuckTasksResolved = metrics.NewCounter("engine_stuck_tasks_total",
+	"Tasks found stuck in PROCESSING, by resolution (completed, requeued).", "resolution")
+
+// StuckTaskScanner recovers the tasks a worker locked and then never settled,
+// e.g. because it crashed between locking them and recording the outcome.
+// Kafka redelivers their messages, but a redelivered message whose task is
+// PROCESSING is skipped, so without the scanner such tasks stay PROCESSING.
+// The workers park such messages (see Worker.SetParking), and the scanner
+// publishes them again once it returns their tasks to RECEIVED.
+type StuckTaskScanner struct {
+	chainLookup
+	cfg         config.StuckTasksConfig
+	republisher *Republisher // Optional; publishes the parked messages of requeued tasks (see SetRepublisher)
+	logger      *log.Logger
+}
+
+// NewStuckTaskScanner creates a scanner looking stuck tasks up through client
+func NewStuckTaskScanner(cfg config.StuckTasksConfig, s store.Store, client blockchain.BlockchainClient, logger *log.Logger) *StuckTaskScanner {
+	return &StuckTaskScanner{chainLookup: chainLookup{store: s, client: client}, cfg: cfg, logger: logger}
+}
+
+// chainLookup finds out whether tasks are anchored, through the client of the
+// routing target their org anchors on
+type chainLookup struct {
+	store   store.Store
+	client  blockchain.BlockchainClient            // Default routing target
+	clients map[string]blockchain.BlockchainClient // Routing target -> client
+	targets map[string]string                      // Org -> routing target
+}
+
+// SetRoutes looks the tasks of the orgs in orgTargets up through the client of
+// their routing target, as the workers anchor them (see Worker.SetRoutes)
+func (s *chainLookup) SetRoutes(clients map[string]blockchain.BlockchainClient, orgTargets map[string]string) {
+	s.clients = clients
+	s.targets = orgTargets
+}
+
+// SetRepublisher publishes the parked messages of the tasks the scanner
+// returns to RECEIVED, which would otherwise wait for a delivery that already
+// happened
+func (s *StuckTaskScanner) SetRepublisher(r *Republisher) {
+	s.republisher = r
+}
+
+// Run scans for stuck tasks every interval until ctx is done
+func (s *StuckTaskScanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		completed, requeued, err := s.Scan(ctx)
+		if err != nil {
+			s.logger.Printf("Warning: stuck task scan failed: %v", err)
+		}
+		if completed > 0 || requeued > 0 {
+			s.logger.Printf("Recovered tasks stuck in PROCESSING for over %v: %d found on chain and completed, %d returned to RECEIVED",
+				s.cfg.Threshold, completed, requeued)
+		}
+	}
+}
+
+// Scan resolves up to batch_size stuck tasks: tasks anchored on chain are
+// marked COMPLETED, the others are returned to RECEIVED counting a retry and
+// their parked messages are published again. A task the chain cannot be asked
+// about is left for the next scan. It returns how many tasks were completed
+// and requeued.
+func (s *StuckTaskScanner) Scan(ctx context.Context) (completed, requeued int, err error) {
+	tasks, err := s.store.ListStuckTasks(ctx, s.cfg.Threshold, s.cfg.BatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var completions []store.CompletionRecord
+	var retries []string
+	var errs []error
+	for _, task := range tasks {
+		anchored, completion, err := s.lookup(ctx, task)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", task.RequestID, err))
+			continue
+		}
+		if anchored {
+			completions = append(completions, completion)
+		} else {
+			retries = append(retries, task.RequestID)
+		}
+	}
+
+	// Both updates only touch tasks still PROCESSING, so a worker that settles one meanwhile wins
+	if len(completions) > 0 {
+		if err := retryOnConflict(func() error { return s.store.MarkBatchAsCompleted(ctx, completions) }); err != nil {
+			return 0, 0, fmt.Errorf("failed to complete stuck tasks found on chain: %w", err)
+		}
+		completed = len(completions)
+		stuckTasksResolved.Add(float64(completed), "completed")
+	}
+	if len(retries) > 0 {
+		reason := fmt.Sprintf("stuck in PROCESSING for over %v and not found on chain", s.cfg.Threshold)
+		if err := retryOnConflict(func() error { return s.store.MarkBatchForRetry(ctx, retries, reason) }); err != nil {
+			return completed, 0, fmt.Errorf("failed to requeue stuck tasks: %w", err)
+		}
+		requeued = len(retries)
+		stuckTasksResolved.Add(float64(requeued), "requeued")
+	}
+	// Also catches the tasks requeued by an earlier scan whose messages were parked after it
+	if s.republisher != nil {
+		republished, err := s.republisher.Republish(ctx)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if republished > 0 {
+			s.logger.Printf("Published the parked messages of %d requeued tasks again", republished)
+		}
+	}
+	return completed, requeued, errors.Join(errs...)
+}
+
+// lookup reports whether a task's log is anchored, from its Merkle proof if the
+// batch anchored a Merkle root, or else from the chain of its routing target
+func (s *chainLookup) lookup(ctx context.Context, task *store.LogStatus) (bool, store.CompletionRecord, error) {
+	completion := store.CompletionRecord{RequestID: task.RequestID, LogHashOnChain: task.LogHash}
+	proof, err := s.store.GetMerkleProof(ctx, task.LogHash)
+	switch {
+	case err == nil:
+		completion.TxHash, completion.BlockHeight = proof.TxHash, proof.BlockHeight
+		return true, completion, nil
+	case !errors.Is(err, store.ErrMerkleProofNotFound) && !errors.Is(err, store.ErrFeatureUnavailable):
+		return false, completion, fmt.Errorf("failed to read merkle proof: %w", err)
+	}
+
+	client := s.client
+	if target, ok := s.targets[task.SourceOrgID]; ok {
+		client = s.clients[target]
+	}
+	onChain, err := client.FindLogByHash(ctx, task.LogHash)
+	if err != nil {
+		return false, completion, fmt.Errorf("failed to look up log hash on chain: %w", err)
+	}
+	// The transaction that anchored it is not known
+	return onChain != "", completion, nil
+}
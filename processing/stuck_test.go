@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/messaging/producer"
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+var testWorkerConfig = config.WorkerConfig{BatchSize: 10, BatchTimeout: "1s", ConsumerRetryDelay: "1s", BlockchainTimeout: "5s"}
+
+func TestStuckTaskIsProcessedAfterRequeue(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New(io.Discard, "", 0)
+	msg := &models.LogMessage{RequestID: "req-1", LogContent: "user login", LogHash: "hash-1", SourceOrgID: "org-a", ReceivedTimestamp: models.NewTimestamp(time.Now())}
+
+	// A worker locked the task and crashed before anchoring it
+	lockedAt := time.Now().Add(-time.Hour)
+	s := newMemStore(&store.LogStatus{RequestID: msg.RequestID, LogHash: msg.LogHash, SourceOrgID: msg.SourceOrgID, Status: store.StatusProcessing, ProcessingStartedAt: &lockedAt})
+	chain := newFakeChain()
+	w := New(testWorkerConfig, 3, logger, s, nil, chain)
+	w.SetParking("logs")
+
+	// Kafka delivers the message again: the task is PROCESSING, so it is skipped and parked
+	if err := w.anchorBatch(ctx, "batch-1", []*models.LogMessage{msg}); err != nil {
+		t.Fatalf("anchorBatch failed: %v", err)
+	}
+	if chain.txs != 0 {
+		t.Fatalf("redelivered message of a PROCESSING task was anchored")
+	}
+	if n := s.parkedCount(); n != 1 {
+		t.Fatalf("%d parked messages, want 1", n)
+	}
+
+	p := &fakeProducer{}
+	scanner := NewStuckTaskScanner(config.StuckTasksConfig{Threshold: 10 * time.Minute, BatchSize: 10}, s, chain, logger)
+	scanner.SetRepublisher(NewRepublisher(s, map[string]producer.Producer{"logs": p}, logger))
+	completed, requeued, err := scanner.Scan(ctx)
+	if err != nil || completed != 0 || requeued != 1 {
+		t.Fatalf("Scan() = %d completed, %d requeued, %v; want 0, 1, nil", completed, requeued, err)
+	}
+	republished := p.take()
+	if len(republished) != 1 {
+		t.Fatalf("republished %d messages, want 1", len(republished))
+	}
+	if got := republished[0]; got.RequestID != msg.RequestID || got.LogContent != msg.LogContent || got.LogHash != msg.LogHash || got.SourceOrgID != msg.SourceOrgID {
+		t.Fatalf("republished %+v, want the parked message %+v", got, msg)
+	}
+	if n := s.parkedCount(); n != 0 {
+		t.Errorf("%d parked messages left after republishing, want 0", n)
+	}
+
+	// The republished message is anchored
+	if err := w.anchorBatch(ctx, "batch-2", republished); err != nil {
+		t.Fatalf("anchorBatch failed: %v", err)
+	}
+	task := s.task(msg.RequestID)
+	if task.Status != store.StatusCompleted || task.RetryCount != 1 {
+		t.Errorf("task is %s with %d retries, want COMPLETED with 1", task.Status, task.RetryCount)
+	}
+	if chain.anchored[msg.LogHash] == "" {
+		t.Errorf("log hash %s was not anchored", msg.LogHash)
+	}
+
+	// Nothing is left to requeue or publish
+	completed, requeued, err = scanner.Scan(ctx)
+	if err != nil || completed != 0 || requeued != 0 || len(p.take()) != 0 {
+		t.Errorf("second Scan() = %d completed, %d requeued, %v; want nothing to do", completed, requeued, err)
+	}
+}
+
+func TestParkedMessageOfSettledTaskIsDropped(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New(io.Discard, "", 0)
+	msg := &models.LogMessage{RequestID: "req-1", LogContent: "user login", LogHash: "hash-1", SourceOrgID: "org-a", ReceivedTimestamp: models.NewTimestamp(time.Now())}
+	lockedAt := time.Now()
+	s := newMemStore(&store.LogStatus{RequestID: msg.RequestID, LogHash: msg.LogHash, Status: store.StatusProcessing, ProcessingStartedAt: &lockedAt})
+
+	w := New(testWorkerConfig, 3, logger, s, nil, newFakeChain())
+	w.SetParking("logs")
+	if err := w.anchorBatch(ctx, "batch-1", []*models.LogMessage{msg}); err != nil {
+		t.Fatalf("anchorBatch failed: %v", err)
+	}
+
+	// The worker holding the task was only slow and completes it
+	if err := s.MarkBatchAsCompleted(ctx, []store.CompletionRecord{{RequestID: msg.RequestID, TxHash: "tx-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	p := &fakeProducer{}
+	n, err := NewRepublisher(s, map[string]producer.Producer{"logs": p}, logger).Republish(ctx)
+	if err != nil || n != 0 || len(p.take()) != 0 {
+		t.Errorf("Republish() = %d, %v; want nothing published", n, err)
+	}
+	if n := s.parkedCount(); n != 0 {
+		t.Errorf("%d parked messages left, want the settled task's dropped", n)
+	}
+}
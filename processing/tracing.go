@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+
+	"tlng/internal/models"
+	"tlng/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of batches, anchoring and blockchain transactions
+var tracer = tracing.Tracer("tlng/engine")
+
+// startBatchSpan starts the root span of a batch, linked to the submissions it anchors
+func startBatchSpan(ctx context.Context, batchID string, batch []*models.LogMessage) (context.Context, trace.Span) {
+	carriers := make([]map[string]string, len(batch))
+	for i, msg := range batch {
+		carriers[i] = msg.TraceContext
+	}
+	return tracer.Start(ctx, "engine.batch", trace.WithNewRoot(), trace.WithLinks(tracing.Links(carriers)...),
+		trace.WithAttributes(tracing.AttrBatchID.String(batchID), tracing.AttrBatchSize.Int(len(batch))))
+}
+
+// startAnchorSpans starts a span per traced message as a child of its
+// submission's span in the gateway, linked to the batch span, so the trace of
+// a request reaches the transaction that anchors it
+func startAnchorSpans(batch []*models.LogMessage, batchID string, batchSpan trace.Span) []trace.Span {
+	spans := make([]trace.Span, 0, len(batch))
+	for _, msg := range batch {
+		if len(msg.TraceContext) == 0 {
+			continue
+		}
+		_, span := tracer.Start(tracing.FromCarrier(context.Background(), msg.TraceContext), "engine.anchor",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithLinks(trace.Link{SpanContext: batchSpan.SpanContext()}),
+			trace.WithAttributes(tracing.AttrRequestID.String(msg.RequestID), tracing.AttrLogHash.String(msg.LogHash), tracing.AttrBatchID.String(batchID)))
+		spans = append(spans, span)
+	}
+	return spans
+}
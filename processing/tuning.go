@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tlng/config"
+	"tlng/storage/store"
+)
+
+// tuningSmoothing is the weight of a new observation in the latency and rate averages
+const tuningSmoothing = 0.2
+
+// batchTuner holds a worker's tuned batch timeout and size and the
+// observations they are derived from
+type batchTuner struct {
+	cfg config.BatchTuningConfig
+
+	timeout atomic.Int64 // Current batch timeout in nanoseconds
+	size    atomic.Int64 // Current batch size
+
+	mu           sync.Mutex
+	chainLatency time.Duration // Average chain submission time per batch
+	e2eLatency   time.Duration // Average gateway receive -> on chain time of anchored logs
+	arrivalRate  float64       // Average messages consumed per second by the worker
+	lastConsumed uint64        // Messages consumed at the last rate sample
+}
+
+// TuningStatus reports the observations batch tuning is based on
+type TuningStatus struct {
+	ChainLatencySeconds    float64 `json:"chain_latency_seconds"`      // Average chain submission time per batch
+	EndToEndLatencySeconds float64 `json:"end_to_end_latency_seconds"` // Average gateway receive -> on chain time
+	ArrivalRate            float64 `json:"arrival_rate"`               // Messages consumed per second
+	TargetLatencySeconds   float64 `json:"target_latency_seconds"`     // Configured end-to-end target
+}
+
+// SetBatchTuning makes the worker tune its batch timeout and size to meet the
+// end-to-end latency target, starting from the configured values
+func (w *Worker) SetBatchTuning(cfg config.BatchTuningConfig) {
+	t := &batchTuner{cfg: cfg}
+	t.timeout.Store(int64(clampDuration(w.batchTimeout, cfg.MinBatchTimeout, cfg.MaxBatchTimeout)))
+	t.size.Store(int64(clampInt(w.workerConfig.BatchSize, cfg.MinBatchSize, cfg.MaxBatchSize)))
+	w.tuner = t
+}
+
+// currentBatchTimeout is the batch timeout in effect
+func (w *Worker) currentBatchTimeout() time.Duration {
+	if w.tuner != nil {
+		return time.Duration(w.tuner.timeout.Load())
+	}
+	return w.batchTimeout
+}
+
+// currentBatchSize is the batch size in effect
+func (w *Worker) currentBatchSize() int {
+	if w.tuner != nil {
+		return int(w.tuner.size.Load())
+	}
+	return w.workerConfig.BatchSize
+}
+
+// observeBatch records the chain submission time of a batch and the end-to-end
+// latency of the logs it anchored
+func (w *Worker) observeBatch(chainLatency time.Duration, tasks map[string]*store.LogStatus, completions []store.CompletionRecord) {
+	if w.tuner == nil || len(completions) == 0 {
+		return
+	}
+	now := w.clock.Now()
+	var total time.Duration
+	var n int
+	for _, c := range completions {
+		if task, ok := tasks[c.RequestID]; ok && !task.ReceivedTimestamp.IsZero() {
+			total += now.Sub(task.ReceivedTimestamp)
+			n++
+		}
+	}
+
+	t := w.tuner
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.chainLatency = smooth(t.chainLatency, chainLatency)
+	if n > 0 {
+		t.e2eLatency = smooth(t.e2eLatency, total/time.Duration(n))
+	}
+}
+
+// tuneBatches recomputes the batch timeout and size every interval until ctx is done
+func (w *Worker) tuneBatches(ctx context.Context) {
+	t := w.tuner
+	t.lastConsumed = w.stats.messagesConsumed.Load()
+	ticker := time.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			consumed := w.stats.messagesConsumed.Load()
+			timeout, size := t.retune(consumed, w.workerConfig.Concurrency)
+			if timeout != w.currentBatchTimeout() || size != w.currentBatchSize() {
+				w.logger.Printf("Batch tuning: batch_timeout=%v, batch_size=%d", timeout, size)
+			}
+			t.timeout.Store(int64(timeout))
+			t.size.Store(int64(size))
+		}
+	}
+}
+
+// retune derives the batch timeout and size from the observations. A log can
+// wait for the batch ahead of it to be anchored, then for its own batch to
+// fill, then for its own batch to be anchored, so the timeout is what the
+// target leaves after two chain submissions; if logs still arrive on chain
+// late, it is cut in proportion. The size is what arrives at each of the
+// worker's goroutines within the timeout, so batches are flushed by size just
+// as the timeout would flush them.
+func (t *batchTuner) retune(consumed uint64, concurrency int) (time.Duration, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rate := float64(consumed-t.lastConsumed) / t.cfg.Interval.Seconds()
+	t.lastConsumed = consumed
+	if t.arrivalRate == 0 {
+		t.arrivalRate = rate
+	} else {
+		t.arrivalRate = tuningSmoothing*rate + (1-tuningSmoothing)*t.arrivalRate
+	}
+
+	timeout := t.cfg.TargetLatency - 2*t.chainLatency
+	if current := time.Duration(t.timeout.Load()); t.e2eLatency > t.cfg.TargetLatency {
+		scaled := time.Duration(float64(current) * float64(t.cfg.TargetLatency) / float64(t.e2eLatency))
+		timeout = min(timeout, scaled)
+	}
+	timeout = clampDuration(timeout, t.cfg.MinBatchTimeout, t.cfg.MaxBatchTimeout)
+
+	size := t.cfg.MaxBatchSize
+	if concurrency > 0 && t.arrivalRate > 0 {
+		perGoroutine := t.arrivalRate / float64(concurrency) * timeout.Seconds()
+		size = int(math.Min(math.Ceil(perGoroutine), float64(t.cfg.MaxBatchSize)))
+	}
+	size = clampInt(size, t.cfg.MinBatchSize, t.cfg.MaxBatchSize)
+	return timeout, size
+}
+
+// status reports the tuner's observations
+func (t *batchTuner) status() *TuningStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &TuningStatus{
+		ChainLatencySeconds:    t.chainLatency.Seconds(),
+		EndToEndLatencySeconds: t.e2eLatency.Seconds(),
+		ArrivalRate:            t.arrivalRate,
+		TargetLatencySeconds:   t.cfg.TargetLatency.Seconds(),
+	}
+}
+
+// smooth folds an observation into a moving average; the first one seeds it
+func smooth(avg, v time.Duration) time.Duration {
+	if avg == 0 {
+		return v
+	}
+	return time.Duration(tuningSmoothing*float64(v) + (1-tuningSmoothing)*float64(avg))
+}
+
+func clampDuration(v, lo, hi time.Duration) time.Duration {
+	return max(lo, min(v, hi))
+}
+
+func clampInt(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
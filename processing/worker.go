@@ -7,14 +7,20 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	// Import necessary packages
 	blockchain "tlng/blockchain/client"
 	"tlng/blockchain/types"
 	"tlng/config"
+	"tlng/internal/clock"
+	"tlng/internal/events"
+	"tlng/internal/idgen"
 	"tlng/internal/messaging/consumer"
+	"tlng/internal/messaging/producer"
 	"tlng/internal/models"
+	"tlng/internal/tracing"
 	"tlng/storage/store"
 )
 
@@ -30,6 +36,45 @@ type Worker struct {
 	store            store.Store
 	consumer         consumer.Consumer
 	blockchainClient blockchain.BlockchainClient // Interface for blockchain client
+
+	stats workerStats // Processing counters exposed via Stats()
+
+	// Cross-region reconciliation (active-active deployments)
+	region     string                 // Local region name
+	peerStores map[string]store.Store // Peer region name -> that region's State DB
+
+	eventBus *events.Bus // Optional; receives status transition events
+
+	// Per-org routing (see SetRoutes)
+	routeClients map[string]blockchain.BlockchainClient // Target name -> client
+	orgTargets   map[string]string                      // Org ID -> target name
+
+	proofCache atomic.Bool // Cache attestation proofs of anchored logs (see SetProofCache)
+
+	merkleOrgID string // Non-empty in Merkle-root anchoring mode (see SetMerkleAnchoring)
+
+	track string // Deployment track label of engine_track_tasks_total (see SetTrack)
+
+	batchIDs idgen.Generator // Time-ordered engine batch IDs (see SetBatchIDGenerator)
+	clock    clock.Clock     // Times batches (see SetClock)
+
+	instanceID string // Engine instance recorded on locked tasks (see SetInstanceID)
+
+	parkTopic string      // Topic recorded on parked messages (see SetParking)
+	parking   atomic.Bool // Park redelivered messages of PROCESSING tasks
+
+	tuner *batchTuner // Optional; tunes the batch timeout and size (see SetBatchTuning)
+
+	readOnly *ReadOnlyMode // Optional; defers anchoring while the State DB is read-only (see SetReadOnlyMode)
+
+	errorPolicy *errorPolicy // Optional; retry or fail by error kind (see SetErrorHandling)
+
+	dlq *producer.DLQProducer // Optional; receives the messages of failed tasks (see SetDeadLetterQueue)
+
+	priorityLanes *config.PriorityLanesConfig // Optional; orders batches by message priority (see SetPriorityLanes)
+
+	replayGuard *config.ReplayGuardConfig // Optional; acks redelivered messages of terminal tasks (see SetReplayGuard)
+	replayUntil time.Time                 // End of the replay guard, set by Run
 }
 
 // New creates a new Worker instance
@@ -58,6 +103,8 @@ func New(cfg config.WorkerConfig, maxTaskRetries int, logger *log.Logger, s stor
 		blockchainTimeout = 15 * time.Second
 	}
 
+	batchIDs, _ := idgen.NewGenerator(idgen.StrategyUUIDv7) // Known strategy, cannot fail
+
 	return &Worker{
 		workerConfig:         cfg,
 		batchTimeout:         batchTimeout,
@@ -68,20 +115,54 @@ func New(cfg config.WorkerConfig, maxTaskRetries int, logger *log.Logger, s stor
 		store:                s,
 		consumer:             c,
 		blockchainClient:     bc,
+		batchIDs:             batchIDs,
+		clock:                clock.Real(),
+		track:                config.TrackStable,
 	}
 }
 
-// Run starts the worker pool
-func (w *Worker) Run(ctx context.Context) {
-	w.logger.Printf("Starting worker pool with concurrency: %d, BatchSize: %d, BatchTimeout: %s",
-		w.workerConfig.Concurrency, w.workerConfig.BatchSize, w.batchTimeout)
+// SetTrack sets the deployment track the worker's task outcomes are counted
+// under, so a canary engine can be compared with the stable ones
+func (w *Worker) SetTrack(track string) {
+	w.track = track
+}
+
+// SetClock replaces the system clock that times batches, e.g. with a fake
+// clock in tests. Call it before Run.
+func (w *Worker) SetClock(c clock.Clock) {
+	w.clock = c
+}
+
+// SetBatchIDGenerator replaces the UUIDv7 generator of engine batch IDs, e.g.
+// with a reproducible sequence in tests
+func (w *Worker) SetBatchIDGenerator(g idgen.Generator) {
+	w.batchIDs = g
+}
+
+// Run starts the worker pool. Cancelling ctx stops consumption; batches already
+// consumed are still processed until drainCtx is done, after which they are
+// nacked and counted as abandoned.
+func (w *Worker) Run(ctx, drainCtx context.Context) {
+	w.logger.Printf("Starting worker pool with concurrency: %d, BatchSize: %d, BatchTimeout: %s, PreBatch: %t",
+		w.workerConfig.Concurrency, w.workerConfig.BatchSize, w.batchTimeout, w.workerConfig.PreBatch)
+	if w.tuner != nil {
+		go w.tuneBatches(ctx)
+	}
+	if w.replayGuard != nil {
+		w.replayUntil = w.clock.Now().Add(w.replayGuard.Duration)
+		w.logger.Printf("Replay guard: Acking messages of COMPLETED and FAILED tasks until %s", w.replayUntil.Format(time.RFC3339))
+	}
 	var wg sync.WaitGroup
 	for i := 0; i < w.workerConfig.Concurrency; i++ {
 		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
 			w.logger.Printf("Worker %d started", workerID)
-			w.processMessagesInBatch(ctx, workerID) // Call the batch processing loop
+			if w.priorityLanes != nil {
+				w.processMessagesByPriority(ctx, drainCtx, workerID)
+			} else {
+				w.processMessagesInBatch(ctx, drainCtx, workerID) // Call the batch processing loop
+			}
 			w.logger.Printf("Worker %d stopped", workerID)
 		}(i + 1)
 	}
@@ -90,17 +171,31 @@ func (w *Worker) Run(ctx context.Context) {
 }
 
 // processMessagesInBatch is the main loop for a worker goroutine
-func (w *Worker) processMessagesInBatch(ctx context.Context, workerID int) {
-	batchMessages := make([]*models.LogMessage, 0, w.workerConfig.BatchSize)
-	kafkaAcks := make([]func(success bool), 0, w.workerConfig.BatchSize)
-	batchTimer := time.NewTimer(0) // Start with stopped timer
+func (w *Worker) processMessagesInBatch(ctx, drainCtx context.Context, workerID int) {
+	batchMessages := make([]*models.LogMessage, 0, w.currentBatchSize())
+	kafkaAcks := make([]func(success bool), 0, w.currentBatchSize())
+	replay := w.newReplayWindow(ctx)
+	defer replay.release()
+	batchTimer := w.clock.NewTimer(0) // Start with stopped timer
 	if !batchTimer.Stop() {
 		select {
-		case <-batchTimer.C:
+		case <-batchTimer.C():
 		default:
 		}
 	}
 
+	// With pre-batching the submitted batch is processed in the background while
+	// the next one is assembled (double buffering). At most one batch is in
+	// flight, so batches are still acked in order.
+	var inFlight chan struct{} // Closed when the in-flight batch is acked; nil if none
+	waitInFlight := func() {
+		if inFlight != nil {
+			<-inFlight
+			inFlight = nil
+		}
+	}
+	defer waitInFlight()
+
 	// Helper function to submit batch
 	processBatch := func() {
 		if len(batchMessages) == 0 {
@@ -110,37 +205,57 @@ func (w *Worker) processMessagesInBatch(ctx context.Context, workerID int) {
 		// Stop and drain timer
 		if !batchTimer.Stop() {
 			select {
-			case <-batchTimer.C:
+			case <-batchTimer.C():
 			default:
 			}
 		}
 
 		// Execute batch processing
-		w.processAndAckBatch(ctx, workerID, batchMessages, kafkaAcks)
+		if w.workerConfig.PreBatch {
+			waitInFlight()
+			w.stats.pendingMessages.Add(-int64(len(batchMessages)))
+			done := make(chan struct{})
+			go func(batch []*models.LogMessage, acks []func(success bool)) {
+				defer close(done)
+				w.processAndAckBatch(ctx, drainCtx, workerID, batch, acks)
+			}(batchMessages, kafkaAcks)
+			inFlight = done
+		} else {
+			w.stats.pendingMessages.Add(-int64(len(batchMessages)))
+			w.processAndAckBatch(ctx, drainCtx, workerID, batchMessages, kafkaAcks)
+		}
 
 		// Reset for next batch
-		batchMessages = make([]*models.LogMessage, 0, w.workerConfig.BatchSize)
-		kafkaAcks = make([]func(success bool), 0, w.workerConfig.BatchSize)
+		batchMessages = make([]*models.LogMessage, 0, w.currentBatchSize())
+		kafkaAcks = make([]func(success bool), 0, w.currentBatchSize())
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			w.logger.Printf("Worker %d: Context cancelled, stopping.", workerID)
+			// Flush the partial batch while the drain budget lasts, otherwise leave it for redelivery
+			if len(batchMessages) > 0 && drainCtx.Err() == nil {
+				w.logger.Printf("Worker %d: Flushing %d buffered messages before stopping.", workerID, len(batchMessages))
+				processBatch()
+				return
+			}
 			if len(kafkaAcks) > 0 {
 				for _, ack := range kafkaAcks {
 					ack(false)
 				}
 			}
+			w.stats.pendingMessages.Add(-int64(len(batchMessages)))
+			w.stats.messagesAbandoned.Add(uint64(len(batchMessages)))
 			return
 
-		case <-batchTimer.C:
+		case <-batchTimer.C():
 			// Batch timeout reached
 			processBatch()
 
 		default:
 			consumeCtx, consumeCancel := context.WithTimeout(ctx, 100*time.Millisecond)
-			msg, ack, err := w.consumer.Consume(consumeCtx)
+			msg, ack, err := replay.consume(consumeCtx)
 			consumeCancel()
 
 			if err != nil {
@@ -148,6 +263,7 @@ func (w *Worker) processMessagesInBatch(ctx context.Context, workerID int) {
 					continue
 				}
 				// Only log real consumer errors
+				w.stats.consumerErrors.Add(1)
 				w.logger.Printf("Worker %d: Consumer error: %v", workerID, err)
 				time.Sleep(w.consumerRetryDelay)
 				continue
@@ -155,16 +271,19 @@ func (w *Worker) processMessagesInBatch(ctx context.Context, workerID int) {
 
 			// Successfully got message
 			if msg != nil {
+				w.stats.messagesConsumed.Add(1)
+
 				// Start batch timer on first message
 				if len(batchMessages) == 0 {
-					batchTimer.Reset(w.batchTimeout)
+					batchTimer.Reset(w.currentBatchTimeout())
 				}
 
 				batchMessages = append(batchMessages, msg)
 				kafkaAcks = append(kafkaAcks, ack)
+				w.stats.pendingMessages.Add(1)
 
 				// Process immediately if batch is full
-				if len(batchMessages) >= w.workerConfig.BatchSize {
+				if len(batchMessages) >= w.currentBatchSize() {
 					processBatch()
 				}
 			}
@@ -173,16 +292,35 @@ func (w *Worker) processMessagesInBatch(ctx context.Context, workerID int) {
 }
 
 // processAndAckBatch handles processing and Kafka acknowledgement
-func (w *Worker) processAndAckBatch(ctx context.Context, workerID int, batch []*models.LogMessage, acks []func(success bool)) {
-	processingErr := w.handleBatch(ctx, batch) // Process the actual batch
+func (w *Worker) processAndAckBatch(ctx, drainCtx context.Context, workerID int, batch []*models.LogMessage, acks []func(success bool)) {
+	w.stats.inFlightBatch.Add(int64(len(batch)))
+	anchoredBatchSize.Observe(float64(len(batch)))
+	batchID := w.batchIDs.NewID()
+	w.stats.lastBatchID.Store(&batchID)
+	batchCtx, span := startBatchSpan(drainCtx, batchID, batch)
+	anchorSpans := startAnchorSpans(batch, batchID, span)
+	processingErr := w.handleBatch(batchCtx, batchID, batch) // Process the actual batch; not cut short by a shutdown signal
+	for _, s := range anchorSpans {
+		tracing.End(s, processingErr)
+	}
+	tracing.End(span, processingErr)
+	w.stats.inFlightBatch.Add(-int64(len(batch)))
+	w.stats.lastBatchAt.Store(w.clock.Now().UnixNano())
 
 	if processingErr != nil {
+		w.stats.batchesFailed.Add(1)
+		if ctx.Err() != nil {
+			w.stats.messagesAbandoned.Add(uint64(len(batch)))
+		}
 		// Transaction FAILED -> Nack ALL messages
-		w.logger.Printf("Worker %d: Batch failed: %v (nacking %d messages)", workerID, processingErr, len(acks))
+		w.logger.Printf("Worker %d: Batch %s failed: %v (nacking %d messages)", workerID, batchID, processingErr, len(acks))
 		for _, ack := range acks {
 			ack(false)
 		}
+		// Back off while the chain keeps failing, rather than refetching the batch at once
+		w.errorPolicy.backoff(ctx, w.stats.bcFailureStreak.Load())
 	} else {
+		w.stats.batchesProcessed.Add(1)
 		// Transaction SUCCEEDED -> Ack ALL messages
 		for _, ack := range acks {
 			ack(true)
@@ -190,11 +328,30 @@ func (w *Worker) processAndAckBatch(ctx context.Context, workerID int, batch []*
 	}
 }
 
-func (w *Worker) handleBatch(ctx context.Context, batch []*models.LogMessage) error {
+// handleBatch anchors a batch, or spools it as an intent while the State DB is read-only
+func (w *Worker) handleBatch(ctx context.Context, batchID string, batch []*models.LogMessage) error {
+	if w.readOnly != nil {
+		if deferred, err := w.readOnly.deferBatch(batchID, batch); deferred {
+			return err
+		}
+	}
+	err := w.anchorBatch(ctx, batchID, batch)
+	if w.readOnly != nil && store.IsReadOnly(err) {
+		// No task was locked yet, so the whole batch can be deferred
+		w.readOnly.enter(err)
+		if deferred, spoolErr := w.readOnly.deferBatch(batchID, batch); deferred {
+			return spoolErr
+		}
+	}
+	return err
+}
+
+// anchorBatch locks the batch's tasks, anchors them and records the results
+func (w *Worker) anchorBatch(ctx context.Context, batchID string, batch []*models.LogMessage) error {
 	if len(batch) == 0 {
 		return nil
 	}
-	batchStart := time.Now()
+	batchStart := w.clock.Now()
 
 	requestIDs := make([]string, 0, len(batch))
 	msgMap := make(map[string]*models.LogMessage, len(batch)) // request_id -> message
@@ -211,126 +368,126 @@ func (w *Worker) handleBatch(ctx context.Context, batch []*models.LogMessage) er
 	// --- 1. Pre-process database status ---
 	validTasks := make(map[string]*store.LogStatus) // request_id -> task
 
-	dbStart := time.Now()
-	tasksFromDB, err := w.store.GetAndMarkBatchAsProcessing(ctx, requestIDs, w.maxTaskRetries)
-	dbQueryDuration := time.Since(dbStart)
+	dbStart := w.clock.Now()
+	var tasksFromDB map[string]*store.LogStatus
+	err := retryOnConflict(func() (err error) {
+		tasksFromDB, err = w.store.GetAndMarkBatchAsProcessing(ctx, requestIDs, w.maxTaskRetries, batchID, w.instanceID)
+		return err
+	})
+	dbQueryDuration := clock.Since(w.clock, dbStart)
 
 	if err != nil {
-		return fmt.Errorf("DB error: GetAndMarkBatchAsProcessing failed: %v", err)
+		return fmt.Errorf("DB error: GetAndMarkBatchAsProcessing failed: %w", err)
 	}
 
-	validEntries := make([]types.LogEntry, 0, len(tasksFromDB))
-
+	var transitions []events.StatusEvent
+	var exhausted []store.FailureRecord
 	for reqID, task := range tasksFromDB {
+		transition := w.transition(task, store.StatusReceived, task.Status)
 		switch task.Status {
 		case store.StatusProcessing:
-			msg := msgMap[reqID]     // Get corresponding original message
 			validTasks[reqID] = task // Add to processing list
-			validEntries = append(validEntries, types.LogEntry{
-				LogHash:     msg.LogHash,
-				LogContent:  msg.LogContent,
-				SenderOrgID: msg.SourceOrgID,
-				Timestamp:   msg.ReceivedTimestamp,
-			})
 		case store.StatusFailed:
-			// Tasks with max retries exceeded are already marked as FAILED by the database
-			// No further action needed - they will be acknowledged and dropped from processing
+			// Tasks with max retries exceeded are already marked as FAILED by the database;
+			// they are acknowledged and dropped from processing
+			exhausted = append(exhausted, store.FailureRecord{RequestID: reqID, ErrorMessage: *task.ErrorMessage})
+			transition.Error = *task.ErrorMessage
+		}
+		if w.eventBus != nil {
+			transitions = append(transitions, transition)
 		}
 	}
-
-	// If no valid tasks to submit
-	if len(validEntries) == 0 {
-		return nil // Ack Kafka messages
+	w.eventBus.Publish(transitions)
+	w.park(ctx, msgMap, tasksFromDB)
+	if len(exhausted) > 0 {
+		w.stats.tasksFailed.Add(uint64(len(exhausted)))
+		trackTasks.Add(float64(len(exhausted)), w.track, "failed")
+		w.deadLetter(ctx, tasksFromDB, msgMap, exhausted)
 	}
 
-	// --- 2. Call blockchain client ---
-	invokeCtx, cancel := context.WithTimeout(ctx, w.blockchainTimeout)
-	defer cancel()
-	bcStart := time.Now()
-	batchProof, results, err := w.blockchainClient.SubmitLogsBatch(invokeCtx, validEntries)
-	bcDuration := time.Since(bcStart)
-
-	// Helper function to extract keys from map
-	getValidRequestIDs := func(tasks map[string]*store.LogStatus) []string {
-		ids := make([]string, 0, len(tasks))
-		for reqID := range tasks {
-			ids = append(ids, reqID)
-		}
-		return ids
+	// Skip logs a peer region has already anchored or is anchoring, so each log
+	// lands on chain exactly once globally; deferred tasks are nacked
+	var deferErr error
+	if len(w.peerStores) > 0 {
+		deferErr = w.reconcileWithPeers(ctx, validTasks, msgMap)
 	}
 
-	// --- 3. Process results ---
-	if err != nil { // Transaction failed
-		w.logger.Printf("Blockchain error: %v", err)
-		if markErr := w.store.MarkBatchForRetry(ctx, getValidRequestIDs(validTasks), err.Error()); markErr != nil {
-			w.logger.Printf("CRITICAL: MarkBatchForRetry failed: %v", markErr)
-		}
-		return fmt.Errorf("SubmitLogsBatch failed: %w", err) // Trigger Nack
+	// Group the tasks by routing target; each target is anchored in its own transaction
+	groups := w.routeBatch(batchID, validTasks, msgMap)
+	validEntries := make([]types.LogEntry, 0, len(validTasks))
+	for _, g := range groups {
+		validEntries = append(validEntries, g.entries...)
 	}
-	resultsMap := make(map[string]types.LogStatusInfo, len(results))
-	for _, res := range results {
-		resultsMap[res.LogHash] = res
+
+	// If no valid tasks to submit
+	if len(validEntries) == 0 {
+		return deferErr // Ack Kafka messages, unless tasks were deferred to a peer region
 	}
 
+	// --- 2. Call blockchain clients ---
+	bcDuration := w.submitGroups(ctx, groups)
+
+	// --- 3. Process results ---
 	// Collect completion and failure records for batch updates
 	var completions []store.CompletionRecord
 	var failures []store.FailureRecord
-
-	for reqID, task := range validTasks {
-		statusInfo, found := resultsMap[task.LogHash]
-		if !found {
-			errMsg := fmt.Sprintf("Missing result for log_hash %s (TxID: %s)", task.LogHash, batchProof.TransactionID)
-			failures = append(failures, store.FailureRecord{
-				RequestID:    reqID,
-				ErrorMessage: errMsg,
-			})
+	var submitErrs []error
+	for _, g := range groups {
+		if g.err != nil {
+			submitErrs = append(submitErrs, g.err)
 			continue
 		}
-
-		switch statusInfo.Status {
-		case types.StatusSuccess:
-			completions = append(completions, store.CompletionRecord{
-				RequestID:      reqID,
-				TxHash:         batchProof.TransactionID,
-				LogHashOnChain: statusInfo.LogHash,
-				BlockHeight:    batchProof.BlockHeight,
-			})
-		default:
-			errMsg := fmt.Sprintf("Contract failed: %s - %s", statusInfo.Status, statusInfo.Message)
-			failures = append(failures, store.FailureRecord{
-				RequestID:    reqID,
-				ErrorMessage: errMsg,
-			})
-		}
+		completions = append(completions, g.completions...)
+		failures = append(failures, g.failures...)
+	}
+	if len(submitErrs) == len(groups) {
+		return fmt.Errorf("SubmitLogsBatch failed: %w", errors.Join(submitErrs...)) // Trigger Nack
 	}
 
 	// Execute batch updates sequentially (now optimized with true bulk operations)
-	dbUpdateStart := time.Now()
+	dbUpdateStart := w.clock.Now()
 	var updateErrors []string
 
 	// Sequential execution since both operations are now true bulk operations
 	if len(completions) > 0 {
-		if err := w.store.MarkBatchAsCompleted(ctx, completions); err != nil {
+		if err := retryOnConflict(func() error { return w.store.MarkBatchAsCompleted(ctx, completions) }); err != nil {
 			updateErrors = append(updateErrors, fmt.Sprintf("completion update failed: %v", err))
+		} else {
+			w.stats.tasksCompleted.Add(uint64(len(completions)))
+			trackTasks.Add(float64(len(completions)), w.track, "completed")
+			w.observeBatch(bcDuration, validTasks, completions)
+			w.publishCompletions(validTasks, completions)
+			w.cacheProofs(ctx, anchoredProofs(validEntries, completions))
 		}
 	}
 
 	if len(failures) > 0 {
-		if err := w.store.MarkBatchAsFailed(ctx, failures); err != nil {
+		if err := retryOnConflict(func() error { return w.store.MarkBatchAsFailed(ctx, failures) }); err != nil {
 			updateErrors = append(updateErrors, fmt.Sprintf("failure update failed: %v", err))
+		} else {
+			w.stats.tasksFailed.Add(uint64(len(failures)))
+			trackTasks.Add(float64(len(failures)), w.track, "failed")
+			w.publishFailures(validTasks, failures)
+			w.deadLetter(ctx, validTasks, msgMap, failures)
 		}
 	}
 
-	dbUpdateDuration := time.Since(dbUpdateStart)
+	dbUpdateDuration := clock.Since(w.clock, dbUpdateStart)
 
 	// Log key performance metrics only
-	totalTime := time.Since(batchStart)
-	w.logger.Printf("Batch performance: size=%d, valid=%d, completions=%d, failures=%d, db_query=%v, db_updates=%v, blockchain=%v, total=%v",
-		len(batch), len(validTasks), len(completions), len(failures), dbQueryDuration, dbUpdateDuration, bcDuration, totalTime)
+	totalTime := clock.Since(w.clock, batchStart)
+	w.logger.Printf("Batch performance: batch_id=%s, size=%d, valid=%d, completions=%d, failures=%d, db_query=%v, db_updates=%v, blockchain=%v, total=%v",
+		batchID, len(batch), len(validTasks), len(completions), len(failures), dbQueryDuration, dbUpdateDuration, bcDuration, totalTime)
 
 	if len(updateErrors) > 0 {
 		w.logger.Printf("DB update errors: %s", strings.Join(updateErrors, "; "))
 	}
 
-	return nil // Transaction succeeded, Ack Kafka messages
+	// Some targets failed: nack so their tasks are redelivered. Tasks anchored
+	// through the other targets are COMPLETED and skipped on redelivery.
+	if len(submitErrs) > 0 {
+		return fmt.Errorf("SubmitLogsBatch failed for %d of %d routing targets: %w", len(submitErrs), len(groups), errors.Join(submitErrs...))
+	}
+
+	return deferErr // Transaction succeeded, Ack Kafka messages unless tasks were deferred to a peer region
 }
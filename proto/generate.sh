@@ -26,7 +26,7 @@ if ! command -v protoc-gen-go-grpc &> /dev/null; then
 fi
 
 # Create output directory
-mkdir -p proto/logingestion
+mkdir -p proto/logingestion proto/engineadmin proto/internalpb
 
 # Generate Go code
 echo "📝 Generating Go code from logingestion.proto..."
@@ -34,10 +34,22 @@ protoc --go_out=proto --go_opt=paths=import,module=tlng/proto \
        --go-grpc_out=proto --go-grpc_opt=paths=import,module=tlng/proto \
        proto/logingestion.proto
 
+echo "📝 Generating Go code from engineadmin.proto..."
+protoc --go_out=proto --go_opt=paths=import,module=tlng/proto \
+       --go-grpc_out=proto --go-grpc_opt=paths=import,module=tlng/proto \
+       proto/engineadmin.proto
+
+echo "📝 Generating Go code from internal/messages.proto..."
+protoc --go_out=proto --go_opt=paths=import,module=tlng/proto \
+       proto/internal/messages.proto
+
 echo "✅ Proto generation completed successfully!"
 echo "📁 Generated files:"
 echo "   - proto/logingestion/logingestion.pb.go"
 echo "   - proto/logingestion/logingestion_grpc.pb.go"
+echo "   - proto/engineadmin/engineadmin.pb.go"
+echo "   - proto/engineadmin/engineadmin_grpc.pb.go"
+echo "   - proto/internalpb/messages.pb.go"
 
 # Show generated files
-ls -la proto/logingestion/
\ No newline at end of file
+ls -la proto/logingestion/ proto/engineadmin/ proto/internalpb/
\ No newline at end of file
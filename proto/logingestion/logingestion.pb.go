@@ -34,8 +34,29 @@ type SubmitLogRequest struct {
 	ClientSourceOrgId string `protobuf:"bytes,3,opt,name=client_source_org_id,json=clientSourceOrgId,proto3" json:"client_source_org_id,omitempty"`
 	// (Optional) Client-specified original timestamp
 	ClientTimestamp *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=client_timestamp,json=clientTimestamp,proto3" json:"client_timestamp,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// (Optional) Client-generated key identifying this submission across retries.
+	// Submissions with the same key, source organization and content receive the
+	// same request_id and are attested only once.
+	IdempotencyKey string `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// (Optional) Log severity: DEBUG, INFO, NOTICE, WARNING, ERROR, CRITICAL,
+	// ALERT or EMERGENCY (case-insensitive; warn, err, crit and emerg accepted).
+	// Stored for search and statistics; not part of the attested content.
+	Severity string `protobuf:"bytes,6,opt,name=severity,proto3" json:"severity,omitempty"`
+	// (Optional) Host that produced the log, at most 255 characters
+	SourceHost string `protobuf:"bytes,7,opt,name=source_host,json=sourceHost,proto3" json:"source_host,omitempty"`
+	// (Optional) Application that produced the log, at most 128 characters
+	Application string `protobuf:"bytes,8,opt,name=application,proto3" json:"application,omitempty"`
+	// (Optional) Position of the log in its source's sequence, starting at 1.
+	// A source is the organization, source_host and application; the gateway
+	// reports numbers skipped within a source as gaps. 0 means unsequenced.
+	Sequence int64 `protobuf:"varint,9,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	// (Optional) Client's own ID of the log, unique per source organization, at
+	// most 255 printable ASCII characters. Re-sending an external_id returns the
+	// submission stored under it with its current status instead of a new one;
+	// re-sending it with different content is rejected with ALREADY_EXISTS.
+	ExternalId    string `protobuf:"bytes,10,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SubmitLogRequest) Reset() {
@@ -96,6 +117,48 @@ func (x *SubmitLogRequest) GetClientTimestamp() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *SubmitLogRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *SubmitLogRequest) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *SubmitLogRequest) GetSourceHost() string {
+	if x != nil {
+		return x.SourceHost
+	}
+	return ""
+}
+
+func (x *SubmitLogRequest) GetApplication() string {
+	if x != nil {
+		return x.Application
+	}
+	return ""
+}
+
+func (x *SubmitLogRequest) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *SubmitLogRequest) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
 // Response message for log submission
 type SubmitLogResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -106,7 +169,21 @@ type SubmitLogResponse struct {
 	// Server-recorded received timestamp
 	ServerReceivedTimestamp *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=server_received_timestamp,json=serverReceivedTimestamp,proto3" json:"server_received_timestamp,omitempty"`
 	// (Optional) Status information, e.g., "ACCEPTED"
-	Status        string `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Status string `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	// Integrity metadata, set if the gateway runs with integrity_metadata.
+	// Algorithm of server_log_hash, e.g. "sha256"
+	HashAlgorithm string `protobuf:"bytes,5,opt,name=hash_algorithm,json=hashAlgorithm,proto3" json:"hash_algorithm,omitempty"`
+	// Profile the log content is brought to before hashing; "raw" hashes the
+	// log_content bytes exactly as submitted
+	Canonicalization string `protobuf:"bytes,6,opt,name=canonicalization,proto3" json:"canonicalization,omitempty"`
+	// Version of the gateway that accepted the submission
+	ServiceVersion string `protobuf:"bytes,7,opt,name=service_version,json=serviceVersion,proto3" json:"service_version,omitempty"`
+	// State DB schema version the submission is recorded under
+	SchemaVersion int32 `protobuf:"varint,8,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	// Anchoring transaction and block of a submission already stored under the
+	// request's external_id, set once it is COMPLETED
+	TxHash        string `protobuf:"bytes,9,opt,name=tx_hash,json=txHash,proto3" json:"tx_hash,omitempty"`
+	BlockHeight   uint64 `protobuf:"varint,10,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -169,25 +246,307 @@ func (x *SubmitLogResponse) GetStatus() string {
 	return ""
 }
 
+func (x *SubmitLogResponse) GetHashAlgorithm() string {
+	if x != nil {
+		return x.HashAlgorithm
+	}
+	return ""
+}
+
+func (x *SubmitLogResponse) GetCanonicalization() string {
+	if x != nil {
+		return x.Canonicalization
+	}
+	return ""
+}
+
+func (x *SubmitLogResponse) GetServiceVersion() string {
+	if x != nil {
+		return x.ServiceVersion
+	}
+	return ""
+}
+
+func (x *SubmitLogResponse) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *SubmitLogResponse) GetTxHash() string {
+	if x != nil {
+		return x.TxHash
+	}
+	return ""
+}
+
+func (x *SubmitLogResponse) GetBlockHeight() uint64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+// Response message for a stream of log submissions
+type SubmitLogStreamResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of entries accepted
+	Accepted int32 `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	// Number of entries rejected
+	Rejected int32 `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	// Outcome of each entry read, in stream order
+	Results       []*SubmitLogStreamResult `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitLogStreamResponse) Reset() {
+	*x = SubmitLogStreamResponse{}
+	mi := &file_proto_logingestion_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitLogStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitLogStreamResponse) ProtoMessage() {}
+
+func (x *SubmitLogStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_logingestion_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitLogStreamResponse.ProtoReflect.Descriptor instead.
+func (*SubmitLogStreamResponse) Descriptor() ([]byte, []int) {
+	return file_proto_logingestion_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubmitLogStreamResponse) GetAccepted() int32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+func (x *SubmitLogStreamResponse) GetRejected() int32 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+func (x *SubmitLogStreamResponse) GetResults() []*SubmitLogStreamResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+// Outcome of one entry of a SubmitLogStream call
+type SubmitLogStreamResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Position of the entry in the stream, from 0
+	Index int32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// "ACCEPTED" or "REJECTED"
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// Server-generated request ID, if accepted
+	RequestId string `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Server-computed or validated log hash, if accepted
+	ServerLogHash string `protobuf:"bytes,4,opt,name=server_log_hash,json=serverLogHash,proto3" json:"server_log_hash,omitempty"`
+	// Server-recorded received timestamp, if accepted
+	ServerReceivedTimestamp *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=server_received_timestamp,json=serverReceivedTimestamp,proto3" json:"server_received_timestamp,omitempty"`
+	// True if the entry was a retry of a submission accepted within the
+	// duplicate window, and was not enqueued again
+	Duplicate bool `protobuf:"varint,6,opt,name=duplicate,proto3" json:"duplicate,omitempty"`
+	// gRPC status code a SubmitLog call would have failed with, if rejected
+	Code int32 `protobuf:"varint,7,opt,name=code,proto3" json:"code,omitempty"`
+	// Reason of the rejection
+	Error string `protobuf:"bytes,8,opt,name=error,proto3" json:"error,omitempty"`
+	// Milliseconds after which a rejected entry may be retried, 0 if not applicable
+	RetryAfterMs int64 `protobuf:"varint,9,opt,name=retry_after_ms,json=retryAfterMs,proto3" json:"retry_after_ms,omitempty"`
+	// Error catalog code of the rejection (see package errcatalog)
+	ErrorCode string `protobuf:"bytes,10,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	// Parameters of the catalog message of the rejection
+	ErrorParams   map[string]string `protobuf:"bytes,11,rep,name=error_params,json=errorParams,proto3" json:"error_params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitLogStreamResult) Reset() {
+	*x = SubmitLogStreamResult{}
+	mi := &file_proto_logingestion_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitLogStreamResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitLogStreamResult) ProtoMessage() {}
+
+func (x *SubmitLogStreamResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_logingestion_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitLogStreamResult.ProtoReflect.Descriptor instead.
+func (*SubmitLogStreamResult) Descriptor() ([]byte, []int) {
+	return file_proto_logingestion_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SubmitLogStreamResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *SubmitLogStreamResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SubmitLogStreamResult) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *SubmitLogStreamResult) GetServerLogHash() string {
+	if x != nil {
+		return x.ServerLogHash
+	}
+	return ""
+}
+
+func (x *SubmitLogStreamResult) GetServerReceivedTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ServerReceivedTimestamp
+	}
+	return nil
+}
+
+func (x *SubmitLogStreamResult) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+func (x *SubmitLogStreamResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SubmitLogStreamResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SubmitLogStreamResult) GetRetryAfterMs() int64 {
+	if x != nil {
+		return x.RetryAfterMs
+	}
+	return 0
+}
+
+func (x *SubmitLogStreamResult) GetErrorCode() string {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ""
+}
+
+func (x *SubmitLogStreamResult) GetErrorParams() map[string]string {
+	if x != nil {
+		return x.ErrorParams
+	}
+	return nil
+}
+
 var File_proto_logingestion_proto protoreflect.FileDescriptor
 
 const file_proto_logingestion_proto_rawDesc = "" +
 	"\n" +
-	"\x18proto/logingestion.proto\x12\flogingestion\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd3\x01\n" +
+	"\x18proto/logingestion.proto\x12\flogingestion\x1a\x1fgoogle/protobuf/timestamp.proto\"\x98\x03\n" +
 	"\x10SubmitLogRequest\x12\x1f\n" +
 	"\vlog_content\x18\x01 \x01(\tR\n" +
 	"logContent\x12&\n" +
 	"\x0fclient_log_hash\x18\x02 \x01(\tR\rclientLogHash\x12/\n" +
 	"\x14client_source_org_id\x18\x03 \x01(\tR\x11clientSourceOrgId\x12E\n" +
-	"\x10client_timestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0fclientTimestamp\"\xca\x01\n" +
+	"\x10client_timestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0fclientTimestamp\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\x12\x1a\n" +
+	"\bseverity\x18\x06 \x01(\tR\bseverity\x12\x1f\n" +
+	"\vsource_host\x18\a \x01(\tR\n" +
+	"sourceHost\x12 \n" +
+	"\vapplication\x18\b \x01(\tR\vapplication\x12\x1a\n" +
+	"\bsequence\x18\t \x01(\x03R\bsequence\x12\x1f\n" +
+	"\vexternal_id\x18\n" +
+	" \x01(\tR\n" +
+	"externalId\"\xa9\x03\n" +
 	"\x11SubmitLogResponse\x12\x1d\n" +
 	"\n" +
 	"request_id\x18\x01 \x01(\tR\trequestId\x12&\n" +
 	"\x0fserver_log_hash\x18\x02 \x01(\tR\rserverLogHash\x12V\n" +
 	"\x19server_received_timestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x17serverReceivedTimestamp\x12\x16\n" +
-	"\x06status\x18\x04 \x01(\tR\x06status2\\\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12%\n" +
+	"\x0ehash_algorithm\x18\x05 \x01(\tR\rhashAlgorithm\x12*\n" +
+	"\x10canonicalization\x18\x06 \x01(\tR\x10canonicalization\x12'\n" +
+	"\x0fservice_version\x18\a \x01(\tR\x0eserviceVersion\x12%\n" +
+	"\x0eschema_version\x18\b \x01(\x05R\rschemaVersion\x12\x17\n" +
+	"\atx_hash\x18\t \x01(\tR\x06txHash\x12!\n" +
+	"\fblock_height\x18\n" +
+	" \x01(\x04R\vblockHeight\"\x90\x01\n" +
+	"\x17SubmitLogStreamResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x05R\baccepted\x12\x1a\n" +
+	"\brejected\x18\x02 \x01(\x05R\brejected\x12=\n" +
+	"\aresults\x18\x03 \x03(\v2#.logingestion.SubmitLogStreamResultR\aresults\"\x8a\x04\n" +
+	"\x15SubmitLogStreamResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\x12&\n" +
+	"\x0fserver_log_hash\x18\x04 \x01(\tR\rserverLogHash\x12V\n" +
+	"\x19server_received_timestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x17serverReceivedTimestamp\x12\x1c\n" +
+	"\tduplicate\x18\x06 \x01(\bR\tduplicate\x12\x12\n" +
+	"\x04code\x18\a \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\b \x01(\tR\x05error\x12$\n" +
+	"\x0eretry_after_ms\x18\t \x01(\x03R\fretryAfterMs\x12\x1d\n" +
+	"\n" +
+	"error_code\x18\n" +
+	" \x01(\tR\terrorCode\x12W\n" +
+	"\ferror_params\x18\v \x03(\v24.logingestion.SubmitLogStreamResult.ErrorParamsEntryR\verrorParams\x1a>\n" +
+	"\x10ErrorParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xb8\x01\n" +
 	"\fLogIngestion\x12L\n" +
-	"\tSubmitLog\x12\x1e.logingestion.SubmitLogRequest\x1a\x1f.logingestion.SubmitLogResponseB\x19Z\x17tlng/proto/logingestionb\x06proto3"
+	"\tSubmitLog\x12\x1e.logingestion.SubmitLogRequest\x1a\x1f.logingestion.SubmitLogResponse\x12Z\n" +
+	"\x0fSubmitLogStream\x12\x1e.logingestion.SubmitLogRequest\x1a%.logingestion.SubmitLogStreamResponse(\x01B\x19Z\x17tlng/proto/logingestionb\x06proto3"
 
 var (
 	file_proto_logingestion_proto_rawDescOnce sync.Once
@@ -201,22 +560,30 @@ func file_proto_logingestion_proto_rawDescGZIP() []byte {
 	return file_proto_logingestion_proto_rawDescData
 }
 
-var file_proto_logingestion_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_logingestion_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_proto_logingestion_proto_goTypes = []any{
-	(*SubmitLogRequest)(nil),      // 0: logingestion.SubmitLogRequest
-	(*SubmitLogResponse)(nil),     // 1: logingestion.SubmitLogResponse
-	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+	(*SubmitLogRequest)(nil),        // 0: logingestion.SubmitLogRequest
+	(*SubmitLogResponse)(nil),       // 1: logingestion.SubmitLogResponse
+	(*SubmitLogStreamResponse)(nil), // 2: logingestion.SubmitLogStreamResponse
+	(*SubmitLogStreamResult)(nil),   // 3: logingestion.SubmitLogStreamResult
+	nil,                             // 4: logingestion.SubmitLogStreamResult.ErrorParamsEntry
+	(*timestamppb.Timestamp)(nil),   // 5: google.protobuf.Timestamp
 }
 var file_proto_logingestion_proto_depIdxs = []int32{
-	2, // 0: logingestion.SubmitLogRequest.client_timestamp:type_name -> google.protobuf.Timestamp
-	2, // 1: logingestion.SubmitLogResponse.server_received_timestamp:type_name -> google.protobuf.Timestamp
-	0, // 2: logingestion.LogIngestion.SubmitLog:input_type -> logingestion.SubmitLogRequest
-	1, // 3: logingestion.LogIngestion.SubmitLog:output_type -> logingestion.SubmitLogResponse
-	3, // [3:4] is the sub-list for method output_type
-	2, // [2:3] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	5, // 0: logingestion.SubmitLogRequest.client_timestamp:type_name -> google.protobuf.Timestamp
+	5, // 1: logingestion.SubmitLogResponse.server_received_timestamp:type_name -> google.protobuf.Timestamp
+	3, // 2: logingestion.SubmitLogStreamResponse.results:type_name -> logingestion.SubmitLogStreamResult
+	5, // 3: logingestion.SubmitLogStreamResult.server_received_timestamp:type_name -> google.protobuf.Timestamp
+	4, // 4: logingestion.SubmitLogStreamResult.error_params:type_name -> logingestion.SubmitLogStreamResult.ErrorParamsEntry
+	0, // 5: logingestion.LogIngestion.SubmitLog:input_type -> logingestion.SubmitLogRequest
+	0, // 6: logingestion.LogIngestion.SubmitLogStream:input_type -> logingestion.SubmitLogRequest
+	1, // 7: logingestion.LogIngestion.SubmitLog:output_type -> logingestion.SubmitLogResponse
+	2, // 8: logingestion.LogIngestion.SubmitLogStream:output_type -> logingestion.SubmitLogStreamResponse
+	7, // [7:9] is the sub-list for method output_type
+	5, // [5:7] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_logingestion_proto_init() }
@@ -230,7 +597,7 @@ func file_proto_logingestion_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_logingestion_proto_rawDesc), len(file_proto_logingestion_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
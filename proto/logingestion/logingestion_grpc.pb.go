@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	LogIngestion_SubmitLog_FullMethodName = "/logingestion.LogIngestion/SubmitLog"
+	LogIngestion_SubmitLog_FullMethodName       = "/logingestion.LogIngestion/SubmitLog"
+	LogIngestion_SubmitLogStream_FullMethodName = "/logingestion.LogIngestion/SubmitLogStream"
 )
 
 // LogIngestionClient is the client API for LogIngestion service.
@@ -30,6 +31,10 @@ const (
 type LogIngestionClient interface {
 	// SubmitLog method for submitting a single log entry, supports HTTP POST
 	SubmitLog(ctx context.Context, in *SubmitLogRequest, opts ...grpc.CallOption) (*SubmitLogResponse, error)
+	// SubmitLogStream accepts a stream of log entries and returns a summary once
+	// the client closes the stream. Each entry is accepted or rejected on its
+	// own, like a SubmitLog call.
+	SubmitLogStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SubmitLogRequest, SubmitLogStreamResponse], error)
 }
 
 type logIngestionClient struct {
@@ -50,6 +55,19 @@ func (c *logIngestionClient) SubmitLog(ctx context.Context, in *SubmitLogRequest
 	return out, nil
 }
 
+func (c *logIngestionClient) SubmitLogStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SubmitLogRequest, SubmitLogStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &LogIngestion_ServiceDesc.Streams[0], LogIngestion_SubmitLogStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubmitLogRequest, SubmitLogStreamResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogIngestion_SubmitLogStreamClient = grpc.ClientStreamingClient[SubmitLogRequest, SubmitLogStreamResponse]
+
 // LogIngestionServer is the server API for LogIngestion service.
 // All implementations must embed UnimplementedLogIngestionServer
 // for forward compatibility.
@@ -58,6 +76,10 @@ func (c *logIngestionClient) SubmitLog(ctx context.Context, in *SubmitLogRequest
 type LogIngestionServer interface {
 	// SubmitLog method for submitting a single log entry, supports HTTP POST
 	SubmitLog(context.Context, *SubmitLogRequest) (*SubmitLogResponse, error)
+	// SubmitLogStream accepts a stream of log entries and returns a summary once
+	// the client closes the stream. Each entry is accepted or rejected on its
+	// own, like a SubmitLog call.
+	SubmitLogStream(grpc.ClientStreamingServer[SubmitLogRequest, SubmitLogStreamResponse]) error
 	mustEmbedUnimplementedLogIngestionServer()
 }
 
@@ -71,6 +93,9 @@ type UnimplementedLogIngestionServer struct{}
 func (UnimplementedLogIngestionServer) SubmitLog(context.Context, *SubmitLogRequest) (*SubmitLogResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SubmitLog not implemented")
 }
+func (UnimplementedLogIngestionServer) SubmitLogStream(grpc.ClientStreamingServer[SubmitLogRequest, SubmitLogStreamResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SubmitLogStream not implemented")
+}
 func (UnimplementedLogIngestionServer) mustEmbedUnimplementedLogIngestionServer() {}
 func (UnimplementedLogIngestionServer) testEmbeddedByValue()                      {}
 
@@ -110,6 +135,13 @@ func _LogIngestion_SubmitLog_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LogIngestion_SubmitLogStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(LogIngestionServer).SubmitLogStream(&grpc.GenericServerStream[SubmitLogRequest, SubmitLogStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type LogIngestion_SubmitLogStreamServer = grpc.ClientStreamingServer[SubmitLogRequest, SubmitLogStreamResponse]
+
 // LogIngestion_ServiceDesc is the grpc.ServiceDesc for LogIngestion service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -122,6 +154,12 @@ var LogIngestion_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _LogIngestion_SubmitLog_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubmitLogStream",
+			Handler:       _LogIngestion_SubmitLogStream_Handler,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "proto/logingestion.proto",
 }
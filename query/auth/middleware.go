@@ -2,7 +2,12 @@ package auth
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
+
+	"tlng/apitoken"
+	"tlng/svcauth"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -12,9 +17,9 @@ const authContextKey contextKey = "auth_context"
 
 // AuthContext holds the authentication information extracted from HTTP headers
 type AuthContext struct {
-	ClientID    string // from X-API-Client-ID
-	OrgID       string // from X-Client-Org-ID
-	AuthMethod  string // "api-key" or "mtls"
+	ClientID    string // from X-API-Client-ID, or the API token ID
+	OrgID       string // from X-Client-Org-ID, or the API token's org
+	AuthMethod  string // "api-key", "api-token" or "mtls"
 	CertSubject string // from X-Cert-Subject (mTLS only)
 	MemberID    string // from X-Member-ID (mTLS only)
 }
@@ -85,6 +90,35 @@ func RequireAPIKey(next http.Handler) http.Handler {
 	})
 }
 
+// RequireAPIKeyOrToken is RequireAPIKey that also accepts an API token with
+// the query scope as a bearer token, verified by v; the caller is then the
+// token, bound to the token's org
+func RequireAPIKeyOrToken(v svcauth.Verifier, next http.Handler) http.Handler {
+	apiKey := RequireAPIKey(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(strings.TrimSpace(token), apitoken.Prefix) {
+			apiKey.ServeHTTP(w, r)
+			return
+		}
+
+		id, err := v.Verify(r.Context(), r.TLS, strings.TrimSpace(token))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, fmt.Sprintf(`{"error":"Unauthorized","message":%q}`, err.Error()), http.StatusUnauthorized)
+			return
+		}
+		if id.OrgID == "" || !id.HasScope(apitoken.ScopeQuery) {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error":"Forbidden","message":"API token lacks the query scope"}`, http.StatusForbidden)
+			return
+		}
+
+		authCtx := &AuthContext{ClientID: id.ID, OrgID: id.OrgID, AuthMethod: "api-token"}
+		next.ServeHTTP(w, r.WithContext(WithAuthContext(r.Context(), authCtx)))
+	})
+}
+
 // RequireMTLS is a middleware that requires mTLS authentication
 func RequireMTLS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,4 +8,7 @@ var (
 	ErrPermissionDenied = errors.New("permission denied")
 	ErrInvalidRequest   = errors.New("invalid request")
 	ErrBlockchainError  = errors.New("blockchain query failed")
+	ErrNotSupported     = errors.New("not supported by the deployed contract")
+	// ErrSchemaNotSupported indicates a database schema too old for the request
+	ErrSchemaNotSupported = errors.New("not supported by the database schema")
 )
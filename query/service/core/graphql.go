@@ -0,0 +1,646 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"tlng/storage/store"
+)
+
+// Page sizes of GraphQL list fields
+const (
+	DefaultGraphQLPageSize = 100
+	MaxGraphQLPageSize     = 1000
+)
+
+// graphQLLookupCost is the complexity of a field that queries the State DB
+const graphQLLookupCost = 10
+
+// GraphQLSchemaSDL is the schema of the GraphQL endpoint: logs with their
+// lifecycle, batches and proofs, searches and counts
+const GraphQLSchemaSDL = `schema {
+  query: Query
+}
+
+"64-bit integer"
+scalar Long
+
+type Query {
+  "A submission by request_id"
+  log(requestId: String!): Log
+  "Submissions of a log hash, oldest first"
+  logsByHash(logHash: String!, first: Int = 100): [Log!]!
+  "Search by severity, application, source host, status and time"
+  logs(
+    severity: String
+    application: String
+    sourceHost: String
+    status: String
+    "RFC 3339; received at or after"
+    since: String
+    "RFC 3339; received before"
+    until: String
+    testTraffic: Boolean
+    first: Int = 100
+    "nextCursor of the previous page"
+    after: String
+  ): LogPage!
+  "Counts by status, severity and application; test traffic is not counted unless selected"
+  stats(
+    severity: String
+    application: String
+    sourceHost: String
+    status: String
+    "RFC 3339; received at or after"
+    since: String
+    "RFC 3339; received before"
+    until: String
+    testTraffic: Boolean
+  ): LogStats!
+  "The caller's records of a gateway or engine batch"
+  batch(id: String!, first: Int = 100): Batch
+}
+
+"A log submission of the caller's organization"
+type Log {
+  requestId: String!
+  logHash: String!
+  sourceOrgId: String!
+  status: String!
+  receivedTimestamp: String!
+  processingStartedAt: String
+  processingFinishedAt: String
+  txHash: String
+  blockHeight: Long
+  errorMessage: String
+  region: String
+  clientTimestamp: String
+  severity: String
+  application: String
+  sourceHost: String
+  testTraffic: Boolean!
+  sequence: Long
+  externalId: String
+  retryCount: Int!
+  gatewayBatchId: String
+  engineBatchId: String
+  lifecycle: Lifecycle!
+  gatewayBatch(first: Int = 100): Batch
+  engineBatch(first: Int = 100): Batch
+  "Present when the proof cache holds the caller's attestation of the hash"
+  attestationProof: AttestationProof
+  "Present for logs anchored under a Merkle root"
+  merkleProof: MerkleProof
+}
+
+"When the submission reached each stage; stages not reached are null"
+type Lifecycle {
+  received: String!
+  queued: String
+  processingStarted: String
+  anchored: String
+  failed: String
+}
+
+"The caller's records of a gateway or engine batch"
+type Batch {
+  id: String!
+  logs: [Log!]!
+  truncated: Boolean!
+}
+
+"Cached on-chain record of a log hash and its anchoring transaction"
+type AttestationProof {
+  senderOrgId: String!
+  timestamp: String
+  clientTimestamp: String
+  txHash: String
+  blockHeight: Long
+  source: String!
+  cachedAt: String!
+}
+
+"Inclusion proof of a log hash anchored under a Merkle root"
+type MerkleProof {
+  merkleRoot: String!
+  leafIndex: Int!
+  leafCount: Int!
+  siblings: [String!]!
+  txHash: String!
+  blockHeight: Long!
+}
+
+"One page of a search, oldest first"
+type LogPage {
+  nodes: [Log!]!
+  nextCursor: String
+}
+
+"Counts of the submissions matching a query"
+type LogStats {
+  total: Long!
+  byStatus: [Count!]!
+  bySeverity: [Count!]!
+  byApplication: [Count!]!
+}
+
+type Count {
+  key: String!
+  count: Long!
+}
+`
+
+// GraphQLRequest is a GraphQL query with its operation name and variables
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// SetGraphQL enables GraphQL queries over the read model with graphql-go.
+// Queries nesting fields deeper than maxDepth are rejected before they run,
+// and a query's State DB lookups stop once they cost more than maxComplexity.
+func (s *Service) SetGraphQL(maxDepth, maxComplexity int) error {
+	schema, err := graphql.ParseSchema(GraphQLSchemaSDL, &graphQLQuery{s: s},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+		graphql.DisableIntrospection(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	s.graphql, s.graphqlComplexity = schema, maxComplexity
+	return nil
+}
+
+// GraphQLEnabled reports whether GraphQL queries are enabled
+func (s *Service) GraphQLEnabled() bool {
+	return s.graphql != nil
+}
+
+// GraphQL executes a GraphQL query. Like the REST APIs for submitters it only
+// sees the caller organization's submissions: others' logs resolve to null
+// and batches list the caller's records only.
+func (s *Service) GraphQL(ctx context.Context, req GraphQLRequest, callerOrgID string) *graphql.Response {
+	caller := &graphQLCaller{orgID: callerOrgID, maxComplexity: int64(s.graphqlComplexity)}
+	ctx = context.WithValue(ctx, graphQLCallerKey{}, caller)
+	return s.graphql.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+// graphQLCaller is the caller of a GraphQL query and the cost its resolvers
+// have spent so far, shared by resolvers running in parallel
+type graphQLCaller struct {
+	orgID         string
+	maxComplexity int64
+	spent         atomic.Int64
+}
+
+type graphQLCallerKey struct{}
+
+func graphQLOrg(ctx context.Context) string {
+	caller, _ := ctx.Value(graphQLCallerKey{}).(*graphQLCaller)
+	if caller == nil {
+		return ""
+	}
+	return caller.orgID
+}
+
+// charge spends the cost of a lookup from the query's complexity budget,
+// failing the lookup instead of running it once the budget is exceeded
+func charge(ctx context.Context, cost int) error {
+	caller, _ := ctx.Value(graphQLCallerKey{}).(*graphQLCaller)
+	if caller == nil {
+		return fmt.Errorf("GraphQL resolver called without a caller")
+	}
+	if spent := caller.spent.Add(int64(cost)); spent > caller.maxComplexity {
+		return fmt.Errorf("query complexity %d exceeds the limit of %d", spent, caller.maxComplexity)
+	}
+	return nil
+}
+
+// Long is the GraphQL scalar of 64-bit integers, which Int cannot hold
+type Long int64
+
+// ImplementsGraphQLType maps Long to the Long scalar
+func (Long) ImplementsGraphQLType(name string) bool {
+	return name == "Long"
+}
+
+// UnmarshalGraphQL is required of scalars; no argument takes a Long
+func (l *Long) UnmarshalGraphQL(input any) error {
+	switch v := input.(type) {
+	case int32:
+		*l = Long(v)
+	case int64:
+		*l = Long(v)
+	case float64:
+		*l = Long(v)
+	default:
+		return fmt.Errorf("cannot unmarshal %T as Long", input)
+	}
+	return nil
+}
+
+// graphQLQuery resolves the Query type. Each resolver checks the caller's
+// org itself: lookups are scoped to it, and records of other orgs are
+// dropped before they reach the response.
+type graphQLQuery struct {
+	s *Service
+}
+
+func (q *graphQLQuery) Log(ctx context.Context, args struct{ RequestID string }) (*graphQLLog, error) {
+	return lookup(ctx, q.s, graphQLLookupCost, func() (*graphQLLog, error) {
+		resp, err := q.s.GetStatusByRequestID(ctx, args.RequestID, graphQLOrg(ctx))
+		if errors.Is(err, ErrLogNotFound) || errors.Is(err, ErrPermissionDenied) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return q.s.graphQLLog(ctx, resp), nil
+	})
+}
+
+func (q *graphQLQuery) LogsByHash(ctx context.Context, args struct {
+	LogHash string
+	First   int32
+}) ([]*graphQLLog, error) {
+	first, err := firstArg(args.First)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(ctx, q.s, graphQLLookupCost+first, func() ([]*graphQLLog, error) {
+		org := graphQLOrg(ctx)
+		statuses, err := q.s.store.ListLogStatusByHash(ctx, args.LogHash, org, first)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query database: %w", err)
+		}
+		logs := make([]*graphQLLog, 0, len(statuses))
+		for _, status := range statuses {
+			if l := q.s.graphQLLog(ctx, convertToResponse(status)); l != nil {
+				logs = append(logs, l)
+			}
+		}
+		return logs, nil
+	})
+}
+
+// graphQLFilter holds the filter arguments of searches and stats
+type graphQLFilter struct {
+	Severity    *string
+	Application *string
+	SourceHost  *string
+	Status      *string
+	Since       *string
+	Until       *string
+	TestTraffic *bool
+}
+
+func (q *graphQLQuery) Logs(ctx context.Context, args struct {
+	graphQLFilter
+	First int32
+	After *string
+}) (*graphQLLogPage, error) {
+	first, err := firstArg(args.First)
+	if err != nil {
+		return nil, err
+	}
+	query, err := args.graphQLFilter.logQuery()
+	if err != nil {
+		return nil, err
+	}
+	return lookup(ctx, q.s, graphQLLookupCost+first, func() (*graphQLLogPage, error) {
+		resp, err := q.s.SearchLogs(ctx, graphQLOrg(ctx), query, deref(args.After), first)
+		if err != nil {
+			return nil, err
+		}
+		page := &graphQLLogPage{nextCursor: optional(resp.NextCursor)}
+		for _, l := range resp.Logs {
+			if gl := q.s.graphQLLog(ctx, l); gl != nil {
+				page.nodes = append(page.nodes, gl)
+			}
+		}
+		return page, nil
+	})
+}
+
+func (q *graphQLQuery) Stats(ctx context.Context, args graphQLFilter) (*graphQLLogStats, error) {
+	query, err := args.logQuery()
+	if err != nil {
+		return nil, err
+	}
+	return lookup(ctx, q.s, graphQLLookupCost, func() (*graphQLLogStats, error) {
+		resp, err := q.s.GetLogStats(ctx, graphQLOrg(ctx), query)
+		if err != nil {
+			return nil, err
+		}
+		return &graphQLLogStats{resp}, nil
+	})
+}
+
+func (q *graphQLQuery) Batch(ctx context.Context, args struct {
+	ID    string
+	First int32
+}) (*graphQLBatch, error) {
+	return q.s.graphQLBatch(ctx, args.ID, args.First)
+}
+
+// graphQLBatch returns the caller's records of a batch, or nil if it has none
+func (s *Service) graphQLBatch(ctx context.Context, batchID string, firstArgValue int32) (*graphQLBatch, error) {
+	first, err := firstArg(firstArgValue)
+	if err != nil {
+		return nil, err
+	}
+	return lookup(ctx, s, graphQLLookupCost+first, func() (*graphQLBatch, error) {
+		trace, err := s.TraceBatch(ctx, batchID)
+		if errors.Is(err, ErrLogNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		batch := &graphQLBatch{id: batchID, truncated: trace.Truncated}
+		for _, record := range trace.Records {
+			l := s.graphQLLog(ctx, record)
+			if l == nil {
+				continue // Another org's record
+			}
+			if len(batch.logs) == first {
+				batch.truncated = true
+				break
+			}
+			batch.logs = append(batch.logs, l)
+		}
+		if len(batch.logs) == 0 {
+			return nil, nil
+		}
+		return batch, nil
+	})
+}
+
+// lookup charges the cost of a resolver that queries the State DB and runs
+// it. Unexpected errors are reported as internal errors, and logged, so
+// database details do not reach callers.
+func lookup[T any](ctx context.Context, s *Service, cost int, resolve func() (T, error)) (T, error) {
+	var zero T
+	if err := charge(ctx, cost); err != nil {
+		return zero, err
+	}
+	v, err := resolve()
+	if err == nil {
+		return v, nil
+	}
+	for _, known := range []error{ErrInvalidRequest, ErrSchemaNotSupported, ErrNotSupported} {
+		if errors.Is(err, known) {
+			return zero, err
+		}
+	}
+	s.logger.Printf("GraphQL resolver failed for org=%s: %v", graphQLOrg(ctx), err)
+	return zero, errors.New("internal server error")
+}
+
+// graphQLLog resolves a Log. It is nil unless the caller's org submitted it,
+// so no resolver can return another org's log.
+type graphQLLog struct {
+	s *Service
+	l *LogStatusResponse
+}
+
+func (s *Service) graphQLLog(ctx context.Context, l *LogStatusResponse) *graphQLLog {
+	if l == nil || l.SourceOrgID != graphQLOrg(ctx) {
+		return nil
+	}
+	return &graphQLLog{s: s, l: l}
+}
+
+func (r *graphQLLog) RequestID() string             { return r.l.RequestID }
+func (r *graphQLLog) LogHash() string               { return r.l.LogHash }
+func (r *graphQLLog) SourceOrgID() string           { return r.l.SourceOrgID }
+func (r *graphQLLog) Status() string                { return r.l.Status }
+func (r *graphQLLog) ReceivedTimestamp() string     { return formatTime(r.l.ReceivedTimestamp) }
+func (r *graphQLLog) ProcessingStartedAt() *string  { return optionalTime(r.l.ProcessingStartedAt) }
+func (r *graphQLLog) ProcessingFinishedAt() *string { return optionalTime(r.l.ProcessingFinishedAt) }
+func (r *graphQLLog) TxHash() *string               { return optional(r.l.TxHash) }
+func (r *graphQLLog) ErrorMessage() *string         { return optional(r.l.ErrorMessage) }
+func (r *graphQLLog) Region() *string               { return optional(r.l.Region) }
+func (r *graphQLLog) ClientTimestamp() *string      { return optionalTime(r.l.ClientTimestamp) }
+func (r *graphQLLog) Severity() *string             { return optional(r.l.Severity) }
+func (r *graphQLLog) Application() *string          { return optional(r.l.Application) }
+func (r *graphQLLog) SourceHost() *string           { return optional(r.l.SourceHost) }
+func (r *graphQLLog) TestTraffic() bool             { return r.l.TestTraffic }
+func (r *graphQLLog) ExternalID() *string           { return optional(r.l.ExternalID) }
+func (r *graphQLLog) RetryCount() int32             { return int32(r.l.RetryCount) }
+func (r *graphQLLog) GatewayBatchID() *string       { return optional(r.l.GatewayBatchID) }
+func (r *graphQLLog) EngineBatchID() *string        { return optional(r.l.EngineBatchID) }
+
+func (r *graphQLLog) BlockHeight() *Long {
+	if r.l.TxHash == "" {
+		return nil
+	}
+	h := Long(r.l.BlockHeight)
+	return &h
+}
+
+func (r *graphQLLog) Sequence() *Long {
+	if r.l.Sequence == 0 {
+		return nil
+	}
+	seq := Long(r.l.Sequence)
+	return &seq
+}
+
+func (r *graphQLLog) Lifecycle() *graphQLLifecycle {
+	return &graphQLLifecycle{&r.l.Lifecycle}
+}
+
+func (r *graphQLLog) GatewayBatch(ctx context.Context, args struct{ First int32 }) (*graphQLBatch, error) {
+	if r.l.GatewayBatchID == "" {
+		return nil, nil
+	}
+	return r.s.graphQLBatch(ctx, r.l.GatewayBatchID, args.First)
+}
+
+func (r *graphQLLog) EngineBatch(ctx context.Context, args struct{ First int32 }) (*graphQLBatch, error) {
+	if r.l.EngineBatchID == "" {
+		return nil, nil
+	}
+	return r.s.graphQLBatch(ctx, r.l.EngineBatchID, args.First)
+}
+
+func (r *graphQLLog) AttestationProof(ctx context.Context) (*graphQLAttestationProof, error) {
+	return lookup(ctx, r.s, graphQLLookupCost, func() (*graphQLAttestationProof, error) {
+		proof := r.s.cachedProof(ctx, r.l.LogHash)
+		if proof == nil || proof.SenderOrgID != graphQLOrg(ctx) {
+			return nil, nil
+		}
+		return &graphQLAttestationProof{proof}, nil
+	})
+}
+
+func (r *graphQLLog) MerkleProof(ctx context.Context) (*graphQLMerkleProof, error) {
+	return lookup(ctx, r.s, graphQLLookupCost, func() (*graphQLMerkleProof, error) {
+		mp, err := r.s.store.GetMerkleProof(ctx, r.l.LogHash)
+		if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrFeatureUnavailable) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query database: %w", err)
+		}
+		return &graphQLMerkleProof{mp}, nil
+	})
+}
+
+type graphQLLifecycle struct {
+	l *LifecycleTimestamps
+}
+
+func (r *graphQLLifecycle) Received() string           { return formatTime(r.l.Received) }
+func (r *graphQLLifecycle) Queued() *string            { return optionalTime(r.l.Queued) }
+func (r *graphQLLifecycle) ProcessingStarted() *string { return optionalTime(r.l.ProcessingStarted) }
+func (r *graphQLLifecycle) Anchored() *string          { return optionalTime(r.l.Anchored) }
+func (r *graphQLLifecycle) Failed() *string            { return optionalTime(r.l.Failed) }
+
+type graphQLBatch struct {
+	id        string
+	logs      []*graphQLLog
+	truncated bool
+}
+
+func (r *graphQLBatch) ID() string          { return r.id }
+func (r *graphQLBatch) Logs() []*graphQLLog { return r.logs }
+func (r *graphQLBatch) Truncated() bool     { return r.truncated }
+
+type graphQLAttestationProof struct {
+	p *store.AttestationProof
+}
+
+func (r *graphQLAttestationProof) SenderOrgID() string      { return r.p.SenderOrgID }
+func (r *graphQLAttestationProof) Timestamp() *string       { return optional(r.p.Timestamp) }
+func (r *graphQLAttestationProof) ClientTimestamp() *string { return optional(r.p.ClientTimestamp) }
+func (r *graphQLAttestationProof) TxHash() *string          { return optional(r.p.TxHash) }
+func (r *graphQLAttestationProof) Source() string           { return r.p.Source }
+func (r *graphQLAttestationProof) CachedAt() string         { return formatTime(r.p.CachedAt) }
+
+func (r *graphQLAttestationProof) BlockHeight() *Long {
+	if r.p.TxHash == "" {
+		return nil
+	}
+	h := Long(r.p.BlockHeight)
+	return &h
+}
+
+type graphQLMerkleProof struct {
+	m *store.MerkleProof
+}
+
+func (r *graphQLMerkleProof) MerkleRoot() string { return r.m.MerkleRoot }
+func (r *graphQLMerkleProof) LeafIndex() int32   { return int32(r.m.LeafIndex) }
+func (r *graphQLMerkleProof) LeafCount() int32   { return int32(r.m.LeafCount) }
+func (r *graphQLMerkleProof) Siblings() []string { return append([]string{}, r.m.Siblings...) }
+func (r *graphQLMerkleProof) TxHash() string     { return r.m.TxHash }
+func (r *graphQLMerkleProof) BlockHeight() Long  { return Long(r.m.BlockHeight) }
+
+type graphQLLogPage struct {
+	nodes      []*graphQLLog
+	nextCursor *string
+}
+
+func (r *graphQLLogPage) Nodes() []*graphQLLog { return r.nodes }
+func (r *graphQLLogPage) NextCursor() *string  { return r.nextCursor }
+
+type graphQLLogStats struct {
+	r *LogStatsResponse
+}
+
+func (r *graphQLLogStats) Total() Long                    { return Long(r.r.Total) }
+func (r *graphQLLogStats) ByStatus() []*graphQLCount      { return counts(r.r.ByStatus) }
+func (r *graphQLLogStats) BySeverity() []*graphQLCount    { return counts(r.r.BySeverity) }
+func (r *graphQLLogStats) ByApplication() []*graphQLCount { return counts(r.r.ByApplication) }
+
+// graphQLCount is one group of LogStats
+type graphQLCount struct {
+	key   string
+	count int64
+}
+
+func (r *graphQLCount) Key() string { return r.key }
+func (r *graphQLCount) Count() Long { return Long(r.count) }
+
+// counts lists the groups of a count map by key
+func counts(m map[string]int64) []*graphQLCount {
+	out := make([]*graphQLCount, 0, len(m))
+	for k, n := range m {
+		out = append(out, &graphQLCount{key: k, count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key < out[j].key })
+	return out
+}
+
+// firstArg validates the page size of a list field
+func firstArg(first int32) (int, error) {
+	if first < 1 || first > MaxGraphQLPageSize {
+		return 0, fmt.Errorf("%w: first must be between 1 and %d", ErrInvalidRequest, MaxGraphQLPageSize)
+	}
+	return int(first), nil
+}
+
+// logQuery builds a search from filter arguments
+func (f graphQLFilter) logQuery() (LogQuery, error) {
+	q := LogQuery{
+		Severity:    deref(f.Severity),
+		Application: deref(f.Application),
+		SourceHost:  deref(f.SourceHost),
+		Status:      deref(f.Status),
+		TestTraffic: f.TestTraffic,
+	}
+	for _, arg := range []struct {
+		name string
+		raw  *string
+		t    *time.Time
+	}{{"since", f.Since, &q.Since}, {"until", f.Until, &q.Until}} {
+		if deref(arg.raw) == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, *arg.raw)
+		if err != nil {
+			return LogQuery{}, fmt.Errorf("%w: %s must be RFC 3339", ErrInvalidRequest, arg.name)
+		}
+		*arg.t = parsed
+	}
+	return q, nil
+}
+
+// optional maps an empty string to null
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalTime formats a time as RFC 3339, mapping nil to null
+func optionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := formatTime(*t)
+	return &s
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
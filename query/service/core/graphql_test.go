@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"tlng/storage/store"
+)
+
+// fakeStore serves the read-model queries of GraphQL from memory. Queries the
+// tests do not expect panic through the embedded nil interface.
+type fakeStore struct {
+	store.Store
+	logs    []*store.LogStatus
+	filters []store.LogFilter // Filters of searches and counts, in order
+}
+
+func (f *fakeStore) GetLogStatusByRequestID(ctx context.Context, requestID string) (*store.LogStatus, error) {
+	for _, l := range f.logs {
+		if l.RequestID == requestID {
+			return l, nil
+		}
+	}
+	return nil, store.ErrLogNotFound
+}
+
+func (f *fakeStore) ListLogStatusByHash(ctx context.Context, logHash, orgID string, limit int) ([]*store.LogStatus, error) {
+	var out []*store.LogStatus
+	for _, l := range f.logs {
+		if l.LogHash == logHash && l.SourceOrgID == orgID && len(out) < limit {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) ListLogStatusByBatchID(ctx context.Context, batchID string, limit int) ([]*store.LogStatus, error) {
+	var out []*store.LogStatus
+	for _, l := range f.logs {
+		if (l.GatewayBatchID == batchID || l.EngineBatchID == batchID) && len(out) < limit {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) SearchLogStatus(ctx context.Context, filter store.LogFilter, after store.LogCursor, limit int) ([]*store.LogStatus, error) {
+	f.filters = append(f.filters, filter)
+	var out []*store.LogStatus
+	for _, l := range f.logs {
+		if l.SourceOrgID == filter.OrgID && len(out) < limit {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) CountLogStatus(ctx context.Context, filter store.LogFilter) ([]store.LogStatusCount, error) {
+	f.filters = append(f.filters, filter)
+	return nil, nil
+}
+
+// graphQLService returns a service with GraphQL enabled over two orgs' logs,
+// which share a log hash and a gateway batch
+func graphQLService(t *testing.T) (*Service, *fakeStore) {
+	t.Helper()
+	received := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fs := &fakeStore{logs: []*store.LogStatus{
+		{RequestID: "a1", LogHash: "h", SourceOrgID: "org-a", Status: store.StatusReceived, ReceivedTimestamp: received, GatewayBatchID: "gb"},
+		{RequestID: "b1", LogHash: "h", SourceOrgID: "org-b", Status: store.StatusReceived, ReceivedTimestamp: received, GatewayBatchID: "gb"},
+		{RequestID: "a2", LogHash: "h", SourceOrgID: "org-a", Status: store.StatusReceived, ReceivedTimestamp: received, GatewayBatchID: "gb"},
+	}}
+	s := NewService(fs, nil, log.New(io.Discard, "", 0))
+	if err := s.SetGraphQL(5, 1000); err != nil {
+		t.Fatalf("SetGraphQL failed: %v", err)
+	}
+	return s, fs
+}
+
+func TestGraphQLOrgScoping(t *testing.T) {
+	tests := []struct {
+		name, org, query, want string
+	}{
+		{
+			name:  "own log",
+			query: `{ log(requestId: "a1") { requestId sourceOrgId } }`,
+			want:  `{"data":{"log":{"requestId":"a1","sourceOrgId":"org-a"}}}`,
+		},
+		{
+			name:  "another org's log is null",
+			query: `{ log(requestId: "b1") { requestId } }`,
+			want:  `{"data":{"log":null}}`,
+		},
+		{
+			name:  "logs by hash",
+			query: `{ logsByHash(logHash: "h") { requestId } }`,
+			want:  `{"data":{"logsByHash":[{"requestId":"a1"},{"requestId":"a2"}]}}`,
+		},
+		{
+			name:  "batch lists the caller's records",
+			query: `{ batch(id: "gb") { logs { requestId } truncated } }`,
+			want:  `{"data":{"batch":{"logs":[{"requestId":"a1"},{"requestId":"a2"}],"truncated":false}}}`,
+		},
+		{
+			name:  "batch page",
+			query: `{ batch(id: "gb", first: 1) { logs { requestId } truncated } }`,
+			want:  `{"data":{"batch":{"logs":[{"requestId":"a1"}],"truncated":true}}}`,
+		},
+		{
+			name:  "batch of a log",
+			query: `{ log(requestId: "a2") { gatewayBatch { logs { sourceOrgId } } } }`,
+			want:  `{"data":{"log":{"gatewayBatch":{"logs":[{"sourceOrgId":"org-a"},{"sourceOrgId":"org-a"}]}}}}`,
+		},
+		{
+			name:  "batch without the caller's records is null",
+			org:   "org-c",
+			query: `{ batch(id: "gb") { id } }`,
+			want:  `{"data":{"batch":null}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := graphQLService(t)
+			org := tt.org
+			if org == "" {
+				org = "org-a"
+			}
+			data, err := json.Marshal(s.GraphQL(context.Background(), GraphQLRequest{Query: tt.query}, org))
+			if err != nil {
+				t.Fatalf("failed to encode response: %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("response = %s\nwant %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLSearchScoping(t *testing.T) {
+	s, fs := graphQLService(t)
+	resp := s.GraphQL(context.Background(), GraphQLRequest{Query: `{ logs { nodes { requestId } } stats { total } }`}, "org-b")
+	if len(resp.Errors) != 0 {
+		t.Fatalf("errors = %v", messages(resp.Errors))
+	}
+	if len(fs.filters) != 2 {
+		t.Fatalf("got %d store queries, want 2", len(fs.filters))
+	}
+	for _, f := range fs.filters {
+		if f.OrgID != "org-b" {
+			t.Errorf("store queried for org %q, want org-b", f.OrgID)
+		}
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	s, _ := graphQLService(t)
+	tests := []struct {
+		name, query, want string
+	}{
+		{"depth", `{ log(requestId: "a1") { gatewayBatch { logs { gatewayBatch { logs { requestId } } } } } }`, `Field "requestId" has depth 6 that exceeds max depth 5`},
+		{"depth through fragments", `{ log(requestId: "a1") { ...batch } } fragment batch on Log { gatewayBatch { logs { gatewayBatch { logs { requestId } } } } }`, `Field "requestId" has depth 6 that exceeds max depth 5`},
+		{"page size multiplies cost", `{ logsByHash(logHash: "h", first: 1000) { requestId } }`, "query complexity 1010 exceeds the limit of 1000"},
+		{"page size is bounded", `{ logsByHash(logHash: "h", first: 1001) { requestId } }`, "invalid request: first must be between 1 and 1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.GraphQL(context.Background(), GraphQLRequest{Query: tt.query}, "org-a")
+			if len(resp.Errors) != 1 || resp.Errors[0].Message != tt.want {
+				t.Errorf("errors = %v, want one saying %q", messages(resp.Errors), tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLComplexityStopsLookups(t *testing.T) {
+	s, _ := graphQLService(t)
+	if err := s.SetGraphQL(5, 25); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each lookup costs 10, so the third one is not run
+	resp := s.GraphQL(context.Background(), GraphQLRequest{
+		Query: `{ a: log(requestId: "a1") { requestId } b: log(requestId: "a2") { requestId } c: log(requestId: "a1") { requestId } }`,
+	}, "org-a")
+	if len(resp.Errors) != 1 || resp.Errors[0].Message != "query complexity 30 exceeds the limit of 25" {
+		t.Fatalf("errors = %v, want one saying the complexity 30 exceeds 25", messages(resp.Errors))
+	}
+	var data map[string]*struct{ RequestID string }
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	resolved := 0
+	for _, l := range data {
+		if l != nil {
+			resolved++
+		}
+	}
+	if resolved != 2 {
+		t.Errorf("%d logs resolved, want 2 within the limit: %s", resolved, resp.Data)
+	}
+
+	// The budget is per query
+	resp = s.GraphQL(context.Background(), GraphQLRequest{Query: `{ log(requestId: "a1") { requestId } }`}, "org-a")
+	if len(resp.Errors) != 0 {
+		t.Errorf("errors = %v on a query within the limit", messages(resp.Errors))
+	}
+}
+
+func TestGraphQLVariables(t *testing.T) {
+	s, _ := graphQLService(t)
+	resp := s.GraphQL(context.Background(), GraphQLRequest{
+		Query:     `query Status($id: String!) { log(requestId: $id) { requestId status lifecycle { received } } }`,
+		Variables: map[string]any{"id": "a1"},
+	}, "org-a")
+	want := `{"log":{"requestId":"a1","status":"RECEIVED","lifecycle":{"received":"2026-01-02T03:04:05Z"}}}`
+	if len(resp.Errors) != 0 || string(resp.Data) != want {
+		t.Errorf("response = %s %v\nwant %s", resp.Data, messages(resp.Errors), want)
+	}
+}
+
+// messages lists the messages of errs
+func messages(errs []*gqlerrors.QueryError) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Message)
+	}
+	return out
+}
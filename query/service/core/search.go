@@ -0,0 +1,196 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tlng/internal/models"
+	"tlng/storage/store"
+)
+
+// Page sizes for SearchLogs
+const (
+	DefaultLogSearchPageSize = 100
+	MaxLogSearchPageSize     = 1000
+)
+
+// LogQuery selects the caller organization's submissions; empty fields match everything
+type LogQuery struct {
+	Severity    string // Case-insensitive; see models.Severities
+	Application string
+	SourceHost  string
+	Status      string
+	Since       time.Time // Received at or after
+	Until       time.Time // Received before
+	TestTraffic *bool     // Test traffic only (true) or real traffic only (false); nil matches both
+}
+
+// filter validates the query and converts it to a store filter for an org
+func (q LogQuery) filter(orgID string) (store.LogFilter, error) {
+	severity, err := models.ParseSeverity(q.Severity)
+	if err != nil {
+		return store.LogFilter{}, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	status := store.Status(strings.ToUpper(q.Status))
+	switch status {
+	case "", store.StatusReceived, store.StatusProcessing, store.StatusCompleted, store.StatusFailed, store.StatusQueuedLocal:
+	default:
+		return store.LogFilter{}, fmt.Errorf("%w: unknown status '%s'", ErrInvalidRequest, q.Status)
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return store.LogFilter{}, fmt.Errorf("%w: since must be before until", ErrInvalidRequest)
+	}
+	return store.LogFilter{
+		OrgID:       orgID,
+		Severity:    severity,
+		Application: q.Application,
+		SourceHost:  q.SourceHost,
+		Status:      status,
+		Since:       q.Since,
+		Until:       q.Until,
+		TestTraffic: q.TestTraffic,
+	}, nil
+}
+
+// SearchLogs lists the caller organization's submissions matching the query,
+// oldest first, one page of up to limit records after the cursor
+func (s *Service) SearchLogs(ctx context.Context, callerOrgID string, q LogQuery, cursor string, limit int) (*LogSearchResponse, error) {
+	if limit < 0 || limit > MaxLogSearchPageSize {
+		return nil, ErrInvalidRequest
+	}
+	if limit == 0 {
+		limit = DefaultLogSearchPageSize
+	}
+	filter, err := q.filter(callerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeLogCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := s.store.SearchLogStatus(ctx, filter, after, limit+1)
+	if err != nil {
+		if errors.Is(err, store.ErrFeatureUnavailable) {
+			return nil, ErrSchemaNotSupported
+		}
+		s.logger.Printf("Failed to search logs of org=%s: %v", callerOrgID, err)
+		return nil, fmt.Errorf("failed to query database: %w", err)
+	}
+
+	resp := &LogSearchResponse{Logs: make([]*LogStatusResponse, 0, min(len(statuses), limit))}
+	if len(statuses) > limit {
+		statuses = statuses[:limit]
+		last := statuses[limit-1]
+		resp.NextCursor = encodeLogCursor(store.LogCursor{ReceivedTimestamp: last.ReceivedTimestamp, RequestID: last.RequestID})
+	}
+	for _, status := range statuses {
+		resp.Logs = append(resp.Logs, convertToResponse(status))
+	}
+	return resp, nil
+}
+
+// GetLogStats counts the caller organization's submissions matching the query
+// by status, severity and application. Test traffic is not counted unless the
+// query selects it.
+func (s *Service) GetLogStats(ctx context.Context, callerOrgID string, q LogQuery) (*LogStatsResponse, error) {
+	filter, err := q.filter(callerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	if filter.TestTraffic == nil {
+		realOnly := false
+		filter.TestTraffic = &realOnly
+	}
+
+	counts, err := s.store.CountLogStatus(ctx, filter)
+	if err != nil {
+		if errors.Is(err, store.ErrFeatureUnavailable) {
+			return nil, ErrSchemaNotSupported
+		}
+		s.logger.Printf("Failed to count logs of org=%s: %v", callerOrgID, err)
+		return nil, fmt.Errorf("failed to query database: %w", err)
+	}
+
+	resp := &LogStatsResponse{
+		ByStatus:      make(map[string]int64),
+		BySeverity:    make(map[string]int64),
+		ByApplication: make(map[string]int64),
+	}
+	for _, c := range counts {
+		resp.Total += c.Count
+		resp.ByStatus[string(c.Status)] += c.Count
+		resp.BySeverity[orUnspecified(c.Severity)] += c.Count
+		resp.ByApplication[orUnspecified(c.Application)] += c.Count
+	}
+	return resp, nil
+}
+
+// ListSequenceGaps returns up to limit gaps recorded in the caller
+// organization's sequenced sources, newest first
+func (s *Service) ListSequenceGaps(ctx context.Context, callerOrgID string, limit int) (*SequenceGapsResponse, error) {
+	if limit < 0 || limit > MaxLogSearchPageSize {
+		return nil, ErrInvalidRequest
+	}
+	if limit == 0 {
+		limit = DefaultLogSearchPageSize
+	}
+
+	gaps, err := s.store.ListSequenceGaps(ctx, callerOrgID, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrFeatureUnavailable) {
+			return nil, ErrSchemaNotSupported
+		}
+		s.logger.Printf("Failed to list sequence gaps of org=%s: %v", callerOrgID, err)
+		return nil, fmt.Errorf("failed to query database: %w", err)
+	}
+	resp := &SequenceGapsResponse{Gaps: make([]SequenceGapResponse, 0, len(gaps))}
+	for _, g := range gaps {
+		resp.Gaps = append(resp.Gaps, SequenceGapResponse{
+			SourceHost:   g.SourceHost,
+			Application:  g.Application,
+			FirstMissing: g.FirstMissing,
+			LastMissing:  g.LastMissing,
+			Missing:      g.Missing(),
+			DetectedAt:   g.DetectedAt,
+		})
+	}
+	return resp, nil
+}
+
+// orUnspecified names the group of submissions without a field value
+func orUnspecified(value string) string {
+	if value == "" {
+		return "unspecified"
+	}
+	return value
+}
+
+// encodeLogCursor encodes a search position as an opaque page token
+func encodeLogCursor(c store.LogCursor) string {
+	raw := strconv.FormatInt(c.ReceivedTimestamp.UnixNano(), 10) + ":" + c.RequestID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeLogCursor decodes a page token; "" is the start
+func decodeLogCursor(token string) (store.LogCursor, error) {
+	if token == "" {
+		return store.LogCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return store.LogCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidRequest)
+	}
+	nanos, requestID, ok := strings.Cut(string(raw), ":")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if !ok || err != nil || requestID == "" {
+		return store.LogCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidRequest)
+	}
+	return store.LogCursor{ReceivedTimestamp: time.Unix(0, n), RequestID: requestID}, nil
+}
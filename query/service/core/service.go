@@ -8,8 +8,13 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"sync/atomic"
+
+	graphql "github.com/graph-gophers/graphql-go"
 
 	blockchain "tlng/blockchain/client"
+	"tlng/blockchain/types"
+	"tlng/internal/integrity"
 	"tlng/storage/store"
 )
 
@@ -18,6 +23,12 @@ type Service struct {
 	store      store.Store
 	blockchain blockchain.BlockchainClient
 	logger     *log.Logger
+
+	proofCache atomic.Bool         // Answer audits from the attestation proof cache (see SetProofCache)
+	integrity  *integrity.Metadata // nil unless integrity metadata is enabled
+
+	graphql           *graphql.Schema // nil unless GraphQL is enabled (see SetGraphQL)
+	graphqlComplexity int
 }
 
 // NewService creates a new query service instance
@@ -29,6 +40,18 @@ func NewService(storeDB store.Store, bc blockchain.BlockchainClient, logger *log
 	}
 }
 
+// SetProofCache makes audits read the attestation proof cache before the chain
+// and write chain results through to it
+func (s *Service) SetProofCache(enabled bool) {
+	s.proofCache.Store(enabled)
+}
+
+// SetIntegrityMetadata makes hash receipts and audits report how log hashes
+// are computed and which service and schema version answered
+func (s *Service) SetIntegrityMetadata(m *integrity.Metadata) {
+	s.integrity = m
+}
+
 // GetStatusByRequestID queries log status by request_id
 // Only allows querying logs from the caller's organization
 func (s *Service) GetStatusByRequestID(ctx context.Context, requestID, callerOrgID string) (*LogStatusResponse, error) {
@@ -56,6 +79,78 @@ func (s *Service) GetStatusByRequestID(ctx context.Context, requestID, callerOrg
 	return convertToResponse(status), nil
 }
 
+// MaxBatchTraceRecords bounds the records returned by TraceBatch
+const MaxBatchTraceRecords = 1000
+
+// TraceBatch returns the records of a gateway or engine batch, across orgs
+func (s *Service) TraceBatch(ctx context.Context, batchID string) (*BatchTraceResponse, error) {
+	if batchID == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	statuses, err := s.store.ListLogStatusByBatchID(ctx, batchID, MaxBatchTraceRecords+1)
+	if err != nil {
+		s.logger.Printf("Failed to query records of batch %s: %v", batchID, err)
+		return nil, fmt.Errorf("failed to query database: %w", err)
+	}
+	if len(statuses) == 0 {
+		return nil, ErrLogNotFound
+	}
+
+	resp := &BatchTraceResponse{BatchID: batchID}
+	if len(statuses) > MaxBatchTraceRecords {
+		statuses, resp.Truncated = statuses[:MaxBatchTraceRecords], true
+	}
+	resp.Records = make([]*LogStatusResponse, len(statuses))
+	for i, status := range statuses {
+		resp.Records[i] = convertToResponse(status)
+	}
+	return resp, nil
+}
+
+// MaxHashReceipts bounds the receipts returned by GetReceiptsByHash
+const MaxHashReceipts = 1000
+
+// GetReceiptsByHash returns the caller organization's submissions of a log
+// hash, for clients that kept the content but lost the request_id, together
+// with the cached attestation proof of the hash if the proof cache holds one
+// anchored for that organization
+func (s *Service) GetReceiptsByHash(ctx context.Context, logHash, callerOrgID string) (*HashReceiptsResponse, error) {
+	if logHash == "" {
+		return nil, ErrInvalidRequest
+	}
+
+	statuses, err := s.store.ListLogStatusByHash(ctx, logHash, callerOrgID, MaxHashReceipts+1)
+	if err != nil {
+		s.logger.Printf("Failed to query receipts by log_hash=%s: %v", logHash, err)
+		return nil, fmt.Errorf("failed to query database: %w", err)
+	}
+	if len(statuses) == 0 {
+		return nil, ErrLogNotFound
+	}
+
+	resp := &HashReceiptsResponse{LogHash: logHash, Metadata: s.integrity}
+	if len(statuses) > MaxHashReceipts {
+		statuses, resp.Truncated = statuses[:MaxHashReceipts], true
+	}
+	resp.Receipts = make([]*LogStatusResponse, len(statuses))
+	for i, status := range statuses {
+		resp.Receipts[i] = convertToResponse(status)
+	}
+	if proof := s.cachedProof(ctx, logHash); proof != nil && proof.SenderOrgID == callerOrgID {
+		resp.Proof = &AttestationProofResponse{
+			SenderOrgID:     proof.SenderOrgID,
+			Timestamp:       proof.Timestamp,
+			ClientTimestamp: proof.ClientTimestamp,
+			TxHash:          proof.TxHash,
+			BlockHeight:     proof.BlockHeight,
+			Source:          proof.Source,
+			CachedAt:        proof.CachedAt,
+		}
+	}
+	return resp, nil
+}
+
 // QueryByContent queries log status by calculating hash from content
 // Only allows querying logs from the caller's organization
 func (s *Service) QueryByContent(ctx context.Context, logContent, callerOrgID string) (*LogStatusResponse, error) {
@@ -88,11 +183,34 @@ func (s *Service) QueryByContent(ctx context.Context, logContent, callerOrgID st
 
 // AuditLogByHash performs on-chain audit query by log_hash
 // No permission restrictions - consortium members can audit all logs
-func (s *Service) AuditLogByHash(ctx context.Context, logHash string) (*OnChainLogResponse, error) {
+// The proof cache answers unless forceChain is set (forensic checks) or it
+// holds no complete proof; chain results are written through to the cache.
+func (s *Service) AuditLogByHash(ctx context.Context, logHash string, forceChain bool) (*OnChainLogResponse, error) {
 	if logHash == "" {
 		return nil, ErrInvalidRequest
 	}
 
+	var cached *store.AttestationProof
+	if !forceChain {
+		cached = s.cachedProof(ctx, logHash)
+		if cached != nil && cached.Complete() {
+			return &OnChainLogResponse{
+				Source:      "cache",
+				LogHash:     logHash,
+				LogContent:  cached.LogContent,
+				SenderOrgID: cached.SenderOrgID,
+				Timestamp:   cached.Timestamp,
+
+				ClientTimestamp: cached.ClientTimestamp,
+				TxHash:          cached.TxHash,
+				BlockHeight:     cached.BlockHeight,
+				CachedAt:        &cached.CachedAt,
+
+				Metadata: s.integrity,
+			}, nil
+		}
+	}
+
 	if s.blockchain == nil {
 		return nil, fmt.Errorf("blockchain client not available")
 	}
@@ -115,14 +233,117 @@ func (s *Service) AuditLogByHash(ctx context.Context, logHash string) (*OnChainL
 		return nil, fmt.Errorf("failed to parse on-chain data: %w", err)
 	}
 
+	s.saveProof(ctx, store.AttestationProof{
+		LogHash:         logHash,
+		SenderOrgID:     logData.OrgID,
+		Timestamp:       logData.Timestamp,
+		ClientTimestamp: logData.ClientTimestamp,
+		LogContent:      logData.Content,
+		Source:          store.ProofSourceChain,
+	})
+
 	// Return structured response
-	return &OnChainLogResponse{
+	resp := &OnChainLogResponse{
 		Source:      "blockchain",
 		LogHash:     logHash,
 		LogContent:  logData.Content,
 		SenderOrgID: logData.OrgID,
 		Timestamp:   logData.Timestamp,
-	}, nil
+
+		ClientTimestamp: logData.ClientTimestamp,
+
+		Metadata: s.integrity,
+	}
+	if cached != nil {
+		resp.TxHash = cached.TxHash
+		resp.BlockHeight = cached.BlockHeight
+	}
+	return resp, nil
+}
+
+// Page sizes for ListOnChainLogs
+const (
+	DefaultOnChainLogPageSize = 100
+	MaxOnChainLogPageSize     = 1000
+)
+
+// ListOnChainLogs enumerates the attestations an org submitted, read from the
+// chain rather than the State DB so auditors can reconcile the two
+// No permission restrictions - consortium members can audit all orgs
+func (s *Service) ListOnChainLogs(ctx context.Context, orgID, cursor string, limit int) (*OnChainLogListResponse, error) {
+	if orgID == "" || limit < 0 || limit > MaxOnChainLogPageSize {
+		return nil, ErrInvalidRequest
+	}
+	if limit == 0 {
+		limit = DefaultOnChainLogPageSize
+	}
+	if s.blockchain == nil {
+		return nil, fmt.Errorf("blockchain client not available")
+	}
+
+	page, err := s.blockchain.ListOnChainLogs(ctx, orgID, types.Pagination{Cursor: cursor, Limit: limit})
+	if err != nil {
+		if errors.Is(err, types.ErrNotSupported) {
+			return nil, ErrNotSupported
+		}
+		s.logger.Printf("Failed to list on-chain logs of org=%s: %v", orgID, err)
+		return nil, ErrBlockchainError
+	}
+
+	resp := &OnChainLogListResponse{
+		Source:     "blockchain",
+		OrgID:      orgID,
+		Logs:       make([]*OnChainLogEntry, len(page.Logs)),
+		NextCursor: page.NextCursor,
+	}
+	for i, l := range page.Logs {
+		resp.Logs[i] = &OnChainLogEntry{
+			LogHash:         l.LogHash,
+			SenderOrgID:     l.SubmitterOrgID,
+			Timestamp:       l.Timestamp,
+			ClientTimestamp: l.ClientTimestamp,
+		}
+	}
+	return resp, nil
+}
+
+// cachedProof returns the cached attestation proof for a log hash, or nil if
+// there is none or the cache is unavailable
+func (s *Service) cachedProof(ctx context.Context, logHash string) *store.AttestationProof {
+	if !s.proofCache.Load() {
+		return nil
+	}
+	proof, err := s.store.GetAttestationProof(ctx, logHash)
+	if err != nil {
+		if !errors.Is(err, store.ErrProofNotFound) {
+			s.proofCacheFailed(err)
+		}
+		return nil
+	}
+	return proof
+}
+
+// saveProof writes a proof read from the chain through to the cache. It fills
+// in what reconciled proofs lack and never overwrites cached fields.
+func (s *Service) saveProof(ctx context.Context, proof store.AttestationProof) {
+	if !s.proofCache.Load() {
+		return
+	}
+	if err := s.store.SaveAttestationProofs(ctx, []store.AttestationProof{proof}); err != nil {
+		s.proofCacheFailed(err)
+	}
+}
+
+// proofCacheFailed logs a proof cache error; the cache switches itself off if
+// the schema does not have it
+func (s *Service) proofCacheFailed(err error) {
+	if errors.Is(err, store.ErrFeatureUnavailable) {
+		if s.proofCache.CompareAndSwap(true, false) {
+			s.logger.Printf("Warning: attestation proof cache disabled: %v", err)
+		}
+		return
+	}
+	s.logger.Printf("Warning: attestation proof cache error: %v", err)
 }
 
 // OnChainLogData represents parsed on-chain log data
@@ -130,6 +351,8 @@ type OnChainLogData struct {
 	OrgID     string
 	Timestamp string
 	Content   string
+
+	ClientTimestamp string // Optional; stored by contracts that record client timestamps
 }
 
 // parseOnChainData parses blockchain response data in key=value&key=value format
@@ -145,6 +368,8 @@ func parseOnChainData(raw string) (*OnChainLogData, error) {
 		OrgID:     values.Get("org_id"),
 		Timestamp: values.Get("ts"),
 		Content:   values.Get("content"),
+
+		ClientTimestamp: values.Get("client_ts"),
 	}
 
 	// Validate required fields
@@ -170,6 +395,30 @@ func convertToResponse(status *store.LogStatus) *LogStatusResponse {
 		SourceOrgID:       status.SourceOrgID,
 		Status:            string(status.Status),
 		ReceivedTimestamp: status.ReceivedTimestamp,
+		Region:            status.Region,
+		ClientTimestamp:   status.ClientTimestamp,
+		GatewayBatchID:    status.GatewayBatchID,
+		EngineBatchID:     status.EngineBatchID,
+		Severity:          status.Severity,
+		SourceHost:        status.SourceHost,
+		Application:       status.Application,
+		TestTraffic:       status.TestTraffic,
+		Sequence:          status.Sequence,
+		ExternalID:        status.ExternalID,
+		RetryCount:        status.RetryCount,
+		Lifecycle: LifecycleTimestamps{
+			Received:          status.ReceivedTimestamp,
+			ProcessingStarted: status.ProcessingStartedAt,
+		},
+	}
+	if !status.ReceivedAtDB.IsZero() {
+		resp.Lifecycle.Queued = &status.ReceivedAtDB
+	}
+	switch status.Status {
+	case store.StatusCompleted:
+		resp.Lifecycle.Anchored = status.ProcessingFinishedAt
+	case store.StatusFailed:
+		resp.Lifecycle.Failed = status.ProcessingFinishedAt
 	}
 
 	// Add optional fields if present
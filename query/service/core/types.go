@@ -1,6 +1,10 @@
 package core
 
-import "time"
+import (
+	"time"
+
+	"tlng/internal/integrity"
+)
 
 // LogStatusResponse represents the response for log status queries
 type LogStatusResponse struct {
@@ -14,6 +18,88 @@ type LogStatusResponse struct {
 	TxHash               string     `json:"tx_hash,omitempty"`
 	BlockHeight          int64      `json:"block_height,omitempty"`
 	ErrorMessage         string     `json:"error_message,omitempty"`
+	Region               string     `json:"region,omitempty"`
+	ClientTimestamp      *time.Time `json:"client_timestamp,omitempty"`
+	GatewayBatchID       string     `json:"gateway_batch_id,omitempty"`
+	EngineBatchID        string     `json:"engine_batch_id,omitempty"`
+	Severity             string     `json:"severity,omitempty"`
+	SourceHost           string     `json:"source_host,omitempty"`
+	Application          string     `json:"application,omitempty"`
+	TestTraffic          bool       `json:"test_traffic,omitempty"`
+	Sequence             int64      `json:"sequence,omitempty"`
+	ExternalID           string     `json:"external_id,omitempty"`
+
+	Lifecycle  LifecycleTimestamps `json:"lifecycle"`   // When the submission reached each stage
+	RetryCount int                 `json:"retry_count"` // Failed anchoring attempts retried so far
+}
+
+// LifecycleTimestamps are the times a submission reached each stage of the
+// pipeline; stages it has not reached are absent
+type LifecycleTimestamps struct {
+	Received          time.Time  `json:"received"`                     // Accepted by the gateway
+	Queued            *time.Time `json:"queued,omitempty"`             // Stored in the State DB and queued for the engine
+	ProcessingStarted *time.Time `json:"processing_started,omitempty"` // Picked up by the engine, on the latest attempt
+	Anchored          *time.Time `json:"anchored,omitempty"`           // Confirmed on chain (COMPLETED)
+	Failed            *time.Time `json:"failed,omitempty"`             // Given up on (FAILED)
+}
+
+// LogSearchResponse is one page of the submissions matching a search
+type LogSearchResponse struct {
+	Logs       []*LogStatusResponse `json:"logs"`                  // Oldest first
+	NextCursor string               `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page; absent on the last page
+}
+
+// LogStatsResponse counts the submissions matching a query. Submissions
+// without a severity or application are counted as "unspecified".
+type LogStatsResponse struct {
+	Total         int64            `json:"total"`
+	ByStatus      map[string]int64 `json:"by_status"`
+	BySeverity    map[string]int64 `json:"by_severity"`
+	ByApplication map[string]int64 `json:"by_application"`
+}
+
+// SequenceGapResponse is a range of sequence numbers missing from a source
+type SequenceGapResponse struct {
+	SourceHost   string    `json:"source_host,omitempty"`
+	Application  string    `json:"application,omitempty"`
+	FirstMissing int64     `json:"first_missing"`
+	LastMissing  int64     `json:"last_missing"`
+	Missing      int64     `json:"missing"` // Count of missing numbers
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// SequenceGapsResponse lists the gaps recorded in an organization's sequenced sources
+type SequenceGapsResponse struct {
+	Gaps []SequenceGapResponse `json:"gaps"` // Newest first
+}
+
+// BatchTraceResponse lists the records of a gateway or engine batch
+type BatchTraceResponse struct {
+	BatchID   string               `json:"batch_id"`
+	Records   []*LogStatusResponse `json:"records"`
+	Truncated bool                 `json:"truncated,omitempty"` // More than MaxBatchTraceRecords records
+}
+
+// HashReceiptsResponse lists the submissions of a log hash
+type HashReceiptsResponse struct {
+	LogHash   string                    `json:"log_hash"`
+	Receipts  []*LogStatusResponse      `json:"receipts"`                    // Oldest first
+	Proof     *AttestationProofResponse `json:"attestation_proof,omitempty"` // Present when the proof cache holds the caller's attestation
+	Truncated bool                      `json:"truncated,omitempty"`         // More than MaxHashReceipts receipts
+
+	*integrity.Metadata // Hash algorithm and versions; nil unless integrity metadata is enabled
+}
+
+// AttestationProofResponse is the cached on-chain record of a log hash and the
+// transaction that anchored it
+type AttestationProofResponse struct {
+	SenderOrgID     string    `json:"sender_org_id"`
+	Timestamp       string    `json:"timestamp,omitempty"` // Empty for proofs reconciled from a peer region
+	ClientTimestamp string    `json:"client_timestamp,omitempty"`
+	TxHash          string    `json:"tx_hash,omitempty"`
+	BlockHeight     uint64    `json:"block_height,omitempty"`
+	Source          string    `json:"source"` // worker, reconcile or chain
+	CachedAt        time.Time `json:"cached_at"`
 }
 
 // OnChainLogResponse represents the response for blockchain audit queries
@@ -23,4 +109,29 @@ type OnChainLogResponse struct {
 	LogContent  string `json:"log_content"`
 	SenderOrgID string `json:"sender_org_id"`
 	Timestamp   string `json:"timestamp"`
+	// Client-reported event time, present for logs anchored with a client timestamp
+	ClientTimestamp string `json:"client_timestamp,omitempty"`
+
+	// Anchoring transaction, present when the proof cache knows it
+	TxHash      string     `json:"tx_hash,omitempty"`
+	BlockHeight uint64     `json:"block_height,omitempty"`
+	CachedAt    *time.Time `json:"cached_at,omitempty"` // When the proof was cached; only for source "cache"
+
+	*integrity.Metadata // Hash algorithm and versions; nil unless integrity metadata is enabled
+}
+
+// OnChainLogEntry is one attestation enumerated from the chain
+type OnChainLogEntry struct {
+	LogHash         string `json:"log_hash"`
+	SenderOrgID     string `json:"sender_org_id"`
+	Timestamp       string `json:"timestamp"`
+	ClientTimestamp string `json:"client_timestamp,omitempty"`
+}
+
+// OnChainLogListResponse is one page of the attestations an org submitted on chain
+type OnChainLogListResponse struct {
+	Source     string             `json:"source"` // Always "blockchain"
+	OrgID      string             `json:"org_id"`
+	Logs       []*OnChainLogEntry `json:"logs"`
+	NextCursor string             `json:"next_cursor,omitempty"` // Pass as ?cursor= for the next page; absent on the last page
 }
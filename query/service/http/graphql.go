@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"tlng/errcatalog"
+	"tlng/query/auth"
+	"tlng/query/service/core"
+)
+
+// maxGraphQLBodyBytes bounds the body of POST /v1/graphql
+const maxGraphQLBodyBytes = 1 << 20
+
+// GraphQL handles GET and POST /v1/graphql. POST takes {"query",
+// "operationName", "variables"}; GET takes them as the query, operationName
+// and variables (JSON) parameters. Query errors are reported in the
+// response's errors with status 200, as GraphQL clients expect.
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	var req core.GraphQLRequest
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		req.Query = params.Get("query")
+		req.OperationName = params.Get("operationName")
+		if v := params.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid variables")
+				return
+			}
+		}
+	case http.MethodPost:
+		defer r.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxGraphQLBodyBytes+1))
+		if err != nil {
+			h.writeError(w, r, errcatalog.CodeInvalidRequest, "failed to read request body")
+			return
+		}
+		if len(body) > maxGraphQLBodyBytes {
+			h.writeCodedError(w, r, errcatalog.CodeRequestTooLarge, "request body too large",
+				map[string]string{errcatalog.ParamLimit: strconv.Itoa(maxGraphQLBodyBytes)})
+			return
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid JSON")
+			return
+		}
+	default:
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	if req.Query == "" {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing query")
+		return
+	}
+
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.OrgID == "" {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, h.service.GraphQL(r.Context(), req, authCtx.OrgID))
+}
+
+// GraphQLSchema handles GET /v1/graphql/schema, returning the schema in SDL
+func (h *Handler) GraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, core.GraphQLSchemaSDL)
+}
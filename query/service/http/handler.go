@@ -3,19 +3,25 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"tlng/errcatalog"
 	"tlng/query/auth"
 	"tlng/query/service/core"
+	"tlng/svcauth"
 )
 
 // Handler wraps the query service with HTTP handlers
 type Handler struct {
 	service *core.Service
 	logger  *log.Logger
+	tokens  svcauth.Verifier // API token verifier of the API-key routes; nil if API tokens are disabled
 }
 
 // NewHandler creates a new HTTP handler
@@ -26,50 +32,87 @@ func NewHandler(service *core.Service, logger *log.Logger) *Handler {
 	}
 }
 
+// SetTokenVerifier lets the API-key routes also accept API tokens with the query scope
+func (h *Handler) SetTokenVerifier(v svcauth.Verifier) {
+	h.tokens = v
+}
+
+// requireAPIKey authenticates a route by API key, or by API token if enabled
+func (h *Handler) requireAPIKey(next http.HandlerFunc) http.Handler {
+	if h.tokens != nil {
+		return auth.RequireAPIKeyOrToken(h.tokens, next)
+	}
+	return auth.RequireAPIKey(next)
+}
+
 // RegisterRoutes registers all query API routes
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	// API 1: Query by request_id (API Key auth)
-	mux.Handle("/v1/query/status/", auth.RequireAPIKey(http.HandlerFunc(h.GetStatusByRequestID)))
+	mux.Handle("/v1/query/status/", h.requireAPIKey(h.GetStatusByRequestID))
+	mux.Handle("/v1/logs/", h.requireAPIKey(h.GetStatusByRequestID)) // Beside the gateway's POST /v1/logs
 
 	// API 2: Query by log content (API Key auth)
-	mux.Handle("/v1/query_by_content", auth.RequireAPIKey(http.HandlerFunc(h.QueryByContent)))
+	mux.Handle("/v1/query_by_content", h.requireAPIKey(h.QueryByContent))
+
+	// API 2b: Submission receipts by log hash (API Key auth)
+	mux.Handle("/v1/hashes/", h.requireAPIKey(h.GetReceiptsByHash))
+
+	// API 2c: Search and count submissions by severity, application and status (API Key auth)
+	mux.Handle("/v1/logs/search", h.requireAPIKey(h.SearchLogs))
+	mux.Handle("/v1/logs/stats", h.requireAPIKey(h.GetLogStats))
+
+	// API 2c': Gaps recorded in the caller's sequenced sources (API Key auth)
+	mux.Handle("/v1/sources/gaps", h.requireAPIKey(h.ListSequenceGaps))
+
+	// API 2d: GraphQL over the read model, if enabled (API Key auth)
+	if h.service.GraphQLEnabled() {
+		mux.Handle("/v1/graphql", h.requireAPIKey(h.GraphQL))
+		mux.Handle("/v1/graphql/schema", h.requireAPIKey(h.GraphQLSchema))
+	}
 
 	// API 3: Audit log by hash (mTLS auth)
 	mux.Handle("/v1/audit/log/", auth.RequireMTLS(http.HandlerFunc(h.AuditLogByHash)))
+
+	// API 4: Trace a gateway or engine batch (mTLS auth)
+	mux.Handle("/v1/audit/batch/", auth.RequireMTLS(http.HandlerFunc(h.TraceBatch)))
+
+	// API 5: List an org's attestations from the chain (mTLS auth)
+	mux.Handle("/v1/audit/org/", auth.RequireMTLS(http.HandlerFunc(h.ListOnChainLogs)))
 }
 
-// GetStatusByRequestID handles GET /v1/query/status/{request_id}
+// GetStatusByRequestID handles GET /v1/query/status/{request_id} and GET /v1/logs/{request_id}
 func (h *Handler) GetStatusByRequestID(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
 	// Extract request_id from path
 	path := strings.TrimPrefix(r.URL.Path, "/v1/query/status/")
+	path = strings.TrimPrefix(path, "/v1/logs/")
 	requestID := strings.TrimSpace(path)
 	if requestID == "" {
-		h.writeError(w, http.StatusBadRequest, "missing request_id")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing request_id")
 		return
 	}
 
 	// Validate request_id to prevent path traversal
 	if strings.Contains(requestID, "..") || strings.Contains(requestID, "/") {
-		h.writeError(w, http.StatusBadRequest, "invalid request_id: path traversal characters not allowed")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid request_id: path traversal characters not allowed")
 		return
 	}
 
 	// Extract auth context
-	authCtx := auth.ExtractAuthContext(r)
+	authCtx := auth.GetAuthContext(r.Context())
 	if authCtx == nil || authCtx.OrgID == "" {
-		h.writeError(w, http.StatusUnauthorized, "missing authentication context")
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
 		return
 	}
 
 	// Call service
 	result, err := h.service.GetStatusByRequestID(r.Context(), requestID, authCtx.OrgID)
 	if err != nil {
-		h.handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
@@ -84,7 +127,7 @@ type QueryByContentRequest struct {
 // QueryByContent handles POST /v1/query_by_content
 func (h *Handler) QueryByContent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
@@ -94,42 +137,209 @@ func (h *Handler) QueryByContent(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "failed to read request body")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "failed to read request body")
 		return
 	}
 
 	var req QueryByContentRequest
 	if err := json.Unmarshal(body, &req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid JSON")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid JSON")
 		return
 	}
 
 	if strings.TrimSpace(req.LogContent) == "" {
-		h.writeError(w, http.StatusBadRequest, "log_content is required")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "log_content is required")
 		return
 	}
 
 	// Extract auth context
-	authCtx := auth.ExtractAuthContext(r)
+	authCtx := auth.GetAuthContext(r.Context())
 	if authCtx == nil || authCtx.OrgID == "" {
-		h.writeError(w, http.StatusUnauthorized, "missing authentication context")
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
 		return
 	}
 
 	// Call service
 	result, err := h.service.QueryByContent(r.Context(), req.LogContent, authCtx.OrgID)
 	if err != nil {
-		h.handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// GetReceiptsByHash handles GET /v1/hashes/{log_hash}
+func (h *Handler) GetReceiptsByHash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	logHash := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/v1/hashes/"))
+	if logHash == "" {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing log_hash")
+		return
+	}
+	if strings.Contains(logHash, "..") || strings.Contains(logHash, "/") {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid log_hash: path traversal characters not allowed")
+		return
+	}
+
+	// Extract auth context
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.OrgID == "" {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+
+	// Only the caller's own submissions are listed
+	result, err := h.service.GetReceiptsByHash(r.Context(), logHash, authCtx.OrgID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// SearchLogs handles GET /v1/logs/search?severity=&application=&source_host=&status=&since=&until=&test_traffic=&cursor=&limit=
+func (h *Handler) SearchLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	q, err := parseLogQuery(r)
+	if err != nil {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, err.Error())
+		return
+	}
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	// Extract auth context
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.OrgID == "" {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+
+	// Only the caller's own submissions are searched
+	result, err := h.service.SearchLogs(r.Context(), authCtx.OrgID, q, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// GetLogStats handles GET /v1/logs/stats?severity=&application=&source_host=&status=&since=&until=&test_traffic=
+func (h *Handler) GetLogStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	q, err := parseLogQuery(r)
+	if err != nil {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Extract auth context
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.OrgID == "" {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+
+	result, err := h.service.GetLogStats(r.Context(), authCtx.OrgID, q)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// ListSequenceGaps handles GET /v1/sources/gaps?limit=
+func (h *Handler) ListSequenceGaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	// Extract auth context
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil || authCtx.OrgID == "" {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+
+	result, err := h.service.ListSequenceGaps(r.Context(), authCtx.OrgID, limit)
+	if err != nil {
+		h.handleServiceError(w, r, err)
 		return
 	}
 
 	h.writeJSON(w, http.StatusOK, result)
 }
 
+// parseLogQuery reads the search filters from the query string; since and
+// until are RFC 3339 timestamps
+func parseLogQuery(r *http.Request) (core.LogQuery, error) {
+	params := r.URL.Query()
+	q := core.LogQuery{
+		Severity:    params.Get("severity"),
+		Application: params.Get("application"),
+		SourceHost:  params.Get("source_host"),
+		Status:      params.Get("status"),
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if v := params.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s: expected an RFC 3339 timestamp", p.name)
+			}
+			*p.dst = t
+		}
+	}
+	if v := params.Get("test_traffic"); v != "" {
+		testTraffic, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid test_traffic: expected true or false")
+		}
+		q.TestTraffic = &testTraffic
+	}
+	return q, nil
+}
+
 // AuditLogByHash handles GET /v1/audit/log/{log_hash}
 func (h *Handler) AuditLogByHash(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
 		return
 	}
 
@@ -137,46 +347,139 @@ func (h *Handler) AuditLogByHash(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/v1/audit/log/")
 	logHash := strings.TrimSpace(path)
 	if logHash == "" {
-		h.writeError(w, http.StatusBadRequest, "missing log_hash")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing log_hash")
 		return
 	}
 
 	// Validate log_hash to prevent path traversal
 	if strings.Contains(logHash, "..") || strings.Contains(logHash, "/") {
-		h.writeError(w, http.StatusBadRequest, "invalid log_hash: path traversal characters not allowed")
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid log_hash: path traversal characters not allowed")
 		return
 	}
 
 	// Extract auth context (mTLS, member_id required)
-	authCtx := auth.ExtractAuthContext(r)
+	authCtx := auth.GetAuthContext(r.Context())
 	if authCtx == nil {
-		h.writeError(w, http.StatusUnauthorized, "missing authentication context")
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
 		return
 	}
 
 	if authCtx.MemberID == "" {
-		h.writeError(w, http.StatusForbidden, "member_id required for audit API")
+		h.writeError(w, r, errcatalog.CodePermissionDenied, "member_id required for audit API")
 		return
 	}
 
+	// force_chain=true bypasses the proof cache for forensic checks
+	forceChain := r.URL.Query().Get("force_chain") == "true"
+
 	// Call service (no org restriction for consortium members)
-	result, err := h.service.AuditLogByHash(r.Context(), logHash)
+	result, err := h.service.AuditLogByHash(r.Context(), logHash, forceChain)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// TraceBatch handles GET /v1/audit/batch/{batch_id}
+func (h *Handler) TraceBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	batchID := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, "/v1/audit/batch/"))
+	if batchID == "" {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing batch_id")
+		return
+	}
+	if strings.Contains(batchID, "..") || strings.Contains(batchID, "/") {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid batch_id: path traversal characters not allowed")
+		return
+	}
+
+	// Extract auth context (mTLS, member_id required)
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+	if authCtx.MemberID == "" {
+		h.writeError(w, r, errcatalog.CodePermissionDenied, "member_id required for audit API")
+		return
+	}
+
+	result, err := h.service.TraceBatch(r.Context(), batchID)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// ListOnChainLogs handles GET /v1/audit/org/{org_id}/logs?cursor=&limit=
+func (h *Handler) ListOnChainLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/audit/org/")
+	orgID, ok := strings.CutSuffix(path, "/logs")
+	orgID = strings.TrimSpace(orgID)
+	if !ok || orgID == "" {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing org_id")
+		return
+	}
+	if strings.Contains(orgID, "..") || strings.Contains(orgID, "/") {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid org_id: path traversal characters not allowed")
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	// Extract auth context (mTLS, member_id required)
+	authCtx := auth.GetAuthContext(r.Context())
+	if authCtx == nil {
+		h.writeError(w, r, errcatalog.CodeUnauthenticated, "missing authentication context")
+		return
+	}
+	if authCtx.MemberID == "" {
+		h.writeError(w, r, errcatalog.CodePermissionDenied, "member_id required for audit API")
+		return
+	}
+
+	result, err := h.service.ListOnChainLogs(r.Context(), orgID, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
-		h.handleServiceError(w, err)
+		h.handleServiceError(w, r, err)
 		return
 	}
 
 	h.writeJSON(w, http.StatusOK, result)
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error string `json:"error"`
+// writeError writes a JSON error response with its catalog code and HTTP
+// status, the message being the detail of the code's message
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, code errcatalog.Code, message string) {
+	h.writeCodedError(w, r, code, message, map[string]string{errcatalog.ParamDetail: message})
 }
 
-// writeError writes a JSON error response
-func (h *Handler) writeError(w http.ResponseWriter, statusCode int, message string) {
-	h.writeJSON(w, statusCode, ErrorResponse{Error: message})
+// writeCodedError writes a JSON error response with its catalog code and
+// message parameters, and the message in the language of r's Accept-Language
+// if the catalog has it
+func (h *Handler) writeCodedError(w http.ResponseWriter, r *http.Request, code errcatalog.Code, message string, params map[string]string) {
+	statusCode := errcatalog.Get(code).HTTPStatus
+	h.writeJSON(w, statusCode, errcatalog.NewHTTPError(code, params, message, statusCode, r.Header.Get("Accept-Language")))
 }
 
 // writeJSON writes a JSON response
@@ -189,18 +492,20 @@ func (h *Handler) writeJSON(w http.ResponseWriter, statusCode int, data interfac
 	}
 }
 
-// handleServiceError maps service errors to HTTP status codes using typed error checking
-func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
+// handleServiceError maps service errors to catalog codes using typed error checking
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
 	switch {
 	case errors.Is(err, core.ErrLogNotFound):
-		h.writeError(w, http.StatusNotFound, err.Error())
+		h.writeError(w, r, errcatalog.CodeNotFound, err.Error())
 	case errors.Is(err, core.ErrPermissionDenied):
-		h.writeError(w, http.StatusForbidden, err.Error())
+		h.writeError(w, r, errcatalog.CodePermissionDenied, err.Error())
 	case errors.Is(err, core.ErrInvalidRequest):
-		h.writeError(w, http.StatusBadRequest, err.Error())
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, err.Error())
+	case errors.Is(err, core.ErrNotSupported), errors.Is(err, core.ErrSchemaNotSupported):
+		h.writeError(w, r, errcatalog.CodeNotImplemented, err.Error())
 	case errors.Is(err, core.ErrBlockchainError):
-		h.writeError(w, http.StatusInternalServerError, err.Error())
+		h.writeError(w, r, errcatalog.CodeChainUnavailable, err.Error())
 	default:
-		h.writeError(w, http.StatusInternalServerError, "internal server error")
+		h.writeCodedError(w, r, errcatalog.CodeInternal, "internal server error", nil)
 	}
 }
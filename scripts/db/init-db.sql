@@ -15,11 +15,281 @@ CREATE TABLE IF NOT EXISTS tbl_log_status (
     block_height BIGINT,
     log_hash_on_chain TEXT,
     error_message TEXT,
-    retry_count INTEGER NOT NULL DEFAULT 0
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    region TEXT,
+    client_timestamp TIMESTAMPTZ,
+    gateway_batch_id TEXT,
+    engine_batch_id TEXT,
+    engine_instance_id TEXT,
+    severity TEXT,
+    source_host TEXT,
+    application TEXT,
+    test_traffic BOOLEAN NOT NULL DEFAULT FALSE,
+    sequence BIGINT,
+    external_id TEXT
 );
 
+-- Columns added after the initial schema (idempotent for existing databases)
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS region TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS client_timestamp TIMESTAMPTZ;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS gateway_batch_id TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS engine_batch_id TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS engine_instance_id TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS severity TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS source_host TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS application TEXT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS test_traffic BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS sequence BIGINT;
+ALTER TABLE tbl_log_status ADD COLUMN IF NOT EXISTS external_id TEXT;
+
 -- Indexes for query APIs
 -- API 1: GET /v1/query/status/{request_id} - uses request_id (already PRIMARY KEY, no extra index needed)
 -- API 2: POST /v1/query_by_content - uses log_hash for content-based lookup
 CREATE INDEX IF NOT EXISTS idx_log_status_log_hash ON tbl_log_status (log_hash);
--- API 3: GET /v1/audit/log/{log_hash} - uses log_hash (covered by above index)
\ No newline at end of file
+-- GET /v1/hashes/{log_hash} - lists every submission of a hash (covered by above index)
+-- API 3: GET /v1/audit/log/{log_hash} - uses log_hash (covered by above index)
+-- GET /v1/logs/search and /v1/logs/stats - an org's submissions in receive order,
+-- narrowed by severity and application
+CREATE INDEX IF NOT EXISTS idx_log_status_org_received
+    ON tbl_log_status (source_org_id, received_timestamp, request_id);
+CREATE INDEX IF NOT EXISTS idx_log_status_org_severity
+    ON tbl_log_status (source_org_id, severity, received_timestamp) WHERE severity IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_log_status_org_application
+    ON tbl_log_status (source_org_id, application, received_timestamp) WHERE application IS NOT NULL;
+
+-- Incremental exports: keyset scan over completed records
+CREATE INDEX IF NOT EXISTS idx_log_status_completed_finished
+    ON tbl_log_status (processing_finished_at, request_id) WHERE status = 'COMPLETED';
+
+-- Stuck task scan: tasks left PROCESSING by a crashed engine worker
+CREATE INDEX IF NOT EXISTS idx_log_status_processing_started
+    ON tbl_log_status (processing_started_at) WHERE status = 'PROCESSING';
+
+-- Retry backlog gauge, read on every /metrics scrape: RECEIVED tasks that failed at least once
+CREATE INDEX IF NOT EXISTS idx_log_status_retry_backlog
+    ON tbl_log_status (retry_count) WHERE status = 'RECEIVED' AND retry_count > 0;
+
+-- Test traffic TTL purge
+CREATE INDEX IF NOT EXISTS idx_log_status_test_traffic_received
+    ON tbl_log_status (received_timestamp) WHERE test_traffic;
+
+-- Batch tracing: records of a gateway or engine batch
+CREATE INDEX IF NOT EXISTS idx_log_status_gateway_batch_id
+    ON tbl_log_status (gateway_batch_id) WHERE gateway_batch_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_log_status_engine_batch_id
+    ON tbl_log_status (engine_batch_id) WHERE engine_batch_id IS NOT NULL;
+
+-- Export cursors: position of each incremental export (e.g. Parquet analytics export)
+CREATE TABLE IF NOT EXISTS tbl_export_cursor (
+    name TEXT PRIMARY KEY,
+    last_finished_at TIMESTAMPTZ NOT NULL,
+    last_request_id TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Per-org submission counts per accounting period (monthly quotas), maintained by the API Gateway
+CREATE TABLE IF NOT EXISTS tbl_org_usage (
+    period TEXT NOT NULL,
+    org_id TEXT NOT NULL,
+    submissions BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (period, org_id)
+);
+
+-- Attestation proof cache: on-chain records of anchored logs, so verification
+-- does not have to query the chain. Populated by engine workers and reconciliation.
+CREATE TABLE IF NOT EXISTS tbl_attestation_proof (
+    log_hash TEXT PRIMARY KEY,
+    sender_org_id TEXT NOT NULL DEFAULT '',
+    onchain_timestamp TEXT NOT NULL DEFAULT '',
+    client_timestamp TEXT NOT NULL DEFAULT '',
+    log_content TEXT NOT NULL DEFAULT '',
+    tx_hash TEXT NOT NULL DEFAULT '',
+    block_height BIGINT NOT NULL DEFAULT 0,
+    source TEXT NOT NULL,
+    cached_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Local queue: messages of submissions accepted while Kafka was unavailable
+-- (status QUEUED_LOCAL). The gateway's relay publishes them once Kafka is back.
+CREATE TABLE IF NOT EXISTS tbl_local_queue (
+    request_id TEXT PRIMARY KEY,
+    tier TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    queued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_local_queue_tier_queued_at ON tbl_local_queue (tier, queued_at);
+
+-- Engine fleet: every engine instance registers here and refreshes its heartbeat.
+-- PROCESSING tasks record the instance that locked them (engine_instance_id), so
+-- the tasks of an instance that stopped heartbeating can be returned to RECEIVED.
+CREATE TABLE IF NOT EXISTS tbl_worker_instances (
+    instance_id TEXT PRIMARY KEY,
+    version TEXT NOT NULL DEFAULT '',
+    hostname TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    partitions INTEGER[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMPTZ NOT NULL,
+    last_heartbeat TIMESTAMPTZ NOT NULL,
+    stopped_at TIMESTAMPTZ,
+    reclaimed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_status_engine_instance_processing
+    ON tbl_log_status (engine_instance_id) WHERE status = 'PROCESSING';
+
+-- Debug captures: raw requests of a sample of rejected submissions of opted-in
+-- orgs, for support. Payloads are encrypted by the gateway and rows expire after
+-- the configured TTL. The table is not part of snapshots and is not readable by
+-- other database roles.
+CREATE TABLE IF NOT EXISTS tbl_debug_capture (
+    capture_id TEXT PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    transport TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    content_type TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    payload_size INTEGER NOT NULL,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    captured_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_debug_capture_org_captured_at ON tbl_debug_capture (org_id, captured_at DESC);
+CREATE INDEX IF NOT EXISTS idx_debug_capture_expires_at ON tbl_debug_capture (expires_at);
+REVOKE ALL ON tbl_debug_capture FROM PUBLIC;
+
+-- Merkle proofs: in Merkle-root anchoring mode the chain holds one root per
+-- batch; each log's inclusion proof (sorted-leaf index, leaf count and sibling
+-- hashes from the leaf level up) leads from its log hash to that root.
+CREATE TABLE IF NOT EXISTS tbl_merkle_proof (
+    log_hash TEXT PRIMARY KEY,
+    merkle_root TEXT NOT NULL,
+    leaf_index INTEGER NOT NULL,
+    leaf_count INTEGER NOT NULL,
+    siblings TEXT[] NOT NULL DEFAULT '{}',
+    tx_hash TEXT NOT NULL,
+    block_height BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_merkle_proof_root ON tbl_merkle_proof (merkle_root);
+
+-- API tokens: org-scoped bearer tokens minted through the gateway's admin API.
+-- Only the SHA-256 hash of a token is stored; revoked_at may lie in the future
+-- when a rotated token keeps working for a grace period.
+CREATE TABLE IF NOT EXISTS tbl_api_token (
+    token_id TEXT PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    scopes TEXT[] NOT NULL,
+    secret_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    rotated_to TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_token_org_created_at ON tbl_api_token (org_id, created_at DESC);
+REVOKE ALL ON tbl_api_token FROM PUBLIC;
+
+-- Outbox: the Kafka message of every submission, written in the transaction that
+-- inserts its tbl_log_status row when the gateway's outbox is enabled. The gateway
+-- publishes right after the commit and sets sent_at; its relay publishes the rows
+-- left unsent by a failed publish or a crash. Sent rows are purged after a retention.
+CREATE TABLE IF NOT EXISTS tbl_outbox (
+    request_id TEXT PRIMARY KEY,
+    tier TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    claimed_until TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unsent ON tbl_outbox (tier, created_at) WHERE sent_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON tbl_outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+-- Sequenced sources: clients may number the logs of a source (org, source_host,
+-- application) from 1. The gap detector scans the sequenced submissions received
+-- since its last run and records the numbers skipped within each source, so logs
+-- deleted at the source before shipping become visible. checked_through is the
+-- highest number checked per source; numbers at or below it are not checked again.
+CREATE INDEX IF NOT EXISTS idx_log_status_sequence_received
+    ON tbl_log_status (received_at_db) WHERE sequence IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_log_status_org_sequence
+    ON tbl_log_status (source_org_id, sequence) WHERE sequence IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS tbl_source_sequence (
+    org_id TEXT NOT NULL,
+    source_host TEXT NOT NULL DEFAULT '',
+    application TEXT NOT NULL DEFAULT '',
+    checked_through BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, source_host, application)
+);
+
+-- Ranges of sequence numbers missing from a source, recorded once when detected
+CREATE TABLE IF NOT EXISTS tbl_sequence_gap (
+    org_id TEXT NOT NULL,
+    source_host TEXT NOT NULL DEFAULT '',
+    application TEXT NOT NULL DEFAULT '',
+    first_missing BIGINT NOT NULL,
+    last_missing BIGINT NOT NULL,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, source_host, application, first_missing)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sequence_gap_org_detected_at ON tbl_sequence_gap (org_id, detected_at DESC);
+
+-- External IDs: clients may key their submissions by their own ID, unique per
+-- org. Re-sending an external ID returns the existing submission instead of
+-- creating another, so at-least-once client pipelines do not duplicate logs.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_log_status_org_external_id
+    ON tbl_log_status (source_org_id, external_id) WHERE external_id IS NOT NULL;
+
+-- Parked messages: a redelivered message whose task is still PROCESSING is skipped
+-- and acknowledged, so the engine keeps it here. Once the stuck task scan or the
+-- fleet's reclaim returns the task to RECEIVED, the message is published again.
+CREATE TABLE IF NOT EXISTS tbl_parked_message (
+    request_id TEXT PRIMARY KEY,
+    topic TEXT NOT NULL DEFAULT '',
+    payload BYTEA NOT NULL,
+    parked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_parked_message_parked_at ON tbl_parked_message (parked_at);
+
+-- Schema versions (see storage/store/schema.go). Each schema change appends a row;
+-- min_compatible is the oldest binary schema version that may still run against it.
+-- Binaries refuse to start if the schema is older than they support or if
+-- min_compatible is newer than they are, so blue/green versions can overlap safely.
+CREATE TABLE IF NOT EXISTS tbl_schema_version (
+    version INT PRIMARY KEY,
+    min_compatible INT NOT NULL,
+    description TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO tbl_schema_version (version, min_compatible, description) VALUES
+    (1, 1, 'tbl_log_status baseline'),
+    (2, 1, 'tbl_log_status.region'),
+    (3, 1, 'tbl_log_status.client_timestamp'),
+    (4, 1, 'tbl_export_cursor'),
+    (5, 1, 'tbl_org_usage'),
+    (6, 1, 'tbl_attestation_proof'),
+    (7, 1, 'tbl_local_queue'),
+    (8, 1, 'tbl_log_status.gateway_batch_id, engine_batch_id'),
+    (9, 1, 'tbl_worker_instances, tbl_log_status.engine_instance_id'),
+    (10, 1, 'tbl_log_status.severity, source_host, application'),
+    (11, 1, 'tbl_debug_capture'),
+    (12, 1, 'tbl_log_status.test_traffic'),
+    (13, 1, 'tbl_merkle_proof'),
+    (14, 1, 'tbl_api_token'),
+    (15, 1, 'tbl_outbox'),
+    (16, 1, 'tbl_log_status.sequence, tbl_source_sequence, tbl_sequence_gap'),
+    (17, 1, 'tbl_log_status.external_id'),
+    (18, 1, 'tbl_parked_message')
+ON CONFLICT (version) DO NOTHING;
@@ -22,7 +22,7 @@ YELLOW='\033[1;33m'
 NC='\033[0m' # No Color
 
 # 1. 提交日志
-echo -e "${YELLOW}[1/4] 测试提交日志 (POST /v1/logs)${NC}"
+echo -e "${YELLOW}[1/5] 测试提交日志 (POST /v1/logs)${NC}"
 LOG_CONTENT="Test log at $(date +%s)"
 SUBMIT_RESPONSE=$(curl -sk -X POST "$BASE_URL/v1/logs" \
   -H "Content-Type: application/json" \
@@ -40,12 +40,28 @@ if [ -z "$REQUEST_ID" ] || [ "$REQUEST_ID" == "null" ]; then
 fi
 echo -e "${GREEN}✓ 提交成功: request_id=$REQUEST_ID${NC}\n"
 
+# 2. 批量提交日志
+echo -e "${YELLOW}[2/5] 测试批量提交日志 (POST /v1/logs:batch)${NC}"
+BATCH_RESPONSE=$(curl -sk -X POST "$BASE_URL/v1/logs:batch" \
+  -H "Content-Type: application/json" \
+  -H "X-API-Key: $API_KEY" \
+  -d "{\"entries\":[{\"log_content\":\"Batch log 1 at $(date +%s)\"},{\"log_content\":\"Batch log 2 at $(date +%s)\"}]}")
+
+echo "$BATCH_RESPONSE" | jq .
+
+ACCEPTED=$(echo "$BATCH_RESPONSE" | jq -r '.accepted')
+if [ "$ACCEPTED" != "2" ]; then
+    echo -e "${RED}✗ 批量提交失败: accepted=$ACCEPTED${NC}"
+    exit 1
+fi
+echo -e "${GREEN}✓ 批量提交成功: accepted=$ACCEPTED${NC}\n"
+
 # 等待处理
 echo "等待 3 秒让日志处理完成..."
 sleep 3
 
-# 2. 按 request_id 查询
-echo -e "${YELLOW}[2/4] 测试按 request_id 查询 (GET /v1/query/status/{id})${NC}"
+# 3. 按 request_id 查询
+echo -e "${YELLOW}[3/5] 测试按 request_id 查询 (GET /v1/query/status/{id})${NC}"
 QUERY_RESPONSE=$(curl -sk -H "X-API-Key: $API_KEY" \
   "$BASE_URL/v1/query/status/$REQUEST_ID")
 
@@ -58,8 +74,8 @@ else
     echo -e "${YELLOW}! 查询成功但状态为: $STATUS (可能还在处理中)${NC}\n"
 fi
 
-# 3. 按内容查询
-echo -e "${YELLOW}[3/4] 测试按内容查询 (POST /v1/query_by_content)${NC}"
+# 4. 按内容查询
+echo -e "${YELLOW}[4/5] 测试按内容查询 (POST /v1/query_by_content)${NC}"
 CONTENT_QUERY_RESPONSE=$(curl -sk -X POST "$BASE_URL/v1/query_by_content" \
   -H "Content-Type: application/json" \
   -H "X-API-Key: $API_KEY" \
@@ -79,8 +95,8 @@ else
     fi
 fi
 
-# 4. 权限测试：尝试查询其他组织的日志
-echo -e "${YELLOW}[4/4] 测试权限隔离 (应该失败)${NC}"
+# 5. 权限测试：尝试查询其他组织的日志
+echo -e "${YELLOW}[5/5] 测试权限隔离 (应该失败)${NC}"
 # 使用一个不存在的 request_id 或者其他组织的日志
 PERM_TEST_RESPONSE=$(curl -sk -H "X-API-Key: $API_KEY" \
   "$BASE_URL/v1/query/status/00000000-0000-0000-0000-000000000000")
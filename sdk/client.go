@@ -0,0 +1,207 @@
+// Package sdk is the Go client SDK for submitting logs to the TLNG API Gateway
+// over gRPC. It supports client-side load balancing across gateway instances
+// (DNS round robin or xDS), gRPC health checking, and retried or hedged
+// submissions that are made safe by idempotency keys.
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "tlng/proto/logingestion"
+	"tlng/svcauth"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // Enables client-side health checking
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Client submits logs to the API Gateway
+type Client struct {
+	cfg  Config
+	conn *grpc.ClientConn
+	rpc  pb.LogIngestionClient
+}
+
+// Submission is one log to submit
+type Submission struct {
+	LogContent      string
+	SourceOrgID     string     // Defaults to Config.SourceOrgID
+	ClientLogHash   string     // Optional SHA-256 hex of LogContent, verified by the gateway
+	ClientTimestamp *time.Time // Optional client event time
+	// IdempotencyKey identifies the submission across retries and hedged
+	// requests; the gateway derives the request ID from it. A random key is
+	// generated when empty. Reuse a key only to resubmit the same log.
+	IdempotencyKey string
+	// ExternalID is the caller's own optional ID of the log, unique per org.
+	// Resending it returns the submission first stored under it, with its
+	// current status, so at-least-once pipelines attest each log once.
+	ExternalID string
+	// Optional structured fields, validated and stored by the gateway for
+	// search and statistics; they are not part of the attested content
+	Severity    string // DEBUG, INFO, NOTICE, WARNING, ERROR, CRITICAL, ALERT or EMERGENCY
+	SourceHost  string
+	Application string
+	// Sequence is the optional position of the log in its source's sequence
+	// (org, source host and application), starting at 1; the gateway reports
+	// the numbers skipped within a source as gaps
+	Sequence int64
+	// TestTraffic flags a synthetic or test submission, anchored but excluded
+	// from usage and statistics; the gateway must allow it for the org
+	TestTraffic bool
+}
+
+// New connects to the gateways described by cfg. The connection is
+// established lazily; New fails only on invalid configuration.
+func New(cfg Config, opts ...grpc.DialOption) (*Client, error) {
+	cfg.SetDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sdk configuration: %w", err)
+	}
+	if cfg.isXDS() && !xdsEnabled {
+		return nil, fmt.Errorf("target %s requires an SDK built with -tags xds", cfg.Target)
+	}
+
+	serviceConfig, err := cfg.serviceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var creds credentials.TransportCredentials
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	} else {
+		creds = credentials.NewTLS(nil)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	}
+	if cfg.ServiceAuth.Enabled() {
+		authCreds, err := svcauth.NewCredentials(cfg.ServiceAuth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize service authentication: %w", err)
+		}
+		// Replaces the TLS credentials above in spiffe mode
+		dialOpts = append(dialOpts, svcauth.DialOptions(authCreds)...)
+	}
+	dialOpts = append(dialOpts, opts...)
+
+	conn, err := grpc.NewClient(cfg.Target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", cfg.Target, err)
+	}
+
+	return &Client{cfg: cfg, conn: conn, rpc: pb.NewLogIngestionClient(conn)}, nil
+}
+
+// SubmitLog submits one log and returns the gateway's acknowledgement
+func (c *Client) SubmitLog(ctx context.Context, s Submission) (*pb.SubmitLogResponse, error) {
+	req := c.request(s)
+	ctx = c.outgoing(ctx, s.TestTraffic)
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+
+	var resp *pb.SubmitLogResponse
+	var err error
+	if c.cfg.Hedging.MaxAttempts > 1 {
+		resp, err = c.submitHedged(ctx, req)
+	} else {
+		resp, err = c.rpc.SubmitLog(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("submit log failed: %w", err)
+	}
+	return resp, nil
+}
+
+// SubmitLogStream submits logs over one client-streaming call and returns the
+// gateway's summary. Each log is accepted or rejected on its own; the results
+// list the outcome of each log the gateway read, in order. The gateway stops
+// reading at its per-stream limit, so there may be fewer results than logs.
+// Test and other traffic cannot share a stream.
+func (c *Client) SubmitLogStream(ctx context.Context, subs []Submission) (*pb.SubmitLogStreamResponse, error) {
+	if len(subs) == 0 {
+		return &pb.SubmitLogStreamResponse{}, nil
+	}
+	for _, s := range subs[1:] {
+		if s.TestTraffic != subs[0].TestTraffic {
+			return nil, fmt.Errorf("submit log stream failed: test traffic cannot share a stream with other submissions")
+		}
+	}
+	ctx = c.outgoing(ctx, subs[0].TestTraffic)
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+
+	stream, err := c.rpc.SubmitLogStream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("submit log stream failed: %w", err)
+	}
+	for _, s := range subs {
+		// Send fails once the gateway has ended the stream; CloseAndRecv then
+		// returns its summary, or the error the stream failed with
+		if err := stream.Send(c.request(s)); err != nil {
+			break
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, fmt.Errorf("submit log stream failed: %w", err)
+	}
+	return resp, nil
+}
+
+// request converts a submission to its gRPC request, filling in the defaults
+func (c *Client) request(s Submission) *pb.SubmitLogRequest {
+	req := &pb.SubmitLogRequest{
+		LogContent:        s.LogContent,
+		ClientLogHash:     s.ClientLogHash,
+		ClientSourceOrgId: s.SourceOrgID,
+		IdempotencyKey:    s.IdempotencyKey,
+		ExternalId:        s.ExternalID,
+		Severity:          s.Severity,
+		SourceHost:        s.SourceHost,
+		Application:       s.Application,
+		Sequence:          s.Sequence,
+	}
+	if req.ClientSourceOrgId == "" {
+		req.ClientSourceOrgId = c.cfg.SourceOrgID
+	}
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = uuid.NewString()
+	}
+	if s.ClientTimestamp != nil {
+		req.ClientTimestamp = timestamppb.New(*s.ClientTimestamp)
+	}
+	return req
+}
+
+// outgoing adds the call metadata: the test traffic flag and the locale of error messages
+func (c *Client) outgoing(ctx context.Context, testTraffic bool) context.Context {
+	if testTraffic {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-test-traffic", "true")
+	}
+	if c.cfg.Locale != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "accept-language", c.cfg.Locale)
+	}
+	return ctx
+}
+
+// Close closes the underlying connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,200 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"tlng/svcauth"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Load balancing policies
+const (
+	RoundRobin = "round_robin" // Spread RPCs across all resolved gateway addresses
+	PickFirst  = "pick_first"  // Use the first reachable address (gRPC default)
+)
+
+// ingestionService is the fully qualified gRPC service name of the gateway
+const ingestionService = "logingestion.LogIngestion"
+
+// RetryPolicy configures gRPC-level retries of failed RPCs (gRPC A6 retry design)
+type RetryPolicy struct {
+	MaxAttempts          int           `yaml:"max_attempts"`           // Total attempts including the first; <= 1 disables retries
+	InitialBackoff       time.Duration `yaml:"initial_backoff"`        // Backoff before the first retry
+	MaxBackoff           time.Duration `yaml:"max_backoff"`            // Upper bound for backoff
+	BackoffMultiplier    float64       `yaml:"backoff_multiplier"`     // Backoff growth factor
+	RetryableStatusCodes []string      `yaml:"retryable_status_codes"` // e.g. ["UNAVAILABLE"]
+}
+
+// HedgingPolicy configures hedged submissions: if no response arrives within
+// Delay, the same request (same idempotency key) is sent again, and the first
+// successful response wins. Hedging is implemented by the SDK because gRPC-Go
+// does not support the service-config hedgingPolicy.
+type HedgingPolicy struct {
+	MaxAttempts         int           `yaml:"max_attempts"`           // Total requests in flight including the first; <= 1 disables hedging
+	Delay               time.Duration `yaml:"delay"`                  // Wait before sending each additional request
+	NonFatalStatusCodes []string      `yaml:"non_fatal_status_codes"` // Codes that trigger the next request immediately instead of failing
+}
+
+// Config defines how the SDK connects to one or more API Gateway instances
+type Config struct {
+	// Target is a gRPC target URI:
+	//   "dns:///gateway.example.com:50051" resolves all A/AAAA records (DNS round robin)
+	//   "xds:///tlng-gateway" uses an xDS control plane (build with -tags xds)
+	//   "gateway:50051" uses the default resolver (a single address)
+	Target string `yaml:"target"`
+
+	LoadBalancing     string        `yaml:"load_balancing"`      // round_robin or pick_first (ignored for xds targets)
+	HealthCheck       bool          `yaml:"health_check"`        // Skip gateways whose gRPC health service reports NOT_SERVING
+	HealthServiceName string        `yaml:"health_service_name"` // Service name checked by health checking
+	Insecure          bool          `yaml:"insecure"`            // Plaintext connection (development only)
+	Timeout           time.Duration `yaml:"timeout"`             // Per-RPC timeout when the caller's context has none
+	SourceOrgID       string        `yaml:"source_org_id"`       // Default client_source_org_id for submissions
+	Locale            string        `yaml:"locale"`              // Language of localized error messages, e.g. "zh"; empty for none
+
+	Retry   RetryPolicy   `yaml:"retry"`
+	Hedging HedgingPolicy `yaml:"hedging"`
+
+	// ServiceAuth authenticates the agent to gateways that enable service_auth:
+	// with its SPIFFE SVID, or with OIDC client-credentials tokens
+	ServiceAuth svcauth.Config `yaml:"service_auth"`
+}
+
+// SetDefaults fills unset fields with the SDK defaults
+func (c *Config) SetDefaults() {
+	if c.LoadBalancing == "" {
+		c.LoadBalancing = RoundRobin
+	}
+	if c.HealthServiceName == "" {
+		c.HealthServiceName = ingestionService
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	// Submissions carry idempotency keys, so retries are safe and on by default.
+	// Hedging and retries are mutually exclusive, as in gRPC.
+	if c.Retry.MaxAttempts == 0 && c.Hedging.MaxAttempts <= 1 {
+		c.Retry.MaxAttempts = 3
+	}
+	if c.Retry.MaxAttempts > 1 {
+		if c.Retry.InitialBackoff == 0 {
+			c.Retry.InitialBackoff = 100 * time.Millisecond
+		}
+		if c.Retry.MaxBackoff == 0 {
+			c.Retry.MaxBackoff = 2 * time.Second
+		}
+		if c.Retry.BackoffMultiplier == 0 {
+			c.Retry.BackoffMultiplier = 2
+		}
+		if len(c.Retry.RetryableStatusCodes) == 0 {
+			c.Retry.RetryableStatusCodes = []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"}
+		}
+	}
+	c.ServiceAuth.SetDefaults()
+	if c.Hedging.MaxAttempts > 1 {
+		if c.Hedging.Delay == 0 {
+			c.Hedging.Delay = 200 * time.Millisecond
+		}
+		if len(c.Hedging.NonFatalStatusCodes) == 0 {
+			c.Hedging.NonFatalStatusCodes = []string{"UNAVAILABLE", "RESOURCE_EXHAUSTED"}
+		}
+	}
+}
+
+// Validate validates the SDK configuration
+func (c *Config) Validate() error {
+	if c.Target == "" {
+		return fmt.Errorf("target is required")
+	}
+	switch c.LoadBalancing {
+	case RoundRobin, PickFirst:
+	default:
+		return fmt.Errorf("invalid load_balancing '%s' (must be round_robin or pick_first)", c.LoadBalancing)
+	}
+	if c.Retry.MaxAttempts > 5 {
+		// gRPC caps attempts at 5; larger values would be silently reduced
+		return fmt.Errorf("retry.max_attempts must not exceed 5")
+	}
+	if c.Retry.MaxAttempts > 1 && (c.Retry.InitialBackoff <= 0 || c.Retry.MaxBackoff <= 0 || c.Retry.BackoffMultiplier <= 0) {
+		return fmt.Errorf("retry backoff settings must be positive")
+	}
+	if c.Hedging.MaxAttempts > 5 {
+		return fmt.Errorf("hedging.max_attempts must not exceed 5")
+	}
+	if c.Hedging.MaxAttempts > 1 {
+		if c.Retry.MaxAttempts > 1 {
+			return fmt.Errorf("retry and hedging cannot both be enabled (set retry.max_attempts to 1)")
+		}
+		if c.Hedging.Delay <= 0 {
+			return fmt.Errorf("hedging.delay must be positive")
+		}
+		for _, name := range c.Hedging.NonFatalStatusCodes {
+			if _, ok := parseCode(name); !ok {
+				return fmt.Errorf("invalid hedging status code '%s'", name)
+			}
+		}
+	}
+	if c.ServiceAuth.Enabled() {
+		if err := c.ServiceAuth.Validate(); err != nil {
+			return fmt.Errorf("invalid service_auth: %w", err)
+		}
+		if c.Insecure {
+			return fmt.Errorf("service_auth requires TLS (unset insecure)")
+		}
+	}
+	return nil
+}
+
+// parseCode converts a status code name such as "UNAVAILABLE" to a gRPC code
+func parseCode(name string) (codes.Code, bool) {
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(`"` + strings.ToUpper(name) + `"`)); err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// isXDS reports whether the target is resolved through xDS
+func (c *Config) isXDS() bool {
+	return strings.HasPrefix(c.Target, "xds:")
+}
+
+// serviceConfig renders the gRPC service config JSON for this configuration.
+// For xDS targets the control plane supplies load balancing, so only the
+// retry and health settings are rendered.
+func (c *Config) serviceConfig() (string, error) {
+	sc := map[string]interface{}{}
+
+	if !c.isXDS() {
+		sc["loadBalancingConfig"] = []map[string]interface{}{{c.LoadBalancing: map[string]interface{}{}}}
+	}
+	if c.HealthCheck {
+		sc["healthCheckConfig"] = map[string]interface{}{"serviceName": c.HealthServiceName}
+	}
+	if c.Retry.MaxAttempts > 1 {
+		sc["methodConfig"] = []map[string]interface{}{{
+			"name": []map[string]string{{"service": ingestionService}},
+			"retryPolicy": map[string]interface{}{
+				"maxAttempts":          c.Retry.MaxAttempts,
+				"initialBackoff":       durationJSON(c.Retry.InitialBackoff),
+				"maxBackoff":           durationJSON(c.Retry.MaxBackoff),
+				"backoffMultiplier":    c.Retry.BackoffMultiplier,
+				"retryableStatusCodes": c.Retry.RetryableStatusCodes,
+			},
+		}}
+	}
+
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return "", fmt.Errorf("failed to render service config: %w", err)
+	}
+	return string(data), nil
+}
+
+// durationJSON formats a duration as a protobuf JSON duration ("1.5s")
+func durationJSON(d time.Duration) string {
+	return fmt.Sprintf("%gs", d.Seconds())
+}
@@ -0,0 +1,23 @@
+package sdk
+
+import (
+	"tlng/errcatalog"
+	pb "tlng/proto/logingestion"
+)
+
+// CatalogError returns the error catalog code and message parameters of an
+// error returned by SubmitLog or SubmitLogStream, if the gateway sent them.
+// Its Localize method gives the message in any catalog language; with
+// Config.Locale set, errcatalog.LocalizedMessage returns the gateway's.
+func CatalogError(err error) (*errcatalog.Error, bool) {
+	return errcatalog.FromError(err)
+}
+
+// ResultError returns the catalog error of a rejected SubmitLogStream entry,
+// nil if the entry was accepted or the gateway sent no catalog code
+func ResultError(r *pb.SubmitLogStreamResult) *errcatalog.Error {
+	if r.GetErrorCode() == "" {
+		return nil
+	}
+	return &errcatalog.Error{Code: errcatalog.Code(r.GetErrorCode()), Params: r.GetErrorParams(), Message: r.GetError()}
+}
@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"context"
+	"time"
+
+	pb "tlng/proto/logingestion"
+
+	"google.golang.org/grpc/status"
+)
+
+// attemptResult is the outcome of one hedged request
+type attemptResult struct {
+	resp *pb.SubmitLogResponse
+	err  error
+}
+
+// submitHedged sends req up to Hedging.MaxAttempts times, starting a new
+// request every Hedging.Delay (or immediately after a non-fatal failure) until
+// one succeeds. All requests share the idempotency key, so the gateway stores
+// at most one attestation however many of them arrive. Outstanding requests
+// are cancelled once a result is returned.
+func (c *Client) submitHedged(ctx context.Context, req *pb.SubmitLogRequest) (*pb.SubmitLogResponse, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	policy := c.cfg.Hedging
+	results := make(chan attemptResult, policy.MaxAttempts)
+	timer := time.NewTimer(policy.Delay)
+	defer timer.Stop()
+
+	sent, pending := 0, 0
+	send := func() {
+		sent++
+		pending++
+		go func() {
+			resp, err := c.rpc.SubmitLog(ctx, req)
+			results <- attemptResult{resp: resp, err: err}
+		}()
+		if sent < policy.MaxAttempts {
+			timer.Reset(policy.Delay)
+		} else {
+			timer.Stop()
+		}
+	}
+	send()
+
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			send()
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.resp, nil
+			}
+			lastErr = r.err
+			if !c.nonFatal(r.err) {
+				return nil, r.err
+			}
+			if sent < policy.MaxAttempts {
+				send()
+			} else if pending == 0 {
+				return nil, lastErr
+			}
+		}
+	}
+}
+
+// nonFatal reports whether a failed hedged request should trigger the next one
+func (c *Client) nonFatal(err error) bool {
+	code := status.Code(err)
+	for _, name := range c.cfg.Hedging.NonFatalStatusCodes {
+		if nonFatal, ok := parseCode(name); ok && nonFatal == code {
+			return true
+		}
+	}
+	return false
+}
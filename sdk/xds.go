@@ -0,0 +1,10 @@
+//go:build xds
+
+package sdk
+
+import (
+	_ "google.golang.org/grpc/xds" // Registers the xds:/// resolver and xDS balancers
+)
+
+// xdsEnabled reports whether the xDS resolver and balancers are linked in
+const xdsEnabled = true
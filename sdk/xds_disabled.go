@@ -0,0 +1,6 @@
+//go:build !xds
+
+package sdk
+
+// xdsEnabled reports whether the xDS resolver and balancers are linked in
+const xdsEnabled = false
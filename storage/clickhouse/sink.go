@@ -0,0 +1,256 @@
+// Package clickhouse writes status transition events to ClickHouse for
+// operational analytics (throughput per org, latency trends), keeping that
+// query load off the transactional PostgreSQL store. It talks to the
+// ClickHouse HTTP interface and inserts batches in JSONEachRow format.
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/events"
+)
+
+// createTableSQL creates the events table; %s.%s is database.table
+const createTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (
+    event_time     DateTime64(6, 'UTC'),
+    received_at    DateTime64(6, 'UTC'),
+    request_id     String,
+    log_hash       String,
+    source_org_id  LowCardinality(String),
+    region         LowCardinality(String),
+    from_status    LowCardinality(String),
+    to_status      LowCardinality(String),
+    retry_count    UInt16,
+    tx_hash        String,
+    block_height   UInt64,
+    error_message  String
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(event_time)
+ORDER BY (source_org_id, to_status, event_time)`
+
+// clickHouseTime is the DateTime64(6) text format accepted by JSONEachRow
+const clickHouseTime = "2006-01-02 15:04:05.000000"
+
+// row is one event in JSONEachRow format
+type row struct {
+	EventTime    string `json:"event_time"`
+	ReceivedAt   string `json:"received_at"`
+	RequestID    string `json:"request_id"`
+	LogHash      string `json:"log_hash"`
+	SourceOrgID  string `json:"source_org_id"`
+	Region       string `json:"region"`
+	FromStatus   string `json:"from_status"`
+	ToStatus     string `json:"to_status"`
+	RetryCount   int    `json:"retry_count"`
+	TxHash       string `json:"tx_hash"`
+	BlockHeight  uint64 `json:"block_height"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// Sink consumes status events from the event bus and inserts them into ClickHouse
+type Sink struct {
+	cfg        config.ClickHouseConfig
+	client     *http.Client
+	logger     *log.Logger
+	retryDelay time.Duration // Wait before the second attempt of an insert, growing linearly
+
+	buffer   bytes.Buffer // Encoded JSONEachRow lines awaiting insert
+	buffered int
+
+	inserted atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// New creates a new ClickHouse sink
+func New(cfg config.ClickHouseConfig, logger *log.Logger) *Sink {
+	return &Sink{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		logger:     logger,
+		retryDelay: time.Second,
+	}
+}
+
+// EnsureTable creates the events table if it does not exist
+func (s *Sink) EnsureTable(ctx context.Context) error {
+	return s.exec(ctx, "", []byte(fmt.Sprintf(createTableSQL, s.cfg.Database, s.cfg.Table)))
+}
+
+// Inserted returns the number of events written to ClickHouse
+func (s *Sink) Inserted() uint64 {
+	return s.inserted.Load()
+}
+
+// Dropped returns the number of events discarded after failed inserts
+func (s *Sink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Run inserts events from sub in batches until the bus is closed, then flushes
+// what is left. It does not stop on its own so that events published by
+// workers while they drain are still delivered; once ctx is done it stops
+// retrying and drops the events it holds.
+func (s *Sink) Run(ctx context.Context, sub *events.Subscription) {
+	s.logger.Printf("ClickHouse sink started: table=%s.%s, batch_size=%d, flush_interval=%v",
+		s.cfg.Database, s.cfg.Table, s.cfg.BatchSize, s.cfg.FlushInterval)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch, ok := <-sub.C():
+			if !ok {
+				s.flush(ctx)
+				s.logger.Printf("ClickHouse sink stopped: inserted=%d, dropped=%d, dropped_by_bus=%d",
+					s.Inserted(), s.Dropped(), sub.Dropped())
+				return
+			}
+			for i := range batch {
+				s.add(&batch[i])
+			}
+			if s.buffered >= s.cfg.BatchSize {
+				s.flush(ctx)
+			}
+
+		case <-ticker.C:
+			s.flush(ctx)
+
+		case <-ctx.Done():
+			s.drop(ctx.Err())
+			s.logger.Printf("ClickHouse sink cancelled: inserted=%d, dropped=%d, dropped_by_bus=%d",
+				s.Inserted(), s.Dropped(), sub.Dropped())
+			return
+		}
+	}
+}
+
+// add encodes one event into the buffer
+func (s *Sink) add(e *events.StatusEvent) {
+	line, err := json.Marshal(row{
+		EventTime:    e.At.UTC().Format(clickHouseTime),
+		ReceivedAt:   e.ReceivedAt.UTC().Format(clickHouseTime),
+		RequestID:    e.RequestID,
+		LogHash:      e.LogHash,
+		SourceOrgID:  e.SourceOrgID,
+		Region:       e.Region,
+		FromStatus:   string(e.From),
+		ToStatus:     string(e.To),
+		RetryCount:   e.RetryCount,
+		TxHash:       e.TxHash,
+		BlockHeight:  e.BlockHeight,
+		ErrorMessage: e.Error,
+	})
+	if err != nil {
+		s.dropped.Add(1)
+		return
+	}
+	s.buffer.Write(line)
+	s.buffer.WriteByte('\n')
+	s.buffered++
+}
+
+// flush inserts the buffered events. Analytics are best effort: after
+// max_retries failed attempts, or once ctx is done, the batch is dropped so
+// the buffer stays bounded.
+func (s *Sink) flush(ctx context.Context) {
+	if s.buffered == 0 {
+		return
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", s.cfg.Database, s.cfg.Table)
+
+	var err error
+	for attempt := 1; attempt <= s.cfg.MaxRetries; attempt++ {
+		insertCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+		err = s.exec(insertCtx, query, s.buffer.Bytes())
+		cancel()
+		if err == nil {
+			s.inserted.Add(uint64(s.buffered))
+			s.reset()
+			return
+		}
+		if attempt == s.cfg.MaxRetries {
+			break
+		}
+		if waitErr := wait(ctx, time.Duration(attempt)*s.retryDelay); waitErr != nil {
+			err = fmt.Errorf("%w (last error: %w)", waitErr, err)
+			break
+		}
+	}
+	s.drop(err)
+}
+
+// wait waits for d, returning early with the error of ctx once it is done
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drop discards the buffered events after they could not be inserted
+func (s *Sink) drop(err error) {
+	if s.buffered == 0 {
+		return
+	}
+	s.logger.Printf("ClickHouse sink: Dropping %d events after failed insert: %v", s.buffered, err)
+	s.dropped.Add(uint64(s.buffered))
+	s.reset()
+}
+
+func (s *Sink) reset() {
+	s.buffer.Reset()
+	s.buffered = 0
+}
+
+// exec sends a statement over the HTTP interface. With a query, body is the
+// INSERT data; without one, body is the statement itself.
+func (s *Sink) exec(ctx context.Context, query string, body []byte) error {
+	u, err := url.Parse(s.cfg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid clickhouse url: %w", err)
+	}
+	params := u.Query()
+	params.Set("database", s.cfg.Database)
+	if query != "" {
+		params.Set("query", query)
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build clickhouse request: %w", err)
+	}
+	if s.cfg.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.cfg.Username)
+		req.Header.Set("X-ClickHouse-Key", s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("clickhouse request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("clickhouse returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,262 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"tlng/config"
+	"tlng/internal/events"
+	"tlng/storage/store"
+)
+
+// insert is a request received by the ClickHouse stub
+type insert struct {
+	query, database, user, key string
+	rows                       []row
+}
+
+// stub serves the ClickHouse HTTP interface, failing the first fail requests
+type stub struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	fail     int
+	block    chan struct{} // When set, requests wait for it to be closed
+	inserts  []insert
+	received chan struct{}
+}
+
+func newStub(t *testing.T) *stub {
+	s := &stub{received: make(chan struct{}, 100)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stub) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	in := insert{
+		query:    r.URL.Query().Get("query"),
+		database: r.URL.Query().Get("database"),
+		user:     r.Header.Get("X-ClickHouse-User"),
+		key:      r.Header.Get("X-ClickHouse-Key"),
+	}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		var rw row
+		if err := json.Unmarshal(sc.Bytes(), &rw); err != nil {
+			s.mu.Unlock()
+			http.Error(w, "Cannot parse input: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		in.rows = append(in.rows, rw)
+	}
+	s.inserts = append(s.inserts, in)
+	failed := s.fail > 0
+	if failed {
+		s.fail--
+	}
+	block := s.block
+	s.mu.Unlock()
+	s.received <- struct{}{}
+
+	if block != nil {
+		<-block
+	}
+	if failed {
+		http.Error(w, "Code: 241. DB::Exception: Memory limit exceeded", http.StatusInternalServerError)
+	}
+}
+
+func (s *stub) requests() []insert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]insert(nil), s.inserts...)
+}
+
+func newTestSink(url string, edit func(*config.ClickHouseConfig)) *Sink {
+	cfg := config.ClickHouseConfig{
+		URL: url, Database: "analytics", Table: "status_events", Username: "tlng", Password: "secret",
+		BatchSize: 3, FlushInterval: time.Hour, BufferSize: 16, Timeout: 5 * time.Second, MaxRetries: 3,
+	}
+	if edit != nil {
+		edit(&cfg)
+	}
+	s := New(cfg, log.New(io.Discard, "", 0))
+	s.retryDelay = time.Millisecond
+	return s
+}
+
+func testEvents(from, n int) []events.StatusEvent {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	batch := make([]events.StatusEvent, n)
+	for i := range batch {
+		batch[i] = events.StatusEvent{
+			RequestID: fmt.Sprintf("req-%d", from+i), LogHash: fmt.Sprintf("hash-%d", from+i),
+			SourceOrgID: "org1", Region: "eu", From: store.StatusProcessing, To: store.StatusCompleted,
+			ReceivedAt: at.Add(-time.Second), At: at, RetryCount: 1, TxHash: "tx-1", BlockHeight: 42,
+		}
+	}
+	return batch
+}
+
+// run starts the sink on a new bus and returns the bus and a channel closed when Run returns
+func run(ctx context.Context, s *Sink, buffer int) (*events.Bus, *events.Subscription, chan struct{}) {
+	bus := events.NewBus()
+	sub := bus.Subscribe("clickhouse", buffer)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx, sub)
+	}()
+	return bus, sub, done
+}
+
+func waitDone(t *testing.T, done chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
+
+func TestSinkBatchesJSONEachRow(t *testing.T) {
+	ch := newStub(t)
+	s := newTestSink(ch.URL, nil)
+	bus, _, done := run(context.Background(), s, 16)
+
+	bus.Publish(testEvents(0, 2)) // Below batch_size: buffered
+	bus.Publish(testEvents(2, 2)) // Reaches batch_size: one insert of all four
+	<-ch.received
+	bus.Publish(testEvents(4, 1)) // Flushed when the bus closes
+	bus.Close()
+	waitDone(t, done)
+
+	inserts := ch.requests()
+	if len(inserts) != 2 || len(inserts[0].rows) != 4 || len(inserts[1].rows) != 1 {
+		t.Fatalf("inserts = %+v, want batches of 4 and 1 rows", inserts)
+	}
+	in := inserts[0]
+	if in.query != "INSERT INTO analytics.status_events FORMAT JSONEachRow" || in.database != "analytics" {
+		t.Errorf("query = %q, database = %q", in.query, in.database)
+	}
+	if in.user != "tlng" || in.key != "secret" {
+		t.Errorf("credentials = %q/%q, want tlng/secret", in.user, in.key)
+	}
+	want := row{
+		EventTime: "2026-03-04 05:06:07.123456", ReceivedAt: "2026-03-04 05:06:06.123456",
+		RequestID: "req-0", LogHash: "hash-0", SourceOrgID: "org1", Region: "eu",
+		FromStatus: string(store.StatusProcessing), ToStatus: string(store.StatusCompleted),
+		RetryCount: 1, TxHash: "tx-1", BlockHeight: 42,
+	}
+	if in.rows[0] != want {
+		t.Errorf("row = %+v, want %+v", in.rows[0], want)
+	}
+	if s.Inserted() != 5 || s.Dropped() != 0 {
+		t.Errorf("inserted = %d, dropped = %d; want 5, 0", s.Inserted(), s.Dropped())
+	}
+}
+
+func TestSinkRetriesFailedInserts(t *testing.T) {
+	ch := newStub(t)
+	ch.fail = 2
+	s := newTestSink(ch.URL, nil)
+	bus, _, done := run(context.Background(), s, 16)
+
+	bus.Publish(testEvents(0, 3))
+	bus.Close()
+	waitDone(t, done)
+
+	inserts := ch.requests()
+	if len(inserts) != 3 {
+		t.Fatalf("%d insert attempts, want 3", len(inserts))
+	}
+	for _, in := range inserts {
+		if len(in.rows) != 3 || in.rows[0].RequestID != "req-0" {
+			t.Errorf("retried insert = %+v, want the same 3 rows", in.rows)
+		}
+	}
+	if s.Inserted() != 3 || s.Dropped() != 0 {
+		t.Errorf("inserted = %d, dropped = %d; want 3, 0", s.Inserted(), s.Dropped())
+	}
+}
+
+func TestSinkDropsAfterMaxRetries(t *testing.T) {
+	ch := newStub(t)
+	ch.fail = 2
+	s := newTestSink(ch.URL, func(c *config.ClickHouseConfig) { c.MaxRetries = 2 })
+	bus, _, done := run(context.Background(), s, 16)
+
+	bus.Publish(testEvents(0, 3)) // Fails twice: dropped
+	<-ch.received
+	<-ch.received
+	bus.Publish(testEvents(3, 3)) // Inserted alone, without the dropped rows
+	bus.Close()
+	waitDone(t, done)
+
+	inserts := ch.requests()
+	if len(inserts) != 3 {
+		t.Fatalf("%d insert attempts, want 3", len(inserts))
+	}
+	if last := inserts[2].rows; len(last) != 3 || last[0].RequestID != "req-3" {
+		t.Errorf("insert after the drop = %+v, want req-3 to req-5", last)
+	}
+	if s.Inserted() != 3 || s.Dropped() != 3 {
+		t.Errorf("inserted = %d, dropped = %d; want 3, 3", s.Inserted(), s.Dropped())
+	}
+}
+
+func TestSinkStopsRetryingWhenCancelled(t *testing.T) {
+	ch := newStub(t)
+	ch.fail = 100
+	s := newTestSink(ch.URL, func(c *config.ClickHouseConfig) { c.MaxRetries = 5 })
+	s.retryDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	bus, _, done := run(ctx, s, 16)
+
+	bus.Publish(testEvents(0, 3))
+	<-ch.received // The first attempt failed; the sink now waits an hour to retry
+	cancel()
+	waitDone(t, done)
+
+	if n := len(ch.requests()); n != 1 {
+		t.Errorf("%d insert attempts, want 1", n)
+	}
+	if s.Inserted() != 0 || s.Dropped() != 3 {
+		t.Errorf("inserted = %d, dropped = %d; want 0, 3", s.Inserted(), s.Dropped())
+	}
+}
+
+func TestSinkDropsEventsWhenBufferFull(t *testing.T) {
+	ch := newStub(t)
+	ch.block = make(chan struct{})
+	s := newTestSink(ch.URL, func(c *config.ClickHouseConfig) { c.BatchSize = 1 })
+	bus, sub, done := run(context.Background(), s, 1)
+
+	bus.Publish(testEvents(0, 2)) // Taken by the sink, whose insert hangs
+	<-ch.received
+	bus.Publish(testEvents(2, 2)) // Queued in the subscription buffer
+	bus.Publish(testEvents(4, 2)) // Buffer full: dropped without blocking
+	close(ch.block)
+	bus.Close()
+	waitDone(t, done)
+
+	if sub.Dropped() != 2 {
+		t.Errorf("dropped by the bus = %d, want 2", sub.Dropped())
+	}
+	if s.Inserted() != 4 || s.Dropped() != 0 {
+		t.Errorf("inserted = %d, dropped = %d; want 4, 0", s.Inserted(), s.Dropped())
+	}
+}
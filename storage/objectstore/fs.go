@@ -0,0 +1,54 @@
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FSStore stores objects as files below a root directory
+type FSStore struct {
+	root   string
+	prefix string
+	logger *log.Logger
+}
+
+// NewFSStore creates a filesystem-backed object store
+func NewFSStore(root, prefix string, logger *log.Logger) (*FSStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create object store directory %s: %w", root, err)
+	}
+	logger.Printf("Filesystem object store initialized at %s", root)
+	return &FSStore{root: root, prefix: prefix, logger: logger}, nil
+}
+
+// Put writes the object atomically via a temporary file and rename
+func (s *FSStore) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	dest := filepath.Join(s.root, filepath.FromSlash(path.Join(s.prefix, key)))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for object %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for object %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write object %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to commit object %s: %w", key, err)
+	}
+	return nil
+}
+
+var _ ObjectStore = (*FSStore)(nil) // Compile-time interface check
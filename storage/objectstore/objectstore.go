@@ -0,0 +1,28 @@
+// Package objectstore writes immutable objects to S3, GCS or a local directory.
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"tlng/config"
+)
+
+// ObjectStore is the object storage interface
+type ObjectStore interface {
+	// Put uploads an object, replacing any existing object with the same key
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+}
+
+// New creates the object store selected by cfg.Backend
+func New(cfg config.ObjectStoreConfig, logger *log.Logger) (ObjectStore, error) {
+	switch cfg.Backend {
+	case "s3", "gcs":
+		return NewS3Store(cfg, logger)
+	case "fs":
+		return NewFSStore(cfg.Directory, cfg.Prefix, logger)
+	default:
+		return nil, fmt.Errorf("unsupported object store backend: %s", cfg.Backend)
+	}
+}
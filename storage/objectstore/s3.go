@@ -0,0 +1,69 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"tlng/config"
+)
+
+// S3Store uploads objects through the S3 API with minio-go, which switches to
+// multipart uploads for large objects. It also serves GCS through its
+// S3-compatible XML API (HMAC keys) and S3-compatible servers such as MinIO.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	logger *log.Logger
+}
+
+// NewS3Store creates an S3-compatible object store
+func NewS3Store(cfg config.ObjectStoreConfig, logger *log.Logger) (*S3Store, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid object store endpoint '%s'", cfg.Endpoint)
+	}
+
+	lookup := minio.BucketLookupDNS
+	if cfg.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure:       endpoint.Scheme == "https",
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object store client: %w", err)
+	}
+
+	logger.Printf("Object store initialized: backend=%s endpoint=%s bucket=%s", cfg.Backend, cfg.Endpoint, cfg.Bucket)
+	return &S3Store{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: cfg.Prefix,
+		logger: logger,
+	}, nil
+}
+
+// Put uploads an object, in parts if it is too large for a single request
+func (s *S3Store) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	objectKey := path.Join(s.prefix, key)
+	_, err := s.client.PutObject(ctx, s.bucket, objectKey, bytes.NewReader(body), int64(len(body)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload object %s: %w", objectKey, err)
+	}
+	return nil
+}
+
+var _ ObjectStore = (*S3Store)(nil) // Compile-time interface check
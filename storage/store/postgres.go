@@ -4,9 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -17,6 +22,9 @@ import (
 type PostgresStore struct {
 	db     *pgxpool.Pool
 	logger *log.Logger
+
+	schema   SchemaInfo
+	features Features // Optional columns and tables available in the database
 }
 
 // NewPostgresStore creates a new PostgresStore instance
@@ -54,7 +62,168 @@ func NewPostgresStore(ctx context.Context, dsn string, maxConns, minConns int, l
 	}
 
 	logger.Println("Successfully connected to PostgreSQL database")
-	return &PostgresStore{db: dbpool, logger: logger}, nil
+
+	// Refuse to run against a schema this binary cannot read or write
+	schema, versioned, err := loadSchemaInfo(ctx, dbpool)
+	if err != nil {
+		dbpool.Close()
+		return nil, err
+	}
+	if !versioned {
+		logger.Println("Warning: tbl_schema_version not found, assuming schema v1; run scripts/db/init-db.sql to enable newer features")
+	}
+	if err := schema.CheckCompatible(); err != nil {
+		dbpool.Close()
+		return nil, fmt.Errorf("%w: %v", ErrIncompatibleSchema, err)
+	}
+	features := schema.Features()
+	logger.Printf("Database schema v%d (min compatible v%d, binary v%d), features: [%s]",
+		schema.Version, schema.MinCompatible, SchemaVersion, features)
+	if schema.Version < SchemaVersion {
+		logger.Printf("Warning: database schema v%d is behind this binary (v%d); newer features are disabled until migrations are applied",
+			schema.Version, SchemaVersion)
+	}
+	if schema.DualWriteWindow() {
+		logger.Printf("Schema dual-write window open: binaries built for v%d..v%d may run concurrently", schema.MinCompatible, schema.Version)
+	}
+
+	return &PostgresStore{db: dbpool, logger: logger, schema: schema, features: features}, nil
+}
+
+// Schema returns the database schema version the store was opened with
+func (s *PostgresStore) Schema() SchemaInfo {
+	return s.schema
+}
+
+// optionalUpdates returns the ON CONFLICT DO UPDATE assignments for optional
+// tbl_log_status columns present in the schema
+func (s *PostgresStore) optionalUpdates() string {
+	var updates string
+	if s.features.Has(FeatureRegion) {
+		updates += "\n                region = EXCLUDED.region,"
+	}
+	if s.features.Has(FeatureClientTimestamp) {
+		updates += "\n                client_timestamp = EXCLUDED.client_timestamp,"
+	}
+	if s.features.Has(FeatureBatchID) {
+		updates += "\n                gateway_batch_id = EXCLUDED.gateway_batch_id,\n                engine_batch_id = NULL,"
+	}
+	if s.features.Has(FeatureLogFields) {
+		updates += "\n                severity = EXCLUDED.severity,\n                source_host = EXCLUDED.source_host,\n                application = EXCLUDED.application,"
+	}
+	if s.features.Has(FeatureTestTraffic) {
+		updates += "\n                test_traffic = EXCLUDED.test_traffic,"
+	}
+	if s.features.Has(FeatureSequence) {
+		updates += "\n                sequence = EXCLUDED.sequence,"
+	}
+	if s.features.Has(FeatureExternalID) {
+		updates += "\n                external_id = EXCLUDED.external_id,"
+	}
+	return updates
+}
+
+// optionalColumns returns the select list for optional tbl_log_status columns,
+// substituting empty values for columns missing from the schema
+func (s *PostgresStore) optionalColumns() string {
+	region, clientTimestamp, batchIDs, logFields, testTraffic, sequence, externalID := "''", "NULL::timestamptz", "'', ''", "'', '', ''", "false", "0::bigint", "''"
+	if s.features.Has(FeatureRegion) {
+		region = "COALESCE(region, '')"
+	}
+	if s.features.Has(FeatureClientTimestamp) {
+		clientTimestamp = "client_timestamp"
+	}
+	if s.features.Has(FeatureBatchID) {
+		batchIDs = "COALESCE(gateway_batch_id, ''), COALESCE(engine_batch_id, '')"
+	}
+	if s.features.Has(FeatureLogFields) {
+		logFields = "COALESCE(severity, ''), COALESCE(source_host, ''), COALESCE(application, '')"
+	}
+	if s.features.Has(FeatureTestTraffic) {
+		testTraffic = "test_traffic"
+	}
+	if s.features.Has(FeatureSequence) {
+		sequence = "COALESCE(sequence, 0)"
+	}
+	if s.features.Has(FeatureExternalID) {
+		externalID = "COALESCE(external_id, '')"
+	}
+	return region + ", " + clientTimestamp + ", " + batchIDs + ", " + logFields + ", " + testTraffic + ", " + sequence + ", " + externalID
+}
+
+// Ping verifies that the database is reachable
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// ErrReadOnly indicates that the database rejects writes, e.g. a primary
+// demoted to standby or not yet promoted during failover
+var ErrReadOnly = errors.New("database is read-only")
+
+// sqlStateReadOnly is PostgreSQL's read_only_sql_transaction error code
+const sqlStateReadOnly = "25006"
+
+// PostgreSQL error codes of writes that conflict with stored or concurrent ones
+const (
+	sqlStateUniqueViolation      = "23505"
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// requestIDConstraints are the primary keys on request_id
+var requestIDConstraints = map[string]bool{
+	"tbl_log_status_pkey":  true,
+	"tbl_local_queue_pkey": true,
+	"tbl_outbox_pkey":      true,
+}
+
+// storeError attaches a typed store error to the driver error it classifies
+type storeError struct {
+	kind error
+	err  error
+}
+
+func (e *storeError) Error() string   { return e.err.Error() }
+func (e *storeError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// classifyError attaches ErrDuplicateRequestID or ErrConflict to a failed
+// write, keeping its message; other errors are returned as they are
+func classifyError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch {
+	case pgErr.Code == sqlStateUniqueViolation && requestIDConstraints[pgErr.ConstraintName]:
+		return &storeError{kind: ErrDuplicateRequestID, err: err}
+	case pgErr.Code == sqlStateUniqueViolation, pgErr.Code == sqlStateSerializationFailure, pgErr.Code == sqlStateDeadlockDetected:
+		return &storeError{kind: ErrConflict, err: err}
+	}
+	return err
+}
+
+// IsReadOnly reports whether err is a write rejected by a read-only database
+func IsReadOnly(err error) bool {
+	if errors.Is(err, ErrReadOnly) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == sqlStateReadOnly
+}
+
+// Writable checks that the server is not in recovery and that new
+// transactions are not read-only by default
+func (s *PostgresStore) Writable(ctx context.Context) error {
+	var readOnly bool
+	err := s.db.QueryRow(ctx,
+		"SELECT pg_is_in_recovery() OR current_setting('transaction_read_only') = 'on'").Scan(&readOnly)
+	if err != nil {
+		return fmt.Errorf("failed to check whether the database is writable: %w", err)
+	}
+	if readOnly {
+		return ErrReadOnly
+	}
+	return nil
 }
 
 // Close closes the database connection pool
@@ -65,7 +234,7 @@ func (s *PostgresStore) Close() {
 
 // GetAndMarkBatchAsProcessing uses a single atomic CTE query to lock, filter,
 // update, and return tasks ready for processing.
-func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, requestIDs []string, maxRetries int) (map[string]*LogStatus, error) {
+func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, requestIDs []string, maxRetries int, batchID, instanceID string) (map[string]*LogStatus, error) {
 	if len(requestIDs) == 0 {
 		return make(map[string]*LogStatus), nil
 	}
@@ -74,6 +243,26 @@ func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, request
 	now := time.Now()
 	failedReason := fmt.Sprintf("reached maximum retry count (%d)", maxRetries)
 
+	// The engine batch and instance are only recorded if the schema has the columns (see schema.go)
+	args := []interface{}{
+		requestIDs,       // $1
+		StatusReceived,   // $2
+		StatusFailed,     // $3
+		failedReason,     // $4
+		now,              // $5
+		maxRetries,       // $6
+		StatusProcessing, // $7
+	}
+	var batchUpdate string
+	if s.features.Has(FeatureBatchID) {
+		args = append(args, batchID)
+		batchUpdate = ",\n            engine_batch_id = NULLIF($8, '')"
+	}
+	if s.features.Has(FeatureFleet) {
+		args = append(args, instanceID)
+		batchUpdate += fmt.Sprintf(",\n            engine_instance_id = NULLIF($%d, '')", len(args))
+	}
+
 	atomicQuery := `
         WITH locked_rows AS (
             -- 1. Lock and select only the tasks we care about
@@ -91,23 +280,36 @@ func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, request
             FROM locked_rows
             WHERE tbl_log_status.request_id = locked_rows.request_id
               AND locked_rows.retry_count >= $6 -- maxRetries
+            RETURNING
+                tbl_log_status.request_id,
+                tbl_log_status.log_hash,
+                tbl_log_status.source_org_id,
+                tbl_log_status.received_timestamp,
+                tbl_log_status.status, -- Will be 'FAILED'
+                tbl_log_status.retry_count,
+                tbl_log_status.processing_started_at
+        ),
+        processing_tasks AS (
+            -- 3. Update tasks that are ready for processing
+            UPDATE tbl_log_status
+            SET status = $7, -- StatusProcessing
+                processing_started_at = $5` + batchUpdate + `
+            FROM locked_rows
+            WHERE tbl_log_status.request_id = locked_rows.request_id
+              AND locked_rows.retry_count < $6 -- maxRetries
+            RETURNING
+                tbl_log_status.request_id,
+                tbl_log_status.log_hash,
+                tbl_log_status.source_org_id,
+                tbl_log_status.received_timestamp,
+                tbl_log_status.status, -- Will be 'PROCESSING'
+                tbl_log_status.retry_count,
+                tbl_log_status.processing_started_at
         )
-        -- 3. Update tasks that are ready for processing
-        UPDATE tbl_log_status
-        SET status = $7, -- StatusProcessing
-            processing_started_at = $5 -- now
-        FROM locked_rows
-        WHERE tbl_log_status.request_id = locked_rows.request_id
-          AND locked_rows.retry_count < $6 -- maxRetries
-        -- 4. Return *only* the tasks we just marked for processing
-        RETURNING
-            tbl_log_status.request_id,
-            tbl_log_status.log_hash,
-            tbl_log_status.source_org_id,
-            tbl_log_status.received_timestamp,
-            tbl_log_status.status, -- Will be 'PROCESSING'
-            tbl_log_status.retry_count,
-            tbl_log_status.processing_started_at;
+        -- 4. Return the tasks we just marked for processing, and those we failed
+        SELECT * FROM processing_tasks
+        UNION ALL
+        SELECT * FROM failed_tasks;
     `
 
 	// We keep your original BeginFunc pattern for transactional safety
@@ -118,15 +320,7 @@ func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, request
 
 		// Execute the single atomic query
 		// We pass requestIDs ([]string) directly as the $1 argument
-		rows, err := tx.Query(ctx, atomicQuery,
-			requestIDs,       // $1
-			StatusReceived,   // $2
-			StatusFailed,     // $3
-			failedReason,     // $4
-			now,              // $5
-			maxRetries,       // $6
-			StatusProcessing, // $7
-		)
+		rows, err := tx.Query(ctx, atomicQuery, args...)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return nil // No rows were returned (none to process), not an error
@@ -138,7 +332,6 @@ func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, request
 		// Scan the rows that were returned by the RETURNING clause
 		for rows.Next() {
 			var task LogStatus
-			var processingStartedAt time.Time // Use non-pointer for scanning
 
 			if err := rows.Scan(
 				&task.RequestID,
@@ -147,12 +340,17 @@ func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, request
 				&task.ReceivedTimestamp,
 				&task.Status,
 				&task.RetryCount,
-				&processingStartedAt, // Scan into the local variable
+				&task.ProcessingStartedAt,
 			); err != nil {
 				return fmt.Errorf("failed to scan processed task row: %w", err)
 			}
 
-			task.ProcessingStartedAt = &processingStartedAt // Assign pointer
+			if task.Status == StatusFailed {
+				task.ErrorMessage = &failedReason
+				task.ProcessingFinishedAt = &now
+			} else if s.features.Has(FeatureBatchID) {
+				task.EngineBatchID = batchID
+			}
 			processingTasks[task.RequestID] = &task
 		}
 		if rows.Err() != nil {
@@ -165,7 +363,7 @@ func (s *PostgresStore) GetAndMarkBatchAsProcessing(ctx context.Context, request
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
 	return processingTasks, nil
@@ -205,9 +403,9 @@ func (s *PostgresStore) MarkBatchAsCompleted(ctx context.Context, completions []
             FROM (
                 SELECT 
                     request_id,
-                    ($3::text[])[idx] AS tx_hash,
+                    NULLIF(($3::text[])[idx], '') AS tx_hash,
                     ($4::text[])[idx] AS log_hash,
-                    ($5::bigint[])[idx] AS block_height
+                    NULLIF(($5::bigint[])[idx], 0) AS block_height
                 FROM
                     UNNEST($2::text[]) WITH ORDINALITY AS t(request_id, idx)
             ) AS data
@@ -236,7 +434,7 @@ func (s *PostgresStore) MarkBatchAsCompleted(ctx context.Context, completions []
 	})
 
 	if err != nil {
-		return fmt.Errorf("batch completion update failed: %w", err)
+		return classifyError(fmt.Errorf("batch completion update failed: %w", err))
 	}
 
 	return nil
@@ -308,7 +506,7 @@ func (s *PostgresStore) MarkBatchAsFailed(ctx context.Context, failures []Failur
 	})
 
 	if err != nil {
-		return fmt.Errorf("batch failure update failed: %w", err)
+		return classifyError(fmt.Errorf("batch failure update failed: %w", err))
 	}
 
 	return nil
@@ -337,36 +535,220 @@ func (s *PostgresStore) MarkBatchForRetry(ctx context.Context, requestIDs []stri
 		s.logger.Printf("Attempted to mark %d tasks as RETRY, actually updated %d rows", len(requestIDs), cmdTag.RowsAffected())
 		return nil
 	})
-	return err
+	return classifyError(err)
 }
 
-// InsertLogStatusBatch performs a high-performance bulk insertion using UNNEST
-func (s *PostgresStore) InsertLogStatusBatch(ctx context.Context, statuses []*LogStatus) error {
-	if len(statuses) == 0 {
+// MarkBatchDeferred restores a batch of tasks to Received, keeping their retry count
+func (s *PostgresStore) MarkBatchDeferred(ctx context.Context, requestIDs []string, reason string) error {
+	if len(requestIDs) == 0 {
 		return nil
 	}
 
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := `
+        UPDATE tbl_log_status
+        SET status = $1, error_message = $2, processing_started_at = NULL
+        WHERE request_id = ANY($3) AND status = $4
+    `
+	cmdTag, err := s.db.Exec(queryCtx, query, StatusReceived, reason, requestIDs, StatusProcessing)
+	if err != nil {
+		return classifyError(fmt.Errorf("failed to batch mark tasks as deferred: %w", err))
+	}
+	s.logger.Printf("Attempted to mark %d tasks as deferred, actually updated %d rows", len(requestIDs), cmdTag.RowsAffected())
+	return nil
+}
+
+// RequeueFailedTasks returns FAILED tasks to RECEIVED with a fresh retry budget,
+// and returns them along with the given tasks that were RECEIVED already
+func (s *PostgresStore) RequeueFailedTasks(ctx context.Context, requestIDs []string) ([]string, error) {
+	if len(requestIDs) == 0 {
+		return nil, nil
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// The outer SELECT sees the rows as they were before the UPDATE
+	query := `
+        WITH requeued AS (
+            UPDATE tbl_log_status
+            SET status = $1, retry_count = 0, error_message = NULL,
+                processing_started_at = NULL, processing_finished_at = NULL
+            WHERE request_id = ANY($2) AND status = $3
+            RETURNING request_id
+        )
+        SELECT request_id FROM requeued
+        UNION
+        SELECT request_id FROM tbl_log_status WHERE request_id = ANY($2) AND status = $1
+        ORDER BY request_id
+    `
+	rows, err := s.db.Query(queryCtx, query, StatusReceived, requestIDs, StatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue failed tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var requeued []string
+	for rows.Next() {
+		var requestID string
+		if err := rows.Scan(&requestID); err != nil {
+			return nil, fmt.Errorf("failed to scan requeued task: %w", err)
+		}
+		requeued = append(requeued, requestID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to requeue failed tasks: %w", err)
+	}
+	return requeued, nil
+}
+
+// CountRetryBacklog returns the number of RECEIVED tasks that have already failed at least once.
+// The predicate is spelled out as in idx_log_status_retry_backlog, so that
+// generic plans can use the partial index too.
+func (s *PostgresStore) CountRetryBacklog(ctx context.Context) (int64, error) {
+	query := `SELECT COUNT(*) FROM tbl_log_status WHERE status = 'RECEIVED' AND retry_count > 0`
+
+	var count int64
+	if err := s.db.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count retry backlog: %w", err)
+	}
+	return count, nil
+}
+
+// InsertLogStatusBatch performs a high-performance bulk insertion using UNNEST.
+// Duplicate request_ids (WAL replay, client retries) are resolved according to policy.
+func (s *PostgresStore) InsertLogStatusBatch(ctx context.Context, statuses []*LogStatus, policy ConflictPolicy) (*InsertResult, error) {
+	if len(statuses) == 0 {
+		return &InsertResult{}, nil
+	}
+
 	// 10-15s might be safer for a single large query
 	queryCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	// 1. Prepare parallel slices for all columns
-	requestIDs := make([]string, len(statuses))
-	logHashes := make([]string, len(statuses))
-	sourceOrgIDs := make([]string, len(statuses))
-	receivedTimestamps := make([]time.Time, len(statuses))
-	statusStrings := make([]string, len(statuses))
+	result, err := s.insertLogStatusBatch(queryCtx, s.db, statuses, policy)
+	return result, classifyError(err)
+}
+
+// insertLogStatusBatch inserts statuses with db, the pool or a transaction
+func (s *PostgresStore) insertLogStatusBatch(ctx context.Context, db interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}, statuses []*LogStatus, policy ConflictPolicy) (*InsertResult, error) {
+	result := &InsertResult{}
+
+	var conflictClause string
+	switch policy {
+	case "", ConflictDoNothing:
+		// No conflict target, so a row whose external ID is taken is skipped too
+		conflictClause = "ON CONFLICT DO NOTHING"
+	case ConflictUpdate:
+		// Only rows that have not been anchored yet may be overwritten
+		conflictClause = `ON CONFLICT (request_id) DO UPDATE
+            SET log_hash = EXCLUDED.log_hash,
+                source_org_id = EXCLUDED.source_org_id,
+                received_timestamp = EXCLUDED.received_timestamp,
+                status = EXCLUDED.status,
+                retry_count = 0,` + s.optionalUpdates() + `
+                processing_started_at = NULL,
+                processing_finished_at = NULL,
+                error_message = NULL
+            WHERE tbl_log_status.status IN ('RECEIVED', 'FAILED')`
+	default:
+		return nil, fmt.Errorf("unsupported conflict policy: %s", policy)
+	}
+
+	// 1. Prepare parallel slices for all columns, dropping duplicates within the batch
+	// (a single INSERT ... ON CONFLICT DO UPDATE cannot touch the same row twice)
+	requestIDs := make([]string, 0, len(statuses))
+	logHashes := make([]string, 0, len(statuses))
+	sourceOrgIDs := make([]string, 0, len(statuses))
+	receivedTimestamps := make([]time.Time, 0, len(statuses))
+	statusStrings := make([]string, 0, len(statuses))
+	regions := make([]string, 0, len(statuses))
+	clientTimestamps := make([]*time.Time, 0, len(statuses))
+	batchIDs := make([]string, 0, len(statuses))
+	severities := make([]string, 0, len(statuses))
+	sourceHosts := make([]string, 0, len(statuses))
+	applications := make([]string, 0, len(statuses))
+	testTraffic := make([]bool, 0, len(statuses))
+	sequences := make([]int64, 0, len(statuses))
+	externalIDs := make([]string, 0, len(statuses))
 	// retry_count is static (0), so we don't need a slice for it
 
-	for i, status := range statuses {
-		requestIDs[i] = status.RequestID
-		logHashes[i] = status.LogHash
-		sourceOrgIDs[i] = status.SourceOrgID
-		receivedTimestamps[i] = status.ReceivedTimestamp
-		statusStrings[i] = string(status.Status)
+	seen := make(map[string]struct{}, len(statuses))
+	for _, status := range statuses {
+		if _, dup := seen[status.RequestID]; dup {
+			result.Skipped = append(result.Skipped, status.RequestID)
+			continue
+		}
+		seen[status.RequestID] = struct{}{}
+
+		requestIDs = append(requestIDs, status.RequestID)
+		logHashes = append(logHashes, status.LogHash)
+		sourceOrgIDs = append(sourceOrgIDs, status.SourceOrgID)
+		receivedTimestamps = append(receivedTimestamps, status.ReceivedTimestamp)
+		statusStrings = append(statusStrings, string(status.Status))
+		regions = append(regions, status.Region)
+		clientTimestamps = append(clientTimestamps, status.ClientTimestamp)
+		batchIDs = append(batchIDs, status.GatewayBatchID)
+		severities = append(severities, status.Severity)
+		sourceHosts = append(sourceHosts, status.SourceHost)
+		applications = append(applications, status.Application)
+		testTraffic = append(testTraffic, status.TestTraffic)
+		sequences = append(sequences, status.Sequence)
+		externalIDs = append(externalIDs, status.ExternalID)
+	}
+
+	// Optional columns are only written if the schema has them (see schema.go)
+	args := []interface{}{
+		requestIDs,         // $1
+		logHashes,          // $2
+		sourceOrgIDs,       // $3
+		receivedTimestamps, // $4
+		statusStrings,      // $5
+	}
+	var optionalColumns, optionalValues string
+	if s.features.Has(FeatureRegion) {
+		args = append(args, regions)
+		optionalColumns += ", region"
+		optionalValues += fmt.Sprintf(", NULLIF(($%d::text[])[idx], '') AS region", len(args))
+	}
+	if s.features.Has(FeatureClientTimestamp) {
+		args = append(args, clientTimestamps)
+		optionalColumns += ", client_timestamp"
+		optionalValues += fmt.Sprintf(", ($%d::timestamptz[])[idx] AS client_timestamp", len(args))
+	}
+	if s.features.Has(FeatureBatchID) {
+		args = append(args, batchIDs)
+		optionalColumns += ", gateway_batch_id"
+		optionalValues += fmt.Sprintf(", NULLIF(($%d::text[])[idx], '') AS gateway_batch_id", len(args))
+	}
+	if s.features.Has(FeatureLogFields) {
+		args = append(args, severities, sourceHosts, applications)
+		optionalColumns += ", severity, source_host, application"
+		optionalValues += fmt.Sprintf(", NULLIF(($%d::text[])[idx], '') AS severity, NULLIF(($%d::text[])[idx], '') AS source_host, NULLIF(($%d::text[])[idx], '') AS application",
+			len(args)-2, len(args)-1, len(args))
+	}
+	if s.features.Has(FeatureTestTraffic) {
+		args = append(args, testTraffic)
+		optionalColumns += ", test_traffic"
+		optionalValues += fmt.Sprintf(", ($%d::boolean[])[idx] AS test_traffic", len(args))
+	}
+	if s.features.Has(FeatureSequence) {
+		args = append(args, sequences)
+		optionalColumns += ", sequence"
+		optionalValues += fmt.Sprintf(", NULLIF(($%d::bigint[])[idx], 0) AS sequence", len(args))
+	}
+	if s.features.Has(FeatureExternalID) {
+		args = append(args, externalIDs)
+		optionalColumns += ", external_id"
+		optionalValues += fmt.Sprintf(", NULLIF(($%d::text[])[idx], '') AS external_id", len(args))
 	}
 
-	// 2. Construct a single query using UNNEST WITH ORDINALITY
+	// 2. Construct a single query using UNNEST WITH ORDINALITY.
+	// xmax = 0 identifies freshly inserted rows; updated rows carry the updating transaction's ID.
 	query := `
         INSERT INTO tbl_log_status (
             request_id, 
@@ -374,7 +756,7 @@ func (s *PostgresStore) InsertLogStatusBatch(ctx context.Context, statuses []*Lo
             source_org_id, 
             received_timestamp, 
             status, 
-            retry_count
+            retry_count` + optionalColumns + `
         )
         SELECT
             request_id,                             -- From the UNNEST
@@ -383,26 +765,150 @@ func (s *PostgresStore) InsertLogStatusBatch(ctx context.Context, statuses []*Lo
             ($4::timestamptz[])[idx] AS received_timestamp, -- Indexed from param $4
             ($5::text[])[idx] AS status,            -- Indexed from param $5
             0 AS retry_count                        -- Static value
+            ` + optionalValues + `
         FROM
             -- Unnest the primary key array to drive the loop
             UNNEST($1::text[]) WITH ORDINALITY AS t(request_id, idx)
-        ON CONFLICT (request_id) DO NOTHING
+        ` + conflictClause + `
+        RETURNING request_id, (xmax = 0) AS inserted
     `
 
 	// 3. Execute the single query
-	_, err := s.db.Exec(queryCtx, query,
-		requestIDs,         // $1
-		logHashes,          // $2
-		sourceOrgIDs,       // $3
-		receivedTimestamps, // $4
-		statusStrings,      // $5
-	)
-
+	rows, err := db.Query(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to batch insert log statuses with unnest: %w", err)
+		return nil, fmt.Errorf("failed to batch insert log statuses with unnest: %w", err)
+	}
+	defer rows.Close()
+
+	// 4. Classify rows: anything not returned was skipped by the conflict clause
+	written := make(map[string]struct{}, len(requestIDs))
+	for rows.Next() {
+		var requestID string
+		var inserted bool
+		if err := rows.Scan(&requestID, &inserted); err != nil {
+			return nil, fmt.Errorf("failed to scan batch insert result: %w", err)
+		}
+		written[requestID] = struct{}{}
+		if inserted {
+			result.Inserted = append(result.Inserted, requestID)
+		} else {
+			result.Updated = append(result.Updated, requestID)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("failed to batch insert log statuses with unnest: %w", rows.Err())
 	}
 
-	return nil
+	for _, requestID := range requestIDs {
+		if _, ok := written[requestID]; !ok {
+			result.Skipped = append(result.Skipped, requestID)
+		}
+	}
+
+	return result, nil
+}
+
+// RestoreLogOutcomes upserts final outcomes using UNNEST. Rows that are missing
+// are inserted; existing ones take the outcome unless they are already COMPLETED
+// and the outcome is not, so replaying a topic twice or out of order is harmless.
+func (s *PostgresStore) RestoreLogOutcomes(ctx context.Context, outcomes []LogOutcome) (int64, error) {
+	if len(outcomes) == 0 {
+		return 0, nil
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Keep the last outcome per request (a single upsert cannot touch the same row twice)
+	last := make(map[string]int, len(outcomes))
+	for i, o := range outcomes {
+		last[o.RequestID] = i
+	}
+	n := len(last)
+	requestIDs := make([]string, 0, n)
+	logHashes := make([]string, 0, n)
+	sourceOrgIDs := make([]string, 0, n)
+	receivedTimestamps := make([]time.Time, 0, n)
+	statuses := make([]string, 0, n)
+	retryCounts := make([]int32, 0, n)
+	txHashes := make([]string, 0, n)
+	blockHeights := make([]int64, 0, n)
+	errorMessages := make([]string, 0, n)
+	finishedAts := make([]time.Time, 0, n)
+	regions := make([]string, 0, n)
+	for i, o := range outcomes {
+		if last[o.RequestID] != i {
+			continue
+		}
+		requestIDs = append(requestIDs, o.RequestID)
+		logHashes = append(logHashes, o.LogHash)
+		sourceOrgIDs = append(sourceOrgIDs, o.SourceOrgID)
+		receivedTimestamps = append(receivedTimestamps, o.ReceivedTimestamp)
+		statuses = append(statuses, string(o.Status))
+		retryCounts = append(retryCounts, int32(o.RetryCount))
+		txHashes = append(txHashes, o.TxHash)
+		blockHeights = append(blockHeights, int64(o.BlockHeight))
+		errorMessages = append(errorMessages, o.ErrorMessage)
+		finishedAts = append(finishedAts, o.FinishedAt)
+		regions = append(regions, o.Region)
+	}
+
+	args := []interface{}{
+		requestIDs, logHashes, sourceOrgIDs, receivedTimestamps, statuses, // $1-$5
+		retryCounts, txHashes, blockHeights, errorMessages, finishedAts, // $6-$10
+	}
+	var optionalColumns, optionalValues string
+	if s.features.Has(FeatureRegion) {
+		args = append(args, regions)
+		optionalColumns += ", region"
+		optionalValues += fmt.Sprintf(", NULLIF(($%d::text[])[idx], '') AS region", len(args))
+	}
+
+	query := `
+        INSERT INTO tbl_log_status (
+            request_id,
+            log_hash,
+            source_org_id,
+            received_timestamp,
+            status,
+            retry_count,
+            tx_hash,
+            block_height,
+            log_hash_on_chain,
+            error_message,
+            processing_finished_at` + optionalColumns + `
+        )
+        SELECT
+            request_id,
+            ($2::text[])[idx],
+            ($3::text[])[idx],
+            ($4::timestamptz[])[idx],
+            ($5::text[])[idx],
+            ($6::integer[])[idx],
+            NULLIF(($7::text[])[idx], ''),
+            NULLIF(($8::bigint[])[idx], 0),
+            CASE WHEN ($5::text[])[idx] = 'COMPLETED' THEN ($2::text[])[idx] END,
+            NULLIF(($9::text[])[idx], ''),
+            ($10::timestamptz[])[idx]
+            ` + optionalValues + `
+        FROM
+            UNNEST($1::text[]) WITH ORDINALITY AS t(request_id, idx)
+        ON CONFLICT (request_id) DO UPDATE
+            SET status = EXCLUDED.status,
+                retry_count = EXCLUDED.retry_count,
+                tx_hash = EXCLUDED.tx_hash,
+                block_height = EXCLUDED.block_height,
+                log_hash_on_chain = EXCLUDED.log_hash_on_chain,
+                error_message = EXCLUDED.error_message,
+                processing_finished_at = EXCLUDED.processing_finished_at
+            WHERE tbl_log_status.status <> 'COMPLETED' OR EXCLUDED.status = 'COMPLETED'
+    `
+
+	cmdTag, err := s.db.Exec(queryCtx, query, args...)
+	if err != nil {
+		return 0, classifyError(fmt.Errorf("failed to restore log outcomes: %w", err))
+	}
+	return cmdTag.RowsAffected(), nil
 }
 
 // GetLogStatusByRequestID queries log status by request_id
@@ -410,7 +916,8 @@ func (s *PostgresStore) GetLogStatusByRequestID(ctx context.Context, requestID s
 	query := `
 		SELECT request_id, log_hash, source_org_id, received_timestamp,
 		       status, received_at_db, processing_started_at, processing_finished_at,
-		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
 		FROM tbl_log_status
 		WHERE request_id = $1
 	`
@@ -430,6 +937,16 @@ func (s *PostgresStore) GetLogStatusByRequestID(ctx context.Context, requestID s
 		&status.LogHashOnChain,
 		&status.ErrorMessage,
 		&status.RetryCount,
+		&status.Region,
+		&status.ClientTimestamp,
+		&status.GatewayBatchID,
+		&status.EngineBatchID,
+		&status.Severity,
+		&status.SourceHost,
+		&status.Application,
+		&status.TestTraffic,
+		&status.Sequence,
+		&status.ExternalID,
 	)
 
 	if err != nil {
@@ -442,12 +959,64 @@ func (s *PostgresStore) GetLogStatusByRequestID(ctx context.Context, requestID s
 	return &status, nil
 }
 
+// GetLogStatusByExternalID queries the log status an org submitted under an external ID
+func (s *PostgresStore) GetLogStatusByExternalID(ctx context.Context, orgID, externalID string) (*LogStatus, error) {
+	if !s.features.Has(FeatureExternalID) {
+		return nil, fmt.Errorf("external ID lookup: %w", ErrFeatureUnavailable)
+	}
+	query := `
+		SELECT request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE source_org_id = $1 AND external_id = $2
+	`
+
+	var status LogStatus
+	err := s.db.QueryRow(ctx, query, orgID, externalID).Scan(
+		&status.RequestID,
+		&status.LogHash,
+		&status.SourceOrgID,
+		&status.ReceivedTimestamp,
+		&status.Status,
+		&status.ReceivedAtDB,
+		&status.ProcessingStartedAt,
+		&status.ProcessingFinishedAt,
+		&status.TxHash,
+		&status.BlockHeight,
+		&status.LogHashOnChain,
+		&status.ErrorMessage,
+		&status.RetryCount,
+		&status.Region,
+		&status.ClientTimestamp,
+		&status.GatewayBatchID,
+		&status.EngineBatchID,
+		&status.Severity,
+		&status.SourceHost,
+		&status.Application,
+		&status.TestTraffic,
+		&status.Sequence,
+		&status.ExternalID,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLogNotFound
+		}
+		return nil, fmt.Errorf("failed to query log status by external_id: %w", err)
+	}
+
+	return &status, nil
+}
+
 // GetLogStatusByHash queries log status by log_hash
 func (s *PostgresStore) GetLogStatusByHash(ctx context.Context, logHash string) (*LogStatus, error) {
 	query := `
 		SELECT request_id, log_hash, source_org_id, received_timestamp,
 		       status, received_at_db, processing_started_at, processing_finished_at,
-		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
 		FROM tbl_log_status
 		WHERE log_hash = $1
 	`
@@ -467,6 +1036,16 @@ func (s *PostgresStore) GetLogStatusByHash(ctx context.Context, logHash string)
 		&status.LogHashOnChain,
 		&status.ErrorMessage,
 		&status.RetryCount,
+		&status.Region,
+		&status.ClientTimestamp,
+		&status.GatewayBatchID,
+		&status.EngineBatchID,
+		&status.Severity,
+		&status.SourceHost,
+		&status.Application,
+		&status.TestTraffic,
+		&status.Sequence,
+		&status.ExternalID,
 	)
 
 	if err != nil {
@@ -478,3 +1057,1657 @@ func (s *PostgresStore) GetLogStatusByHash(ctx context.Context, logHash string)
 
 	return &status, nil
 }
+
+// ListUnqueuedReceived returns the given request_ids that are still RECEIVED
+// and have no outbox row, so their messages may never have been published
+func (s *PostgresStore) ListUnqueuedReceived(ctx context.Context, requestIDs []string) ([]string, error) {
+	if len(requestIDs) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT request_id FROM tbl_log_status WHERE request_id = ANY($1) AND status = $2`
+	if s.features.Has(FeatureOutbox) {
+		query += ` AND NOT EXISTS (SELECT 1 FROM tbl_outbox o WHERE o.request_id = tbl_log_status.request_id)`
+	}
+	rows, err := s.db.Query(ctx, query, requestIDs, StatusReceived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unqueued log statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var unqueued []string
+	for rows.Next() {
+		var requestID string
+		if err := rows.Scan(&requestID); err != nil {
+			return nil, fmt.Errorf("failed to scan unqueued log status: %w", err)
+		}
+		unqueued = append(unqueued, requestID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate unqueued log statuses: %w", err)
+	}
+	return unqueued, nil
+}
+
+// GetTerminalStatuses returns the status of the given tasks that are COMPLETED
+// or FAILED, keyed by request_id, without locking them
+func (s *PostgresStore) GetTerminalStatuses(ctx context.Context, requestIDs []string) (map[string]Status, error) {
+	result := make(map[string]Status)
+	if len(requestIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT request_id, status
+		FROM tbl_log_status
+		WHERE request_id = ANY($1) AND status IN ($2, $3)
+	`
+	rows, err := s.db.Query(ctx, query, requestIDs, StatusCompleted, StatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query terminal log statuses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var requestID string
+		var status Status
+		if err := rows.Scan(&requestID, &status); err != nil {
+			return nil, fmt.Errorf("failed to scan terminal log status: %w", err)
+		}
+		result[requestID] = status
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate terminal log statuses: %w", err)
+	}
+	return result, nil
+}
+
+// GetCompletedByHashes returns COMPLETED records for the given log hashes, keyed by log_hash
+func (s *PostgresStore) GetCompletedByHashes(ctx context.Context, logHashes []string) (map[string]*LogStatus, error) {
+	return s.getByHashes(ctx, logHashes, StatusCompleted, "processing_finished_at")
+}
+
+// GetProcessingByHashes returns PROCESSING records for the given log hashes,
+// the one picked up first for each, keyed by log_hash
+func (s *PostgresStore) GetProcessingByHashes(ctx context.Context, logHashes []string) (map[string]*LogStatus, error) {
+	return s.getByHashes(ctx, logHashes, StatusProcessing, "processing_started_at")
+}
+
+// getByHashes returns one record in status for each of the given log hashes,
+// the first by orderBy, keyed by log_hash
+func (s *PostgresStore) getByHashes(ctx context.Context, logHashes []string, status Status, orderBy string) (map[string]*LogStatus, error) {
+	result := make(map[string]*LogStatus)
+	if len(logHashes) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT DISTINCT ON (log_hash)
+		       request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE log_hash = ANY($1) AND status = $2
+		ORDER BY log_hash, ` + orderBy + `, request_id
+	`
+
+	rows, err := s.db.Query(ctx, query, logHashes, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s log statuses by log_hash: %w", status, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record LogStatus
+		if err := rows.Scan(
+			&record.RequestID,
+			&record.LogHash,
+			&record.SourceOrgID,
+			&record.ReceivedTimestamp,
+			&record.Status,
+			&record.ReceivedAtDB,
+			&record.ProcessingStartedAt,
+			&record.ProcessingFinishedAt,
+			&record.TxHash,
+			&record.BlockHeight,
+			&record.LogHashOnChain,
+			&record.ErrorMessage,
+			&record.RetryCount,
+			&record.Region,
+			&record.ClientTimestamp,
+			&record.GatewayBatchID,
+			&record.EngineBatchID,
+			&record.Severity,
+			&record.SourceHost,
+			&record.Application,
+			&record.TestTraffic,
+			&record.Sequence,
+			&record.ExternalID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan %s log status row: %w", status, err)
+		}
+		result[record.LogHash] = &record
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating %s log statuses: %w", status, rows.Err())
+	}
+
+	return result, nil
+}
+
+// ListCompletedAfter returns up to limit COMPLETED records positioned after the
+// cursor and finished before the given time, ordered by (processing_finished_at, request_id)
+func (s *PostgresStore) ListCompletedAfter(ctx context.Context, after ExportCursor, before time.Time, limit int) ([]*LogStatus, error) {
+	query := `
+		SELECT request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE status = $1
+		  AND (processing_finished_at, request_id) > ($2, $3)
+		  AND processing_finished_at < $4
+		ORDER BY processing_finished_at, request_id
+		LIMIT $5
+	`
+
+	rows, err := s.db.Query(ctx, query, StatusCompleted, after.FinishedAt, after.RequestID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list completed log statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*LogStatus
+	for rows.Next() {
+		var status LogStatus
+		if err := rows.Scan(
+			&status.RequestID,
+			&status.LogHash,
+			&status.SourceOrgID,
+			&status.ReceivedTimestamp,
+			&status.Status,
+			&status.ReceivedAtDB,
+			&status.ProcessingStartedAt,
+			&status.ProcessingFinishedAt,
+			&status.TxHash,
+			&status.BlockHeight,
+			&status.LogHashOnChain,
+			&status.ErrorMessage,
+			&status.RetryCount,
+			&status.Region,
+			&status.ClientTimestamp,
+			&status.GatewayBatchID,
+			&status.EngineBatchID,
+			&status.Severity,
+			&status.SourceHost,
+			&status.Application,
+			&status.TestTraffic,
+			&status.Sequence,
+			&status.ExternalID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan completed log status row: %w", err)
+		}
+		result = append(result, &status)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating completed log statuses: %w", rows.Err())
+	}
+
+	return result, nil
+}
+
+// ListLogStatusByBatchID returns up to limit records of a gateway or engine
+// batch, ordered by request_id
+func (s *PostgresStore) ListLogStatusByBatchID(ctx context.Context, batchID string, limit int) ([]*LogStatus, error) {
+	if !s.features.Has(FeatureBatchID) {
+		return nil, fmt.Errorf("batch IDs: %w", ErrFeatureUnavailable)
+	}
+	query := `
+		SELECT request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE gateway_batch_id = $1 OR engine_batch_id = $1
+		ORDER BY request_id
+		LIMIT $2
+	`
+
+	rows, err := s.db.Query(ctx, query, batchID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log statuses of batch %s: %w", batchID, err)
+	}
+	defer rows.Close()
+
+	var result []*LogStatus
+	for rows.Next() {
+		var status LogStatus
+		if err := rows.Scan(
+			&status.RequestID,
+			&status.LogHash,
+			&status.SourceOrgID,
+			&status.ReceivedTimestamp,
+			&status.Status,
+			&status.ReceivedAtDB,
+			&status.ProcessingStartedAt,
+			&status.ProcessingFinishedAt,
+			&status.TxHash,
+			&status.BlockHeight,
+			&status.LogHashOnChain,
+			&status.ErrorMessage,
+			&status.RetryCount,
+			&status.Region,
+			&status.ClientTimestamp,
+			&status.GatewayBatchID,
+			&status.EngineBatchID,
+			&status.Severity,
+			&status.SourceHost,
+			&status.Application,
+			&status.TestTraffic,
+			&status.Sequence,
+			&status.ExternalID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan log status row: %w", err)
+		}
+		result = append(result, &status)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating log statuses of batch %s: %w", batchID, rows.Err())
+	}
+	return result, nil
+}
+
+// ListStuckTasks returns up to limit tasks PROCESSING for longer than stuckFor, oldest first
+func (s *PostgresStore) ListStuckTasks(ctx context.Context, stuckFor time.Duration, limit int) ([]*LogStatus, error) {
+	query := `
+		SELECT request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE status = $1 AND processing_started_at < NOW() - $2::double precision * INTERVAL '1 second'
+		ORDER BY processing_started_at, request_id
+		LIMIT $3
+	`
+
+	rows, err := s.db.Query(ctx, query, StatusProcessing, stuckFor.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stuck tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*LogStatus
+	for rows.Next() {
+		var status LogStatus
+		if err := rows.Scan(
+			&status.RequestID,
+			&status.LogHash,
+			&status.SourceOrgID,
+			&status.ReceivedTimestamp,
+			&status.Status,
+			&status.ReceivedAtDB,
+			&status.ProcessingStartedAt,
+			&status.ProcessingFinishedAt,
+			&status.TxHash,
+			&status.BlockHeight,
+			&status.LogHashOnChain,
+			&status.ErrorMessage,
+			&status.RetryCount,
+			&status.Region,
+			&status.ClientTimestamp,
+			&status.GatewayBatchID,
+			&status.EngineBatchID,
+			&status.Severity,
+			&status.SourceHost,
+			&status.Application,
+			&status.TestTraffic,
+			&status.Sequence,
+			&status.ExternalID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan stuck task row: %w", err)
+		}
+		result = append(result, &status)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating stuck tasks: %w", rows.Err())
+	}
+	return result, nil
+}
+
+// ListLogStatusByHash returns up to limit records with the log hash, submitted
+// by orgID unless it is empty, ordered by (received_timestamp, request_id)
+func (s *PostgresStore) ListLogStatusByHash(ctx context.Context, logHash, orgID string, limit int) ([]*LogStatus, error) {
+	query := `
+		SELECT request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE log_hash = $1 AND ($2 = '' OR source_org_id = $2)
+		ORDER BY received_timestamp, request_id
+		LIMIT $3
+	`
+
+	rows, err := s.db.Query(ctx, query, logHash, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log statuses by log_hash: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*LogStatus
+	for rows.Next() {
+		var status LogStatus
+		if err := rows.Scan(
+			&status.RequestID,
+			&status.LogHash,
+			&status.SourceOrgID,
+			&status.ReceivedTimestamp,
+			&status.Status,
+			&status.ReceivedAtDB,
+			&status.ProcessingStartedAt,
+			&status.ProcessingFinishedAt,
+			&status.TxHash,
+			&status.BlockHeight,
+			&status.LogHashOnChain,
+			&status.ErrorMessage,
+			&status.RetryCount,
+			&status.Region,
+			&status.ClientTimestamp,
+			&status.GatewayBatchID,
+			&status.EngineBatchID,
+			&status.Severity,
+			&status.SourceHost,
+			&status.Application,
+			&status.TestTraffic,
+			&status.Sequence,
+			&status.ExternalID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan log status row: %w", err)
+		}
+		result = append(result, &status)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating log statuses by log_hash: %w", rows.Err())
+	}
+	return result, nil
+}
+
+// logFilterWhere returns the WHERE conditions of a filter, appending their
+// parameters to args
+func (s *PostgresStore) logFilterWhere(filter LogFilter, args []interface{}) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.OrgID != "" {
+		add("source_org_id = $%d", filter.OrgID)
+	}
+	if filter.Severity != "" {
+		add("severity = $%d", filter.Severity)
+	}
+	if filter.Application != "" {
+		add("application = $%d", filter.Application)
+	}
+	if filter.SourceHost != "" {
+		add("source_host = $%d", filter.SourceHost)
+	}
+	if filter.Status != "" {
+		add("status = $%d", string(filter.Status))
+	}
+	if !filter.Since.IsZero() {
+		add("received_timestamp >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("received_timestamp < $%d", filter.Until)
+	}
+	if filter.TestTraffic != nil {
+		if s.features.Has(FeatureTestTraffic) {
+			add("test_traffic = $%d", *filter.TestTraffic)
+		} else if *filter.TestTraffic {
+			conditions = append(conditions, "FALSE") // No test traffic without the column
+		}
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// SearchLogStatus returns up to limit records matching the filter, positioned
+// after the cursor and ordered by (received_timestamp, request_id)
+func (s *PostgresStore) SearchLogStatus(ctx context.Context, filter LogFilter, after LogCursor, limit int) ([]*LogStatus, error) {
+	if !s.features.Has(FeatureLogFields) {
+		return nil, fmt.Errorf("log search: %w", ErrFeatureUnavailable)
+	}
+
+	where, args := s.logFilterWhere(filter, nil)
+	if after.RequestID != "" {
+		args = append(args, after.ReceivedTimestamp, after.RequestID)
+		where += fmt.Sprintf(" AND (received_timestamp, request_id) > ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, limit)
+	query := `
+		SELECT request_id, log_hash, source_org_id, received_timestamp,
+		       status, received_at_db, processing_started_at, processing_finished_at,
+		       tx_hash, block_height, log_hash_on_chain, error_message, retry_count,
+		       ` + s.optionalColumns() + `
+		FROM tbl_log_status
+		WHERE ` + where + `
+		ORDER BY received_timestamp, request_id
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search log statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*LogStatus
+	for rows.Next() {
+		var status LogStatus
+		if err := rows.Scan(
+			&status.RequestID,
+			&status.LogHash,
+			&status.SourceOrgID,
+			&status.ReceivedTimestamp,
+			&status.Status,
+			&status.ReceivedAtDB,
+			&status.ProcessingStartedAt,
+			&status.ProcessingFinishedAt,
+			&status.TxHash,
+			&status.BlockHeight,
+			&status.LogHashOnChain,
+			&status.ErrorMessage,
+			&status.RetryCount,
+			&status.Region,
+			&status.ClientTimestamp,
+			&status.GatewayBatchID,
+			&status.EngineBatchID,
+			&status.Severity,
+			&status.SourceHost,
+			&status.Application,
+			&status.TestTraffic,
+			&status.Sequence,
+			&status.ExternalID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan log status row: %w", err)
+		}
+		result = append(result, &status)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating searched log statuses: %w", rows.Err())
+	}
+	return result, nil
+}
+
+// CountLogStatus counts the records matching the filter by status, severity and application
+func (s *PostgresStore) CountLogStatus(ctx context.Context, filter LogFilter) ([]LogStatusCount, error) {
+	if !s.features.Has(FeatureLogFields) {
+		return nil, fmt.Errorf("log statistics: %w", ErrFeatureUnavailable)
+	}
+
+	where, args := s.logFilterWhere(filter, nil)
+	query := `
+		SELECT status, COALESCE(severity, ''), COALESCE(application, ''), COUNT(*)
+		FROM tbl_log_status
+		WHERE ` + where + `
+		GROUP BY 1, 2, 3
+		ORDER BY 1, 2, 3`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count log statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []LogStatusCount
+	for rows.Next() {
+		var c LogStatusCount
+		if err := rows.Scan(&c.Status, &c.Severity, &c.Application, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan log status count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating log status counts: %w", rows.Err())
+	}
+	return counts, nil
+}
+
+// GetExportCursor returns the saved position of the named export (zero cursor if none)
+func (s *PostgresStore) GetExportCursor(ctx context.Context, name string) (ExportCursor, error) {
+	if !s.features.Has(FeatureExportCursor) {
+		return ExportCursor{}, fmt.Errorf("export cursors: %w", ErrFeatureUnavailable)
+	}
+	var cursor ExportCursor
+	err := s.db.QueryRow(ctx,
+		`SELECT last_finished_at, last_request_id FROM tbl_export_cursor WHERE name = $1`, name,
+	).Scan(&cursor.FinishedAt, &cursor.RequestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ExportCursor{}, nil
+		}
+		return ExportCursor{}, fmt.Errorf("failed to read export cursor %s: %w", name, err)
+	}
+	return cursor, nil
+}
+
+// SaveExportCursor persists the position of the named export
+func (s *PostgresStore) SaveExportCursor(ctx context.Context, name string, cursor ExportCursor) error {
+	if !s.features.Has(FeatureExportCursor) {
+		return fmt.Errorf("export cursors: %w", ErrFeatureUnavailable)
+	}
+	query := `
+		INSERT INTO tbl_export_cursor (name, last_finished_at, last_request_id, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET last_finished_at = EXCLUDED.last_finished_at,
+		    last_request_id = EXCLUDED.last_request_id,
+		    updated_at = NOW()
+	`
+	if _, err := s.db.Exec(ctx, query, name, cursor.FinishedAt, cursor.RequestID); err != nil {
+		return fmt.Errorf("failed to save export cursor %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddOrgUsage adds the given submission counts to the usage totals and returns
+// the resulting totals. A zero count reads the current total.
+func (s *PostgresStore) AddOrgUsage(ctx context.Context, deltas []OrgUsage) ([]OrgUsage, error) {
+	if len(deltas) == 0 {
+		return nil, nil
+	}
+	if !s.features.Has(FeatureOrgUsage) {
+		return nil, fmt.Errorf("org usage: %w", ErrFeatureUnavailable)
+	}
+
+	periods := make([]string, len(deltas))
+	orgIDs := make([]string, len(deltas))
+	counts := make([]int64, len(deltas))
+	for i, d := range deltas {
+		periods[i] = d.Period
+		orgIDs[i] = d.OrgID
+		counts[i] = d.Submissions
+	}
+
+	query := `
+		INSERT INTO tbl_org_usage (period, org_id, submissions, updated_at)
+		SELECT period, org_id, submissions, NOW()
+		FROM UNNEST($1::text[], $2::text[], $3::bigint[]) AS t(period, org_id, submissions)
+		ON CONFLICT (period, org_id) DO UPDATE
+		SET submissions = tbl_org_usage.submissions + EXCLUDED.submissions,
+		    updated_at = NOW()
+		RETURNING period, org_id, submissions
+	`
+	rows, err := s.db.Query(ctx, query, periods, orgIDs, counts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add org usage: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make([]OrgUsage, 0, len(deltas))
+	for rows.Next() {
+		var u OrgUsage
+		if err := rows.Scan(&u.Period, &u.OrgID, &u.Submissions); err != nil {
+			return nil, fmt.Errorf("failed to scan org usage row: %w", err)
+		}
+		totals = append(totals, u)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating org usage: %w", rows.Err())
+	}
+	return totals, nil
+}
+
+// SaveAttestationProofs caches attestation proofs. Fields already cached are
+// kept; empty ones are filled in from the new proof.
+func (s *PostgresStore) SaveAttestationProofs(ctx context.Context, proofs []AttestationProof) error {
+	if len(proofs) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureProofCache) {
+		return fmt.Errorf("attestation proofs: %w", ErrFeatureUnavailable)
+	}
+
+	n := len(proofs)
+	hashes, orgs, timestamps, clientTimestamps := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	contents, txHashes, sources := make([]string, n), make([]string, n), make([]string, n)
+	heights := make([]int64, n)
+	for i, p := range proofs {
+		hashes[i] = p.LogHash
+		orgs[i] = p.SenderOrgID
+		timestamps[i] = p.Timestamp
+		clientTimestamps[i] = p.ClientTimestamp
+		contents[i] = p.LogContent
+		txHashes[i] = p.TxHash
+		heights[i] = int64(p.BlockHeight)
+		sources[i] = p.Source
+	}
+
+	query := `
+		INSERT INTO tbl_attestation_proof
+			(log_hash, sender_org_id, onchain_timestamp, client_timestamp, log_content, tx_hash, block_height, source, cached_at)
+		SELECT DISTINCT ON (log_hash) log_hash, sender_org_id, onchain_timestamp, client_timestamp, log_content, tx_hash, block_height, source, NOW()
+		FROM UNNEST($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::bigint[], $8::text[])
+			AS t(log_hash, sender_org_id, onchain_timestamp, client_timestamp, log_content, tx_hash, block_height, source)
+		ORDER BY log_hash, tx_hash DESC, onchain_timestamp DESC
+		ON CONFLICT (log_hash) DO UPDATE
+		SET sender_org_id = COALESCE(NULLIF(tbl_attestation_proof.sender_org_id, ''), EXCLUDED.sender_org_id),
+		    onchain_timestamp = COALESCE(NULLIF(tbl_attestation_proof.onchain_timestamp, ''), EXCLUDED.onchain_timestamp),
+		    client_timestamp = CASE WHEN tbl_attestation_proof.onchain_timestamp = ''
+		                            THEN EXCLUDED.client_timestamp ELSE tbl_attestation_proof.client_timestamp END,
+		    log_content = COALESCE(NULLIF(tbl_attestation_proof.log_content, ''), EXCLUDED.log_content),
+		    tx_hash = COALESCE(NULLIF(tbl_attestation_proof.tx_hash, ''), EXCLUDED.tx_hash),
+		    block_height = CASE WHEN tbl_attestation_proof.tx_hash = ''
+		                        THEN EXCLUDED.block_height ELSE tbl_attestation_proof.block_height END
+	`
+	if _, err := s.db.Exec(ctx, query, hashes, orgs, timestamps, clientTimestamps, contents, txHashes, heights, sources); err != nil {
+		return classifyError(fmt.Errorf("failed to save attestation proofs: %w", err))
+	}
+	return nil
+}
+
+// GetAttestationProof returns the cached proof for a log hash
+func (s *PostgresStore) GetAttestationProof(ctx context.Context, logHash string) (*AttestationProof, error) {
+	if !s.features.Has(FeatureProofCache) {
+		return nil, fmt.Errorf("attestation proofs: %w", ErrFeatureUnavailable)
+	}
+	var p AttestationProof
+	var height int64
+	err := s.db.QueryRow(ctx, `
+		SELECT log_hash, sender_org_id, onchain_timestamp, client_timestamp, log_content, tx_hash, block_height, source, cached_at
+		FROM tbl_attestation_proof WHERE log_hash = $1`, logHash,
+	).Scan(&p.LogHash, &p.SenderOrgID, &p.Timestamp, &p.ClientTimestamp, &p.LogContent, &p.TxHash, &height, &p.Source, &p.CachedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProofNotFound
+		}
+		return nil, fmt.Errorf("failed to read attestation proof %s: %w", logHash, err)
+	}
+	p.BlockHeight = uint64(height)
+	return &p, nil
+}
+
+// SaveMerkleProofs stores Merkle inclusion proofs; existing proofs are kept
+func (s *PostgresStore) SaveMerkleProofs(ctx context.Context, proofs []MerkleProof) error {
+	if len(proofs) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureMerkleProof) {
+		return fmt.Errorf("merkle proofs: %w", ErrFeatureUnavailable)
+	}
+
+	n := len(proofs)
+	hashes, roots, siblings, txHashes := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	indexes, counts, heights := make([]int32, n), make([]int32, n), make([]int64, n)
+	for i, p := range proofs {
+		hashes[i] = p.LogHash
+		roots[i] = p.MerkleRoot
+		indexes[i] = int32(p.LeafIndex)
+		counts[i] = int32(p.LeafCount)
+		siblings[i] = strings.Join(p.Siblings, ",")
+		txHashes[i] = p.TxHash
+		heights[i] = int64(p.BlockHeight)
+	}
+
+	query := `
+		INSERT INTO tbl_merkle_proof
+			(log_hash, merkle_root, leaf_index, leaf_count, siblings, tx_hash, block_height, created_at)
+		SELECT DISTINCT ON (log_hash) log_hash, merkle_root, leaf_index, leaf_count,
+			COALESCE(string_to_array(NULLIF(siblings, ''), ','), '{}'), tx_hash, block_height, NOW()
+		FROM UNNEST($1::text[], $2::text[], $3::int[], $4::int[], $5::text[], $6::text[], $7::bigint[])
+			AS t(log_hash, merkle_root, leaf_index, leaf_count, siblings, tx_hash, block_height)
+		ORDER BY log_hash, tx_hash
+		ON CONFLICT (log_hash) DO NOTHING
+	`
+	if _, err := s.db.Exec(ctx, query, hashes, roots, indexes, counts, siblings, txHashes, heights); err != nil {
+		return classifyError(fmt.Errorf("failed to save merkle proofs: %w", err))
+	}
+	return nil
+}
+
+// GetMerkleProof returns the Merkle proof of a log hash
+func (s *PostgresStore) GetMerkleProof(ctx context.Context, logHash string) (*MerkleProof, error) {
+	if !s.features.Has(FeatureMerkleProof) {
+		return nil, fmt.Errorf("merkle proofs: %w", ErrFeatureUnavailable)
+	}
+	var p MerkleProof
+	var index, count int32
+	var height int64
+	err := s.db.QueryRow(ctx, `
+		SELECT log_hash, merkle_root, leaf_index, leaf_count, siblings, tx_hash, block_height, created_at
+		FROM tbl_merkle_proof WHERE log_hash = $1`, logHash,
+	).Scan(&p.LogHash, &p.MerkleRoot, &index, &count, &p.Siblings, &p.TxHash, &height, &p.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMerkleProofNotFound
+		}
+		return nil, fmt.Errorf("failed to read merkle proof %s: %w", logHash, err)
+	}
+	p.LeafIndex, p.LeafCount, p.BlockHeight = int(index), int(count), uint64(height)
+	return &p, nil
+}
+
+// QueueLocalMessages holds messages in the local queue and moves their
+// submissions from RECEIVED to QUEUED_LOCAL, in one transaction
+func (s *PostgresStore) QueueLocalMessages(ctx context.Context, messages []LocalMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureLocalQueue) {
+		return fmt.Errorf("local queue: %w", ErrFeatureUnavailable)
+	}
+
+	requestIDs, tiers, payloads := make([]string, len(messages)), make([]string, len(messages)), make([][]byte, len(messages))
+	for i, m := range messages {
+		requestIDs[i] = m.RequestID
+		tiers[i] = m.Tier
+		payloads[i] = m.Payload
+	}
+
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO tbl_local_queue (request_id, tier, payload, queued_at)
+			SELECT request_id, tier, payload, NOW()
+			FROM UNNEST($1::text[], $2::text[], $3::bytea[]) AS t(request_id, tier, payload)
+			ON CONFLICT (request_id) DO UPDATE
+			SET tier = EXCLUDED.tier, payload = EXCLUDED.payload, claimed_until = NULL
+		`, requestIDs, tiers, payloads)
+		if err != nil {
+			return fmt.Errorf("failed to queue local messages: %w", err)
+		}
+		_, err = tx.Exec(ctx, `UPDATE tbl_log_status SET status = $1 WHERE request_id = ANY($2) AND status = $3`,
+			StatusQueuedLocal, requestIDs, StatusReceived)
+		if err != nil {
+			return fmt.Errorf("failed to mark submissions as QUEUED_LOCAL: %w", err)
+		}
+		return nil
+	})
+	return classifyError(err)
+}
+
+// ClaimLocalMessages leases up to limit queued messages of a tier, oldest first,
+// and moves their submissions to RECEIVED. Concurrent relays claim disjoint sets.
+func (s *PostgresStore) ClaimLocalMessages(ctx context.Context, tier string, limit int, lease time.Duration) ([]LocalMessage, error) {
+	if !s.features.Has(FeatureLocalQueue) {
+		return nil, fmt.Errorf("local queue: %w", ErrFeatureUnavailable)
+	}
+
+	var messages []LocalMessage
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE tbl_local_queue q
+			SET claimed_until = NOW() + $3::double precision * INTERVAL '1 second'
+			FROM (
+				SELECT request_id FROM tbl_local_queue
+				WHERE tier = $1 AND (claimed_until IS NULL OR claimed_until < NOW())
+				ORDER BY queued_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			) c
+			WHERE q.request_id = c.request_id
+			RETURNING q.request_id, q.tier, q.payload, q.queued_at
+		`, tier, limit, lease.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to claim local messages: %w", err)
+		}
+		defer rows.Close()
+
+		requestIDs := make([]string, 0, limit)
+		for rows.Next() {
+			var m LocalMessage
+			if err := rows.Scan(&m.RequestID, &m.Tier, &m.Payload, &m.QueuedAt); err != nil {
+				return fmt.Errorf("failed to scan local message: %w", err)
+			}
+			messages = append(messages, m)
+			requestIDs = append(requestIDs, m.RequestID)
+		}
+		if rows.Err() != nil {
+			return fmt.Errorf("error iterating local messages: %w", rows.Err())
+		}
+		rows.Close()
+
+		// Published messages must find their submissions RECEIVED, so move them before publishing
+		_, err = tx.Exec(ctx, `UPDATE tbl_log_status SET status = $1 WHERE request_id = ANY($2) AND status = $3`,
+			StatusReceived, requestIDs, StatusQueuedLocal)
+		if err != nil {
+			return fmt.Errorf("failed to mark claimed submissions as RECEIVED: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].QueuedAt.Before(messages[j].QueuedAt) })
+	return messages, nil
+}
+
+// ReleaseLocalMessages ends a claim: published messages are deleted, the
+// others are unclaimed and their submissions moved back to QUEUED_LOCAL
+func (s *PostgresStore) ReleaseLocalMessages(ctx context.Context, requestIDs []string, published bool) error {
+	if len(requestIDs) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureLocalQueue) {
+		return fmt.Errorf("local queue: %w", ErrFeatureUnavailable)
+	}
+
+	if published {
+		if _, err := s.db.Exec(ctx, `DELETE FROM tbl_local_queue WHERE request_id = ANY($1)`, requestIDs); err != nil {
+			return fmt.Errorf("failed to delete relayed local messages: %w", err)
+		}
+		return nil
+	}
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `UPDATE tbl_local_queue SET claimed_until = NULL WHERE request_id = ANY($1)`, requestIDs); err != nil {
+			return fmt.Errorf("failed to unclaim local messages: %w", err)
+		}
+		_, err := tx.Exec(ctx, `UPDATE tbl_log_status SET status = $1 WHERE request_id = ANY($2) AND status = $3`,
+			StatusQueuedLocal, requestIDs, StatusReceived)
+		if err != nil {
+			return fmt.Errorf("failed to mark unpublished submissions as QUEUED_LOCAL: %w", err)
+		}
+		return nil
+	})
+}
+
+// InsertLogStatusBatchWithOutbox inserts statuses and the outbox messages of
+// the rows written in one transaction, so a submission is never stored
+// without its message. The messages are claimed for lease: the caller
+// publishes them first, the relay only after the claim expires.
+func (s *PostgresStore) InsertLogStatusBatchWithOutbox(ctx context.Context, statuses []*LogStatus, policy ConflictPolicy, messages []OutboxMessage, lease time.Duration) (*InsertResult, error) {
+	if len(statuses) == 0 {
+		return &InsertResult{}, nil
+	}
+	if !s.features.Has(FeatureOutbox) {
+		return nil, fmt.Errorf("outbox: %w", ErrFeatureUnavailable)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var result *InsertResult
+	err := s.db.BeginFunc(queryCtx, func(tx pgx.Tx) error {
+		var err error
+		if result, err = s.insertLogStatusBatch(queryCtx, tx, statuses, policy); err != nil {
+			return err
+		}
+
+		// Skipped rows keep the message written with them
+		written := make(map[string]struct{}, len(result.Inserted)+len(result.Updated))
+		for _, requestID := range result.Inserted {
+			written[requestID] = struct{}{}
+		}
+		for _, requestID := range result.Updated {
+			written[requestID] = struct{}{}
+		}
+		requestIDs := make([]string, 0, len(written))
+		tiers := make([]string, 0, len(written))
+		payloads := make([][]byte, 0, len(written))
+		for _, m := range messages {
+			if _, ok := written[m.RequestID]; !ok {
+				continue
+			}
+			delete(written, m.RequestID)
+			requestIDs = append(requestIDs, m.RequestID)
+			tiers = append(tiers, m.Tier)
+			payloads = append(payloads, m.Payload)
+		}
+		if len(requestIDs) == 0 {
+			return nil
+		}
+		_, err = tx.Exec(queryCtx, `
+			INSERT INTO tbl_outbox (request_id, tier, payload, created_at, claimed_until)
+			SELECT request_id, tier, payload, NOW(), NOW() + $4::double precision * INTERVAL '1 second'
+			FROM UNNEST($1::text[], $2::text[], $3::bytea[]) AS t(request_id, tier, payload)
+			ON CONFLICT (request_id) DO UPDATE
+			SET tier = EXCLUDED.tier, payload = EXCLUDED.payload, created_at = EXCLUDED.created_at,
+			    claimed_until = EXCLUDED.claimed_until, sent_at = NULL
+		`, requestIDs, tiers, payloads, lease.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to write outbox messages: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	return result, nil
+}
+
+// ClaimOutboxMessages leases up to limit unsent outbox messages of a tier
+// whose claim has expired, oldest first
+func (s *PostgresStore) ClaimOutboxMessages(ctx context.Context, tier string, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	if !s.features.Has(FeatureOutbox) {
+		return nil, fmt.Errorf("outbox: %w", ErrFeatureUnavailable)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		UPDATE tbl_outbox o
+		SET claimed_until = NOW() + $3::double precision * INTERVAL '1 second'
+		FROM (
+			SELECT request_id FROM tbl_outbox
+			WHERE tier = $1 AND sent_at IS NULL AND (claimed_until IS NULL OR claimed_until < NOW())
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		) c
+		WHERE o.request_id = c.request_id
+		RETURNING o.request_id, o.tier, o.payload, o.created_at
+	`, tier, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err := rows.Scan(&m.RequestID, &m.Tier, &m.Payload, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating outbox messages: %w", rows.Err())
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+	return messages, nil
+}
+
+// MarkOutboxSent marks outbox messages as published
+func (s *PostgresStore) MarkOutboxSent(ctx context.Context, requestIDs []string) error {
+	if len(requestIDs) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureOutbox) {
+		return fmt.Errorf("outbox: %w", ErrFeatureUnavailable)
+	}
+	_, err := s.db.Exec(ctx, `UPDATE tbl_outbox SET sent_at = NOW(), claimed_until = NULL WHERE request_id = ANY($1) AND sent_at IS NULL`, requestIDs)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox messages as sent: %w", err)
+	}
+	return nil
+}
+
+// ReleaseOutboxMessages ends a claim on unpublished outbox messages
+func (s *PostgresStore) ReleaseOutboxMessages(ctx context.Context, requestIDs []string) error {
+	if len(requestIDs) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureOutbox) {
+		return fmt.Errorf("outbox: %w", ErrFeatureUnavailable)
+	}
+	_, err := s.db.Exec(ctx, `UPDATE tbl_outbox SET claimed_until = NULL WHERE request_id = ANY($1) AND sent_at IS NULL`, requestIDs)
+	if err != nil {
+		return fmt.Errorf("failed to release outbox messages: %w", err)
+	}
+	return nil
+}
+
+// PurgeOutbox deletes outbox messages sent before the given time
+func (s *PostgresStore) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	if !s.features.Has(FeatureOutbox) {
+		return 0, fmt.Errorf("outbox: %w", ErrFeatureUnavailable)
+	}
+	tag, err := s.db.Exec(ctx, `DELETE FROM tbl_outbox WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge sent outbox messages: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ParkMessages keeps the messages of the tasks that are still PROCESSING
+func (s *PostgresStore) ParkMessages(ctx context.Context, messages []ParkedMessage) (int64, error) {
+	if len(messages) == 0 {
+		return 0, nil
+	}
+	if !s.features.Has(FeatureParkedMessage) {
+		return 0, fmt.Errorf("parked messages: %w", ErrFeatureUnavailable)
+	}
+
+	requestIDs := make([]string, len(messages))
+	topics := make([]string, len(messages))
+	payloads := make([][]byte, len(messages))
+	for i, m := range messages {
+		requestIDs[i], topics[i], payloads[i] = m.RequestID, m.Topic, m.Payload
+	}
+	tag, err := s.db.Exec(ctx, `
+		INSERT INTO tbl_parked_message (request_id, topic, payload, parked_at)
+		SELECT DISTINCT ON (m.request_id) m.request_id, m.topic, m.payload, NOW()
+		FROM UNNEST($1::text[], $2::text[], $3::bytea[]) AS m(request_id, topic, payload)
+		JOIN tbl_log_status s ON s.request_id = m.request_id AND s.status = $4
+		ON CONFLICT (request_id) DO NOTHING
+	`, requestIDs, topics, payloads, StatusProcessing)
+	if err != nil {
+		return 0, fmt.Errorf("failed to park messages: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListReleasedParkedMessages returns up to limit parked messages of the given
+// topics whose task is no longer PROCESSING, oldest first. A message whose
+// task was deleted is listed as RECEIVED.
+func (s *PostgresStore) ListReleasedParkedMessages(ctx context.Context, topics []string, limit int) ([]ParkedMessage, error) {
+	if !s.features.Has(FeatureParkedMessage) {
+		return nil, fmt.Errorf("parked messages: %w", ErrFeatureUnavailable)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT p.request_id, p.topic, p.payload, p.parked_at, COALESCE(s.status, $3)
+		FROM tbl_parked_message p
+		LEFT JOIN tbl_log_status s ON s.request_id = p.request_id
+		WHERE p.topic = ANY($1) AND (s.status IS NULL OR s.status <> $4)
+		ORDER BY p.parked_at
+		LIMIT $2
+	`, topics, limit, StatusReceived, StatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list parked messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []ParkedMessage
+	for rows.Next() {
+		var m ParkedMessage
+		if err := rows.Scan(&m.RequestID, &m.Topic, &m.Payload, &m.ParkedAt, &m.Status); err != nil {
+			return nil, fmt.Errorf("failed to scan parked message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating parked messages: %w", rows.Err())
+	}
+	return messages, nil
+}
+
+// DeleteParkedMessages deletes parked messages
+func (s *PostgresStore) DeleteParkedMessages(ctx context.Context, requestIDs []string) error {
+	if len(requestIDs) == 0 {
+		return nil
+	}
+	if !s.features.Has(FeatureParkedMessage) {
+		return fmt.Errorf("parked messages: %w", ErrFeatureUnavailable)
+	}
+	if _, err := s.db.Exec(ctx, `DELETE FROM tbl_parked_message WHERE request_id = ANY($1)`, requestIDs); err != nil {
+		return fmt.Errorf("failed to delete parked messages: %w", err)
+	}
+	return nil
+}
+
+// DetectSequenceGaps records the sequence numbers skipped by the sources with
+// submissions received in [after, NOW() - grace). Each source is checked from
+// its checkpoint through the highest number received in the range, counting
+// submissions received later too, so only numbers still missing at the
+// deadline are gaps. Gaps are inserted once: concurrent detectors on several
+// gateways report each gap at most once.
+func (s *PostgresStore) DetectSequenceGaps(ctx context.Context, after time.Time, grace time.Duration) ([]SequenceGap, time.Time, error) {
+	if !s.features.Has(FeatureSequence) {
+		return nil, after, fmt.Errorf("sequence gaps: %w", ErrFeatureUnavailable)
+	}
+
+	var until time.Time
+	if err := s.db.QueryRow(ctx, `SELECT NOW() - $1::double precision * INTERVAL '1 second'`, grace.Seconds()).Scan(&until); err != nil {
+		return nil, after, fmt.Errorf("failed to read the database clock: %w", err)
+	}
+	if !until.After(after) {
+		return nil, after, nil
+	}
+
+	rows, err := s.db.Query(ctx, `
+		WITH touched AS (
+			SELECT source_org_id AS org_id, COALESCE(source_host, '') AS source_host,
+			       COALESCE(application, '') AS application, MAX(sequence) AS high
+			FROM tbl_log_status
+			WHERE sequence IS NOT NULL AND source_org_id IS NOT NULL
+			  AND received_at_db >= $1 AND received_at_db < $2
+			GROUP BY 1, 2, 3
+		), pending AS (
+			SELECT t.org_id, t.source_host, t.application, t.high, c.checked_through
+			FROM touched t
+			LEFT JOIN tbl_source_sequence c
+			       ON c.org_id = t.org_id AND c.source_host = t.source_host AND c.application = t.application
+			WHERE c.checked_through IS NULL OR t.high > c.checked_through
+		), seen AS (
+			SELECT DISTINCT p.org_id, p.source_host, p.application, p.checked_through, l.sequence
+			FROM pending p
+			JOIN tbl_log_status l
+			  ON l.source_org_id = p.org_id
+			 AND COALESCE(l.source_host, '') = p.source_host
+			 AND COALESCE(l.application, '') = p.application
+			 AND l.sequence > COALESCE(p.checked_through, 0) AND l.sequence <= p.high
+		), ordered AS (
+			SELECT org_id, source_host, application, sequence,
+			       COALESCE(LAG(sequence) OVER (PARTITION BY org_id, source_host, application ORDER BY sequence),
+			                checked_through) AS previous
+			FROM seen
+		), gaps AS (
+			INSERT INTO tbl_sequence_gap (org_id, source_host, application, first_missing, last_missing)
+			SELECT org_id, source_host, application, previous + 1, sequence - 1
+			FROM ordered
+			WHERE sequence > previous + 1
+			ON CONFLICT DO NOTHING
+			RETURNING org_id, source_host, application, first_missing, last_missing, detected_at
+		), checkpoints AS (
+			INSERT INTO tbl_source_sequence (org_id, source_host, application, checked_through)
+			SELECT org_id, source_host, application, high FROM pending
+			ON CONFLICT (org_id, source_host, application) DO UPDATE
+			SET checked_through = GREATEST(tbl_source_sequence.checked_through, EXCLUDED.checked_through),
+			    updated_at = NOW()
+		)
+		SELECT org_id, source_host, application, first_missing, last_missing, detected_at
+		FROM gaps
+	`, after, until)
+	if err != nil {
+		return nil, after, fmt.Errorf("failed to detect sequence gaps: %w", err)
+	}
+	defer rows.Close()
+
+	var gaps []SequenceGap
+	for rows.Next() {
+		var g SequenceGap
+		if err := rows.Scan(&g.OrgID, &g.SourceHost, &g.Application, &g.FirstMissing, &g.LastMissing, &g.DetectedAt); err != nil {
+			return nil, after, fmt.Errorf("failed to scan sequence gap: %w", err)
+		}
+		gaps = append(gaps, g)
+	}
+	if rows.Err() != nil {
+		return nil, after, fmt.Errorf("failed to detect sequence gaps: %w", rows.Err())
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		a, b := gaps[i], gaps[j]
+		if a.OrgID != b.OrgID {
+			return a.OrgID < b.OrgID
+		}
+		if a.SourceHost != b.SourceHost {
+			return a.SourceHost < b.SourceHost
+		}
+		if a.Application != b.Application {
+			return a.Application < b.Application
+		}
+		return a.FirstMissing < b.FirstMissing
+	})
+	return gaps, until, nil
+}
+
+// ListSequenceGaps returns up to limit recorded gaps of an org, newest first
+func (s *PostgresStore) ListSequenceGaps(ctx context.Context, orgID string, limit int) ([]SequenceGap, error) {
+	if !s.features.Has(FeatureSequence) {
+		return nil, fmt.Errorf("sequence gaps: %w", ErrFeatureUnavailable)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT org_id, source_host, application, first_missing, last_missing, detected_at
+		FROM tbl_sequence_gap
+		WHERE org_id = $1
+		ORDER BY detected_at DESC, source_host, application, first_missing
+		LIMIT $2
+	`, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sequence gaps: %w", err)
+	}
+	defer rows.Close()
+
+	var gaps []SequenceGap
+	for rows.Next() {
+		var g SequenceGap
+		if err := rows.Scan(&g.OrgID, &g.SourceHost, &g.Application, &g.FirstMissing, &g.LastMissing, &g.DetectedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sequence gap: %w", err)
+		}
+		gaps = append(gaps, g)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating sequence gaps: %w", rows.Err())
+	}
+	return gaps, nil
+}
+
+// HeartbeatInstance registers an engine instance or refreshes its heartbeat.
+// A heartbeat also revives an instance that was marked stopped or reclaimed.
+func (s *PostgresStore) HeartbeatInstance(ctx context.Context, instance WorkerInstance) error {
+	if !s.features.Has(FeatureFleet) {
+		return fmt.Errorf("fleet: %w", ErrFeatureUnavailable)
+	}
+
+	partitions := make([]int32, len(instance.Partitions))
+	for i, p := range instance.Partitions {
+		partitions[i] = int32(p)
+	}
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO tbl_worker_instances (instance_id, version, hostname, region, partitions, started_at, last_heartbeat)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (instance_id) DO UPDATE
+		SET version = EXCLUDED.version, hostname = EXCLUDED.hostname, region = EXCLUDED.region,
+		    partitions = EXCLUDED.partitions, started_at = EXCLUDED.started_at,
+		    last_heartbeat = NOW(), stopped_at = NULL, reclaimed_at = NULL
+	`, instance.InstanceID, instance.Version, instance.Hostname, instance.Region, partitions, instance.StartedAt)
+	if err != nil {
+		return fmt.Errorf("failed to heartbeat instance %s: %w", instance.InstanceID, err)
+	}
+	return nil
+}
+
+// StopInstance marks an instance as cleanly stopped and returns its PROCESSING
+// tasks to RECEIVED, in one transaction. The tasks keep their retry count: they
+// were interrupted, not failed.
+func (s *PostgresStore) StopInstance(ctx context.Context, instanceID string) (int64, error) {
+	if !s.features.Has(FeatureFleet) {
+		return 0, fmt.Errorf("fleet: %w", ErrFeatureUnavailable)
+	}
+
+	var released int64
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `UPDATE tbl_worker_instances SET stopped_at = NOW() WHERE instance_id = $1`, instanceID); err != nil {
+			return fmt.Errorf("failed to mark instance %s as stopped: %w", instanceID, err)
+		}
+		tag, err := tx.Exec(ctx, `
+			UPDATE tbl_log_status
+			SET status = $1, processing_started_at = NULL, engine_instance_id = NULL
+			WHERE engine_instance_id = $2 AND status = $3
+		`, StatusReceived, instanceID, StatusProcessing)
+		if err != nil {
+			return fmt.Errorf("failed to release tasks of instance %s: %w", instanceID, err)
+		}
+		released = tag.RowsAffected()
+		return nil
+	})
+	return released, err
+}
+
+// ListWorkerInstances returns the registered instances, ordered by instance ID
+func (s *PostgresStore) ListWorkerInstances(ctx context.Context) ([]*WorkerInstance, error) {
+	if !s.features.Has(FeatureFleet) {
+		return nil, fmt.Errorf("fleet: %w", ErrFeatureUnavailable)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT instance_id, version, hostname, region, partitions, started_at, last_heartbeat, stopped_at, reclaimed_at
+		FROM tbl_worker_instances
+		ORDER BY instance_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worker instances: %w", err)
+	}
+	defer rows.Close()
+
+	var instances []*WorkerInstance
+	for rows.Next() {
+		var inst WorkerInstance
+		var partitions []int32
+		if err := rows.Scan(&inst.InstanceID, &inst.Version, &inst.Hostname, &inst.Region, &partitions,
+			&inst.StartedAt, &inst.LastHeartbeat, &inst.StoppedAt, &inst.ReclaimedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan worker instance: %w", err)
+		}
+		for _, p := range partitions {
+			inst.Partitions = append(inst.Partitions, int(p))
+		}
+		instances = append(instances, &inst)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating worker instances: %w", rows.Err())
+	}
+	return instances, nil
+}
+
+// ReclaimDeadInstances returns the PROCESSING tasks of instances that have
+// not heartbeated within deadAfter to RECEIVED and marks the instances as
+// reclaimed, in one transaction. The dead instances are locked with SKIP
+// LOCKED, so concurrent reclaimers never reclaim the same instance twice.
+func (s *PostgresStore) ReclaimDeadInstances(ctx context.Context, deadAfter time.Duration) ([]ReclaimedInstance, error) {
+	if !s.features.Has(FeatureFleet) {
+		return nil, fmt.Errorf("fleet: %w", ErrFeatureUnavailable)
+	}
+
+	var reclaimed []ReclaimedInstance
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT instance_id, last_heartbeat
+			FROM tbl_worker_instances
+			WHERE last_heartbeat < NOW() - $1::double precision * INTERVAL '1 second'
+			  AND stopped_at IS NULL AND reclaimed_at IS NULL
+			ORDER BY instance_id
+			FOR UPDATE SKIP LOCKED
+		`, deadAfter.Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to find dead instances: %w", err)
+		}
+		for rows.Next() {
+			var r ReclaimedInstance
+			if err := rows.Scan(&r.InstanceID, &r.LastHeartbeat); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan dead instance: %w", err)
+			}
+			reclaimed = append(reclaimed, r)
+		}
+		rows.Close()
+		if rows.Err() != nil {
+			return fmt.Errorf("error iterating dead instances: %w", rows.Err())
+		}
+
+		for i := range reclaimed {
+			r := &reclaimed[i]
+			reason := fmt.Sprintf("reclaimed from engine instance %s: no heartbeat since %s", r.InstanceID, r.LastHeartbeat.UTC().Format(time.RFC3339))
+			tag, err := tx.Exec(ctx, `
+				UPDATE tbl_log_status
+				SET status = $1, retry_count = retry_count + 1, error_message = $2,
+				    processing_started_at = NULL, engine_instance_id = NULL
+				WHERE engine_instance_id = $3 AND status = $4
+			`, StatusReceived, reason, r.InstanceID, StatusProcessing)
+			if err != nil {
+				return fmt.Errorf("failed to reclaim tasks of instance %s: %w", r.InstanceID, err)
+			}
+			r.Tasks = tag.RowsAffected()
+			if _, err := tx.Exec(ctx, `UPDATE tbl_worker_instances SET reclaimed_at = NOW() WHERE instance_id = $1`, r.InstanceID); err != nil {
+				return fmt.Errorf("failed to mark instance %s as reclaimed: %w", r.InstanceID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reclaimed, nil
+}
+
+// InsertDebugCapture stores the raw request of a rejected submission
+func (s *PostgresStore) InsertDebugCapture(ctx context.Context, c DebugCapture) error {
+	if !s.features.Has(FeatureDebugCapture) {
+		return fmt.Errorf("debug capture: %w", ErrFeatureUnavailable)
+	}
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO tbl_debug_capture (capture_id, org_id, transport, status_code, reason, content_type,
+		                               payload, payload_size, truncated, captured_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, c.CaptureID, c.OrgID, c.Transport, c.StatusCode, c.Reason, c.ContentType,
+		c.Payload, c.PayloadSize, c.Truncated, c.CapturedAt, c.ExpiresAt)
+	if err != nil {
+		return classifyError(fmt.Errorf("failed to insert debug capture %s: %w", c.CaptureID, err))
+	}
+	return nil
+}
+
+// ListDebugCaptures returns up to limit unexpired captures, newest first,
+// without their payloads; orgID "" lists all orgs
+func (s *PostgresStore) ListDebugCaptures(ctx context.Context, orgID string, limit int) ([]*DebugCapture, error) {
+	if !s.features.Has(FeatureDebugCapture) {
+		return nil, fmt.Errorf("debug capture: %w", ErrFeatureUnavailable)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT capture_id, org_id, transport, status_code, reason, content_type,
+		       payload_size, truncated, captured_at, expires_at
+		FROM tbl_debug_capture
+		WHERE ($1 = '' OR org_id = $1) AND expires_at > NOW()
+		ORDER BY captured_at DESC
+		LIMIT $2
+	`, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list debug captures: %w", err)
+	}
+	defer rows.Close()
+
+	var captures []*DebugCapture
+	for rows.Next() {
+		var c DebugCapture
+		if err := rows.Scan(&c.CaptureID, &c.OrgID, &c.Transport, &c.StatusCode, &c.Reason, &c.ContentType,
+			&c.PayloadSize, &c.Truncated, &c.CapturedAt, &c.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan debug capture: %w", err)
+		}
+		captures = append(captures, &c)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating debug captures: %w", rows.Err())
+	}
+	return captures, nil
+}
+
+// GetDebugCapture returns an unexpired capture with its payload
+func (s *PostgresStore) GetDebugCapture(ctx context.Context, captureID string) (*DebugCapture, error) {
+	if !s.features.Has(FeatureDebugCapture) {
+		return nil, fmt.Errorf("debug capture: %w", ErrFeatureUnavailable)
+	}
+
+	var c DebugCapture
+	err := s.db.QueryRow(ctx, `
+		SELECT capture_id, org_id, transport, status_code, reason, content_type,
+		       payload, payload_size, truncated, captured_at, expires_at
+		FROM tbl_debug_capture
+		WHERE capture_id = $1 AND expires_at > NOW()
+	`, captureID).Scan(&c.CaptureID, &c.OrgID, &c.Transport, &c.StatusCode, &c.Reason, &c.ContentType,
+		&c.Payload, &c.PayloadSize, &c.Truncated, &c.CapturedAt, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCaptureNotFound
+		}
+		return nil, fmt.Errorf("failed to get debug capture %s: %w", captureID, err)
+	}
+	return &c, nil
+}
+
+// PurgeDebugCaptures deletes the expired captures
+func (s *PostgresStore) PurgeDebugCaptures(ctx context.Context) (int64, error) {
+	if !s.features.Has(FeatureDebugCapture) {
+		return 0, fmt.Errorf("debug capture: %w", ErrFeatureUnavailable)
+	}
+
+	tag, err := s.db.Exec(ctx, `DELETE FROM tbl_debug_capture WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge debug captures: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// apiTokenColumns are the columns scanned by scanAPIToken
+const apiTokenColumns = `token_id, org_id, description, scopes, secret_hash, created_at, expires_at, revoked_at, COALESCE(rotated_to, '')`
+
+// scanAPIToken scans a row of apiTokenColumns
+func scanAPIToken(row pgx.Row) (*APIToken, error) {
+	var t APIToken
+	if err := row.Scan(&t.TokenID, &t.OrgID, &t.Description, &t.Scopes, &t.SecretHash,
+		&t.CreatedAt, &t.ExpiresAt, &t.RevokedAt, &t.RotatedTo); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateAPIToken stores a new API token
+func (s *PostgresStore) CreateAPIToken(ctx context.Context, t APIToken) error {
+	if !s.features.Has(FeatureAPIToken) {
+		return fmt.Errorf("api tokens: %w", ErrFeatureUnavailable)
+	}
+	if err := insertAPIToken(ctx, s.db, t); err != nil {
+		return classifyError(fmt.Errorf("failed to create api token %s: %w", t.TokenID, err))
+	}
+	return nil
+}
+
+// insertAPIToken inserts a token with db, the pool or a transaction
+func insertAPIToken(ctx context.Context, db interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}, t APIToken) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO tbl_api_token (token_id, org_id, description, scopes, secret_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, t.TokenID, t.OrgID, t.Description, t.Scopes, t.SecretHash, t.CreatedAt, t.ExpiresAt)
+	return err
+}
+
+// GetAPIToken returns an API token by ID
+func (s *PostgresStore) GetAPIToken(ctx context.Context, tokenID string) (*APIToken, error) {
+	if !s.features.Has(FeatureAPIToken) {
+		return nil, fmt.Errorf("api tokens: %w", ErrFeatureUnavailable)
+	}
+	t, err := scanAPIToken(s.db.QueryRow(ctx, `SELECT `+apiTokenColumns+` FROM tbl_api_token WHERE token_id = $1`, tokenID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get api token %s: %w", tokenID, err)
+	}
+	return t, nil
+}
+
+// GetAPITokenByHash returns the API token with the given secret hash
+func (s *PostgresStore) GetAPITokenByHash(ctx context.Context, secretHash string) (*APIToken, error) {
+	if !s.features.Has(FeatureAPIToken) {
+		return nil, fmt.Errorf("api tokens: %w", ErrFeatureUnavailable)
+	}
+	t, err := scanAPIToken(s.db.QueryRow(ctx, `SELECT `+apiTokenColumns+` FROM tbl_api_token WHERE secret_hash = $1`, secretHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get api token by hash: %w", err)
+	}
+	return t, nil
+}
+
+// ListAPITokens returns the API tokens of an org, newest first
+func (s *PostgresStore) ListAPITokens(ctx context.Context, orgID string) ([]*APIToken, error) {
+	if !s.features.Has(FeatureAPIToken) {
+		return nil, fmt.Errorf("api tokens: %w", ErrFeatureUnavailable)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+apiTokenColumns+`
+		FROM tbl_api_token
+		WHERE $1 = '' OR org_id = $1
+		ORDER BY created_at DESC, token_id
+	`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	if rows.Err() != nil {
+		return nil, fmt.Errorf("error iterating api tokens: %w", rows.Err())
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken rejects a token from at on, keeping an earlier revocation time
+func (s *PostgresStore) RevokeAPIToken(ctx context.Context, tokenID string, at time.Time) error {
+	if !s.features.Has(FeatureAPIToken) {
+		return fmt.Errorf("api tokens: %w", ErrFeatureUnavailable)
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		UPDATE tbl_api_token SET revoked_at = LEAST(COALESCE(revoked_at, $2), $2)
+		WHERE token_id = $1
+	`, tokenID, at)
+	if err != nil {
+		return classifyError(fmt.Errorf("failed to revoke api token %s: %w", tokenID, err))
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// RotateAPIToken stores next as the replacement of an active token and revokes the old one from revokeAt on
+func (s *PostgresStore) RotateAPIToken(ctx context.Context, tokenID string, next APIToken, revokeAt time.Time) error {
+	if !s.features.Has(FeatureAPIToken) {
+		return fmt.Errorf("api tokens: %w", ErrFeatureUnavailable)
+	}
+
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// Only an active, not yet rotated token can be rotated, so a token is replaced at most once
+		tag, err := tx.Exec(ctx, `
+			UPDATE tbl_api_token SET revoked_at = LEAST(COALESCE(revoked_at, $2), $2), rotated_to = $3
+			WHERE token_id = $1 AND rotated_to IS NULL AND expires_at > NOW()
+			  AND (revoked_at IS NULL OR revoked_at > NOW())
+		`, tokenID, revokeAt, next.TokenID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAPITokenNotFound
+		}
+		return insertAPIToken(ctx, tx, next)
+	})
+	if errors.Is(err, ErrAPITokenNotFound) {
+		return err
+	}
+	if err != nil {
+		return classifyError(fmt.Errorf("failed to rotate api token %s: %w", tokenID, err))
+	}
+	return nil
+}
+
+// PurgeTestTraffic deletes the completed or failed test traffic received before the cutoff
+func (s *PostgresStore) PurgeTestTraffic(ctx context.Context, before time.Time) (int64, error) {
+	if !s.features.Has(FeatureTestTraffic) {
+		return 0, fmt.Errorf("test traffic: %w", ErrFeatureUnavailable)
+	}
+
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM tbl_log_status
+		WHERE test_traffic AND status IN ($1, $2) AND received_timestamp < $3`,
+		StatusCompleted, StatusFailed, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge test traffic: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// snapshotTables are the tables exported by ExportSnapshot, with the primary
+// key that orders their rows. Tables missing from older schemas are skipped.
+var snapshotTables = []struct{ name, orderBy string }{
+	{"tbl_schema_version", "version"},
+	{"tbl_log_status", "request_id"},
+	{"tbl_attestation_proof", "log_hash"},
+	{"tbl_merkle_proof", "log_hash"},
+	{"tbl_local_queue", "request_id"},
+}
+
+// ExportSnapshot copies the attestation tables in one REPEATABLE READ, READ
+// ONLY transaction, so all tables reflect the same point in time. Timestamps
+// are rendered in UTC so that exports of the same state are byte-identical.
+func (s *PostgresStore) ExportSnapshot(ctx context.Context, open func(table string) (io.WriteCloser, error)) (*Snapshot, error) {
+	snapshot := &Snapshot{SchemaVersion: s.schema.Version}
+	txOptions := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err := s.db.BeginTxFunc(ctx, txOptions, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SET LOCAL TimeZone = 'UTC'`); err != nil {
+			return fmt.Errorf("failed to set snapshot time zone: %w", err)
+		}
+		if err := tx.QueryRow(ctx, `SELECT NOW()`).Scan(&snapshot.TakenAt); err != nil {
+			return fmt.Errorf("failed to read snapshot time: %w", err)
+		}
+
+		for _, table := range snapshotTables {
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, table.name).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to look up %s: %w", table.name, err)
+			}
+			if !exists {
+				continue
+			}
+
+			w, err := open(table.name)
+			if err != nil {
+				return fmt.Errorf("failed to open destination for %s: %w", table.name, err)
+			}
+			tag, err := tx.Conn().PgConn().CopyTo(ctx, w,
+				fmt.Sprintf(`COPY (SELECT * FROM %s ORDER BY %s) TO STDOUT WITH (FORMAT csv, HEADER)`, table.name, table.orderBy))
+			if closeErr := w.Close(); err == nil && closeErr != nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", table.name, err)
+			}
+			snapshot.Tables = append(snapshot.Tables, SnapshotTable{Name: table.name, Rows: tag.RowsAffected()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
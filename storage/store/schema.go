@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// SchemaVersion is the State DB schema version this binary is built for. Every
+// schema change appends a row to tbl_schema_version (scripts/db/init-db.sql)
+// and bumps this constant.
+//
+// Rolling (blue/green) upgrades follow expand/contract:
+//   - expand: the new version only adds tables or nullable columns and keeps
+//     min_compatible, so old and new binaries run side by side. Rows written by
+//     old binaries leave the new columns NULL, and readers must accept that.
+//   - contract: once no old binaries remain, a later version raises
+//     min_compatible. Only then may columns be dropped, renamed or made NOT NULL.
+const SchemaVersion = 18
+
+// MinSchemaVersion is the oldest schema this binary can run against. Features
+// introduced after the database's version are switched off.
+const MinSchemaVersion = 1
+
+// Feature is an optional part of the schema, available from a given version
+type Feature string
+
+const (
+	FeatureRegion          Feature = "region"           // tbl_log_status.region
+	FeatureClientTimestamp Feature = "client_timestamp" // tbl_log_status.client_timestamp
+	FeatureExportCursor    Feature = "export_cursor"    // tbl_export_cursor
+	FeatureOrgUsage        Feature = "org_usage"        // tbl_org_usage
+	FeatureProofCache      Feature = "proof_cache"      // tbl_attestation_proof
+	FeatureLocalQueue      Feature = "local_queue"      // tbl_local_queue
+	FeatureBatchID         Feature = "batch_id"         // tbl_log_status.gateway_batch_id, engine_batch_id
+	FeatureFleet           Feature = "fleet"            // tbl_worker_instances, tbl_log_status.engine_instance_id
+	FeatureLogFields       Feature = "log_fields"       // tbl_log_status.severity, source_host, application
+	FeatureDebugCapture    Feature = "debug_capture"    // tbl_debug_capture
+	FeatureTestTraffic     Feature = "test_traffic"     // tbl_log_status.test_traffic
+	FeatureMerkleProof     Feature = "merkle_proof"     // tbl_merkle_proof
+	FeatureAPIToken        Feature = "api_token"        // tbl_api_token
+	FeatureOutbox          Feature = "outbox"           // tbl_outbox
+	FeatureSequence        Feature = "sequence"         // tbl_log_status.sequence, tbl_source_sequence, tbl_sequence_gap
+	FeatureExternalID      Feature = "external_id"      // tbl_log_status.external_id
+	FeatureParkedMessage   Feature = "parked_message"   // tbl_parked_message
+)
+
+// featureSince maps each feature to the schema version that introduced it
+var featureSince = map[Feature]int{
+	FeatureRegion:          2,
+	FeatureClientTimestamp: 3,
+	FeatureExportCursor:    4,
+	FeatureOrgUsage:        5,
+	FeatureProofCache:      6,
+	FeatureLocalQueue:      7,
+	FeatureBatchID:         8,
+	FeatureFleet:           9,
+	FeatureLogFields:       10,
+	FeatureDebugCapture:    11,
+	FeatureTestTraffic:     12,
+	FeatureMerkleProof:     13,
+	FeatureAPIToken:        14,
+	FeatureOutbox:          15,
+	FeatureSequence:        16,
+	FeatureExternalID:      17,
+	FeatureParkedMessage:   18,
+}
+
+// ErrIncompatibleSchema indicates a database schema this binary must not run against
+var ErrIncompatibleSchema = errors.New("incompatible database schema")
+
+// ErrFeatureUnavailable indicates an operation that needs a newer database schema
+var ErrFeatureUnavailable = errors.New("not supported by the database schema version")
+
+// SchemaInfo is the version state recorded in tbl_schema_version
+type SchemaInfo struct {
+	Version       int // Highest applied schema version
+	MinCompatible int // Oldest binary SchemaVersion allowed to run against the database
+}
+
+// CheckCompatible reports whether this binary may run against the database
+func (i SchemaInfo) CheckCompatible() error {
+	if i.Version < MinSchemaVersion {
+		return fmt.Errorf("database schema v%d is older than v%d required by this binary; apply the migrations first", i.Version, MinSchemaVersion)
+	}
+	if SchemaVersion < i.MinCompatible {
+		return fmt.Errorf("database schema v%d requires binaries built for v%d or later, this binary is built for v%d", i.Version, i.MinCompatible, SchemaVersion)
+	}
+	return nil
+}
+
+// DualWriteWindow reports whether binaries that do not write every column of
+// the current schema may still be running (min_compatible < version)
+func (i SchemaInfo) DualWriteWindow() bool {
+	return i.MinCompatible < i.Version
+}
+
+// Features returns the features available at this schema version
+func (i SchemaInfo) Features() Features {
+	features := make(Features, len(featureSince))
+	for feature, since := range featureSince {
+		if since <= i.Version {
+			features[feature] = true
+		}
+	}
+	return features
+}
+
+// Features is the set of schema features the store may read and write
+type Features map[Feature]bool
+
+// Has reports whether a feature is available
+func (f Features) Has(feature Feature) bool {
+	return f[feature]
+}
+
+func (f Features) String() string {
+	names := make([]string, 0, len(f))
+	for feature := range f {
+		names = append(names, string(feature))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// loadSchemaInfo reads the schema version. Databases created before schema
+// versioning have no tbl_schema_version and are treated as version 1.
+func loadSchemaInfo(ctx context.Context, db *pgxpool.Pool) (SchemaInfo, bool, error) {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT to_regclass('tbl_schema_version') IS NOT NULL`).Scan(&exists); err != nil {
+		return SchemaInfo{}, false, fmt.Errorf("failed to look up tbl_schema_version: %w", err)
+	}
+	if !exists {
+		return SchemaInfo{Version: 1, MinCompatible: 1}, false, nil
+	}
+
+	var info SchemaInfo
+	err := db.QueryRow(ctx,
+		`SELECT version, min_compatible FROM tbl_schema_version ORDER BY version DESC LIMIT 1`,
+	).Scan(&info.Version, &info.MinCompatible)
+	if err != nil {
+		return SchemaInfo{}, true, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return info, true, nil
+}
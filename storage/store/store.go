@@ -3,12 +3,27 @@ package store
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"time"
 )
 
-// Store-level errors
+// Store-level errors. Backends wrap driver errors in them, so callers can
+// branch with errors.Is instead of matching messages.
 var (
-	ErrLogNotFound = errors.New("log not found")
+	// ErrNotFound is wrapped by every "not found" error of the store
+	ErrNotFound = errors.New("not found")
+
+	// ErrDuplicateRequestID indicates a write rejected because its request_id is
+	// already stored; the earlier write stands, so retrying it is idempotent
+	ErrDuplicateRequestID = errors.New("duplicate request_id")
+
+	// ErrConflict indicates a write that lost against a concurrent one
+	// (serialization failure, deadlock) or violated a uniqueness constraint other
+	// than the request_id; a write that lost a race may succeed when retried
+	ErrConflict = errors.New("conflicting write")
+
+	ErrLogNotFound = fmt.Errorf("log %w", ErrNotFound)
 )
 
 // Status defines the task status enum type
@@ -19,14 +34,36 @@ const (
 	StatusProcessing Status = "PROCESSING"
 	StatusCompleted  Status = "COMPLETED"
 	StatusFailed     Status = "FAILED"
+	// StatusQueuedLocal marks a submission accepted while Kafka was unavailable.
+	// Its message waits in the local queue until the gateway's relay publishes
+	// it, which moves it to RECEIVED.
+	StatusQueuedLocal Status = "QUEUED_LOCAL"
+)
+
+// ConflictPolicy defines how InsertLogStatusBatch treats rows whose request_id already exists
+type ConflictPolicy string
+
+const (
+	// ConflictDoNothing keeps the existing row untouched (default)
+	ConflictDoNothing ConflictPolicy = "do_nothing"
+	// ConflictUpdate overwrites the existing row if it has not been anchored yet
+	// (status RECEIVED or FAILED), resetting it to RECEIVED with a zero retry count
+	ConflictUpdate ConflictPolicy = "update"
 )
 
+// InsertResult reports the outcome of a batch insert per request_id
+type InsertResult struct {
+	Inserted []string // Rows newly created
+	Updated  []string // Existing rows overwritten under ConflictUpdate
+	Skipped  []string // Rows left untouched because of a conflict
+}
+
 // CompletionRecord represents a completed log record for batch updates
 type CompletionRecord struct {
 	RequestID      string
-	TxHash         string
+	TxHash         string // Empty if the anchoring transaction is not known
 	LogHashOnChain string
-	BlockHeight    uint64
+	BlockHeight    uint64 // 0 if not known
 }
 
 // FailureRecord represents a failed log record for batch updates
@@ -35,6 +72,201 @@ type FailureRecord struct {
 	ErrorMessage string
 }
 
+// LogOutcome is the final state of a submission as recorded in the status
+// events topic, used to rebuild the State DB from Kafka
+type LogOutcome struct {
+	RequestID         string
+	LogHash           string
+	SourceOrgID       string
+	Region            string
+	ReceivedTimestamp time.Time
+	Status            Status // COMPLETED or FAILED
+	RetryCount        int
+	TxHash            string // COMPLETED only
+	BlockHeight       uint64 // COMPLETED only
+	ErrorMessage      string // FAILED only
+	FinishedAt        time.Time
+}
+
+// ExportCursor is the keyset position of an incremental export over completed records
+type ExportCursor struct {
+	FinishedAt time.Time // processing_finished_at of the last exported record
+	RequestID  string    // request_id of the last exported record (tie-breaker)
+}
+
+// OrgUsage is the number of submissions accepted for an org in an accounting period
+type OrgUsage struct {
+	Period      string // Accounting period, e.g. "2026-10" for monthly quotas
+	OrgID       string
+	Submissions int64
+}
+
+// AttestationProof is a cached copy of a log's on-chain record and the
+// transaction that anchored it, so verification need not query the chain
+type AttestationProof struct {
+	LogHash         string
+	SenderOrgID     string
+	Timestamp       string // On-chain timestamp as anchored; empty if not known exactly (see Complete)
+	ClientTimestamp string // On-chain client timestamp; empty if none or not known exactly
+	LogContent      string
+	TxHash          string // Empty if the proof was read back from the chain without its transaction
+	BlockHeight     uint64
+	Source          string    // What populated the entry: ProofSourceWorker, ProofSourceReconcile or ProofSourceChain
+	CachedAt        time.Time // Set by the store
+}
+
+// Complete reports whether the proof holds the full on-chain record. Proofs
+// reconciled from a peer region lack the exact on-chain timestamps.
+func (p *AttestationProof) Complete() bool {
+	return p.Timestamp != ""
+}
+
+// Attestation proof sources
+const (
+	ProofSourceWorker    = "worker"    // Anchored by an engine worker
+	ProofSourceReconcile = "reconcile" // Anchored by a peer region and reconciled locally
+	ProofSourceChain     = "chain"     // Read back from the chain by a verification call
+)
+
+// ErrProofNotFound indicates that no attestation proof is cached for a log hash
+var ErrProofNotFound = fmt.Errorf("attestation proof %w", ErrNotFound)
+
+// MerkleProof is the inclusion proof of a log anchored in Merkle-root mode:
+// the chain holds only the root, anchored by transaction TxHash
+type MerkleProof struct {
+	LogHash     string
+	MerkleRoot  string // Hex-encoded root anchored on chain
+	LeafIndex   int
+	LeafCount   int
+	Siblings    []string // Hex-encoded sibling hashes from the leaf level up
+	TxHash      string
+	BlockHeight uint64
+	CreatedAt   time.Time // Set by the store
+}
+
+// ErrMerkleProofNotFound indicates that no Merkle proof is stored for a log hash
+var ErrMerkleProofNotFound = fmt.Errorf("merkle proof %w", ErrNotFound)
+
+// LocalMessage is a Kafka message held in the local queue while Kafka is unavailable
+type LocalMessage struct {
+	RequestID string
+	Tier      string // Gateway batch path the message belongs to; selects the topic it is relayed to
+	Payload   []byte // JSON-encoded message
+	QueuedAt  time.Time
+}
+
+// OutboxMessage is a Kafka message written to the outbox in the transaction
+// that stored its submission
+type OutboxMessage struct {
+	RequestID string
+	Tier      string // Gateway batch path the message belongs to; selects the topic it is relayed to
+	Payload   []byte // JSON-encoded message
+	CreatedAt time.Time
+}
+
+// ParkedMessage is the log message of a task that was PROCESSING when it was
+// delivered again, kept to be published again if the task returns to RECEIVED
+type ParkedMessage struct {
+	RequestID string
+	Topic     string // Topic or subject the message was consumed from
+	Payload   []byte // JSON-encoded message
+	ParkedAt  time.Time
+	Status    Status // Status of the task when listed
+}
+
+// SequenceGap is a range of sequence numbers missing from a source
+type SequenceGap struct {
+	OrgID        string
+	SourceHost   string // Empty if the source has no host
+	Application  string // Empty if the source has no application
+	FirstMissing int64
+	LastMissing  int64
+	DetectedAt   time.Time
+}
+
+// Missing returns how many sequence numbers the gap covers
+func (g SequenceGap) Missing() int64 {
+	return g.LastMissing - g.FirstMissing + 1
+}
+
+// WorkerInstance is an engine instance registered in the fleet table
+type WorkerInstance struct {
+	InstanceID    string
+	Version       string // Build version of the engine binary
+	Hostname      string
+	Region        string
+	Partitions    []int // Kafka partitions the instance consumed at its last heartbeat
+	StartedAt     time.Time
+	LastHeartbeat time.Time
+	StoppedAt     *time.Time // Set when the instance shut down cleanly
+	ReclaimedAt   *time.Time // Set when another instance reclaimed its tasks after it stopped heartbeating
+}
+
+// ReclaimedInstance is a dead instance whose PROCESSING tasks were returned to RECEIVED
+type ReclaimedInstance struct {
+	InstanceID    string
+	LastHeartbeat time.Time
+	Tasks         int64
+}
+
+// DebugCapture is the raw request of a rejected submission, kept for a
+// limited time so support can reproduce client serialization bugs
+type DebugCapture struct {
+	CaptureID   string
+	OrgID       string
+	Transport   string // http or grpc
+	StatusCode  int    // HTTP status, or gRPC code, returned to the client
+	Reason      string // Error returned to the client
+	ContentType string
+	Payload     []byte // Request bytes as stored (encrypted by the gateway); nil in listings
+	PayloadSize int    // Size of the request, before truncation
+	Truncated   bool   // Payload was cut at the capture size limit
+	CapturedAt  time.Time
+	ExpiresAt   time.Time
+}
+
+// ErrCaptureNotFound indicates that no unexpired debug capture has the given ID
+var ErrCaptureNotFound = fmt.Errorf("debug capture %w", ErrNotFound)
+
+// APIToken is an org-scoped API token. Only the hash of its secret is stored,
+// so a lost token cannot be recovered, only rotated or revoked.
+type APIToken struct {
+	TokenID     string
+	OrgID       string
+	Description string
+	Scopes      []string
+	SecretHash  string // Hex-encoded SHA-256 of the token
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	RevokedAt   *time.Time // Time from which the token is rejected; nil if not revoked
+	RotatedTo   string     // Token that replaced this one on rotation; "" if none
+}
+
+// Active reports whether the token authenticates callers at now
+func (t *APIToken) Active(now time.Time) bool {
+	if !now.Before(t.ExpiresAt) {
+		return false
+	}
+	return t.RevokedAt == nil || now.Before(*t.RevokedAt)
+}
+
+// ErrAPITokenNotFound indicates that no API token (or no active one, where
+// noted) has the given ID or hash
+var ErrAPITokenNotFound = fmt.Errorf("api token %w", ErrNotFound)
+
+// Snapshot describes a consistent export of the attestation tables
+type Snapshot struct {
+	TakenAt       time.Time // Database time at which the snapshot was taken
+	SchemaVersion int
+	Tables        []SnapshotTable // In export order
+}
+
+// SnapshotTable is one table of a snapshot
+type SnapshotTable struct {
+	Name string
+	Rows int64
+}
+
 // LogStatus is the Go struct corresponding to the database table Tbl_Log_Status
 type LogStatus struct {
 	RequestID            string     `db:"request_id"`
@@ -50,13 +282,53 @@ type LogStatus struct {
 	LogHashOnChain       *string    `db:"log_hash_on_chain"`
 	ErrorMessage         *string    `db:"error_message"`
 	RetryCount           int        `db:"retry_count"`
+	Region               string     `db:"region"`           // Region that accepted the submission; empty in single-region deployments
+	ClientTimestamp      *time.Time `db:"client_timestamp"` // Client-reported event time after the timestamp policy; nil if not provided
+	GatewayBatchID       string     `db:"gateway_batch_id"` // Gateway batch that persisted and published the submission
+	EngineBatchID        string     `db:"engine_batch_id"`  // Engine batch that last picked the task up for anchoring
+	Severity             string     `db:"severity"`         // Canonical severity (see models.Severities); empty if not provided
+	SourceHost           string     `db:"source_host"`      // Host that produced the log; empty if not provided
+	Application          string     `db:"application"`      // Application that produced the log; empty if not provided
+	TestTraffic          bool       `db:"test_traffic"`     // Synthetic or test submission, excluded from statistics and purged after its TTL
+	Sequence             int64      `db:"sequence"`         // Position in the source's sequence (org, source host, application); 0 if unsequenced
+	ExternalID           string     `db:"external_id"`      // Client's own ID of the submission, unique per org; empty if not provided
+}
+
+// LogFilter selects the submissions of an org; empty fields match everything
+type LogFilter struct {
+	OrgID       string
+	Severity    string
+	Application string
+	SourceHost  string
+	Status      Status
+	Since       time.Time // Received at or after
+	Until       time.Time // Received before
+	TestTraffic *bool     // Test traffic only (true) or real traffic only (false); nil matches both
+}
+
+// LogCursor is the position after the last listed submission, ordered by
+// (received_timestamp, request_id)
+type LogCursor struct {
+	ReceivedTimestamp time.Time
+	RequestID         string
+}
+
+// LogStatusCount is the number of submissions with a status, severity and application
+type LogStatusCount struct {
+	Status      Status
+	Severity    string
+	Application string
+	Count       int64
 }
 
 // Store is the data storage interface
 type Store interface {
 
-	// GetAndMarkBatchAsProcessing attempts to batch lock tasks with RECEIVED status
-	GetAndMarkBatchAsProcessing(ctx context.Context, requestIDs []string, maxRetries int) (map[string]*LogStatus, error)
+	// GetAndMarkBatchAsProcessing attempts to batch lock tasks with RECEIVED status,
+	// recording batchID as the engine batch and instanceID as the engine instance
+	// that picked them up. Tasks that reached maxRetries are marked FAILED
+	// instead and returned with that status.
+	GetAndMarkBatchAsProcessing(ctx context.Context, requestIDs []string, maxRetries int, batchID, instanceID string) (map[string]*LogStatus, error)
 
 	// MarkBatchAsCompleted marks multiple tasks as successfully completed in a single transaction
 	MarkBatchAsCompleted(ctx context.Context, completions []CompletionRecord) error
@@ -67,15 +339,217 @@ type Store interface {
 	// MarkBatchForRetry restores a batch of tasks to Received and increments retry count
 	MarkBatchForRetry(ctx context.Context, requestIDs []string, lastError string) error
 
-	// InsertLogStatusBatch performs bulk insertion of log statuses
-	InsertLogStatusBatch(ctx context.Context, statuses []*LogStatus) error
+	// MarkBatchDeferred restores a batch of PROCESSING tasks to Received
+	// without incrementing retry count, for tasks set aside rather than failed
+	MarkBatchDeferred(ctx context.Context, requestIDs []string, reason string) error
+
+	// ListStuckTasks returns up to limit tasks that have been PROCESSING for
+	// longer than stuckFor, oldest first
+	ListStuckTasks(ctx context.Context, stuckFor time.Duration, limit int) ([]*LogStatus, error)
+
+	// RequeueFailedTasks returns FAILED tasks to RECEIVED with a fresh retry
+	// budget. It returns the given tasks that are RECEIVED afterwards, whose log
+	// messages can be published again.
+	RequeueFailedTasks(ctx context.Context, requestIDs []string) ([]string, error)
+
+	// InsertLogStatusBatch performs bulk insertion of log statuses, resolving
+	// duplicate request_ids according to policy. A backend that cannot resolve
+	// a duplicate per row fails the batch with ErrDuplicateRequestID.
+	InsertLogStatusBatch(ctx context.Context, statuses []*LogStatus, policy ConflictPolicy) (*InsertResult, error)
+
+	// RestoreLogOutcomes writes final outcomes, inserting submissions that are
+	// missing and never downgrading a COMPLETED one. It returns the rows written.
+	RestoreLogOutcomes(ctx context.Context, outcomes []LogOutcome) (int64, error)
 
 	// GetLogStatusByRequestID queries log status by request_id
 	GetLogStatusByRequestID(ctx context.Context, requestID string) (*LogStatus, error)
 
+	// GetLogStatusByExternalID queries the log status an org submitted under an
+	// external ID, ErrLogNotFound if there is none
+	GetLogStatusByExternalID(ctx context.Context, orgID, externalID string) (*LogStatus, error)
+
 	// GetLogStatusByHash queries log status by log_hash
 	GetLogStatusByHash(ctx context.Context, logHash string) (*LogStatus, error)
 
+	// ListLogStatusByHash returns up to limit records with the log hash,
+	// submitted by orgID unless it is empty, ordered by (received_timestamp, request_id)
+	ListLogStatusByHash(ctx context.Context, logHash, orgID string, limit int) ([]*LogStatus, error)
+
+	// SearchLogStatus returns up to limit records matching the filter, positioned
+	// after the cursor and ordered by (received_timestamp, request_id)
+	SearchLogStatus(ctx context.Context, filter LogFilter, after LogCursor, limit int) ([]*LogStatus, error)
+
+	// CountLogStatus counts the records matching the filter by status, severity and application
+	CountLogStatus(ctx context.Context, filter LogFilter) ([]LogStatusCount, error)
+
+	// GetCompletedByHashes returns COMPLETED records for the given log hashes, keyed by log_hash
+	GetCompletedByHashes(ctx context.Context, logHashes []string) (map[string]*LogStatus, error)
+
+	// GetProcessingByHashes returns PROCESSING records for the given log hashes,
+	// the one picked up first for each, keyed by log_hash
+	GetProcessingByHashes(ctx context.Context, logHashes []string) (map[string]*LogStatus, error)
+
+	// ListUnqueuedReceived returns the given request_ids that are still RECEIVED
+	// and have no outbox row, so their messages may never have been published
+	ListUnqueuedReceived(ctx context.Context, requestIDs []string) ([]string, error)
+
+	// GetTerminalStatuses returns the status of the given tasks that are COMPLETED
+	// or FAILED, keyed by request_id, without locking them
+	GetTerminalStatuses(ctx context.Context, requestIDs []string) (map[string]Status, error)
+
+	// CountRetryBacklog returns the number of RECEIVED tasks waiting to be retried
+	CountRetryBacklog(ctx context.Context) (int64, error)
+
+	// ListCompletedAfter returns up to limit COMPLETED records positioned after the
+	// cursor and finished before the given time, ordered by (processing_finished_at, request_id)
+	ListCompletedAfter(ctx context.Context, after ExportCursor, before time.Time, limit int) ([]*LogStatus, error)
+
+	// ListLogStatusByBatchID returns up to limit records whose gateway or engine
+	// batch is batchID, ordered by request_id
+	ListLogStatusByBatchID(ctx context.Context, batchID string, limit int) ([]*LogStatus, error)
+
+	// GetExportCursor returns the saved position of the named export (zero cursor if none)
+	GetExportCursor(ctx context.Context, name string) (ExportCursor, error)
+
+	// SaveExportCursor persists the position of the named export
+	SaveExportCursor(ctx context.Context, name string, cursor ExportCursor) error
+
+	// AddOrgUsage adds the given submission counts to the usage totals and returns
+	// the resulting totals. A zero count reads the current total.
+	AddOrgUsage(ctx context.Context, deltas []OrgUsage) ([]OrgUsage, error)
+
+	// SaveAttestationProofs caches attestation proofs. Fields already cached for
+	// a log hash are kept; empty ones are filled in from the new proof.
+	SaveAttestationProofs(ctx context.Context, proofs []AttestationProof) error
+
+	// GetAttestationProof returns the cached proof for a log hash (ErrProofNotFound if none)
+	GetAttestationProof(ctx context.Context, logHash string) (*AttestationProof, error)
+
+	// SaveMerkleProofs stores Merkle inclusion proofs. A log hash that already
+	// has a proof keeps it: its earlier root is anchored as well.
+	SaveMerkleProofs(ctx context.Context, proofs []MerkleProof) error
+
+	// GetMerkleProof returns the Merkle proof of a log hash (ErrMerkleProofNotFound if none)
+	GetMerkleProof(ctx context.Context, logHash string) (*MerkleProof, error)
+
+	// QueueLocalMessages holds messages in the local queue and moves their
+	// submissions from RECEIVED to QUEUED_LOCAL
+	QueueLocalMessages(ctx context.Context, messages []LocalMessage) error
+
+	// ClaimLocalMessages leases up to limit queued messages of a tier, oldest
+	// first, and moves their submissions to RECEIVED. Messages not released
+	// within lease can be claimed again.
+	ClaimLocalMessages(ctx context.Context, tier string, limit int, lease time.Duration) ([]LocalMessage, error)
+
+	// ReleaseLocalMessages ends a claim. Published messages leave the queue;
+	// the others return to it and their submissions to QUEUED_LOCAL.
+	ReleaseLocalMessages(ctx context.Context, requestIDs []string, published bool) error
+
+	// InsertLogStatusBatchWithOutbox inserts like InsertLogStatusBatch and, in
+	// the same transaction, writes the messages of the rows it inserted or
+	// updated to the outbox, claimed by the caller for lease
+	InsertLogStatusBatchWithOutbox(ctx context.Context, statuses []*LogStatus, policy ConflictPolicy, messages []OutboxMessage, lease time.Duration) (*InsertResult, error)
+
+	// ClaimOutboxMessages leases up to limit unsent outbox messages of a tier
+	// whose claim has expired, oldest first. Concurrent relays claim disjoint sets.
+	ClaimOutboxMessages(ctx context.Context, tier string, limit int, lease time.Duration) ([]OutboxMessage, error)
+
+	// MarkOutboxSent marks outbox messages as published
+	MarkOutboxSent(ctx context.Context, requestIDs []string) error
+
+	// ReleaseOutboxMessages ends a claim on unpublished outbox messages, so
+	// the next relay pass retries them
+	ReleaseOutboxMessages(ctx context.Context, requestIDs []string) error
+
+	// PurgeOutbox deletes outbox messages sent before the given time, returning how many
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
+
+	// ParkMessages keeps the messages of the tasks that are still PROCESSING,
+	// skipping the others and tasks that already have one. It returns how many
+	// messages it kept.
+	ParkMessages(ctx context.Context, messages []ParkedMessage) (int64, error)
+
+	// ListReleasedParkedMessages returns up to limit parked messages of the
+	// given topics whose task is no longer PROCESSING, oldest first
+	ListReleasedParkedMessages(ctx context.Context, topics []string, limit int) ([]ParkedMessage, error)
+
+	// DeleteParkedMessages deletes parked messages
+	DeleteParkedMessages(ctx context.Context, requestIDs []string) error
+
+	// DetectSequenceGaps checks the sources with sequenced submissions received
+	// from after until grace before now, records the numbers skipped since each
+	// source's last check and returns the newly recorded gaps and the end of the
+	// scanned range, to pass as after on the next call. Numbers below the first
+	// one seen from a source are not gaps.
+	DetectSequenceGaps(ctx context.Context, after time.Time, grace time.Duration) ([]SequenceGap, time.Time, error)
+
+	// ListSequenceGaps returns up to limit recorded gaps of an org, newest first
+	ListSequenceGaps(ctx context.Context, orgID string, limit int) ([]SequenceGap, error)
+
+	// HeartbeatInstance registers an engine instance or refreshes its heartbeat
+	HeartbeatInstance(ctx context.Context, instance WorkerInstance) error
+
+	// StopInstance marks an instance as cleanly stopped and returns its
+	// PROCESSING tasks to RECEIVED, returning how many were returned
+	StopInstance(ctx context.Context, instanceID string) (int64, error)
+
+	// ListWorkerInstances returns the registered instances, ordered by instance ID
+	ListWorkerInstances(ctx context.Context) ([]*WorkerInstance, error)
+
+	// ReclaimDeadInstances returns the PROCESSING tasks of instances that have
+	// not heartbeated within deadAfter to RECEIVED, counting a retry, and marks
+	// those instances as reclaimed. Concurrent callers reclaim disjoint instances.
+	ReclaimDeadInstances(ctx context.Context, deadAfter time.Duration) ([]ReclaimedInstance, error)
+
+	// InsertDebugCapture stores the raw request of a rejected submission
+	InsertDebugCapture(ctx context.Context, capture DebugCapture) error
+
+	// ListDebugCaptures returns up to limit unexpired captures, newest first,
+	// without their payloads; orgID "" lists all orgs
+	ListDebugCaptures(ctx context.Context, orgID string, limit int) ([]*DebugCapture, error)
+
+	// GetDebugCapture returns an unexpired capture with its payload (ErrCaptureNotFound if none)
+	GetDebugCapture(ctx context.Context, captureID string) (*DebugCapture, error)
+
+	// PurgeDebugCaptures deletes the expired captures and returns how many were deleted
+	PurgeDebugCaptures(ctx context.Context) (int64, error)
+
+	// CreateAPIToken stores a new API token
+	CreateAPIToken(ctx context.Context, token APIToken) error
+
+	// GetAPIToken returns an API token by ID, active or not (ErrAPITokenNotFound if none)
+	GetAPIToken(ctx context.Context, tokenID string) (*APIToken, error)
+
+	// GetAPITokenByHash returns the API token with the given secret hash,
+	// active or not (ErrAPITokenNotFound if none)
+	GetAPITokenByHash(ctx context.Context, secretHash string) (*APIToken, error)
+
+	// ListAPITokens returns the API tokens of an org, newest first, including
+	// revoked and expired ones; orgID "" lists all orgs
+	ListAPITokens(ctx context.Context, orgID string) ([]*APIToken, error)
+
+	// RevokeAPIToken rejects a token from at on; an earlier revocation time
+	// is kept (ErrAPITokenNotFound if the token does not exist)
+	RevokeAPIToken(ctx context.Context, tokenID string, at time.Time) error
+
+	// RotateAPIToken stores next as the replacement of an active token and
+	// revokes the old one from revokeAt on, in one transaction
+	// (ErrAPITokenNotFound if no active token has the ID)
+	RotateAPIToken(ctx context.Context, tokenID string, next APIToken, revokeAt time.Time) error
+
+	// PurgeTestTraffic deletes the completed or failed test traffic received
+	// before the cutoff and returns how many records were deleted
+	PurgeTestTraffic(ctx context.Context, before time.Time) (int64, error)
+
+	// ExportSnapshot copies the attestation tables the schema has, from a
+	// single consistent read-only snapshot, as CSV with a header row and rows
+	// ordered by primary key. open is called once per table for its destination.
+	ExportSnapshot(ctx context.Context, open func(table string) (io.WriteCloser, error)) (*Snapshot, error)
+
+	// Writable returns nil if the database accepts writes, and an error
+	// wrapping ErrReadOnly if it is a read-only standby, e.g. during failover
+	Writable(ctx context.Context) error
+
 	// Close closes the database connection
 	Close()
 }
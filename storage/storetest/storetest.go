@@ -0,0 +1,1970 @@
+// Package storetest provides a conformance test suite for store.Store implementations.
+// Every backend (PostgreSQL, MySQL, SQLite, ...) must pass this suite so that the
+// API Gateway, Engine and Query services observe identical semantics regardless
+// of the database in use.
+//
+// Usage from a backend's own test file:
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) store.Store {
+//			s, err := NewMySQLStore(...)
+//			if err != nil {
+//				t.Fatalf("failed to create store: %v", err)
+//			}
+//			return s
+//		})
+//	}
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"tlng/blockchain/types"
+	"tlng/storage/store"
+
+	"github.com/google/uuid"
+)
+
+// Factory returns a ready-to-use Store. The suite closes the store when the
+// subtest finishes. Stores may be shared between subtests; every subtest uses
+// freshly generated request IDs and log hashes so no cleanup is required.
+type Factory func(t *testing.T) store.Store
+
+// Run executes the full conformance suite against the store returned by newStore
+func Run(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store)
+	}{
+		{"InsertAndGetByRequestID", testInsertAndGetByRequestID},
+		{"InsertAndGetByHash", testInsertAndGetByHash},
+		{"ListByHash", testListByHash},
+		{"InsertEmptyBatch", testInsertEmptyBatch},
+		{"InsertDuplicateRequestID", testInsertDuplicateRequestID},
+		{"InsertDuplicateWithinBatch", testInsertDuplicateWithinBatch},
+		{"InsertConflictUpdate", testInsertConflictUpdate},
+		{"RestoreLogOutcomes", testRestoreLogOutcomes},
+		{"GetNotFound", testGetNotFound},
+		{"Writable", testWritable},
+		{"MarkProcessingOnlyReceived", testMarkProcessingOnlyReceived},
+		{"MarkProcessingIgnoresUnknownIDs", testMarkProcessingIgnoresUnknownIDs},
+		{"MarkCompleted", testMarkCompleted},
+		{"MarkCompletedRequiresProcessing", testMarkCompletedRequiresProcessing},
+		{"ListStuckTasks", testListStuckTasks},
+		{"MarkFailed", testMarkFailed},
+		{"RetryCounting", testRetryCounting},
+		{"RetryLimitMarksFailed", testRetryLimitMarksFailed},
+		{"DeferKeepsRetryCount", testDeferKeepsRetryCount},
+		{"RequeueFailedTasks", testRequeueFailedTasks},
+		{"ConcurrentWorkersLockDisjointSets", testConcurrentWorkersLockDisjointSets},
+		{"RegionRoundTrip", testRegionRoundTrip},
+		{"ClientTimestampRoundTrip", testClientTimestampRoundTrip},
+		{"BatchIDRoundTrip", testBatchIDRoundTrip},
+		{"SearchAndCountByLogFields", testSearchAndCountByLogFields},
+		{"TestTrafficFilterAndPurge", testTestTrafficFilterAndPurge},
+		{"GetCompletedByHashes", testGetCompletedByHashes},
+		{"GetProcessingByHashes", testGetProcessingByHashes},
+		{"GetTerminalStatuses", testGetTerminalStatuses},
+		{"ListUnqueuedReceived", testListUnqueuedReceived},
+		{"CountRetryBacklog", testCountRetryBacklog},
+		{"ListCompletedAfter", testListCompletedAfter},
+		{"ExportCursorRoundTrip", testExportCursorRoundTrip},
+		{"OrgUsageAccumulates", testOrgUsageAccumulates},
+		{"AttestationProofRoundTrip", testAttestationProofRoundTrip},
+		{"MerkleProofRoundTrip", testMerkleProofRoundTrip},
+		{"NotFoundErrorsAreTyped", testNotFoundErrorsAreTyped},
+		{"LocalQueueRelay", testLocalQueueRelay},
+		{"OutboxRelay", testOutboxRelay},
+		{"ParkedMessages", testParkedMessages},
+		{"SequenceGaps", testSequenceGaps},
+		{"ExternalIDs", testExternalIDs},
+		{"WorkerInstances", testWorkerInstances},
+		{"DebugCaptures", testDebugCaptures},
+		{"APITokens", testAPITokens},
+		{"ExportSnapshot", testExportSnapshot},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newStore(t)
+			defer s.Close()
+			tc.fn(t, s)
+		})
+	}
+}
+
+// newStatuses builds n RECEIVED log statuses with unique request IDs and hashes
+func newStatuses(n int, orgID string) []*store.LogStatus {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	statuses := make([]*store.LogStatus, n)
+	for i := range statuses {
+		statuses[i] = &store.LogStatus{
+			RequestID:         uuid.NewString(),
+			LogHash:           fmt.Sprintf("storetest-%s", uuid.NewString()),
+			SourceOrgID:       orgID,
+			ReceivedTimestamp: now,
+			Status:            store.StatusReceived,
+		}
+	}
+	return statuses
+}
+
+// requestIDsOf extracts request IDs from a slice of statuses
+func requestIDsOf(statuses []*store.LogStatus) []string {
+	ids := make([]string, len(statuses))
+	for i, s := range statuses {
+		ids[i] = s.RequestID
+	}
+	return ids
+}
+
+// mustInsert inserts statuses and fails the test on error
+func mustInsert(t *testing.T, s store.Store, statuses []*store.LogStatus) {
+	t.Helper()
+	if _, err := s.InsertLogStatusBatch(context.Background(), statuses, store.ConflictDoNothing); err != nil {
+		t.Fatalf("InsertLogStatusBatch failed: %v", err)
+	}
+}
+
+// mustGet fetches a status by request ID and fails the test on error
+func mustGet(t *testing.T, s store.Store, requestID string) *store.LogStatus {
+	t.Helper()
+	status, err := s.GetLogStatusByRequestID(context.Background(), requestID)
+	if err != nil {
+		t.Fatalf("GetLogStatusByRequestID(%s) failed: %v", requestID, err)
+	}
+	return status
+}
+
+// mustMarkProcessing locks the given IDs and fails the test on error
+func mustMarkProcessing(t *testing.T, s store.Store, requestIDs []string, maxRetries int) map[string]*store.LogStatus {
+	t.Helper()
+	tasks, err := s.GetAndMarkBatchAsProcessing(context.Background(), requestIDs, maxRetries, "", "")
+	if err != nil {
+		t.Fatalf("GetAndMarkBatchAsProcessing failed: %v", err)
+	}
+	return tasks
+}
+
+func testInsertAndGetByRequestID(t *testing.T, s store.Store) {
+	statuses := newStatuses(3, "org-insert")
+	mustInsert(t, s, statuses)
+
+	for _, want := range statuses {
+		got := mustGet(t, s, want.RequestID)
+		if got.LogHash != want.LogHash {
+			t.Errorf("log_hash = %q, want %q", got.LogHash, want.LogHash)
+		}
+		if got.SourceOrgID != want.SourceOrgID {
+			t.Errorf("source_org_id = %q, want %q", got.SourceOrgID, want.SourceOrgID)
+		}
+		if got.Status != store.StatusReceived {
+			t.Errorf("status = %s, want %s", got.Status, store.StatusReceived)
+		}
+		if got.RetryCount != 0 {
+			t.Errorf("retry_count = %d, want 0", got.RetryCount)
+		}
+		if !got.ReceivedTimestamp.Equal(want.ReceivedTimestamp) {
+			t.Errorf("received_timestamp = %v, want %v", got.ReceivedTimestamp, want.ReceivedTimestamp)
+		}
+		if got.ReceivedAtDB.IsZero() {
+			t.Errorf("received_at_db must be set by the store")
+		}
+		if got.ProcessingStartedAt != nil || got.ProcessingFinishedAt != nil {
+			t.Errorf("processing timestamps must be unset for a RECEIVED task")
+		}
+		if got.TxHash != nil || got.BlockHeight != nil || got.ErrorMessage != nil {
+			t.Errorf("completion fields must be unset for a RECEIVED task")
+		}
+	}
+}
+
+func testInsertAndGetByHash(t *testing.T, s store.Store) {
+	statuses := newStatuses(1, "org-hash")
+	mustInsert(t, s, statuses)
+
+	got, err := s.GetLogStatusByHash(context.Background(), statuses[0].LogHash)
+	if err != nil {
+		t.Fatalf("GetLogStatusByHash failed: %v", err)
+	}
+	if got.RequestID != statuses[0].RequestID {
+		t.Errorf("request_id = %q, want %q", got.RequestID, statuses[0].RequestID)
+	}
+}
+
+func testListByHash(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	statuses := append(newStatuses(2, "org-hash-a"), newStatuses(1, "org-hash-b")...)
+	for i, st := range statuses {
+		st.LogHash = statuses[0].LogHash
+		st.ReceivedTimestamp = st.ReceivedTimestamp.Add(time.Duration(i) * time.Second)
+	}
+	mustInsert(t, s, statuses)
+
+	all, err := s.ListLogStatusByHash(ctx, statuses[0].LogHash, "", 10)
+	if err != nil {
+		t.Fatalf("ListLogStatusByHash failed: %v", err)
+	}
+	if got := requestIDsOf(all); !slices.Equal(got, requestIDsOf(statuses)) {
+		t.Errorf("records = %v, want %v in submission order", got, requestIDsOf(statuses))
+	}
+
+	byOrg, err := s.ListLogStatusByHash(ctx, statuses[0].LogHash, "org-hash-b", 10)
+	if err != nil {
+		t.Fatalf("ListLogStatusByHash failed: %v", err)
+	}
+	assertIDs(t, "records of org-hash-b", requestIDsOf(byOrg), statuses[2].RequestID)
+
+	limited, err := s.ListLogStatusByHash(ctx, statuses[0].LogHash, "", 2)
+	if err != nil {
+		t.Fatalf("ListLogStatusByHash failed: %v", err)
+	}
+	assertIDs(t, "first 2 records", requestIDsOf(limited), requestIDsOf(statuses[:2])...)
+
+	if none, err := s.ListLogStatusByHash(ctx, "storetest-missing-"+uuid.NewString(), "", 10); err != nil || len(none) != 0 {
+		t.Errorf("ListLogStatusByHash(unknown hash) = %v, %v, want no records", none, err)
+	}
+}
+
+func testInsertEmptyBatch(t *testing.T, s store.Store) {
+	result, err := s.InsertLogStatusBatch(context.Background(), nil, store.ConflictDoNothing)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatch(nil) = %v, want nil", err)
+	}
+	if len(result.Inserted)+len(result.Updated)+len(result.Skipped) != 0 {
+		t.Fatalf("InsertLogStatusBatch(nil) reported rows: %+v", result)
+	}
+	tasks := mustMarkProcessing(t, s, nil, 3)
+	if len(tasks) != 0 {
+		t.Fatalf("GetAndMarkBatchAsProcessing(nil) returned %d tasks, want 0", len(tasks))
+	}
+}
+
+func testInsertDuplicateRequestID(t *testing.T, s store.Store) {
+	statuses := newStatuses(1, "org-dup")
+	mustInsert(t, s, statuses)
+
+	// Re-inserting the same request_id must not fail nor overwrite the original row
+	dup := *statuses[0]
+	dup.LogHash = "storetest-overwritten"
+	fresh := newStatuses(1, "org-dup")
+	result, err := s.InsertLogStatusBatch(context.Background(), []*store.LogStatus{&dup, fresh[0]}, store.ConflictDoNothing)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatch failed: %v", err)
+	}
+	assertIDs(t, "inserted", result.Inserted, fresh[0].RequestID)
+	assertIDs(t, "updated", result.Updated)
+	assertIDs(t, "skipped", result.Skipped, dup.RequestID)
+
+	got := mustGet(t, s, statuses[0].RequestID)
+	if got.LogHash != statuses[0].LogHash {
+		t.Errorf("duplicate insert overwrote log_hash: got %q, want %q", got.LogHash, statuses[0].LogHash)
+	}
+}
+
+func testInsertDuplicateWithinBatch(t *testing.T, s store.Store) {
+	for _, policy := range []store.ConflictPolicy{store.ConflictDoNothing, store.ConflictUpdate} {
+		fresh := newStatuses(1, "org-dup-batch")
+		second := *fresh[0]
+		result, err := s.InsertLogStatusBatch(context.Background(), []*store.LogStatus{fresh[0], &second}, policy)
+		if err != nil {
+			t.Fatalf("%s: InsertLogStatusBatch with in-batch duplicate failed: %v", policy, err)
+		}
+		assertIDs(t, string(policy)+" inserted", result.Inserted, fresh[0].RequestID)
+		assertIDs(t, string(policy)+" skipped", result.Skipped, fresh[0].RequestID)
+	}
+}
+
+func testInsertConflictUpdate(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-upsert")
+	mustInsert(t, s, statuses)
+
+	// Lock and complete the second task: anchored rows must never be overwritten
+	mustMarkProcessing(t, s, []string{statuses[1].RequestID}, 3)
+	if err := s.MarkBatchAsCompleted(context.Background(), []store.CompletionRecord{{
+		RequestID:      statuses[1].RequestID,
+		TxHash:         "tx-anchored",
+		LogHashOnChain: statuses[1].LogHash,
+		BlockHeight:    7,
+	}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+
+	pending := *statuses[0]
+	pending.LogHash = "storetest-upserted-" + uuid.NewString()
+	anchored := *statuses[1]
+	anchored.LogHash = "storetest-upserted-" + uuid.NewString()
+
+	result, err := s.InsertLogStatusBatch(context.Background(), []*store.LogStatus{&pending, &anchored}, store.ConflictUpdate)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatch(update) failed: %v", err)
+	}
+	assertIDs(t, "inserted", result.Inserted)
+	assertIDs(t, "updated", result.Updated, pending.RequestID)
+	assertIDs(t, "skipped", result.Skipped, anchored.RequestID)
+
+	if got := mustGet(t, s, pending.RequestID); got.LogHash != pending.LogHash || got.Status != store.StatusReceived {
+		t.Errorf("pending row = (%q, %s), want (%q, %s)", got.LogHash, got.Status, pending.LogHash, store.StatusReceived)
+	}
+	if got := mustGet(t, s, anchored.RequestID); got.LogHash != statuses[1].LogHash || got.Status != store.StatusCompleted {
+		t.Errorf("anchored row overwritten: (%q, %s)", got.LogHash, got.Status)
+	}
+}
+
+func testRestoreLogOutcomes(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-restore")
+	mustInsert(t, s, statuses)
+	finished := time.Now().UTC().Truncate(time.Microsecond)
+
+	// An existing RECEIVED row, a missing submission, and a FAILED outcome
+	// arriving after COMPLETED for the same request in one batch
+	missing := uuid.NewString()
+	outcomes := []store.LogOutcome{
+		{RequestID: statuses[0].RequestID, LogHash: statuses[0].LogHash, SourceOrgID: "org-restore",
+			ReceivedTimestamp: statuses[0].ReceivedTimestamp, Status: store.StatusCompleted,
+			TxHash: "tx-restored", BlockHeight: 11, FinishedAt: finished},
+		{RequestID: missing, LogHash: "storetest-" + uuid.NewString(), SourceOrgID: "org-restore",
+			ReceivedTimestamp: finished, Status: store.StatusFailed, RetryCount: 3,
+			ErrorMessage: "rejected", FinishedAt: finished},
+		{RequestID: statuses[1].RequestID, LogHash: statuses[1].LogHash, SourceOrgID: "org-restore",
+			ReceivedTimestamp: statuses[1].ReceivedTimestamp, Status: store.StatusCompleted,
+			TxHash: "tx-first", BlockHeight: 12, FinishedAt: finished},
+		{RequestID: statuses[1].RequestID, LogHash: statuses[1].LogHash, SourceOrgID: "org-restore",
+			ReceivedTimestamp: statuses[1].ReceivedTimestamp, Status: store.StatusCompleted,
+			TxHash: "tx-last", BlockHeight: 13, FinishedAt: finished},
+	}
+	written, err := s.RestoreLogOutcomes(context.Background(), outcomes)
+	if err != nil {
+		t.Fatalf("RestoreLogOutcomes failed: %v", err)
+	}
+	if written != 3 {
+		t.Errorf("RestoreLogOutcomes wrote %d rows, want 3", written)
+	}
+
+	got := mustGet(t, s, statuses[0].RequestID)
+	if got.Status != store.StatusCompleted || got.TxHash == nil || *got.TxHash != "tx-restored" ||
+		got.BlockHeight == nil || *got.BlockHeight != 11 || got.LogHashOnChain == nil || *got.LogHashOnChain != statuses[0].LogHash {
+		t.Errorf("restored completion = %+v", got)
+	}
+	if got := mustGet(t, s, statuses[1].RequestID); got.TxHash == nil || *got.TxHash != "tx-last" {
+		t.Errorf("in-batch duplicate did not keep the last outcome: %+v", got)
+	}
+	got = mustGet(t, s, missing)
+	if got.Status != store.StatusFailed || got.RetryCount != 3 || got.ErrorMessage == nil || *got.ErrorMessage != "rejected" {
+		t.Errorf("restored failure = %+v", got)
+	}
+
+	// A COMPLETED row is never downgraded
+	downgrade := outcomes[0]
+	downgrade.Status = store.StatusFailed
+	downgrade.ErrorMessage = "late failure"
+	if _, err := s.RestoreLogOutcomes(context.Background(), []store.LogOutcome{downgrade}); err != nil {
+		t.Fatalf("RestoreLogOutcomes(downgrade) failed: %v", err)
+	}
+	if got := mustGet(t, s, statuses[0].RequestID); got.Status != store.StatusCompleted {
+		t.Errorf("completed row downgraded to %s", got.Status)
+	}
+}
+
+// assertIDs checks that got contains exactly the wanted request IDs, in any order
+func assertIDs(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+		return
+	}
+	wanted := make(map[string]int, len(want))
+	for _, id := range want {
+		wanted[id]++
+	}
+	for _, id := range got {
+		if wanted[id] == 0 {
+			t.Errorf("%s = %v, want %v", what, got, want)
+			return
+		}
+		wanted[id]--
+	}
+}
+
+func testGetNotFound(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	if _, err := s.GetLogStatusByRequestID(ctx, uuid.NewString()); !errors.Is(err, store.ErrLogNotFound) {
+		t.Errorf("GetLogStatusByRequestID error = %v, want ErrLogNotFound", err)
+	}
+	if _, err := s.GetLogStatusByHash(ctx, "storetest-missing-"+uuid.NewString()); !errors.Is(err, store.ErrLogNotFound) {
+		t.Errorf("GetLogStatusByHash error = %v, want ErrLogNotFound", err)
+	}
+}
+
+// testWritable expects the conformance database to be a writable primary
+func testWritable(t *testing.T, s store.Store) {
+	if err := s.Writable(context.Background()); err != nil {
+		t.Fatalf("Writable: %v", err)
+	}
+	if store.IsReadOnly(errors.New("unrelated")) {
+		t.Error("IsReadOnly(unrelated error) = true")
+	}
+	if !store.IsReadOnly(fmt.Errorf("wrapped: %w", store.ErrReadOnly)) {
+		t.Error("IsReadOnly(wrapped ErrReadOnly) = false")
+	}
+}
+
+func testMarkProcessingOnlyReceived(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-lock")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	tasks := mustMarkProcessing(t, s, ids, 3)
+	if len(tasks) != len(ids) {
+		t.Fatalf("first lock returned %d tasks, want %d", len(tasks), len(ids))
+	}
+	for _, id := range ids {
+		task, ok := tasks[id]
+		if !ok {
+			t.Fatalf("task %s missing from result", id)
+		}
+		if task.Status != store.StatusProcessing {
+			t.Errorf("task %s status = %s, want %s", id, task.Status, store.StatusProcessing)
+		}
+		if task.ProcessingStartedAt == nil {
+			t.Errorf("task %s processing_started_at not set", id)
+		}
+	}
+
+	// A second call must not return tasks that are already PROCESSING
+	again := mustMarkProcessing(t, s, ids, 3)
+	if len(again) != 0 {
+		t.Fatalf("second lock returned %d tasks, want 0", len(again))
+	}
+}
+
+func testMarkProcessingIgnoresUnknownIDs(t *testing.T, s store.Store) {
+	tasks := mustMarkProcessing(t, s, []string{uuid.NewString(), uuid.NewString()}, 3)
+	if len(tasks) != 0 {
+		t.Fatalf("GetAndMarkBatchAsProcessing returned %d tasks for unknown IDs, want 0", len(tasks))
+	}
+}
+
+func testListStuckTasks(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-org-" + uuid.NewString()
+	statuses := newStatuses(3, org)
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+	mustMarkProcessing(t, s, ids[:2], 3)
+
+	recent, err := s.ListStuckTasks(ctx, time.Hour, 1000)
+	if err != nil {
+		t.Fatalf("ListStuckTasks failed: %v", err)
+	}
+	for _, task := range recent {
+		if task.RequestID == ids[0] || task.RequestID == ids[1] {
+			t.Errorf("task %s listed as stuck for an hour right after it was locked", task.RequestID)
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	stuck, err := s.ListStuckTasks(ctx, time.Millisecond, 1000)
+	if err != nil {
+		t.Fatalf("ListStuckTasks failed: %v", err)
+	}
+	var own []string
+	for _, task := range stuck {
+		if task.Status != store.StatusProcessing {
+			t.Errorf("stuck task %s is %s, want PROCESSING", task.RequestID, task.Status)
+		}
+		if task.SourceOrgID == org {
+			own = append(own, task.RequestID)
+		}
+	}
+	assertIDs(t, "stuck", own, ids[0], ids[1])
+
+	// A task found on chain completes without a known transaction
+	if err := s.MarkBatchAsCompleted(ctx, []store.CompletionRecord{{RequestID: ids[0], LogHashOnChain: statuses[0].LogHash}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+	got := mustGet(t, s, ids[0])
+	if got.Status != store.StatusCompleted || got.TxHash != nil || got.BlockHeight != nil {
+		t.Errorf("completed without a transaction: status %s, tx_hash %v, block_height %v; want COMPLETED, nil, nil",
+			got.Status, got.TxHash, got.BlockHeight)
+	}
+}
+
+func testMarkCompleted(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-complete")
+	mustInsert(t, s, statuses)
+	mustMarkProcessing(t, s, requestIDsOf(statuses), 3)
+
+	completions := make([]store.CompletionRecord, len(statuses))
+	for i, st := range statuses {
+		completions[i] = store.CompletionRecord{
+			RequestID:      st.RequestID,
+			TxHash:         fmt.Sprintf("tx-%d", i),
+			LogHashOnChain: st.LogHash,
+			BlockHeight:    uint64(100 + i),
+		}
+	}
+	if err := s.MarkBatchAsCompleted(context.Background(), completions); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+
+	for i, st := range statuses {
+		got := mustGet(t, s, st.RequestID)
+		if got.Status != store.StatusCompleted {
+			t.Errorf("status = %s, want %s", got.Status, store.StatusCompleted)
+		}
+		if got.TxHash == nil || *got.TxHash != completions[i].TxHash {
+			t.Errorf("tx_hash = %v, want %q", got.TxHash, completions[i].TxHash)
+		}
+		if got.BlockHeight == nil || *got.BlockHeight != int64(completions[i].BlockHeight) {
+			t.Errorf("block_height = %v, want %d", got.BlockHeight, completions[i].BlockHeight)
+		}
+		if got.LogHashOnChain == nil || *got.LogHashOnChain != st.LogHash {
+			t.Errorf("log_hash_on_chain = %v, want %q", got.LogHashOnChain, st.LogHash)
+		}
+		if got.ProcessingFinishedAt == nil {
+			t.Errorf("processing_finished_at not set")
+		}
+		if got.ErrorMessage != nil {
+			t.Errorf("error_message = %q, want nil", *got.ErrorMessage)
+		}
+	}
+}
+
+func testMarkCompletedRequiresProcessing(t *testing.T, s store.Store) {
+	statuses := newStatuses(1, "org-complete-guard")
+	mustInsert(t, s, statuses)
+
+	// Completing a task that was never locked must leave it untouched
+	err := s.MarkBatchAsCompleted(context.Background(), []store.CompletionRecord{{
+		RequestID:      statuses[0].RequestID,
+		TxHash:         "tx-unlocked",
+		LogHashOnChain: statuses[0].LogHash,
+		BlockHeight:    1,
+	}})
+	if err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+
+	got := mustGet(t, s, statuses[0].RequestID)
+	if got.Status != store.StatusReceived {
+		t.Errorf("status = %s, want %s", got.Status, store.StatusReceived)
+	}
+	if got.TxHash != nil {
+		t.Errorf("tx_hash = %q, want nil", *got.TxHash)
+	}
+}
+
+func testMarkFailed(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-fail")
+	mustInsert(t, s, statuses)
+	mustMarkProcessing(t, s, requestIDsOf(statuses), 3)
+
+	failures := []store.FailureRecord{
+		{RequestID: statuses[0].RequestID, ErrorMessage: "contract rejected"},
+		{RequestID: statuses[1].RequestID, ErrorMessage: "missing result"},
+	}
+	if err := s.MarkBatchAsFailed(context.Background(), failures); err != nil {
+		t.Fatalf("MarkBatchAsFailed failed: %v", err)
+	}
+
+	for i, st := range statuses {
+		got := mustGet(t, s, st.RequestID)
+		if got.Status != store.StatusFailed {
+			t.Errorf("status = %s, want %s", got.Status, store.StatusFailed)
+		}
+		if got.ErrorMessage == nil || *got.ErrorMessage != failures[i].ErrorMessage {
+			t.Errorf("error_message = %v, want %q", got.ErrorMessage, failures[i].ErrorMessage)
+		}
+		if got.ProcessingFinishedAt == nil {
+			t.Errorf("processing_finished_at not set")
+		}
+	}
+
+	// Failing an already FAILED task must not overwrite the original error
+	if err := s.MarkBatchAsFailed(context.Background(), []store.FailureRecord{
+		{RequestID: statuses[0].RequestID, ErrorMessage: "second failure"},
+	}); err != nil {
+		t.Fatalf("MarkBatchAsFailed (repeat) failed: %v", err)
+	}
+	got := mustGet(t, s, statuses[0].RequestID)
+	if got.ErrorMessage == nil || *got.ErrorMessage != failures[0].ErrorMessage {
+		t.Errorf("repeat failure overwrote error_message: got %v, want %q", got.ErrorMessage, failures[0].ErrorMessage)
+	}
+}
+
+func testRetryCounting(t *testing.T, s store.Store) {
+	statuses := newStatuses(1, "org-retry")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if tasks := mustMarkProcessing(t, s, ids, 5); len(tasks) != 1 {
+			t.Fatalf("attempt %d: lock returned %d tasks, want 1", attempt, len(tasks))
+		}
+		if err := s.MarkBatchForRetry(context.Background(), ids, fmt.Sprintf("transient error %d", attempt)); err != nil {
+			t.Fatalf("attempt %d: MarkBatchForRetry failed: %v", attempt, err)
+		}
+
+		got := mustGet(t, s, ids[0])
+		if got.Status != store.StatusReceived {
+			t.Errorf("attempt %d: status = %s, want %s", attempt, got.Status, store.StatusReceived)
+		}
+		if got.RetryCount != attempt {
+			t.Errorf("attempt %d: retry_count = %d, want %d", attempt, got.RetryCount, attempt)
+		}
+		if got.ProcessingStartedAt != nil {
+			t.Errorf("attempt %d: processing_started_at must be cleared on retry", attempt)
+		}
+		if got.ErrorMessage == nil || *got.ErrorMessage != fmt.Sprintf("transient error %d", attempt) {
+			t.Errorf("attempt %d: error_message = %v", attempt, got.ErrorMessage)
+		}
+	}
+
+	// Retrying a task that is not PROCESSING must be a no-op
+	if err := s.MarkBatchForRetry(context.Background(), ids, "not processing"); err != nil {
+		t.Fatalf("MarkBatchForRetry (not processing) failed: %v", err)
+	}
+	if got := mustGet(t, s, ids[0]); got.RetryCount != 2 {
+		t.Errorf("retry_count = %d after no-op retry, want 2", got.RetryCount)
+	}
+}
+
+func testRetryLimitMarksFailed(t *testing.T, s store.Store) {
+	const maxRetries = 2
+	statuses := newStatuses(1, "org-retry-limit")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	for i := 0; i < maxRetries; i++ {
+		if tasks := mustMarkProcessing(t, s, ids, maxRetries); len(tasks) != 1 {
+			t.Fatalf("retry %d: lock returned %d tasks, want 1", i, len(tasks))
+		}
+		if err := s.MarkBatchForRetry(context.Background(), ids, "transient"); err != nil {
+			t.Fatalf("retry %d: MarkBatchForRetry failed: %v", i, err)
+		}
+	}
+
+	// retry_count has now reached maxRetries: the task must be failed, and
+	// returned as such rather than for processing
+	tasks := mustMarkProcessing(t, s, ids, maxRetries)
+	if len(tasks) != 1 {
+		t.Fatalf("lock at retry limit returned %d tasks, want 1", len(tasks))
+	}
+	if task := tasks[ids[0]]; task == nil || task.Status != store.StatusFailed {
+		t.Fatalf("lock at retry limit returned %+v, want the task with status %s", task, store.StatusFailed)
+	}
+
+	got := mustGet(t, s, ids[0])
+	if got.Status != store.StatusFailed {
+		t.Errorf("status = %s, want %s", got.Status, store.StatusFailed)
+	}
+	if got.ErrorMessage == nil || *got.ErrorMessage == "" {
+		t.Errorf("error_message must explain the retry limit")
+	}
+	if got.ProcessingFinishedAt == nil {
+		t.Errorf("processing_finished_at not set")
+	}
+}
+
+func testDeferKeepsRetryCount(t *testing.T, s store.Store) {
+	const maxRetries = 2
+	statuses := newStatuses(1, "org-defer")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	// Deferring more often than maxRetries neither counts retries nor fails the task
+	for i := 1; i <= maxRetries+1; i++ {
+		tasks := mustMarkProcessing(t, s, ids, maxRetries)
+		if task := tasks[ids[0]]; task == nil || task.Status != store.StatusProcessing {
+			t.Fatalf("deferral %d: lock returned %+v, want the task PROCESSING", i, task)
+		}
+		if err := s.MarkBatchDeferred(context.Background(), ids, "deferred to a peer"); err != nil {
+			t.Fatalf("deferral %d: MarkBatchDeferred failed: %v", i, err)
+		}
+		got := mustGet(t, s, ids[0])
+		if got.Status != store.StatusReceived || got.RetryCount != 0 {
+			t.Errorf("deferral %d: task is %s with retry_count %d, want %s with 0", i, got.Status, got.RetryCount, store.StatusReceived)
+		}
+		if got.ProcessingStartedAt != nil {
+			t.Errorf("deferral %d: processing_started_at must be cleared", i)
+		}
+		if got.ErrorMessage == nil || *got.ErrorMessage != "deferred to a peer" {
+			t.Errorf("deferral %d: error_message = %v", i, got.ErrorMessage)
+		}
+	}
+
+	// Deferring a task that is not PROCESSING is a no-op
+	if err := s.MarkBatchDeferred(context.Background(), ids, "not processing"); err != nil {
+		t.Fatalf("MarkBatchDeferred (not processing) failed: %v", err)
+	}
+	if got := mustGet(t, s, ids[0]); got.ErrorMessage == nil || *got.ErrorMessage != "deferred to a peer" {
+		t.Errorf("no-op deferral changed error_message to %v", got.ErrorMessage)
+	}
+}
+
+func testRequeueFailedTasks(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	statuses := newStatuses(3, "org-requeue")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	// ids[0] FAILED, ids[1] COMPLETED, ids[2] still RECEIVED
+	mustMarkProcessing(t, s, ids[:2], 3)
+	if err := s.MarkBatchAsFailed(ctx, []store.FailureRecord{{RequestID: ids[0], ErrorMessage: "permanent"}}); err != nil {
+		t.Fatalf("MarkBatchAsFailed failed: %v", err)
+	}
+	if err := s.MarkBatchAsCompleted(ctx, []store.CompletionRecord{{RequestID: ids[1], TxHash: "tx-requeue", LogHashOnChain: statuses[1].LogHash, BlockHeight: 1}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+
+	requeued, err := s.RequeueFailedTasks(ctx, append(ids, "unknown-request"))
+	if err != nil {
+		t.Fatalf("RequeueFailedTasks failed: %v", err)
+	}
+	want := []string{ids[0], ids[2]}
+	slices.Sort(want)
+	if !slices.Equal(requeued, want) {
+		t.Errorf("requeued = %v, want %v", requeued, want)
+	}
+
+	got := mustGet(t, s, ids[0])
+	if got.Status != store.StatusReceived || got.RetryCount != 0 || got.ErrorMessage != nil {
+		t.Errorf("requeued task = (%s, retries %d, error %v), want (%s, 0, nil)", got.Status, got.RetryCount, got.ErrorMessage, store.StatusReceived)
+	}
+	if got := mustGet(t, s, ids[1]); got.Status != store.StatusCompleted {
+		t.Errorf("completed task status = %s, want %s", got.Status, store.StatusCompleted)
+	}
+}
+
+func testConcurrentWorkersLockDisjointSets(t *testing.T, s store.Store) {
+	const (
+		workers = 8
+		tasks   = 200
+	)
+	statuses := newStatuses(tasks, "org-concurrent")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	var (
+		mu      sync.Mutex
+		claimed = make(map[string]int)
+		wg      sync.WaitGroup
+		errs    = make(chan error, workers)
+	)
+
+	// Every worker races for the full ID set; each task must be claimed exactly once
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			got, err := s.GetAndMarkBatchAsProcessing(context.Background(), ids, 3, "", "")
+			if err != nil {
+				errs <- fmt.Errorf("worker %d: %w", workerID, err)
+				return
+			}
+			mu.Lock()
+			for id := range got {
+				claimed[id]++
+			}
+			mu.Unlock()
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent GetAndMarkBatchAsProcessing failed: %v", err)
+	}
+	for id, n := range claimed {
+		if n != 1 {
+			t.Errorf("task %s claimed by %d workers, want 1", id, n)
+		}
+	}
+
+	// SKIP LOCKED semantics may leave some tasks unclaimed in this round; a
+	// follow-up pass must pick up exactly the remainder.
+	remaining := mustMarkProcessing(t, s, ids, 3)
+	for id := range remaining {
+		if claimed[id] != 0 {
+			t.Errorf("task %s returned again after being claimed", id)
+		}
+		claimed[id]++
+	}
+	if len(claimed) != tasks {
+		t.Errorf("claimed %d distinct tasks, want %d", len(claimed), tasks)
+	}
+}
+
+func testRegionRoundTrip(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-region")
+	statuses[0].Region = "region-a"
+	mustInsert(t, s, statuses)
+
+	if got := mustGet(t, s, statuses[0].RequestID); got.Region != "region-a" {
+		t.Errorf("region = %q, want %q", got.Region, "region-a")
+	}
+	if got := mustGet(t, s, statuses[1].RequestID); got.Region != "" {
+		t.Errorf("region = %q, want empty", got.Region)
+	}
+}
+
+func testSearchAndCountByLogFields(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-org-" + uuid.NewString() // Isolates this subtest's records in a shared store
+	statuses := newStatuses(4, org)
+	for i, st := range statuses {
+		st.ReceivedTimestamp = st.ReceivedTimestamp.Add(time.Duration(i) * time.Second)
+	}
+	statuses[0].Severity, statuses[0].Application, statuses[0].SourceHost = "ERROR", "billing", "host-a"
+	statuses[1].Severity, statuses[1].Application = "ERROR", "billing"
+	statuses[2].Severity, statuses[2].Application = "INFO", "billing"
+	mustInsert(t, s, statuses)
+
+	got := mustGet(t, s, statuses[0].RequestID)
+	if got.Severity != "ERROR" || got.Application != "billing" || got.SourceHost != "host-a" {
+		t.Errorf("log fields = (%q, %q, %q), want (ERROR, billing, host-a)", got.Severity, got.Application, got.SourceHost)
+	}
+	if got := mustGet(t, s, statuses[3].RequestID); got.Severity != "" || got.Application != "" || got.SourceHost != "" {
+		t.Errorf("log fields of a submission without them = (%q, %q, %q), want empty", got.Severity, got.Application, got.SourceHost)
+	}
+
+	// Pages follow receive order
+	filter := store.LogFilter{OrgID: org, Severity: "ERROR", Application: "billing"}
+	page, err := s.SearchLogStatus(ctx, filter, store.LogCursor{}, 1)
+	if err != nil {
+		t.Fatalf("SearchLogStatus failed: %v", err)
+	}
+	if len(page) != 1 || page[0].RequestID != statuses[0].RequestID {
+		t.Fatalf("first page = %v, want [%s]", requestIDsOf(page), statuses[0].RequestID)
+	}
+	next, err := s.SearchLogStatus(ctx, filter, store.LogCursor{ReceivedTimestamp: page[0].ReceivedTimestamp, RequestID: page[0].RequestID}, 10)
+	if err != nil {
+		t.Fatalf("SearchLogStatus failed: %v", err)
+	}
+	assertIDs(t, "second page", requestIDsOf(next), statuses[1].RequestID)
+
+	all, err := s.SearchLogStatus(ctx, store.LogFilter{OrgID: org, Status: store.StatusReceived}, store.LogCursor{}, 10)
+	if err != nil {
+		t.Fatalf("SearchLogStatus failed: %v", err)
+	}
+	assertIDs(t, "RECEIVED records", requestIDsOf(all), requestIDsOf(statuses)...)
+
+	counts, err := s.CountLogStatus(ctx, store.LogFilter{OrgID: org, Application: "billing"})
+	if err != nil {
+		t.Fatalf("CountLogStatus failed: %v", err)
+	}
+	bySeverity := make(map[string]int64)
+	for _, c := range counts {
+		if c.Status != store.StatusReceived || c.Application != "billing" {
+			t.Errorf("unexpected count group %+v", c)
+		}
+		bySeverity[c.Severity] += c.Count
+	}
+	if bySeverity["ERROR"] != 2 || bySeverity["INFO"] != 1 || len(bySeverity) != 2 {
+		t.Errorf("counts by severity = %v, want map[ERROR:2 INFO:1]", bySeverity)
+	}
+}
+
+func testTestTrafficFilterAndPurge(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-org-" + uuid.NewString()
+	statuses := newStatuses(3, org)
+	for _, st := range statuses {
+		st.ReceivedTimestamp = st.ReceivedTimestamp.Add(-2 * time.Hour)
+	}
+	statuses[0].TestTraffic, statuses[1].TestTraffic = true, true
+	mustInsert(t, s, statuses)
+
+	if got := mustGet(t, s, statuses[0].RequestID); !got.TestTraffic {
+		t.Errorf("test_traffic = false, want true")
+	}
+	testOnly, realOnly := true, false
+	tests, err := s.SearchLogStatus(ctx, store.LogFilter{OrgID: org, TestTraffic: &testOnly}, store.LogCursor{}, 10)
+	if err != nil {
+		t.Fatalf("SearchLogStatus failed: %v", err)
+	}
+	assertIDs(t, "test traffic", requestIDsOf(tests), statuses[0].RequestID, statuses[1].RequestID)
+	counts, err := s.CountLogStatus(ctx, store.LogFilter{OrgID: org, TestTraffic: &realOnly})
+	if err != nil {
+		t.Fatalf("CountLogStatus failed: %v", err)
+	}
+	if len(counts) != 1 || counts[0].Count != 1 {
+		t.Errorf("counts of real traffic = %+v, want a single record", counts)
+	}
+
+	// Only finished test traffic is purged
+	ids := []string{statuses[0].RequestID, statuses[2].RequestID}
+	mustMarkProcessing(t, s, ids, 3)
+	if err := s.MarkBatchAsFailed(ctx, []store.FailureRecord{
+		{RequestID: ids[0], ErrorMessage: "test"}, {RequestID: ids[1], ErrorMessage: "real"},
+	}); err != nil {
+		t.Fatalf("MarkBatchAsFailed failed: %v", err)
+	}
+	purged, err := s.PurgeTestTraffic(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PurgeTestTraffic failed: %v", err)
+	}
+	if purged < 1 {
+		t.Errorf("PurgeTestTraffic purged %d records, want at least the failed test one", purged)
+	}
+	if _, err := s.GetLogStatusByRequestID(ctx, statuses[0].RequestID); !errors.Is(err, store.ErrLogNotFound) {
+		t.Errorf("GetLogStatusByRequestID(purged) error = %v, want ErrLogNotFound", err)
+	}
+	mustGet(t, s, statuses[1].RequestID)
+	mustGet(t, s, statuses[2].RequestID)
+}
+
+func testSequenceGaps(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-org-" + uuid.NewString()
+	insertSequenced := func(sequences ...int64) {
+		statuses := newStatuses(len(sequences), org)
+		for i, st := range statuses {
+			st.SourceHost, st.Application, st.Sequence = "host-a", "app", sequences[i]
+		}
+		mustInsert(t, s, statuses)
+	}
+	// detect returns the new gaps of this subtest's org; other subtests may share the store
+	detect := func(after time.Time) ([]store.SequenceGap, time.Time) {
+		t.Helper()
+		gaps, until, err := s.DetectSequenceGaps(ctx, after, 0)
+		if err != nil {
+			t.Fatalf("DetectSequenceGaps failed: %v", err)
+		}
+		var own []store.SequenceGap
+		for _, g := range gaps {
+			if g.OrgID == org {
+				own = append(own, g)
+			}
+		}
+		return own, until
+	}
+
+	insertSequenced(2, 3, 6)
+	statuses := newStatuses(1, org)
+	mustInsert(t, s, statuses)
+	if got := mustGet(t, s, statuses[0].RequestID).Sequence; got != 0 {
+		t.Errorf("sequence of an unsequenced submission = %d, want 0", got)
+	}
+	gaps, until := detect(time.Now().Add(-time.Hour))
+	if len(gaps) != 1 || gaps[0].FirstMissing != 4 || gaps[0].LastMissing != 5 || gaps[0].SourceHost != "host-a" {
+		t.Fatalf("gaps = %+v, want 4-5 of host-a (numbers before the first one seen are not gaps)", gaps)
+	}
+	if again, _ := detect(time.Now().Add(-time.Hour)); len(again) != 0 {
+		t.Errorf("gaps on rescan = %+v, want none: each gap is recorded once", again)
+	}
+
+	// A late arrival inside a recorded gap does not reopen it, and a reset below the checkpoint is ignored
+	insertSequenced(5, 9, 1)
+	gaps, _ = detect(until)
+	if len(gaps) != 1 || gaps[0].FirstMissing != 7 || gaps[0].LastMissing != 8 || gaps[0].Missing() != 2 {
+		t.Fatalf("gaps = %+v, want 7-8", gaps)
+	}
+
+	listed, err := s.ListSequenceGaps(ctx, org, 10)
+	if err != nil {
+		t.Fatalf("ListSequenceGaps failed: %v", err)
+	}
+	if len(listed) != 2 {
+		t.Fatalf("listed %d gaps, want 2", len(listed))
+	}
+	if listed[1].DetectedAt.After(listed[0].DetectedAt) {
+		t.Errorf("gaps not listed newest first: %+v", listed)
+	}
+}
+
+func testBatchIDRoundTrip(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	gatewayBatch, engineBatch := "storetest-gw-"+uuid.NewString(), "storetest-en-"+uuid.NewString()
+	statuses := newStatuses(3, "org-batch")
+	for _, st := range statuses[:2] {
+		st.GatewayBatchID = gatewayBatch
+	}
+	mustInsert(t, s, statuses)
+
+	tasks, err := s.GetAndMarkBatchAsProcessing(ctx, requestIDsOf(statuses[1:]), 3, engineBatch, "")
+	if err != nil {
+		t.Fatalf("GetAndMarkBatchAsProcessing failed: %v", err)
+	}
+	for id, task := range tasks {
+		if task.EngineBatchID != engineBatch {
+			t.Errorf("engine batch of locked %s = %q, want %q", id, task.EngineBatchID, engineBatch)
+		}
+	}
+
+	got := mustGet(t, s, statuses[1].RequestID)
+	if got.GatewayBatchID != gatewayBatch || got.EngineBatchID != engineBatch {
+		t.Errorf("batch IDs = (%q, %q), want (%q, %q)", got.GatewayBatchID, got.EngineBatchID, gatewayBatch, engineBatch)
+	}
+	if got := mustGet(t, s, statuses[0].RequestID); got.EngineBatchID != "" {
+		t.Errorf("engine batch of a task not picked up = %q, want empty", got.EngineBatchID)
+	}
+
+	byGateway, err := s.ListLogStatusByBatchID(ctx, gatewayBatch, 10)
+	if err != nil {
+		t.Fatalf("ListLogStatusByBatchID failed: %v", err)
+	}
+	ids := make([]string, len(byGateway))
+	for i, st := range byGateway {
+		ids[i] = st.RequestID
+	}
+	assertIDs(t, "gateway batch", ids, requestIDsOf(statuses[:2])...)
+
+	byEngine, err := s.ListLogStatusByBatchID(ctx, engineBatch, 10)
+	if err != nil {
+		t.Fatalf("ListLogStatusByBatchID failed: %v", err)
+	}
+	ids = ids[:0]
+	for _, st := range byEngine {
+		ids = append(ids, st.RequestID)
+	}
+	assertIDs(t, "engine batch", ids, requestIDsOf(statuses[1:])...)
+}
+
+func testClientTimestampRoundTrip(t *testing.T, s store.Store) {
+	statuses := newStatuses(2, "org-client-ts")
+	clientTS := statuses[0].ReceivedTimestamp.Add(-time.Minute)
+	statuses[0].ClientTimestamp = &clientTS
+	mustInsert(t, s, statuses)
+
+	got := mustGet(t, s, statuses[0].RequestID)
+	if got.ClientTimestamp == nil || !got.ClientTimestamp.Equal(clientTS) {
+		t.Errorf("client_timestamp = %v, want %v", got.ClientTimestamp, clientTS)
+	}
+	if !got.ReceivedTimestamp.Equal(statuses[0].ReceivedTimestamp) {
+		t.Errorf("received_timestamp = %v, want %v (server time kept distinct)", got.ReceivedTimestamp, statuses[0].ReceivedTimestamp)
+	}
+	if got := mustGet(t, s, statuses[1].RequestID); got.ClientTimestamp != nil {
+		t.Errorf("client_timestamp = %v, want nil", got.ClientTimestamp)
+	}
+}
+
+func testGetCompletedByHashes(t *testing.T, s store.Store) {
+	statuses := newStatuses(3, "org-completed-hashes")
+	mustInsert(t, s, statuses)
+	mustMarkProcessing(t, s, requestIDsOf(statuses), 3)
+
+	// Only the first task completes; the second stays PROCESSING, the third fails
+	if err := s.MarkBatchAsCompleted(context.Background(), []store.CompletionRecord{{
+		RequestID:      statuses[0].RequestID,
+		TxHash:         "tx-completed",
+		LogHashOnChain: statuses[0].LogHash,
+		BlockHeight:    42,
+	}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+	if err := s.MarkBatchAsFailed(context.Background(), []store.FailureRecord{
+		{RequestID: statuses[2].RequestID, ErrorMessage: "failed"},
+	}); err != nil {
+		t.Fatalf("MarkBatchAsFailed failed: %v", err)
+	}
+
+	hashes := []string{statuses[0].LogHash, statuses[1].LogHash, statuses[2].LogHash, "storetest-unknown-" + uuid.NewString()}
+	got, err := s.GetCompletedByHashes(context.Background(), hashes)
+	if err != nil {
+		t.Fatalf("GetCompletedByHashes failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("GetCompletedByHashes returned %d records, want 1", len(got))
+	}
+	record, ok := got[statuses[0].LogHash]
+	if !ok {
+		t.Fatalf("completed record for %s missing", statuses[0].LogHash)
+	}
+	if record.TxHash == nil || *record.TxHash != "tx-completed" {
+		t.Errorf("tx_hash = %v, want %q", record.TxHash, "tx-completed")
+	}
+
+	empty, err := s.GetCompletedByHashes(context.Background(), nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("GetCompletedByHashes(nil) = %d records, %v; want 0, nil", len(empty), err)
+	}
+}
+
+func testGetProcessingByHashes(t *testing.T, s store.Store) {
+	statuses := newStatuses(3, "org-processing-hashes")
+	mustInsert(t, s, statuses)
+	mustMarkProcessing(t, s, requestIDsOf(statuses[:2]), 3)
+
+	// The first task completes and the second stays PROCESSING; the third was never picked up
+	if err := s.MarkBatchAsCompleted(context.Background(), []store.CompletionRecord{{
+		RequestID:      statuses[0].RequestID,
+		TxHash:         "tx-processing",
+		LogHashOnChain: statuses[0].LogHash,
+		BlockHeight:    7,
+	}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+
+	hashes := []string{statuses[0].LogHash, statuses[1].LogHash, statuses[2].LogHash}
+	got, err := s.GetProcessingByHashes(context.Background(), hashes)
+	if err != nil {
+		t.Fatalf("GetProcessingByHashes failed: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("GetProcessingByHashes returned %d records, want 1", len(got))
+	}
+	record, ok := got[statuses[1].LogHash]
+	if !ok {
+		t.Fatalf("processing record for %s missing", statuses[1].LogHash)
+	}
+	if record.RequestID != statuses[1].RequestID || record.ProcessingStartedAt == nil {
+		t.Errorf("record = %s started at %v, want %s with a start time", record.RequestID, record.ProcessingStartedAt, statuses[1].RequestID)
+	}
+}
+
+func testGetTerminalStatuses(t *testing.T, s store.Store) {
+	statuses := newStatuses(4, "org-terminal-statuses")
+	mustInsert(t, s, statuses)
+	mustMarkProcessing(t, s, requestIDsOf(statuses[:3]), 3)
+
+	// The first task completes and the third fails; the second stays PROCESSING, the fourth RECEIVED
+	if err := s.MarkBatchAsCompleted(context.Background(), []store.CompletionRecord{{
+		RequestID:      statuses[0].RequestID,
+		TxHash:         "tx-terminal",
+		LogHashOnChain: statuses[0].LogHash,
+		BlockHeight:    7,
+	}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+	if err := s.MarkBatchAsFailed(context.Background(), []store.FailureRecord{
+		{RequestID: statuses[2].RequestID, ErrorMessage: "failed"},
+	}); err != nil {
+		t.Fatalf("MarkBatchAsFailed failed: %v", err)
+	}
+
+	got, err := s.GetTerminalStatuses(context.Background(), append(requestIDsOf(statuses), "storetest-unknown-"+uuid.NewString()))
+	if err != nil {
+		t.Fatalf("GetTerminalStatuses failed: %v", err)
+	}
+	want := map[string]store.Status{
+		statuses[0].RequestID: store.StatusCompleted,
+		statuses[2].RequestID: store.StatusFailed,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("GetTerminalStatuses returned %d statuses, want %d: %v", len(got), len(want), got)
+	}
+	for reqID, status := range want {
+		if got[reqID] != status {
+			t.Errorf("status of %s = %q, want %q", reqID, got[reqID], status)
+		}
+	}
+	if got := mustGet(t, s, statuses[1].RequestID); got.Status != store.StatusProcessing {
+		t.Errorf("status of the PROCESSING task = %s after GetTerminalStatuses, want unchanged", got.Status)
+	}
+
+	empty, err := s.GetTerminalStatuses(context.Background(), nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("GetTerminalStatuses(nil) = %d statuses, %v; want 0, nil", len(empty), err)
+	}
+}
+
+func testListUnqueuedReceived(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	statuses := newStatuses(2, "org-unqueued")
+	mustInsert(t, s, statuses)
+	mustMarkProcessing(t, s, requestIDsOf(statuses[1:]), 3)
+
+	// A RECEIVED row whose message waits in the outbox is queued
+	outboxed := newStatuses(1, "org-unqueued")
+	_, err := s.InsertLogStatusBatchWithOutbox(ctx, outboxed, store.ConflictDoNothing,
+		[]store.OutboxMessage{{RequestID: outboxed[0].RequestID, Tier: "storetest-" + uuid.NewString(), Payload: []byte(`{}`)}}, 0)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatchWithOutbox failed: %v", err)
+	}
+
+	ids := append(requestIDsOf(statuses), outboxed[0].RequestID, "storetest-unknown-"+uuid.NewString())
+	got, err := s.ListUnqueuedReceived(ctx, ids)
+	if err != nil {
+		t.Fatalf("ListUnqueuedReceived failed: %v", err)
+	}
+	assertIDs(t, "unqueued", got, statuses[0].RequestID)
+
+	empty, err := s.ListUnqueuedReceived(ctx, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("ListUnqueuedReceived(nil) = %v, %v; want none, nil", empty, err)
+	}
+}
+
+func testCountRetryBacklog(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	before, err := s.CountRetryBacklog(ctx)
+	if err != nil {
+		t.Fatalf("CountRetryBacklog failed: %v", err)
+	}
+
+	statuses := newStatuses(2, "org-backlog")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	// Freshly received tasks are not part of the backlog
+	if got, err := s.CountRetryBacklog(ctx); err != nil || got != before {
+		t.Fatalf("backlog = %d (err %v) after insert, want %d", got, err, before)
+	}
+
+	mustMarkProcessing(t, s, ids[:1], 5)
+	if err := s.MarkBatchForRetry(ctx, ids[:1], "transient error"); err != nil {
+		t.Fatalf("MarkBatchForRetry failed: %v", err)
+	}
+	if got, err := s.CountRetryBacklog(ctx); err != nil || got != before+1 {
+		t.Fatalf("backlog = %d (err %v) after retry, want %d", got, err, before+1)
+	}
+
+	// Picking the task up again removes it from the backlog
+	mustMarkProcessing(t, s, ids[:1], 5)
+	if got, err := s.CountRetryBacklog(ctx); err != nil || got != before {
+		t.Errorf("backlog = %d (err %v) after re-lock, want %d", got, err, before)
+	}
+}
+
+func testListCompletedAfter(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	statuses := newStatuses(3, "org-export")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+	mustMarkProcessing(t, s, ids, 3)
+
+	// The first two complete; the third fails and must never be listed
+	if err := s.MarkBatchAsCompleted(ctx, []store.CompletionRecord{
+		{RequestID: ids[0], TxHash: "tx-export", LogHashOnChain: statuses[0].LogHash, BlockHeight: 7},
+		{RequestID: ids[1], TxHash: "tx-export", LogHashOnChain: statuses[1].LogHash, BlockHeight: 7},
+	}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+	if err := s.MarkBatchAsFailed(ctx, []store.FailureRecord{{RequestID: ids[2], ErrorMessage: "failed"}}); err != nil {
+		t.Fatalf("MarkBatchAsFailed failed: %v", err)
+	}
+
+	first, second := mustGet(t, s, ids[0]), mustGet(t, s, ids[1])
+	if first.ProcessingFinishedAt == nil || second.ProcessingFinishedAt == nil {
+		t.Fatalf("completed records have no processing_finished_at")
+	}
+	// Expected order is (processing_finished_at, request_id)
+	if second.ProcessingFinishedAt.Before(*first.ProcessingFinishedAt) ||
+		(second.ProcessingFinishedAt.Equal(*first.ProcessingFinishedAt) && second.RequestID < first.RequestID) {
+		first, second = second, first
+	}
+
+	start := store.ExportCursor{FinishedAt: first.ProcessingFinishedAt.Add(-time.Microsecond)}
+	before := second.ProcessingFinishedAt.Add(time.Second)
+
+	page, err := s.ListCompletedAfter(ctx, start, before, 1)
+	if err != nil {
+		t.Fatalf("ListCompletedAfter failed: %v", err)
+	}
+	if len(page) != 1 {
+		t.Fatalf("first page has %d records, want 1", len(page))
+	}
+	assertIDs(t, "first page", requestIDsOf(page), first.RequestID)
+	if page[0].BlockHeight == nil || *page[0].BlockHeight != 7 {
+		t.Errorf("block_height = %v, want 7", page[0].BlockHeight)
+	}
+
+	next := store.ExportCursor{FinishedAt: *page[0].ProcessingFinishedAt, RequestID: page[0].RequestID}
+	page, err = s.ListCompletedAfter(ctx, next, before, 10)
+	if err != nil {
+		t.Fatalf("ListCompletedAfter failed: %v", err)
+	}
+	assertIDs(t, "second page", requestIDsOf(page), second.RequestID)
+
+	// Records finished at or after the upper bound are held back
+	page, err = s.ListCompletedAfter(ctx, start, *first.ProcessingFinishedAt, 10)
+	if err != nil {
+		t.Fatalf("ListCompletedAfter failed: %v", err)
+	}
+	if len(page) != 0 {
+		t.Errorf("ListCompletedAfter with upper bound returned %d records, want 0", len(page))
+	}
+}
+
+func testExportCursorRoundTrip(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	name := "storetest-" + uuid.NewString()
+
+	cursor, err := s.GetExportCursor(ctx, name)
+	if err != nil {
+		t.Fatalf("GetExportCursor failed: %v", err)
+	}
+	if !cursor.FinishedAt.IsZero() || cursor.RequestID != "" {
+		t.Fatalf("unknown export cursor = %+v, want zero", cursor)
+	}
+
+	for _, want := range []store.ExportCursor{
+		{FinishedAt: time.Now().UTC().Truncate(time.Microsecond), RequestID: uuid.NewString()},
+		{FinishedAt: time.Now().UTC().Add(time.Minute).Truncate(time.Microsecond), RequestID: uuid.NewString()},
+	} {
+		if err := s.SaveExportCursor(ctx, name, want); err != nil {
+			t.Fatalf("SaveExportCursor failed: %v", err)
+		}
+		got, err := s.GetExportCursor(ctx, name)
+		if err != nil {
+			t.Fatalf("GetExportCursor failed: %v", err)
+		}
+		if !got.FinishedAt.Equal(want.FinishedAt) || got.RequestID != want.RequestID {
+			t.Errorf("export cursor = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func testOrgUsageAccumulates(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	period := "storetest-" + uuid.NewString()
+
+	totals, err := s.AddOrgUsage(ctx, []store.OrgUsage{
+		{Period: period, OrgID: "org-a", Submissions: 3},
+		{Period: period, OrgID: "org-b", Submissions: 1},
+	})
+	if err != nil {
+		t.Fatalf("AddOrgUsage failed: %v", err)
+	}
+	assertUsage(t, totals, map[string]int64{"org-a": 3, "org-b": 1})
+
+	// A zero delta reads the total without changing it
+	totals, err = s.AddOrgUsage(ctx, []store.OrgUsage{
+		{Period: period, OrgID: "org-a", Submissions: 2},
+		{Period: period, OrgID: "org-b", Submissions: 0},
+	})
+	if err != nil {
+		t.Fatalf("AddOrgUsage failed: %v", err)
+	}
+	assertUsage(t, totals, map[string]int64{"org-a": 5, "org-b": 1})
+}
+
+// assertUsage checks org usage totals against the wanted submissions per org
+func assertUsage(t *testing.T, got []store.OrgUsage, want map[string]int64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("usage = %+v, want %v", got, want)
+	}
+	for _, u := range got {
+		if u.Submissions != want[u.OrgID] {
+			t.Errorf("usage of %s = %d, want %d", u.OrgID, u.Submissions, want[u.OrgID])
+		}
+	}
+}
+
+func testAttestationProofRoundTrip(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	hash := "storetest-" + uuid.NewString()
+
+	if _, err := s.GetAttestationProof(ctx, hash); !errors.Is(err, store.ErrProofNotFound) {
+		t.Fatalf("GetAttestationProof of an uncached hash: err = %v, want ErrProofNotFound", err)
+	}
+
+	// A reconciled proof carries the transaction but not the exact on-chain timestamps
+	reconciled := store.AttestationProof{LogHash: hash, SenderOrgID: "org-a", LogContent: "content", TxHash: "tx-1", BlockHeight: 42, Source: store.ProofSourceReconcile}
+	if err := s.SaveAttestationProofs(ctx, []store.AttestationProof{reconciled}); err != nil {
+		t.Fatalf("SaveAttestationProofs failed: %v", err)
+	}
+	got, err := s.GetAttestationProof(ctx, hash)
+	if err != nil {
+		t.Fatalf("GetAttestationProof failed: %v", err)
+	}
+	if got.Complete() || got.TxHash != "tx-1" || got.CachedAt.IsZero() {
+		t.Errorf("reconciled proof = %+v, want incomplete with tx-1 and CachedAt set", got)
+	}
+
+	// Reading the record back from the chain fills in the timestamps and keeps the transaction
+	fromChain := store.AttestationProof{LogHash: hash, SenderOrgID: "org-a", Timestamp: "2024-01-02T03:04:05.123456789Z", ClientTimestamp: "2024-01-02T03:04:00Z", LogContent: "content", Source: store.ProofSourceChain}
+	if err := s.SaveAttestationProofs(ctx, []store.AttestationProof{fromChain}); err != nil {
+		t.Fatalf("SaveAttestationProofs failed: %v", err)
+	}
+
+	// Fields already cached are never overwritten
+	other := fromChain
+	other.Timestamp, other.TxHash, other.BlockHeight, other.Source = "2025-01-01T00:00:00Z", "tx-2", 43, store.ProofSourceWorker
+	if err := s.SaveAttestationProofs(ctx, []store.AttestationProof{other}); err != nil {
+		t.Fatalf("SaveAttestationProofs failed: %v", err)
+	}
+
+	got, err = s.GetAttestationProof(ctx, hash)
+	if err != nil {
+		t.Fatalf("GetAttestationProof failed: %v", err)
+	}
+	want := store.AttestationProof{
+		LogHash: hash, SenderOrgID: "org-a", Timestamp: fromChain.Timestamp, ClientTimestamp: fromChain.ClientTimestamp,
+		LogContent: "content", TxHash: "tx-1", BlockHeight: 42, Source: store.ProofSourceReconcile, CachedAt: got.CachedAt,
+	}
+	if *got != want {
+		t.Errorf("proof = %+v, want %+v", *got, want)
+	}
+}
+
+func testMerkleProofRoundTrip(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	hashes := []string{"storetest-" + uuid.NewString(), "storetest-" + uuid.NewString(), "storetest-" + uuid.NewString()}
+
+	if _, err := s.GetMerkleProof(ctx, hashes[0]); !errors.Is(err, store.ErrMerkleProofNotFound) {
+		t.Fatalf("GetMerkleProof of an unknown hash: err = %v, want ErrMerkleProofNotFound", err)
+	}
+
+	tree, err := types.NewMerkleTree(hashes)
+	if err != nil {
+		t.Fatalf("NewMerkleTree failed: %v", err)
+	}
+	proofs := make([]store.MerkleProof, len(hashes))
+	for i, h := range hashes {
+		inclusion, err := tree.Proof(h)
+		if err != nil {
+			t.Fatalf("Proof(%s) failed: %v", h, err)
+		}
+		proofs[i] = store.MerkleProof{
+			LogHash: h, MerkleRoot: tree.Root(), LeafIndex: inclusion.LeafIndex, LeafCount: inclusion.LeafCount,
+			Siblings: inclusion.Siblings, TxHash: "tx-1", BlockHeight: 42,
+		}
+	}
+	if err := s.SaveMerkleProofs(ctx, proofs); err != nil {
+		t.Fatalf("SaveMerkleProofs failed: %v", err)
+	}
+
+	// A log anchored again under another root keeps its first proof
+	again := proofs[0]
+	again.MerkleRoot, again.LeafIndex, again.LeafCount, again.Siblings, again.TxHash = "other-root", 0, 1, []string{}, "tx-2"
+	if err := s.SaveMerkleProofs(ctx, []store.MerkleProof{again}); err != nil {
+		t.Fatalf("SaveMerkleProofs failed: %v", err)
+	}
+
+	for _, want := range proofs {
+		got, err := s.GetMerkleProof(ctx, want.LogHash)
+		if err != nil {
+			t.Fatalf("GetMerkleProof failed: %v", err)
+		}
+		if got.CreatedAt.IsZero() {
+			t.Errorf("proof of %s has no CreatedAt", want.LogHash)
+		}
+		want.CreatedAt = got.CreatedAt
+		if got.MerkleRoot != want.MerkleRoot || got.LeafIndex != want.LeafIndex || got.LeafCount != want.LeafCount ||
+			!slices.Equal(got.Siblings, want.Siblings) || got.TxHash != want.TxHash || got.BlockHeight != want.BlockHeight {
+			t.Errorf("proof = %+v, want %+v", *got, want)
+		}
+		inclusion := types.MerkleProof{LeafIndex: got.LeafIndex, LeafCount: got.LeafCount, Siblings: got.Siblings}
+		if err := types.VerifyMerkleProof(got.LogHash, inclusion, got.MerkleRoot); err != nil {
+			t.Errorf("stored proof of %s does not verify: %v", got.LogHash, err)
+		}
+	}
+}
+
+func testNotFoundErrorsAreTyped(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	unknown := "storetest-" + uuid.NewString()
+	lookups := map[string]func() error{
+		"GetLogStatusByRequestID": func() error { _, err := s.GetLogStatusByRequestID(ctx, unknown); return err },
+		"GetLogStatusByHash":      func() error { _, err := s.GetLogStatusByHash(ctx, unknown); return err },
+		"GetAttestationProof":     func() error { _, err := s.GetAttestationProof(ctx, unknown); return err },
+		"GetMerkleProof":          func() error { _, err := s.GetMerkleProof(ctx, unknown); return err },
+		"GetDebugCapture":         func() error { _, err := s.GetDebugCapture(ctx, unknown); return err },
+	}
+	for name, lookup := range lookups {
+		if err := lookup(); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s of an unknown key: err = %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func testLocalQueueRelay(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	tier := "storetest-" + uuid.NewString() // Isolates this subtest's messages in a shared store
+	statuses := newStatuses(3, "org-a")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+
+	messages := make([]store.LocalMessage, len(ids))
+	for i, id := range ids {
+		messages[i] = store.LocalMessage{RequestID: id, Tier: tier, Payload: []byte(`{"RequestID":"` + id + `"}`)}
+	}
+	if err := s.QueueLocalMessages(ctx, messages); err != nil {
+		t.Fatalf("QueueLocalMessages failed: %v", err)
+	}
+	for _, id := range ids {
+		if got := mustGet(t, s, id).Status; got != store.StatusQueuedLocal {
+			t.Errorf("status of queued %s = %s, want QUEUED_LOCAL", id, got)
+		}
+	}
+	// Queued submissions are not picked up by workers
+	if tasks := mustMarkProcessing(t, s, ids, 3); len(tasks) != 0 {
+		t.Errorf("GetAndMarkBatchAsProcessing locked %d queued submissions, want 0", len(tasks))
+	}
+
+	claimed, err := s.ClaimLocalMessages(ctx, tier, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimLocalMessages failed: %v", err)
+	}
+	if len(claimed) != 2 {
+		t.Fatalf("claimed %d messages, want 2", len(claimed))
+	}
+	for _, m := range claimed {
+		if m.Tier != tier || string(m.Payload) != `{"RequestID":"`+m.RequestID+`"}` {
+			t.Errorf("claimed message %+v does not match the queued one", m)
+		}
+	}
+	first := []string{claimed[0].RequestID, claimed[1].RequestID}
+	for _, id := range first {
+		if got := mustGet(t, s, id).Status; got != store.StatusReceived {
+			t.Errorf("status of claimed %s = %s, want RECEIVED", id, got)
+		}
+	}
+
+	// Leased messages are not claimed twice
+	rest, err := s.ClaimLocalMessages(ctx, tier, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimLocalMessages failed: %v", err)
+	}
+	if len(rest) != 1 {
+		t.Fatalf("second claim returned %d messages, want 1", len(rest))
+	}
+
+	// A failed publish returns the messages to the queue
+	if err := s.ReleaseLocalMessages(ctx, first, false); err != nil {
+		t.Fatalf("ReleaseLocalMessages(unpublished) failed: %v", err)
+	}
+	for _, id := range first {
+		if got := mustGet(t, s, id).Status; got != store.StatusQueuedLocal {
+			t.Errorf("status of unpublished %s = %s, want QUEUED_LOCAL", id, got)
+		}
+	}
+
+	// Published messages leave the queue
+	if err := s.ReleaseLocalMessages(ctx, []string{rest[0].RequestID}, true); err != nil {
+		t.Fatalf("ReleaseLocalMessages(published) failed: %v", err)
+	}
+	again, err := s.ClaimLocalMessages(ctx, tier, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimLocalMessages failed: %v", err)
+	}
+	reclaimed := make([]string, len(again))
+	for i, m := range again {
+		reclaimed[i] = m.RequestID
+	}
+	assertIDs(t, "reclaimed", reclaimed, first...)
+}
+
+func testOutboxRelay(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	tier := "storetest-" + uuid.NewString() // Isolates this subtest's messages in a shared store
+	statuses := newStatuses(3, "org-a")
+	ids := requestIDsOf(statuses)
+	messages := make([]store.OutboxMessage, len(ids))
+	for i, id := range ids {
+		messages[i] = store.OutboxMessage{RequestID: id, Tier: tier, Payload: []byte(`{"RequestID":"` + id + `"}`)}
+	}
+
+	// The inserting caller holds the claim
+	result, err := s.InsertLogStatusBatchWithOutbox(ctx, statuses, store.ConflictDoNothing, messages, time.Minute)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatchWithOutbox failed: %v", err)
+	}
+	assertIDs(t, "inserted", result.Inserted, ids...)
+	if got := mustGet(t, s, ids[0]).Status; got != store.StatusReceived {
+		t.Errorf("status of %s = %s, want RECEIVED", ids[0], got)
+	}
+	claimed, err := s.ClaimOutboxMessages(ctx, tier, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutboxMessages failed: %v", err)
+	}
+	if len(claimed) != 0 {
+		t.Fatalf("claimed %d messages held by the inserting caller, want 0", len(claimed))
+	}
+
+	// Rows skipped on conflict keep their message and claim; a zero lease leaves new ones to the relay
+	retried := newStatuses(1, "org-a")
+	result, err = s.InsertLogStatusBatchWithOutbox(ctx, append(retried, statuses[0]), store.ConflictDoNothing,
+		[]store.OutboxMessage{{RequestID: retried[0].RequestID, Tier: tier, Payload: []byte(`{}`)}, messages[0]}, 0)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatchWithOutbox failed: %v", err)
+	}
+	assertIDs(t, "skipped", result.Skipped, ids[0])
+
+	// A failed publish releases the claim; sent messages are never claimed
+	if err := s.ReleaseOutboxMessages(ctx, ids[:2]); err != nil {
+		t.Fatalf("ReleaseOutboxMessages failed: %v", err)
+	}
+	if err := s.MarkOutboxSent(ctx, ids[2:]); err != nil {
+		t.Fatalf("MarkOutboxSent failed: %v", err)
+	}
+	claimed, err = s.ClaimOutboxMessages(ctx, tier, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutboxMessages failed: %v", err)
+	}
+	got := make([]string, len(claimed))
+	for i, m := range claimed {
+		got[i] = m.RequestID
+		if m.Tier != tier || m.CreatedAt.IsZero() {
+			t.Errorf("claimed message %+v does not match the written one", m)
+		}
+	}
+	assertIDs(t, "claimed", got, ids[0], ids[1], retried[0].RequestID)
+
+	// Leased messages are not claimed twice
+	again, err := s.ClaimOutboxMessages(ctx, tier, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutboxMessages failed: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("second claim returned %d messages, want 0", len(again))
+	}
+
+	if err := s.MarkOutboxSent(ctx, got); err != nil {
+		t.Fatalf("MarkOutboxSent failed: %v", err)
+	}
+	if _, err := s.PurgeOutbox(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PurgeOutbox failed: %v", err)
+	}
+	if err := s.ReleaseOutboxMessages(ctx, got); err != nil {
+		t.Fatalf("ReleaseOutboxMessages failed: %v", err)
+	}
+	if again, _ = s.ClaimOutboxMessages(ctx, tier, 10, 0); len(again) != 0 {
+		t.Errorf("claimed %d purged messages, want 0", len(again))
+	}
+}
+
+func testParkedMessages(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	topic := "storetest-topic-" + uuid.NewString()
+	statuses := newStatuses(4, "org-a")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+	mustMarkProcessing(t, s, ids[:3], 3)
+
+	// Only messages of PROCESSING tasks are parked, and only once
+	messages := make([]store.ParkedMessage, len(ids))
+	for i, id := range ids {
+		messages[i] = store.ParkedMessage{RequestID: id, Topic: topic, Payload: []byte(`{"RequestID":"` + id + `"}`)}
+	}
+	n, err := s.ParkMessages(ctx, messages)
+	if err != nil {
+		t.Fatalf("ParkMessages failed: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("parked %d messages, want the 3 of PROCESSING tasks", n)
+	}
+	if n, _ = s.ParkMessages(ctx, messages[:1]); n != 0 {
+		t.Errorf("parked %d messages again, want 0", n)
+	}
+
+	// Messages of tasks still PROCESSING are not listed
+	listed, err := s.ListReleasedParkedMessages(ctx, []string{topic}, 10)
+	if err != nil {
+		t.Fatalf("ListReleasedParkedMessages failed: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("listed %d messages of PROCESSING tasks, want 0", len(listed))
+	}
+
+	if err := s.MarkBatchForRetry(ctx, ids[:1], "stuck"); err != nil {
+		t.Fatalf("MarkBatchForRetry failed: %v", err)
+	}
+	if err := s.MarkBatchAsCompleted(ctx, []store.CompletionRecord{{RequestID: ids[1], TxHash: "tx-1", LogHashOnChain: statuses[1].LogHash}}); err != nil {
+		t.Fatalf("MarkBatchAsCompleted failed: %v", err)
+	}
+	listed, err = s.ListReleasedParkedMessages(ctx, []string{topic}, 10)
+	if err != nil {
+		t.Fatalf("ListReleasedParkedMessages failed: %v", err)
+	}
+	got := make([]string, len(listed))
+	for i, m := range listed {
+		got[i] = m.RequestID
+		want := store.StatusReceived
+		if m.RequestID == ids[1] {
+			want = store.StatusCompleted
+		}
+		payload := []byte(`{"RequestID":"` + m.RequestID + `"}`)
+		if m.Status != want || m.Topic != topic || !bytes.Equal(m.Payload, payload) || m.ParkedAt.IsZero() {
+			t.Errorf("listed message %+v, want status %s and the parked payload", m, want)
+		}
+	}
+	assertIDs(t, "released", got, ids[0], ids[1])
+	if other, _ := s.ListReleasedParkedMessages(ctx, []string{"storetest-other-topic"}, 10); len(other) != 0 {
+		t.Errorf("listed %d messages of another topic, want 0", len(other))
+	}
+
+	if err := s.DeleteParkedMessages(ctx, got); err != nil {
+		t.Fatalf("DeleteParkedMessages failed: %v", err)
+	}
+	if listed, _ = s.ListReleasedParkedMessages(ctx, []string{topic}, 10); len(listed) != 0 {
+		t.Errorf("listed %d deleted messages, want 0", len(listed))
+	}
+}
+
+// nopCloser adapts a buffer to the io.WriteCloser ExportSnapshot writes to
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func testExternalIDs(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-org-" + uuid.NewString()
+	statuses := newStatuses(2, org)
+	statuses[0].ExternalID = "order-1"
+	mustInsert(t, s, statuses)
+
+	got, err := s.GetLogStatusByExternalID(ctx, org, "order-1")
+	if err != nil {
+		t.Fatalf("GetLogStatusByExternalID failed: %v", err)
+	}
+	if got.RequestID != statuses[0].RequestID || got.ExternalID != "order-1" {
+		t.Errorf("GetLogStatusByExternalID = %s (external_id %q), want %s", got.RequestID, got.ExternalID, statuses[0].RequestID)
+	}
+	if _, err := s.GetLogStatusByExternalID(ctx, "storetest-org-"+uuid.NewString(), "order-1"); !errors.Is(err, store.ErrLogNotFound) {
+		t.Errorf("GetLogStatusByExternalID for another org: got %v, want ErrLogNotFound", err)
+	}
+
+	// A new request under a taken external ID is skipped, not stored twice
+	dup := newStatuses(1, org)
+	dup[0].ExternalID = "order-1"
+	result, err := s.InsertLogStatusBatch(ctx, dup, store.ConflictDoNothing)
+	if err != nil {
+		t.Fatalf("InsertLogStatusBatch failed: %v", err)
+	}
+	assertIDs(t, "inserted", result.Inserted)
+	assertIDs(t, "skipped", result.Skipped, dup[0].RequestID)
+	if got := mustGet(t, s, statuses[1].RequestID); got.ExternalID != "" {
+		t.Errorf("external_id of a submission without one = %q, want empty", got.ExternalID)
+	}
+}
+
+func testWorkerInstances(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	live := store.WorkerInstance{InstanceID: "storetest-" + uuid.NewString(), Version: "v1", Hostname: "host-a", Partitions: []int{0, 2}, StartedAt: time.Now()}
+	dead := store.WorkerInstance{InstanceID: "storetest-" + uuid.NewString(), Version: "v1", Hostname: "host-b", StartedAt: time.Now()}
+	for _, inst := range []store.WorkerInstance{live, dead} {
+		if err := s.HeartbeatInstance(ctx, inst); err != nil {
+			t.Fatalf("HeartbeatInstance(%s) failed: %v", inst.InstanceID, err)
+		}
+	}
+
+	statuses := newStatuses(3, "org-a")
+	mustInsert(t, s, statuses)
+	ids := requestIDsOf(statuses)
+	if _, err := s.GetAndMarkBatchAsProcessing(ctx, ids[:2], 3, "", dead.InstanceID); err != nil {
+		t.Fatalf("GetAndMarkBatchAsProcessing failed: %v", err)
+	}
+	if _, err := s.GetAndMarkBatchAsProcessing(ctx, ids[2:], 3, "", live.InstanceID); err != nil {
+		t.Fatalf("GetAndMarkBatchAsProcessing failed: %v", err)
+	}
+
+	instances, err := s.ListWorkerInstances(ctx)
+	if err != nil {
+		t.Fatalf("ListWorkerInstances failed: %v", err)
+	}
+	idx := slices.IndexFunc(instances, func(i *store.WorkerInstance) bool { return i.InstanceID == live.InstanceID })
+	if idx < 0 {
+		t.Fatalf("ListWorkerInstances did not return %s", live.InstanceID)
+	}
+	if got := instances[idx]; got.Hostname != "host-a" || !slices.Equal(got.Partitions, []int{0, 2}) || got.StoppedAt != nil || got.ReclaimedAt != nil {
+		t.Errorf("listed instance = %+v, want host-a with partitions [0 2], not stopped or reclaimed", got)
+	}
+
+	// A clean stop releases the instance's tasks without counting a retry
+	released, err := s.StopInstance(ctx, live.InstanceID)
+	if err != nil {
+		t.Fatalf("StopInstance failed: %v", err)
+	}
+	if released != 1 {
+		t.Errorf("StopInstance released %d tasks, want 1", released)
+	}
+	if got := mustGet(t, s, ids[2]); got.Status != store.StatusReceived || got.RetryCount != 0 {
+		t.Errorf("released task: status %s retry_count %d, want RECEIVED 0", got.Status, got.RetryCount)
+	}
+
+	// With no grace period every running instance is dead; stopped ones are not
+	time.Sleep(10 * time.Millisecond)
+	reclaimed, err := s.ReclaimDeadInstances(ctx, 0)
+	if err != nil {
+		t.Fatalf("ReclaimDeadInstances failed: %v", err)
+	}
+	var found bool
+	for _, r := range reclaimed {
+		switch r.InstanceID {
+		case dead.InstanceID:
+			found = true
+			if r.Tasks != 2 {
+				t.Errorf("reclaimed %d tasks of the dead instance, want 2", r.Tasks)
+			}
+		case live.InstanceID:
+			t.Errorf("ReclaimDeadInstances reclaimed the stopped instance")
+		}
+	}
+	if !found {
+		t.Fatalf("ReclaimDeadInstances did not reclaim %s", dead.InstanceID)
+	}
+	for _, id := range ids[:2] {
+		if got := mustGet(t, s, id); got.Status != store.StatusReceived || got.RetryCount != 1 {
+			t.Errorf("reclaimed task %s: status %s retry_count %d, want RECEIVED 1", id, got.Status, got.RetryCount)
+		}
+	}
+
+	// A reclaimed instance is only reclaimed once
+	again, err := s.ReclaimDeadInstances(ctx, 0)
+	if err != nil {
+		t.Fatalf("ReclaimDeadInstances failed: %v", err)
+	}
+	if slices.ContainsFunc(again, func(r store.ReclaimedInstance) bool { return r.InstanceID == dead.InstanceID }) {
+		t.Errorf("ReclaimDeadInstances reclaimed %s twice", dead.InstanceID)
+	}
+}
+
+func testExportSnapshot(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	statuses := newStatuses(3, "org-a")
+	mustInsert(t, s, statuses)
+
+	buffers := make(map[string]*bytes.Buffer)
+	snapshot, err := s.ExportSnapshot(ctx, func(table string) (io.WriteCloser, error) {
+		buffers[table] = new(bytes.Buffer)
+		return nopCloser{buffers[table]}, nil
+	})
+	if err != nil {
+		t.Fatalf("ExportSnapshot failed: %v", err)
+	}
+	if snapshot.TakenAt.IsZero() {
+		t.Error("snapshot has no TakenAt")
+	}
+	var logStatus *store.SnapshotTable
+	for i := range snapshot.Tables {
+		if buffers[snapshot.Tables[i].Name] == nil {
+			t.Errorf("table %s reported but not written", snapshot.Tables[i].Name)
+		}
+		if snapshot.Tables[i].Name == "tbl_log_status" {
+			logStatus = &snapshot.Tables[i]
+		}
+	}
+	if logStatus == nil {
+		t.Fatalf("snapshot tables %+v lack tbl_log_status", snapshot.Tables)
+	}
+	if logStatus.Rows < int64(len(statuses)) {
+		t.Errorf("tbl_log_status rows = %d, want at least %d", logStatus.Rows, len(statuses))
+	}
+	csv := buffers["tbl_log_status"].String()
+	if !strings.HasPrefix(csv, "request_id,") {
+		t.Errorf("tbl_log_status export lacks the header row: %.80q", csv)
+	}
+	for _, st := range statuses {
+		if !strings.Contains(csv, st.RequestID) {
+			t.Errorf("tbl_log_status export lacks %s", st.RequestID)
+		}
+	}
+}
+
+func testDebugCaptures(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-" + uuid.NewString()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	live := store.DebugCapture{
+		CaptureID: uuid.NewString(), OrgID: org, Transport: "http", StatusCode: 400, Reason: "invalid JSON",
+		ContentType: "application/json", Payload: []byte("{\"log_content\":"), PayloadSize: 15,
+		CapturedAt: now, ExpiresAt: now.Add(time.Hour),
+	}
+	expired := live
+	expired.CaptureID = uuid.NewString()
+	expired.CapturedAt, expired.ExpiresAt = now.Add(-2*time.Hour), now.Add(-time.Hour)
+	for _, c := range []store.DebugCapture{live, expired} {
+		if err := s.InsertDebugCapture(ctx, c); err != nil {
+			t.Fatalf("InsertDebugCapture(%s) failed: %v", c.CaptureID, err)
+		}
+	}
+
+	listed, err := s.ListDebugCaptures(ctx, org, 10)
+	if err != nil {
+		t.Fatalf("ListDebugCaptures failed: %v", err)
+	}
+	if len(listed) != 1 || listed[0].CaptureID != live.CaptureID || listed[0].Payload != nil || listed[0].PayloadSize != 15 {
+		t.Fatalf("ListDebugCaptures = %+v, want only %s without payload", listed, live.CaptureID)
+	}
+
+	got, err := s.GetDebugCapture(ctx, live.CaptureID)
+	if err != nil {
+		t.Fatalf("GetDebugCapture failed: %v", err)
+	}
+	if string(got.Payload) != string(live.Payload) || got.StatusCode != 400 || got.Reason != live.Reason {
+		t.Errorf("GetDebugCapture = %+v, want %+v", got, live)
+	}
+	if _, err := s.GetDebugCapture(ctx, expired.CaptureID); !errors.Is(err, store.ErrCaptureNotFound) {
+		t.Errorf("GetDebugCapture(expired) error = %v, want ErrCaptureNotFound", err)
+	}
+
+	purged, err := s.PurgeDebugCaptures(ctx)
+	if err != nil {
+		t.Fatalf("PurgeDebugCaptures failed: %v", err)
+	}
+	if purged < 1 {
+		t.Errorf("PurgeDebugCaptures purged %d captures, want at least the expired one", purged)
+	}
+	if _, err := s.GetDebugCapture(ctx, live.CaptureID); err != nil {
+		t.Errorf("GetDebugCapture after purge failed: %v", err)
+	}
+}
+
+func testAPITokens(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	org := "storetest-" + uuid.NewString()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	token := store.APIToken{
+		TokenID: uuid.NewString(), OrgID: org, Description: "ci agent", Scopes: []string{"submit", "query"},
+		SecretHash: "storetest-" + uuid.NewString(), CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+	}
+	if err := s.CreateAPIToken(ctx, token); err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+
+	got, err := s.GetAPITokenByHash(ctx, token.SecretHash)
+	if err != nil {
+		t.Fatalf("GetAPITokenByHash failed: %v", err)
+	}
+	if got.TokenID != token.TokenID || got.OrgID != org || !slices.Equal(got.Scopes, token.Scopes) ||
+		got.RevokedAt != nil || !got.Active(now) {
+		t.Fatalf("GetAPITokenByHash = %+v, want active %+v", got, token)
+	}
+	if _, err := s.GetAPITokenByHash(ctx, "storetest-"+uuid.NewString()); !errors.Is(err, store.ErrAPITokenNotFound) {
+		t.Errorf("GetAPITokenByHash(unknown) error = %v, want ErrAPITokenNotFound", err)
+	}
+
+	next := token
+	next.TokenID, next.SecretHash = uuid.NewString(), "storetest-"+uuid.NewString()
+	next.CreatedAt = now.Add(time.Second)
+	graceEnd := now.Add(time.Minute)
+	if err := s.RotateAPIToken(ctx, token.TokenID, next, graceEnd); err != nil {
+		t.Fatalf("RotateAPIToken failed: %v", err)
+	}
+	if err := s.RotateAPIToken(ctx, token.TokenID, next, graceEnd); !errors.Is(err, store.ErrAPITokenNotFound) {
+		t.Errorf("second RotateAPIToken error = %v, want ErrAPITokenNotFound", err)
+	}
+	old, err := s.GetAPIToken(ctx, token.TokenID)
+	if err != nil {
+		t.Fatalf("GetAPIToken failed: %v", err)
+	}
+	if old.RotatedTo != next.TokenID || old.RevokedAt == nil || !old.RevokedAt.Equal(graceEnd) || !old.Active(now) {
+		t.Errorf("rotated token = %+v, want active until %v and rotated to %s", old, graceEnd, next.TokenID)
+	}
+
+	// An earlier revocation ends the grace period; a later one does not extend it
+	if err := s.RevokeAPIToken(ctx, token.TokenID, now); err != nil {
+		t.Fatalf("RevokeAPIToken failed: %v", err)
+	}
+	if err := s.RevokeAPIToken(ctx, token.TokenID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeAPIToken (later) failed: %v", err)
+	}
+	if old, err = s.GetAPIToken(ctx, token.TokenID); err != nil || old.Active(now) {
+		t.Errorf("GetAPIToken after revocation = %+v, %v, want inactive", old, err)
+	}
+	if err := s.RevokeAPIToken(ctx, uuid.NewString(), now); !errors.Is(err, store.ErrAPITokenNotFound) {
+		t.Errorf("RevokeAPIToken(unknown) error = %v, want ErrAPITokenNotFound", err)
+	}
+
+	listed, err := s.ListAPITokens(ctx, org)
+	if err != nil {
+		t.Fatalf("ListAPITokens failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0].TokenID != next.TokenID || listed[1].TokenID != token.TokenID {
+		t.Errorf("ListAPITokens = %+v, want %s then %s", listed, next.TokenID, token.TokenID)
+	}
+}
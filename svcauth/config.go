@@ -0,0 +1,110 @@
+package svcauth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// Authentication methods
+const (
+	ModeNone   = ""       // Service-to-service authentication disabled
+	ModeSPIFFE = "spiffe" // X.509 SVIDs: mutual TLS between SPIFFE IDs
+	ModeOIDC   = "oidc"   // OIDC client-credentials access tokens (JWT bearer tokens)
+)
+
+// Config defines how a service authenticates the services calling it and
+// how it authenticates itself when calling others. It has YAML tags, so
+// services and agents embed it as service_auth.
+type Config struct {
+	Mode string `yaml:"mode"` // "" (disabled), spiffe or oidc
+
+	// TrustDomain is the trust domain callers must belong to: the SPIFFE
+	// trust domain (e.g. "tlng.internal") or the OIDC issuer URL
+	TrustDomain string   `yaml:"trust_domain"`
+	AllowedIDs  []string `yaml:"allowed_ids"` // Caller SPIFFE IDs or OIDC subjects allowed; empty allows the whole trust domain
+
+	SPIFFE SPIFFEConfig `yaml:"spiffe"`
+	OIDC   OIDCConfig   `yaml:"oidc"`
+}
+
+// SPIFFEConfig locates the workload's X.509 SVID, e.g. as written by
+// spiffe-helper. The files are reloaded when they change, so rotated SVIDs
+// are picked up without a restart.
+type SPIFFEConfig struct {
+	CertFile   string `yaml:"cert_file"`   // SVID certificate chain (leaf first)
+	KeyFile    string `yaml:"key_file"`    // SVID private key
+	BundleFile string `yaml:"bundle_file"` // Trust bundle of the trust domain (PEM CA certificates)
+	ServerID   string `yaml:"server_id"`   // SPIFFE ID the called server must present; empty accepts any ID in the trust domain
+}
+
+// OIDCConfig defines the OIDC provider. Services verifying tokens need the
+// audience and JWKS URL; callers need the client credentials.
+type OIDCConfig struct {
+	Audience    string        `yaml:"audience"`     // aud claim inbound tokens must carry
+	JWKSURL     string        `yaml:"jwks_url"`     // Issuer's JSON Web Key Set
+	JWKSRefresh time.Duration `yaml:"jwks_refresh"` // How long fetched keys are used before refetching
+
+	TokenURL         string   `yaml:"token_url"`          // Token endpoint for the client-credentials grant
+	ClientID         string   `yaml:"client_id"`          // Client ID of this service
+	ClientSecretFile string   `yaml:"client_secret_file"` // File holding the client secret, read on every token request
+	Scopes           []string `yaml:"scopes"`             // Requested scopes
+	TokenAudience    string   `yaml:"token_audience"`     // audience parameter of token requests (Auth0, Keycloak); empty omits it
+}
+
+// Enabled reports whether service-to-service authentication is configured
+func (c *Config) Enabled() bool {
+	return c.Mode != ModeNone
+}
+
+// SetDefaults fills unset fields with defaults
+func (c *Config) SetDefaults() {
+	if c.Mode == ModeOIDC && c.OIDC.JWKSRefresh <= 0 {
+		c.OIDC.JWKSRefresh = 5 * time.Minute
+	}
+}
+
+// Validate validates the settings shared by callers and callees. Settings
+// only one side needs are checked by NewVerifier and NewCredentials.
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case ModeNone:
+		return nil
+	case ModeSPIFFE:
+		td, err := spiffeid.TrustDomainFromString(c.TrustDomain)
+		if err != nil || strings.Contains(c.TrustDomain, "/") {
+			return fmt.Errorf("trust_domain must be a SPIFFE trust domain name, e.g. tlng.internal")
+		}
+		if c.SPIFFE.CertFile == "" || c.SPIFFE.KeyFile == "" || c.SPIFFE.BundleFile == "" {
+			return fmt.Errorf("spiffe.cert_file, spiffe.key_file and spiffe.bundle_file are required")
+		}
+		if c.SPIFFE.ServerID != "" {
+			id, err := spiffeid.FromString(c.SPIFFE.ServerID)
+			if err != nil {
+				return fmt.Errorf("invalid spiffe.server_id '%s': %w", c.SPIFFE.ServerID, err)
+			}
+			if !id.MemberOf(td) {
+				return fmt.Errorf("spiffe.server_id %s is not in trust domain %s", id, td)
+			}
+		}
+		for _, id := range c.AllowedIDs {
+			if id == "" {
+				continue
+			}
+			if _, err := spiffeid.FromString(id); err != nil {
+				return fmt.Errorf("invalid SPIFFE ID '%s': %w", id, err)
+			}
+		}
+	case ModeOIDC:
+		u, err := url.Parse(c.TrustDomain)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("trust_domain must be the https issuer URL in oidc mode")
+		}
+	default:
+		return fmt.Errorf("unknown mode '%s' (want spiffe or oidc)", c.Mode)
+	}
+	return nil
+}
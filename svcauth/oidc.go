@@ -0,0 +1,314 @@
+package svcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	clockSkew       = time.Minute      // Tolerance for exp and nbf
+	jwksMinRefetch  = 30 * time.Second // Minimum interval between JWKS fetches for unknown key IDs
+	tokenRenewAhead = 30 * time.Second // Renew access tokens this long before they expire
+)
+
+// signingAlgorithms are the JWS algorithms accepted on access tokens. HMAC
+// and "none" are excluded, so a token cannot be signed with a public key.
+var signingAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+}
+
+// oidcVerifier verifies JWT access tokens issued by the trust domain's issuer
+type oidcVerifier struct {
+	issuer   string
+	audience string
+	jwksURL  string
+	refresh  time.Duration
+	client   *http.Client
+
+	mu       sync.Mutex
+	keys     *jose.JSONWebKeySet
+	fetched  time.Time
+	fetching chan struct{} // Closed when the JWKS fetch in flight ends; nil when none is
+}
+
+func newOIDCVerifier(cfg Config, client *http.Client) (*oidcVerifier, error) {
+	if cfg.OIDC.Audience == "" || cfg.OIDC.JWKSURL == "" {
+		return nil, fmt.Errorf("oidc.audience and oidc.jwks_url are required to verify tokens")
+	}
+	return &oidcVerifier{
+		issuer:   cfg.TrustDomain,
+		audience: cfg.OIDC.Audience,
+		jwksURL:  cfg.OIDC.JWKSURL,
+		refresh:  cfg.OIDC.JWKSRefresh,
+		client:   client,
+	}, nil
+}
+
+// ServerTLSConfig is nil: tokens are verified per call
+func (v *oidcVerifier) ServerTLSConfig() *tls.Config {
+	return nil
+}
+
+// Verify checks the token's signature against the issuer's keys with
+// go-jose, and its issuer, audience and validity period
+func (v *oidcVerifier) Verify(ctx context.Context, _ *tls.ConnectionState, token string) (*Identity, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: no bearer token", ErrUnauthenticated)
+	}
+	tok, err := jwt.ParseSigned(token, signingAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	header := tok.Headers[0] // ParseSigned accepts exactly one signature
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := keyAllows(key, header.Algorithm); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	var claims jwt.Claims
+	if err := tok.Claims(key.Key, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	err = claims.ValidateWithLeeway(jwt.Expected{Issuer: v.issuer, AnyAudience: jwt.Audience{v.audience}, Time: time.Now()}, clockSkew)
+	switch {
+	case errors.Is(err, jwt.ErrInvalidIssuer):
+		return nil, fmt.Errorf("%w: token issued by %s, not by trust domain %s", ErrUnauthenticated, claims.Issuer, v.issuer)
+	case errors.Is(err, jwt.ErrInvalidAudience):
+		return nil, fmt.Errorf("%w: token audience %v does not include %s", ErrUnauthenticated, []string(claims.Audience), v.audience)
+	case errors.Is(err, jwt.ErrExpired) || claims.Expiry == nil:
+		return nil, fmt.Errorf("%w: token expired", ErrUnauthenticated)
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+	return &Identity{Method: ModeOIDC, ID: claims.Subject}, nil
+}
+
+// keyAllows checks that a token's algorithm is one its signing key is meant
+// for: the key's own alg if the issuer set one, and for EC keys the algorithm
+// of their curve, which go-jose does not check
+func keyAllows(key *jose.JSONWebKey, alg string) error {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return fmt.Errorf("token algorithm '%s' does not match key algorithm '%s'", alg, key.Algorithm)
+	}
+	want := ""
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS") {
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			want = string(jose.ES256)
+		case elliptic.P384():
+			want = string(jose.ES384)
+		case elliptic.P521():
+			want = string(jose.ES512)
+		}
+		if alg == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("token algorithm '%s' does not match the signing key", alg)
+}
+
+// key returns the issuer's key with the given ID, refetching the key set
+// when it is stale or does not hold the key. The fetch runs without the lock:
+// callers holding a known key keep using it meanwhile, and callers needing
+// the new set wait for the one fetch in flight.
+func (v *oidcVerifier) key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	for {
+		v.mu.Lock()
+		age := time.Since(v.fetched)
+		key := v.lookup(kid)
+		if key != nil && age < v.refresh {
+			v.mu.Unlock()
+			return key, nil
+		}
+		if v.keys != nil && age < jwksMinRefetch {
+			v.mu.Unlock()
+			return v.found(kid, key)
+		}
+		if wait := v.fetching; wait != nil {
+			v.mu.Unlock()
+			if key != nil {
+				return key, nil // Keep using the known key while the set is refetched
+			}
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, fmt.Errorf("failed to fetch JWKS: %w", ctx.Err())
+			}
+		}
+		done := make(chan struct{})
+		v.fetching = done
+		v.mu.Unlock()
+
+		keys, err := v.fetchKeys(ctx)
+
+		v.mu.Lock()
+		v.fetching = nil
+		close(done)
+		if err == nil {
+			v.keys, v.fetched = keys, time.Now()
+		}
+		key = v.lookup(kid)
+		v.mu.Unlock()
+		if err != nil && key == nil {
+			return nil, err
+		}
+		return v.found(kid, key) // Keep using known keys while the issuer is unreachable
+	}
+}
+
+// lookup returns the signing key with the given ID, nil if the key set has
+// none. v.mu must be held.
+func (v *oidcVerifier) lookup(kid string) *jose.JSONWebKey {
+	if v.keys == nil {
+		return nil
+	}
+	for _, k := range v.keys.Key(kid) {
+		if k.Use == "" || k.Use == "sig" {
+			return &k
+		}
+	}
+	return nil
+}
+
+func (v *oidcVerifier) found(kid string, key *jose.JSONWebKey) (*jose.JSONWebKey, error) {
+	if key == nil {
+		return nil, fmt.Errorf("%w: unknown signing key '%s'", ErrUnauthenticated, kid)
+	}
+	return key, nil
+}
+
+// fetchKeys downloads the issuer's JSON Web Key Set
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	set := &jose.JSONWebKeySet{}
+	for _, data := range raw.Keys {
+		var k jose.JSONWebKey
+		if err := k.UnmarshalJSON(data); err != nil || !k.IsPublic() {
+			continue // Skip key types go-jose does not support rather than the whole set
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return set, nil
+}
+
+// oidcCredentials obtains access tokens with the client-credentials grant
+type oidcCredentials struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newOIDCCredentials(cfg Config, client *http.Client) (*oidcCredentials, error) {
+	if cfg.OIDC.TokenURL == "" || cfg.OIDC.ClientID == "" || cfg.OIDC.ClientSecretFile == "" {
+		return nil, fmt.Errorf("oidc.token_url, oidc.client_id and oidc.client_secret_file are required to obtain tokens")
+	}
+	return &oidcCredentials{cfg: cfg.OIDC, client: client}, nil
+}
+
+// ClientTLSConfig is nil: tokens travel over the transport's own TLS
+func (c *oidcCredentials) ClientTLSConfig() *tls.Config {
+	return nil
+}
+
+// Token returns a cached access token, requesting a new one shortly before it expires
+func (c *oidcCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Until(c.expiry) > tokenRenewAhead {
+		return c.token, nil
+	}
+
+	secret, err := os.ReadFile(c.cfg.ClientSecretFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read client secret: %w", err)
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+	if c.cfg.TokenAudience != "" {
+		form.Set("audience", c.cfg.TokenAudience)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(strings.TrimSpace(string(secret))))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %s", resp.Status)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+
+	c.token = body.AccessToken
+	c.expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	if body.ExpiresIn <= 0 {
+		c.expiry = time.Now().Add(tokenRenewAhead + time.Minute) // No lifetime given: reuse briefly
+	}
+	return c.token, nil
+}
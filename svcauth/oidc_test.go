@@ -0,0 +1,267 @@
+package svcauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "tlng-engine"
+)
+
+// testIssuerKeys are the signing keys of a test issuer, served as its JWKS
+type testIssuerKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey // P-256
+}
+
+func newTestIssuerKeys(t *testing.T) *testIssuerKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testIssuerKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func (k *testIssuerKeys) jwks() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: k.rsa.Public(), KeyID: "rsa-1", Use: "sig"},
+		{Key: k.ec.Public(), KeyID: "ec-1", Use: "sig"},
+	}}
+}
+
+// newTestVerifier returns a verifier of the issuer whose JWKS endpoint is served by keys
+func newTestVerifier(t *testing.T, keys *testIssuerKeys) *oidcVerifier {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(keys.jwks())
+	}))
+	t.Cleanup(srv.Close)
+	cfg := Config{Mode: ModeOIDC, TrustDomain: testIssuer, OIDC: OIDCConfig{Audience: testAudience, JWKSURL: srv.URL}}
+	cfg.SetDefaults()
+	v, err := newOIDCVerifier(cfg, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func validClaims() jwt.Claims {
+	now := time.Now()
+	return jwt.Claims{
+		Issuer:    testIssuer,
+		Subject:   "agent-1",
+		Audience:  jwt.Audience{testAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Expiry:    jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func sign(t *testing.T, alg jose.SignatureAlgorithm, key any, kid string, claims jwt.Claims) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key, KeyID: kid}}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// handSigned builds a compact JWS with an arbitrary header, for the algorithms
+// and key pairings go-jose refuses to sign with
+func handSigned(t *testing.T, header map[string]string, claims jwt.Claims, signFn func(signed []byte) []byte) string {
+	t.Helper()
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signFn([]byte(signed)))
+}
+
+func TestOIDCVerify(t *testing.T) {
+	keys := newTestIssuerKeys(t)
+	v := newTestVerifier(t, keys)
+	claims := func(edit func(*jwt.Claims)) jwt.Claims {
+		c := validClaims()
+		edit(&c)
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string // Substring of the error; "" when the token is valid
+	}{
+		{"valid RS256", sign(t, jose.RS256, keys.rsa, "rsa-1", validClaims()), ""},
+		{"valid ES256", sign(t, jose.ES256, keys.ec, "ec-1", validClaims()), ""},
+		{"expired", sign(t, jose.RS256, keys.rsa, "rsa-1", claims(func(c *jwt.Claims) {
+			c.Expiry = jwt.NewNumericDate(time.Now().Add(-clockSkew - time.Minute))
+		})), "expired"},
+		{"no expiry", sign(t, jose.RS256, keys.rsa, "rsa-1", claims(func(c *jwt.Claims) { c.Expiry = nil })), "expired"},
+		{"not yet valid", sign(t, jose.RS256, keys.rsa, "rsa-1", claims(func(c *jwt.Claims) {
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(clockSkew + time.Minute))
+		})), "not valid yet"},
+		{"wrong audience", sign(t, jose.RS256, keys.rsa, "rsa-1", claims(func(c *jwt.Claims) { c.Audience = jwt.Audience{"other-service"} })), "audience"},
+		{"wrong issuer", sign(t, jose.RS256, keys.rsa, "rsa-1", claims(func(c *jwt.Claims) { c.Issuer = "https://evil.example.com" })), "issued by"},
+		{"no subject", sign(t, jose.RS256, keys.rsa, "rsa-1", claims(func(c *jwt.Claims) { c.Subject = "" })), "no subject"},
+		{"unknown key", sign(t, jose.RS256, keys.rsa, "rsa-2", validClaims()), "unknown signing key"},
+		{"malformed", "not.a.jwt", "unauthenticated"},
+		{"empty", "", "no bearer token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := v.Verify(context.Background(), nil, tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() failed: %v", err)
+				}
+				if id.Method != ModeOIDC || id.ID != "agent-1" {
+					t.Errorf("Verify() = %+v, want oidc identity agent-1", id)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnauthenticated) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want unauthenticated containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyRejectsAlgorithmConfusion(t *testing.T) {
+	keys := newTestIssuerKeys(t)
+	v := newTestVerifier(t, keys)
+	rsaDER, err := x509.MarshalPKIXPublicKey(keys.rsa.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{
+			// HS256 keyed with the issuer's public key, which anyone can fetch
+			"HMAC with the public key",
+			sign(t, jose.HS256, rsaDER, "rsa-1", validClaims()),
+		},
+		{
+			"none",
+			handSigned(t, map[string]string{"alg": "none", "kid": "rsa-1"}, validClaims(), func([]byte) []byte { return nil }),
+		},
+		{
+			// An EC algorithm naming the RSA key
+			"ES256 on an RSA key",
+			sign(t, jose.ES256, keys.ec, "rsa-1", validClaims()),
+		},
+		{
+			// ES384 signed with the P-256 key: go-jose verifies the signature,
+			// so the curve must be bound to the algorithm by the verifier
+			"ES384 on a P-256 key",
+			handSigned(t, map[string]string{"alg": "ES384", "kid": "ec-1"}, validClaims(), func(signed []byte) []byte {
+				digest := sha512.Sum384(signed)
+				r, s, err := ecdsa.Sign(rand.Reader, keys.ec, digest[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+				sig := make([]byte, 96)
+				r.FillBytes(sig[:48])
+				s.FillBytes(sig[48:])
+				return sig
+			}),
+		},
+		{
+			"RS256 signature with PS256 header",
+			handSigned(t, map[string]string{"alg": "PS256", "kid": "rsa-1"}, validClaims(), func(signed []byte) []byte {
+				digest := crypto.SHA256.New()
+				digest.Write(signed)
+				sig, err := rsa.SignPKCS1v15(rand.Reader, keys.rsa, crypto.SHA256, digest.Sum(nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sig
+			}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, err := v.Verify(context.Background(), nil, tt.token); !errors.Is(err, ErrUnauthenticated) {
+				t.Fatalf("Verify() = %+v, %v; want unauthenticated", id, err)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRefetchDoesNotBlockKnownKeys(t *testing.T) {
+	keys := newTestIssuerKeys(t)
+	requests := make(chan struct{}, 10)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests <- struct{}{}
+		if len(requests) > 1 {
+			<-release // The issuer hangs on refetches
+		}
+		json.NewEncoder(w).Encode(keys.jwks())
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cfg := Config{Mode: ModeOIDC, TrustDomain: testIssuer, OIDC: OIDCConfig{Audience: testAudience, JWKSURL: srv.URL, JWKSRefresh: time.Hour}}
+	v, err := newOIDCVerifier(cfg, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := sign(t, jose.RS256, keys.rsa, "rsa-1", validClaims())
+	if _, err := v.Verify(context.Background(), nil, token); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+
+	// A token signed with an unknown key triggers a refetch that hangs
+	v.mu.Lock()
+	v.fetched = time.Now().Add(-jwksMinRefetch)
+	v.mu.Unlock()
+	go v.Verify(context.Background(), nil, sign(t, jose.RS256, keys.rsa, "rsa-2", validClaims()))
+	for len(requests) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Tokens of known keys are still verified meanwhile
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(context.Background(), nil, token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Verify() failed during the refetch: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Verify() of a known key waited for the JWKS refetch")
+	}
+}
@@ -0,0 +1,129 @@
+package svcauth
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+// spiffeAuth authenticates both directions of a connection with X.509 SVIDs:
+// each side presents its SVID and verifies the peer's against the trust bundle
+// with go-spiffe. It is the x509svid.Source and x509bundle.Source of its TLS
+// configurations, so every handshake uses the current files.
+type spiffeAuth struct {
+	cfg SPIFFEConfig
+	td  spiffeid.TrustDomain
+
+	mu      sync.Mutex
+	svid    *x509svid.SVID
+	bundle  *x509bundle.Bundle
+	modTime [3]time.Time // cert, key and bundle file times of the loaded SVID
+}
+
+func newSPIFFE(cfg Config) (*spiffeAuth, error) {
+	td, err := spiffeid.TrustDomainFromString(cfg.TrustDomain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trust domain '%s': %w", cfg.TrustDomain, err)
+	}
+	s := &spiffeAuth{cfg: cfg.SPIFFE, td: td}
+	if _, _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load returns the current SVID and trust bundle, reloading the files when
+// they changed since the last load
+func (s *spiffeAuth) load() (*x509svid.SVID, *x509bundle.Bundle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var modTime [3]time.Time
+	for i, path := range []string{s.cfg.CertFile, s.cfg.KeyFile, s.cfg.BundleFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			if s.svid != nil {
+				return s.svid, s.bundle, nil // Keep the loaded SVID while the files are being rewritten
+			}
+			return nil, nil, fmt.Errorf("failed to stat SVID file: %w", err)
+		}
+		modTime[i] = info.ModTime()
+	}
+	if s.svid != nil && modTime == s.modTime {
+		return s.svid, s.bundle, nil
+	}
+
+	svid, err := x509svid.Load(s.cfg.CertFile, s.cfg.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load SVID: %w", err)
+	}
+	if !svid.ID.MemberOf(s.td) {
+		return nil, nil, fmt.Errorf("SVID %s is not in trust domain %s", svid.ID, s.td)
+	}
+	bundle, err := x509bundle.Load(s.td, s.cfg.BundleFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load trust bundle: %w", err)
+	}
+
+	s.svid, s.bundle, s.modTime = svid, bundle, modTime
+	return s.svid, s.bundle, nil
+}
+
+// GetX509SVID implements x509svid.Source
+func (s *spiffeAuth) GetX509SVID() (*x509svid.SVID, error) {
+	svid, _, err := s.load()
+	return svid, err
+}
+
+// GetX509BundleForTrustDomain implements x509bundle.Source. Only the
+// configured trust domain has a bundle, so peers of other trust domains fail
+// verification.
+func (s *spiffeAuth) GetX509BundleForTrustDomain(td spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	_, bundle, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return bundle.GetX509BundleForTrustDomain(td)
+}
+
+// ServerTLSConfig presents the SVID and requires callers to present one of the trust domain
+func (s *spiffeAuth) ServerTLSConfig() *tls.Config {
+	return tlsconfig.MTLSServerConfig(s, s, tlsconfig.AuthorizeMemberOf(s.td))
+}
+
+// ClientTLSConfig presents the SVID and verifies the server's, and its ID if server_id is set
+func (s *spiffeAuth) ClientTLSConfig() *tls.Config {
+	authorize := tlsconfig.AuthorizeMemberOf(s.td)
+	if s.cfg.ServerID != "" {
+		authorize = tlsconfig.AuthorizeID(spiffeid.RequireFromString(s.cfg.ServerID)) // Validated by Config.Validate
+	}
+	return tlsconfig.MTLSClientConfig(s, s, authorize)
+}
+
+// Verify returns the SPIFFE ID of the caller's SVID, verified during the handshake
+func (s *spiffeAuth) Verify(_ context.Context, state *tls.ConnectionState, _ string) (*Identity, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("%w: no client SVID", ErrUnauthenticated)
+	}
+	id, err := x509svid.IDFromCert(state.PeerCertificates[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	if !id.MemberOf(s.td) {
+		return nil, fmt.Errorf("%w: %s is not in trust domain %s", ErrUnauthenticated, id, s.td)
+	}
+	return &Identity{Method: ModeSPIFFE, ID: id.String()}, nil
+}
+
+// Token is empty: SPIFFE authenticates at the transport
+func (s *spiffeAuth) Token(context.Context) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,217 @@
+package svcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testTrustDomain = "tlng.internal"
+
+// testCA is a certificate authority that issues X.509 SVIDs
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, dir: t.TempDir()}
+}
+
+// bundle writes the CA certificate as a trust bundle and returns its path
+func (ca *testCA) bundle(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(ca.dir, "bundle.pem")
+	writePEM(t, path, "CERTIFICATE", ca.cert.Raw)
+	return path
+}
+
+// issue writes an SVID for id and returns the paths of its certificate and key
+func (ca *testCA) issue(t *testing.T, id string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{uri},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, key.Public(), ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := serial.String()
+	certFile, keyFile = filepath.Join(ca.dir, name+".crt"), filepath.Join(ca.dir, name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "PRIVATE KEY", pkcs8)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestSPIFFE creates SPIFFE auth in the test trust domain with an SVID for
+// id issued by issuer, trusting the bundle of trusted
+func newTestSPIFFE(t *testing.T, issuer, trusted *testCA, id, serverID string) *spiffeAuth {
+	t.Helper()
+	certFile, keyFile := issuer.issue(t, id)
+	s, err := newSPIFFE(Config{Mode: ModeSPIFFE, TrustDomain: testTrustDomain, SPIFFE: SPIFFEConfig{
+		CertFile: certFile, KeyFile: keyFile, BundleFile: trusted.bundle(t), ServerID: serverID,
+	}})
+	if err != nil {
+		t.Fatalf("newSPIFFE(%s) failed: %v", id, err)
+	}
+	return s
+}
+
+// handshake connects client to server over TLS and returns the identity the
+// server verified, or the first handshake error of either side
+func handshake(t *testing.T, server, client *spiffeAuth) (*Identity, error) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", server.ServerTLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		id  *Identity
+		err error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			accepted <- result{err: err}
+			return
+		}
+		defer conn.Close()
+		tc := conn.(*tls.Conn)
+		if err := tc.Handshake(); err != nil {
+			accepted <- result{err: err}
+			return
+		}
+		state := tc.ConnectionState()
+		id, err := server.Verify(context.Background(), &state, "")
+		accepted <- result{id: id, err: err}
+	}()
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", ln.Addr().String(), client.ClientTLSConfig())
+	if err == nil {
+		// TLS 1.3 clients finish before the server has checked their SVID
+		conn.Write([]byte{0})
+		defer conn.Close()
+	}
+	r := <-accepted
+	if err != nil {
+		return nil, err
+	}
+	return r.id, r.err
+}
+
+func TestSPIFFEMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTestSPIFFE(t, ca, ca, "spiffe://tlng.internal/engine", "")
+	client := newTestSPIFFE(t, ca, ca, "spiffe://tlng.internal/agent", "spiffe://tlng.internal/engine")
+
+	id, err := handshake(t, server, client)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if id.Method != ModeSPIFFE || id.ID != "spiffe://tlng.internal/agent" {
+		t.Errorf("Verify() = %+v, want spiffe identity spiffe://tlng.internal/agent", id)
+	}
+}
+
+func TestSPIFFERejectsForeignTrustDomain(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTestSPIFFE(t, ca, ca, "spiffe://tlng.internal/engine", "")
+
+	// A client of another trust domain, even with an SVID signed by a trusted CA
+	certFile, keyFile := ca.issue(t, "spiffe://other.internal/agent")
+	foreign := &spiffeAuth{cfg: SPIFFEConfig{CertFile: certFile, KeyFile: keyFile, BundleFile: ca.bundle(t)}, td: server.td}
+	if _, err := handshake(t, server, foreign); err == nil {
+		t.Error("handshake of a client in another trust domain succeeded")
+	}
+
+	// An SVID of another trust domain is refused as the workload's own
+	_, err := newSPIFFE(Config{Mode: ModeSPIFFE, TrustDomain: testTrustDomain, SPIFFE: SPIFFEConfig{
+		CertFile: certFile, KeyFile: keyFile, BundleFile: ca.bundle(t),
+	}})
+	if err == nil {
+		t.Error("newSPIFFE() accepted an SVID of another trust domain")
+	}
+}
+
+func TestSPIFFERejectsUntrustedCA(t *testing.T) {
+	ca, rogue := newTestCA(t), newTestCA(t)
+	server := newTestSPIFFE(t, ca, ca, "spiffe://tlng.internal/engine", "")
+	client := newTestSPIFFE(t, rogue, ca, "spiffe://tlng.internal/agent", "")
+
+	if _, err := handshake(t, server, client); err == nil {
+		t.Error("handshake of a client SVID signed by an untrusted CA succeeded")
+	}
+}
+
+func TestSPIFFERejectsUnexpectedServerID(t *testing.T) {
+	ca := newTestCA(t)
+	server := newTestSPIFFE(t, ca, ca, "spiffe://tlng.internal/query", "")
+	client := newTestSPIFFE(t, ca, ca, "spiffe://tlng.internal/agent", "spiffe://tlng.internal/engine")
+
+	if _, err := handshake(t, server, client); err == nil {
+		t.Error("handshake with a server other than server_id succeeded")
+	}
+}
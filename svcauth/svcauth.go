@@ -0,0 +1,127 @@
+// Package svcauth authenticates service-to-service traffic (agents calling
+// the gateway, operators and services calling the engine's admin API) with
+// SPIFFE X.509 SVIDs or OIDC client-credentials tokens. Each method plugs in
+// by implementing Verifier for the called side and Credentials for the
+// calling side.
+package svcauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// ErrUnauthenticated is returned when a caller presents no valid identity
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrForbidden is returned when an authenticated caller lacks the scope a call needs
+var ErrForbidden = errors.New("forbidden")
+
+// Identity is an authenticated caller
+type Identity struct {
+	Method string // spiffe, oidc or api_token
+	ID     string // SPIFFE ID, the OIDC subject, or the API token ID
+
+	OrgID  string   // Org an API token is bound to; "" for service identities
+	Scopes []string // Scopes of an API token; nil grants every scope
+}
+
+// HasScope reports whether the caller may make calls needing scope
+func (id *Identity) HasScope(scope string) bool {
+	return id.Scopes == nil || slices.Contains(id.Scopes, scope)
+}
+
+// Verifier authenticates inbound calls
+type Verifier interface {
+	// ServerTLSConfig is the TLS configuration servers must listen with; nil
+	// when the method does not authenticate at the transport
+	ServerTLSConfig() *tls.Config
+	// Verify authenticates a caller from its TLS connection state (nil over
+	// plaintext) and bearer token ("" when none was sent)
+	Verify(ctx context.Context, state *tls.ConnectionState, token string) (*Identity, error)
+}
+
+// Credentials authenticate outbound calls
+type Credentials interface {
+	// ClientTLSConfig is the TLS configuration to dial with; nil keeps the
+	// transport's own TLS settings
+	ClientTLSConfig() *tls.Config
+	// Token returns the bearer token to send; "" when the method does not use one
+	Token(ctx context.Context) (string, error)
+}
+
+// NewVerifier creates the verifier of the configured method
+func NewVerifier(cfg Config) (Verifier, error) {
+	cfg.SetDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var v Verifier
+	var err error
+	switch cfg.Mode {
+	case ModeSPIFFE:
+		v, err = newSPIFFE(cfg)
+	case ModeOIDC:
+		v, err = newOIDCVerifier(cfg, http.DefaultClient)
+	default:
+		return nil, fmt.Errorf("service authentication is disabled")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.AllowedIDs) > 0 {
+		v = &allowList{Verifier: v, ids: cfg.AllowedIDs}
+	}
+	return v, nil
+}
+
+// NewCredentials creates the credentials of the configured method
+func NewCredentials(cfg Config) (Credentials, error) {
+	cfg.SetDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch cfg.Mode {
+	case ModeSPIFFE:
+		return newSPIFFE(cfg)
+	case ModeOIDC:
+		return newOIDCCredentials(cfg, http.DefaultClient)
+	default:
+		return nil, fmt.Errorf("service authentication is disabled")
+	}
+}
+
+// allowList restricts a verifier to the configured caller IDs
+type allowList struct {
+	Verifier
+	ids []string
+}
+
+func (a *allowList) Verify(ctx context.Context, state *tls.ConnectionState, token string) (*Identity, error) {
+	id, err := a.Verifier.Verify(ctx, state, token)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(a.ids, id.ID) {
+		return nil, fmt.Errorf("%w: %s is not an allowed caller", ErrUnauthenticated, id.ID)
+	}
+	return id, nil
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the authenticated caller
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the authenticated caller, nil if none
+func IdentityFromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
@@ -0,0 +1,11 @@
+package core
+
+import "errors"
+
+// Standard errors for the verification service
+var (
+	ErrLogNotFound     = errors.New("log hash not anchored on any configured chain")
+	ErrInvalidRequest  = errors.New("invalid request")
+	ErrUnknownChain    = errors.New("no configured chain matches")
+	ErrBlockchainError = errors.New("blockchain query failed")
+)
@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	blockchain "tlng/blockchain/client"
+	"tlng/blockchain/types"
+	"tlng/storage/store"
+)
+
+// Chain is a configured chain the service verifies against
+type Chain struct {
+	Name      string
+	Client    blockchain.BlockchainClient
+	Type      string // types.ChainTypeChainMaker or types.ChainTypeFabric
+	NetworkID string // Chain ID (ChainMaker) or channel (Fabric)
+}
+
+// NewChain wraps a blockchain client, reading its chain identity
+func NewChain(name string, client blockchain.BlockchainClient) Chain {
+	chainType, networkID := blockchain.Network(client)
+	return Chain{Name: name, Client: client, Type: chainType, NetworkID: networkID}
+}
+
+// ProofStore is the read-only part of the store the service uses to find
+// where a log hash was anchored
+type ProofStore interface {
+	GetAttestationProof(ctx context.Context, logHash string) (*store.AttestationProof, error)
+	GetMerkleProof(ctx context.Context, logHash string) (*store.MerkleProof, error)
+}
+
+// Service verifies log hashes and evidence against the chains. It only
+// reads: nothing is written to the chains or the proof store.
+type Service struct {
+	chains []Chain
+	proofs ProofStore // nil verifies against the chains only
+	logger *log.Logger
+}
+
+// NewService creates a verification service; proofs may be nil
+func NewService(chains []Chain, proofs ProofStore, logger *log.Logger) *Service {
+	return &Service{
+		chains: chains,
+		proofs: proofs,
+		logger: logger,
+	}
+}
+
+// Chains returns the configured chains
+func (s *Service) Chains() []Chain {
+	return s.chains
+}
+
+// VerifyContent hashes log content as the gateway does and verifies the hash
+func (s *Service) VerifyContent(ctx context.Context, content, chainName string) (*Verification, error) {
+	if content == "" {
+		return nil, ErrInvalidRequest
+	}
+	sum := sha256.Sum256([]byte(content))
+	return s.VerifyHash(ctx, hex.EncodeToString(sum[:]), chainName)
+}
+
+// VerifyHash checks that a log hash is anchored on one of the chains, or on
+// the named one. With the anchoring transaction known from the proof store
+// the result carries evidence; otherwise the chain is asked for the hash
+// (or its Merkle root) directly and the result has none.
+func (s *Service) VerifyHash(ctx context.Context, logHash, chainName string) (*Verification, error) {
+	if logHash == "" {
+		return nil, ErrInvalidRequest
+	}
+	chains, err := s.candidates(chainName)
+	if err != nil {
+		return nil, err
+	}
+
+	txHash, merkle := s.storedProof(ctx, logHash)
+	anchored := logHash
+	if merkle != nil {
+		anchored = merkle.MerkleRoot
+	}
+
+	failed := 0
+	if txHash != "" {
+		for _, chain := range chains {
+			entries, err := chain.Client.GetLogByTxHash(ctx, txHash)
+			if err != nil {
+				s.logger.Printf("Failed to read tx %s from chain %s: %v", txHash, chain.Name, err)
+				failed++
+				continue
+			}
+			for i := range entries {
+				if entries[i].LogHash != anchored {
+					continue
+				}
+				ev := newEvidence(chain, txHash, &entries[i], logHash, merkle)
+				return verification(chain, logHash, ev, &entries[i], ev.Verify(logHash, &entries[i])), nil
+			}
+		}
+	}
+
+	// Without the transaction the chain can only confirm the hash is stored
+	for _, chain := range chains {
+		raw, err := chain.Client.FindLogByHash(ctx, anchored)
+		if err != nil {
+			s.logger.Printf("Failed to look up %s on chain %s: %v", anchored, chain.Name, err)
+			failed++
+			continue
+		}
+		if raw == "" {
+			continue
+		}
+		var verifyErr error
+		if merkle != nil {
+			verifyErr = types.VerifyMerkleProof(logHash, merkleProof(merkle), merkle.MerkleRoot)
+		}
+		return verification(chain, logHash, nil, nil, verifyErr), nil
+	}
+	if failed > 0 {
+		return nil, ErrBlockchainError
+	}
+	return nil, ErrLogNotFound
+}
+
+// VerifyEvidence checks evidence against the chain it names. logHash is the
+// hash the caller expects the evidence to prove; empty takes the evidence's own.
+func (s *Service) VerifyEvidence(ctx context.Context, ev *types.Evidence, logHash string) (*Verification, error) {
+	if logHash == "" {
+		logHash = ev.LogHash
+	}
+	var chain *Chain
+	for i := range s.chains {
+		if s.chains[i].Type == ev.ChainType && s.chains[i].NetworkID == ev.NetworkID {
+			chain = &s.chains[i]
+			break
+		}
+	}
+	if chain == nil {
+		return nil, fmt.Errorf("%w chain_type %s network_id %s", ErrUnknownChain, ev.ChainType, ev.NetworkID)
+	}
+
+	entries, err := chain.Client.GetLogByTxHash(ctx, ev.TxID)
+	if err != nil {
+		s.logger.Printf("Failed to read tx %s from chain %s: %v", ev.TxID, chain.Name, err)
+		return nil, ErrBlockchainError
+	}
+	for i := range entries {
+		if entries[i].LogHash == ev.AnchoredHash() {
+			return verification(*chain, logHash, ev, &entries[i], ev.Verify(logHash, &entries[i])), nil
+		}
+	}
+	reason := fmt.Errorf("%w: transaction %s does not anchor %s", types.ErrEvidenceInvalid, ev.TxID, ev.AnchoredHash())
+	return verification(*chain, logHash, ev, nil, reason), nil
+}
+
+// candidates returns the named chain, or all chains for an empty name
+func (s *Service) candidates(name string) ([]Chain, error) {
+	if name == "" {
+		return s.chains, nil
+	}
+	for _, chain := range s.chains {
+		if chain.Name == name {
+			return []Chain{chain}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w name %s", ErrUnknownChain, name)
+}
+
+// storedProof returns the anchoring transaction of a log hash and, for logs
+// anchored under a Merkle root, its inclusion proof, as far as the proof
+// store knows them
+func (s *Service) storedProof(ctx context.Context, logHash string) (string, *store.MerkleProof) {
+	if s.proofs == nil {
+		return "", nil
+	}
+	mp, err := s.proofs.GetMerkleProof(ctx, logHash)
+	if err == nil {
+		return mp.TxHash, mp
+	}
+	s.proofStoreFailed(err)
+	proof, err := s.proofs.GetAttestationProof(ctx, logHash)
+	if err == nil {
+		return proof.TxHash, nil
+	}
+	s.proofStoreFailed(err)
+	return "", nil
+}
+
+// proofStoreFailed logs a proof store error other than a missing proof or a
+// schema without the table
+func (s *Service) proofStoreFailed(err error) {
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, store.ErrFeatureUnavailable) {
+		return
+	}
+	s.logger.Printf("Warning: proof store lookup failed, verifying against the chain only: %v", err)
+}
+
+// newEvidence builds the evidence of a log hash from the chain's record of its anchoring transaction
+func newEvidence(chain Chain, txHash string, onChain *types.AuditData, logHash string, merkle *store.MerkleProof) *types.Evidence {
+	block := types.EvidenceBlock{Height: onChain.BlockHeight, Hash: onChain.BlockHash}
+	if !onChain.BlockTimestamp.IsZero() {
+		ts := onChain.BlockTimestamp.UTC()
+		block.Timestamp = &ts
+	}
+	if merkle != nil {
+		return types.NewMerkleEvidence(chain.Type, chain.NetworkID, txHash, block, logHash, merkle.MerkleRoot, merkleProof(merkle))
+	}
+	return types.NewEvidence(chain.Type, chain.NetworkID, txHash, block, logHash)
+}
+
+func merkleProof(mp *store.MerkleProof) types.MerkleProof {
+	return types.MerkleProof{LeafIndex: mp.LeafIndex, LeafCount: mp.LeafCount, Siblings: mp.Siblings}
+}
+
+// verification assembles the result; verifyErr, if any, is why the check failed
+func verification(chain Chain, logHash string, ev *types.Evidence, onChain *types.AuditData, verifyErr error) *Verification {
+	v := &Verification{
+		Verified:   verifyErr == nil,
+		LogHash:    logHash,
+		Chain:      chain.Name,
+		Evidence:   ev,
+		VerifiedAt: time.Now().UTC(),
+	}
+	if verifyErr != nil {
+		v.Reason = verifyErr.Error()
+	}
+	if onChain != nil {
+		v.OnChain = &OnChainRecord{
+			LogHash:         onChain.LogHash,
+			SubmitterOrgID:  onChain.SubmitterOrgID,
+			Timestamp:       onChain.Timestamp,
+			ClientTimestamp: onChain.ClientTimestamp,
+			BlockHeight:     onChain.BlockHeight,
+			BlockHash:       onChain.BlockHash,
+			Confirmations:   onChain.Confirmations,
+		}
+	}
+	return v
+}
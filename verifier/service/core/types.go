@@ -0,0 +1,45 @@
+package core
+
+import (
+	"time"
+
+	"tlng/blockchain/types"
+)
+
+// Verification is the outcome of verifying a log hash or evidence
+type Verification struct {
+	Verified bool   `json:"verified"`
+	LogHash  string `json:"log_hash"`
+	Chain    string `json:"chain"`            // Name of the configured chain that was checked
+	Reason   string `json:"reason,omitempty"` // Why verification failed
+
+	// Evidence of the anchoring; absent when the anchoring transaction is not
+	// known and the chain only confirmed it stores the hash
+	Evidence *types.Evidence `json:"evidence,omitempty"`
+	OnChain  *OnChainRecord  `json:"on_chain,omitempty"` // The chain's record of the anchored hash
+
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// OnChainRecord is the record read back from the anchoring transaction
+type OnChainRecord struct {
+	LogHash         string `json:"log_hash"` // Anchored hash: the log hash or its Merkle root
+	SubmitterOrgID  string `json:"submitter_org_id"`
+	Timestamp       string `json:"timestamp"`
+	ClientTimestamp string `json:"client_timestamp,omitempty"`
+	BlockHeight     uint64 `json:"block_height"`
+	BlockHash       string `json:"block_hash,omitempty"`
+	Confirmations   uint64 `json:"confirmations,omitempty"`
+}
+
+// ContentRequest is the body of POST /v1/verify/content
+type ContentRequest struct {
+	LogContent string `json:"log_content"`
+}
+
+// ChainInfo describes a configured chain in GET /v1/chains
+type ChainInfo struct {
+	Name      string `json:"name"`
+	ChainType string `json:"chain_type"`
+	NetworkID string `json:"network_id"`
+}
@@ -0,0 +1,226 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"tlng/blockchain/types"
+	"tlng/errcatalog"
+	"tlng/verifier/service/core"
+)
+
+// maxEvidenceBytes bounds the body of POST /v1/verify/evidence
+const maxEvidenceBytes = 1 << 20
+
+// maxContentBytes bounds the body of POST /v1/verify/content
+const maxContentBytes = 10 << 20
+
+// cborContentType is the media type of CBOR evidence
+const cborContentType = "application/cbor"
+
+// Handler wraps the verification service with HTTP handlers
+type Handler struct {
+	service *core.Service
+	logger  *log.Logger
+}
+
+// NewHandler creates a new HTTP handler
+func NewHandler(service *core.Service, logger *log.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers the read-only verification routes
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/verify/hash/", h.VerifyHash)
+	mux.HandleFunc("/v1/verify/content", h.VerifyContent)
+	mux.HandleFunc("/v1/verify/evidence", h.VerifyEvidence)
+	mux.HandleFunc("/v1/evidence/", h.GetEvidence)
+	mux.HandleFunc("/v1/chains", h.ListChains)
+}
+
+// VerifyHash handles GET /v1/verify/hash/{log_hash}?chain=
+func (h *Handler) VerifyHash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	logHash, ok := h.pathHash(w, r, "/v1/verify/hash/")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.VerifyHash(r.Context(), logHash, r.URL.Query().Get("chain"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// VerifyContent handles POST /v1/verify/content?chain=
+func (h *Handler) VerifyContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	var req core.ContentRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxContentBytes)).Decode(&req); err != nil {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid request body")
+		return
+	}
+
+	result, err := h.service.VerifyContent(r.Context(), req.LogContent, r.URL.Query().Get("chain"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// VerifyEvidence handles POST /v1/verify/evidence?log_hash= with JSON or
+// CBOR evidence as the body; log_hash is the hash the evidence must prove
+func (h *Handler) VerifyEvidence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEvidenceBytes+1))
+	if err != nil {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "failed to read request body")
+		return
+	}
+	if len(body) > maxEvidenceBytes {
+		h.writeCodedError(w, r, errcatalog.CodeRequestTooLarge, "evidence too large",
+			map[string]string{errcatalog.ParamLimit: strconv.Itoa(maxEvidenceBytes)})
+		return
+	}
+	ev, err := types.DecodeEvidence(body)
+	if err != nil {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	result, err := h.service.VerifyEvidence(r.Context(), ev, r.URL.Query().Get("log_hash"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// GetEvidence handles GET /v1/evidence/{log_hash}?chain=, returning verified
+// evidence as JSON, or as CBOR for Accept: application/cbor
+func (h *Handler) GetEvidence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	logHash, ok := h.pathHash(w, r, "/v1/evidence/")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.VerifyHash(r.Context(), logHash, r.URL.Query().Get("chain"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	if !result.Verified {
+		h.writeError(w, r, errcatalog.CodeConflict, result.Reason)
+		return
+	}
+	if result.Evidence == nil {
+		h.writeError(w, r, errcatalog.CodeNotFound, "log hash is anchored but its transaction is not in the proof store")
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), cborContentType) {
+		data, err := result.Evidence.MarshalCBOR()
+		if err != nil {
+			h.handleServiceError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", cborContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, result.Evidence)
+}
+
+// ListChains handles GET /v1/chains
+func (h *Handler) ListChains(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeCodedError(w, r, errcatalog.CodeMethodNotAllowed, "method not allowed", nil)
+		return
+	}
+	chains := h.service.Chains()
+	infos := make([]core.ChainInfo, len(chains))
+	for i, c := range chains {
+		infos[i] = core.ChainInfo{Name: c.Name, ChainType: c.Type, NetworkID: c.NetworkID}
+	}
+	h.writeJSON(w, http.StatusOK, infos)
+}
+
+// pathHash extracts the log hash following prefix, writing an error if it is missing or invalid
+func (h *Handler) pathHash(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+	logHash := strings.TrimSpace(strings.TrimPrefix(r.URL.Path, prefix))
+	if logHash == "" {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "missing log_hash")
+		return "", false
+	}
+	if strings.Contains(logHash, "..") || strings.Contains(logHash, "/") {
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, "invalid log_hash: path traversal characters not allowed")
+		return "", false
+	}
+	return logHash, true
+}
+
+// writeError writes a JSON error response with its catalog code and HTTP
+// status, the message being the detail of the code's message
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, code errcatalog.Code, message string) {
+	h.writeCodedError(w, r, code, message, map[string]string{errcatalog.ParamDetail: message})
+}
+
+// writeCodedError writes a JSON error response with its catalog code and
+// message parameters, and the message in the language of r's Accept-Language
+// if the catalog has it
+func (h *Handler) writeCodedError(w http.ResponseWriter, r *http.Request, code errcatalog.Code, message string, params map[string]string) {
+	statusCode := errcatalog.Get(code).HTTPStatus
+	h.writeJSON(w, statusCode, errcatalog.NewHTTPError(code, params, message, statusCode, r.Header.Get("Accept-Language")))
+}
+
+// writeJSON writes a JSON response
+func (h *Handler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		// Status code already sent, can only log the error
+		h.logger.Printf("ERROR: Failed to encode JSON response: %v", err)
+	}
+}
+
+// handleServiceError maps service errors to catalog codes using typed error checking
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, core.ErrLogNotFound):
+		h.writeError(w, r, errcatalog.CodeNotFound, err.Error())
+	case errors.Is(err, core.ErrInvalidRequest):
+		h.writeError(w, r, errcatalog.CodeInvalidRequest, err.Error())
+	case errors.Is(err, core.ErrUnknownChain):
+		h.writeError(w, r, errcatalog.CodeUnknownChain, err.Error())
+	case errors.Is(err, core.ErrBlockchainError):
+		h.writeError(w, r, errcatalog.CodeChainUnavailable, err.Error())
+	default:
+		h.logger.Printf("ERROR: %v", err)
+		h.writeCodedError(w, r, errcatalog.CodeInternal, "internal server error", nil)
+	}
+}